@@ -0,0 +1,290 @@
+package fire
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/256dpi/xo"
+
+	"github.com/256dpi/fire/coal"
+)
+
+// Component is a component of an application that is started, checked and
+// closed by the app e.g. a job queue or a watcher.
+type Component struct {
+	// The name used in errors and health reports.
+	Name string
+
+	// The optional function that starts the component. It should return once
+	// the component is available.
+	Start func() error
+
+	// The optional function that checks the health of the component.
+	Check func(ctx context.Context) error
+
+	// The optional function that closes the component. The shutdown
+	// continues if the function does not return before the timeout.
+	Close func(ctx context.Context) error
+
+	// The time the component is given to close.
+	//
+	// Default: 10s.
+	Timeout time.Duration
+}
+
+// App wires the parts of an application together and manages their
+// lifecycle. Start will start all components in order and then the HTTP
+// server. Shutdown will first stop the HTTP server from accepting new
+// requests, then drain the in-flight operations of all groups and finally
+// close all components in reverse order and the store.
+type App struct {
+	// The store that is checked and closed after all components.
+	Store *coal.Store
+
+	// The HTTP server that serves the application.
+	Server *http.Server
+
+	// The groups that are drained.
+	Groups []*Group
+
+	// The components that are started in order and closed in reverse order
+	// after the groups have been drained. Usually stores are registered before
+	// queues so that queues are closed first.
+	Components []Component
+
+	// The time the server and groups are given to drain.
+	//
+	// Default: 30s.
+	DrainTimeout time.Duration
+
+	// The reporter that is called with server errors.
+	Reporter func(error)
+
+	listener net.Listener
+}
+
+// Start will start all components in order and then serve the HTTP server in
+// the background. It will return an error if a component failed to start or
+// the server address could not be bound. In this case, the already started
+// components are closed in reverse order.
+func (a *App) Start() error {
+	// start components
+	for i, component := range a.Components {
+		if component.Start != nil {
+			err := component.Start()
+			if err != nil {
+				a.rollback(i)
+				return xo.WF(err, "unable to start %s", component.Name)
+			}
+		}
+	}
+
+	// check server
+	if a.Server == nil {
+		return nil
+	}
+
+	// get address
+	addr := a.Server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+
+	// bind address
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		a.rollback(len(a.Components))
+		return xo.WF(err, "unable to start server")
+	}
+
+	// set listener
+	a.listener = listener
+
+	// serve requests
+	go func() {
+		err := a.Server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed && a.Reporter != nil {
+			a.Reporter(xo.W(err))
+		}
+	}()
+
+	return nil
+}
+
+// Addr returns the address of the started HTTP server.
+func (a *App) Addr() net.Addr {
+	if a.listener == nil {
+		return nil
+	}
+
+	return a.listener.Addr()
+}
+
+// Health will check the store and all components and return the errors by
+// name. Healthy parts have a nil error.
+func (a *App) Health(ctx context.Context) map[string]error {
+	// prepare report
+	report := map[string]error{}
+
+	// check store
+	if a.Store != nil {
+		err := a.Store.Client().Ping(ctx, nil)
+		if err != nil {
+			err = xo.W(err)
+		}
+		report["store"] = err
+	}
+
+	// check components
+	for _, component := range a.Components {
+		if component.Check != nil {
+			report[component.Name] = component.Check(ctx)
+		}
+	}
+
+	return report
+}
+
+// HealthHandler returns a handler that responds with a JSON object that
+// contains the health status of the store and all components. The status is
+// "Service Unavailable" if some part is unhealthy. Errors are not exposed and
+// are reported using the reporter instead.
+func (a *App) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// check health
+		report := a.Health(r.Context())
+
+		// prepare response
+		status := http.StatusOK
+		res := map[string]string{}
+		for name, err := range report {
+			if err != nil {
+				status = http.StatusServiceUnavailable
+				res[name] = "unhealthy"
+				if a.Reporter != nil {
+					a.Reporter(xo.WF(err, "%s is unhealthy", name))
+				}
+			} else {
+				res[name] = "ok"
+			}
+		}
+
+		// write response
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(res)
+	})
+}
+
+// Shutdown will shut down the application. All components are closed even if
+// draining the server or groups failed. The first encountered error is
+// returned.
+func (a *App) Shutdown(ctx context.Context) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "fire/App.Shutdown")
+	defer span.End()
+
+	// get drain timeout
+	drainTimeout := a.DrainTimeout
+	if drainTimeout == 0 {
+		drainTimeout = 30 * time.Second
+	}
+
+	// prepare error
+	var firstErr error
+	record := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// create drain context
+	drainCtx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+
+	// reject new group requests
+	for _, group := range a.Groups {
+		group.reject()
+	}
+
+	// shut down server
+	if a.Server != nil {
+		err := a.Server.Shutdown(drainCtx)
+		if err != nil {
+			record(xo.WF(err, "unable to shut down server"))
+		}
+	}
+
+	// drain groups
+	for _, group := range a.Groups {
+		err := group.Drain(drainCtx)
+		if err != nil {
+			record(xo.WF(err, "unable to drain group"))
+		}
+	}
+
+	// close components in reverse order
+	for i := len(a.Components) - 1; i >= 0; i-- {
+		if a.Components[i].Close != nil {
+			record(a.close(ctx, a.Components[i]))
+		}
+	}
+
+	// close store
+	if a.Store != nil {
+		record(a.close(ctx, Component{
+			Name: "store",
+			Close: func(context.Context) error {
+				return a.Store.Close()
+			},
+		}))
+	}
+
+	return firstErr
+}
+
+func (a *App) rollback(started int) {
+	// close started components in reverse order
+	for i := started - 1; i >= 0; i-- {
+		component := a.Components[i]
+		if component.Close != nil {
+			err := a.close(context.Background(), component)
+			if err != nil && a.Reporter != nil {
+				a.Reporter(err)
+			}
+		}
+	}
+}
+
+func (a *App) close(ctx context.Context, component Component) error {
+	// get timeout
+	timeout := component.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	// create context
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// close component
+	done := make(chan error, 1)
+	go func() {
+		done <- component.Close(ctx)
+	}()
+
+	// await close
+	select {
+	case err := <-done:
+		if err != nil {
+			return xo.WF(err, "unable to close %s", component.Name)
+		}
+	case <-ctx.Done():
+		return xo.WF(ctx.Err(), "unable to close %s", component.Name)
+	}
+
+	return nil
+}
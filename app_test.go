@@ -0,0 +1,285 @@
+package fire
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire/coal"
+)
+
+func TestAppShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	group := NewGroup(xo.Crash)
+	group.Handle("wait", &GroupAction{
+		Action: A("TestAppShutdown", []string{"GET"}, 0, 0, func(ctx *Context) error {
+			close(started)
+			<-release
+			ctx.ResponseWriter.WriteHeader(http.StatusOK)
+			return nil
+		}),
+	})
+
+	handler := group.Endpoint("")
+
+	request := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/wait", nil))
+		return rec.Code
+	}
+
+	inflight := make(chan int, 1)
+	go func() {
+		inflight <- request()
+	}()
+
+	<-started
+
+	var events []string
+	app := &App{
+		Groups: []*Group{group},
+		Components: []Component{
+			{
+				Name: "store",
+				Close: func(ctx context.Context) error {
+					events = append(events, "store")
+					return nil
+				},
+			},
+			{
+				Name:    "slow",
+				Timeout: 10 * time.Millisecond,
+				Close: func(ctx context.Context) error {
+					select {}
+				},
+			},
+			{
+				Name: "queue",
+				Close: func(ctx context.Context) error {
+					events = append(events, "queue")
+					return nil
+				},
+			},
+		},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Shutdown(context.Background())
+	}()
+
+	assert.Eventually(t, func() bool {
+		return request() == http.StatusServiceUnavailable
+	}, time.Second, time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("shutdown returned before drain")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-inflight)
+
+	err := <-done
+	assert.Error(t, err)
+	assert.Equal(t, "unable to close slow: context deadline exceeded", err.Error())
+	assert.Equal(t, []string{"queue", "store"}, events)
+}
+
+func TestAppShutdownDrainTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	group := NewGroup(xo.Crash)
+	group.Handle("wait", &GroupAction{
+		Action: A("TestAppShutdownDrainTimeout", []string{"GET"}, 0, 0, func(ctx *Context) error {
+			close(started)
+			<-release
+			return nil
+		}),
+	})
+
+	go func() {
+		rec := httptest.NewRecorder()
+		group.Endpoint("").ServeHTTP(rec, httptest.NewRequest("GET", "/wait", nil))
+	}()
+
+	<-started
+
+	var closed bool
+	app := &App{
+		Groups: []*Group{group},
+		Components: []Component{
+			{
+				Name: "store",
+				Close: func(ctx context.Context) error {
+					closed = true
+					return nil
+				},
+			},
+		},
+		DrainTimeout: 10 * time.Millisecond,
+	}
+
+	err := app.Shutdown(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, "unable to drain group: context deadline exceeded", err.Error())
+	assert.True(t, closed)
+}
+
+func TestAppLifecycle(t *testing.T) {
+	var events []string
+	var unhealthy error
+	var reported []string
+
+	app := &App{
+		Store: coal.MustOpen(nil, "test-fire-app", xo.Crash),
+		Components: []Component{
+			{
+				Name: "queue",
+				Start: func() error {
+					events = append(events, "start queue")
+					return nil
+				},
+				Check: func(ctx context.Context) error {
+					return unhealthy
+				},
+				Close: func(ctx context.Context) error {
+					events = append(events, "close queue")
+					return nil
+				},
+			},
+			{
+				Name: "watcher",
+				Close: func(ctx context.Context) error {
+					events = append(events, "close watcher")
+					return nil
+				},
+			},
+		},
+		Reporter: func(err error) {
+			reported = append(reported, err.Error())
+		},
+	}
+
+	app.Server = &http.Server{
+		Addr:    "127.0.0.1:0",
+		Handler: app.HealthHandler(),
+	}
+
+	err := app.Start()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start queue"}, events)
+	assert.NotNil(t, app.Addr())
+
+	res, err := http.Get("http://" + app.Addr().String())
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"store": "ok", "queue": "ok"}`, string(body))
+
+	unhealthy = xo.F("stalled")
+
+	res, err = http.Get("http://" + app.Addr().String())
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.JSONEq(t, `{"store": "ok", "queue": "unhealthy"}`, string(body))
+	assert.Equal(t, []string{"queue is unhealthy: stalled"}, reported)
+
+	err = app.Shutdown(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"start queue", "close watcher", "close queue"}, events)
+
+	_, err = http.Get("http://" + app.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestAppStartError(t *testing.T) {
+	app := &App{
+		Components: []Component{
+			{
+				Name: "queue",
+				Start: func() error {
+					return xo.F("failed")
+				},
+			},
+		},
+	}
+
+	err := app.Start()
+	assert.Error(t, err)
+	assert.Equal(t, "unable to start queue: failed", err.Error())
+
+	// started components are closed in reverse order
+	var events []string
+	var reported []string
+	app = &App{
+		Components: []Component{
+			{
+				Name: "store",
+				Start: func() error {
+					events = append(events, "start store")
+					return nil
+				},
+				Close: func(context.Context) error {
+					events = append(events, "close store")
+					return nil
+				},
+			},
+			{
+				Name: "cache",
+				Close: func(context.Context) error {
+					events = append(events, "close cache")
+					return xo.F("failed")
+				},
+			},
+			{
+				Name: "queue",
+				Start: func() error {
+					events = append(events, "start queue")
+					return xo.F("failed")
+				},
+				Close: func(context.Context) error {
+					events = append(events, "close queue")
+					return nil
+				},
+			},
+			{
+				Name: "worker",
+				Start: func() error {
+					events = append(events, "start worker")
+					return nil
+				},
+			},
+		},
+		Reporter: func(err error) {
+			reported = append(reported, err.Error())
+		},
+	}
+
+	err = app.Start()
+	assert.Error(t, err)
+	assert.Equal(t, "unable to start queue: failed", err.Error())
+	assert.Equal(t, []string{
+		"start store",
+		"start queue",
+		"close cache",
+		"close store",
+	}, events)
+	assert.Equal(t, []string{
+		"unable to close cache: failed",
+	}, reported)
+}
@@ -0,0 +1,213 @@
+package fire
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/256dpi/fire/coal"
+)
+
+const archiveIDKey = "fire:archive-id"
+
+// Archive is the record of a request and its response.
+type Archive struct {
+	// The ID of the archive generated by the server. It may be obtained during
+	// the request using ArchiveID to correlate other records (e.g. audit
+	// entries) with the archive.
+	ID string `json:"id"`
+
+	// The client provided value of the "X-Request-ID" header, if present.
+	RequestID string `json:"request-id"`
+
+	// The time the request has been received.
+	Time time.Time `json:"time"`
+
+	// The duration of the request.
+	Duration time.Duration `json:"duration"`
+
+	// The name of the controller and the executed operation.
+	Controller string `json:"controller"`
+	Operation  string `json:"operation"`
+
+	// The request method and URL.
+	Method string `json:"method"`
+	URL    string `json:"url"`
+
+	// The request headers and body.
+	RequestHeader http.Header `json:"request-header"`
+	RequestBody   []byte      `json:"request-body"`
+
+	// The response status, headers and body.
+	Status         int         `json:"status"`
+	ResponseHeader http.Header `json:"response-header"`
+	ResponseBody   []byte      `json:"response-body"`
+}
+
+// Archiver stores archives of requests and responses.
+type Archiver interface {
+	// Archive should durably store the provided archive.
+	Archive(ctx context.Context, archive *Archive) error
+}
+
+// ArchiveID returns the ID of the archive that is created for the current
+// request. It returns an empty string if the request is not archived.
+func ArchiveID(ctx *Context) string {
+	id, _ := ctx.Data[archiveIDKey].(string)
+	return id
+}
+
+var archiveRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+const archiveRedacted = "[redacted]"
+
+type archiveWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *archiveWriter) WriteHeader(status int) {
+	// record status
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *archiveWriter) Write(data []byte) (int, error) {
+	// record status
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	// record body
+	w.body.Write(data)
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *archiveWriter) Flush() {
+	// flush writer if supported
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+type archiveBody struct {
+	io.ReadCloser
+	body bytes.Buffer
+}
+
+func (b *archiveBody) Read(data []byte) (int, error) {
+	// read data
+	n, err := b.ReadCloser.Read(data)
+
+	// record body
+	b.body.Write(data[:n])
+
+	return n, err
+}
+
+func (c *Controller) archive(ctx *Context) (http.ResponseWriter, func()) {
+	// generate ID
+	id := coal.New().Hex()
+	ctx.Data[archiveIDKey] = id
+
+	// prepare archive
+	archive := &Archive{
+		ID:            id,
+		RequestID:     ctx.HTTPRequest.Header.Get("X-Request-ID"),
+		Time:          time.Now(),
+		Controller:    c.meta.PluralName,
+		Method:        ctx.HTTPRequest.Method,
+		URL:           ctx.HTTPRequest.URL.String(),
+		RequestHeader: ctx.HTTPRequest.Header.Clone(),
+	}
+
+	// wrap body
+	body := &archiveBody{ReadCloser: ctx.HTTPRequest.Body}
+	ctx.HTTPRequest.Body = body
+
+	// wrap writer
+	writer := &archiveWriter{ResponseWriter: ctx.ResponseWriter}
+	ctx.ResponseWriter = writer
+
+	return writer, func() {
+		// finish archive
+		archive.Duration = time.Since(archive.Time)
+		archive.Operation = ctx.Operation.String()
+		archive.RequestBody = c.redactBody(body.body.Bytes())
+		archive.Status = writer.status
+		archive.ResponseHeader = writer.Header().Clone()
+		archive.ResponseBody = c.redactBody(writer.body.Bytes())
+
+		// redact headers
+		for _, header := range []http.Header{archive.RequestHeader, archive.ResponseHeader} {
+			for _, key := range archiveRedactedHeaders {
+				if header.Get(key) != "" {
+					header.Set(key, archiveRedacted)
+				}
+			}
+		}
+
+		// store archive
+		err := c.Archiver.Archive(context.Background(), archive)
+		if err != nil && ctx.Group != nil && ctx.Group.reporter != nil {
+			ctx.Group.reporter(err)
+		}
+	}
+}
+
+func (c *Controller) redactBody(body []byte) []byte {
+	// check body and redactions
+	if len(body) == 0 || len(c.ArchiveRedactions) == 0 {
+		return body
+	}
+
+	// decode body, omit unknown bodies
+	var doc map[string]interface{}
+	err := json.Unmarshal(body, &doc)
+	if err != nil {
+		return nil
+	}
+
+	// prepare redaction
+	redact := func(value interface{}) {
+		res, _ := value.(map[string]interface{})
+		attrs, _ := res["attributes"].(map[string]interface{})
+		for _, key := range c.ArchiveRedactions {
+			if _, ok := attrs[key]; ok {
+				attrs[key] = archiveRedacted
+			}
+		}
+	}
+
+	// redact primary data
+	switch data := doc["data"].(type) {
+	case map[string]interface{}:
+		redact(data)
+	case []interface{}:
+		for _, res := range data {
+			redact(res)
+		}
+	}
+
+	// redact included resources
+	included, _ := doc["included"].([]interface{})
+	for _, res := range included {
+		redact(res)
+	}
+
+	// encode body
+	body, err = json.Marshal(doc)
+	if err != nil {
+		return nil
+	}
+
+	return body
+}
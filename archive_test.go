@@ -0,0 +1,117 @@
+package fire
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/256dpi/fire/coal"
+)
+
+type testArchiver struct {
+	mutex    sync.Mutex
+	archives []*Archive
+}
+
+func (a *testArchiver) Archive(_ context.Context, archive *Archive) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.archives = append(a.archives, archive)
+	return nil
+}
+
+func TestArchiver(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		archiver := &testArchiver{}
+
+		var archiveID string
+		tester.Assign("", &Controller{
+			Model:             &postModel{},
+			Store:             tester.Store,
+			Archiver:          archiver,
+			ArchiveRedactions: []string{"text-body"},
+			Notifiers: L{
+				C("ArchiveID", 0, All(), func(ctx *Context) error {
+					archiveID = ArchiveID(ctx)
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+			Store: tester.Store,
+		}, &Controller{
+			Model: &selectionModel{},
+			Store: tester.Store,
+		}, &Controller{
+			Model: &noteModel{},
+			Store: tester.Store,
+		})
+
+		tester.Header["Authorization"] = "Bearer secret"
+		defer delete(tester.Header, "Authorization")
+
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Hello",
+					"text-body": "Secret"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "Secret", gjson.Get(r.Body.String(), "data.attributes.text-body").String())
+		})
+
+		assert.Len(t, archiver.archives, 1)
+		archive := archiver.archives[0]
+		assert.NotEmpty(t, archive.ID)
+		assert.Equal(t, archiveID, archive.ID)
+		assert.Equal(t, "posts", archive.Controller)
+		assert.Equal(t, "Create", archive.Operation)
+		assert.Equal(t, "POST", archive.Method)
+		assert.Equal(t, "/posts", archive.URL)
+		assert.Equal(t, "[redacted]", archive.RequestHeader.Get("Authorization"))
+		assert.Equal(t, "Hello", gjson.GetBytes(archive.RequestBody, "data.attributes.title").String())
+		assert.Equal(t, "[redacted]", gjson.GetBytes(archive.RequestBody, "data.attributes.text-body").String())
+		assert.Equal(t, http.StatusCreated, archive.Status)
+		assert.Equal(t, "Hello", gjson.GetBytes(archive.ResponseBody, "data.attributes.title").String())
+		assert.Equal(t, "[redacted]", gjson.GetBytes(archive.ResponseBody, "data.attributes.text-body").String())
+		assert.NotZero(t, archive.Time)
+
+		tester.Header["X-Request-ID"] = "foo"
+		defer delete(tester.Header, "X-Request-ID")
+
+		tester.Request("GET", "posts/"+coal.New().Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNotFound, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		assert.Len(t, archiver.archives, 2)
+		archive = archiver.archives[1]
+		assert.NotEqual(t, "foo", archive.ID)
+		assert.Equal(t, "foo", archive.RequestID)
+		assert.Equal(t, http.StatusNotFound, archive.Status)
+		assert.Equal(t, "404", gjson.GetBytes(archive.ResponseBody, "errors.0.status").String())
+
+		tester.Request("GET", "comments", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+		assert.Len(t, archiver.archives, 2)
+	})
+}
+
+func TestArchiveWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	var writer http.ResponseWriter = &archiveWriter{ResponseWriter: rec}
+	flusher, ok := writer.(http.Flusher)
+	assert.True(t, ok)
+
+	flusher.Flush()
+	assert.True(t, rec.Flushed)
+}
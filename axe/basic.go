@@ -20,6 +20,10 @@ import (
 // the same rules applies also to unlabeled jobs in addition to that finished
 // jobs must be older than the specified duration.
 func Enqueue(ctx context.Context, store *coal.Store, job Job, delay, isolation time.Duration) (bool, error) {
+	return enqueue(ctx, store, job, delay, isolation, false)
+}
+
+func enqueue(ctx context.Context, store *coal.Store, job Job, delay, isolation time.Duration, exclusive bool) (bool, error) {
 	// get meta and base
 	meta := GetMeta(job)
 	base := job.GetBase()
@@ -57,6 +61,12 @@ func Enqueue(ctx context.Context, store *coal.Store, job Job, delay, isolation t
 		return false, err
 	}
 
+	// merge metadata
+	metadata := GetMetadata(WithMetadata(ctx, base.Metadata))
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+
 	// get time
 	now := time.Now()
 
@@ -66,6 +76,7 @@ func Enqueue(ctx context.Context, store *coal.Store, job Job, delay, isolation t
 		Name:      meta.Name,
 		Label:     base.Label,
 		Data:      data,
+		Metadata:  metadata,
 		State:     Enqueued,
 		Created:   now,
 		Available: now.Add(delay),
@@ -77,8 +88,8 @@ func Enqueue(ctx context.Context, store *coal.Store, job Job, delay, isolation t
 		},
 	}
 
-	// insert unlabeled non-isolated jobs immediately
-	if base.Label == "" && isolation == 0 {
+	// insert unlabeled non-isolated non-exclusive jobs immediately
+	if base.Label == "" && isolation == 0 && !exclusive {
 		err := store.M(&Model{}).Insert(ctx, model)
 		if err != nil {
 			return false, err
@@ -197,6 +208,9 @@ func Dequeue(ctx context.Context, store *coal.Store, job Job, timeout time.Durat
 	job.GetBase().Label = model.Label
 	span.Tag("label", model.Label)
 
+	// set metadata
+	job.GetBase().Metadata = model.Metadata
+
 	// validate job
 	err = job.Validate()
 	if err != nil {
@@ -298,6 +312,88 @@ func Update(ctx context.Context, store *coal.Store, job Job, status string, prog
 	return nil
 }
 
+// Record will record the completion of a side effect with the provided
+// idempotency key for the specified job. Only jobs in the "dequeued" state can
+// record side effects. Recorded side effects are retained across attempts.
+func Record(ctx context.Context, store *coal.Store, job Job, key string) error {
+	// get meta and base
+	meta := GetMeta(job)
+	base := job.GetBase()
+
+	// trace
+	ctx, span := xo.Trace(ctx, "axe/Record")
+	span.Tag("name", meta.Name)
+	span.Tag("label", base.Label)
+	span.Tag("id", job.ID().Hex())
+	span.Tag("key", key)
+	defer span.End()
+
+	// check key
+	if key == "" {
+		return xo.F("missing key")
+	}
+
+	// update job
+	found, err := store.M(&Model{}).UpdateFirst(ctx, nil, bson.M{
+		"_id":   job.ID(),
+		"State": Dequeued,
+		"Effects": bson.M{
+			"$nin": bson.A{key},
+		},
+	}, bson.M{
+		"$push": bson.M{
+			"Effects": key,
+		},
+	}, nil, false)
+	if err != nil {
+		return err
+	} else if found {
+		return nil
+	}
+
+	// check if already recorded
+	count, err := store.M(&Model{}).Count(ctx, bson.M{
+		"_id":     job.ID(),
+		"State":   Dequeued,
+		"Effects": key,
+	}, 0, 0, false, coal.NoTransaction)
+	if err != nil {
+		return err
+	} else if count == 0 {
+		return xo.F("missing job")
+	}
+
+	return nil
+}
+
+// Recorded will return the idempotency keys of the side effects that have
+// been recorded for the specified job.
+func Recorded(ctx context.Context, store *coal.Store, job Job) ([]string, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "axe/Recorded")
+	span.Tag("id", job.ID().Hex())
+	defer span.End()
+
+	// get effects
+	value, found, err := store.M(&Model{}).Project(ctx, job.ID(), "Effects", false)
+	if err != nil {
+		return nil, err
+	} else if !found {
+		return nil, xo.F("missing job")
+	}
+
+	// collect keys
+	list, _ := value.(bson.A)
+	keys := make([]string, 0, len(list))
+	for _, item := range list {
+		if key, ok := item.(string); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
 // Complete will complete the specified job. Only jobs in the "dequeued" state
 // can be completed.
 func Complete(ctx context.Context, store *coal.Store, job Job) error {
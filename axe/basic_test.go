@@ -337,6 +337,51 @@ func TestExtend(t *testing.T) {
 	})
 }
 
+func TestRecord(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		job := testJob{
+			Data: "Hello!",
+		}
+
+		enqueued, err := Enqueue(nil, tester.Store, &job, 0, 0)
+		assert.NoError(t, err)
+		assert.True(t, enqueued)
+
+		err = Record(nil, tester.Store, &job, "foo")
+		assert.Error(t, err)
+		assert.Equal(t, "missing job", err.Error())
+
+		dequeued, attempt, err := Dequeue(nil, tester.Store, &job, time.Hour)
+		assert.NoError(t, err)
+		assert.True(t, dequeued)
+		assert.Equal(t, 1, attempt)
+
+		keys, err := Recorded(nil, tester.Store, &job)
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+
+		err = Record(nil, tester.Store, &job, "")
+		assert.Error(t, err)
+		assert.Equal(t, "missing key", err.Error())
+
+		err = Record(nil, tester.Store, &job, "foo")
+		assert.NoError(t, err)
+
+		err = Record(nil, tester.Store, &job, "bar")
+		assert.NoError(t, err)
+
+		err = Record(nil, tester.Store, &job, "foo")
+		assert.NoError(t, err)
+
+		keys, err = Recorded(nil, tester.Store, &job)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo", "bar"}, keys)
+
+		model := tester.Fetch(&Model{}, job.ID()).(*Model)
+		assert.Equal(t, []string{"foo", "bar"}, model.Effects)
+	})
+}
+
 func TestFail(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *fire.Tester) {
 		job := testJob{
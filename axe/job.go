@@ -24,6 +24,10 @@ type Base struct {
 
 	// The label of the job.
 	Label string
+
+	// The metadata of the job. It is merged with the metadata carried by the
+	// context when the job is enqueued (see WithMetadata).
+	Metadata stick.Map
 }
 
 // B is a shorthand to construct a base with a label.
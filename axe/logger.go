@@ -0,0 +1,61 @@
+package axe
+
+import (
+	"time"
+
+	"github.com/256dpi/fire/coal"
+)
+
+// Entry describes a job lifecycle change.
+type Entry struct {
+	// The time of the change.
+	Time time.Time
+
+	// The new state of the job.
+	State State
+
+	// The job name, label and ID.
+	Name  string
+	Label string
+	ID    coal.ID
+
+	// The attempt of the execution. Zero when enqueued.
+	Attempt int
+
+	// The execution duration when completed, failed or cancelled.
+	Duration time.Duration
+
+	// The reason when failed or cancelled.
+	Reason string
+}
+
+// Logger receives job lifecycle entries. Implementations must be safe for
+// concurrent use.
+type Logger interface {
+	Log(entry Entry)
+}
+
+// LoggerFunc is a function that implements the Logger interface.
+type LoggerFunc func(entry Entry)
+
+// Log implements the Logger interface.
+func (f LoggerFunc) Log(entry Entry) {
+	f(entry)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(Entry) {}
+
+func (q *Queue) log(state State, job Job, attempt int, duration time.Duration, reason string) {
+	q.options.Logger.Log(Entry{
+		Time:     time.Now(),
+		State:    state,
+		Name:     GetMeta(job).Name,
+		Label:    job.GetBase().Label,
+		ID:       job.ID(),
+		Attempt:  attempt,
+		Duration: duration,
+		Reason:   reason,
+	})
+}
@@ -0,0 +1,45 @@
+package axe
+
+import (
+	"context"
+
+	"github.com/256dpi/fire/stick"
+)
+
+type metadataKey struct{}
+
+// WithMetadata will return a context that carries the provided metadata merged
+// with the metadata already carried by the context. The metadata is attached
+// to all jobs enqueued using the context. Handlers are called with a context
+// that carries the metadata of the executed job. Therefore, metadata like the
+// tenant, actor or request ID is propagated to all downstream jobs.
+func WithMetadata(ctx context.Context, metadata stick.Map) context.Context {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// merge metadata
+	merged := stick.Map{}
+	for key, value := range GetMetadata(ctx) {
+		merged[key] = value
+	}
+	for key, value := range metadata {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// GetMetadata will return the metadata carried by the context.
+func GetMetadata(ctx context.Context) stick.Map {
+	// check context
+	if ctx == nil {
+		return nil
+	}
+
+	// get metadata
+	metadata, _ := ctx.Value(metadataKey{}).(stick.Map)
+
+	return metadata
+}
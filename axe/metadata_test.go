@@ -0,0 +1,95 @@
+package axe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/stick"
+)
+
+func TestMetadata(t *testing.T) {
+	assert.Nil(t, GetMetadata(nil))
+	assert.Nil(t, GetMetadata(context.Background()))
+
+	ctx := WithMetadata(nil, stick.Map{"tenant": "t1", "actor": "a1"})
+	assert.Equal(t, stick.Map{"tenant": "t1", "actor": "a1"}, GetMetadata(ctx))
+
+	ctx2 := WithMetadata(ctx, stick.Map{"actor": "a2", "request": "r1"})
+	assert.Equal(t, stick.Map{"tenant": "t1", "actor": "a2", "request": "r1"}, GetMetadata(ctx2))
+	assert.Equal(t, stick.Map{"tenant": "t1", "actor": "a1"}, GetMetadata(ctx))
+}
+
+func TestEnqueueMetadata(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		job := &testJob{
+			Base: Base{
+				Metadata: stick.Map{"actor": "a2"},
+			},
+		}
+
+		ctx := WithMetadata(nil, stick.Map{"tenant": "t1", "actor": "a1"})
+		enqueued, err := Enqueue(ctx, tester.Store, job, 0, 0)
+		assert.NoError(t, err)
+		assert.True(t, enqueued)
+
+		model := tester.Fetch(&Model{}, job.ID()).(*Model)
+		assert.Equal(t, stick.Map{"tenant": "t1", "actor": "a2"}, model.Metadata)
+
+		job2 := &testJob{}
+		enqueued, err = Enqueue(nil, tester.Store, job2, 0, 0)
+		assert.NoError(t, err)
+		assert.True(t, enqueued)
+
+		model = tester.Fetch(&Model{}, job2.ID()).(*Model)
+		assert.Nil(t, model.Metadata)
+
+		dequeued, _, err := Dequeue(nil, tester.Store, job, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, dequeued)
+		assert.Equal(t, stick.Map{"tenant": "t1", "actor": "a2"}, job.Metadata)
+	})
+}
+
+func TestQueueMetadataPropagation(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		done := make(chan stick.Map, 1)
+
+		queue := NewQueue(Options{
+			Store:    tester.Store,
+			Reporter: xo.Crash,
+		})
+
+		queue.Add(&Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				job := ctx.Job.(*testJob)
+				if job.Data == "first" {
+					_, err := ctx.Queue.Enqueue(ctx, &testJob{Data: "second"}, 0, 0)
+					return err
+				}
+				done <- GetMetadata(ctx)
+				return nil
+			},
+		})
+
+		<-queue.Run()
+
+		ctx := WithMetadata(nil, stick.Map{"request": "r1"})
+		enqueued, err := queue.Enqueue(ctx, &testJob{Data: "first"}, 0, 0)
+		assert.NoError(t, err)
+		assert.True(t, enqueued)
+
+		assert.Equal(t, stick.Map{"request": "r1"}, <-done)
+
+		model := tester.FindLast(&Model{}).(*Model)
+		assert.Equal(t, stick.Map{"data": "second"}, model.Data)
+		assert.Equal(t, stick.Map{"request": "r1"}, model.Metadata)
+
+		queue.Close()
+	})
+}
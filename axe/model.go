@@ -63,6 +63,9 @@ type Model struct {
 	// The encoded job data.
 	Data stick.Map `json:"data"`
 
+	// The job metadata e.g. the tenant, actor or request ID.
+	Metadata stick.Map `json:"metadata" bson:",omitempty"`
+
 	// The current state of the job.
 	State State `json:"state"`
 
@@ -92,6 +95,9 @@ type Model struct {
 
 	// The individual job events.
 	Events []Event `json:"events"`
+
+	// The idempotency keys of the recorded side effects.
+	Effects []string `json:"effects" bson:",omitempty"`
 }
 
 // Validate will validate the model.
@@ -104,5 +110,6 @@ func (m *Model) Validate() error {
 		v.Value("Started", true, stick.IsNotZero)
 		v.Value("Ended", true, stick.IsNotZero)
 		v.Value("Finished", true, stick.IsNotZero)
+		v.Items("Effects", stick.IsNotZero, stick.IsValidUTF8)
 	})
 }
@@ -1,12 +1,17 @@
 package axe
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
+	"reflect"
 	"sync"
 	"time"
 
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
 	"gopkg.in/tomb.v2"
 
 	"github.com/256dpi/fire"
@@ -16,7 +21,8 @@ import (
 
 type board struct {
 	sync.Mutex
-	jobs map[coal.ID]*Model
+	jobs  map[coal.ID]*Model
+	locks map[coal.ID]string
 }
 
 // Blueprint describes a queueable job.
@@ -60,6 +66,44 @@ type Options struct {
 
 	// The callback that is called with job errors.
 	Reporter func(error)
+
+	// The logger that receives job lifecycle entries for jobs enqueued using
+	// the queue and jobs executed by the queue.
+	//
+	// Default: no-op.
+	Logger Logger
+
+	// The number of jobs loaded per batch when the boards are primed with the
+	// current backlog on startup.
+	//
+	// Default: 1000.
+	PrimeBatchSize int64
+
+	// The writer that receives progress messages while the boards are primed.
+	PrimeLogger io.Writer
+
+	// The thresholds at which backlog alerts are reported.
+	Alerts Alerts
+}
+
+// ErrBacklogExceeded is reported by the queue if the backlog of a task exceeds
+// the configured alert thresholds.
+var ErrBacklogExceeded = xo.BF("backlog exceeded")
+
+// Alerts defines the thresholds at which the queue reports ErrBacklogExceeded
+// using the reporter. An alert is reported once when the backlog of a task
+// exceeds a threshold and again if it exceeds a threshold after it recovered.
+type Alerts struct {
+	// The maximum number of available jobs.
+	MaxSize int
+
+	// The maximum age of the oldest available job.
+	MaxAge time.Duration
+
+	// The interval at which the backlogs are checked.
+	//
+	// Default: 1m.
+	Interval time.Duration
 }
 
 // Queue manages job queueing.
@@ -82,6 +126,21 @@ func NewQueue(options Options) *Queue {
 		options.BlockPeriod = 10 * time.Second
 	}
 
+	// set default logger
+	if options.Logger == nil {
+		options.Logger = nopLogger{}
+	}
+
+	// set default prime batch size
+	if options.PrimeBatchSize == 0 {
+		options.PrimeBatchSize = 1000
+	}
+
+	// set default alert interval
+	if options.Alerts.Interval == 0 {
+		options.Alerts.Interval = time.Minute
+	}
+
 	return &Queue{
 		options: options,
 		tasks:   make(map[string]*Task),
@@ -113,7 +172,18 @@ func (q *Queue) Add(task *Task) {
 // Enqueue will enqueue a job. If the context carries a transaction it must be
 // associated with the store that is also used by the queue.
 func (q *Queue) Enqueue(ctx context.Context, job Job, delay, isolation time.Duration) (bool, error) {
-	return Enqueue(ctx, q.options.Store, job, delay, isolation)
+	// enqueue job
+	enqueued, err := Enqueue(ctx, q.options.Store, job, delay, isolation)
+	if err != nil {
+		return false, err
+	}
+
+	// log enqueue
+	if enqueued {
+		q.log(Enqueued, job, 0, 0, "")
+	}
+
+	return enqueued, nil
 }
 
 // Callback is a factory to create callbacks that can be used to enqueue jobs
@@ -189,7 +259,8 @@ func (q *Queue) Run() chan struct{} {
 	for _, task := range q.tasks {
 		name := GetMeta(task.Job).Name
 		q.boards[name] = &board{
-			jobs: make(map[coal.ID]*Model),
+			jobs:  make(map[coal.ID]*Model),
+			locks: make(map[coal.ID]string),
 		}
 	}
 
@@ -211,23 +282,62 @@ func (q *Queue) Close() {
 	_ = q.tomb.Wait()
 }
 
+// Component returns a component that runs the queue when the application is
+// started and closes it during the shutdown. Closing the queue will wait for
+// all running jobs to complete.
+func (q *Queue) Component() fire.Component {
+	return fire.Component{
+		Name: "queue",
+		Start: func() error {
+			<-q.Run()
+			return nil
+		},
+		Close: func(context.Context) error {
+			q.Close()
+			return nil
+		},
+	}
+}
+
 func (q *Queue) process(synced chan struct{}) error {
 	// start tasks
 	for _, task := range q.tasks {
 		task.start(q)
 	}
 
-	// reconcile jobs
+	// open stream
 	var once sync.Once
-	stream := coal.Reconcile(q.options.Store, &Model{}, func() {
-		once.Do(func() {
-			close(synced)
+	stream := coal.OpenStream(q.options.Store, &Model{}, nil, func(event coal.Event, _ coal.ID, model coal.Model, err error, _ []byte) error {
+		switch event {
+		case coal.Opened:
+			// prime boards
+			err = q.prime()
+			if err != nil {
+				return err
+			}
+
+			// signal sync
+			once.Do(func() {
+				close(synced)
+			})
+		case coal.Created, coal.Updated:
+			q.update(model.(*Model))
+		case coal.Errored:
+			if q.options.Reporter != nil {
+				q.options.Reporter(err)
+			}
+		}
+
+		return nil
+	})
+
+	// check backlogs if alerts are configured
+	alerts := q.options.Alerts
+	if q.options.Reporter != nil && (alerts.MaxSize > 0 || alerts.MaxAge > 0) {
+		q.tomb.Go(func() error {
+			return q.monitor(synced)
 		})
-	}, func(model coal.Model) {
-		q.update(model.(*Model))
-	}, func(model coal.Model) {
-		q.update(model.(*Model))
-	}, nil, q.options.Reporter)
+	}
 
 	// await close
 	<-q.tomb.Dying()
@@ -238,6 +348,94 @@ func (q *Queue) process(synced chan struct{}) error {
 	return tomb.ErrDying
 }
 
+func (q *Queue) prime() error {
+	// collect names
+	names := make([]string, 0, len(q.tasks))
+	for name := range q.tasks {
+		names = append(names, name)
+	}
+
+	// log start
+	start := time.Now()
+	if q.options.PrimeLogger != nil {
+		_, _ = fmt.Fprintf(q.options.PrimeLogger, "priming queue: %d tasks\n", len(names))
+	}
+
+	// load backlog in batches
+	var total int
+	err := q.options.Store.Iterate(context.Background(), &Model{}, bson.M{
+		"Name": bson.M{
+			"$in": names,
+		},
+		"State": bson.M{
+			"$in": []State{Enqueued, Dequeued, Failed},
+		},
+	}, q.options.PrimeBatchSize, func(batch []coal.Model) error {
+		// update boards
+		for _, model := range batch {
+			q.update(model.(*Model))
+		}
+
+		// log progress
+		total += len(batch)
+		if q.options.PrimeLogger != nil {
+			_, _ = fmt.Fprintf(q.options.PrimeLogger, "primed queue: %d jobs\n", total)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// log end
+	if q.options.PrimeLogger != nil {
+		_, _ = fmt.Fprintf(q.options.PrimeLogger, "primed queue: %d jobs in %s\n", total, time.Since(start).Round(time.Millisecond))
+	}
+
+	return nil
+}
+
+func (q *Queue) monitor(synced chan struct{}) error {
+	// await sync
+	select {
+	case <-synced:
+	case <-q.tomb.Dying():
+		return tomb.ErrDying
+	}
+
+	// prepare ticker
+	ticker := time.NewTicker(q.options.Alerts.Interval)
+	defer ticker.Stop()
+
+	// check backlogs
+	alerted := map[string]bool{}
+	for {
+		select {
+		case <-ticker.C:
+			for name := range q.tasks {
+				// get backlog
+				backlog := q.Backlog(name)
+				size := backlog.Fresh + backlog.Retried
+				age := backlog.FreshAge
+				if backlog.RetriedAge > age {
+					age = backlog.RetriedAge
+				}
+
+				// check thresholds
+				alerts := q.options.Alerts
+				exceeded := (alerts.MaxSize > 0 && size > alerts.MaxSize) || (alerts.MaxAge > 0 && age > alerts.MaxAge)
+				if exceeded && !alerted[name] {
+					q.options.Reporter(ErrBacklogExceeded.WrapF("task %q has %d available jobs with the oldest available for %s", name, size, age.Round(time.Second)))
+				}
+				alerted[name] = exceeded
+			}
+		case <-q.tomb.Dying():
+			return tomb.ErrDying
+		}
+	}
+}
+
 func (q *Queue) update(job *Model) {
 	// get board
 	board, ok := q.boards[job.Name]
@@ -264,7 +462,61 @@ func (q *Queue) update(job *Model) {
 	}
 }
 
-func (q *Queue) get(name string) (coal.ID, bool) {
+// Backlog describes the available jobs of a task.
+type Backlog struct {
+	// The number of available fresh jobs and the age of the oldest.
+	Fresh    int
+	FreshAge time.Duration
+
+	// The number of available retried jobs and the age of the oldest.
+	Retried    int
+	RetriedAge time.Duration
+}
+
+// Backlog will return the backlog of available jobs for the specified task. The
+// age of a job is measured from the time it became available. The queue must
+// be running.
+func (q *Queue) Backlog(name string) Backlog {
+	// get board
+	board, ok := q.boards[name]
+	if !ok {
+		return Backlog{}
+	}
+
+	// lock board
+	board.Lock()
+	defer board.Unlock()
+
+	// get time
+	now := time.Now()
+
+	// collect available jobs
+	var backlog Backlog
+	for _, job := range board.jobs {
+		// skip unavailable jobs
+		if !job.Available.Before(now) {
+			continue
+		}
+
+		// count job
+		age := now.Sub(job.Available)
+		if job.Attempts > 0 {
+			backlog.Retried++
+			if age > backlog.RetriedAge {
+				backlog.RetriedAge = age
+			}
+		} else {
+			backlog.Fresh++
+			if age > backlog.FreshAge {
+				backlog.FreshAge = age
+			}
+		}
+	}
+
+	return backlog
+}
+
+func (q *Queue) get(name string, priority RetryPriority, key string) (coal.ID, bool) {
 	// get board
 	board := q.boards[name]
 
@@ -275,15 +527,125 @@ func (q *Queue) get(name string) (coal.ID, bool) {
 	// get time
 	now := time.Now()
 
-	// return first available job
+	// collect held keys
+	var held map[string]bool
+	if key != "" {
+		held = make(map[string]bool, len(board.locks))
+		for _, value := range board.locks {
+			held[value] = true
+		}
+		for _, job := range board.jobs {
+			if job.State == Dequeued && job.Available.After(now) {
+				if value := jobKey(job, key); value != "" {
+					held[value] = true
+				}
+			}
+		}
+	}
+
+	// find first available fresh and retried job
+	var fresh, retried *Model
 	for _, job := range board.jobs {
-		if job.Available.Before(now) {
-			// block job until the specified timeout has been reached
-			job.Available = job.Available.Add(q.options.BlockPeriod)
+		// skip unavailable jobs
+		if !job.Available.Before(now) {
+			continue
+		}
+
+		// handle serialized jobs
+		if key != "" {
+			// skip held jobs
+			if held[jobKey(job, key)] {
+				continue
+			}
 
-			return job.ID(), true
+			// select oldest job
+			if job.Attempts > 0 {
+				if retried == nil || earlier(job, retried) {
+					retried = job
+				}
+			} else if fresh == nil || earlier(job, fresh) {
+				fresh = job
+			}
+
+			continue
 		}
+
+		// select job
+		if job.Attempts > 0 {
+			if retried == nil {
+				retried = job
+			}
+		} else if fresh == nil {
+			fresh = job
+		}
+
+		// stop if priority is satisfied
+		if priority == RetryMixed || (priority == RetryFirst && retried != nil) || (priority == RetryLast && fresh != nil) {
+			break
+		}
+	}
+
+	// pick job
+	job := fresh
+	if retried != nil && (fresh == nil || priority == RetryFirst) {
+		job = retried
+	}
+	if job == nil {
+		return coal.ID{}, false
+	}
+
+	// block job until the specified timeout has been reached
+	job.Available = job.Available.Add(q.options.BlockPeriod)
+
+	// lock key until the job has been executed
+	if value := jobKey(job, key); value != "" {
+		if board.locks == nil {
+			board.locks = make(map[coal.ID]string)
+		}
+		board.locks[job.ID()] = value
+	}
+
+	return job.ID(), true
+}
+
+func (q *Queue) release(name string, id coal.ID) {
+	// get board
+	board := q.boards[name]
+
+	// lock board
+	board.Lock()
+	defer board.Unlock()
+
+	// release key
+	delete(board.locks, id)
+}
+
+func earlier(a, b *Model) bool {
+	// compare availability and IDs for jobs that became available at the
+	// same (stored) time
+	if !a.Available.Equal(b.Available) {
+		return a.Available.Before(b.Available)
+	}
+
+	return bytes.Compare(a.DocID[:], b.DocID[:]) < 0
+}
+
+func jobKey(job *Model, key string) string {
+	// check key
+	if key == "" {
+		return ""
+	}
+
+	// get value
+	value, ok := job.Data[key]
+	if !ok || value == nil {
+		return ""
+	}
+
+	// check zero value
+	if reflect.ValueOf(value).IsZero() {
+		return ""
 	}
 
-	return coal.ID{}, false
+	return fmt.Sprint(value)
 }
@@ -1,7 +1,11 @@
 package axe
 
 import (
+	"bytes"
+	"context"
 	"io"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/coal"
 	"github.com/256dpi/fire/stick"
 )
 
@@ -228,6 +233,143 @@ func TestQueueFailed(t *testing.T) {
 	})
 }
 
+func TestQueueLogger(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		done := make(chan struct{})
+
+		var mutex sync.Mutex
+		var entries []Entry
+
+		queue := NewQueue(Options{
+			Store:    tester.Store,
+			Reporter: xo.Crash,
+			Logger: LoggerFunc(func(entry Entry) {
+				mutex.Lock()
+				entries = append(entries, entry)
+				mutex.Unlock()
+			}),
+		})
+
+		queue.Add(&Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				if ctx.Attempt == 1 {
+					return E("some error", true)
+				}
+
+				return nil
+			},
+			Notifier: func(ctx *Context, cancelled bool, reason string) error {
+				close(done)
+				return nil
+			},
+			MinDelay: 10 * time.Millisecond,
+		})
+
+		<-queue.Run()
+
+		job := testJob{
+			Data: "Hello!",
+		}
+
+		enqueued, err := queue.Enqueue(nil, &job, 0, 0)
+		assert.NoError(t, err)
+		assert.True(t, enqueued)
+
+		<-done
+
+		queue.Close()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		assert.Len(t, entries, 5)
+		for _, entry := range entries {
+			assert.NotZero(t, entry.Time)
+			assert.Equal(t, "test", entry.Name)
+			assert.Equal(t, job.ID(), entry.ID)
+		}
+
+		var states []State
+		var attempts []int
+		for _, entry := range entries {
+			states = append(states, entry.State)
+			attempts = append(attempts, entry.Attempt)
+		}
+		assert.Equal(t, []State{Enqueued, Dequeued, Failed, Dequeued, Completed}, states)
+		assert.Equal(t, []int{0, 1, 1, 2, 2}, attempts)
+		assert.Equal(t, "some error", entries[2].Reason)
+		assert.NotZero(t, entries[2].Duration)
+		assert.Zero(t, entries[3].Duration)
+		assert.NotZero(t, entries[4].Duration)
+	})
+}
+
+func TestQueueOnce(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		done := make(chan struct{})
+
+		var effects []int
+		queue := NewQueue(Options{
+			Store:    tester.Store,
+			Reporter: xo.Crash,
+		})
+
+		queue.Add(&Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				err := ctx.Once("effect", func() error {
+					effects = append(effects, ctx.Attempt)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				err = ctx.Once("effect", func() error {
+					effects = append(effects, ctx.Attempt)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				if ctx.Attempt == 1 {
+					return E("some error", true)
+				}
+
+				return nil
+			},
+			Notifier: func(ctx *Context, cancelled bool, reason string) error {
+				close(done)
+				return nil
+			},
+			MinDelay: 10 * time.Millisecond,
+		})
+
+		<-queue.Run()
+
+		job := testJob{
+			Data: "Hello!",
+		}
+
+		enqueued, err := queue.Enqueue(nil, &job, 0, 0)
+		assert.NoError(t, err)
+		assert.True(t, enqueued)
+
+		<-done
+
+		assert.Equal(t, []int{1}, effects)
+
+		model := tester.Fetch(&Model{}, job.ID()).(*Model)
+		assert.Equal(t, Completed, model.State)
+		assert.Equal(t, 2, model.Attempts)
+		assert.Equal(t, []string{"effect"}, model.Effects)
+
+		queue.Close()
+	})
+}
+
 func TestQueueCrashed(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *fire.Tester) {
 		done := make(chan struct{})
@@ -905,3 +1047,469 @@ func TestQueuePeriodically(t *testing.T) {
 		queue.Close()
 	})
 }
+
+func TestQueuePeriodicMissed(t *testing.T) {
+	hour := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	task := &Task{
+		Periodicity: time.Hour,
+	}
+
+	// first run
+	assert.Equal(t, []time.Time{hour}, task.schedule(time.Time{}, hour))
+
+	// on time
+	assert.Equal(t, []time.Time{hour}, task.schedule(hour.Add(-time.Hour), hour))
+
+	// already handled
+	assert.Empty(t, task.schedule(hour, hour))
+
+	// run once
+	assert.Equal(t, []time.Time{hour}, task.schedule(hour.Add(-3*time.Hour), hour))
+
+	// skip
+	task.PeriodicMissed = MissedSkip
+	assert.Empty(t, task.schedule(time.Time{}, hour))
+	assert.Empty(t, task.schedule(hour.Add(-3*time.Hour), hour))
+	assert.Equal(t, []time.Time{hour}, task.schedule(hour.Add(-time.Hour), hour))
+
+	// catch up
+	task.PeriodicMissed = MissedCatchUp
+	task.PeriodicCatchUp = 5
+	assert.Equal(t, []time.Time{
+		hour.Add(-4 * time.Hour),
+		hour.Add(-3 * time.Hour),
+		hour.Add(-2 * time.Hour),
+		hour.Add(-time.Hour),
+		hour,
+	}, task.schedule(time.Time{}, hour))
+	assert.Equal(t, []time.Time{
+		hour.Add(-time.Hour),
+		hour,
+	}, task.schedule(hour.Add(-2*time.Hour), hour))
+}
+
+func TestQueuePeriodicExclusive(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		queue := NewQueue(Options{
+			Store:    tester.Store,
+			Reporter: xo.Crash,
+		})
+
+		task := &Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				return nil
+			},
+			Periodicity: time.Hour,
+			PeriodicJob: Blueprint{
+				Job: &testJob{
+					Data: "Hello!",
+				},
+			},
+		}
+		task.prepare()
+
+		hour := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+
+		// same run is only enqueued once
+		err := task.enqueue(queue, []time.Time{hour})
+		assert.NoError(t, err)
+		err = task.enqueue(queue, []time.Time{hour})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, tester.Count(&Model{}))
+
+		// different runs are enqueued
+		err = task.enqueue(queue, []time.Time{hour.Add(time.Hour)})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, tester.Count(&Model{}))
+
+		// exclusive runs are not enqueued while pending
+		task.PeriodicExclusive = true
+		err = task.enqueue(queue, []time.Time{hour.Add(2 * time.Hour)})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, tester.Count(&Model{}))
+
+		// exclusive runs are enqueued without pending jobs
+		tester.DeleteAll(&Model{})
+		err = task.enqueue(queue, []time.Time{hour.Add(2 * time.Hour)})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, tester.Count(&Model{}))
+	})
+}
+
+func TestQueuePeriodicTemplate(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		queue := NewQueue(Options{
+			Store:    tester.Store,
+			Reporter: xo.Crash,
+		})
+
+		task := &Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				return nil
+			},
+			Periodicity: 24 * time.Hour,
+			PeriodicTemplate: func(run time.Time) (Blueprint, error) {
+				if run.Year() == 2000 {
+					return Blueprint{}, xo.F("invalid run")
+				}
+				return Blueprint{
+					Job: &testJob{
+						Data: run.Add(-24 * time.Hour).Format("2006-01-02"),
+					},
+				}, nil
+			},
+		}
+		task.prepare()
+
+		day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+		err := task.enqueue(queue, []time.Time{day, day.Add(24 * time.Hour)})
+		assert.NoError(t, err)
+
+		models := *tester.FindAll(&Model{}).(*[]*Model)
+		assert.Len(t, models, 2)
+		assert.Equal(t, stick.Map{"data": "2020-01-01"}, models[0].Data)
+		assert.Equal(t, stick.Map{"data": "2020-01-02"}, models[1].Data)
+
+		err = task.enqueue(queue, []time.Time{time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)})
+		assert.Error(t, err)
+		assert.Equal(t, 2, tester.Count(&Model{}))
+	})
+
+	assert.PanicsWithValue(t, "axe: periodic job and template are exclusive", func() {
+		task := &Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				return nil
+			},
+			Periodicity: time.Hour,
+			PeriodicJob: Blueprint{
+				Job: &testJob{},
+			},
+			PeriodicTemplate: func(time.Time) (Blueprint, error) {
+				return Blueprint{}, nil
+			},
+		}
+		task.prepare()
+	})
+}
+
+func TestQueueRetryPriority(t *testing.T) {
+	queue := NewQueue(Options{
+		BlockPeriod: time.Hour,
+	})
+
+	now := time.Now()
+	fresh := &Model{Base: coal.B(), Available: now.Add(-time.Minute)}
+	retried := &Model{Base: coal.B(), Available: now.Add(-2 * time.Minute), Attempts: 1}
+	pending := &Model{Base: coal.B(), Available: now.Add(time.Minute)}
+
+	reset := func() {
+		fresh.Available = now.Add(-time.Minute)
+		retried.Available = now.Add(-2 * time.Minute)
+		queue.boards = map[string]*board{
+			"test": {
+				jobs: map[coal.ID]*Model{
+					fresh.ID():   fresh,
+					retried.ID(): retried,
+					pending.ID(): pending,
+				},
+			},
+		}
+	}
+
+	reset()
+	backlog := queue.Backlog("test")
+	assert.Equal(t, 1, backlog.Fresh)
+	assert.Equal(t, 1, backlog.Retried)
+	assert.True(t, backlog.FreshAge >= time.Minute && backlog.FreshAge < 2*time.Minute)
+	assert.True(t, backlog.RetriedAge >= 2*time.Minute)
+	assert.Equal(t, Backlog{}, queue.Backlog("foo"))
+
+	reset()
+	id, ok := queue.get("test", RetryFirst, "")
+	assert.True(t, ok)
+	assert.Equal(t, retried.ID(), id)
+	id, ok = queue.get("test", RetryFirst, "")
+	assert.True(t, ok)
+	assert.Equal(t, fresh.ID(), id)
+	_, ok = queue.get("test", RetryFirst, "")
+	assert.False(t, ok)
+
+	reset()
+	id, ok = queue.get("test", RetryLast, "")
+	assert.True(t, ok)
+	assert.Equal(t, fresh.ID(), id)
+	id, ok = queue.get("test", RetryLast, "")
+	assert.True(t, ok)
+	assert.Equal(t, retried.ID(), id)
+	_, ok = queue.get("test", RetryLast, "")
+	assert.False(t, ok)
+
+	reset()
+	var ids []coal.ID
+	for i := 0; i < 3; i++ {
+		id, ok := queue.get("test", RetryMixed, "")
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	assert.ElementsMatch(t, []coal.ID{fresh.ID(), retried.ID()}, ids)
+}
+
+func TestQueueConcurrencyKey(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		queue := NewQueue(Options{
+			Store:    tester.Store,
+			Reporter: xo.Crash,
+			MaxLag:   time.Nanosecond,
+		})
+
+		assert.PanicsWithValue(t, `axe: invalid concurrency key "Foo"`, func() {
+			queue.Add(&Task{
+				Job:            &accountJob{},
+				Handler:        func(ctx *Context) error { return nil },
+				ConcurrencyKey: "Foo",
+			})
+		})
+
+		var mutex sync.Mutex
+		var wg sync.WaitGroup
+		running := map[string]int{}
+		var total, maxTotal, maxKey int
+		steps := map[string][]int{}
+
+		queue.Add(&Task{
+			Job:            &accountJob{},
+			Workers:        4,
+			Interval:       10 * time.Millisecond,
+			ConcurrencyKey: "Account",
+			Handler: func(ctx *Context) error {
+				job := ctx.Job.(*accountJob)
+
+				mutex.Lock()
+				running[job.Account]++
+				total++
+				if running[job.Account] > maxKey {
+					maxKey = running[job.Account]
+				}
+				if total > maxTotal {
+					maxTotal = total
+				}
+				steps[job.Account] = append(steps[job.Account], job.Step)
+				mutex.Unlock()
+
+				time.Sleep(100 * time.Millisecond)
+
+				mutex.Lock()
+				running[job.Account]--
+				total--
+				mutex.Unlock()
+
+				return nil
+			},
+			Notifier: func(ctx *Context, cancelled bool, reason string) error {
+				wg.Done()
+				return nil
+			},
+		})
+
+		<-queue.Run()
+
+		for i := 0; i < 3; i++ {
+			for _, account := range []string{"a", "b"} {
+				wg.Add(1)
+				enqueued, err := queue.Enqueue(nil, &accountJob{Account: account, Step: i}, 0, 0)
+				assert.NoError(t, err)
+				assert.True(t, enqueued)
+			}
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, 1, maxKey)
+		assert.Equal(t, 2, maxTotal)
+		assert.Equal(t, map[string][]int{
+			"a": {0, 1, 2},
+			"b": {0, 1, 2},
+		}, steps)
+
+		queue.Close()
+	})
+}
+
+func TestQueueConcurrencyKeyBoard(t *testing.T) {
+	queue := NewQueue(Options{
+		BlockPeriod: time.Hour,
+	})
+
+	now := time.Now()
+	a1 := &Model{Base: coal.B(), Data: stick.Map{"account": "a"}, Available: now.Add(-3 * time.Minute)}
+	a2 := &Model{Base: coal.B(), Data: stick.Map{"account": "a"}, Available: now.Add(-2 * time.Minute)}
+	b1 := &Model{Base: coal.B(), Data: stick.Map{"account": "b"}, State: Dequeued, Available: now.Add(time.Minute)}
+	b2 := &Model{Base: coal.B(), Data: stick.Map{"account": "b"}, Available: now.Add(-time.Minute)}
+	c1 := &Model{Base: coal.B(), Data: stick.Map{"account": ""}, Available: now.Add(-time.Minute)}
+
+	queue.boards = map[string]*board{
+		"test": {
+			jobs: map[coal.ID]*Model{
+				a1.ID(): a1,
+				a2.ID(): a2,
+				b1.ID(): b1,
+				b2.ID(): b2,
+				c1.ID(): c1,
+			},
+		},
+	}
+
+	id, ok := queue.get("test", RetryMixed, "account")
+	assert.True(t, ok)
+	assert.Equal(t, a1.ID(), id)
+
+	id, ok = queue.get("test", RetryMixed, "account")
+	assert.True(t, ok)
+	assert.Equal(t, c1.ID(), id)
+
+	_, ok = queue.get("test", RetryMixed, "account")
+	assert.False(t, ok)
+
+	queue.release("test", a1.ID())
+
+	id, ok = queue.get("test", RetryMixed, "account")
+	assert.True(t, ok)
+	assert.Equal(t, a2.ID(), id)
+}
+
+func TestQueueComponent(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		started := make(chan struct{})
+
+		queue := NewQueue(Options{
+			Store:    tester.Store,
+			Reporter: xo.Crash,
+		})
+
+		queue.Add(&Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				close(started)
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			},
+		})
+
+		<-queue.Run()
+
+		job := testJob{
+			Data: "Hello!",
+		}
+
+		enqueued, err := queue.Enqueue(nil, &job, 0, 0)
+		assert.NoError(t, err)
+		assert.True(t, enqueued)
+
+		<-started
+
+		app := &fire.App{
+			Components: []fire.Component{queue.Component()},
+		}
+
+		err = app.Shutdown(context.Background())
+		assert.NoError(t, err)
+
+		model := tester.Fetch(&Model{}, job.ID()).(*Model)
+		assert.Equal(t, Completed, model.State)
+	})
+}
+
+func TestQueuePrime(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		for i := 0; i < 5; i++ {
+			_, err := Enqueue(nil, tester.Store, &testJob{}, time.Hour, 0)
+			assert.NoError(t, err)
+		}
+
+		job := &testJob{}
+		_, err := Enqueue(nil, tester.Store, job, 0, 0)
+		assert.NoError(t, err)
+		_, _, err = Dequeue(nil, tester.Store, job, time.Hour)
+		assert.NoError(t, err)
+		err = Complete(nil, tester.Store, job)
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		queue := NewQueue(Options{
+			Store:          tester.Store,
+			Reporter:       xo.Crash,
+			PrimeBatchSize: 2,
+			PrimeLogger:    &buf,
+		})
+
+		queue.Add(&Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				return nil
+			},
+		})
+
+		<-queue.Run()
+
+		assert.Len(t, queue.boards["test"].jobs, 5)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 5)
+		assert.Equal(t, []string{
+			"priming queue: 1 tasks",
+			"primed queue: 2 jobs",
+			"primed queue: 4 jobs",
+			"primed queue: 5 jobs",
+		}, lines[:4])
+		assert.True(t, strings.HasPrefix(lines[4], "primed queue: 5 jobs in "))
+
+		queue.Close()
+	})
+}
+
+func TestQueueAlerts(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		for i := 0; i < 3; i++ {
+			_, err := Enqueue(nil, tester.Store, &testJob{}, 0, 0)
+			assert.NoError(t, err)
+		}
+
+		errs := make(chan error, 10)
+		queue := NewQueue(Options{
+			Store: tester.Store,
+			Reporter: func(err error) {
+				errs <- err
+			},
+			Alerts: Alerts{
+				MaxSize:  1,
+				Interval: 10 * time.Millisecond,
+			},
+		})
+
+		block := make(chan struct{})
+		queue.Add(&Task{
+			Job: &testJob{},
+			Handler: func(ctx *Context) error {
+				<-block
+				return nil
+			},
+			Workers: 1,
+		})
+
+		<-queue.Run()
+
+		err := <-errs
+		assert.True(t, ErrBacklogExceeded.Is(err))
+		assert.Contains(t, err.Error(), `task "test" has`)
+
+		close(block)
+
+		queue.Close()
+	})
+}
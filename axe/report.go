@@ -0,0 +1,320 @@
+package axe
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/coal"
+)
+
+// Report summarizes the executions of a task during a time window.
+type Report struct {
+	// The task name.
+	Name string `json:"name"`
+
+	// The time window.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// The number of executions that ended in the window by their state.
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+
+	// The number of completed executions per second.
+	Throughput float64 `json:"throughput"`
+
+	// The share of completed executions.
+	SuccessRate float64 `json:"success-rate"`
+
+	// The 95th percentile of the execution duration of all executions.
+	P95Duration time.Duration `json:"p95-duration"`
+
+	// The 95th percentile of the time from creation to completion of all
+	// completed jobs, including delays and retries.
+	P95Latency time.Duration `json:"p95-latency"`
+}
+
+// ReportOptions defines options for generating reports.
+type ReportOptions struct {
+	// The collection to read jobs from e.g. an archive collection that stores
+	// jobs in the same format.
+	//
+	// Default: "jobs".
+	Collection string
+
+	// The task names to report on.
+	//
+	// Default: all tasks.
+	Names []string
+
+	// The length of the time windows.
+	//
+	// Default: 1h.
+	Window time.Duration
+
+	// The end of the last time window.
+	//
+	// Default: now.
+	Until time.Time
+
+	// The start of the first time window.
+	//
+	// Default: 24 windows before Until.
+	Since time.Time
+}
+
+// ErrInvalidTimeRange is returned by GenerateReports if the since time is not
+// before the until time.
+var ErrInvalidTimeRange = xo.BF("invalid time range")
+
+// GenerateReports will generate reports for all tasks and time windows using
+// the job events. Windows without executions are omitted. The reports are
+// sorted by window start and task name.
+//
+// Note: Finished jobs are removed from the job collection after a minute by
+// default. Longer periods require an archive collection.
+func GenerateReports(ctx context.Context, store *coal.Store, opts ReportOptions) ([]Report, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "axe/GenerateReports")
+	defer span.End()
+
+	// set default collection
+	if opts.Collection == "" {
+		opts.Collection = coal.GetMeta(&Model{}).Collection
+	}
+
+	// set default window
+	if opts.Window <= 0 {
+		opts.Window = time.Hour
+	}
+
+	// set default until
+	if opts.Until.IsZero() {
+		opts.Until = time.Now()
+	}
+
+	// set default since
+	if opts.Since.IsZero() {
+		opts.Since = opts.Until.Add(-24 * opts.Window)
+	}
+
+	// check range
+	if !opts.Since.Before(opts.Until) {
+		return nil, ErrInvalidTimeRange.Wrap()
+	}
+
+	// prepare filter, jobs that have been dequeued again are not ended but may
+	// have events in the range
+	filter := bson.M{
+		"Events.Timestamp": bson.M{
+			"$gte": opts.Since,
+		},
+	}
+	if len(opts.Names) > 0 {
+		filter["Name"] = bson.M{
+			"$in": opts.Names,
+		}
+	}
+
+	// translate filter
+	filterDoc, err := coal.NewTranslator(&Model{}).Document(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// find jobs
+	csr, err := store.DB().Collection(opts.Collection).Find(ctx, filterDoc)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// prepare samples
+	type key struct {
+		name  string
+		index int
+	}
+	type sample struct {
+		report    Report
+		durations []time.Duration
+		latencies []time.Duration
+	}
+	samples := map[key]*sample{}
+
+	// collect samples
+	defer csr.Close(ctx)
+	for csr.Next(ctx) {
+		// decode job
+		var job Model
+		err = csr.Decode(&job)
+		if err != nil {
+			return nil, xo.W(err)
+		}
+
+		// process events
+		var started time.Time
+		for _, event := range job.Events {
+			// track executions
+			if event.State == Dequeued {
+				started = event.Timestamp
+				continue
+			} else if event.State == Enqueued {
+				continue
+			}
+
+			// check window
+			if event.Timestamp.Before(opts.Since) || !event.Timestamp.Before(opts.Until) {
+				started = time.Time{}
+				continue
+			}
+
+			// get sample
+			index := int(event.Timestamp.Sub(opts.Since) / opts.Window)
+			s := samples[key{name: job.Name, index: index}]
+			if s == nil {
+				start := opts.Since.Add(time.Duration(index) * opts.Window)
+				s = &sample{
+					report: Report{
+						Name:  job.Name,
+						Start: start,
+						End:   start.Add(opts.Window),
+					},
+				}
+				samples[key{name: job.Name, index: index}] = s
+			}
+
+			// count execution
+			switch event.State {
+			case Completed:
+				s.report.Completed++
+				s.latencies = append(s.latencies, event.Timestamp.Sub(job.Created))
+			case Failed:
+				s.report.Failed++
+			case Cancelled:
+				s.report.Cancelled++
+			}
+
+			// add duration
+			if !started.IsZero() {
+				s.durations = append(s.durations, event.Timestamp.Sub(started))
+				started = time.Time{}
+			}
+		}
+	}
+
+	// check error
+	err = csr.Err()
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// finalize reports
+	reports := make([]Report, 0, len(samples))
+	for _, s := range samples {
+		total := s.report.Completed + s.report.Failed + s.report.Cancelled
+		s.report.Throughput = float64(s.report.Completed) / opts.Window.Seconds()
+		s.report.SuccessRate = float64(s.report.Completed) / float64(total)
+		s.report.P95Duration = percentile(s.durations, 0.95)
+		s.report.P95Latency = percentile(s.latencies, 0.95)
+		reports = append(reports, s.report)
+	}
+
+	// sort reports
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].Start.Equal(reports[j].Start) {
+			return reports[i].Start.Before(reports[j].Start)
+		}
+		return reports[i].Name < reports[j].Name
+	})
+
+	return reports, nil
+}
+
+// ReportHandler returns a handler that responds with a JSON array of reports
+// generated using the provided options. The "name", "window", "since" and
+// "until" query parameters may be used to override the options. Times are
+// expected in RFC 3339 format.
+func ReportHandler(store *coal.Store, opts ReportOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// copy options
+		o := opts
+		query := r.URL.Query()
+
+		// parse names
+		if names := query["name"]; len(names) > 0 {
+			o.Names = names
+		}
+
+		// parse window
+		if value := query.Get("window"); value != "" {
+			window, err := time.ParseDuration(value)
+			if err != nil {
+				http.Error(w, "invalid window", http.StatusBadRequest)
+				return
+			}
+			o.Window = window
+		}
+
+		// parse since
+		if value := query.Get("since"); value != "" {
+			since, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "invalid since", http.StatusBadRequest)
+				return
+			}
+			o.Since = since
+		}
+
+		// parse until
+		if value := query.Get("until"); value != "" {
+			until, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "invalid until", http.StatusBadRequest)
+				return
+			}
+			o.Until = until
+		}
+
+		// generate reports
+		reports, err := GenerateReports(r.Context(), store, o)
+		if ErrInvalidTimeRange.Is(err) {
+			http.Error(w, "invalid time range", http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// write response
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(reports)
+	})
+}
+
+func percentile(list []time.Duration, p float64) time.Duration {
+	// check list
+	if len(list) == 0 {
+		return 0
+	}
+
+	// sort list
+	sort.Slice(list, func(i, j int) bool {
+		return list[i] < list[j]
+	})
+
+	// get index
+	index := int(math.Ceil(p*float64(len(list)))) - 1
+	if index < 0 {
+		index = 0
+	}
+
+	return list[index]
+}
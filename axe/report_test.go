@@ -0,0 +1,171 @@
+package axe
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/stick"
+)
+
+func TestGenerateReports(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		base := time.Now().Truncate(time.Hour).Add(-2 * time.Hour)
+		at := func(min int) time.Time {
+			return base.Add(time.Duration(min) * time.Minute)
+		}
+
+		// completed on first attempt
+		tester.Insert(&Model{
+			Name:      "foo",
+			State:     Completed,
+			Created:   at(0),
+			Available: at(0),
+			Ended:     stick.P(at(2)),
+			Finished:  stick.P(at(2)),
+			Events: []Event{
+				{Timestamp: at(0), State: Enqueued},
+				{Timestamp: at(1), State: Dequeued},
+				{Timestamp: at(2), State: Completed},
+			},
+		})
+
+		// completed on second attempt
+		tester.Insert(&Model{
+			Name:      "foo",
+			State:     Completed,
+			Created:   at(0),
+			Available: at(10),
+			Ended:     stick.P(at(20)),
+			Finished:  stick.P(at(20)),
+			Events: []Event{
+				{Timestamp: at(0), State: Enqueued},
+				{Timestamp: at(5), State: Dequeued},
+				{Timestamp: at(6), State: Failed},
+				{Timestamp: at(15), State: Dequeued},
+				{Timestamp: at(20), State: Completed},
+			},
+		})
+
+		// cancelled in second window
+		tester.Insert(&Model{
+			Name:      "bar",
+			State:     Cancelled,
+			Created:   at(60),
+			Available: at(60),
+			Ended:     stick.P(at(63)),
+			Finished:  stick.P(at(63)),
+			Events: []Event{
+				{Timestamp: at(60), State: Enqueued},
+				{Timestamp: at(62), State: Dequeued},
+				{Timestamp: at(63), State: Cancelled},
+			},
+		})
+
+		// failed and dequeued again in second window
+		tester.Insert(&Model{
+			Name:      "bar",
+			State:     Dequeued,
+			Created:   at(70),
+			Available: at(75),
+			Events: []Event{
+				{Timestamp: at(70), State: Enqueued},
+				{Timestamp: at(71), State: Dequeued},
+				{Timestamp: at(72), State: Failed},
+				{Timestamp: at(80), State: Dequeued},
+			},
+		})
+
+		// ended before range
+		tester.Insert(&Model{
+			Name:      "foo",
+			State:     Completed,
+			Created:   at(-90),
+			Available: at(-90),
+			Ended:     stick.P(at(-80)),
+			Finished:  stick.P(at(-80)),
+			Events: []Event{
+				{Timestamp: at(-90), State: Enqueued},
+				{Timestamp: at(-85), State: Dequeued},
+				{Timestamp: at(-80), State: Completed},
+			},
+		})
+
+		reports, err := GenerateReports(nil, tester.Store, ReportOptions{
+			Window: time.Hour,
+			Since:  base,
+			Until:  base.Add(2 * time.Hour),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []Report{
+			{
+				Name:        "foo",
+				Start:       base,
+				End:         base.Add(time.Hour),
+				Completed:   2,
+				Failed:      1,
+				Throughput:  2.0 / 3600,
+				SuccessRate: 2.0 / 3,
+				P95Duration: 5 * time.Minute,
+				P95Latency:  20 * time.Minute,
+			},
+			{
+				Name:        "bar",
+				Start:       base.Add(time.Hour),
+				End:         base.Add(2 * time.Hour),
+				Failed:      1,
+				Cancelled:   1,
+				P95Duration: time.Minute,
+			},
+		}, reports)
+
+		reports, err = GenerateReports(nil, tester.Store, ReportOptions{
+			Names:  []string{"bar"},
+			Window: 2 * time.Hour,
+			Since:  base,
+			Until:  base.Add(2 * time.Hour),
+		})
+		assert.NoError(t, err)
+		assert.Len(t, reports, 1)
+		assert.Equal(t, "bar", reports[0].Name)
+
+		_, err = GenerateReports(nil, tester.Store, ReportOptions{
+			Since: base,
+			Until: base,
+		})
+		assert.Error(t, err)
+		assert.True(t, ErrInvalidTimeRange.Is(err))
+
+		handler := ReportHandler(tester.Store, ReportOptions{
+			Window: time.Hour,
+		})
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/?name=foo&since="+base.Format(time.RFC3339)+"&until="+base.Add(2*time.Hour).Format(time.RFC3339), nil)
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var list []Report
+		err = json.Unmarshal(rec.Body.Bytes(), &list)
+		assert.NoError(t, err)
+		assert.Len(t, list, 1)
+		assert.Equal(t, "foo", list[0].Name)
+		assert.Equal(t, 2, list[0].Completed)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest("GET", "/?window=foo", nil)
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest("GET", "/?since="+base.Format(time.RFC3339)+"&until="+base.Add(-time.Hour).Format(time.RFC3339), nil)
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "invalid time range\n", rec.Body.String())
+	})
+}
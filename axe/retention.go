@@ -0,0 +1,41 @@
+package axe
+
+import (
+	"time"
+
+	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/stick"
+)
+
+// RetentionJob is the periodic job enqueued to apply retention policies.
+type RetentionJob struct {
+	Base               `json:"-" axe:"axe/retention"`
+	stick.NoValidation `json:"-"`
+}
+
+// RetentionTask will return a periodic task that applies the provided
+// retention policies in order to the store using the specified periodicity.
+func RetentionTask(store *coal.Store, periodicity time.Duration, policies ...coal.Retention) *Task {
+	return &Task{
+		Job: &RetentionJob{},
+		Handler: func(ctx *Context) error {
+			for _, policy := range policies {
+				_, err := coal.ApplyRetention(ctx, store, policy)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Workers:     1,
+		MaxAttempts: 1,
+		Lifetime:    time.Minute,
+		Timeout:     2 * time.Minute,
+		Periodicity: periodicity,
+		PeriodicJob: Blueprint{
+			Job: &RetentionJob{
+				Base: B("retention"),
+			},
+		},
+	}
+}
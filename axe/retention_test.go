@@ -0,0 +1,58 @@
+package axe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/coal"
+)
+
+func TestRetentionTask(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		old := time.Now().Add(-48 * time.Hour)
+
+		tester.Insert(&Model{
+			Name:      "test",
+			State:     Completed,
+			Created:   old,
+			Available: old,
+		})
+		tester.Insert(&Model{
+			Name:      "test",
+			State:     Failed,
+			Created:   old,
+			Available: old,
+		})
+		tester.Insert(&Model{
+			Name:      "test",
+			State:     Completed,
+			Created:   time.Now(),
+			Available: time.Now(),
+		})
+
+		task := RetentionTask(tester.Store, time.Hour, coal.Retention{
+			Model: &Model{},
+			Field: "Created",
+			Age:   24 * time.Hour,
+			Filter: bson.M{
+				"State": Completed,
+			},
+		})
+		assert.Equal(t, time.Hour, task.Periodicity)
+
+		err := task.Handler(&Context{
+			Context: context.Background(),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, tester.Count(&Model{}))
+		assert.Equal(t, 0, tester.Count(&Model{}, bson.M{
+			"State":   Completed,
+			"Created": bson.M{"$lt": time.Now().Add(-time.Hour)},
+		}))
+	})
+}
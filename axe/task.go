@@ -2,7 +2,10 @@ package axe
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"time"
 
 	"github.com/256dpi/xo"
@@ -67,6 +70,7 @@ type Context struct {
 	parent   context.Context
 	cancel   context.CancelFunc
 	lifetime time.Duration
+	effects  map[string]bool
 }
 
 // Extend will extend the timeout and lifetime of the job.
@@ -105,6 +109,81 @@ func (c *Context) Update(status string, progress float64) error {
 	return Update(c, c.Queue.options.Store, c.Job, status, progress)
 }
 
+// Once will run the provided function only if no side effect with the provided
+// idempotency key has been recorded for the job. Once the function returns
+// without an error, the key is recorded with the job. Retried jobs will
+// therefore skip non-idempotent steps that have already been performed by a
+// previous attempt, e.g. sending an email.
+//
+// Note: The function is run again if the worker crashes or the recording fails
+// after the side effect has been performed.
+func (c *Context) Once(key string, fn func() error) error {
+	// load effects
+	if c.effects == nil {
+		keys, err := Recorded(c, c.Queue.options.Store, c.Job)
+		if err != nil {
+			return err
+		}
+		c.effects = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			c.effects[key] = true
+		}
+	}
+
+	// skip recorded effects
+	if c.effects[key] {
+		return nil
+	}
+
+	// perform effect
+	err := fn()
+	if err != nil {
+		return err
+	}
+
+	// record effect
+	err = Record(c, c.Queue.options.Store, c.Job, key)
+	if err != nil {
+		return err
+	}
+
+	// mark effect
+	c.effects[key] = true
+
+	return nil
+}
+
+// MissedPolicy defines how missed periodic runs are handled.
+type MissedPolicy int
+
+// The available missed policies.
+const (
+	// MissedRunOnce will enqueue a single job for all missed runs.
+	MissedRunOnce MissedPolicy = iota
+
+	// MissedSkip will skip all missed runs and wait for the next run.
+	MissedSkip
+
+	// MissedCatchUp will enqueue a job for each of the most recent missed
+	// runs up to the configured limit.
+	MissedCatchUp
+)
+
+// RetryPriority defines how retried jobs are prioritized over fresh jobs.
+type RetryPriority int
+
+// The available retry priorities.
+const (
+	// RetryMixed will process retried and fresh jobs in no particular order.
+	RetryMixed RetryPriority = iota
+
+	// RetryFirst will process retried jobs before fresh jobs.
+	RetryFirst
+
+	// RetryLast will process retried jobs after fresh jobs.
+	RetryLast
+)
+
 // Task describes work that is managed using a job queue.
 type Task struct {
 	// The job this task should execute.
@@ -129,6 +208,24 @@ type Task struct {
 	// Default: 0
 	MaxAttempts int
 
+	// The priority of available retried jobs over available fresh jobs. A job
+	// is considered retried if it has been attempted before. Processing
+	// retries first drains failing work quickly while processing them last
+	// prevents retry storms from starving new work.
+	//
+	// Default: RetryMixed.
+	RetryPriority RetryPriority
+
+	// The name of a job field whose value serializes the execution of jobs.
+	// Jobs that share the same value (e.g. an account ID) are executed strictly
+	// sequentially in the order of their availability, while jobs with
+	// different values are executed in parallel. Jobs with a zero value are
+	// not serialized.
+	//
+	// Note: Other queues learn about dequeued jobs through the stream. Jobs
+	// dequeued concurrently by several queues may therefore overlap briefly.
+	ConcurrencyKey string
+
 	// The rate at which a worker will request a job from the queue.
 	//
 	// Default: 100ms.
@@ -171,6 +268,38 @@ type Task struct {
 	//
 	// Default: Blueprint{Name: Task.Name}.
 	PeriodicJob Blueprint
+
+	// PeriodicTemplate may be set instead of PeriodicJob to compute the
+	// blueprint of a periodic job when it is scheduled. The callback receives
+	// the aligned time of the run and may use it to compute the job payload,
+	// e.g. the date range of the previous day. Returned errors are reported
+	// and the run is retried.
+	PeriodicTemplate func(run time.Time) (Blueprint, error)
+
+	// The policy used for periodic runs that have been missed. Runs are aligned
+	// to multiples of the periodicity and a run is missed if its time has
+	// passed while no queue was waiting for it, e.g. when starting a queue
+	// or after a queue has been stalled.
+	//
+	// Runs are identified per job name, label and time. Therefore, a run is
+	// only enqueued once even if several queues are processing the task.
+	//
+	// Default: MissedRunOnce.
+	PeriodicMissed MissedPolicy
+
+	// The maximum number of missed runs that are enqueued when using the
+	// MissedCatchUp policy.
+	//
+	// Default: 1.
+	PeriodicCatchUp int
+
+	// If set, a periodic job is not enqueued while another job with the same
+	// name and label is still enqueued, dequeued or failed. This prevents
+	// overlapping runs of the periodic job across all queues. Runs that are
+	// prevented are not enqueued later.
+	PeriodicExclusive bool
+
+	concurrencyKey string
 }
 
 func (t *Task) prepare() {
@@ -184,6 +313,16 @@ func (t *Task) prepare() {
 		panic("axe: missing handler")
 	}
 
+	// check concurrency key
+	if t.ConcurrencyKey != "" {
+		meta := GetMeta(t.Job)
+		field, ok := meta.Type.FieldByName(t.ConcurrencyKey)
+		if !ok || meta.Coding.GetKey(field) == "" {
+			panic(fmt.Sprintf(`axe: invalid concurrency key "%s"`, t.ConcurrencyKey))
+		}
+		t.concurrencyKey = meta.Coding.GetKey(field)
+	}
+
 	// set default workers
 	if t.Workers == 0 {
 		t.Workers = 2
@@ -227,14 +366,23 @@ func (t *Task) prepare() {
 	// check periodic job
 	if t.Periodicity > 0 {
 		// check existence
-		if t.PeriodicJob.Job == nil {
+		if t.PeriodicJob.Job == nil && t.PeriodicTemplate == nil {
 			panic("axe: missing periodic job")
+		} else if t.PeriodicJob.Job != nil && t.PeriodicTemplate != nil {
+			panic("axe: periodic job and template are exclusive")
 		}
 
 		// validate job
-		err := t.PeriodicJob.Job.Validate()
-		if err != nil {
-			panic(err.Error())
+		if t.PeriodicJob.Job != nil {
+			err := t.PeriodicJob.Job.Validate()
+			if err != nil {
+				panic(err.Error())
+			}
+		}
+
+		// set default catch up
+		if t.PeriodicCatchUp == 0 {
+			t.PeriodicCatchUp = 1
 		}
 	}
 }
@@ -267,7 +415,7 @@ func (t *Task) worker(queue *Queue) error {
 		}
 
 		// attempt to get job from queue
-		id, ok := queue.get(name)
+		id, ok := queue.get(name, t.RetryPriority, t.concurrencyKey)
 		if !ok {
 			// wait some time before trying again
 			select {
@@ -284,22 +432,23 @@ func (t *Task) worker(queue *Queue) error {
 		if err != nil && queue.options.Reporter != nil {
 			queue.options.Reporter(err)
 		}
+
+		// release job
+		queue.release(name, id)
 	}
 }
 
 func (t *Task) enqueuer(queue *Queue) error {
-	// get job, delay and isolation
-	job := t.PeriodicJob.Job
-	delay := t.PeriodicJob.Delay
-	isolation := t.PeriodicJob.Isolation
+	// the last handled run
+	var last time.Time
 
 	// run forever
 	for {
-		// reset ID
-		job.GetBase().DocID = coal.New()
+		// get current run
+		current := time.Now().Truncate(t.Periodicity)
 
-		// enqueue task
-		_, err := queue.Enqueue(nil, job, delay, isolation)
+		// enqueue runs
+		err := t.enqueue(queue, t.schedule(last, current))
 		if err != nil && queue.options.Reporter != nil {
 			// report error
 			queue.options.Reporter(err)
@@ -314,15 +463,99 @@ func (t *Task) enqueuer(queue *Queue) error {
 			continue
 		}
 
-		// wait for next interval
+		// set last run
+		last = current
+
+		// wait for next run
 		select {
-		case <-time.After(t.Periodicity):
+		case <-time.After(time.Until(current.Add(t.Periodicity))):
 		case <-queue.tomb.Dying():
 			return tomb.ErrDying
 		}
 	}
 }
 
+func (t *Task) schedule(last, current time.Time) []time.Time {
+	// check if already handled
+	if !last.IsZero() && !current.After(last) {
+		return nil
+	}
+
+	// check if on time
+	if !last.IsZero() && current.Equal(last.Add(t.Periodicity)) {
+		return []time.Time{current}
+	}
+
+	// handle missed runs
+	switch t.PeriodicMissed {
+	case MissedSkip:
+		return nil
+	case MissedCatchUp:
+		var runs []time.Time
+		for i := t.PeriodicCatchUp - 1; i >= 0; i-- {
+			run := current.Add(-time.Duration(i) * t.Periodicity)
+			if !last.IsZero() && !run.After(last) {
+				continue
+			}
+			runs = append(runs, run)
+		}
+		return runs
+	default:
+		return []time.Time{current}
+	}
+}
+
+func (t *Task) enqueue(queue *Queue, runs []time.Time) error {
+	// enqueue runs
+	for _, run := range runs {
+		// get blueprint
+		bp := t.PeriodicJob
+		if t.PeriodicTemplate != nil {
+			var err error
+			bp, err = t.PeriodicTemplate(run)
+			if err != nil {
+				return err
+			} else if bp.Job == nil {
+				return xo.F("missing periodic job")
+			}
+		}
+
+		// set run ID
+		bp.Job.GetBase().DocID = runID(GetMeta(bp.Job).Name, bp.Job.GetBase().Label, run)
+
+		// enqueue job, duplicates have been enqueued by another queue
+		enqueued, err := enqueue(nil, queue.options.Store, bp.Job, bp.Delay, bp.Isolation, t.PeriodicExclusive)
+		if coal.IsDuplicate(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		// log enqueue
+		if enqueued {
+			queue.log(Enqueued, bp.Job, 0, 0, "")
+		}
+	}
+
+	return nil
+}
+
+func runID(name, label string, run time.Time) coal.ID {
+	// hash name, label and time
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(name))
+	_, _ = hash.Write([]byte{0})
+	_, _ = hash.Write([]byte(label))
+	_ = binary.Write(hash, binary.BigEndian, run.UnixNano())
+
+	// prepare ID
+	var id coal.ID
+	binary.BigEndian.PutUint32(id[0:4], uint32(run.Unix()))
+	copy(id[4:], hash.Sum(nil))
+
+	return id
+}
+
 func (t *Task) execute(queue *Queue, name string, id coal.ID) error {
 	// create tracer
 	tracer, outerContext := xo.CreateTracer(context.Background(), "TASK "+name)
@@ -346,6 +579,14 @@ func (t *Task) execute(queue *Queue, name string, id coal.ID) error {
 	// get time
 	start := time.Now()
 
+	// log dequeue
+	queue.log(Dequeued, job, attempt, 0, "")
+
+	// propagate metadata
+	if job.GetBase().Metadata != nil {
+		outerContext = WithMetadata(outerContext, job.GetBase().Metadata)
+	}
+
 	// add timeout
 	innerContext, cancel := context.WithTimeout(outerContext, t.Lifetime)
 
@@ -391,6 +632,9 @@ func (t *Task) execute(queue *Queue, name string, id coal.ID) error {
 				return err
 			}
 
+			// log failure
+			queue.log(Failed, job, attempt, time.Since(start), anError.Reason)
+
 			return nil
 		}
 
@@ -400,6 +644,9 @@ func (t *Task) execute(queue *Queue, name string, id coal.ID) error {
 			return err
 		}
 
+		// log cancel
+		queue.log(Cancelled, job, attempt, time.Since(start), anError.Reason)
+
 		// call notifier if available
 		if t.Notifier != nil {
 			err = t.Notifier(ctx, true, anError.Reason)
@@ -419,12 +666,18 @@ func (t *Task) execute(queue *Queue, name string, id coal.ID) error {
 			delay := stick.Backoff(t.MinDelay, t.MaxDelay, t.DelayFactor, attempt)
 			_ = Fail(outerContext, queue.options.Store, job, err.Error(), delay)
 
+			// log failure
+			queue.log(Failed, job, attempt, time.Since(start), err.Error())
+
 			return err
 		}
 
 		// cancel job
 		_ = Cancel(outerContext, queue.options.Store, job, err.Error())
 
+		// log cancel
+		queue.log(Cancelled, job, attempt, time.Since(start), err.Error())
+
 		// call notifier if available
 		if t.Notifier != nil {
 			_ = t.Notifier(ctx, true, err.Error())
@@ -439,6 +692,9 @@ func (t *Task) execute(queue *Queue, name string, id coal.ID) error {
 		return err
 	}
 
+	// log completion
+	queue.log(Completed, job, attempt, time.Since(start), "")
+
 	// call notifier if available
 	if t.Notifier != nil {
 		err = t.Notifier(ctx, false, "")
@@ -9,7 +9,7 @@ import (
 	"github.com/256dpi/fire/coal"
 )
 
-var mongoStore = coal.MustConnect("mongodb://0.0.0.0/test-fire-axe", xo.Crash)
+var mongoStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire-axe", xo.Crash)
 var lungoStore = coal.MustOpen(nil, "test-fire-axe", xo.Crash)
 
 var modelList = []coal.Model{&Model{}}
@@ -29,8 +29,23 @@ func (j *testJob) Validate() error {
 	return nil
 }
 
+type accountJob struct {
+	Base `json:"-" axe:"account"`
+
+	Account string `json:"account"`
+	Step    int    `json:"step"`
+}
+
+func (j *accountJob) Validate() error {
+	return nil
+}
+
 func withTester(t *testing.T, fn func(*testing.T, *fire.Tester)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		tester := fire.NewTester(mongoStore, modelList...)
 		tester.Clean()
 		fn(t, tester)
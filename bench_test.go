@@ -16,29 +16,51 @@ import (
 
 const benchListItems = 20
 
-var benchStore = coal.MustConnect("mongodb://0.0.0.0/test-fire-coal?maxPoolSize=100", xo.Crash)
+var benchStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire-coal?maxPoolSize=100", xo.Crash)
 
 var benchThrottle = 100
 
 func BenchmarkList(b *testing.B) {
 	b.Run("00X", func(b *testing.B) {
-		listBenchmark(b, benchStore, 0)
+		listBenchmark(b, benchStore, false, 0)
 	})
 
 	b.Run("01X", func(b *testing.B) {
-		listBenchmark(b, benchStore, 1)
+		listBenchmark(b, benchStore, false, 1)
 	})
 
 	b.Run("10X", func(b *testing.B) {
-		listBenchmark(b, benchStore, 10)
+		listBenchmark(b, benchStore, false, 10)
 	})
 
 	b.Run("50X", func(b *testing.B) {
-		listBenchmark(b, benchStore, 50)
+		listBenchmark(b, benchStore, false, 50)
 	})
 
 	b.Run("100X", func(b *testing.B) {
-		listBenchmark(b, benchStore, 100)
+		listBenchmark(b, benchStore, false, 100)
+	})
+}
+
+func BenchmarkListPooled(b *testing.B) {
+	b.Run("00X", func(b *testing.B) {
+		listBenchmark(b, benchStore, true, 0)
+	})
+
+	b.Run("01X", func(b *testing.B) {
+		listBenchmark(b, benchStore, true, 1)
+	})
+
+	b.Run("10X", func(b *testing.B) {
+		listBenchmark(b, benchStore, true, 10)
+	})
+
+	b.Run("50X", func(b *testing.B) {
+		listBenchmark(b, benchStore, true, 50)
+	})
+
+	b.Run("100X", func(b *testing.B) {
+		listBenchmark(b, benchStore, true, 100)
 	})
 }
 
@@ -86,12 +108,13 @@ func BenchmarkCreate(b *testing.B) {
 	})
 }
 
-func listBenchmark(b *testing.B, store *coal.Store, parallelism int) {
+func listBenchmark(b *testing.B, store *coal.Store, pooled bool, parallelism int) {
 	tester := NewTester(store, modelList...)
 	tester.Clean()
 
 	group := tester.Assign("", &Controller{
-		Model: &postModel{},
+		Model:      &postModel{},
+		PoolModels: pooled,
 	}, &Controller{
 		Model: &commentModel{},
 	}, &Controller{
@@ -0,0 +1,250 @@
+package blaze
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/axe"
+	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/stick"
+)
+
+func init() {
+	// add indexes
+	coal.AddIndex(&Archive{}, false, 0, "Correlation")
+	coal.AddIndex(&Archive{}, false, 0, "Expires")
+}
+
+// Archive tracks an archived request and response stored as a blob.
+type Archive struct {
+	coal.Base `json:"-" bson:",inline" coal:"archives"`
+
+	// The ID of the archived request (see fire.ArchiveID).
+	Correlation string `json:"correlation"`
+
+	// The controller and operation of the archived request.
+	Controller string `json:"controller"`
+	Operation  string `json:"operation"`
+
+	// The response status.
+	Status int `json:"status"`
+
+	// The time the request has been received.
+	Created time.Time `json:"created-at" bson:"created_at"`
+
+	// The time after which the archive is deleted.
+	Expires *time.Time `json:"expires-at" bson:"expires_at"`
+
+	// The size of the blob.
+	Size int64 `json:"size"`
+
+	// The service specific blob handle.
+	Handle Handle `json:"handle"`
+}
+
+// Validate will validate the model.
+func (a *Archive) Validate() error {
+	return stick.Validate(a, func(v *stick.Validator) {
+		v.Value("Correlation", false, stick.IsNotZero)
+		v.Value("Created", false, stick.IsNotZero)
+		v.Value("Size", false, stick.IsMinInt(1))
+		v.Value("Handle", false, stick.IsNotEmpty)
+	})
+}
+
+// ArchiveCleanupJob is the periodic job enqueued to clean up expired archives.
+type ArchiveCleanupJob struct {
+	axe.Base           `json:"-" axe:"blaze/archive-cleanup"`
+	stick.NoValidation `json:"-"`
+}
+
+// Archiver implements the fire.Archiver interface by storing archives as blobs
+// using a service and tracking them using archive models.
+type Archiver struct {
+	store     *coal.Store
+	service   Service
+	retention time.Duration
+}
+
+// NewArchiver creates and returns a new archiver. If retention is set, archives
+// expire after the specified duration and are deleted by the cleanup task.
+func NewArchiver(store *coal.Store, service Service, retention time.Duration) *Archiver {
+	return &Archiver{
+		store:     store,
+		service:   service,
+		retention: retention,
+	}
+}
+
+// Archive implements the fire.Archiver interface.
+func (a *Archiver) Archive(ctx context.Context, archive *fire.Archive) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "blaze/Archiver.Archive")
+	defer span.End()
+
+	// encode archive
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// prepare handle
+	handle, err := a.service.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	// begin upload
+	upload, err := a.service.Upload(ctx, handle, Info{
+		Size:      int64(len(data)),
+		MediaType: "application/json",
+	})
+	if err != nil {
+		return err
+	}
+
+	// write data
+	_, err = upload.Write(data)
+	if err != nil {
+		_ = upload.Abort()
+		return err
+	}
+
+	// finish upload
+	err = upload.Close()
+	if err != nil {
+		return err
+	}
+
+	// prepare model
+	model := &Archive{
+		Correlation: archive.ID,
+		Controller:  archive.Controller,
+		Operation:   archive.Operation,
+		Status:      archive.Status,
+		Created:     archive.Time,
+		Size:        int64(len(data)),
+		Handle:      handle,
+	}
+
+	// set expiry
+	if a.retention > 0 {
+		model.Expires = stick.P(archive.Time.Add(a.retention))
+	}
+
+	// insert model
+	err = a.store.M(model).Insert(ctx, model)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Load will load the archive with the specified ID (see fire.ArchiveID).
+func (a *Archiver) Load(ctx context.Context, id string) (*fire.Archive, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "blaze/Archiver.Load")
+	defer span.End()
+
+	// find model
+	var model Archive
+	found, err := a.store.M(&model).FindFirst(ctx, &model, bson.M{
+		"Correlation": id,
+	}, nil, 0, false)
+	if err != nil {
+		return nil, err
+	} else if !found {
+		return nil, ErrNotFound.Wrap()
+	}
+
+	// download blob
+	download, err := a.service.Download(ctx, model.Handle)
+	if err != nil {
+		return nil, err
+	}
+	defer download.Close()
+
+	// read data
+	data, err := io.ReadAll(download)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// decode archive
+	var archive fire.Archive
+	err = json.Unmarshal(data, &archive)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	return &archive, nil
+}
+
+// Cleanup will delete up to the specified amount of expired archives and
+// return the number of deleted archives.
+func (a *Archiver) Cleanup(ctx context.Context, batch int) (int, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "blaze/Archiver.Cleanup")
+	defer span.End()
+
+	// find expired archives
+	var archives []Archive
+	err := a.store.M(&Archive{}).FindAll(ctx, &archives, bson.M{
+		"Expires": bson.M{
+			"$lt": time.Now(),
+		},
+	}, nil, 0, int64(batch), false, coal.NoTransaction)
+	if err != nil {
+		return 0, err
+	}
+
+	// delete archives
+	for _, archive := range archives {
+		// delete blob
+		err = a.service.Delete(ctx, archive.Handle)
+		if err != nil && !ErrNotFound.Is(err) {
+			return 0, err
+		}
+
+		// delete model
+		_, err = a.store.M(&Archive{}).Delete(ctx, nil, archive.ID())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(archives), nil
+}
+
+// CleanupTask will return a periodic task that will delete expired archives.
+func (a *Archiver) CleanupTask(batch int) *axe.Task {
+	// set default batch
+	if batch == 0 {
+		batch = 100
+	}
+
+	return &axe.Task{
+		Job: &ArchiveCleanupJob{},
+		Handler: func(ctx *axe.Context) error {
+			_, err := a.Cleanup(ctx, batch)
+			return err
+		},
+		Workers:     1,
+		MaxAttempts: 1,
+		Lifetime:    time.Minute,
+		Timeout:     2 * time.Minute,
+		Periodicity: 5 * time.Minute,
+		PeriodicJob: axe.Blueprint{
+			Job: &ArchiveCleanupJob{
+				Base: axe.B("cleanup"),
+			},
+		},
+	}
+}
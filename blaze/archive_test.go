@@ -0,0 +1,66 @@
+package blaze
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire"
+)
+
+func TestArchiver(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		service := NewMemory()
+		archiver := NewArchiver(tester.Store, service, time.Hour)
+
+		now := time.Now().Truncate(time.Millisecond)
+		err := archiver.Archive(nil, &fire.Archive{
+			ID:           "foo",
+			Time:         now,
+			Controller:   "posts",
+			Operation:    "Create",
+			Method:       "POST",
+			URL:          "/posts",
+			Status:       http.StatusCreated,
+			RequestBody:  []byte(`{"data":{}}`),
+			ResponseBody: []byte(`{"data":{"id":"1"}}`),
+		})
+		assert.NoError(t, err)
+		assert.Len(t, service.Blobs, 1)
+
+		model := tester.FindLast(&Archive{}).(*Archive)
+		assert.Equal(t, "foo", model.Correlation)
+		assert.Equal(t, "posts", model.Controller)
+		assert.Equal(t, "Create", model.Operation)
+		assert.Equal(t, http.StatusCreated, model.Status)
+		assert.Equal(t, now.Add(time.Hour), model.Expires.Local())
+		assert.NotEmpty(t, model.Handle)
+
+		archive, err := archiver.Load(nil, "foo")
+		assert.NoError(t, err)
+		assert.Equal(t, "/posts", archive.URL)
+		assert.Equal(t, `{"data":{"id":"1"}}`, string(archive.ResponseBody))
+
+		_, err = archiver.Load(nil, "bar")
+		assert.True(t, ErrNotFound.Is(err))
+
+		n, err := archiver.Cleanup(nil, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		tester.Update(model, bson.M{
+			"$set": bson.M{
+				"Expires": time.Now().Add(-time.Minute),
+			},
+		})
+
+		n, err = archiver.Cleanup(nil, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Empty(t, service.Blobs)
+		assert.Equal(t, 0, tester.Count(&Archive{}))
+	})
+}
@@ -12,10 +12,10 @@ import (
 	"github.com/256dpi/fire/stick"
 )
 
-var mongoStore = coal.MustConnect("mongodb://0.0.0.0/test-fire-blaze", xo.Crash)
+var mongoStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire-blaze", xo.Crash)
 var lungoStore = coal.MustOpen(nil, "test-fire-blaze", xo.Crash)
 
-var modelList = []coal.Model{&File{}, &testModel{}, &axe.Model{}}
+var modelList = []coal.Model{&File{}, &Archive{}, &testModel{}, &axe.Model{}}
 
 var testNotary = heat.NewNotary("test", heat.MustRand(32))
 
@@ -52,6 +52,10 @@ func init() {
 
 func withTester(t *testing.T, fn func(*testing.T, *fire.Tester)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		tester := fire.NewTester(mongoStore, modelList...)
 		tester.Clean()
 		fn(t, tester)
@@ -3,7 +3,6 @@ package fire
 import (
 	"fmt"
 	"net/http"
-	"reflect"
 	"time"
 
 	"github.com/256dpi/jsonapi/v2"
@@ -24,6 +23,18 @@ var ErrResourceNotFound = xo.BW(jsonapi.NotFound("resource not found"))
 // the required uniqueness constraints.
 var ErrDocumentNotUnique = xo.BW(jsonapi.BadRequest("document not unique"))
 
+// ErrDocumentTooLarge is returned if a provided document exceeds the maximum
+// document size configured on the store.
+var ErrDocumentTooLarge = xo.BW(jsonapi.ErrorFromStatus(http.StatusRequestEntityTooLarge, "document too large"))
+
+// ErrResourceForbidden may be returned to indicate forbidden access to an
+// existing resource.
+var ErrResourceForbidden = xo.BW(jsonapi.ErrorFromStatus(http.StatusForbidden, "access forbidden"))
+
+// ErrBudgetExhausted is returned if the request has been cancelled or its
+// timeout has been exceeded while processing the callbacks.
+var ErrBudgetExhausted = xo.BW(jsonapi.ErrorFromStatus(http.StatusServiceUnavailable, "request budget exhausted"))
+
 // BasicAuthorizer authorizes requests based on a simple credentials list.
 func BasicAuthorizer(credentials map[string]string) *Callback {
 	return C("fire/BasicAuthorizer", Authorizer, All(), func(ctx *Context) error {
@@ -45,6 +56,9 @@ func BasicAuthorizer(credentials map[string]string) *Callback {
 // TimestampModifier will set timestamp fields on create and update operations.
 // Missing created timestamps are retroactively set using the timestamp encoded
 // in the model ID.
+//
+// Note: Flagging the fields using coal.CreatedFlag and coal.UpdatedFlag will
+// have the store set the timestamps for all operations instead.
 func TimestampModifier(createdField, updatedField string) *Callback {
 	return C("fire/TimestampModifier", Modifier, Only(Create|Update), func(ctx *Context) error {
 		// get time
@@ -69,6 +83,37 @@ func TimestampModifier(createdField, updatedField string) *Callback {
 	})
 }
 
+// SlugModifier will set the slug field to a unique slug generated from the
+// source field on creation and whenever the source field has been modified.
+// Previous slugs remain reserved for the resource and can be resolved using
+// coal.LookupSlug to redirect requests.
+func SlugModifier(sourceField, slugField string) *Callback {
+	return C("fire/SlugModifier", Modifier, Only(Create|Update), func(ctx *Context) error {
+		// check slug
+		current := stick.MustGet(ctx.Model, slugField).(string)
+		if ctx.Operation == Update && current != "" && !ctx.Modified(sourceField) {
+			return nil
+		}
+
+		// check source
+		source := stick.MustGet(ctx.Model, sourceField).(string)
+		if coal.Slugify(source) == "" {
+			return xo.SF("missing slug source")
+		}
+
+		// reserve slug
+		slug, err := coal.ReserveSlug(ctx, ctx.Store, ctx.Model, ctx.Model.ID(), source)
+		if err != nil {
+			return err
+		}
+
+		// set slug
+		stick.MustSet(ctx.Model, slugField, slug)
+
+		return nil
+	})
+}
+
 // NoDefault marks the specified field to have no default that needs to be
 // enforced while executing the ProtectedFieldsValidator.
 const NoDefault noDefault = iota
@@ -100,7 +145,7 @@ func ProtectedFieldsValidator(pairs map[string]interface{}) *Callback {
 				}
 
 				// check equality
-				if !reflect.DeepEqual(stick.MustGet(ctx.Model, field), def) {
+				if !coal.Equal(stick.MustGet(ctx.Model, field), def) {
 					return xo.SF("field " + field + " is protected")
 				}
 			}
@@ -132,14 +177,37 @@ func ProtectedFieldsValidator(pairs map[string]interface{}) *Callback {
 //		&Comment{}: "Author",
 //	})
 //
-// The callback supports models that use the soft delete mechanism.
+// The callback supports models that use the soft delete mechanism. For bulk
+// deletions in the InBulk mode, the IDs of the documents matched by the query
+// are checked.
 func DependentResourcesValidator(pairs map[coal.Model]string) *Callback {
 	return C("fire/DependentResourcesValidator", Validator, Only(Delete), func(ctx *Context) error {
+		// get deleted IDs
+		var ids []interface{}
+		if ctx.Model != nil {
+			ids = append(ids, ctx.Model.ID())
+		} else if ctx.Controller != nil {
+			list, err := ctx.Store.M(ctx.Controller.Model).Distinct(ctx, "_id", ctx.Query(), false)
+			if err != nil {
+				return err
+			}
+			ids = list
+		} else {
+			return xo.F("missing model")
+		}
+
+		// skip if nothing is deleted
+		if len(ids) == 0 {
+			return nil
+		}
+
 		// check all relations
 		for model, field := range pairs {
 			// prepare query
 			query := bson.M{
-				field: ctx.Model.ID(),
+				field: bson.M{
+					"$in": ids,
+				},
 			}
 
 			// exclude soft deleted documents if supported
@@ -237,9 +305,69 @@ func ReferencedResourcesValidator(pairs map[string]coal.Model) *Callback {
 	})
 }
 
+// PolymorphicReferencesValidator makes sure all polymorphic references in the
+// document are pointing to one of the candidate models and are existing by
+// counting the referenced documents.
+//
+// References are defined by passing pairs of fields and candidate models which
+// may be referenced by the current model:
+//
+//	fire.PolymorphicReferencesValidator(map[string][]coal.Model{
+//		"Subject": {&Post{}, &Comment{}},
+//	})
+//
+// The callback supports polymorphic and optional polymorphic relationships.
+func PolymorphicReferencesValidator(pairs map[string][]coal.Model) *Callback {
+	return C("fire/PolymorphicReferencesValidator", Validator, Only(Create|Update), func(ctx *Context) error {
+		// check all references
+		for field, candidates := range pairs {
+			// read reference
+			var ref coal.Ref
+			switch value := stick.MustGet(ctx.Model, field).(type) {
+			case coal.Ref:
+				ref = value
+			case *coal.Ref:
+				// continue if reference is not set
+				if value == nil {
+					continue
+				}
+				ref = *value
+			}
+
+			// find candidate
+			var collection coal.Model
+			for _, candidate := range candidates {
+				if coal.GetMeta(candidate).PluralName == ref.Type {
+					collection = candidate
+				}
+			}
+			if collection == nil {
+				return xo.SF("invalid reference type for field " + field)
+			}
+
+			// count entities in database
+			count, err := ctx.Store.M(collection).Count(ctx, bson.M{
+				"_id": ref.ID,
+			}, 0, 1, false)
+			if err != nil {
+				return err
+			}
+
+			// check for existence
+			if count != 1 {
+				return xo.SF("missing reference for field " + field)
+			}
+		}
+
+		// pass validation
+		return nil
+	})
+}
+
 // RelationshipValidator makes sure all relationships of a model are correct and
-// in place. It does so by combining a DependentResourcesValidator and a
-// ReferencedResourcesValidator based on the specified model and catalog.
+// in place. It does so by combining a DependentResourcesValidator, a
+// ReferencedResourcesValidator and a PolymorphicReferencesValidator based on
+// the specified model and catalog.
 func RelationshipValidator(model coal.Model, models []coal.Model, exclude ...string) *Callback {
 	// build index
 	index := make(map[string]coal.Model, len(models))
@@ -250,6 +378,7 @@ func RelationshipValidator(model coal.Model, models []coal.Model, exclude ...str
 	// prepare lists
 	resources := make(map[coal.Model]string)
 	references := make(map[string]coal.Model)
+	polymorphic := make(map[string][]coal.Model)
 
 	// iterate through all fields
 	for _, field := range coal.GetMeta(model).Relationships {
@@ -292,14 +421,29 @@ func RelationshipValidator(model coal.Model, models []coal.Model, exclude ...str
 			// add reference
 			references[field.Name] = relatedModel
 		}
+
+		// handle polymorphic relationships
+		if field.Polymorphic {
+			for _, typ := range field.RelTypes {
+				// get related model
+				relatedModel := index[typ]
+				if relatedModel == nil {
+					panic(fmt.Sprintf(`fire: missing model in catalog: "%s"`, typ))
+				}
+
+				// add candidate
+				polymorphic[field.Name] = append(polymorphic[field.Name], relatedModel)
+			}
+		}
 	}
 
 	// create callbacks
 	drv := DependentResourcesValidator(resources)
 	rrv := ReferencedResourcesValidator(references)
+	prv := PolymorphicReferencesValidator(polymorphic)
 
 	// combine callbacks
-	cb := Combine("fire/RelationshipValidator", Validator, drv, rrv)
+	cb := Combine("fire/RelationshipValidator", Validator, drv, rrv, prv)
 
 	return cb
 }
@@ -381,3 +525,253 @@ func MatchingReferencesValidator(reference string, target coal.Model, matcher ma
 		return nil
 	})
 }
+
+// PrunePolicy defines which references to deleted resources are pruned.
+type PrunePolicy int
+
+// The available prune policies.
+const (
+	// PruneToMany removes the IDs of deleted resources from to-many
+	// relationships.
+	PruneToMany PrunePolicy = 1 << iota
+
+	// PruneToOne unsets optional to-one relationships that reference deleted
+	// resources.
+	PruneToOne
+
+	// PruneAll prunes to-many and optional to-one relationships.
+	PruneAll = PruneToMany | PruneToOne
+)
+
+// ReferencesPruner removes references to deleted resources from referencing
+// documents after the deletion. This callback complements the
+// DependentResourcesValidator for relationships that should not block the
+// deletion of a resource.
+//
+// Referencing resources are defined by passing pairs of models and fields that
+// reference the current model. The policy selects which kinds of fields are
+// pruned:
+//
+//	fire.ReferencesPruner(map[coal.Model]string{
+//		&Post{}:  "Tags",
+//		&Draft{}: "Tag",
+//	}, fire.PruneAll)
+//
+// The callback supports to-many and optional to-one relationships. Resources
+// that are soft deleted are not pruned as they may be restored. Bulk
+// deletions are only pruned if the deleted models have been loaded. Remaining
+// references may be hidden using the ReferencesDecorator.
+func ReferencesPruner(pairs map[coal.Model]string, policy PrunePolicy) *Callback {
+	// check fields
+	for model, name := range pairs {
+		field := coal.GetMeta(model).Fields[name]
+		if field == nil || !(field.ToMany || field.ToOne && field.Optional) {
+			panic(fmt.Sprintf(`fire: expected to-many or optional to-one relationship: "%s"`, name))
+		}
+	}
+
+	return C("fire/ReferencesPruner", Notifier, Only(Delete), func(ctx *Context) error {
+		// skip soft deleted resources
+		if ctx.Controller != nil && ctx.Controller.SoftDelete {
+			return nil
+		}
+
+		// collect IDs
+		var ids []coal.ID
+		if ctx.Model != nil {
+			ids = append(ids, ctx.Model.ID())
+		}
+		for _, model := range ctx.Models {
+			ids = append(ids, model.ID())
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// prune all references
+		for model, name := range pairs {
+			// get manager
+			manager := ctx.Store.M(model)
+
+			// prepare query
+			query := bson.M{
+				name: bson.M{
+					"$in": ids,
+				},
+			}
+
+			// unset optional to-one references
+			if !coal.GetMeta(model).Fields[name].ToMany {
+				if policy&PruneToOne == 0 {
+					continue
+				}
+
+				// update referencing documents
+				_, err := manager.UpdateAll(ctx, query, bson.M{
+					"$set": bson.M{
+						name: nil,
+					},
+				}, false)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			// check policy
+			if policy&PruneToMany == 0 {
+				continue
+			}
+
+			// remove references
+			_, err := manager.UpdateAll(ctx, query, bson.M{
+				"$pullAll": bson.M{
+					name: ids,
+				},
+			}, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RelationshipPruner creates a ReferencesPruner for the specified model that
+// prunes all to-many and optional to-one relationships of the models in the
+// catalog that reference the model. Required to-one relationships are skipped
+// and should be protected using a DependentResourcesValidator.
+func RelationshipPruner(model coal.Model, models []coal.Model, policy PrunePolicy) *Callback {
+	// get type
+	typ := coal.GetMeta(model).PluralName
+
+	// create pruners for referencing fields
+	var pruners []*Callback
+	for _, other := range models {
+		for _, field := range coal.GetMeta(other).OrderedFields {
+			// check relationship
+			if field.RelType != typ || !(field.ToMany || field.ToOne && field.Optional) {
+				continue
+			}
+
+			// add pruner
+			pruners = append(pruners, ReferencesPruner(map[coal.Model]string{
+				other: field.Name,
+			}, policy))
+		}
+	}
+
+	// combine callbacks
+	cb := Combine("fire/RelationshipPruner", Notifier, pruners...)
+
+	return cb
+}
+
+// ReferencesDecorator removes references to deleted resources from the loaded
+// models before they are returned. This callback complements the
+// ReferencesPruner by hiding references that have not been pruned yet, e.g.
+// references to soft deleted resources or resources that have been deleted in
+// bulk. The references are only removed from the response and not from the
+// stored documents.
+//
+// References are defined by passing pairs of fields and models which are
+// referenced by the current model. The policy selects which kinds of fields are
+// decorated:
+//
+//	fire.ReferencesDecorator(map[string]coal.Model{
+//		"Tags": &Tag{},
+//		"Tag":  &Tag{},
+//	}, fire.PruneAll)
+//
+// The callback supports to-many and optional to-one relationships.
+func ReferencesDecorator(pairs map[string]coal.Model, policy PrunePolicy) *Callback {
+	return C("fire/ReferencesDecorator", Decorator, Only(List|Find|Create|Update), func(ctx *Context) error {
+		// collect models
+		var models []coal.Model
+		if ctx.Model != nil {
+			models = append(models, ctx.Model)
+		}
+		models = append(models, ctx.Models...)
+		if len(models) == 0 {
+			return nil
+		}
+
+		// decorate all references
+		for name, target := range pairs {
+			// check field
+			field := coal.GetMeta(models[0]).Fields[name]
+			if field == nil || !(field.ToMany || field.ToOne && field.Optional) {
+				return xo.F(`expected to-many or optional to-one relationship: "%s"`, name)
+			}
+
+			// check policy
+			if field.ToMany && policy&PruneToMany == 0 || field.ToOne && policy&PruneToOne == 0 {
+				continue
+			}
+
+			// collect references
+			var ids []coal.ID
+			for _, model := range models {
+				switch ref := stick.MustGet(model, name).(type) {
+				case []coal.ID:
+					ids = append(ids, ref...)
+				case *coal.ID:
+					if ref != nil {
+						ids = append(ids, *ref)
+					}
+				}
+			}
+			if len(ids) == 0 {
+				continue
+			}
+
+			// prepare query
+			query := bson.M{
+				"_id": bson.M{
+					"$in": stick.Unique(ids),
+				},
+			}
+
+			// exclude soft deleted documents if supported
+			if sdf := coal.L(target, "fire-soft-delete", false); sdf != "" {
+				query[sdf] = nil
+			}
+
+			// find existing references
+			list, err := ctx.Store.M(target).Distinct(ctx, "_id", query, false)
+			if err != nil {
+				return err
+			}
+
+			// prepare set
+			existing := make(map[coal.ID]bool, len(list))
+			for _, id := range list {
+				existing[id.(coal.ID)] = true
+			}
+
+			// remove missing references
+			for _, model := range models {
+				switch ref := stick.MustGet(model, name).(type) {
+				case []coal.ID:
+					var refs []coal.ID
+					for _, id := range ref {
+						if existing[id] {
+							refs = append(refs, id)
+						}
+					}
+					if len(refs) != len(ref) {
+						stick.MustSet(model, name, refs)
+					}
+				case *coal.ID:
+					if ref != nil && !existing[*ref] {
+						stick.MustSet(model, name, (*coal.ID)(nil))
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+}
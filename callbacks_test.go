@@ -2,10 +2,13 @@ package fire
 
 import (
 	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
 
 	"github.com/256dpi/fire/coal"
 	"github.com/256dpi/fire/stick"
@@ -72,6 +75,51 @@ func TestTimestampModifier(t *testing.T) {
 	})
 }
 
+func TestSlugModifier(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		type model struct {
+			coal.Base          `json:"-" bson:",inline" coal:"posts"`
+			Title              string
+			Slug               string
+			stick.NoValidation `json:"-" bson:"-"`
+		}
+
+		tester.DeleteAll(&coal.Slug{})
+
+		modifier := SlugModifier("Title", "Slug")
+
+		m1 := &model{Base: coal.B(), Title: "Hello World"}
+		err := tester.RunCallback(&Context{Operation: Create, Model: m1}, modifier)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello-world", m1.Slug)
+
+		m2 := &model{Base: coal.B(), Title: "Hello World"}
+		err = tester.RunCallback(&Context{Operation: Create, Model: m2}, modifier)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello-world-2", m2.Slug)
+
+		original := *m1
+		m1.Title = "Goodbye"
+		err = tester.RunCallback(&Context{Operation: Update, Model: m1, Original: &original}, modifier)
+		assert.NoError(t, err)
+		assert.Equal(t, "goodbye", m1.Slug)
+
+		original = *m1
+		err = tester.RunCallback(&Context{Operation: Update, Model: m1, Original: &original}, modifier)
+		assert.NoError(t, err)
+		assert.Equal(t, "goodbye", m1.Slug)
+
+		id, err := coal.LookupSlug(nil, tester.Store, m1, "hello-world")
+		assert.NoError(t, err)
+		assert.Equal(t, m1.ID(), id)
+
+		m3 := &model{Base: coal.B(), Title: "!"}
+		err = tester.RunCallback(&Context{Operation: Create, Model: m3}, modifier)
+		assert.Error(t, err)
+		assert.Equal(t, "missing slug source", err.Error())
+	})
+}
+
 func TestProtectedAttributesValidatorOnCreate(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		validator := ProtectedFieldsValidator(map[string]interface{}{
@@ -201,6 +249,157 @@ func TestDependentResourcesValidatorSoftDelete(t *testing.T) {
 	})
 }
 
+func TestDependentResourcesValidatorInBulk(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:          &postModel{},
+			Filters:        []string{"Published"},
+			BulkDelete:     true,
+			BulkDeleteMode: InBulk,
+			Validators: L{
+				DependentResourcesValidator(map[coal.Model]string{
+					&commentModel{}: "Post",
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		post1 := tester.Insert(&postModel{
+			Title:     "A",
+			Published: true,
+		})
+		tester.Insert(&postModel{
+			Title: "B",
+		})
+
+		comment := tester.Insert(&commentModel{
+			Post: post1.ID(),
+		})
+
+		tester.Request("DELETE", "posts/delete-many?filter[published]=true", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "resource has dependent resources", gjson.Get(r.Body.String(), "errors.0.detail").String(), tester.DebugRequest(rq, r))
+		})
+
+		tester.Request("DELETE", "posts/delete-many?filter[published]=false", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		tester.Delete(comment)
+
+		tester.Request("DELETE", "posts/delete-many?filter[published]=true", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		assert.Equal(t, 0, tester.Count(&postModel{}))
+	})
+}
+
+func TestReferencesPruner(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		pruner := ReferencesPruner(map[coal.Model]string{
+			&fooModel{}: "Bars",
+		}, PruneAll)
+
+		bar := tester.Insert(&barModel{})
+		other := coal.New()
+
+		foo := tester.Insert(&fooModel{
+			Bars: []coal.ID{other, bar.ID()},
+		}).(*fooModel)
+
+		err := tester.RunCallback(&Context{Operation: Delete, Model: bar}, pruner)
+		assert.NoError(t, err)
+		assert.Equal(t, []coal.ID{other}, tester.Fetch(&fooModel{}, foo.ID()).(*fooModel).Bars)
+
+		assert.PanicsWithValue(t, `fire: expected to-many or optional to-one relationship: "Bar"`, func() {
+			ReferencesPruner(map[coal.Model]string{
+				&fooModel{}: "Bar",
+			}, PruneAll)
+		})
+	})
+}
+
+func TestRelationshipPruner(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		bar1 := tester.Insert(&barModel{})
+		bar2 := tester.Insert(&barModel{})
+		other := coal.New()
+
+		foo := tester.Insert(&fooModel{
+			Bar:    bar1.ID(),
+			OptBar: stick.P(bar1.ID()),
+			Bars:   []coal.ID{bar1.ID(), other, bar2.ID()},
+		}).(*fooModel)
+
+		pruner := RelationshipPruner(&barModel{}, modelList, PruneToMany)
+
+		err := tester.RunCallback(&Context{Operation: Delete, Model: bar1}, pruner)
+		assert.NoError(t, err)
+
+		foo = tester.Fetch(&fooModel{}, foo.ID()).(*fooModel)
+		assert.Equal(t, bar1.ID(), foo.Bar)
+		assert.Equal(t, stick.P(bar1.ID()), foo.OptBar)
+		assert.Equal(t, []coal.ID{other, bar2.ID()}, foo.Bars)
+
+		pruner = RelationshipPruner(&barModel{}, modelList, PruneAll)
+
+		err = tester.RunCallback(&Context{Operation: Delete, Models: []coal.Model{bar1, bar2}}, pruner)
+		assert.NoError(t, err)
+
+		foo = tester.Fetch(&fooModel{}, foo.ID()).(*fooModel)
+		assert.Equal(t, bar1.ID(), foo.Bar)
+		assert.Nil(t, foo.OptBar)
+		assert.Equal(t, []coal.ID{other}, foo.Bars)
+	})
+}
+
+func TestReferencesDecorator(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		bar := tester.Insert(&barModel{})
+		other := coal.New()
+
+		foo := tester.Insert(&fooModel{
+			OptBar: stick.P(other),
+			Bars:   []coal.ID{other, bar.ID()},
+		}).(*fooModel)
+
+		decorator := ReferencesDecorator(map[string]coal.Model{
+			"OptBar": &barModel{},
+			"Bars":   &barModel{},
+		}, PruneToMany)
+
+		err := tester.RunCallback(&Context{Operation: Find, Model: foo}, decorator)
+		assert.NoError(t, err)
+		assert.Equal(t, stick.P(other), foo.OptBar)
+		assert.Equal(t, []coal.ID{bar.ID()}, foo.Bars)
+
+		decorator = ReferencesDecorator(map[string]coal.Model{
+			"OptBar": &barModel{},
+		}, PruneAll)
+
+		err = tester.RunCallback(&Context{Operation: List, Models: []coal.Model{foo}}, decorator)
+		assert.NoError(t, err)
+		assert.Nil(t, foo.OptBar)
+
+		foo = tester.Fetch(&fooModel{}, foo.ID()).(*fooModel)
+		assert.Equal(t, stick.P(other), foo.OptBar)
+		assert.Equal(t, []coal.ID{other, bar.ID()}, foo.Bars)
+
+		decorator = ReferencesDecorator(map[string]coal.Model{
+			"Bar": &barModel{},
+		}, PruneAll)
+
+		err = tester.RunCallback(&Context{Operation: Find, Model: foo}, decorator)
+		assert.Error(t, err)
+	})
+}
+
 func TestReferencedResourcesValidatorToOne(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		validator := ReferencedResourcesValidator(map[string]coal.Model{
@@ -266,6 +465,48 @@ func TestReferencedResourcesValidatorToOne(t *testing.T) {
 	})
 }
 
+func TestPolymorphicReferencesValidator(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		validator := PolymorphicReferencesValidator(map[string][]coal.Model{
+			"Subject":    {&postModel{}, &noteModel{}},
+			"OptSubject": {&postModel{}, &commentModel{}},
+		})
+
+		post := tester.Insert(&postModel{})
+		note := tester.Insert(&noteModel{
+			Post: post.ID(),
+		})
+
+		err := tester.RunCallback(&Context{Operation: Create, Model: tester.Insert(&reactionModel{
+			Subject: coal.Ref{Type: "notes", ID: coal.New()}, // <- missing
+		})}, validator)
+		assert.Error(t, err)
+		assert.Equal(t, "missing reference for field Subject", err.Error())
+
+		err = tester.RunCallback(&Context{Operation: Create, Model: tester.Insert(&reactionModel{
+			Subject: coal.R(note),
+			OptSubject: &coal.Ref{
+				Type: "notes", // <- invalid
+				ID:   note.ID(),
+			},
+		})}, validator)
+		assert.Error(t, err)
+		assert.Equal(t, "invalid reference type for field OptSubject", err.Error())
+
+		err = tester.RunCallback(&Context{Operation: Create, Model: tester.Insert(&reactionModel{
+			Subject:    coal.R(note),
+			OptSubject: nil, // <- not set
+		})}, validator)
+		assert.NoError(t, err)
+
+		err = tester.RunCallback(&Context{Operation: Create, Model: tester.Insert(&reactionModel{
+			Subject:    coal.R(note),
+			OptSubject: stick.P(coal.R(post)),
+		})}, validator)
+		assert.NoError(t, err)
+	})
+}
+
 func TestRelationshipValidatorDependentResources(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		validator := RelationshipValidator(&postModel{}, []coal.Model{
@@ -312,6 +553,28 @@ func TestRelationshipValidatorReferencedResources(t *testing.T) {
 	})
 }
 
+func TestRelationshipValidatorPolymorphicReferences(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		validator := RelationshipValidator(&reactionModel{}, modelList)
+
+		reaction := tester.Insert(&reactionModel{
+			Subject: coal.Ref{Type: "posts", ID: coal.New()},
+		})
+
+		err := tester.RunCallback(&Context{Operation: Create, Model: reaction}, validator)
+		assert.Error(t, err)
+		assert.Equal(t, "missing reference for field Subject", err.Error())
+
+		post := tester.Insert(&postModel{})
+		reaction = tester.Insert(&reactionModel{
+			Subject: coal.R(post),
+		})
+
+		err = tester.RunCallback(&Context{Operation: Create, Model: reaction}, validator)
+		assert.NoError(t, err)
+	})
+}
+
 func TestMatchingReferencesValidatorToOne(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		validator := MatchingReferencesValidator("Foo", &fooModel{}, map[string]string{
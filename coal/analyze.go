@@ -0,0 +1,255 @@
+package coal
+
+import (
+	"context"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// IndexUsage describes an existing index and its usage.
+type IndexUsage struct {
+	// The index name e.g. "published_1_title_1".
+	Name string
+
+	// The index keys.
+	Keys bson.D
+
+	// The number of operations that used the index since the statistics have
+	// been reset. The value is summed across all reporting hosts.
+	Ops int64
+
+	// The time since which the operations have been counted.
+	Since time.Time
+
+	// Whether the index is declared for the model.
+	Declared bool
+
+	// Whether the index has not been used. Only set if statistics are
+	// available.
+	Unused bool
+
+	// The name of the index whose keys begin with the keys of this index and
+	// thus also covers its queries.
+	CoveredBy string
+}
+
+// IndexReport describes the existing indexes of a model and how they relate
+// to the declared indexes.
+type IndexReport struct {
+	// The model.
+	Model Model
+
+	// Whether usage statistics have been available.
+	Stats bool
+
+	// The existing indexes.
+	Indexes []IndexUsage
+
+	// The declared indexes that do not exist.
+	Missing []Index
+}
+
+// Unused returns the names of the existing indexes that have not been used.
+func (r *IndexReport) Unused() []string {
+	var list []string
+	for _, index := range r.Indexes {
+		if index.Unused {
+			list = append(list, index.Name)
+		}
+	}
+	return list
+}
+
+// Redundant returns the names of the existing indexes that are covered by
+// other indexes.
+func (r *IndexReport) Redundant() []string {
+	var list []string
+	for _, index := range r.Indexes {
+		if index.CoveredBy != "" {
+			list = append(list, index.Name)
+		}
+	}
+	return list
+}
+
+// Undeclared returns the names of the existing indexes that are not declared.
+func (r *IndexReport) Undeclared() []string {
+	var list []string
+	for _, index := range r.Indexes {
+		if !index.Declared {
+			list = append(list, index.Name)
+		}
+	}
+	return list
+}
+
+// AnalyzeIndexes will read the usage statistics of the existing indexes of the
+// provided models using "$indexStats" and return a report for each model. The
+// report flags unused indexes, indexes that are redundant because another
+// index with the same leading keys exists and compares the existing with the
+// declared indexes.
+//
+// Note: Usage statistics are only available if the backend supports the
+// IndexStats capability. They are reset when the server restarts.
+func AnalyzeIndexes(ctx context.Context, store *Store, models ...Model) ([]IndexReport, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/AnalyzeIndexes")
+	defer span.End()
+
+	// prepare reports
+	reports := make([]IndexReport, 0, len(models))
+
+	// iterate models
+	for _, model := range models {
+		// get meta and collection
+		meta := GetMeta(model)
+		coll := store.C(model).Native()
+
+		// list existing indexes
+		existing, err := listIndexes(ctx, coll.Indexes())
+		if err != nil {
+			return nil, err
+		}
+
+		// prepare report
+		report := IndexReport{
+			Model: model,
+			Stats: store.Supports(IndexStats),
+		}
+
+		// read statistics
+		stats := map[string]*IndexUsage{}
+		if report.Stats {
+			stats, err = indexStats(ctx, store, model)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// compare declared indexes
+		declared := map[string]bool{}
+		for _, index := range meta.Indexes {
+			// skip unsupported special indexes
+			if !store.Supports(SpecialIndexes) && index.special() {
+				continue
+			}
+
+			// find existing index
+			spec, err := findIndex(existing, index)
+			if err != nil {
+				return nil, err
+			} else if spec == nil {
+				report.Missing = append(report.Missing, index)
+				continue
+			}
+
+			// mark index
+			declared[spec.Name] = true
+		}
+
+		// add indexes
+		for i, spec := range existing {
+			// prepare usage
+			usage := IndexUsage{
+				Name:     spec.Name,
+				Keys:     spec.Key,
+				Declared: declared[spec.Name] || spec.Name == "_id_",
+			}
+
+			// add statistics
+			if stat := stats[spec.Name]; stat != nil {
+				usage.Ops = stat.Ops
+				usage.Since = stat.Since
+			}
+			usage.Unused = report.Stats && usage.Ops == 0 && spec.Name != "_id_"
+
+			// find covering index
+			for j, other := range existing {
+				if i != j && coversIndex(other, spec) {
+					usage.CoveredBy = other.Name
+					break
+				}
+			}
+
+			// add usage
+			report.Indexes = append(report.Indexes, usage)
+		}
+
+		// add report
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func indexStats(ctx context.Context, store *Store, model Model) (map[string]*IndexUsage, error) {
+	// aggregate statistics
+	csr, err := store.C(model).Native().Aggregate(ctx, []bson.M{
+		{"$indexStats": bson.M{}},
+	})
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// decode statistics
+	var list []struct {
+		Name     string `bson:"name"`
+		Accesses struct {
+			Ops   int64     `bson:"ops"`
+			Since time.Time `bson:"since"`
+		} `bson:"accesses"`
+	}
+	err = csr.All(ctx, &list)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// merge statistics of all hosts
+	stats := map[string]*IndexUsage{}
+	for _, item := range list {
+		stat := stats[item.Name]
+		if stat == nil {
+			stat = &IndexUsage{Name: item.Name, Since: item.Accesses.Since}
+			stats[item.Name] = stat
+		}
+		stat.Ops += item.Accesses.Ops
+		if item.Accesses.Since.Before(stat.Since) {
+			stat.Since = item.Accesses.Since
+		}
+	}
+
+	return stats, nil
+}
+
+func coversIndex(index, other indexSpec) bool {
+	// the primary, unique, partial and expiring indexes are never redundant
+	if other.Name == "_id_" || other.Unique || len(other.Partial) > 0 || other.Expiry > 0 {
+		return false
+	}
+
+	// check partial filter
+	if len(index.Partial) > 0 {
+		return false
+	}
+
+	// check collations
+	if (index.Collation == nil) != (other.Collation == nil) {
+		return false
+	} else if index.Collation != nil && *index.Collation != *other.Collation {
+		return false
+	}
+
+	// check keys
+	if len(other.Key) >= len(index.Key) {
+		return false
+	}
+	for i, key := range other.Key {
+		if key.Key != index.Key[i].Key || toFloat(key.Value) == 0 || toFloat(key.Value) != toFloat(index.Key[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
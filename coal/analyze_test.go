@@ -0,0 +1,58 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestAnalyzeIndexes(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		meta := GetMeta(&postModel{})
+
+		err := tester.Store.C(&postModel{}).Native().Drop(nil)
+		assert.NoError(t, err)
+
+		reports, err := AnalyzeIndexes(nil, tester.Store, &postModel{})
+		assert.NoError(t, err)
+		assert.Len(t, reports, 1)
+		assert.Equal(t, meta.Indexes, reports[0].Missing)
+		assert.Empty(t, reports[0].Indexes)
+
+		err = EnsureIndexes(tester.Store, &postModel{})
+		assert.NoError(t, err)
+
+		_, err = tester.Store.C(&postModel{}).Native().Indexes().CreateOne(nil, mongo.IndexModel{
+			Keys: bson.D{{Key: "published", Value: 1}},
+		})
+		assert.NoError(t, err)
+
+		reports, err = AnalyzeIndexes(nil, tester.Store, &postModel{})
+		assert.NoError(t, err)
+		assert.Len(t, reports, 1)
+		assert.Empty(t, reports[0].Missing)
+		assert.Equal(t, []string{"published_1"}, reports[0].Undeclared())
+		assert.Equal(t, []string{"published_1"}, reports[0].Redundant())
+		assert.Equal(t, tester.Store.Supports(IndexStats), reports[0].Stats)
+		if reports[0].Stats {
+			assert.Contains(t, reports[0].Unused(), "published_1")
+		} else {
+			assert.Empty(t, reports[0].Unused())
+		}
+
+		for _, index := range reports[0].Indexes {
+			if index.Name == "published_1" {
+				assert.Equal(t, "published_1_title_1", index.CoveredBy)
+				assert.False(t, index.Declared)
+			} else {
+				assert.Empty(t, index.CoveredBy)
+				assert.True(t, index.Declared)
+			}
+		}
+
+		err = tester.Store.C(&postModel{}).Native().Drop(nil)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,107 @@
+package coal
+
+import (
+	"github.com/256dpi/lungo"
+	"github.com/256dpi/xo"
+)
+
+// Capabilities describes the features supported by a backend.
+type Capabilities int
+
+// The available capabilities.
+const (
+	// ClusterTimes is set if the backend reports the cluster time of operations
+	// and change streams can be started at a cluster time.
+	ClusterTimes Capabilities = 1 << iota
+
+	// SnapshotSessions is set if the backend supports snapshot sessions to
+	// perform isolated reads from secondaries.
+	SnapshotSessions
+
+	// Aggregations is set if the backend supports aggregation pipelines and
+	// updates with aggregation pipelines.
+	Aggregations
+
+	// Validators is set if the backend supports JSON schema validators.
+	Validators
+
+	// Collations is set if the backend supports collations.
+	Collations
+
+	// SpecialIndexes is set if the backend supports text, geospatial and
+	// hashed indexes.
+	SpecialIndexes
+
+	// PreImages is set if the backend supports change stream pre-images.
+	PreImages
+
+	// CollectionStats is set if the backend supports the collection
+	// statistics aggregation stage.
+	CollectionStats
+
+	// IndexStats is set if the backend supports the index statistics
+	// aggregation stage.
+	IndexStats
+)
+
+// AllCapabilities contains all capabilities and is used for MongoDB.
+const AllCapabilities = ClusterTimes | SnapshotSessions | Aggregations | Validators | Collations | SpecialIndexes | PreImages | CollectionStats | IndexStats
+
+// Has returns whether the provided capabilities are all present.
+func (c Capabilities) Has(caps Capabilities) bool {
+	return c&caps == caps
+}
+
+// Backend is the minimal interface implemented by store backends. Alternative
+// backends e.g. for MongoDB compatible databases with a reduced feature set can
+// be used by providing a client and the supported capabilities.
+type Backend interface {
+	// Client returns the client used to access the database.
+	Client() lungo.IClient
+
+	// Capabilities returns the capabilities supported by the backend.
+	Capabilities() Capabilities
+
+	// Close closes the backend.
+	Close() error
+}
+
+type backend struct {
+	client lungo.IClient
+	caps   Capabilities
+	close  func()
+}
+
+// NewBackend creates and returns a backend for the specified client that
+// supports the provided capabilities. The optional close function is called
+// after the client has been disconnected.
+func NewBackend(client lungo.IClient, caps Capabilities, close func()) Backend {
+	return &backend{
+		client: client,
+		caps:   caps,
+		close:  close,
+	}
+}
+
+func (b *backend) Client() lungo.IClient {
+	return b.client
+}
+
+func (b *backend) Capabilities() Capabilities {
+	return b.caps
+}
+
+func (b *backend) Close() error {
+	// disconnect client
+	err := b.client.Disconnect(nil)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// call close
+	if b.close != nil {
+		b.close()
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/256dpi/lungo"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestCapabilities(t *testing.T) {
+	caps := ClusterTimes | Collations
+	assert.True(t, caps.Has(ClusterTimes))
+	assert.True(t, caps.Has(ClusterTimes|Collations))
+	assert.False(t, caps.Has(ClusterTimes|Aggregations))
+	assert.True(t, AllCapabilities.Has(caps))
+	assert.True(t, Capabilities(0).Has(0))
+}
+
+func TestNewBackendStore(t *testing.T) {
+	client, engine, err := lungo.Open(nil, lungo.Options{
+		Store: lungo.NewMemoryStore(),
+	})
+	assert.NoError(t, err)
+
+	var closed bool
+	backend := NewBackend(client, Validators, func() {
+		engine.Close()
+		closed = true
+	})
+
+	store := NewBackendStore(backend, "test-fire-coal", nil)
+	assert.Equal(t, backend, store.Backend())
+	assert.Equal(t, client, store.Client())
+	assert.True(t, store.Lungo())
+	assert.True(t, store.Supports(Validators))
+	assert.False(t, store.Supports(Validators|Collations))
+	assert.False(t, store.Supports(ClusterTimes))
+
+	ts, err := store.ClusterTime(nil)
+	assert.NoError(t, err)
+	assert.NotZero(t, ts)
+
+	err = store.Close()
+	assert.NoError(t, err)
+	assert.True(t, closed)
+}
+
+func TestNewStoreCapabilities(t *testing.T) {
+	assert.False(t, lungoStore.Supports(ClusterTimes))
+	assert.False(t, lungoStore.Supports(Aggregations))
+
+	client, err := lungo.Connect(nil, options.Client().ApplyURI("mongodb://0.0.0.0"))
+	assert.NoError(t, err)
+
+	store := NewStore(client, "test-fire-coal", nil, nil)
+	assert.False(t, store.Lungo())
+	assert.True(t, store.Supports(AllCapabilities))
+
+	err = store.Close()
+	assert.NoError(t, err)
+}
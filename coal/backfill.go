@@ -0,0 +1,172 @@
+package coal
+
+import (
+	"context"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Backfill describes the backfill of a newly added field with a default value.
+type Backfill struct {
+	// The model.
+	Model Model
+
+	// The raw field e.g. "tire_size".
+	RawField string
+
+	// The default value.
+	Value interface{}
+
+	// The number of documents updated per batch.
+	//
+	// Default: 1000.
+	BatchSize int64
+
+	// The delay between batches to throttle the load on the database.
+	//
+	// Default: 100ms.
+	Delay time.Duration
+
+	// The function called after each batch with the number of updated
+	// documents and the number of documents that were missing the field
+	// when the backfill started.
+	Progress func(done, total int64)
+}
+
+// BackfillField will set the default value on all documents that do not have
+// the field yet. The documents are updated in batches of increasing IDs with a
+// delay between batches. As only documents missing the field are updated, an
+// interrupted backfill is resumed by running it again. It will return the
+// number of matched and modified documents.
+func BackfillField(ctx context.Context, store *Store, backfill Backfill) (int64, int64, error) {
+	// set default batch size
+	if backfill.BatchSize <= 0 {
+		backfill.BatchSize = 1000
+	}
+
+	// set default delay
+	if backfill.Delay == 0 {
+		backfill.Delay = 100 * time.Millisecond
+	}
+
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/BackfillField")
+	span.Tag("field", backfill.RawField)
+	defer span.End()
+
+	// get collection
+	coll := store.C(backfill.Model)
+
+	// count documents
+	total, err := coll.CountDocuments(ctx, bson.M{
+		backfill.RawField: bson.M{
+			"$exists": false,
+		},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// process batches
+	var last ID
+	var matched, modified int64
+	for {
+		// prepare filter
+		filter := bson.M{
+			backfill.RawField: bson.M{
+				"$exists": false,
+			},
+		}
+		if !last.IsZero() {
+			filter["_id"] = bson.M{
+				"$gt": last,
+			}
+		}
+
+		// find batch
+		iter, err := coll.Find(ctx, filter, options.Find().
+			SetSort(bson.M{"_id": 1}).
+			SetLimit(backfill.BatchSize).
+			SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return matched, modified, err
+		}
+
+		// collect IDs
+		var docs []struct {
+			ID ID `bson:"_id"`
+		}
+		err = iter.All(&docs)
+		if err != nil {
+			return matched, modified, err
+		}
+
+		// check batch
+		if len(docs) == 0 {
+			break
+		}
+
+		// get IDs
+		ids := make([]ID, 0, len(docs))
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		last = ids[len(ids)-1]
+
+		// update batch
+		res, err := coll.UpdateMany(ctx, bson.M{
+			"_id": bson.M{
+				"$in": ids,
+			},
+			backfill.RawField: bson.M{
+				"$exists": false,
+			},
+		}, bson.M{
+			"$set": bson.M{
+				backfill.RawField: backfill.Value,
+			},
+		})
+		if err != nil {
+			return matched, modified, err
+		}
+
+		// update counters
+		matched += res.MatchedCount
+		modified += res.ModifiedCount
+
+		// report progress
+		if backfill.Progress != nil {
+			backfill.Progress(modified, total)
+		}
+
+		// check end
+		if int64(len(docs)) < backfill.BatchSize {
+			break
+		}
+
+		// throttle
+		select {
+		case <-time.After(backfill.Delay):
+		case <-ctx.Done():
+			return matched, modified, xo.W(ctx.Err())
+		}
+	}
+
+	return matched, modified, nil
+}
+
+// Migration will return a synchronous migration with the provided name and
+// timeout that runs the backfill. Once applied, code can rely on the field
+// being present in all documents, given newly created documents set it.
+func (b Backfill) Migration(name string, timeout time.Duration) Migration {
+	return Migration{
+		Name:    name,
+		Timeout: timeout,
+		Migrator: func(ctx context.Context, store *Store) (int64, int64, error) {
+			return BackfillField(ctx, store, b)
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package coal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBackfillField(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		for i := 0; i < 5; i++ {
+			tester.Insert(&fooModel{})
+		}
+		tester.Insert(&fooModel{Name: "bar"})
+
+		var progress [][2]int64
+		matched, modified, err := BackfillField(nil, tester.Store, Backfill{
+			Model:     &fooModel{},
+			RawField:  "name",
+			Value:     "foo",
+			BatchSize: 2,
+			Delay:     time.Millisecond,
+			Progress: func(done, total int64) {
+				progress = append(progress, [2]int64{done, total})
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), matched)
+		assert.Equal(t, int64(5), modified)
+		assert.Equal(t, [][2]int64{{2, 5}, {4, 5}, {5, 5}}, progress)
+
+		n, err := tester.Store.C(&fooModel{}).CountDocuments(nil, bson.M{"name": "foo"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+
+		matched, modified, err = BackfillField(nil, tester.Store, Backfill{
+			Model:    &fooModel{},
+			RawField: "name",
+			Value:    "foo",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), matched)
+		assert.Equal(t, int64(0), modified)
+	})
+}
+
+func TestBackfillMigration(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Insert(&fooModel{})
+		tester.Insert(&fooModel{})
+
+		m := NewMigrator()
+		m.Add(Backfill{
+			Model:    &fooModel{},
+			RawField: "body",
+			Value:    "default",
+		}.Migration("backfill-body", 0))
+
+		num, err := m.Apply(context.Background(), tester.Store, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, num)
+
+		record := tester.FindLast(&AppliedMigration{}).(*AppliedMigration)
+		assert.Equal(t, "backfill-body", record.Name)
+		assert.Equal(t, int64(2), record.Modified)
+
+		n, err := tester.Store.C(&fooModel{}).CountDocuments(nil, bson.M{"body": "default"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+	})
+}
@@ -0,0 +1,239 @@
+package coal
+
+import (
+	"context"
+	"time"
+
+	"github.com/256dpi/lungo"
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Change is a single change delivered as part of a batch.
+type Change struct {
+	// The event, either Created, Updated or Deleted.
+	Event Event
+
+	// The document ID.
+	ID ID
+
+	// The document for created and updated events.
+	Model Model
+
+	// The document before the change for updated and deleted events. Only
+	// available if pre-images are enabled for the collection.
+	Before Model
+
+	// The time of the change.
+	Time primitive.Timestamp
+
+	// The resume token of the change.
+	Token []byte
+}
+
+// BatchReceiver is a callback that receives batched stream events. Batches of
+// changes are delivered with the Changed event and the resume token of the
+// last change. All other events are delivered without changes.
+type BatchReceiver func(event Event, changes []Change, err error, token []byte) error
+
+// Batching configures the batched delivery of stream events.
+type Batching struct {
+	// The maximum number of changes delivered per batch.
+	//
+	// Default: 100.
+	MaxSize int
+
+	// The maximum time changes are buffered before the batch is delivered.
+	//
+	// Default: 100ms.
+	MaxDelay time.Duration
+
+	// The lag of a delivered batch behind the collection after which
+	// backpressure is signaled.
+	//
+	// Default: 10s.
+	MaxLag time.Duration
+
+	// The function called with the current lag if a batch has been delivered
+	// later than the configured maximum lag. Consumers may use it to shed load
+	// or to scale out. While the function is called the stream does not read
+	// further changes.
+	Backpressure func(lag time.Duration)
+}
+
+// OpenBatchedStream will open a stream like OpenStream but deliver changes in
+// batches to the specified receiver. A batch is delivered once it reached the
+// maximum size or the oldest change has been buffered for the maximum delay.
+// Changes are only read from the underlying change stream while the receiver
+// is not processing a batch, which naturally applies backpressure to the
+// stream. If the receiver falls behind the collection for more than the
+// maximum lag, the backpressure callback is called as well.
+//
+// The internally stored resume token is only advanced once a batch has been
+// delivered. Therefore, if a batch fails, all changes of the batch are
+// delivered again once the stream has been resumed.
+func OpenBatchedStream(store *Store, model Model, token []byte, batching Batching, receiver BatchReceiver) *Stream {
+	// set defaults
+	if batching.MaxSize <= 0 {
+		batching.MaxSize = 100
+	}
+	if batching.MaxDelay <= 0 {
+		batching.MaxDelay = 100 * time.Millisecond
+	}
+	if batching.MaxLag <= 0 {
+		batching.MaxLag = 10 * time.Second
+	}
+
+	// create stream
+	s := newStream(store, model, token, func(event Event, _ ID, _ Model, err error, token []byte) error {
+		return receiver(event, nil, err, token)
+	})
+
+	// set batching and enable pre-images
+	s.batching = &batching
+	s.batches = receiver
+	s.preImages = true
+
+	// open stream
+	s.tomb.Go(s.open)
+
+	return s
+}
+
+func (s *Stream) consume(ctx context.Context, cs lungo.IChangeStream) error {
+	// prepare batch
+	var batch []Change
+	var pending []*change
+	var since time.Time
+
+	// prepare flush
+	flush := func() error {
+		// deliver batch
+		if len(batch) > 0 {
+			err := s.batches(Changed, batch, nil, batch[len(batch)-1].Token)
+			if err != nil {
+				return xo.W(err)
+			}
+		}
+
+		// signal backpressure
+		if s.batching.Backpressure != nil && len(pending) > 0 {
+			lag := time.Since(time.Unix(int64(pending[0].ClusterTime.T), 0))
+			if lag > s.batching.MaxLag {
+				s.batching.Backpressure(lag)
+			}
+		}
+
+		// save token
+		if len(pending) > 0 {
+			s.token = pending[len(pending)-1].ResumeToken
+		}
+
+		// release waiters
+		for _, ch := range pending {
+			s.release(ch.DocumentKey.ID, ch.ClusterTime)
+		}
+
+		// reset batch
+		batch = nil
+		pending = nil
+
+		return nil
+	}
+
+	for {
+		// get next change, only block if no changes are pending
+		var ok bool
+		if len(pending) == 0 {
+			ok = cs.Next(ctx)
+		} else {
+			ok = cs.TryNext(ctx)
+		}
+
+		// handle missing change
+		if !ok {
+			// check error
+			if cs.Err() != nil || ctx.Err() != nil {
+				break
+			}
+
+			// flush if due
+			remaining := s.batching.MaxDelay - time.Since(since)
+			if remaining <= 0 {
+				err := flush()
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			// await next attempt
+			if remaining > 10*time.Millisecond {
+				remaining = 10 * time.Millisecond
+			}
+			select {
+			case <-time.After(remaining):
+			case <-ctx.Done():
+			}
+
+			continue
+		}
+
+		// decode result
+		var ch change
+		err := cs.Decode(&ch)
+		if err != nil {
+			return xo.W(err)
+		}
+
+		// decode change
+		event, doc, skip, err := s.decode(&ch)
+		if err != nil {
+			return err
+		}
+
+		// decode before
+		var before Model
+		if !skip {
+			before, err = s.before(&ch)
+			if err != nil {
+				return err
+			}
+		}
+
+		// set time of first pending change
+		if len(pending) == 0 {
+			since = time.Now()
+		}
+
+		// add change
+		pending = append(pending, &ch)
+		if !skip {
+			batch = append(batch, Change{
+				Event:  event,
+				ID:     ch.DocumentKey.ID,
+				Model:  doc,
+				Before: before,
+				Time:   ch.ClusterTime,
+				Token:  ch.ResumeToken,
+			})
+		}
+
+		// flush if full or due, as changes may arrive faster than the delay
+		if len(batch) >= s.batching.MaxSize || time.Since(since) >= s.batching.MaxDelay {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// close stream and check error
+	err := cs.Close(ctx)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package coal
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchedStream(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		time.Sleep(100 * time.Millisecond)
+
+		open := make(chan struct{})
+		done := make(chan struct{})
+
+		var mutex sync.Mutex
+		var batches [][]Change
+		stream := OpenBatchedStream(tester.Store, &postModel{}, nil, Batching{
+			MaxSize:  2,
+			MaxDelay: 50 * time.Millisecond,
+		}, func(e Event, changes []Change, err error, token []byte) error {
+			switch e {
+			case Opened:
+				assert.Nil(t, changes)
+				close(open)
+			case Changed:
+				assert.NotEmpty(t, changes)
+				assert.Equal(t, changes[len(changes)-1].Token, token)
+				mutex.Lock()
+				batches = append(batches, changes)
+				mutex.Unlock()
+			case Stopped:
+				close(done)
+			}
+
+			return nil
+		})
+
+		<-open
+
+		now, err := tester.Store.ClusterTime(nil)
+		assert.NoError(t, err)
+
+		post1 := tester.Insert(&postModel{Title: "foo"}).(*postModel)
+		post2 := tester.Insert(&postModel{Title: "bar"}).(*postModel)
+		post3 := tester.Insert(&postModel{Title: "baz"}).(*postModel)
+
+		err = stream.Await(nil, post3.ID(), now)
+		assert.NoError(t, err)
+
+		mutex.Lock()
+		assert.Len(t, batches, 2)
+		assert.Len(t, batches[0], 2)
+		assert.Len(t, batches[1], 1)
+		assert.Equal(t, Created, batches[0][0].Event)
+		assert.Equal(t, post1.ID(), batches[0][0].ID)
+		assert.Equal(t, "foo", batches[0][0].Model.(*postModel).Title)
+		assert.Equal(t, post2.ID(), batches[0][1].ID)
+		assert.Equal(t, post3.ID(), batches[1][0].ID)
+		mutex.Unlock()
+
+		now, err = tester.Store.ClusterTime(nil)
+		assert.NoError(t, err)
+
+		tester.Delete(post1)
+
+		err = stream.Await(nil, post1.ID(), now)
+		assert.NoError(t, err)
+
+		mutex.Lock()
+		assert.Len(t, batches, 3)
+		assert.Equal(t, []Change{{
+			Event: Deleted,
+			ID:    post1.ID(),
+			Time:  batches[2][0].Time,
+			Token: batches[2][0].Token,
+		}}, batches[2])
+		mutex.Unlock()
+
+		stream.Close()
+
+		<-done
+	})
+}
+
+func TestBatchedStreamBackpressure(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		time.Sleep(100 * time.Millisecond)
+
+		open := make(chan struct{})
+		lagged := make(chan time.Duration, 1)
+
+		stream := OpenBatchedStream(tester.Store, &postModel{}, nil, Batching{
+			MaxLag: time.Nanosecond,
+			Backpressure: func(lag time.Duration) {
+				select {
+				case lagged <- lag:
+				default:
+				}
+			},
+		}, func(e Event, changes []Change, err error, token []byte) error {
+			if e == Opened {
+				close(open)
+			}
+
+			return nil
+		})
+
+		<-open
+
+		tester.Insert(&postModel{Title: "foo"})
+
+		select {
+		case lag := <-lagged:
+			assert.True(t, lag > 0)
+		case <-time.After(2 * time.Second):
+			assert.Fail(t, "missing backpressure")
+		}
+
+		stream.Close()
+	})
+}
+
+func TestBatchedStreamMaxDelay(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		time.Sleep(100 * time.Millisecond)
+
+		open := make(chan struct{})
+		first := make(chan struct{})
+
+		var mutex sync.Mutex
+		var batches [][]Change
+		stream := OpenBatchedStream(tester.Store, &postModel{}, nil, Batching{
+			MaxSize:  10000,
+			MaxDelay: time.Millisecond,
+		}, func(e Event, changes []Change, err error, token []byte) error {
+			switch e {
+			case Opened:
+				close(open)
+			case Changed:
+				mutex.Lock()
+				batches = append(batches, changes)
+				mutex.Unlock()
+
+				// block first batch until the backlog has been created
+				if len(batches) == 1 {
+					<-first
+				}
+			}
+
+			return nil
+		})
+
+		<-open
+
+		tester.Insert(&postModel{Title: "first"})
+
+		now, err := tester.Store.ClusterTime(nil)
+		assert.NoError(t, err)
+
+		// create backlog
+		var last *postModel
+		for i := 0; i < 1000; i++ {
+			last = tester.Insert(&postModel{Title: "post"}).(*postModel)
+		}
+		close(first)
+
+		err = stream.Await(nil, last.ID(), now)
+		assert.NoError(t, err)
+
+		// backlog is delivered in multiple batches
+		mutex.Lock()
+		assert.True(t, len(batches) > 2)
+		mutex.Unlock()
+
+		stream.Close()
+	})
+}
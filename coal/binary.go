@@ -0,0 +1,413 @@
+package coal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/256dpi/lungo"
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// ErrMissingBinary is returned if a binary field does not reference a file.
+var ErrMissingBinary = xo.BF("missing binary")
+
+var binaryType = reflect.TypeOf(Binary{})
+var optBinaryType = reflect.TypeOf(&Binary{})
+
+// Binary is a field type that references a binary payload stored in a GridFS
+// bucket. Only the reference is stored in the document while the payload is
+// written and read using Binaries.
+type Binary struct {
+	// The ID of the file in the bucket.
+	File ID `json:"file" bson:"file"`
+
+	// The size of the payload.
+	Size int64 `json:"size" bson:"size"`
+}
+
+// IsZero returns whether the binary does not reference a file.
+func (b Binary) IsZero() bool {
+	return b.File.IsZero()
+}
+
+// Binaries manages the payloads of binary fields in a GridFS bucket.
+type Binaries struct {
+	store  *Store
+	bucket *lungo.Bucket
+}
+
+// NewBinaries creates and returns a new binaries manager that stores payloads
+// in the GridFS bucket with the specified name e.g. "binaries".
+func NewBinaries(store *Store, name string) *Binaries {
+	return &Binaries{
+		store:  store,
+		bucket: lungo.NewBucket(store.DB(), options.GridFSBucket().SetName(name)),
+	}
+}
+
+// Bucket returns the underlying GridFS bucket.
+func (b *Binaries) Bucket() *lungo.Bucket {
+	return b.bucket
+}
+
+// EnsureIndexes will ensure that the indexes of the bucket exist.
+func (b *Binaries) EnsureIndexes(ctx context.Context) error {
+	return xo.W(b.bucket.EnsureIndexes(ctx, false))
+}
+
+// Write will store the payload read from the provided reader as a new file and
+// set a reference to it on the specified binary field of the model. The model
+// must be saved afterwards to persist the reference. Previously referenced
+// files are removed by Collect once they are no longer referenced.
+func (b *Binaries) Write(ctx context.Context, model Model, field string, r io.Reader) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Binaries.Write")
+	defer span.End()
+
+	// check field
+	optional := binaryField(model, field).Optional
+
+	// open stream
+	id := New()
+	stream, err := b.bucket.OpenUploadStreamWithID(ctx, id, "")
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// write payload
+	size, err := io.Copy(stream, r)
+	if err != nil {
+		_ = stream.Abort()
+		return xo.W(err)
+	}
+
+	// close stream
+	err = stream.Close()
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// set reference
+	binary := Binary{
+		File: id,
+		Size: size,
+	}
+	if optional {
+		stick.MustSet(model, field, &binary)
+	} else {
+		stick.MustSet(model, field, binary)
+	}
+
+	return nil
+}
+
+// Open will open a stream to read the payload of the specified binary field
+// of the model. The stream must be closed by the caller.
+func (b *Binaries) Open(ctx context.Context, model Model, field string) (*lungo.DownloadStream, error) {
+	// get binary
+	binary := getBinary(model, field)
+	if binary.IsZero() {
+		return nil, ErrMissingBinary.Wrap()
+	}
+
+	// open stream
+	stream, err := b.bucket.OpenDownloadStream(ctx, binary.File)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	return stream, nil
+}
+
+// Read will read the payload of the specified binary field of the model and
+// write it to the provided writer.
+func (b *Binaries) Read(ctx context.Context, model Model, field string, w io.Writer) (int64, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Binaries.Read")
+	defer span.End()
+
+	// get binary
+	binary := getBinary(model, field)
+	if binary.IsZero() {
+		return 0, ErrMissingBinary.Wrap()
+	}
+
+	// download payload
+	n, err := b.bucket.DownloadToStream(ctx, binary.File, w)
+	if err != nil {
+		return n, xo.W(err)
+	}
+
+	return n, nil
+}
+
+// the number of candidate files that are checked at once by Collect
+const collectBatchSize = 1000
+
+// Collect will delete files that have been uploaded at least the specified age
+// ago and are not referenced by the binary fields of the provided models. The age
+// protects recent uploads whose models have not been saved yet. Binary fields
+// are also found in items and nested structs. Candidates are checked in batches
+// while only the fields holding references are loaded. It returns the number
+// of deleted files.
+//
+// Note: Binary fields within maps are not supported and cause a panic.
+func (b *Binaries) Collect(ctx context.Context, age time.Duration, models ...Model) (int, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Binaries.Collect")
+	defer span.End()
+
+	// get reference paths
+	paths := make([][]string, len(models))
+	for i, model := range models {
+		for _, field := range GetMeta(model).OrderedFields {
+			paths[i] = appendBinaryPaths(paths[i], &field.ItemField, "", nil)
+		}
+	}
+
+	// find candidates
+	csr, err := b.bucket.Find(ctx, bson.M{
+		"uploadDate": bson.M{
+			"$lte": time.Now().Add(-age),
+		},
+	}, options.GridFSFind().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, xo.W(err)
+	}
+
+	// ensure close
+	defer csr.Close(ctx)
+
+	// check candidates in batches
+	var deleted int
+	batch := make([]ID, 0, collectBatchSize)
+	for {
+		// get next candidate
+		next := csr.Next(ctx)
+		if next {
+			var file struct {
+				ID interface{} `bson:"_id"`
+			}
+			err = csr.Decode(&file)
+			if err != nil {
+				return deleted, xo.W(err)
+			}
+			if id, ok := file.ID.(ID); ok {
+				batch = append(batch, id)
+			}
+		}
+
+		// check batch
+		if len(batch) > 0 && (!next || len(batch) >= collectBatchSize) {
+			n, err := b.collect(ctx, batch, models, paths)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+			batch = batch[:0]
+		}
+
+		// check end
+		if !next {
+			break
+		}
+	}
+
+	// check error
+	err = csr.Err()
+	if err != nil {
+		return deleted, xo.W(err)
+	}
+
+	return deleted, nil
+}
+
+func (b *Binaries) collect(ctx context.Context, ids []ID, models []Model, paths [][]string) (int, error) {
+	// prepare candidates
+	candidates := make(map[ID]bool, len(ids))
+	for _, id := range ids {
+		candidates[id] = true
+	}
+
+	// remove referenced files
+	for i, model := range models {
+		// check paths
+		if len(paths[i]) == 0 {
+			continue
+		}
+
+		// prepare filter and projection
+		filters := make(bson.A, 0, len(paths[i]))
+		projection := bson.M{}
+		for _, path := range paths[i] {
+			filters = append(filters, bson.M{
+				path: bson.M{
+					"$in": ids,
+				},
+			})
+			projection[strings.SplitN(path, ".", 2)[0]] = 1
+		}
+
+		// find referencing documents
+		iter, err := b.store.C(model).Find(ctx, bson.M{
+			"$or": filters,
+		}, options.Find().SetProjection(projection))
+		if err != nil {
+			return 0, err
+		}
+
+		// remove references
+		for iter.Next() {
+			// decode references
+			var raw bson.Raw
+			err = iter.Decode(&raw)
+			if err != nil {
+				iter.Close()
+				return 0, err
+			}
+			doc := GetMeta(model).Make()
+			err = bson.Unmarshal(raw, doc)
+			if err != nil {
+				iter.Close()
+				return 0, xo.W(err)
+			}
+
+			// remove candidates
+			walkBinaries(reflect.ValueOf(doc), func(binary Binary) {
+				delete(candidates, binary.File)
+			})
+		}
+
+		// close iterator
+		iter.Close()
+		err = iter.Error()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// delete orphaned files
+	var deleted int
+	for _, id := range ids {
+		if candidates[id] {
+			err := b.bucket.Delete(ctx, id)
+			if err != nil {
+				return deleted, xo.W(err)
+			}
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func appendBinaryPaths(paths []string, field *ItemField, prefix string, seen map[*ItemMeta]bool) []string {
+	// check field
+	if field.BSONKey == "" {
+		return paths
+	}
+
+	// get path
+	path := prefix + field.BSONKey
+
+	// unwrap pointer, slice and map
+	typ := field.Type
+	var mapped bool
+	for typ != binaryType && (typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map) {
+		mapped = mapped || typ.Kind() == reflect.Map
+		typ = typ.Elem()
+	}
+
+	// handle binaries
+	if typ == binaryType {
+		if mapped {
+			panic(fmt.Sprintf(`coal: unsupported binary field in map "%s"`, path))
+		}
+		return append(paths, path+".file")
+	}
+
+	// get sub meta
+	sub := field.ItemMeta
+	if sub == nil {
+		sub = field.Nested
+	}
+	if sub == nil || seen[sub] {
+		return paths
+	}
+
+	// add sub paths
+	if seen == nil {
+		seen = map[*ItemMeta]bool{}
+	}
+	seen[sub] = true
+	length := len(paths)
+	for _, subField := range sub.OrderedFields {
+		paths = appendBinaryPaths(paths, subField, path+".", seen)
+	}
+	delete(seen, sub)
+
+	// check map
+	if mapped && len(paths) > length {
+		panic(fmt.Sprintf(`coal: unsupported binary field in map "%s"`, paths[length]))
+	}
+
+	return paths
+}
+
+func walkBinaries(value reflect.Value, fn func(Binary)) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			walkBinaries(value.Elem(), fn)
+		}
+	case reflect.Struct:
+		if value.Type() == binaryType {
+			fn(value.Interface().(Binary))
+			return
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				walkBinaries(value.Field(i), fn)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			walkBinaries(value.Index(i), fn)
+		}
+	}
+}
+
+func binaryField(model Model, name string) *Field {
+	// get field
+	field := GetMeta(model).Fields[name]
+	if field == nil || (field.Type != binaryType && field.Type != optBinaryType) {
+		panic(fmt.Sprintf(`coal: expected binary field "%s"`, name))
+	}
+
+	return field
+}
+
+func getBinary(model Model, name string) Binary {
+	// check field
+	binaryField(model, name)
+
+	// get binary
+	switch value := stick.MustGet(model, name).(type) {
+	case Binary:
+		return value
+	case *Binary:
+		if value != nil {
+			return *value
+		}
+	}
+
+	return Binary{}
+}
@@ -0,0 +1,98 @@
+package coal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaries(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		binaries := NewBinaries(tester.Store, "test-binaries")
+		assert.NoError(t, binaries.Bucket().Drop(nil))
+		assert.NoError(t, tester.Store.C(&binaryModel{}).Native().Drop(nil))
+
+		err := binaries.EnsureIndexes(nil)
+		assert.NoError(t, err)
+
+		model := &binaryModel{Base: B()}
+
+		_, err = binaries.Read(nil, model, "Payload", &bytes.Buffer{})
+		assert.True(t, ErrMissingBinary.Is(err))
+
+		err = binaries.Write(nil, model, "Payload", strings.NewReader("Hello World!"))
+		assert.NoError(t, err)
+		assert.False(t, model.Payload.IsZero())
+		assert.Equal(t, int64(12), model.Payload.Size)
+
+		err = binaries.Write(nil, model, "Preview", strings.NewReader("Hello"))
+		assert.NoError(t, err)
+		assert.NotNil(t, model.Preview)
+		assert.Equal(t, int64(5), model.Preview.Size)
+
+		tester.Insert(model)
+
+		var buf bytes.Buffer
+		n, err := binaries.Read(nil, model, "Payload", &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(12), n)
+		assert.Equal(t, "Hello World!", buf.String())
+
+		stream, err := binaries.Open(nil, model, "Preview")
+		assert.NoError(t, err)
+		buf.Reset()
+		_, err = buf.ReadFrom(stream)
+		assert.NoError(t, err)
+		assert.NoError(t, stream.Close())
+		assert.Equal(t, "Hello", buf.String())
+
+		// replace payload
+		old := model.Payload
+		err = binaries.Write(nil, model, "Payload", strings.NewReader("Hello Again!"))
+		assert.NoError(t, err)
+		assert.NotEqual(t, old, model.Payload)
+		tester.Replace(model)
+
+		// nested reference
+		nested := &binaryModel{}
+		err = binaries.Write(nil, nested, "Payload", strings.NewReader("Nested"))
+		assert.NoError(t, err)
+		model.Attachments = []binaryAttachment{{Name: "nested", File: nested.Payload}}
+		tester.Replace(model)
+
+		// unsaved upload
+		orphan := &binaryModel{Base: B()}
+		err = binaries.Write(nil, orphan, "Payload", strings.NewReader("Orphan"))
+		assert.NoError(t, err)
+
+		n2, err := binaries.Collect(nil, time.Hour, &binaryModel{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n2)
+
+		n2, err = binaries.Collect(nil, 0, &binaryModel{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n2)
+
+		_, err = binaries.Read(nil, &binaryModel{Payload: old}, "Payload", &buf)
+		assert.Error(t, err)
+
+		buf.Reset()
+		_, err = binaries.Read(nil, model, "Payload", &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello Again!", buf.String())
+
+		buf.Reset()
+		_, err = binaries.Read(nil, nested, "Payload", &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "Nested", buf.String())
+
+		assert.PanicsWithValue(t, `coal: expected binary field "ID"`, func() {
+			_ = binaries.Write(nil, model, "ID", strings.NewReader(""))
+		})
+
+		assert.NoError(t, tester.Store.C(&binaryModel{}).Native().Drop(nil))
+	})
+}
@@ -0,0 +1,364 @@
+package coal
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BridgeEvent is the normalized change event published by a bridge.
+type BridgeEvent struct {
+	// The operation, either "create", "update" or "delete".
+	Operation string `json:"operation"`
+
+	// The collection of the changed document.
+	Collection string `json:"collection"`
+
+	// The ID of the changed document.
+	Document ID `json:"document"`
+
+	// The document before the change. Only available for update and delete
+	// operations if pre-images are enabled for the collection.
+	Before bson.M `json:"before,omitempty"`
+
+	// The document after the change. Only available for create and update
+	// operations.
+	After bson.M `json:"after,omitempty"`
+
+	// The time the change has been applied.
+	Time time.Time `json:"time"`
+
+	// The time the event has been published.
+	Published time.Time `json:"published"`
+}
+
+// Publisher publishes bridge events to a message broker like Kafka or NATS.
+type Publisher interface {
+	// Publish should publish the payload to the specified subject (topic) and
+	// return when it has been acknowledged by the broker. The key is the hex
+	// encoded document ID and may be used to partition the messages.
+	Publish(ctx context.Context, subject, key string, payload []byte) error
+}
+
+// Checkpointer persists the position of bridge streams.
+type Checkpointer interface {
+	// Load should return the last saved token or nil if missing.
+	Load(ctx context.Context, name string) ([]byte, error)
+
+	// Save should persist the provided token.
+	Save(ctx context.Context, name string, token []byte) error
+}
+
+// Checkpoint stores the position of a bridge stream.
+type Checkpoint struct {
+	Base    `json:"-" bson:",inline" coal:"checkpoints"`
+	Name    string    `json:"name"`
+	Token   []byte    `json:"token"`
+	Updated time.Time `json:"updated-at" bson:"updated_at"`
+}
+
+// Validate implements the Model interface.
+func (c *Checkpoint) Validate() error {
+	// check name
+	if c.Name == "" {
+		return xo.SF("missing name")
+	}
+
+	return nil
+}
+
+// StoreCheckpointer is a checkpointer that persists tokens as checkpoint
+// documents in the provided store.
+type StoreCheckpointer struct {
+	// The store used to persist the checkpoints.
+	Store *Store
+
+	// The collection used to persist the checkpoints.
+	//
+	// Default: The collection of the Checkpoint model.
+	Collection string
+}
+
+// Load implements the Checkpointer interface.
+func (c *StoreCheckpointer) Load(ctx context.Context, name string) ([]byte, error) {
+	// find checkpoint
+	var checkpoint Checkpoint
+	err := c.coll().FindOne(ctx, bson.M{
+		"name": name,
+	}).Decode(&checkpoint)
+	if IsMissing(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return checkpoint.Token, nil
+}
+
+// Save implements the Checkpointer interface.
+func (c *StoreCheckpointer) Save(ctx context.Context, name string, token []byte) error {
+	// upsert checkpoint
+	_, err := c.coll().UpdateOne(ctx, bson.M{
+		"name": name,
+	}, bson.M{
+		"$set": bson.M{
+			"token":      token,
+			"updated_at": time.Now(),
+		},
+		"$setOnInsert": bson.M{
+			"_id": New(),
+		},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *StoreCheckpointer) coll() *Collection {
+	// use custom collection
+	if c.Collection != "" {
+		return c.Store.collection(c.Collection)
+	}
+
+	return c.Store.C(&Checkpoint{})
+}
+
+// BridgeOptions defines the options for a bridge.
+type BridgeOptions struct {
+	// The name of the bridge used to store checkpoints.
+	//
+	// Default: "bridge".
+	Name string
+
+	// The store to tail.
+	Store *Store
+
+	// The models to tail.
+	Models []Model
+
+	// The publisher used to publish events.
+	Publisher Publisher
+
+	// The checkpointer used to persist stream positions. If missing, the
+	// streams start from the current position when opened.
+	Checkpointer Checkpointer
+
+	// The subject prefix. The subject is the prefix followed by the
+	// collection name.
+	//
+	// Default: "coal.".
+	Prefix string
+
+	// The function used to encode events.
+	//
+	// Default: json.Marshal.
+	Encoder func(event *BridgeEvent) ([]byte, error)
+
+	// The delay after which a failed stream is resumed.
+	//
+	// Default: 1s.
+	RetryDelay time.Duration
+
+	// The callback that is called with errors.
+	Reporter func(error)
+}
+
+// Bridge tails the change streams of the configured models and publishes
+// normalized events to a message broker. Events are published with
+// at-least-once semantics: the position of a stream is only advanced and
+// checkpointed once an event has been acknowledged by the publisher. Failed
+// events are published again after the stream has been resumed.
+type Bridge struct {
+	options BridgeOptions
+	streams []*Stream
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// OpenBridge will open a bridge using the provided options. The last
+// checkpoints are loaded to resume the streams.
+func OpenBridge(options BridgeOptions) (*Bridge, error) {
+	// set default name
+	if options.Name == "" {
+		options.Name = "bridge"
+	}
+
+	// set default prefix
+	if options.Prefix == "" {
+		options.Prefix = "coal."
+	}
+
+	// set default encoder
+	if options.Encoder == nil {
+		options.Encoder = func(event *BridgeEvent) ([]byte, error) {
+			return json.Marshal(event)
+		}
+	}
+
+	// set default retry delay
+	if options.RetryDelay == 0 {
+		options.RetryDelay = time.Second
+	}
+
+	// check publisher
+	if options.Publisher == nil {
+		return nil, xo.F("missing publisher")
+	}
+
+	// create bridge
+	b := &Bridge{
+		options: options,
+		closed:  make(chan struct{}),
+	}
+
+	// load tokens
+	tokens := make([][]byte, len(options.Models))
+	if options.Checkpointer != nil {
+		for i, model := range options.Models {
+			token, err := options.Checkpointer.Load(context.Background(), b.checkpoint(model))
+			if err != nil {
+				return nil, xo.W(err)
+			}
+			tokens[i] = token
+		}
+	}
+
+	// get cluster time to not miss events that fail before a stream has
+	// received its first token (lungo can only resume from known tokens)
+	var startAt *primitive.Timestamp
+	if options.Store.Supports(ClusterTimes) {
+		now, err := options.Store.ClusterTime(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		startAt = &now
+	}
+
+	// open streams
+	for i, model := range options.Models {
+		b.streams = append(b.streams, b.open(model, tokens[i], startAt))
+	}
+
+	return b, nil
+}
+
+// Close will close the bridge.
+func (b *Bridge) Close() {
+	// signal close
+	b.once.Do(func() {
+		close(b.closed)
+	})
+
+	// close streams
+	for _, stream := range b.streams {
+		stream.Close()
+	}
+}
+
+func (b *Bridge) checkpoint(model Model) string {
+	return b.options.Name + "/" + GetMeta(model).Collection
+}
+
+func (b *Bridge) open(model Model, token []byte, startAt *primitive.Timestamp) *Stream {
+	// prepare stream
+	var stream *Stream
+	stream = newStream(b.options.Store, model, token, func(event Event, id ID, _ Model, err error, token []byte) error {
+		// handle events
+		switch event {
+		case Created, Updated, Deleted:
+			return b.publish(stream.tomb.Context(nil), stream.current, event, model, id, token)
+		case Errored:
+			// report error
+			if b.options.Reporter != nil {
+				b.options.Reporter(err)
+			}
+
+			// delay resumption
+			select {
+			case <-time.After(b.options.RetryDelay):
+			case <-b.closed:
+				return ErrStop.Wrap()
+			}
+		}
+
+		return nil
+	})
+
+	// enable pre-images and set start
+	stream.preImages = true
+	stream.startAt = startAt
+
+	// open stream
+	stream.tomb.Go(stream.open)
+
+	return stream
+}
+
+func (b *Bridge) publish(ctx context.Context, ch *change, event Event, model Model, id ID, token []byte) error {
+	// get collection
+	collection := GetMeta(model).Collection
+
+	// prepare event
+	evt := &BridgeEvent{
+		Collection: collection,
+		Document:   id,
+		Time:       time.Unix(int64(ch.ClusterTime.T), 0).UTC(),
+		Published:  time.Now().UTC(),
+	}
+
+	// set operation
+	switch event {
+	case Created:
+		evt.Operation = "create"
+	case Updated:
+		evt.Operation = "update"
+	case Deleted:
+		evt.Operation = "delete"
+	}
+
+	// decode before
+	if len(ch.FullDocumentBeforeChange) > 0 {
+		err := bson.Unmarshal(ch.FullDocumentBeforeChange, &evt.Before)
+		if err != nil {
+			return xo.W(err)
+		}
+	}
+
+	// decode after
+	if event != Deleted && len(ch.FullDocument) > 0 {
+		err := bson.Unmarshal(ch.FullDocument, &evt.After)
+		if err != nil {
+			return xo.W(err)
+		}
+	}
+
+	// encode event
+	payload, err := b.options.Encoder(evt)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// publish event
+	err = b.options.Publisher.Publish(ctx, b.options.Prefix+collection, id.Hex(), payload)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// save checkpoint
+	if b.options.Checkpointer != nil {
+		err = b.options.Checkpointer.Save(ctx, b.checkpoint(model), token)
+		if err != nil {
+			return xo.W(err)
+		}
+	}
+
+	return nil
+}
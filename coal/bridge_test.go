@@ -0,0 +1,151 @@
+package coal
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type testMessage struct {
+	subject string
+	key     string
+	event   BridgeEvent
+}
+
+type testPublisher struct {
+	mutex    sync.Mutex
+	failures int
+	messages chan testMessage
+}
+
+func (p *testPublisher) Publish(_ context.Context, subject, key string, payload []byte) error {
+	// check failures
+	p.mutex.Lock()
+	if p.failures > 0 {
+		p.failures--
+		p.mutex.Unlock()
+		return xo.F("publish failed")
+	}
+	p.mutex.Unlock()
+
+	// decode event
+	var event BridgeEvent
+	err := json.Unmarshal(payload, &event)
+	if err != nil {
+		return err
+	}
+
+	// add message
+	p.messages <- testMessage{subject: subject, key: key, event: event}
+
+	return nil
+}
+
+func (p *testPublisher) receive(t *testing.T) testMessage {
+	select {
+	case msg := <-p.messages:
+		return msg
+	case <-time.After(time.Second):
+		t.Error("missing message")
+		return testMessage{}
+	}
+}
+
+func TestBridge(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		time.Sleep(100 * time.Millisecond)
+
+		_, err := tester.Store.C(&Checkpoint{}).DeleteMany(nil, bson.M{})
+		assert.NoError(t, err)
+
+		publisher := &testPublisher{
+			messages: make(chan testMessage, 10),
+		}
+
+		var mutex sync.Mutex
+		var errs []error
+		options := BridgeOptions{
+			Store:        tester.Store,
+			Models:       []Model{&postModel{}},
+			Publisher:    publisher,
+			Checkpointer: &StoreCheckpointer{Store: tester.Store},
+			RetryDelay:   10 * time.Millisecond,
+			Reporter: func(err error) {
+				mutex.Lock()
+				errs = append(errs, err)
+				mutex.Unlock()
+			},
+		}
+
+		bridge, err := OpenBridge(options)
+		assert.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+
+		post := tester.Insert(&postModel{Title: "foo"}).(*postModel)
+
+		msg := publisher.receive(t)
+		assert.Equal(t, "coal.posts", msg.subject)
+		assert.Equal(t, post.ID().Hex(), msg.key)
+		assert.Equal(t, "create", msg.event.Operation)
+		assert.Equal(t, "posts", msg.event.Collection)
+		assert.Equal(t, post.ID(), msg.event.Document)
+		assert.Nil(t, msg.event.Before)
+		assert.Equal(t, "foo", msg.event.After["title"])
+		assert.False(t, msg.event.Time.IsZero())
+		assert.False(t, msg.event.Published.IsZero())
+		mutex.Lock()
+		assert.Empty(t, errs)
+		mutex.Unlock()
+
+		publisher.mutex.Lock()
+		publisher.failures = 1
+		publisher.mutex.Unlock()
+
+		post.Title = "bar"
+		tester.Replace(post)
+
+		msg = publisher.receive(t)
+		assert.Equal(t, "update", msg.event.Operation)
+		assert.Equal(t, "bar", msg.event.After["title"])
+		mutex.Lock()
+		assert.Len(t, errs, 1)
+		mutex.Unlock()
+
+		bridge.Close()
+
+		token, err := options.Checkpointer.Load(nil, "bridge/posts")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+
+		tester.Delete(post)
+
+		bridge, err = OpenBridge(options)
+		assert.NoError(t, err)
+
+		msg = publisher.receive(t)
+		assert.Equal(t, "delete", msg.event.Operation)
+		assert.Equal(t, post.ID(), msg.event.Document)
+		assert.Nil(t, msg.event.After)
+
+		bridge.Close()
+
+		select {
+		case msg = <-publisher.messages:
+			t.Errorf("unexpected message: %+v", msg)
+		default:
+		}
+	})
+}
+
+func TestBridgeMissingPublisher(t *testing.T) {
+	bridge, err := OpenBridge(BridgeOptions{})
+	assert.Error(t, err)
+	assert.Nil(t, bridge)
+}
@@ -0,0 +1,223 @@
+package coal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CacheStats contains the metrics of a cache.
+type CacheStats struct {
+	// The number of reads served from the cache.
+	Hits int64
+
+	// The number of reads that had to load the document.
+	Misses int64
+
+	// The number of entries removed due to changes.
+	Invalidations int64
+
+	// The number of currently cached entries.
+	Entries int
+}
+
+// HitRate returns the share of reads that have been served from the cache.
+func (s CacheStats) HitRate() float64 {
+	// check total
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
+}
+
+type cacheKey struct {
+	coll string
+	id   ID
+}
+
+// Cache is a read-through cache for single document reads performed using
+// Manager.Find. Entries are keyed by collection and document ID and are
+// invalidated using change streams. Only documents of the models provided
+// to NewCache are cached and only while the respective stream is open.
+// Reads that lock the document or run during a transaction bypass the cache.
+//
+// Note: Since invalidations are received asynchronously, reads may return a
+// stale document for a short period after a write.
+type Cache struct {
+	store   *Store
+	limit   int
+	streams []*Stream
+	metas   map[*Meta]bool
+
+	mutex   sync.Mutex
+	ready   map[*Meta]bool
+	entries map[cacheKey]bson.Raw
+	epochs  map[*Meta]uint64
+	stats   CacheStats
+}
+
+// NewCache creates and returns a cache for the provided models that is used
+// by all managers of the specified store. If the cache is full, a random entry
+// is evicted to make room for new entries. A zero limit disables the limit.
+// The cache must be closed to stop the underlying streams.
+func NewCache(store *Store, limit int, models ...Model) *Cache {
+	// create cache
+	cache := &Cache{
+		store:   store,
+		limit:   limit,
+		metas:   map[*Meta]bool{},
+		ready:   map[*Meta]bool{},
+		entries: map[cacheKey]bson.Raw{},
+		epochs:  map[*Meta]uint64{},
+	}
+
+	// open streams
+	for _, model := range models {
+		meta := GetMeta(model)
+		cache.metas[meta] = true
+		cache.streams = append(cache.streams, OpenStream(store, model, nil, func(event Event, id ID, _ Model, err error, _ []byte) error {
+			cache.handle(meta, event, id, err)
+			return nil
+		}))
+	}
+
+	// set cache
+	store.cache.Store(cache)
+
+	return cache
+}
+
+// Stats returns the current metrics of the cache.
+func (c *Cache) Stats() CacheStats {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// get stats
+	stats := c.stats
+	stats.Entries = len(c.entries)
+
+	return stats
+}
+
+// Purge will remove all entries from the cache.
+func (c *Cache) Purge() {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// remove entries
+	for meta := range c.metas {
+		c.purge(meta)
+	}
+}
+
+// Close will detach the cache from the store and close the streams.
+func (c *Cache) Close() {
+	// detach cache
+	c.store.cache.CompareAndSwap(c, nil)
+
+	// close streams
+	for _, stream := range c.streams {
+		stream.Close()
+	}
+
+	// remove entries
+	c.Purge()
+}
+
+func (c *Cache) handle(meta *Meta, event Event, id ID, err error) {
+	// acquire mutex
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// handle event
+	switch event {
+	case Opened, Resumed:
+		c.ready[meta] = true
+		c.epochs[meta]++
+	case Created, Updated, Deleted:
+		key := cacheKey{coll: meta.Collection, id: id}
+		if _, ok := c.entries[key]; ok {
+			delete(c.entries, key)
+			c.stats.Invalidations++
+		}
+		c.epochs[meta]++
+	case Errored, Stopped:
+		c.ready[meta] = false
+		c.purge(meta)
+	}
+
+	// report error
+	if err != nil && c.store.reporter != nil {
+		c.store.reporter(err)
+	}
+}
+
+func (c *Cache) purge(meta *Meta) {
+	// remove entries of collection
+	for key := range c.entries {
+		if key.coll == meta.Collection {
+			delete(c.entries, key)
+			c.stats.Invalidations++
+		}
+	}
+
+	// invalidate pending loads
+	c.epochs[meta]++
+}
+
+func (c *Cache) usable(meta *Meta) bool {
+	return c.metas[meta]
+}
+
+func (c *Cache) find(ctx context.Context, meta *Meta, coll *Collection, id ID, model Model) error {
+	// prepare key
+	key := cacheKey{coll: meta.Collection, id: id}
+
+	// lookup entry
+	c.mutex.Lock()
+	raw, ok := c.entries[key]
+	if ok {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	epoch := c.epochs[meta]
+	c.mutex.Unlock()
+
+	// decode cached document
+	if ok {
+		return xo.W(bson.Unmarshal(raw, model))
+	}
+
+	// load document
+	raw, err := coll.FindOne(ctx, bson.M{
+		"_id": id,
+	}).Raw()
+	if err != nil {
+		return err
+	}
+
+	// copy document
+	raw = append(bson.Raw(nil), raw...)
+
+	// store entry if stream is ready and no change has been received
+	c.mutex.Lock()
+	if c.ready[meta] && c.epochs[meta] == epoch {
+		if c.limit > 0 && len(c.entries) >= c.limit {
+			for k := range c.entries {
+				delete(c.entries, k)
+				break
+			}
+		}
+		c.entries[key] = raw
+	}
+	c.mutex.Unlock()
+
+	return xo.W(bson.Unmarshal(raw, model))
+}
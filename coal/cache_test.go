@@ -0,0 +1,128 @@
+package coal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCache(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		post := tester.Insert(&postModel{
+			Title: "foo",
+		}).(*postModel)
+
+		cache := NewCache(tester.Store, 0, &postModel{})
+		defer cache.Close()
+
+		assert.Eventually(t, func() bool {
+			cache.mutex.Lock()
+			defer cache.mutex.Unlock()
+			return cache.ready[GetMeta(&postModel{})]
+		}, time.Second, time.Millisecond)
+
+		var res postModel
+		found, err := tester.Store.M(&postModel{}).Find(nil, &res, post.ID(), false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "foo", res.Title)
+
+		res = postModel{}
+		found, err = tester.Store.M(&postModel{}).Find(nil, &res, post.ID(), false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "foo", res.Title)
+
+		assert.Equal(t, CacheStats{
+			Hits:    1,
+			Misses:  1,
+			Entries: 1,
+		}, cache.Stats())
+		assert.Equal(t, 0.5, cache.Stats().HitRate())
+
+		found, err = tester.Store.M(&postModel{}).Update(nil, nil, post.ID(), bson.M{
+			"$set": bson.M{
+				"Title": "bar",
+			},
+		}, false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		assert.Eventually(t, func() bool {
+			return cache.Stats().Invalidations == 1
+		}, time.Second, time.Millisecond)
+
+		res = postModel{}
+		found, err = tester.Store.M(&postModel{}).Find(nil, &res, post.ID(), false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "bar", res.Title)
+
+		found, err = tester.Store.M(&postModel{}).Delete(nil, nil, post.ID())
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		assert.Eventually(t, func() bool {
+			return cache.Stats().Invalidations == 2
+		}, time.Second, time.Millisecond)
+
+		found, err = tester.Store.M(&postModel{}).Find(nil, &res, post.ID(), false)
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		note := tester.Insert(&noteModel{
+			Title: "foo",
+		})
+
+		found, err = tester.Store.M(&noteModel{}).Find(nil, &noteModel{}, note.ID(), false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		assert.Equal(t, CacheStats{
+			Hits:          1,
+			Misses:        3,
+			Invalidations: 2,
+			Entries:       0,
+		}, cache.Stats())
+
+		cache.Close()
+
+		found, err = tester.Store.M(&postModel{}).Find(nil, &res, post.ID(), false)
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.Equal(t, int64(3), cache.Stats().Misses)
+	})
+}
+
+func TestCacheLimit(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var ids []ID
+		for i := 0; i < 3; i++ {
+			ids = append(ids, tester.Insert(&postModel{
+				Title: "foo",
+			}).ID())
+		}
+
+		cache := NewCache(tester.Store, 2, &postModel{})
+		defer cache.Close()
+
+		assert.Eventually(t, func() bool {
+			cache.mutex.Lock()
+			defer cache.mutex.Unlock()
+			return cache.ready[GetMeta(&postModel{})]
+		}, time.Second, time.Millisecond)
+
+		for _, id := range ids {
+			found, err := tester.Store.M(&postModel{}).Find(nil, nil, id, false)
+			assert.NoError(t, err)
+			assert.True(t, found)
+		}
+
+		assert.Equal(t, 2, cache.Stats().Entries)
+
+		cache.Purge()
+		assert.Equal(t, 0, cache.Stats().Entries)
+	})
+}
@@ -0,0 +1,56 @@
+package coal
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type collationKey struct{}
+
+// CaseInsensitive returns a collation for the specified locale that compares
+// strings ignoring case and diacritics e.g. "en" or "de".
+func CaseInsensitive(locale string) *options.Collation {
+	return &options.Collation{
+		Locale:   locale,
+		Strength: 1,
+	}
+}
+
+// WithCollation will return a context that carries the provided collation.
+// Collection and manager operations started with the context will use the
+// collation to compare strings in filters and sorts unless options specify
+// another collation. To use an index, the collation of the operation must
+// match the collation of the index.
+//
+// Note: Lungo stores do not support collations and ignore them.
+func WithCollation(ctx context.Context, collation *options.Collation) context.Context {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, collationKey{}, collation)
+}
+
+// GetCollation will return the collation carried by the context.
+func GetCollation(ctx context.Context) *options.Collation {
+	// check context
+	if ctx == nil {
+		return nil
+	}
+
+	// get value
+	collation, _ := ctx.Value(collationKey{}).(*options.Collation)
+
+	return collation
+}
+
+func (c *Collection) collation(ctx context.Context) *options.Collation {
+	// check support
+	if !c.collations {
+		return nil
+	}
+
+	return GetCollation(ctx)
+}
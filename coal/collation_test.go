@@ -0,0 +1,52 @@
+package coal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCollation(t *testing.T) {
+	assert.Nil(t, GetCollation(nil))
+	assert.Nil(t, GetCollation(context.Background()))
+
+	collation := CaseInsensitive("en")
+	ctx := WithCollation(nil, collation)
+	assert.Equal(t, collation, GetCollation(ctx))
+}
+
+func TestCollationQuery(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		for _, title := range []string{"a", "B", "c"} {
+			tester.Insert(&postModel{
+				Title: title,
+			})
+		}
+
+		ctx := WithCollation(nil, CaseInsensitive("en"))
+
+		var posts []postModel
+		err := tester.Store.M(&postModel{}).FindAll(ctx, &posts, nil, []string{"Title"}, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+
+		var titles []string
+		for _, post := range posts {
+			titles = append(titles, post.Title)
+		}
+
+		count, err := tester.Store.M(&postModel{}).Count(ctx, bson.M{
+			"Title": "b",
+		}, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+
+		if tester.Store.Lungo() {
+			assert.Equal(t, []string{"B", "a", "c"}, titles)
+			assert.Equal(t, int64(0), count)
+		} else {
+			assert.Equal(t, []string{"a", "B", "c"}, titles)
+			assert.Equal(t, int64(1), count)
+		}
+	})
+}
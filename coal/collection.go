@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"time"
 
 	"github.com/256dpi/lungo"
 	"github.com/256dpi/xo"
@@ -16,6 +17,10 @@ import (
 // transaction.
 var ErrReadOnlyTransaction = xo.BF("read only transaction")
 
+// ErrCrossClusterTransaction is returned when accessing a collection of another
+// cluster under a transaction.
+var ErrCrossClusterTransaction = xo.BF("cross cluster transaction")
+
 // IsMissing returns whether the provided error describes a missing document.
 func IsMissing(err error) bool {
 	return err == lungo.ErrNoDocuments || errors.Is(err, lungo.ErrNoDocuments)
@@ -28,7 +33,9 @@ func IsDuplicate(err error) bool {
 
 // Collection mimics a collection and adds tracing.
 type Collection struct {
-	coll lungo.ICollection
+	store      *Store
+	coll       lungo.ICollection
+	collations bool
 }
 
 // Native will return the underlying native collection.
@@ -36,6 +43,37 @@ func (c *Collection) Native() lungo.ICollection {
 	return c.coll
 }
 
+func (c *Collection) native(ctx context.Context) lungo.ICollection {
+	// get preference
+	pref := GetPreference(ctx)
+	if pref.ReadPreference == nil && pref.WriteConcern == nil {
+		return c.coll
+	}
+
+	// transactions apply their own preference
+	ok, tx := GetTransaction(ctx)
+	if ok && !tx.Snapshot {
+		return c.coll
+	}
+
+	// prepare options
+	opts := options.Collection()
+	if pref.ReadPreference != nil {
+		opts.SetReadPreference(pref.ReadPreference)
+	}
+	if pref.WriteConcern != nil {
+		opts.SetWriteConcern(pref.WriteConcern)
+	}
+
+	// clone collection
+	coll, err := c.coll.Clone(opts)
+	if err != nil {
+		return c.coll
+	}
+
+	return coll
+}
+
 // Aggregate wraps the native Aggregate collection method and yields the
 // returned cursor.
 func (c *Collection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*Iterator, error) {
@@ -43,15 +81,24 @@ func (c *Collection) Aggregate(ctx context.Context, pipeline interface{}, opts .
 	ctx, span := xo.Trace(ctx, "coal/Collection.Aggregate")
 	span.Tag("collection", c.coll.Name())
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.AggregateOptions{options.Aggregate().SetCollation(collation)}, opts...)
+	}
+
 	// aggregate
-	csr, err := c.coll.Aggregate(ctx, pipeline, opts...)
+	var csr lungo.ICursor
+	err := c.run(ctx, false, func() (err error) {
+		csr, err = c.native(ctx).Aggregate(ctx, pipeline, opts...)
+		return err
+	})
 	if err != nil {
 		span.End()
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	// create iterator
-	iterator := newIterator(ctx, csr, span)
+	iterator := newIterator(ctx, c.store, csr, span)
 
 	return iterator, nil
 }
@@ -70,9 +117,13 @@ func (c *Collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, o
 	}
 
 	// bulk write
-	res, err := c.coll.BulkWrite(ctx, models, opts...)
+	var res *mongo.BulkWriteResult
+	err := c.run(ctx, true, func() (err error) {
+		res, err = c.native(ctx).BulkWrite(ctx, models, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	// log result
@@ -92,10 +143,19 @@ func (c *Collection) CountDocuments(ctx context.Context, filter interface{}, opt
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.CountOptions{options.Count().SetCollation(collation)}, opts...)
+	}
+
 	// count documents
-	count, err := c.coll.CountDocuments(ctx, filter, opts...)
+	var count int64
+	err := c.run(ctx, false, func() (err error) {
+		count, err = c.native(ctx).CountDocuments(ctx, filter, opts...)
+		return err
+	})
 	if err != nil {
-		return 0, xo.W(err)
+		return 0, err
 	}
 
 	// log result
@@ -111,6 +171,11 @@ func (c *Collection) DeleteMany(ctx context.Context, filter interface{}, opts ..
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.DeleteOptions{options.Delete().SetCollation(collation)}, opts...)
+	}
+
 	// check transaction
 	ok, tx := GetTransaction(ctx)
 	if ok && tx.ReadOnly {
@@ -118,9 +183,13 @@ func (c *Collection) DeleteMany(ctx context.Context, filter interface{}, opts ..
 	}
 
 	// delete many
-	res, err := c.coll.DeleteMany(ctx, filter, opts...)
+	var res *mongo.DeleteResult
+	err := c.run(ctx, true, func() (err error) {
+		res, err = c.native(ctx).DeleteMany(ctx, filter, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	// log result
@@ -136,6 +205,11 @@ func (c *Collection) DeleteOne(ctx context.Context, filter interface{}, opts ...
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.DeleteOptions{options.Delete().SetCollation(collation)}, opts...)
+	}
+
 	// check transaction
 	ok, tx := GetTransaction(ctx)
 	if ok && tx.ReadOnly {
@@ -143,9 +217,13 @@ func (c *Collection) DeleteOne(ctx context.Context, filter interface{}, opts ...
 	}
 
 	// delete one
-	res, err := c.coll.DeleteOne(ctx, filter, opts...)
+	var res *mongo.DeleteResult
+	err := c.run(ctx, true, func() (err error) {
+		res, err = c.native(ctx).DeleteOne(ctx, filter, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	// log result
@@ -162,10 +240,19 @@ func (c *Collection) Distinct(ctx context.Context, field string, filter interfac
 	span.Tag("field", field)
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.DistinctOptions{options.Distinct().SetCollation(collation)}, opts...)
+	}
+
 	// distinct
-	list, err := c.coll.Distinct(ctx, field, filter, opts...)
+	var list []interface{}
+	err := c.run(ctx, false, func() (err error) {
+		list, err = c.native(ctx).Distinct(ctx, field, filter, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	// log result
@@ -182,9 +269,13 @@ func (c *Collection) EstimatedDocumentCount(ctx context.Context, opts ...*option
 	defer span.End()
 
 	// estimate count
-	count, err := c.coll.EstimatedDocumentCount(ctx, opts...)
+	var count int64
+	err := c.run(ctx, false, func() (err error) {
+		count, err = c.native(ctx).EstimatedDocumentCount(ctx, opts...)
+		return err
+	})
 	if err != nil {
-		return 0, xo.W(err)
+		return 0, err
 	}
 
 	// log result
@@ -199,15 +290,24 @@ func (c *Collection) Find(ctx context.Context, filter interface{}, opts ...*opti
 	ctx, span := xo.Trace(ctx, "coal/Collection.Find")
 	span.Tag("collection", c.coll.Name())
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.FindOptions{options.Find().SetCollation(collation)}, opts...)
+	}
+
 	// find
-	csr, err := c.coll.Find(ctx, filter, opts...)
+	var csr lungo.ICursor
+	err := c.run(ctx, false, func() (err error) {
+		csr, err = c.native(ctx).Find(ctx, filter, opts...)
+		return err
+	})
 	if err != nil {
 		span.End()
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	// create iterator
-	iterator := newIterator(ctx, csr, span)
+	iterator := newIterator(ctx, c.store, csr, span)
 
 	return iterator, nil
 }
@@ -219,10 +319,25 @@ func (c *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*o
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.FindOneOptions{options.FindOne().SetCollation(collation)}, opts...)
+	}
+
 	// find one
-	res := c.coll.FindOne(ctx, filter, opts...)
+	var res lungo.ISingleResult
+	err := c.run(ctx, false, func() error {
+		res = c.native(ctx).FindOne(ctx, filter, opts...)
+		if IsMissing(res.Err()) {
+			return nil
+		}
+		return res.Err()
+	})
+	if err != nil {
+		return &SingleResult{err: err}
+	}
 
-	return &SingleResult{res: res}
+	return &SingleResult{store: c.store, res: res}
 }
 
 // FindOneAndDelete wraps the native FindOneAndDelete collection method.
@@ -232,6 +347,11 @@ func (c *Collection) FindOneAndDelete(ctx context.Context, filter interface{}, o
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.FindOneAndDeleteOptions{options.FindOneAndDelete().SetCollation(collation)}, opts...)
+	}
+
 	// check transaction
 	ok, tx := GetTransaction(ctx)
 	if ok && tx.ReadOnly {
@@ -239,9 +359,19 @@ func (c *Collection) FindOneAndDelete(ctx context.Context, filter interface{}, o
 	}
 
 	// find one and delete
-	res := c.coll.FindOneAndDelete(ctx, filter, opts...)
+	var res lungo.ISingleResult
+	err := c.run(ctx, true, func() error {
+		res = c.native(ctx).FindOneAndDelete(ctx, filter, opts...)
+		if IsMissing(res.Err()) {
+			return nil
+		}
+		return res.Err()
+	})
+	if err != nil {
+		return &SingleResult{err: err}
+	}
 
-	return &SingleResult{res: res}
+	return &SingleResult{store: c.store, res: res}
 }
 
 // FindOneAndReplace wraps the native FindOneAndReplace collection method.
@@ -251,6 +381,11 @@ func (c *Collection) FindOneAndReplace(ctx context.Context, filter interface{},
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.FindOneAndReplaceOptions{options.FindOneAndReplace().SetCollation(collation)}, opts...)
+	}
+
 	// check transaction
 	ok, tx := GetTransaction(ctx)
 	if ok && tx.ReadOnly {
@@ -258,9 +393,19 @@ func (c *Collection) FindOneAndReplace(ctx context.Context, filter interface{},
 	}
 
 	// find and replace one
-	res := c.coll.FindOneAndReplace(ctx, filter, replacement, opts...)
+	var res lungo.ISingleResult
+	err := c.run(ctx, true, func() error {
+		res = c.native(ctx).FindOneAndReplace(ctx, filter, replacement, opts...)
+		if IsMissing(res.Err()) {
+			return nil
+		}
+		return res.Err()
+	})
+	if err != nil {
+		return &SingleResult{err: err}
+	}
 
-	return &SingleResult{res: res}
+	return &SingleResult{store: c.store, res: res}
 }
 
 // FindOneAndUpdate wraps the native FindOneAndUpdate collection method.
@@ -270,6 +415,11 @@ func (c *Collection) FindOneAndUpdate(ctx context.Context, filter interface{}, u
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.FindOneAndUpdateOptions{options.FindOneAndUpdate().SetCollation(collation)}, opts...)
+	}
+
 	// check transaction
 	ok, tx := GetTransaction(ctx)
 	if ok && tx.ReadOnly {
@@ -277,9 +427,19 @@ func (c *Collection) FindOneAndUpdate(ctx context.Context, filter interface{}, u
 	}
 
 	// find one and update
-	res := c.coll.FindOneAndUpdate(ctx, filter, update, opts...)
+	var res lungo.ISingleResult
+	err := c.run(ctx, true, func() error {
+		res = c.native(ctx).FindOneAndUpdate(ctx, filter, update, opts...)
+		if IsMissing(res.Err()) {
+			return nil
+		}
+		return res.Err()
+	})
+	if err != nil {
+		return &SingleResult{err: err}
+	}
 
-	return &SingleResult{res: res}
+	return &SingleResult{store: c.store, res: res}
 }
 
 // InsertMany wraps the native InsertMany collection method.
@@ -297,9 +457,13 @@ func (c *Collection) InsertMany(ctx context.Context, documents []interface{}, op
 	}
 
 	// insert many
-	res, err := c.coll.InsertMany(ctx, documents, opts...)
+	var res *mongo.InsertManyResult
+	err := c.run(ctx, true, func() (err error) {
+		res, err = c.native(ctx).InsertMany(ctx, documents, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	return res, nil
@@ -319,9 +483,13 @@ func (c *Collection) InsertOne(ctx context.Context, document interface{}, opts .
 	}
 
 	// insert one
-	res, err := c.coll.InsertOne(ctx, document, opts...)
+	var res *mongo.InsertOneResult
+	err := c.run(ctx, true, func() (err error) {
+		res, err = c.native(ctx).InsertOne(ctx, document, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	return res, nil
@@ -334,6 +502,11 @@ func (c *Collection) ReplaceOne(ctx context.Context, filter interface{}, replace
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.ReplaceOptions{options.Replace().SetCollation(collation)}, opts...)
+	}
+
 	// check transaction
 	ok, tx := GetTransaction(ctx)
 	if ok && tx.ReadOnly {
@@ -341,9 +514,13 @@ func (c *Collection) ReplaceOne(ctx context.Context, filter interface{}, replace
 	}
 
 	// replace one
-	res, err := c.coll.ReplaceOne(ctx, filter, replacement, opts...)
+	var res *mongo.UpdateResult
+	err := c.run(ctx, true, func() (err error) {
+		res, err = c.native(ctx).ReplaceOne(ctx, filter, replacement, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	return res, nil
@@ -356,6 +533,11 @@ func (c *Collection) UpdateMany(ctx context.Context, filter interface{}, update
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.UpdateOptions{options.Update().SetCollation(collation)}, opts...)
+	}
+
 	// check transaction
 	ok, tx := GetTransaction(ctx)
 	if ok && tx.ReadOnly {
@@ -363,9 +545,13 @@ func (c *Collection) UpdateMany(ctx context.Context, filter interface{}, update
 	}
 
 	// update many
-	res, err := c.coll.UpdateMany(ctx, filter, update, opts...)
+	var res *mongo.UpdateResult
+	err := c.run(ctx, true, func() (err error) {
+		res, err = c.native(ctx).UpdateMany(ctx, filter, update, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	// log result
@@ -383,6 +569,11 @@ func (c *Collection) UpdateOne(ctx context.Context, filter interface{}, update i
 	span.Tag("collection", c.coll.Name())
 	defer span.End()
 
+	// apply collation
+	if collation := c.collation(ctx); collation != nil {
+		opts = append([]*options.UpdateOptions{options.Update().SetCollation(collation)}, opts...)
+	}
+
 	// check transaction
 	ok, tx := GetTransaction(ctx)
 	if ok && tx.ReadOnly {
@@ -390,9 +581,13 @@ func (c *Collection) UpdateOne(ctx context.Context, filter interface{}, update i
 	}
 
 	// update one
-	res, err := c.coll.UpdateOne(ctx, filter, update, opts...)
+	var res *mongo.UpdateResult
+	err := c.run(ctx, true, func() (err error) {
+		res, err = c.native(ctx).UpdateOne(ctx, filter, update, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, xo.W(err)
+		return nil, err
 	}
 
 	// log result
@@ -403,18 +598,57 @@ func (c *Collection) UpdateOne(ctx context.Context, filter interface{}, update i
 	return res, nil
 }
 
+func (c *Collection) run(ctx context.Context, write bool, fn func() error) error {
+	// check cluster
+	ok, tx := GetTransaction(ctx)
+	if ok && tx.Store != nil && c.store != nil && tx.Store.client != c.store.client {
+		return ErrCrossClusterTransaction.Wrap()
+	}
+
+	// get retries
+	var retries int
+	if c.store != nil && !HasTransaction(ctx) {
+		retries = c.store.Retries
+	}
+
+	// run function
+	delay := 50 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		// call function
+		err := Classify(fn())
+		if err == nil {
+			return nil
+		}
+
+		// check retry, writes are only retried if they have not been executed
+		if attempt >= retries || !ErrTransient.Is(err) || (write && !isRetryableWrite(err)) {
+			return xo.W(err)
+		}
+
+		// await delay
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return xo.W(err)
+		}
+	}
+}
+
 // Iterator manages the iteration over a cursor.
 type Iterator struct {
 	ctx     context.Context
+	store   *Store
 	cursor  lungo.ICursor
 	spans   []xo.Span
 	counter int64
 	error   error
 }
 
-func newIterator(ctx context.Context, cursor lungo.ICursor, span xo.Span) *Iterator {
+func newIterator(ctx context.Context, store *Store, cursor lungo.ICursor, span xo.Span) *Iterator {
 	return &Iterator{
 		ctx:    ctx,
+		store:  store,
 		cursor: cursor,
 		spans:  []xo.Span{span},
 	}
@@ -426,8 +660,11 @@ func (i *Iterator) All(list interface{}) error {
 	// get initial length
 	length := int64(reflect.ValueOf(list).Elem().Len())
 
-	// decode all documents
-	err := i.cursor.All(i.ctx, list)
+	// decode all documents, verified if requested
+	ok, err := decodeAll(i.ctx, i.store, i.cursor, list)
+	if !ok {
+		err = i.cursor.All(i.ctx, list)
+	}
 
 	// set counter
 	i.counter = int64(reflect.ValueOf(list).Elem().Len()) - length
@@ -438,7 +675,7 @@ func (i *Iterator) All(list interface{}) error {
 		span.End()
 	}
 
-	return xo.W(err)
+	return xo.W(Classify(err))
 }
 
 // Next will load the next document from the cursor and if available return true.
@@ -465,13 +702,13 @@ func (i *Iterator) Next() bool {
 
 // Decode will decode the loaded document to the specified value.
 func (i *Iterator) Decode(v interface{}) error {
-	return xo.W(i.cursor.Decode(v))
+	return decode(i.store, v, i.cursor.Decode)
 }
 
 // Error returns the first error encountered during iteration. It should always
 // be checked when done to ensure there have been no errors.
 func (i *Iterator) Error() error {
-	return xo.W(i.error)
+	return xo.W(Classify(i.error))
 }
 
 // Close will close the underlying cursor. A call to it should be deferred right
@@ -495,8 +732,9 @@ func (i *Iterator) Close() {
 
 // SingleResult wraps a single operation result.
 type SingleResult struct {
-	res lungo.ISingleResult
-	err error
+	store *Store
+	res   lungo.ISingleResult
+	err   error
 }
 
 // Decode will decode the document to the specified value.
@@ -504,7 +742,7 @@ func (r *SingleResult) Decode(i interface{}) error {
 	if r.err != nil {
 		return r.err
 	}
-	return xo.W(r.res.Decode(i))
+	return decode(r.store, i, r.res.Decode)
 }
 
 // Raw will return the raw document bytes.
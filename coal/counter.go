@@ -0,0 +1,352 @@
+package coal
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/stick"
+)
+
+func init() {
+	// add indexes
+	AddIndex(&Counter{}, true, 0, "Name", "Target")
+	AddIndex(&Counter{}, false, time.Minute, "Expires")
+}
+
+// Counter is a materialized count of documents that reference a target
+// document e.g. the number of comments of a post. Counters are identified by
+// the name of their counting and the target ID. Expired counters are
+// eventually removed and recomputed when loaded.
+type Counter struct {
+	Base    `json:"-" bson:",inline" coal:"counters"`
+	Name    string    `json:"name"`
+	Target  ID        `json:"target"`
+	Count   int64     `json:"count"`
+	Expires time.Time `json:"expires-at" bson:"expires_at"`
+}
+
+// Validate implements the Model interface.
+func (c *Counter) Validate() error {
+	// check name
+	if c.Name == "" {
+		return xo.SF("missing name")
+	}
+
+	// check target
+	if c.Target.IsZero() {
+		return xo.SF("missing target")
+	}
+
+	// check expires
+	if c.Expires.IsZero() {
+		return xo.SF("missing expires")
+	}
+
+	return nil
+}
+
+// Counting describes materialized counters of the documents of a model that
+// reference target documents using a to-one or to-many field.
+type Counting struct {
+	// The unique name of the counting e.g. "post-comments".
+	Name string
+
+	// The counted model e.g. &Comment{}.
+	Model Model
+
+	// The to-one or to-many field that references the target e.g. "Post".
+	Field string
+
+	// The optional filter that selects the counted documents e.g. to exclude
+	// soft deleted documents.
+	Filter bson.M
+
+	// The time after which counters are recomputed. This bounds the drift of
+	// counters that are not updated on every change.
+	//
+	// Default: 1h.
+	TTL time.Duration
+}
+
+// Load will return the counts for the specified targets. Missing and expired
+// counters are recomputed and stored.
+func (c *Counting) Load(ctx context.Context, store *Store, targets ...ID) (map[ID]int64, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Counting.Load")
+	span.Tag("name", c.Name)
+	defer span.End()
+
+	// find counters
+	var counters []Counter
+	err := store.M(&Counter{}).FindAll(ctx, &counters, bson.M{
+		"Name": c.Name,
+		"Target": bson.M{
+			"$in": targets,
+		},
+		"Expires": bson.M{
+			"$gt": time.Now(),
+		},
+	}, nil, 0, 0, false, NoTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	// collect counts
+	counts := make(map[ID]int64, len(targets))
+	for _, counter := range counters {
+		counts[counter.Target] = counter.Count
+	}
+
+	// collect missing targets
+	var missing []ID
+	for _, target := range targets {
+		if _, ok := counts[target]; !ok {
+			missing = append(missing, target)
+		}
+	}
+	if len(missing) == 0 {
+		return counts, nil
+	}
+
+	// recompute missing counters
+	computed, err := c.recompute(ctx, store, missing)
+	if err != nil {
+		return nil, err
+	}
+	for target, count := range computed {
+		counts[target] = count
+	}
+
+	return counts, nil
+}
+
+// Recompute will count the documents that reference the target and store the
+// counter. It returns the computed count. Within read only transactions, the
+// counter is not stored.
+func (c *Counting) Recompute(ctx context.Context, store *Store, target ID) (int64, error) {
+	// recompute counter
+	counts, err := c.recompute(ctx, store, []ID{target})
+	if err != nil {
+		return 0, err
+	}
+
+	return counts[target], nil
+}
+
+func (c *Counting) recompute(ctx context.Context, store *Store, targets []ID) (map[ID]int64, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Counting.Recompute")
+	span.Tag("name", c.Name)
+	span.Tag("targets", len(targets))
+	defer span.End()
+
+	// count documents
+	counts, err := c.count(ctx, store, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	// skip storing in read only transactions
+	if ok, tx := GetTransaction(ctx); ok && tx.ReadOnly {
+		return counts, nil
+	}
+
+	// store counters
+	for _, target := range targets {
+		_, err = store.M(&Counter{}).Upsert(ctx, nil, bson.M{
+			"Name":   c.Name,
+			"Target": target,
+		}, bson.M{
+			"$set": bson.M{
+				"Count":   counts[target],
+				"Expires": time.Now().Add(c.ttl()),
+			},
+		}, nil, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return counts, nil
+}
+
+func (c *Counting) count(ctx context.Context, store *Store, targets []ID) (map[ID]int64, error) {
+	// prepare counts
+	counts := make(map[ID]int64, len(targets))
+	for _, target := range targets {
+		counts[target] = 0
+	}
+
+	// count documents per target if aggregations are not supported
+	if !store.Supports(Aggregations) {
+		for _, target := range targets {
+			count, err := store.M(c.Model).Count(ctx, c.filter(target), 0, 0, false, NoTransaction)
+			if err != nil {
+				return nil, err
+			}
+			counts[target] = count
+		}
+
+		return counts, nil
+	}
+
+	// prepare pipeline
+	in := bson.M{
+		"$in": targets,
+	}
+	pipeline := NewPipeline(c.Model).Match(c.filter(in))
+	if GetMeta(c.Model).Fields[c.Field].Type.Kind() == reflect.Slice {
+		pipeline = pipeline.Unwind(c.Field).Match(bson.M{
+			c.Field: in,
+		})
+	}
+	pipeline = pipeline.Group("$"+c.Field, bson.M{
+		"count": bson.M{
+			"$sum": 1,
+		},
+	})
+
+	// count documents of all targets
+	var groups []struct {
+		Target ID    `bson:"_id"`
+		Count  int64 `bson:"count"`
+	}
+	err := store.M(c.Model).Aggregate(ctx, &groups, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	// collect counts
+	for _, group := range groups {
+		counts[group.Target] = group.Count
+	}
+
+	return counts, nil
+}
+
+func (c *Counting) filter(target interface{}) bson.M {
+	// prepare filter
+	filter := bson.M{
+		c.Field: target,
+	}
+	if len(c.Filter) > 0 {
+		filter = bson.M{
+			"$and": []bson.M{filter, c.Filter},
+		}
+	}
+
+	return filter
+}
+
+// Increment will add the delta to the counter of the target if it exists and
+// has not expired. Missing counters are left to be recomputed when loaded.
+// Calling the method in the transaction that creates or deletes a counted
+// document keeps the counter consistent.
+func (c *Counting) Increment(ctx context.Context, store *Store, target ID, delta int64) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Counting.Increment")
+	span.Tag("name", c.Name)
+	defer span.End()
+
+	// update counter
+	_, err := store.M(&Counter{}).UpdateFirst(ctx, nil, bson.M{
+		"Name":   c.Name,
+		"Target": target,
+		"Expires": bson.M{
+			"$gt": time.Now(),
+		},
+	}, bson.M{
+		"$inc": bson.M{
+			"Count": delta,
+		},
+	}, nil, false)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Invalidate will remove the counters of the specified targets to force their
+// recomputation.
+func (c *Counting) Invalidate(ctx context.Context, store *Store, targets ...ID) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Counting.Invalidate")
+	span.Tag("name", c.Name)
+	defer span.End()
+
+	// delete counters
+	_, err := store.M(&Counter{}).DeleteAll(ctx, bson.M{
+		"Name": c.Name,
+		"Target": bson.M{
+			"$in": targets,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Track will open a stream that recomputes the counters of the targets that
+// are referenced by created, updated and deleted documents. The counters of
+// targets that are no longer referenced by updated and deleted documents are
+// only recomputed if pre-images are enabled for the collection using
+// EnablePreImages. Otherwise, they are reflected once the counters expire
+// unless Increment is called when changing the reference.
+func (c *Counting) Track(store *Store, reporter func(error)) *Stream {
+	return OpenImageStream(store, c.Model, nil, func(event Event, _ ID, before, after Model, err error, _ []byte) error {
+		switch event {
+		case Created, Updated, Deleted:
+			// collect targets
+			var targets []ID
+			for _, model := range []Model{before, after} {
+				if model != nil {
+					targets = append(targets, c.targets(model)...)
+				}
+			}
+			if len(targets) == 0 {
+				return nil
+			}
+
+			// recompute counters
+			_, err := c.recompute(context.Background(), store, stick.Unique(targets))
+			if err != nil {
+				return err
+			}
+		case Errored:
+			if reporter != nil {
+				reporter(err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (c *Counting) targets(model Model) []ID {
+	switch value := stick.MustGet(model, c.Field).(type) {
+	case ID:
+		return []ID{value}
+	case *ID:
+		if value != nil {
+			return []ID{*value}
+		}
+	case []ID:
+		return value
+	}
+
+	return nil
+}
+
+func (c *Counting) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return time.Hour
+}
@@ -0,0 +1,143 @@
+package coal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCounting(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		counting := &Counting{
+			Name:  "post-comments",
+			Model: &commentModel{},
+			Field: "Post",
+			Filter: bson.M{
+				"Message": bson.M{"$ne": "hidden"},
+			},
+		}
+
+		post1 := tester.Insert(&postModel{Title: "foo"}).ID()
+		post2 := tester.Insert(&postModel{Title: "bar"}).ID()
+
+		tester.Insert(&commentModel{Post: post1, Message: "a"})
+		tester.Insert(&commentModel{Post: post1, Message: "b"})
+		tester.Insert(&commentModel{Post: post1, Message: "hidden"})
+
+		counts, err := counting.Load(nil, tester.Store, post1, post2)
+		assert.NoError(t, err)
+		assert.Equal(t, map[ID]int64{post1: 2, post2: 0}, counts)
+		assert.Equal(t, 2, tester.Count(&Counter{}))
+
+		// loaded from counters
+		tester.Insert(&commentModel{Post: post2, Message: "c"})
+		counts, err = counting.Load(nil, tester.Store, post1, post2)
+		assert.NoError(t, err)
+		assert.Equal(t, map[ID]int64{post1: 2, post2: 0}, counts)
+
+		// increment existing counter
+		err = counting.Increment(nil, tester.Store, post2, 1)
+		assert.NoError(t, err)
+		counts, err = counting.Load(nil, tester.Store, post2)
+		assert.NoError(t, err)
+		assert.Equal(t, map[ID]int64{post2: 1}, counts)
+
+		// increment missing counter
+		err = counting.Increment(nil, tester.Store, New(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, tester.Count(&Counter{}))
+
+		// recompute expired counters
+		tester.Insert(&commentModel{Post: post1, Message: "d"})
+		tester.Update(tester.FindLast(&Counter{}, bson.M{"Target": post1}), bson.M{
+			"$set": bson.M{
+				"Expires": time.Now().Add(-time.Minute),
+			},
+		})
+		counts, err = counting.Load(nil, tester.Store, post1)
+		assert.NoError(t, err)
+		assert.Equal(t, map[ID]int64{post1: 3}, counts)
+
+		// invalidate counters
+		err = counting.Invalidate(nil, tester.Store, post1, post2)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, tester.Count(&Counter{}))
+	})
+}
+
+func TestCountingTrack(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		counting := &Counting{
+			Name:  "post-comments",
+			Model: &commentModel{},
+			Field: "Post",
+		}
+
+		post := tester.Insert(&postModel{Title: "foo"}).ID()
+
+		counts, err := counting.Load(nil, tester.Store, post)
+		assert.NoError(t, err)
+		assert.Equal(t, map[ID]int64{post: 0}, counts)
+
+		err = EnablePreImages(tester.Store, &commentModel{})
+		assert.NoError(t, err)
+
+		stream := counting.Track(tester.Store, func(err error) {
+			panic(err)
+		})
+		defer stream.Close()
+
+		time.Sleep(100 * time.Millisecond)
+
+		comment := tester.Insert(&commentModel{Post: post, Message: "a"})
+
+		assert.Eventually(t, func() bool {
+			counter := tester.FindLast(&Counter{}).(*Counter)
+			return counter.Count == 1
+		}, time.Second, 10*time.Millisecond)
+
+		// move comment
+		other := tester.Insert(&postModel{Title: "bar"}).ID()
+		tester.Update(comment, bson.M{
+			"$set": bson.M{
+				"Post": other,
+			},
+		})
+
+		assert.Eventually(t, func() bool {
+			counter := tester.FindLast(&Counter{}, bson.M{"Target": other})
+			return counter != nil && counter.(*Counter).Count == 1
+		}, time.Second, 10*time.Millisecond)
+
+		if tester.Store.Supports(PreImages) {
+			assert.Eventually(t, func() bool {
+				counter := tester.FindLast(&Counter{}, bson.M{"Target": post}).(*Counter)
+				return counter.Count == 0
+			}, time.Second, 10*time.Millisecond)
+		}
+	})
+}
+
+func TestCountingToMany(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		counting := &Counting{
+			Name:  "post-selections",
+			Model: &selectionModel{},
+			Field: "Posts",
+		}
+
+		post1 := tester.Insert(&postModel{Title: "foo"}).ID()
+		post2 := tester.Insert(&postModel{Title: "bar"}).ID()
+		post3 := tester.Insert(&postModel{Title: "baz"}).ID()
+
+		tester.Insert(&selectionModel{Posts: []ID{post1, post2}})
+		tester.Insert(&selectionModel{Posts: []ID{post1}})
+
+		counts, err := counting.Load(nil, tester.Store, post1, post2, post3)
+		assert.NoError(t, err)
+		assert.Equal(t, map[ID]int64{post1: 2, post2: 1, post3: 0}, counts)
+		assert.Equal(t, 3, tester.Count(&Counter{}))
+	})
+}
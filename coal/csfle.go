@@ -0,0 +1,235 @@
+package coal
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The algorithms used to encrypt fields.
+const (
+	RandomAlgorithm        = "AEAD_AES_256_CBC_HMAC_SHA_512-Random"
+	DeterministicAlgorithm = "AEAD_AES_256_CBC_HMAC_SHA_512-Deterministic"
+)
+
+// KeyVault configures client-side field level encryption.
+type KeyVault struct {
+	// The key vault namespace e.g. "encryption.__keyVault".
+	//
+	// Default: "encryption.__keyVault".
+	Namespace string
+
+	// The KMS provider configurations e.g. "local" with a 96 byte "key".
+	Providers map[string]map[string]interface{}
+
+	// The KMS provider used to create the data key.
+	//
+	// Default: "local".
+	Provider string
+
+	// The master key used to create the data key. Must be nil for the local
+	// provider.
+	MasterKey interface{}
+
+	// The alternate name of the data key. The key is created if missing.
+	//
+	// Default: "fire".
+	KeyName string
+
+	// The models with encrypted fields.
+	Models []Model
+
+	// Additional options for the mongocryptd process or the crypt shared
+	// library e.g. "cryptSharedLibPath".
+	ExtraOptions map[string]interface{}
+}
+
+// EncryptionSchema will generate the JSON schema used for automatic encryption
+// of the fields flagged with EncryptedFlag or DeterministicFlag. Only top-level
+// fields are supported. Deterministically encrypted fields must have a single
+// BSON type.
+func EncryptionSchema(model Model, keyID primitive.Binary) (bson.M, error) {
+	// get meta
+	meta := GetMeta(model)
+
+	// prepare properties
+	properties := bson.M{}
+
+	// add fields
+	for _, field := range meta.OrderedFields {
+		// get encryption
+		encryption := GetEncryption(model, field.Name)
+		if encryption == Unencrypted || field.BSONKey == "" {
+			continue
+		}
+
+		// handle random encryption
+		if encryption == Random {
+			properties[field.BSONKey] = bson.M{
+				"encrypt": bson.M{
+					"algorithm": RandomAlgorithm,
+				},
+			}
+			continue
+		}
+
+		// get type
+		bsonType, ok := schemaType(field.Type)["bsonType"].(string)
+		if !ok {
+			return nil, xo.F("unsupported type of deterministic field %s", field.Name)
+		}
+
+		// add property
+		properties[field.BSONKey] = bson.M{
+			"encrypt": bson.M{
+				"bsonType":  bsonType,
+				"algorithm": DeterministicAlgorithm,
+			},
+		}
+	}
+
+	return bson.M{
+		"bsonType": "object",
+		"encryptMetadata": bson.M{
+			"keyId": bson.A{keyID},
+		},
+		"properties": properties,
+	}, nil
+}
+
+// EnsureDataKey will return the ID of the data key with the specified alternate
+// name. The data key is created using the provided KMS provider and master key
+// if missing.
+func EnsureDataKey(ctx context.Context, ce *mongo.ClientEncryption, provider string, masterKey interface{}, name string) (primitive.Binary, error) {
+	// find key
+	var key struct {
+		ID primitive.Binary `bson:"_id"`
+	}
+	err := ce.GetKeyByAltName(ctx, name).Decode(&key)
+	if err == nil {
+		return key.ID, nil
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return primitive.Binary{}, xo.W(err)
+	}
+
+	// prepare options
+	opts := options.DataKey().SetKeyAltNames([]string{name})
+	if masterKey != nil {
+		opts.SetMasterKey(masterKey)
+	}
+
+	// create key
+	id, err := ce.CreateDataKey(ctx, provider, opts)
+	if err != nil {
+		return primitive.Binary{}, xo.W(err)
+	}
+
+	return id, nil
+}
+
+// MustConnectEncrypted will call ConnectEncrypted and panic on errors.
+func MustConnectEncrypted(uri string, vault KeyVault, reporter func(error), opts ...*options.ClientOptions) *Store {
+	// connect store
+	store, err := ConnectEncrypted(uri, vault, reporter, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return store
+}
+
+// ConnectEncrypted will connect to the specified database like Connect and
+// enable automatic client-side field level encryption for the fields of the
+// configured models. The data key is ensured in the key vault before the store
+// is returned. Encrypted fields are encrypted on write and decrypted on read
+// by the driver, which also encrypts query values of deterministically
+// encrypted fields. Therefore, the store Encrypter must not be set.
+//
+// Note: The driver must be built using the "cse" build tag and requires
+// libmongocrypt as well as mongocryptd or the crypt shared library.
+func ConnectEncrypted(uri string, vault KeyVault, reporter func(error), opts ...*options.ClientOptions) (*Store, error) {
+	// set default namespace
+	if vault.Namespace == "" {
+		vault.Namespace = "encryption.__keyVault"
+	}
+
+	// set default provider
+	if vault.Provider == "" {
+		vault.Provider = "local"
+	}
+
+	// set default key name
+	if vault.KeyName == "" {
+		vault.KeyName = "fire"
+	}
+
+	// parse url
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// get default db
+	defaultDB := strings.Trim(parsedURL.Path, "/")
+
+	// create context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// connect key vault client
+	keyVaultClient, err := mongo.Connect(ctx, options.MergeClientOptions(opts...).ApplyURI(uri))
+	if err != nil {
+		return nil, xo.W(err)
+	}
+	defer keyVaultClient.Disconnect(ctx)
+
+	// create client encryption
+	ce, err := mongo.NewClientEncryption(keyVaultClient, options.ClientEncryption().
+		SetKeyVaultNamespace(vault.Namespace).
+		SetKmsProviders(vault.Providers))
+	if err != nil {
+		return nil, xo.W(err)
+	}
+	defer ce.Close(ctx)
+
+	// ensure data key
+	keyID, err := EnsureDataKey(ctx, ce, vault.Provider, vault.MasterKey, vault.KeyName)
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare schema map
+	schemaMap := map[string]interface{}{}
+	for _, model := range vault.Models {
+		schema, err := EncryptionSchema(model, keyID)
+		if err != nil {
+			return nil, err
+		}
+		schemaMap[defaultDB+"."+GetMeta(model).Collection] = schema
+	}
+
+	// prepare auto encryption options
+	autoOpts := options.AutoEncryption().
+		SetKeyVaultNamespace(vault.Namespace).
+		SetKmsProviders(vault.Providers).
+		SetSchemaMap(schemaMap)
+	if vault.ExtraOptions != nil {
+		autoOpts.SetExtraOptions(vault.ExtraOptions)
+	}
+
+	// connect store
+	store, err := Connect(uri, reporter, append(opts, options.Client().SetAutoEncryptionOptions(autoOpts))...)
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
@@ -0,0 +1,48 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/256dpi/fire/stick"
+)
+
+type countedSecretModel struct {
+	Base  `json:"-" bson:",inline" coal:"counted-secrets"`
+	Count int `json:"count" coal:"coal-deterministic"`
+	stick.NoValidation
+}
+
+func TestEncryptionSchema(t *testing.T) {
+	keyID := primitive.Binary{Subtype: 4, Data: []byte("0123456789abcdef")}
+
+	schema, err := EncryptionSchema(&secretModel{}, keyID)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"bsonType": "object",
+		"encryptMetadata": bson.M{
+			"keyId": bson.A{keyID},
+		},
+		"properties": bson.M{
+			"name": bson.M{
+				"encrypt": bson.M{
+					"bsonType":  "string",
+					"algorithm": DeterministicAlgorithm,
+				},
+			},
+			"code": bson.M{
+				"encrypt": bson.M{
+					"algorithm": RandomAlgorithm,
+				},
+			},
+		},
+	}, schema)
+
+	schema, err = EncryptionSchema(&countedSecretModel{}, keyID)
+	assert.Error(t, err)
+	assert.Nil(t, schema)
+	assert.Equal(t, "unsupported type of deterministic field Count", err.Error())
+}
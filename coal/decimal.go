@@ -16,10 +16,15 @@ type Decimal = decimal.Decimal
 var decimalType = reflect.TypeOf(Decimal{})
 
 func init() {
-	Extend(func(builder *bsoncodec.RegistryBuilder) {
-		// register decimal encoder
-		var dve = bsoncodec.DefaultValueEncoders{}
-		builder.RegisterTypeEncoder(decimalType, bsoncodec.ValueEncoderFunc(func(ec bsoncodec.EncodeContext, w bsonrw.ValueWriter, v reflect.Value) error {
+	// decimal codecs
+	var dve = bsoncodec.DefaultValueEncoders{}
+	var dvd = bsoncodec.DefaultValueDecoders{}
+
+	// register decimal type
+	RegisterType(Type{
+		Type:     decimalType,
+		BSONType: "decimal",
+		Encoder: bsoncodec.ValueEncoderFunc(func(ec bsoncodec.EncodeContext, w bsonrw.ValueWriter, v reflect.Value) error {
 			// convert value
 			dec := v.Interface().(Decimal)
 			pd, ok := primitive.ParseDecimal128FromBigInt(dec.Coefficient(), int(dec.Exponent()))
@@ -34,11 +39,8 @@ func init() {
 			}
 
 			return nil
-		}))
-
-		// register decimal decoder
-		var dvd = bsoncodec.DefaultValueDecoders{}
-		builder.RegisterTypeDecoder(decimalType, bsoncodec.ValueDecoderFunc(func(dc bsoncodec.DecodeContext, r bsonrw.ValueReader, v reflect.Value) error {
+		}),
+		Decoder: bsoncodec.ValueDecoderFunc(func(dc bsoncodec.DecodeContext, r bsonrw.ValueReader, v reflect.Value) error {
 			// decode value
 			val := reflect.New(reflect.TypeOf(primitive.Decimal128{})).Elem()
 			err := dvd.Decimal128DecodeValue(dc, r, val)
@@ -57,6 +59,9 @@ func init() {
 			v.Set(reflect.ValueOf(decimal.NewFromBigInt(big, int32(exp))))
 
 			return nil
-		}))
+		}),
+		Parse: func(str string) (interface{}, error) {
+			return decimal.NewFromString(str)
+		},
 	})
 }
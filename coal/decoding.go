@@ -0,0 +1,172 @@
+package coal
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/256dpi/lungo"
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var modelType = reflect.TypeOf((*Model)(nil)).Elem()
+
+// ErrSchemaDrift is returned if a document does not match its model.
+var ErrSchemaDrift = xo.BF("schema drift")
+
+// Decoding defines how documents are verified when decoded into models.
+type Decoding int
+
+// The available decoding modes.
+const (
+	// DecodeLenient will not verify documents.
+	DecodeLenient Decoding = iota
+
+	// DecodeReport will report mismatching documents using the store reporter
+	// and decode them regardless.
+	DecodeReport
+
+	// DecodeStrict will fail to decode mismatching documents.
+	DecodeStrict
+)
+
+// VerifyDocument will verify that the provided document matches the specified
+// model. It returns ErrSchemaDrift if the document has unknown fields or is
+// missing required fields. Required fields are determined as described by
+// ValidationSchema. Internal fields prefixed with an underscore are ignored.
+func VerifyDocument(model Model, doc bson.Raw) error {
+	// get meta
+	meta := GetMeta(model)
+
+	// get elements
+	elements, err := doc.Elements()
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// collect keys
+	keys := make(map[string]bool, len(elements))
+	for _, element := range elements {
+		keys[element.Key()] = true
+	}
+
+	// check required fields
+	var missing []string
+	for _, field := range meta.OrderedFields {
+		// skip ignored and optional fields
+		if field.BSONKey == "" || field.Optional || hasOmitEmpty(meta.Type, field.Index) {
+			continue
+		}
+
+		// check key
+		if !keys[field.BSONKey] {
+			missing = append(missing, field.BSONKey)
+		}
+	}
+
+	// check unknown fields
+	var unknown []string
+	for key := range keys {
+		if !strings.HasPrefix(key, "_") && meta.DatabaseFields[key] == nil {
+			unknown = append(unknown, key)
+		}
+	}
+
+	// sort unknown
+	sort.Strings(unknown)
+
+	// check result
+	if len(missing) == 0 && len(unknown) == 0 {
+		return nil
+	}
+
+	// prepare details
+	var details []string
+	if len(missing) > 0 {
+		details = append(details, "missing "+strings.Join(missing, ", "))
+	}
+	if len(unknown) > 0 {
+		details = append(details, "unknown "+strings.Join(unknown, ", "))
+	}
+
+	// get ID
+	id, _ := doc.Lookup("_id").ObjectIDOK()
+
+	return ErrSchemaDrift.WrapF(`document "%s" in "%s": %s`, id.Hex(), meta.Collection, strings.Join(details, "; "))
+}
+
+func decode(store *Store, v interface{}, fn func(interface{}) error) error {
+	// decode directly if not verified
+	model, ok := v.(Model)
+	if !ok || store == nil || store.Decoding == DecodeLenient {
+		return xo.W(fn(v))
+	}
+
+	// decode document
+	var doc bson.Raw
+	err := fn(&doc)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// verify document
+	err = VerifyDocument(model, doc)
+	if err != nil && store.Decoding == DecodeStrict {
+		return err
+	} else if err != nil && store.reporter != nil {
+		store.reporter(err)
+	}
+
+	// decode model
+	err = bson.Unmarshal(doc, model)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	return nil
+}
+
+func decodeAll(ctx context.Context, store *Store, cursor lungo.ICursor, list interface{}) (bool, error) {
+	// get slice and element type
+	slice := reflect.ValueOf(list).Elem()
+	typ := slice.Type().Elem()
+	ptr := typ.Kind() == reflect.Ptr
+	if ptr {
+		typ = typ.Elem()
+	}
+
+	// check mode and type
+	if store == nil || store.Decoding == DecodeLenient || !reflect.PtrTo(typ).Implements(modelType) {
+		return false, nil
+	}
+
+	// ensure close
+	defer cursor.Close(ctx)
+
+	// reset slice
+	slice = slice.Slice(0, 0)
+
+	// decode documents
+	for cursor.Next(ctx) {
+		// decode model
+		value := reflect.New(typ)
+		err := decode(store, value.Interface(), cursor.Decode)
+		if err != nil {
+			return true, err
+		}
+
+		// add model
+		if ptr {
+			slice = reflect.Append(slice, value)
+		} else {
+			slice = reflect.Append(slice, value.Elem())
+		}
+	}
+
+	// set list
+	reflect.ValueOf(list).Elem().Set(slice)
+
+	return true, cursor.Err()
+}
@@ -0,0 +1,110 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestVerifyDocument(t *testing.T) {
+	id := New()
+
+	doc, err := bson.Marshal(bson.M{
+		"_id":       id,
+		"_lk":       1,
+		"title":     "foo",
+		"published": true,
+		"text_body": "bar",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyDocument(&postModel{}, doc))
+
+	doc, err = bson.Marshal(bson.M{
+		"_id":    id,
+		"title":  "foo",
+		"legacy": "bar",
+		"author": "baz",
+	})
+	assert.NoError(t, err)
+	err = VerifyDocument(&postModel{}, doc)
+	assert.True(t, ErrSchemaDrift.Is(err))
+	assert.Equal(t, `document "`+id.Hex()+`" in "posts": missing published, text_body; unknown author, legacy: schema drift`, err.Error())
+
+	doc, err = bson.Marshal(bson.M{
+		"_id":       id,
+		"name":      "foo",
+		"count":     1,
+		"rate":      nil,
+		"state":     "draft",
+		"opt_state": nil,
+		"date":      nil,
+		"tags":      nil,
+		"data":      nil,
+		"item":      nil,
+		"owner_id":  New(),
+		"time":      nil,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyDocument(&schemaModel{}, doc))
+}
+
+func TestStoreDecoding(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		defer func() {
+			tester.Store.Decoding = DecodeLenient
+		}()
+
+		id := New()
+		_, err := tester.Store.C(&postModel{}).InsertOne(nil, bson.M{
+			"_id":    id,
+			"title":  "foo",
+			"legacy": true,
+		})
+		assert.NoError(t, err)
+
+		var post postModel
+		found, err := tester.Store.M(&postModel{}).Find(nil, &post, id, false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "foo", post.Title)
+
+		tester.Store.Decoding = DecodeStrict
+
+		found, err = tester.Store.M(&postModel{}).Find(nil, &post, id, false)
+		assert.True(t, ErrSchemaDrift.Is(err))
+		assert.False(t, found)
+
+		var posts []postModel
+		err = tester.Store.M(&postModel{}).FindAll(nil, &posts, bson.M{}, nil, 0, 0, false, NoTransaction)
+		assert.True(t, ErrSchemaDrift.Is(err))
+
+		var raw bson.M
+		err = tester.Store.C(&postModel{}).FindOne(nil, bson.M{"_id": id}).Decode(&raw)
+		assert.NoError(t, err)
+		assert.Equal(t, true, raw["legacy"])
+
+		var reported []error
+		tester.Store.Decoding = DecodeReport
+		reporter := tester.Store.reporter
+		tester.Store.reporter = func(err error) {
+			reported = append(reported, err)
+		}
+		defer func() {
+			tester.Store.reporter = reporter
+		}()
+
+		posts = nil
+		err = tester.Store.M(&postModel{}).FindAll(nil, &posts, bson.M{}, nil, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+		assert.Len(t, posts, 1)
+		assert.Equal(t, "foo", posts[0].Title)
+		assert.Len(t, reported, 1)
+		assert.True(t, ErrSchemaDrift.Is(reported[0]))
+
+		err = tester.Store.M(&postModel{}).FindAll(nil, &posts, bson.M{}, nil, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+		assert.Len(t, posts, 1)
+		assert.Len(t, reported, 2)
+	})
+}
@@ -0,0 +1,180 @@
+package coal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// Denormalization declares a field of a target model that holds a denormalized
+// copy of a field of the source model referenced by a relationship.
+type Denormalization struct {
+	// The source model and field e.g. &User{} and "Name".
+	Source      Model
+	SourceField string
+
+	// The target model, the to-one or to-many relationship field referencing
+	// the source model and the field holding the copy e.g. &Post{}, "Author"
+	// and "AuthorName".
+	Target      Model
+	Reference   string
+	TargetField string
+}
+
+func (d *Denormalization) validate() {
+	// get metas
+	sourceMeta := GetMeta(d.Source)
+	targetMeta := GetMeta(d.Target)
+
+	// check reference
+	ref := targetMeta.Fields[d.Reference]
+	if ref == nil || (!ref.ToOne && !ref.ToMany) || ref.RelType != sourceMeta.PluralName {
+		panic(fmt.Sprintf(`coal: reference "%s" is not a relationship to "%s"`, d.Reference, sourceMeta.PluralName))
+	}
+
+	// check fields
+	sourceField := sourceMeta.Fields[d.SourceField]
+	targetField := targetMeta.Fields[d.TargetField]
+	if sourceField == nil || targetField == nil || sourceField.Type != targetField.Type {
+		panic(fmt.Sprintf(`coal: fields "%s" and "%s" are missing or of different types`, d.SourceField, d.TargetField))
+	}
+}
+
+// Denormalizer keeps denormalized copies of fields in sync by watching the
+// source collections using change streams.
+//
+// Note: Changes made while the denormalizer is not running are not applied.
+// Sync may be used to update all copies e.g. after a deployment.
+type Denormalizer struct {
+	store            *Store
+	denormalizations []Denormalization
+	streams          []*Stream
+	closed           chan struct{}
+	once             sync.Once
+}
+
+// Denormalize will validate the provided denormalizations and open a stream for
+// each source model that updates the denormalized copies whenever a source
+// document has been created or updated. Errors are reported to the optional
+// reporter and the streams are resumed after a delay.
+func Denormalize(store *Store, reporter func(error), denormalizations ...Denormalization) *Denormalizer {
+	// validate denormalizations
+	for i := range denormalizations {
+		denormalizations[i].validate()
+	}
+
+	// create denormalizer
+	d := &Denormalizer{
+		store:            store,
+		denormalizations: denormalizations,
+		closed:           make(chan struct{}),
+	}
+
+	// group denormalizations by source
+	var sources []*Meta
+	groups := map[*Meta][]Denormalization{}
+	for _, denormalization := range denormalizations {
+		meta := GetMeta(denormalization.Source)
+		if groups[meta] == nil {
+			sources = append(sources, meta)
+		}
+		groups[meta] = append(groups[meta], denormalization)
+	}
+
+	// open streams
+	for _, meta := range sources {
+		group := groups[meta]
+		d.streams = append(d.streams, OpenStream(store, group[0].Source, nil, func(event Event, id ID, model Model, err error, _ []byte) error {
+			switch event {
+			case Created, Updated:
+				// apply denormalizations
+				for _, denormalization := range group {
+					_, err := d.apply(context.Background(), denormalization, model)
+					if err != nil {
+						return err
+					}
+				}
+			case Errored:
+				// report error
+				if reporter != nil {
+					reporter(err)
+				}
+
+				// delay resumption
+				select {
+				case <-time.After(time.Second):
+				case <-d.closed:
+					return ErrStop.Wrap()
+				}
+			}
+
+			return nil
+		}))
+	}
+
+	return d
+}
+
+// Sync will update all denormalized copies using the current values of all
+// source documents. It returns the number of updated target documents.
+func (d *Denormalizer) Sync(ctx context.Context) (int64, error) {
+	// apply denormalizations
+	var total int64
+	for _, denormalization := range d.denormalizations {
+		err := d.store.Iterate(ctx, denormalization.Source, bson.M{}, 0, func(batch []Model) error {
+			for _, model := range batch {
+				n, err := d.apply(ctx, denormalization, model)
+				if err != nil {
+					return err
+				}
+				total += n
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// Close will close the denormalizer.
+func (d *Denormalizer) Close() {
+	// signal close
+	d.once.Do(func() {
+		close(d.closed)
+	})
+
+	// close streams
+	for _, stream := range d.streams {
+		stream.Close()
+	}
+}
+
+func (d *Denormalizer) apply(ctx context.Context, denormalization Denormalization, model Model) (int64, error) {
+	// get value
+	value := stick.MustGet(model, denormalization.SourceField)
+
+	// update outdated copies
+	n, err := d.store.M(denormalization.Target).UpdateAll(ctx, bson.M{
+		denormalization.Reference: model.ID(),
+		denormalization.TargetField: bson.M{
+			"$ne": value,
+		},
+	}, bson.M{
+		"$set": bson.M{
+			denormalization.TargetField: value,
+		},
+	}, false)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,120 @@
+package coal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type authorModel struct {
+	Base `json:"-" bson:",inline" coal:"authors"`
+	Name string `json:"name"`
+}
+
+func (m *authorModel) Validate() error {
+	return nil
+}
+
+type articleModel struct {
+	Base        `json:"-" bson:",inline" coal:"articles"`
+	Author      ID     `json:"-" coal:"author:authors"`
+	AuthorName  string `json:"author-name"`
+	Editors     []ID   `json:"-" coal:"editors:authors"`
+	EditorNames string `json:"editor-names"`
+}
+
+func (m *articleModel) Validate() error {
+	return nil
+}
+
+func TestDenormalize(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		_, err := tester.Store.C(&authorModel{}).DeleteMany(nil, bson.M{})
+		assert.NoError(t, err)
+		_, err = tester.Store.C(&articleModel{}).DeleteMany(nil, bson.M{})
+		assert.NoError(t, err)
+
+		assert.PanicsWithValue(t, `coal: reference "AuthorName" is not a relationship to "authors"`, func() {
+			Denormalize(tester.Store, nil, Denormalization{
+				Source:      &authorModel{},
+				SourceField: "Name",
+				Target:      &articleModel{},
+				Reference:   "AuthorName",
+				TargetField: "AuthorName",
+			})
+		})
+
+		assert.PanicsWithValue(t, `coal: fields "Name" and "Editors" are missing or of different types`, func() {
+			Denormalize(tester.Store, nil, Denormalization{
+				Source:      &authorModel{},
+				SourceField: "Name",
+				Target:      &articleModel{},
+				Reference:   "Author",
+				TargetField: "Editors",
+			})
+		})
+
+		author := tester.Insert(&authorModel{
+			Name: "foo",
+		}).(*authorModel)
+		other := tester.Insert(&authorModel{
+			Name: "bar",
+		}).(*authorModel)
+
+		article1 := tester.Insert(&articleModel{
+			Author:  author.ID(),
+			Editors: []ID{other.ID()},
+		})
+		article2 := tester.Insert(&articleModel{
+			Author:      other.ID(),
+			AuthorName:  "bar",
+			EditorNames: "bar",
+		})
+
+		denormalizer := Denormalize(tester.Store, func(err error) {
+			panic(err)
+		}, Denormalization{
+			Source:      &authorModel{},
+			SourceField: "Name",
+			Target:      &articleModel{},
+			Reference:   "Author",
+			TargetField: "AuthorName",
+		}, Denormalization{
+			Source:      &authorModel{},
+			SourceField: "Name",
+			Target:      &articleModel{},
+			Reference:   "Editors",
+			TargetField: "EditorNames",
+		})
+		defer denormalizer.Close()
+
+		n, err := denormalizer.Sync(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		res := tester.Fetch(&articleModel{}, article1.ID()).(*articleModel)
+		assert.Equal(t, "foo", res.AuthorName)
+		assert.Equal(t, "bar", res.EditorNames)
+
+		n, err = denormalizer.Sync(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+
+		time.Sleep(100 * time.Millisecond)
+
+		tester.Update(other, bson.M{
+			"$set": bson.M{
+				"Name": "baz",
+			},
+		})
+
+		assert.Eventually(t, func() bool {
+			res1 := tester.Fetch(&articleModel{}, article1.ID()).(*articleModel)
+			res2 := tester.Fetch(&articleModel{}, article2.ID()).(*articleModel)
+			return res1.AuthorName == "foo" && res1.EditorNames == "baz" &&
+				res2.AuthorName == "baz" && res2.EditorNames == "bar"
+		}, time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,152 @@
+package coal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// Clone will return a deep copy of the provided model. The copy is created
+// by transferring the model using BSON and therefore only contains the base
+// and the fields that are stored in the database.
+func Clone[M Model](model M) (M, error) {
+	// make clone
+	clone := GetMeta(model).Make().(M)
+
+	// transfer model
+	err := stick.BSON.Transfer(model, clone)
+	if err != nil {
+		var zero M
+		return zero, xo.W(err)
+	}
+
+	return clone, nil
+}
+
+// Difference describes the change of a single field between two models.
+type Difference struct {
+	// The field name e.g. "Title".
+	Field string
+
+	// The field values.
+	Before interface{}
+	After  interface{}
+}
+
+// Diff will return the differences between the stored fields of the provided
+// models in field order. Values are compared using their BSON representation
+// as stored in the database. Either model may be nil to compare against the
+// zero model e.g. when a model has been created or deleted.
+//
+// Note: Diff will panic if the models are of different types or both are nil.
+func Diff(a, b Model) []Difference {
+	// get meta
+	var meta *Meta
+	if a != nil {
+		meta = GetMeta(a)
+	} else if b != nil {
+		meta = GetMeta(b)
+	} else {
+		panic("coal: unable to diff nil models")
+	}
+
+	// check types
+	if a != nil && b != nil && GetMeta(b) != meta {
+		panic(fmt.Sprintf(`coal: unable to diff "%s" with "%s"`, meta.Name, GetMeta(b).Name))
+	}
+
+	// ensure models
+	if a == nil {
+		a = meta.Make()
+	}
+	if b == nil {
+		b = meta.Make()
+	}
+
+	// compare fields
+	var diff []Difference
+	for _, field := range meta.OrderedFields {
+		// skip fields that are not stored
+		if field.BSONKey == "" {
+			continue
+		}
+
+		// get values
+		before := stick.MustGet(a, field.Name)
+		after := stick.MustGet(b, field.Name)
+
+		// check equality
+		if Equal(before, after) {
+			continue
+		}
+
+		// add difference
+		diff = append(diff, Difference{
+			Field:  field.Name,
+			Before: before,
+			After:  after,
+		})
+	}
+
+	return diff
+}
+
+// Equal will return whether the provided field values are equal. Values are
+// compared using their BSON representation as stored in the database, with
+// document keys sorted as Go maps are marshaled in random order. Values that
+// cannot be marshaled are compared using reflection.
+func Equal(a, b interface{}) bool {
+	// get canonical values
+	av, err1 := canonicalValue(a)
+	bv, err2 := canonicalValue(b)
+	if err1 != nil || err2 != nil {
+		return reflect.DeepEqual(a, b)
+	}
+
+	return reflect.DeepEqual(av, bv)
+}
+
+func canonicalValue(v interface{}) (interface{}, error) {
+	// marshal value
+	typ, data, err := bson.MarshalValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode value
+	var value interface{}
+	err = bson.RawValue{Type: typ, Value: data}.Unmarshal(&value)
+	if err != nil {
+		return nil, err
+	}
+
+	return canonicalize(value), nil
+}
+
+func canonicalize(v interface{}) interface{} {
+	switch value := v.(type) {
+	case bson.D:
+		// sort keys
+		doc := make(bson.D, len(value))
+		for i, elem := range value {
+			doc[i] = bson.E{Key: elem.Key, Value: canonicalize(elem.Value)}
+		}
+		sort.Slice(doc, func(i, j int) bool {
+			return doc[i].Key < doc[j].Key
+		})
+		return doc
+	case bson.A:
+		list := make(bson.A, len(value))
+		for i, item := range value {
+			list[i] = canonicalize(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
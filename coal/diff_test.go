@@ -0,0 +1,101 @@
+package coal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire/stick"
+)
+
+func TestClone(t *testing.T) {
+	id1, id2 := New(), New()
+
+	selection := &selectionModel{
+		Base:  B(),
+		Name:  "foo",
+		Posts: []ID{id1, id2},
+	}
+
+	clone, err := Clone(selection)
+	assert.NoError(t, err)
+	assert.Equal(t, selection, clone)
+	assert.NotSame(t, selection, clone)
+
+	clone.Posts[0] = New()
+	assert.Equal(t, []ID{id1, id2}, selection.Posts)
+}
+
+func TestDiff(t *testing.T) {
+	id1, id2 := New(), New()
+
+	a := &selectionModel{
+		Base:  B(),
+		Name:  "foo",
+		Posts: []ID{id1},
+	}
+
+	b, err := Clone(a)
+	assert.NoError(t, err)
+	assert.Empty(t, Diff(a, b))
+
+	b.Name = "bar"
+	b.Posts = append(b.Posts, id2)
+	assert.Equal(t, []Difference{
+		{Field: "Name", Before: "foo", After: "bar"},
+		{Field: "Posts", Before: []ID{id1}, After: []ID{id1, id2}},
+	}, Diff(a, b))
+
+	// times are compared as stored
+	now := time.Now()
+	c := &noteModel{Base: B(), Title: "foo", CreatedAt: now}
+	d := &noteModel{Base: c.Base, Title: "foo", CreatedAt: now.UTC().Round(0)}
+	assert.Empty(t, Diff(c, d))
+
+	// nil models
+	assert.Equal(t, []Difference{
+		{Field: "Name", Before: "", After: "foo"},
+		{Field: "Posts", Before: []ID(nil), After: []ID{id1}},
+	}, Diff(nil, a))
+	assert.Equal(t, []Difference{
+		{Field: "Name", Before: "foo", After: ""},
+		{Field: "Posts", Before: []ID{id1}, After: []ID(nil)},
+	}, Diff(a, nil))
+
+	assert.Panics(t, func() {
+		Diff(a, c)
+	})
+	assert.Panics(t, func() {
+		Diff(nil, nil)
+	})
+}
+
+func TestEqualMaps(t *testing.T) {
+	newData := func() stick.Map {
+		return stick.Map{"a": 1, "b": "2", "c": 3.0, "d": true, "e": []string{"f"}, "g": stick.Map{"h": 1, "i": 2}}
+	}
+
+	a := &mapModel{
+		Base:   B(),
+		Labels: map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5", "f": "6"},
+		Data:   newData(),
+	}
+
+	b, err := Clone(a)
+	assert.NoError(t, err)
+	b.Data = newData()
+
+	// maps are compared independent of their order
+	for i := 0; i < 100; i++ {
+		assert.True(t, Equal(a.Labels, b.Labels))
+		assert.True(t, Equal(a.Data, b.Data))
+		assert.Empty(t, Diff(a, b))
+	}
+
+	b.Data["g"].(stick.Map)["i"] = 3
+	assert.False(t, Equal(a.Data, b.Data))
+	assert.Equal(t, []Difference{
+		{Field: "Data", Before: a.Data, After: b.Data},
+	}, Diff(a, b))
+}
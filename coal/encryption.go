@@ -0,0 +1,52 @@
+package coal
+
+import (
+	"context"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// The flags used to mark fields for field-level encryption. Fields flagged with
+// EncryptedFlag are encrypted randomly and cannot be queried. Fields flagged
+// with DeterministicFlag are encrypted deterministically and support queries
+// for equality.
+const (
+	EncryptedFlag     = "coal-encrypted"
+	DeterministicFlag = "coal-deterministic"
+)
+
+// Encryption describes how a field is encrypted.
+type Encryption int
+
+// The available encryption modes.
+const (
+	Unencrypted Encryption = iota
+	Random
+	Deterministic
+)
+
+// GetEncryption returns the encryption of the specified field.
+func GetEncryption(model Model, field string) Encryption {
+	// get field
+	f := GetMeta(model).Fields[field]
+	if f == nil {
+		return Unencrypted
+	}
+
+	// check flags
+	if stick.Contains(f.Flags, DeterministicFlag) {
+		return Deterministic
+	} else if stick.Contains(f.Flags, EncryptedFlag) {
+		return Random
+	}
+
+	return Unencrypted
+}
+
+// Encrypter encrypts values of deterministically encrypted fields to allow
+// queries for equality.
+type Encrypter interface {
+	// EncryptValue returns the deterministic ciphertext of the provided value
+	// for the specified field.
+	EncryptValue(ctx context.Context, model Model, field string, value interface{}) (interface{}, error)
+}
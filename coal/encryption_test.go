@@ -0,0 +1,25 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type secretModel struct {
+	Base   `json:"-" bson:",inline" coal:"secrets"`
+	Name   string `json:"name" coal:"coal-deterministic"`
+	Code   string `json:"code" coal:"coal-encrypted"`
+	Public string `json:"public"`
+}
+
+func (m *secretModel) Validate() error {
+	return nil
+}
+
+func TestGetEncryption(t *testing.T) {
+	assert.Equal(t, Deterministic, GetEncryption(&secretModel{}, "Name"))
+	assert.Equal(t, Random, GetEncryption(&secretModel{}, "Code"))
+	assert.Equal(t, Unencrypted, GetEncryption(&secretModel{}, "Public"))
+	assert.Equal(t, Unencrypted, GetEncryption(&secretModel{}, "Foo"))
+}
@@ -0,0 +1,130 @@
+package coal
+
+import (
+	"errors"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
+)
+
+// ErrDuplicateKey is used to classify errors caused by a violated unique index.
+var ErrDuplicateKey = xo.BF("duplicate key")
+
+// ErrInvalidDocument is used to classify errors caused by a document that
+// failed the collection validation.
+var ErrInvalidDocument = xo.BF("invalid document")
+
+// ErrTimeout is used to classify errors caused by an exceeded operation
+// timeout or a cancelled context.
+var ErrTimeout = xo.BF("timeout")
+
+// ErrTransient is used to classify errors caused by a temporary condition
+// e.g. a network error or an election. Operations may succeed when retried.
+var ErrTransient = xo.BF("transient error")
+
+// the server error codes considered transient
+var transientCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// Classify will classify the provided error using ErrDuplicateKey,
+// ErrInvalidDocument, ErrTimeout or ErrTransient. The returned error matches
+// both the classification and the original error. Errors that cannot be
+// classified are returned as is. All errors returned by collections are
+// classified.
+func Classify(err error) error {
+	// check error
+	if err == nil {
+		return nil
+	}
+
+	// get class
+	var class error
+	if IsDuplicate(err) {
+		class = ErrDuplicateKey.Self()
+	} else if hasErrorCode(err, 121) {
+		class = ErrInvalidDocument.Self()
+	} else if mongo.IsTimeout(err) || hasErrorCode(err, 50) {
+		class = ErrTimeout.Self()
+	} else if isTransientError(err) {
+		class = ErrTransient.Self()
+	}
+
+	// check class
+	if class == nil || errors.Is(err, class) {
+		return err
+	}
+
+	return &classifiedError{
+		class: class,
+		err:   err,
+	}
+}
+
+func isTransientError(err error) bool {
+	// check network error
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+
+	// check server error
+	var srvErr mongo.ServerError
+	if !errors.As(err, &srvErr) {
+		return false
+	}
+
+	// check labels
+	if srvErr.HasErrorLabel(driver.TransientTransactionError) || srvErr.HasErrorLabel("RetryableWriteError") {
+		return true
+	}
+
+	// check codes
+	for _, code := range transientCodes {
+		if srvErr.HasErrorCode(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isRetryableWrite(err error) bool {
+	// check server error
+	var srvErr mongo.ServerError
+	if !errors.As(err, &srvErr) {
+		return false
+	}
+
+	// the failed attempt may have been applied unless the server reports that
+	// no writes have been performed, a replay could then apply changes twice
+	return srvErr.HasErrorLabel("NoWritesPerformed")
+}
+
+func hasErrorCode(err error, code int) bool {
+	var srvErr mongo.ServerError
+	return errors.As(err, &srvErr) && srvErr.HasErrorCode(code)
+}
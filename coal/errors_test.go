@@ -0,0 +1,139 @@
+package coal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestClassify(t *testing.T) {
+	assert.NoError(t, Classify(nil))
+
+	duplicate := mongo.WriteException{
+		WriteErrors: []mongo.WriteError{
+			{Code: 11000, Message: "E11000 duplicate key error collection: test.posts"},
+		},
+	}
+	err := Classify(duplicate)
+	assert.True(t, ErrDuplicateKey.Is(err))
+	assert.True(t, IsDuplicate(err))
+	assert.Equal(t, duplicate.Error(), err.Error())
+
+	var we mongo.WriteException
+	assert.True(t, errors.As(err, &we))
+
+	err = Classify(mongo.CommandError{Code: 121, Message: "Document failed validation"})
+	assert.True(t, ErrInvalidDocument.Is(err))
+
+	err = Classify(mongo.CommandError{Code: 50, Message: "operation exceeded time limit"})
+	assert.True(t, ErrTimeout.Is(err))
+
+	err = Classify(context.DeadlineExceeded)
+	assert.True(t, ErrTimeout.Is(err))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	err = Classify(mongo.CommandError{Code: 189, Message: "primary stepped down"})
+	assert.True(t, ErrTransient.Is(err))
+
+	err = Classify(mongo.CommandError{Code: 1, Labels: []string{"RetryableWriteError"}})
+	assert.True(t, ErrTransient.Is(err))
+
+	err = Classify(mongo.CommandError{Code: 2, Message: "bad value"})
+	assert.False(t, ErrTransient.Is(err))
+	assert.False(t, ErrTimeout.Is(err))
+	assert.Equal(t, mongo.CommandError{Code: 2, Message: "bad value"}, err)
+
+	classified := Classify(context.DeadlineExceeded)
+	assert.Equal(t, classified, Classify(classified))
+}
+
+func TestCollectionRetries(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		coll := tester.Store.C(&postModel{})
+
+		transient := mongo.CommandError{Code: 189, Message: "primary stepped down"}
+
+		var attempts int
+		err := coll.run(context.Background(), false, func() error {
+			attempts++
+			return transient
+		})
+		assert.True(t, ErrTransient.Is(err))
+		assert.Equal(t, 1, attempts)
+
+		tester.Store.Retries = 2
+		defer func() {
+			tester.Store.Retries = 0
+		}()
+
+		attempts = 0
+		start := time.Now()
+		err = coll.run(context.Background(), false, func() error {
+			attempts++
+			return transient
+		})
+		assert.True(t, ErrTransient.Is(err))
+		assert.Equal(t, 3, attempts)
+		assert.True(t, time.Since(start) >= 150*time.Millisecond)
+
+		attempts = 0
+		err = coll.run(context.Background(), false, func() error {
+			attempts++
+			if attempts < 2 {
+				return transient
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+
+		attempts = 0
+		err = coll.run(context.Background(), false, func() error {
+			attempts++
+			return mongo.CommandError{Code: 121}
+		})
+		assert.True(t, ErrInvalidDocument.Is(err))
+		assert.Equal(t, 1, attempts)
+
+		attempts = 0
+		err = coll.run(context.Background(), true, func() error {
+			attempts++
+			return transient
+		})
+		assert.True(t, ErrTransient.Is(err))
+		assert.Equal(t, 1, attempts)
+
+		attempts = 0
+		err = coll.run(context.Background(), true, func() error {
+			attempts++
+			return mongo.CommandError{Code: 189, Labels: []string{"RetryableWriteError"}}
+		})
+		assert.True(t, ErrTransient.Is(err))
+		assert.Equal(t, 1, attempts)
+
+		attempts = 0
+		err = coll.run(context.Background(), true, func() error {
+			attempts++
+			if attempts < 2 {
+				return mongo.CommandError{Code: 189, Labels: []string{"RetryableWriteError", "NoWritesPerformed"}}
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+
+		attempts = 0
+		err = tester.Store.T(nil, false, func(ctx context.Context) error {
+			return coll.run(ctx, false, func() error {
+				attempts++
+				return transient
+			})
+		})
+		assert.True(t, ErrTransient.Is(err))
+		assert.Equal(t, 1, attempts)
+	})
+}
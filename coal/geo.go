@@ -0,0 +1,152 @@
+package coal
+
+import (
+	"math"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// EarthRadius is the equatorial radius of the earth in meters used to convert
+// distances to radians.
+const EarthRadius = 6378100.0
+
+// Point is a GeoJSON point. Fields of this type should be indexed using
+// AddGeoIndex to support geospatial queries.
+type Point struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// NewPoint returns a point for the provided longitude and latitude.
+func NewPoint(lng, lat float64) Point {
+	return Point{
+		Type:        "Point",
+		Coordinates: []float64{lng, lat},
+	}
+}
+
+// Lng returns the longitude of the point.
+func (p Point) Lng() float64 {
+	if len(p.Coordinates) != 2 {
+		return 0
+	}
+
+	return p.Coordinates[0]
+}
+
+// Lat returns the latitude of the point.
+func (p Point) Lat() float64 {
+	if len(p.Coordinates) != 2 {
+		return 0
+	}
+
+	return p.Coordinates[1]
+}
+
+// Validate will validate the point.
+func (p Point) Validate() error {
+	// check type
+	if p.Type != "Point" {
+		return xo.SF("invalid point type")
+	}
+
+	// check coordinates
+	if len(p.Coordinates) != 2 {
+		return xo.SF("invalid point coordinates")
+	}
+
+	// check range
+	if !(math.Abs(p.Lng()) <= 180 && math.Abs(p.Lat()) <= 90) {
+		return xo.SF("point coordinates out of range")
+	}
+
+	return nil
+}
+
+// Polygon is a GeoJSON polygon.
+type Polygon struct {
+	Type        string        `json:"type" bson:"type"`
+	Coordinates [][][]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// NewPolygon returns a polygon with an exterior ring through the provided
+// points. The ring is closed automatically if necessary.
+func NewPolygon(points ...Point) Polygon {
+	// prepare ring
+	ring := make([][]float64, 0, len(points)+1)
+	for _, point := range points {
+		ring = append(ring, []float64{point.Lng(), point.Lat()})
+	}
+
+	// close ring
+	if len(points) > 0 {
+		first, last := points[0], points[len(points)-1]
+		if first.Lng() != last.Lng() || first.Lat() != last.Lat() {
+			ring = append(ring, []float64{first.Lng(), first.Lat()})
+		}
+	}
+
+	return Polygon{
+		Type:        "Polygon",
+		Coordinates: [][][]float64{ring},
+	}
+}
+
+// Box returns a rectangular polygon for the provided bounds.
+func Box(minLng, minLat, maxLng, maxLat float64) Polygon {
+	return NewPolygon(
+		NewPoint(minLng, minLat),
+		NewPoint(maxLng, minLat),
+		NewPoint(maxLng, maxLat),
+		NewPoint(minLng, maxLat),
+	)
+}
+
+// Near returns a filter expression that matches points within the specified
+// distance range in meters and sorts them by distance. A zero distance
+// disables the respective bound.
+//
+// Note: Near may not be used with counts, use WithinRadius instead.
+func Near(point Point, minDistance, maxDistance float64) bson.M {
+	// prepare expression
+	near := bson.D{
+		{Key: "$geometry", Value: point},
+	}
+
+	// set bounds
+	if minDistance > 0 {
+		near = append(near, bson.E{Key: "$minDistance", Value: minDistance})
+	}
+	if maxDistance > 0 {
+		near = append(near, bson.E{Key: "$maxDistance", Value: maxDistance})
+	}
+
+	return bson.M{
+		"$near": near,
+	}
+}
+
+// Within returns a filter expression that matches points within the provided
+// GeoJSON geometry e.g. a Polygon.
+func Within(geometry interface{}) bson.M {
+	return bson.M{
+		"$geoWithin": bson.M{
+			"$geometry": geometry,
+		},
+	}
+}
+
+// WithinRadius returns a filter expression that matches points within the
+// specified radius in meters around the provided point. Unlike Near, the
+// results are not sorted by distance.
+func WithinRadius(point Point, radius float64) bson.M {
+	return bson.M{
+		"$geoWithin": bson.M{
+			"$centerSphere": bson.A{
+				bson.A{point.Lng(), point.Lat()},
+				radius / EarthRadius,
+			},
+		},
+	}
+}
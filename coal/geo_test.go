@@ -0,0 +1,134 @@
+package coal
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type placeModel struct {
+	Base     `json:"-" bson:",inline" coal:"places"`
+	Name     string `json:"name"`
+	Location Point  `json:"location"`
+}
+
+func (m *placeModel) Validate() error {
+	return m.Location.Validate()
+}
+
+func init() {
+	AddGeoIndex(&placeModel{}, "Location")
+}
+
+func TestPoint(t *testing.T) {
+	point := NewPoint(8.5, 47.4)
+	assert.Equal(t, Point{
+		Type:        "Point",
+		Coordinates: []float64{8.5, 47.4},
+	}, point)
+	assert.Equal(t, 8.5, point.Lng())
+	assert.Equal(t, 47.4, point.Lat())
+	assert.NoError(t, point.Validate())
+
+	assert.Equal(t, "invalid point type", Point{}.Validate().Error())
+	assert.Equal(t, "invalid point coordinates", Point{Type: "Point"}.Validate().Error())
+	assert.Equal(t, "point coordinates out of range", NewPoint(190, 0).Validate().Error())
+	assert.Equal(t, "point coordinates out of range", NewPoint(0, math.NaN()).Validate().Error())
+	assert.Zero(t, Point{}.Lng())
+	assert.Zero(t, Point{}.Lat())
+}
+
+func TestPolygon(t *testing.T) {
+	assert.Equal(t, Polygon{
+		Type: "Polygon",
+		Coordinates: [][][]float64{
+			{{0, 0}, {2, 0}, {2, 1}, {0, 1}, {0, 0}},
+		},
+	}, Box(0, 0, 2, 1))
+
+	assert.Equal(t, Polygon{
+		Type: "Polygon",
+		Coordinates: [][][]float64{
+			{{0, 0}, {1, 0}, {0, 1}, {0, 0}},
+		},
+	}, NewPolygon(NewPoint(0, 0), NewPoint(1, 0), NewPoint(0, 1), NewPoint(0, 0)))
+}
+
+func TestGeoIndex(t *testing.T) {
+	assert.Equal(t, Index{
+		Fields: []string{"Location"},
+		Keys: bson.D{
+			{Key: "location", Value: "2dsphere"},
+		},
+	}, GetMeta(&placeModel{}).Indexes[1])
+}
+
+func TestGeoQueries(t *testing.T) {
+	trans := NewTranslator(&placeModel{})
+
+	doc, err := trans.Document(bson.M{
+		"Location": Near(NewPoint(1, 2), 10, 100),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "location", Value: bson.D{
+			{Key: "$near", Value: bson.D{
+				{Key: "$geometry", Value: bson.D{
+					{Key: "type", Value: "Point"},
+					{Key: "coordinates", Value: bson.A{1.0, 2.0}},
+				}},
+				{Key: "$minDistance", Value: 10.0},
+				{Key: "$maxDistance", Value: 100.0},
+			}},
+		}},
+	}, doc)
+
+	doc, err = trans.Document(bson.M{
+		"Location": WithinRadius(NewPoint(1, 2), EarthRadius),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "location", Value: bson.D{
+			{Key: "$geoWithin", Value: bson.D{
+				{Key: "$centerSphere", Value: bson.A{
+					bson.A{1.0, 2.0},
+					1.0,
+				}},
+			}},
+		}},
+	}, doc)
+
+	withTester(t, func(t *testing.T, tester *Tester) {
+		if tester.Store.Lungo() {
+			return
+		}
+
+		_ = tester.Store.C(&placeModel{}).Native().Drop(nil)
+
+		err := EnsureIndexes(tester.Store, &placeModel{})
+		assert.NoError(t, err)
+
+		tester.Insert(&placeModel{Name: "zurich", Location: NewPoint(8.54, 47.37)})
+		tester.Insert(&placeModel{Name: "bern", Location: NewPoint(7.45, 46.95)})
+		tester.Insert(&placeModel{Name: "berlin", Location: NewPoint(13.40, 52.52)})
+
+		var places []placeModel
+		err = tester.Store.M(&placeModel{}).FindAll(nil, &places, bson.M{
+			"Location": Near(NewPoint(8.5, 47.4), 0, 200000),
+		}, nil, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+		assert.Len(t, places, 2)
+		assert.Equal(t, "zurich", places[0].Name)
+		assert.Equal(t, "bern", places[1].Name)
+
+		count, err := tester.Store.M(&placeModel{}).Count(nil, bson.M{
+			"Location": Within(Box(5, 45, 11, 48)),
+		}, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		_ = tester.Store.C(&placeModel{}).Native().Drop(nil)
+	})
+}
@@ -0,0 +1,294 @@
+package coal
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MonitorHooks are callbacks for the connection events of a store connected
+// using Connect. The hooks are called synchronously by the driver and should
+// therefore return quickly.
+type MonitorHooks struct {
+	// The function called for all connection pool events.
+	PoolEvent func(evt *event.PoolEvent)
+
+	// The function called for all server heartbeats. The error is set if the
+	// heartbeat failed.
+	Heartbeat func(address string, duration time.Duration, err error)
+
+	// The function called when the topology changed.
+	TopologyChanged func(evt *event.TopologyDescriptionChangedEvent)
+
+	// The function called for commands that took at least the slow query
+	// threshold to complete e.g. to log expensive queries.
+	SlowQuery func(query SlowQuery)
+
+	// The duration after which commands are reported as slow queries.
+	//
+	// Default: 100ms.
+	SlowQueryThreshold time.Duration
+}
+
+// PoolStats contains statistics about the connection pool.
+type PoolStats struct {
+	// The number of open connections.
+	Open int64
+
+	// The number of connections currently checked out.
+	InUse int64
+
+	// The total number of created and closed connections.
+	Created int64
+	Closed  int64
+
+	// The total number of failed connection checkouts.
+	CheckoutFailures int64
+
+	// The total number of times the pool has been cleared.
+	Cleared int64
+}
+
+// ServerHealth describes the health of a single server as reported by the
+// last heartbeat.
+type ServerHealth struct {
+	// The server address.
+	Address string
+
+	// Whether the last heartbeat succeeded.
+	Healthy bool
+
+	// The duration of the last heartbeat.
+	Duration time.Duration
+
+	// The error of the last heartbeat, if failed.
+	Error error
+
+	// The time of the last heartbeat.
+	Checked time.Time
+}
+
+// Health describes the health of a store.
+type Health struct {
+	// The round trip time of a ping.
+	Latency time.Duration
+
+	// The topology kind e.g. "ReplicaSetWithPrimary", if known.
+	Topology string
+
+	// The connection pool statistics.
+	Pool PoolStats
+
+	// The servers ordered by address.
+	Servers []ServerHealth
+}
+
+type monitor struct {
+	hooks            atomic.Pointer[MonitorHooks]
+	open             atomic.Int64
+	inUse            atomic.Int64
+	created          atomic.Int64
+	closed           atomic.Int64
+	checkoutFailures atomic.Int64
+	cleared          atomic.Int64
+	mutex            sync.Mutex
+	topology         string
+	servers          map[string]ServerHealth
+	commands         sync.Map
+}
+
+func newMonitor(opts *options.ClientOptions) *monitor {
+	// create monitor
+	m := &monitor{
+		servers: map[string]ServerHealth{},
+	}
+
+	// get existing monitors
+	poolMonitor := opts.PoolMonitor
+	serverMonitor := opts.ServerMonitor
+	commandMonitor := opts.Monitor
+
+	// set pool monitor
+	opts.SetPoolMonitor(&event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			m.poolEvent(evt)
+			if poolMonitor != nil && poolMonitor.Event != nil {
+				poolMonitor.Event(evt)
+			}
+		},
+	})
+
+	// prepare server monitor
+	sm := &event.ServerMonitor{}
+	if serverMonitor != nil {
+		*sm = *serverMonitor
+	}
+
+	// set server monitor
+	sm.ServerHeartbeatSucceeded = func(evt *event.ServerHeartbeatSucceededEvent) {
+		m.heartbeat(evt.ConnectionID, evt.Duration, nil)
+		if serverMonitor != nil && serverMonitor.ServerHeartbeatSucceeded != nil {
+			serverMonitor.ServerHeartbeatSucceeded(evt)
+		}
+	}
+	sm.ServerHeartbeatFailed = func(evt *event.ServerHeartbeatFailedEvent) {
+		m.heartbeat(evt.ConnectionID, evt.Duration, evt.Failure)
+		if serverMonitor != nil && serverMonitor.ServerHeartbeatFailed != nil {
+			serverMonitor.ServerHeartbeatFailed(evt)
+		}
+	}
+	sm.TopologyDescriptionChanged = func(evt *event.TopologyDescriptionChangedEvent) {
+		m.topologyChanged(evt)
+		if serverMonitor != nil && serverMonitor.TopologyDescriptionChanged != nil {
+			serverMonitor.TopologyDescriptionChanged(evt)
+		}
+	}
+	opts.SetServerMonitor(sm)
+
+	// set command monitor
+	opts.SetMonitor(&event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			m.commandStarted(evt)
+			if commandMonitor != nil && commandMonitor.Started != nil {
+				commandMonitor.Started(ctx, evt)
+			}
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			m.commandFinished(&evt.CommandFinishedEvent, "")
+			if commandMonitor != nil && commandMonitor.Succeeded != nil {
+				commandMonitor.Succeeded(ctx, evt)
+			}
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			m.commandFinished(&evt.CommandFinishedEvent, evt.Failure)
+			if commandMonitor != nil && commandMonitor.Failed != nil {
+				commandMonitor.Failed(ctx, evt)
+			}
+		},
+	})
+
+	return m
+}
+
+func (m *monitor) poolEvent(evt *event.PoolEvent) {
+	// update statistics
+	switch evt.Type {
+	case event.ConnectionCreated:
+		m.created.Add(1)
+		m.open.Add(1)
+	case event.ConnectionClosed:
+		m.closed.Add(1)
+		m.open.Add(-1)
+	case event.GetSucceeded:
+		m.inUse.Add(1)
+	case event.GetFailed:
+		m.checkoutFailures.Add(1)
+	case event.ConnectionReturned:
+		m.inUse.Add(-1)
+	case event.PoolCleared:
+		m.cleared.Add(1)
+	}
+
+	// call hook
+	if hooks := m.hooks.Load(); hooks != nil && hooks.PoolEvent != nil {
+		hooks.PoolEvent(evt)
+	}
+}
+
+func (m *monitor) heartbeat(id string, duration time.Duration, err error) {
+	// get address
+	address, _, _ := strings.Cut(id, "[")
+
+	// update server
+	m.mutex.Lock()
+	m.servers[address] = ServerHealth{
+		Address:  address,
+		Healthy:  err == nil,
+		Duration: duration,
+		Error:    err,
+		Checked:  time.Now(),
+	}
+	m.mutex.Unlock()
+
+	// call hook
+	if hooks := m.hooks.Load(); hooks != nil && hooks.Heartbeat != nil {
+		hooks.Heartbeat(address, duration, err)
+	}
+}
+
+func (m *monitor) topologyChanged(evt *event.TopologyDescriptionChangedEvent) {
+	// update topology
+	m.mutex.Lock()
+	m.topology = evt.NewDescription.Kind.String()
+	m.mutex.Unlock()
+
+	// call hook
+	if hooks := m.hooks.Load(); hooks != nil && hooks.TopologyChanged != nil {
+		hooks.TopologyChanged(evt)
+	}
+}
+
+// Monitor will set the hooks that are called for connection events. Hooks are
+// only called for stores connected using Connect.
+func (s *Store) Monitor(hooks MonitorHooks) {
+	if s.monitor != nil {
+		s.monitor.hooks.Store(&hooks)
+	}
+}
+
+// Health will ping the database and return the latency together with the
+// connection pool statistics and last known server states. The statistics are
+// only available for stores connected using Connect. The health is returned
+// along with the error if the ping failed.
+func (s *Store) Health(ctx context.Context) (Health, error) {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Store.Health")
+	defer span.End()
+
+	// ping database
+	start := time.Now()
+	err := s.client.Ping(ctx, nil)
+	health := Health{
+		Latency: time.Since(start),
+	}
+
+	// add statistics
+	if m := s.monitor; m != nil {
+		health.Pool = PoolStats{
+			Open:             m.open.Load(),
+			InUse:            m.inUse.Load(),
+			Created:          m.created.Load(),
+			Closed:           m.closed.Load(),
+			CheckoutFailures: m.checkoutFailures.Load(),
+			Cleared:          m.cleared.Load(),
+		}
+		m.mutex.Lock()
+		health.Topology = m.topology
+		for _, server := range m.servers {
+			health.Servers = append(health.Servers, server)
+		}
+		m.mutex.Unlock()
+		sort.Slice(health.Servers, func(i, j int) bool {
+			return health.Servers[i].Address < health.Servers[j].Address
+		})
+	}
+
+	// check error
+	if err != nil {
+		return health, xo.W(err)
+	}
+
+	return health, nil
+}
@@ -0,0 +1,112 @@
+package coal
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestStoreHealth(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		health, err := tester.Store.Health(nil)
+		assert.NoError(t, err)
+		assert.True(t, health.Latency >= 0)
+
+		if tester.Store.Lungo() {
+			assert.Equal(t, Health{
+				Latency: health.Latency,
+			}, health)
+		} else {
+			assert.NotEmpty(t, health.Topology)
+			assert.NotEmpty(t, health.Servers)
+			assert.True(t, health.Pool.Open > 0)
+		}
+	})
+}
+
+func TestMonitor(t *testing.T) {
+	var chained []string
+	opts := options.Client().SetPoolMonitor(&event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			chained = append(chained, evt.Type)
+		},
+	})
+
+	m := newMonitor(opts)
+
+	var events []string
+	var heartbeats []string
+	var topologies []string
+	store := &Store{monitor: m}
+	store.Monitor(MonitorHooks{
+		PoolEvent: func(evt *event.PoolEvent) {
+			events = append(events, evt.Type)
+		},
+		Heartbeat: func(address string, duration time.Duration, err error) {
+			heartbeats = append(heartbeats, address)
+		},
+		TopologyChanged: func(evt *event.TopologyDescriptionChangedEvent) {
+			topologies = append(topologies, evt.NewDescription.Kind.String())
+		},
+	})
+
+	for _, typ := range []string{
+		event.ConnectionCreated,
+		event.ConnectionCreated,
+		event.GetSucceeded,
+		event.GetSucceeded,
+		event.ConnectionReturned,
+		event.GetFailed,
+		event.ConnectionClosed,
+		event.PoolCleared,
+	} {
+		opts.PoolMonitor.Event(&event.PoolEvent{Type: typ})
+	}
+
+	opts.ServerMonitor.ServerHeartbeatSucceeded(&event.ServerHeartbeatSucceededEvent{
+		ConnectionID: "b:27017[-1]",
+		Duration:     time.Millisecond,
+	})
+	opts.ServerMonitor.ServerHeartbeatFailed(&event.ServerHeartbeatFailedEvent{
+		ConnectionID: "a:27017[-2]",
+		Duration:     time.Second,
+		Failure:      io.EOF,
+	})
+	opts.ServerMonitor.TopologyDescriptionChanged(&event.TopologyDescriptionChangedEvent{
+		NewDescription: description.Topology{
+			Kind: description.ReplicaSetWithPrimary,
+		},
+	})
+
+	assert.Len(t, chained, 8)
+	assert.Equal(t, chained, events)
+	assert.Equal(t, []string{"b:27017", "a:27017"}, heartbeats)
+	assert.Equal(t, []string{"ReplicaSetWithPrimary"}, topologies)
+
+	assert.Equal(t, PoolStats{
+		Open:             1,
+		InUse:            1,
+		Created:          2,
+		Closed:           1,
+		CheckoutFailures: 1,
+		Cleared:          1,
+	}, PoolStats{
+		Open:             m.open.Load(),
+		InUse:            m.inUse.Load(),
+		Created:          m.created.Load(),
+		Closed:           m.closed.Load(),
+		CheckoutFailures: m.checkoutFailures.Load(),
+		Cleared:          m.cleared.Load(),
+	})
+
+	assert.Equal(t, "ReplicaSetWithPrimary", m.topology)
+	assert.Len(t, m.servers, 2)
+	assert.True(t, m.servers["b:27017"].Healthy)
+	assert.False(t, m.servers["a:27017"].Healthy)
+	assert.Equal(t, io.EOF, m.servers["a:27017"].Error)
+}
@@ -206,3 +206,36 @@ func Apply(model Model, update bson.M, translate bool) error {
 
 	return nil
 }
+
+// Match will return whether the provided model matches the specified query. If
+// requested the query is translated before matching.
+func Match(model Model, query bson.M, translate bool) (bool, error) {
+	// transform model
+	modelDoc, err := bsonkit.Transform(model)
+	if err != nil {
+		return false, xo.W(err)
+	}
+
+	// translate query if requested
+	var queryDoc bson.D
+	if translate {
+		queryDoc, err = NewTranslator(model).Document(query)
+	} else {
+		var doc bsonkit.Doc
+		doc, err = bsonkit.Transform(query)
+		if err == nil {
+			queryDoc = *doc
+		}
+	}
+	if err != nil {
+		return false, xo.W(err)
+	}
+
+	// match model
+	ok, err := mongokit.Match(modelDoc, &queryDoc)
+	if err != nil {
+		return false, xo.W(err)
+	}
+
+	return ok, nil
+}
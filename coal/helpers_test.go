@@ -152,3 +152,41 @@ func BenchmarkApply(b *testing.B) {
 		}
 	}
 }
+
+func TestMatch(t *testing.T) {
+	post := &postModel{
+		Title:    "Hello",
+		TextBody: "World",
+	}
+
+	ok, err := Match(post, bson.M{}, true)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Match(post, bson.M{
+		"Title": "Hello",
+	}, true)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Match(post, bson.M{
+		"$and": []bson.M{
+			{"Title": bson.M{"$in": []string{"Hello", "Hi"}}},
+			{"TextBody": "Foo"},
+		},
+	}, true)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = Match(post, bson.M{
+		"text_body": "World",
+	}, false)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Match(post, bson.M{
+		"Foo": "Bar",
+	}, true)
+	assert.Error(t, err)
+	assert.False(t, ok)
+}
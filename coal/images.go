@@ -0,0 +1,159 @@
+package coal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EnablePreImages will ensure that the collections of the specified models
+// exist and record change stream pre- and post-images. Stores that do not
+// support pre-images are skipped.
+func EnablePreImages(store *Store, models ...Model) error {
+	// check support
+	if !store.Supports(PreImages) {
+		return nil
+	}
+
+	// create context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// iterate models
+	for _, model := range models {
+		// get meta
+		meta := GetMeta(model)
+
+		// modify collection
+		err := store.DB().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: meta.Collection},
+			{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": true}},
+		}).Err()
+
+		// create collection if missing
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceNotFound" {
+			err = store.DB().RunCommand(ctx, bson.D{
+				{Key: "create", Value: meta.Collection},
+				{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": true}},
+			}).Err()
+		}
+		if err != nil {
+			return xo.WF(err, "unable to enable pre-images for %s", meta.Name)
+		}
+	}
+
+	return nil
+}
+
+// ImageReceiver is a callback that receives stream events including the
+// document before the change. The before document is only available for
+// updated and deleted events if pre-images are enabled for the collection.
+type ImageReceiver func(event Event, id ID, before, after Model, err error, token []byte) error
+
+// OpenImageStream will open a stream like OpenStream but request pre-images
+// and forward the document before the change to the specified receiver.
+// Pre-images must be enabled for the collection using EnablePreImages.
+func OpenImageStream(store *Store, model Model, token []byte, receiver ImageReceiver) *Stream {
+	// prepare stream
+	var stream *Stream
+	stream = newStream(store, model, token, func(event Event, id ID, after Model, err error, token []byte) error {
+		// decode before
+		var before Model
+		if stream.current != nil {
+			before, err = stream.before(stream.current)
+			if err != nil {
+				return err
+			}
+		}
+
+		return receiver(event, id, before, after, err, token)
+	})
+
+	// enable pre-images
+	stream.preImages = true
+
+	// open stream
+	stream.tomb.Go(stream.open)
+
+	return stream
+}
+
+// ImageReconcile works like Reconcile but uses an image stream to yield the
+// document before the change to the updated and deleted callbacks. The before
+// document is nil if pre-images are not available.
+func ImageReconcile(store *Store, model Model, loaded func(), created func(Model), updated func(before, after Model), deleted func(id ID, before Model), errored func(error)) *Stream {
+	// prepare load
+	load := func() error {
+		// load models
+		err := loadModels(store, model, func(model Model) {
+			// call callback if available
+			if created != nil {
+				created(model)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		// call callback if available
+		if loaded != nil {
+			loaded()
+		}
+
+		return nil
+	}
+
+	// open stream
+	stream := OpenImageStream(store, model, nil, func(event Event, id ID, before, after Model, err error, token []byte) error {
+		// handle events
+		switch event {
+		case Opened:
+			return load()
+		case Created:
+			// call callback if available
+			if created != nil {
+				created(after)
+			}
+		case Updated:
+			// call callback if available
+			if updated != nil {
+				updated(before, after)
+			}
+		case Deleted:
+			// call callback if available
+			if deleted != nil {
+				deleted(id, before)
+			}
+		case Errored:
+			// call callback if available
+			if errored != nil {
+				errored(err)
+			}
+		}
+
+		return nil
+	})
+
+	return stream
+}
+
+func (s *Stream) before(ch *change) (Model, error) {
+	// check pre-image
+	if len(ch.FullDocumentBeforeChange) == 0 {
+		return nil, nil
+	}
+
+	// decode document
+	doc := GetMeta(s.model).Make()
+	err := bson.Unmarshal(ch.FullDocumentBeforeChange, doc)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,65 @@
+package coal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageReconcile(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		err := EnablePreImages(tester.Store, &postModel{})
+		assert.NoError(t, err)
+
+		time.Sleep(10 * time.Millisecond)
+
+		post := &postModel{
+			Base:  B(),
+			Title: "foo",
+		}
+
+		supported := tester.Store.Supports(PreImages)
+
+		open := make(chan struct{})
+		done := make(chan struct{})
+
+		stream := ImageReconcile(tester.Store, &postModel{}, func() {
+			close(open)
+		}, func(model Model) {
+			assert.Equal(t, post.ID(), model.ID())
+			assert.Equal(t, "foo", model.(*postModel).Title)
+		}, func(before, after Model) {
+			assert.Equal(t, post.ID(), after.ID())
+			assert.Equal(t, "bar", after.(*postModel).Title)
+			if supported {
+				assert.Equal(t, "foo", before.(*postModel).Title)
+			} else {
+				assert.Nil(t, before)
+			}
+		}, func(id ID, before Model) {
+			assert.Equal(t, post.ID(), id)
+			if supported {
+				assert.Equal(t, "bar", before.(*postModel).Title)
+			} else {
+				assert.Nil(t, before)
+			}
+			close(done)
+		}, func(err error) {
+			panic(err)
+		})
+
+		<-open
+
+		tester.Insert(post)
+
+		post.Title = "bar"
+		tester.Replace(post)
+
+		tester.Delete(post)
+
+		<-done
+
+		stream.Close()
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/256dpi/lungo"
+	"github.com/256dpi/lungo/bsonkit"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -26,6 +28,16 @@ type Index struct {
 
 	// The partial filter expression.
 	Filter bson.D
+
+	// The collation used to compare strings.
+	Collation *options.Collation
+
+	// The field weights and default language of a text index.
+	Weights  bson.D
+	Language string
+
+	// The field projection of a wildcard index.
+	Projection bson.D
 }
 
 // Compile will compile the index to a mongo.IndexModel.
@@ -43,6 +55,24 @@ func (i *Index) Compile() mongo.IndexModel {
 		opts.SetPartialFilterExpression(i.Filter)
 	}
 
+	// set collation if available
+	if i.Collation != nil {
+		opts.SetCollation(i.Collation)
+	}
+
+	// set text options if available
+	if i.Weights != nil {
+		opts.SetWeights(i.Weights)
+	}
+	if i.Language != "" {
+		opts.SetDefaultLanguage(i.Language)
+	}
+
+	// set wildcard projection if available
+	if i.Projection != nil {
+		opts.SetWildcardProjection(i.Projection)
+	}
+
 	// add index
 	return mongo.IndexModel{
 		Keys:    i.Keys,
@@ -50,11 +80,25 @@ func (i *Index) Compile() mongo.IndexModel {
 	}
 }
 
+func (i *Index) compile(store *Store) mongo.IndexModel {
+	// compile index
+	model := i.Compile()
+
+	// remove collation if not supported
+	if !store.Supports(Collations) {
+		model.Options.Collation = nil
+	}
+
+	return model
+}
+
 // AddIndex will add an index to the models index list. Fields that are prefixed
 // with a dash will result in a descending key. Fields may be paths to nested
-// item fields or begin wih a "#" (after prefix) to specify unknown fields.
+// item fields or begin wih a "#" (after prefix) to specify unknown fields. If
+// tenancy is enabled for the model, the tenant field is prepended unless it
+// is already included or the index has an expiry.
 func AddIndex(model Model, unique bool, expiry time.Duration, fields ...string) {
-	addIndex(model, unique, expiry, fields, nil)
+	addIndex(model, unique, expiry, fields, nil, nil)
 }
 
 // AddPartialIndex adds an index with a partial filter expression.
@@ -65,14 +109,199 @@ func AddPartialIndex(model Model, unique bool, expiry time.Duration, fields []st
 	}
 
 	// add index
-	addIndex(model, unique, expiry, fields, filter)
+	addIndex(model, unique, expiry, fields, filter, nil)
+}
+
+// AddCollatedIndex adds an index that uses the provided collation to compare
+// strings. Queries must use the same collation to use the index.
+func AddCollatedIndex(model Model, unique bool, expiry time.Duration, fields []string, collation *options.Collation) {
+	// check collation
+	if collation == nil || collation.Locale == "" {
+		panic(`coal: missing collation locale`)
+	}
+
+	// add index
+	addIndex(model, unique, expiry, fields, nil, collation)
+}
+
+// AddGeoIndex adds a 2dsphere index for the specified GeoJSON field that is
+// required by geospatial queries using Near. If tenancy is enabled, the tenant
+// field is prepended.
+//
+// Note: Geo indexes are not created for lungo stores.
+func AddGeoIndex(model Model, field string) {
+	// add index
+	addIndex(model, false, 0, []string{field}, nil, nil)
+
+	// change index type of field
+	meta := GetMeta(model)
+	keys := meta.Indexes[len(meta.Indexes)-1].Keys
+	keys[len(keys)-1].Value = "2dsphere"
+}
+
+// AddTextIndex adds a text index for the specified fields that is required
+// for full text search using the $text operator. The optional weights map
+// fields to their relative significance (default 1). The default language
+// defaults to "english" if empty. If tenancy is enabled, the tenant field is
+// prepended.
+//
+// Note: Text indexes are not created for lungo stores.
+func AddTextIndex(model Model, language string, weights map[string]int, fields ...string) {
+	// check fields
+	if len(fields) == 0 {
+		panic(`coal: missing text index fields`)
+	}
+
+	// get translator
+	trans := NewTranslator(model)
+
+	// translate weights
+	var weightsDoc bson.D
+	for _, field := range fields {
+		if weight, ok := weights[field]; ok {
+			key, err := trans.Field(field)
+			if err != nil {
+				panic(err)
+			}
+			weightsDoc = append(weightsDoc, bson.E{Key: key, Value: int32(weight)})
+		}
+	}
+
+	// check weights
+	if len(weightsDoc) != len(weights) {
+		panic(`coal: text index weight for unknown field`)
+	}
+
+	// add index
+	addIndex(model, false, 0, fields, nil, nil)
+
+	// change index type of fields
+	meta := GetMeta(model)
+	index := &meta.Indexes[len(meta.Indexes)-1]
+	keys := index.Keys
+	for i := len(keys) - len(fields); i < len(keys); i++ {
+		keys[i].Value = "text"
+	}
+
+	// set options
+	index.Weights = weightsDoc
+	index.Language = language
+}
+
+// AddHashedIndex adds a hashed index for the specified field that supports
+// equality queries and hashed sharding. If tenancy is enabled, the tenant
+// field is prepended.
+//
+// Note: Hashed indexes are not created for lungo stores.
+func AddHashedIndex(model Model, field string) {
+	// add index
+	addIndex(model, false, 0, []string{field}, nil, nil)
+
+	// change index type of field
+	meta := GetMeta(model)
+	keys := meta.Indexes[len(meta.Indexes)-1].Keys
+	keys[len(keys)-1].Value = "hashed"
+}
+
+// AddWildcardIndex adds a wildcard index for all nested fields of the
+// specified field. If the field is empty, all fields of the document are
+// indexed and the optional projection may be used to limit the index to the
+// specified fields. The tenant field is not prepended as wildcard indexes
+// cannot be compounded.
+//
+// Note: Wildcard indexes with a projection are not created for lungo stores.
+func AddWildcardIndex(model Model, field string, projection ...string) {
+	// get meta and translator
+	meta := GetMeta(model)
+	trans := NewTranslator(model)
+
+	// check projection
+	if field != "" && len(projection) > 0 {
+		panic(`coal: wildcard projection requires a root wildcard index`)
+	}
+
+	// prepare key
+	key := "$**"
+	if field != "" {
+		path, err := trans.Field(field)
+		if err != nil {
+			panic(err)
+		}
+		key = path + ".$**"
+	}
+
+	// translate projection
+	var projectionDoc bson.D
+	for _, field := range projection {
+		path, err := trans.Field(field)
+		if err != nil {
+			panic(err)
+		}
+		projectionDoc = append(projectionDoc, bson.E{Key: path, Value: int32(1)})
+	}
+
+	// prepare fields
+	var fields []string
+	if field != "" {
+		fields = []string{field}
+	}
+
+	// add index
+	meta.Indexes = append(meta.Indexes, Index{
+		Fields: fields,
+		Keys: bson.D{
+			{Key: key, Value: int32(1)},
+		},
+		Projection: projectionDoc,
+	})
+}
+
+func (i *Index) special() bool {
+	// check projection
+	if i.Projection != nil {
+		return true
+	}
+
+	// check index types
+	for _, key := range i.Keys {
+		if _, ok := key.Value.(string); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i *Index) wildcard() bool {
+	for _, key := range i.Keys {
+		if strings.HasSuffix(key.Key, "$**") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i *Index) text() bool {
+	for _, key := range i.Keys {
+		if key.Value == "text" {
+			return true
+		}
+	}
+
+	return false
 }
 
-func addIndex(model Model, unique bool, expiry time.Duration, fields []string, filter bson.M) {
+func addIndex(model Model, unique bool, expiry time.Duration, fields []string, filter bson.M, collation *options.Collation) {
 	// get meta and translator
 	meta := GetMeta(model)
 	trans := NewTranslator(model)
 
+	// prepend tenant field if missing, expiry indexes must have a single field
+	if tenant := TenantField(model); tenant != nil && expiry == 0 && !hasTenantField(fields, tenant.Name) {
+		fields = append([]string{tenant.Name}, fields...)
+	}
+
 	// translate keys
 	keys, err := trans.Sort(fields)
 	if err != nil {
@@ -96,14 +325,114 @@ func addIndex(model Model, unique bool, expiry time.Duration, fields []string, f
 
 	// add index
 	meta.Indexes = append(meta.Indexes, Index{
-		Fields: cleanFields,
-		Keys:   keys,
-		Unique: unique,
-		Expiry: expiry,
-		Filter: filterDoc,
+		Fields:    cleanFields,
+		Keys:      keys,
+		Unique:    unique,
+		Expiry:    expiry,
+		Filter:    filterDoc,
+		Collation: collation,
 	})
 }
 
+func hasTenantField(fields []string, name string) bool {
+	for _, field := range fields {
+		if strings.TrimPrefix(field, "-") == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LookupIndex will return the first registered index of the model that
+// supports a query with conditions on the provided fields and the provided
+// sort. The index must begin with the condition fields in any order followed
+// by the sort fields in the same or the fully inverted direction. If tenancy
+// is enabled for the model, the tenant field is added to the conditions
+// unless it is already included. Partial, collated and special indexes are
+// not considered. Nil is returned if no such index is registered or no fields
+// and sort are provided.
+func LookupIndex(model Model, fields []string, sort []string) (*Index, error) {
+	// check query
+	if len(fields) == 0 && len(sort) == 0 {
+		return nil, nil
+	}
+
+	// get meta and translator
+	meta := GetMeta(model)
+	trans := NewTranslator(model)
+
+	// prepend tenant field if missing
+	if tenant := TenantField(model); tenant != nil && !hasTenantField(fields, tenant.Name) {
+		fields = append([]string{tenant.Name}, fields...)
+	}
+
+	// translate fields
+	conditions, err := trans.Sort(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// translate sort
+	order, err := trans.Sort(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// find index
+	for i, index := range meta.Indexes {
+		// skip unsuitable indexes
+		if index.Filter != nil || index.Collation != nil || index.special() || index.wildcard() {
+			continue
+		}
+
+		// check length
+		if len(index.Keys) < len(conditions)+len(order) {
+			continue
+		}
+
+		// check conditions
+		prefix := map[string]bool{}
+		for _, key := range index.Keys[:len(conditions)] {
+			prefix[key.Key] = true
+		}
+		matched := true
+		for _, key := range conditions {
+			if !prefix[key.Key] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		// check sort
+		var inverted bool
+		for j, key := range order {
+			indexKey := index.Keys[len(conditions)+j]
+			if indexKey.Key != key.Key {
+				matched = false
+				break
+			}
+			flipped := toFloat(indexKey.Value) != toFloat(key.Value)
+			if j == 0 {
+				inverted = flipped
+			} else if flipped != inverted {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		return &meta.Indexes[i], nil
+	}
+
+	return nil, nil
+}
+
 // EnsureIndexes will ensure that the registered indexes of the specified models
 // exist. It may fail early if some indexes are already existing and do not
 // match the registered indexes.
@@ -119,7 +448,13 @@ func EnsureIndexes(store *Store, models ...Model) error {
 
 		// ensure all indexes
 		for _, index := range meta.Indexes {
-			_, err := store.C(model).Native().Indexes().CreateOne(ctx, index.Compile())
+			// skip unsupported special indexes
+			if !store.Supports(SpecialIndexes) && index.special() {
+				continue
+			}
+
+			// create index
+			_, err := store.C(model).Native().Indexes().CreateOne(ctx, index.compile(store))
 			if err != nil {
 				return err
 			}
@@ -128,3 +463,350 @@ func EnsureIndexes(store *Store, models ...Model) error {
 
 	return nil
 }
+
+// IndexDrift describes a declared index whose options differ from the
+// existing index with the same keys.
+type IndexDrift struct {
+	// The declared index.
+	Index Index
+
+	// The name of the existing index.
+	Name string
+
+	// The drifted options e.g. "unique", "expiry", "filter", "collation",
+	// "language", "weights" or "projection".
+	Options []string
+}
+
+// IndexDiff describes the differences between the declared and existing
+// indexes of a model.
+type IndexDiff struct {
+	// The model.
+	Model Model
+
+	// The declared indexes that did not exist.
+	Missing []Index
+
+	// The declared indexes with drifted options.
+	Drifted []IndexDrift
+
+	// The names of existing indexes that are not declared.
+	Extra []string
+}
+
+// Empty returns whether the diff does not contain any differences.
+func (d *IndexDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Drifted) == 0 && len(d.Extra) == 0
+}
+
+// ReconcileIndexes will compare the registered indexes of the specified models
+// with the existing indexes and create missing indexes. If prune is set, extra
+// indexes are dropped and drifted indexes are dropped and recreated. It will
+// return a diff for each model describing the differences found.
+func ReconcileIndexes(store *Store, prune bool, models ...Model) ([]IndexDiff, error) {
+	// create context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// prepare diffs
+	diffs := make([]IndexDiff, 0, len(models))
+
+	// iterate models
+	for _, model := range models {
+		// get meta and view
+		meta := GetMeta(model)
+		view := store.C(model).Native().Indexes()
+
+		// list existing indexes
+		existing, err := listIndexes(ctx, view)
+		if err != nil {
+			return nil, err
+		}
+
+		// prepare diff
+		diff := IndexDiff{
+			Model: model,
+		}
+
+		// compare declared indexes
+		matched := map[string]bool{}
+		for _, index := range meta.Indexes {
+			// skip unsupported special indexes
+			if !store.Supports(SpecialIndexes) && index.special() {
+				continue
+			}
+
+			// find existing index
+			spec, err := findIndex(existing, index)
+			if err != nil {
+				return nil, err
+			}
+
+			// handle missing index
+			if spec == nil {
+				diff.Missing = append(diff.Missing, index)
+				_, err = view.CreateOne(ctx, index.compile(store))
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			// mark index
+			matched[spec.Name] = true
+
+			// compare options
+			options, err := spec.drift(index, store.Supports(Collations))
+			if err != nil {
+				return nil, err
+			} else if len(options) == 0 {
+				continue
+			}
+
+			// add drift
+			diff.Drifted = append(diff.Drifted, IndexDrift{
+				Index:   index,
+				Name:    spec.Name,
+				Options: options,
+			})
+
+			// recreate index if pruning
+			if prune {
+				_, err = view.DropOne(ctx, spec.Name)
+				if err != nil {
+					return nil, err
+				}
+				_, err = view.CreateOne(ctx, index.compile(store))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// handle extra indexes
+		for _, spec := range existing {
+			// skip matched and primary indexes
+			if matched[spec.Name] || spec.Name == "_id_" {
+				continue
+			}
+
+			// add extra
+			diff.Extra = append(diff.Extra, spec.Name)
+
+			// drop index if pruning
+			if prune {
+				_, err = view.DropOne(ctx, spec.Name)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// add diff
+		diffs = append(diffs, diff)
+	}
+
+	return diffs, nil
+}
+
+type indexSpec struct {
+	Name      string `bson:"name"`
+	Key       bson.D `bson:"key"`
+	Unique    bool   `bson:"unique"`
+	Expiry    int64  `bson:"expireAfterSeconds"`
+	Partial   bson.D `bson:"partialFilterExpression"`
+	Collation *struct {
+		Locale   string `bson:"locale"`
+		Strength int    `bson:"strength"`
+	} `bson:"collation"`
+	Weights    bson.D `bson:"weights"`
+	Language   string `bson:"default_language"`
+	Projection bson.D `bson:"wildcardProjection"`
+}
+
+func (s *indexSpec) drift(index Index, collation bool) ([]string, error) {
+	// prepare options
+	var options []string
+
+	// check unique
+	if s.Unique != index.Unique {
+		options = append(options, "unique")
+	}
+
+	// check expiry
+	if s.Expiry != int64(index.Expiry/time.Second) {
+		options = append(options, "expiry")
+	}
+
+	// check filter
+	equal, err := equalDocs(s.Partial, index.Filter)
+	if err != nil {
+		return nil, err
+	} else if !equal {
+		options = append(options, "filter")
+	}
+
+	// check collation
+	if collation {
+		var locale string
+		var strength int
+		if index.Collation != nil {
+			locale = index.Collation.Locale
+			strength = index.Collation.Strength
+			if strength == 0 {
+				strength = 3
+			}
+		}
+		if s.Collation == nil && locale != "" {
+			options = append(options, "collation")
+		} else if s.Collation != nil && (s.Collation.Locale != locale || s.Collation.Strength != strength) {
+			options = append(options, "collation")
+		}
+	}
+
+	// check text options
+	if index.text() {
+		language := index.Language
+		if language == "" {
+			language = "english"
+		}
+		if s.Language != language {
+			options = append(options, "language")
+		}
+		if !equalWeights(s.Weights, index) {
+			options = append(options, "weights")
+		}
+	}
+
+	// check projection
+	equal, err = equalDocs(s.Projection, index.Projection)
+	if err != nil {
+		return nil, err
+	} else if !equal {
+		options = append(options, "projection")
+	}
+
+	return options, nil
+}
+
+func listIndexes(ctx context.Context, view lungo.IIndexView) ([]indexSpec, error) {
+	// list indexes
+	csr, err := view.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode indexes
+	var list []indexSpec
+	err = csr.All(ctx, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func findIndex(list []indexSpec, index Index) (*indexSpec, error) {
+	// get keys
+	keys := index.Keys
+	if index.text() {
+		keys = textKeys(keys)
+	}
+
+	// find index
+	for i := range list {
+		equal, err := equalDocs(list[i].Key, keys)
+		if err != nil {
+			return nil, err
+		} else if equal {
+			return &list[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func textKeys(keys bson.D) bson.D {
+	// text fields are reported as a single "_fts" and "_ftsx" key pair
+	var list bson.D
+	var added bool
+	for _, key := range keys {
+		if key.Value != "text" {
+			list = append(list, key)
+		} else if !added {
+			list = append(list, bson.E{Key: "_fts", Value: "text"}, bson.E{Key: "_ftsx", Value: int32(1)})
+			added = true
+		}
+	}
+
+	return list
+}
+
+func equalWeights(weights bson.D, index Index) bool {
+	// prepare expected weights
+	expected := map[string]float64{}
+	for _, key := range index.Keys {
+		if key.Value == "text" {
+			expected[key.Key] = 1
+		}
+	}
+	for _, weight := range index.Weights {
+		expected[weight.Key] = toFloat(weight.Value)
+	}
+
+	// compare weights
+	if len(weights) != len(expected) {
+		return false
+	}
+	for _, weight := range weights {
+		if value, ok := expected[weight.Key]; !ok || value != toFloat(weight.Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func toFloat(value interface{}) float64 {
+	switch value := value.(type) {
+	case int:
+		return float64(value)
+	case int32:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case float64:
+		return value
+	default:
+		return 0
+	}
+}
+
+func equalDocs(a, b bson.D) (bool, error) {
+	// check empty
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b), nil
+	}
+
+	// normalize documents
+	na, err := bsonkit.Transform(a)
+	if err != nil {
+		return false, err
+	}
+	nb, err := bsonkit.Transform(b)
+	if err != nil {
+		return false, err
+	}
+
+	// check order of keys
+	if len(*na) != len(*nb) {
+		return false, nil
+	}
+	for i := range *na {
+		if (*na)[i].Key != (*nb)[i].Key {
+			return false, nil
+		}
+	}
+
+	return bsonkit.Compare(*na, *nb) == 0, nil
+}
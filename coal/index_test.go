@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func TestIndex(t *testing.T) {
@@ -74,6 +75,63 @@ func TestIndex(t *testing.T) {
 	})
 }
 
+func TestLookupIndex(t *testing.T) {
+	oldMeta := GetMeta(&postModel{})
+	delete(metaCache, oldMeta.Type)
+	defer func() {
+		metaCache[oldMeta.Type] = oldMeta
+	}()
+
+	index, err := LookupIndex(&postModel{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, index)
+
+	index, err = LookupIndex(&postModel{}, []string{"Title"}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, index)
+
+	AddIndex(&postModel{}, false, 0, "Published", "Title", "-TextBody")
+	AddPartialIndex(&postModel{}, false, 0, []string{"TextBody"}, bson.M{
+		"Published": true,
+	})
+
+	index, err = LookupIndex(&postModel{}, []string{"Published"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Published", "Title", "TextBody"}, index.Fields)
+
+	index, err = LookupIndex(&postModel{}, []string{"Title", "Published"}, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, index)
+
+	index, err = LookupIndex(&postModel{}, []string{"Published"}, []string{"Title", "-TextBody"})
+	assert.NoError(t, err)
+	assert.NotNil(t, index)
+
+	index, err = LookupIndex(&postModel{}, []string{"Published"}, []string{"-Title", "TextBody"})
+	assert.NoError(t, err)
+	assert.NotNil(t, index)
+
+	index, err = LookupIndex(&postModel{}, []string{"Published"}, []string{"Title", "TextBody"})
+	assert.NoError(t, err)
+	assert.Nil(t, index)
+
+	index, err = LookupIndex(&postModel{}, []string{"Title"}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, index)
+
+	index, err = LookupIndex(&postModel{}, []string{"TextBody"}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, index)
+
+	index, err = LookupIndex(&postModel{}, nil, []string{"Published"})
+	assert.NoError(t, err)
+	assert.NotNil(t, index)
+
+	index, err = LookupIndex(&postModel{}, []string{"Foo"}, nil)
+	assert.Error(t, err)
+	assert.Nil(t, index)
+}
+
 func TestItemIndex(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		oldMeta := GetMeta(&listModel{})
@@ -120,3 +178,201 @@ func TestItemIndex(t *testing.T) {
 		metaCache[oldMeta.Type] = oldMeta
 	})
 }
+
+func TestReconcileIndexes(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		meta := GetMeta(&postModel{})
+
+		err := tester.Store.C(&postModel{}).Native().Drop(nil)
+		assert.NoError(t, err)
+
+		diffs, err := ReconcileIndexes(tester.Store, false, &postModel{})
+		assert.NoError(t, err)
+		assert.Len(t, diffs, 1)
+		assert.Equal(t, meta.Indexes, diffs[0].Missing)
+		assert.Empty(t, diffs[0].Drifted)
+		assert.Empty(t, diffs[0].Extra)
+
+		diffs, err = ReconcileIndexes(tester.Store, false, &postModel{})
+		assert.NoError(t, err)
+		assert.True(t, diffs[0].Empty())
+
+		_, err = tester.Store.C(&postModel{}).Native().Indexes().CreateOne(nil, mongo.IndexModel{
+			Keys: bson.D{{Key: "foo", Value: 1}},
+		})
+		assert.NoError(t, err)
+
+		meta.Indexes[1].Unique = true
+		defer func() {
+			meta.Indexes[1].Unique = false
+		}()
+
+		for i := 0; i < 2; i++ {
+			diffs, err = ReconcileIndexes(tester.Store, false, &postModel{})
+			assert.NoError(t, err)
+			assert.Empty(t, diffs[0].Missing)
+			assert.Equal(t, []IndexDrift{
+				{
+					Index:   meta.Indexes[1],
+					Name:    "published_1_title_1",
+					Options: []string{"unique"},
+				},
+			}, diffs[0].Drifted)
+			assert.Equal(t, []string{"foo_1"}, diffs[0].Extra)
+		}
+
+		diffs, err = ReconcileIndexes(tester.Store, true, &postModel{})
+		assert.NoError(t, err)
+		assert.Len(t, diffs[0].Drifted, 1)
+		assert.Equal(t, []string{"foo_1"}, diffs[0].Extra)
+
+		diffs, err = ReconcileIndexes(tester.Store, false, &postModel{})
+		assert.NoError(t, err)
+		assert.True(t, diffs[0].Empty())
+
+		err = tester.Store.C(&postModel{}).Native().Drop(nil)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCollatedIndex(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		oldMeta := GetMeta(&listModel{})
+		delete(metaCache, oldMeta.Type)
+
+		newMeta := GetMeta(&listModel{})
+
+		assert.PanicsWithValue(t, `coal: missing collation locale`, func() {
+			AddCollatedIndex(&listModel{}, false, 0, []string{"Item.Title"}, nil)
+		})
+
+		AddCollatedIndex(&listModel{}, true, 0, []string{"Item.Title"}, CaseInsensitive("en"))
+		assert.EqualValues(t, Index{
+			Fields: []string{"Item.Title"},
+			Keys: bson.D{
+				{Key: "item.title", Value: int32(1)},
+			},
+			Unique:    true,
+			Collation: CaseInsensitive("en"),
+		}, newMeta.Indexes[1])
+		assert.Equal(t, CaseInsensitive("en"), newMeta.Indexes[1].Compile().Options.Collation)
+
+		err := tester.Store.C(&listModel{}).Native().Drop(nil)
+		assert.NoError(t, err)
+
+		err = EnsureIndexes(tester.Store, &listModel{})
+		assert.NoError(t, err)
+
+		diffs, err := ReconcileIndexes(tester.Store, false, &listModel{})
+		assert.NoError(t, err)
+		assert.True(t, diffs[0].Empty())
+
+		if !tester.Store.Lungo() {
+			newMeta.Indexes[1].Collation = CaseInsensitive("de")
+
+			diffs, err = ReconcileIndexes(tester.Store, false, &listModel{})
+			assert.NoError(t, err)
+			assert.Len(t, diffs[0].Drifted, 1)
+			assert.Equal(t, []string{"collation"}, diffs[0].Drifted[0].Options)
+		}
+
+		err = tester.Store.C(&listModel{}).Native().Drop(nil)
+		assert.NoError(t, err)
+
+		metaCache[oldMeta.Type] = oldMeta
+	})
+}
+
+func TestSpecialIndexes(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		oldMeta := GetMeta(&postModel{})
+		delete(metaCache, oldMeta.Type)
+
+		newMeta := GetMeta(&postModel{})
+
+		assert.PanicsWithValue(t, `coal: text index weight for unknown field`, func() {
+			AddTextIndex(&postModel{}, "", map[string]int{"Published": 2}, "Title")
+		})
+		assert.PanicsWithValue(t, `coal: wildcard projection requires a root wildcard index`, func() {
+			AddWildcardIndex(&postModel{}, "Title", "TextBody")
+		})
+
+		AddTextIndex(&postModel{}, "german", map[string]int{"Title": 10}, "Title", "TextBody")
+		AddHashedIndex(&postModel{}, "Title")
+		AddWildcardIndex(&postModel{}, "", "Title", "TextBody")
+		assert.EqualValues(t, []Index{
+			{
+				Fields: []string{"Title", "TextBody"},
+				Keys: bson.D{
+					{Key: "title", Value: "text"},
+					{Key: "text_body", Value: "text"},
+				},
+				Weights: bson.D{
+					{Key: "title", Value: int32(10)},
+				},
+				Language: "german",
+			},
+			{
+				Fields: []string{"Title"},
+				Keys: bson.D{
+					{Key: "title", Value: "hashed"},
+				},
+			},
+			{
+				Keys: bson.D{
+					{Key: "$**", Value: int32(1)},
+				},
+				Projection: bson.D{
+					{Key: "title", Value: int32(1)},
+					{Key: "text_body", Value: int32(1)},
+				},
+			},
+		}, newMeta.Indexes[1:])
+
+		opts := newMeta.Indexes[1].Compile().Options
+		assert.Equal(t, bson.D{{Key: "title", Value: int32(10)}}, opts.Weights)
+		assert.Equal(t, "german", *opts.DefaultLanguage)
+		opts = newMeta.Indexes[3].Compile().Options
+		assert.Equal(t, newMeta.Indexes[3].Projection, opts.WildcardProjection)
+
+		spec := indexSpec{
+			Name: "title_text_text_body_text",
+			Key: bson.D{
+				{Key: "_fts", Value: "text"},
+				{Key: "_ftsx", Value: int32(1)},
+			},
+			Weights: bson.D{
+				{Key: "text_body", Value: int32(1)},
+				{Key: "title", Value: int32(10)},
+			},
+			Language: "german",
+		}
+		found, err := findIndex([]indexSpec{spec}, newMeta.Indexes[1])
+		assert.NoError(t, err)
+		assert.Equal(t, &spec, found)
+		options, err := spec.drift(newMeta.Indexes[1], true)
+		assert.NoError(t, err)
+		assert.Empty(t, options)
+
+		spec.Weights[1].Value = int32(5)
+		spec.Language = "english"
+		options, err = spec.drift(newMeta.Indexes[1], true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"language", "weights"}, options)
+
+		err = tester.Store.C(&postModel{}).Native().Drop(nil)
+		assert.NoError(t, err)
+
+		err = EnsureIndexes(tester.Store, &postModel{})
+		assert.NoError(t, err)
+
+		diffs, err := ReconcileIndexes(tester.Store, false, &postModel{})
+		assert.NoError(t, err)
+		assert.True(t, diffs[0].Empty())
+
+		err = tester.Store.C(&postModel{}).Native().Drop(nil)
+		assert.NoError(t, err)
+
+		metaCache[oldMeta.Type] = oldMeta
+	})
+}
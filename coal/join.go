@@ -0,0 +1,253 @@
+package coal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// Join manages a many-to-many relationship between two models through an
+// explicit join model. The join model stores one document per link and uses
+// two to-one relationships to reference the linked documents. Additional
+// fields may be used to store data about the link e.g. a role.
+type Join struct {
+	model Model
+	left  Model
+	right Model
+
+	leftField  *Field
+	rightField *Field
+}
+
+// NewJoin will declare a many-to-many relationship between the left and the
+// right model through the provided join model. The specified join model fields
+// must be to-one relationships referencing the left and right model. A unique
+// index on both fields and an index on the right field are added to the join
+// model to support lookups in both directions. The join should be declared
+// once, e.g. in a package level variable.
+//
+//	var memberships = coal.NewJoin(&Membership{}, &User{}, "User", &Group{}, "Group")
+func NewJoin(model, left Model, leftField string, right Model, rightField string) *Join {
+	// get meta
+	meta := GetMeta(model)
+
+	// check fields
+	lf := meta.Fields[leftField]
+	if lf == nil || !lf.ToOne || lf.Optional || lf.RelType != GetMeta(left).PluralName {
+		panic(fmt.Sprintf(`coal: field "%s" is not a to-one relationship to "%s"`, leftField, GetMeta(left).PluralName))
+	}
+	rf := meta.Fields[rightField]
+	if rf == nil || !rf.ToOne || rf.Optional || rf.RelType != GetMeta(right).PluralName {
+		panic(fmt.Sprintf(`coal: field "%s" is not a to-one relationship to "%s"`, rightField, GetMeta(right).PluralName))
+	}
+
+	// add indexes
+	AddIndex(model, true, 0, leftField, rightField)
+	AddIndex(model, false, 0, rightField)
+
+	return &Join{
+		model:      model,
+		left:       left,
+		right:      right,
+		leftField:  lf,
+		rightField: rf,
+	}
+}
+
+// Link will link the specified left and right document by inserting a join
+// document if missing. It will return whether a join document has been
+// inserted.
+func (j *Join) Link(ctx context.Context, store *Store, left, right ID) (bool, error) {
+	// prepare model
+	model := GetMeta(j.model).Make()
+	stick.MustSet(model, j.leftField.Name, left)
+	stick.MustSet(model, j.rightField.Name, right)
+
+	return j.LinkModel(ctx, store, model)
+}
+
+// LinkModel will link the documents referenced by the provided join model by
+// inserting it if missing. This allows setting additional fields on the join
+// document. It will return whether the join document has been inserted.
+func (j *Join) LinkModel(ctx context.Context, store *Store, model Model) (bool, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Join.LinkModel")
+	defer span.End()
+
+	// get references
+	left := stick.MustGet(model, j.leftField.Name).(ID)
+	right := stick.MustGet(model, j.rightField.Name).(ID)
+
+	// check references
+	if left.IsZero() || right.IsZero() {
+		return false, xo.F("missing references")
+	}
+
+	// insert join document if missing
+	inserted, err := store.M(j.model).InsertIfMissing(ctx, j.filter(left, right), model, false)
+	if err != nil {
+		return false, err
+	}
+
+	return inserted, nil
+}
+
+// Unlink will unlink the specified left and right document by deleting the
+// join document. It will return whether a join document has been deleted.
+func (j *Join) Unlink(ctx context.Context, store *Store, left, right ID) (bool, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Join.Unlink")
+	defer span.End()
+
+	// delete join document
+	deleted, err := store.M(j.model).DeleteAll(ctx, j.filter(left, right))
+	if err != nil {
+		return false, err
+	}
+
+	return deleted > 0, nil
+}
+
+// UnlinkAll will delete all join documents that reference the specified
+// document on either side. It should be called when a linked document is
+// deleted and returns the number of deleted join documents.
+func (j *Join) UnlinkAll(ctx context.Context, store *Store, id ID) (int64, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Join.UnlinkAll")
+	defer span.End()
+
+	// delete join documents
+	deleted, err := store.M(j.model).DeleteAll(ctx, bson.M{
+		"$or": []bson.M{
+			{j.leftField.Name: id},
+			{j.rightField.Name: id},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// Linked will return whether the specified left and right document are linked.
+//
+// A transaction is required to ensure isolation.
+func (j *Join) Linked(ctx context.Context, store *Store, left, right ID, flags ...Flags) (bool, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Join.Linked")
+	defer span.End()
+
+	// count join documents
+	count, err := store.M(j.model).Count(ctx, j.filter(left, right), 0, 1, false, flags...)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// RightIDs will return the IDs of all right documents linked to the specified
+// left document.
+//
+// A transaction is required to ensure isolation.
+func (j *Join) RightIDs(ctx context.Context, store *Store, left ID, flags ...Flags) ([]ID, error) {
+	return j.ids(ctx, store, j.leftField, left, j.rightField, flags)
+}
+
+// LeftIDs will return the IDs of all left documents linked to the specified
+// right document.
+//
+// A transaction is required to ensure isolation.
+func (j *Join) LeftIDs(ctx context.Context, store *Store, right ID, flags ...Flags) ([]ID, error) {
+	return j.ids(ctx, store, j.rightField, right, j.leftField, flags)
+}
+
+// FindRight will find all right documents linked to the specified left
+// document that match the optional filter.
+//
+// A transaction is required to ensure isolation.
+func (j *Join) FindRight(ctx context.Context, store *Store, list interface{}, left ID, filter bson.M, sort []string, flags ...Flags) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Join.FindRight")
+	defer span.End()
+
+	// get IDs
+	ids, err := j.RightIDs(ctx, store, left, flags...)
+	if err != nil {
+		return err
+	}
+
+	return j.find(ctx, store, j.right, list, ids, filter, sort, flags)
+}
+
+// FindLeft will find all left documents linked to the specified right
+// document that match the optional filter.
+//
+// A transaction is required to ensure isolation.
+func (j *Join) FindLeft(ctx context.Context, store *Store, list interface{}, right ID, filter bson.M, sort []string, flags ...Flags) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Join.FindLeft")
+	defer span.End()
+
+	// get IDs
+	ids, err := j.LeftIDs(ctx, store, right, flags...)
+	if err != nil {
+		return err
+	}
+
+	return j.find(ctx, store, j.left, list, ids, filter, sort, flags)
+}
+
+func (j *Join) filter(left, right ID) bson.M {
+	return bson.M{
+		j.leftField.Name:  left,
+		j.rightField.Name: right,
+	}
+}
+
+func (j *Join) ids(ctx context.Context, store *Store, field *Field, id ID, other *Field, flags []Flags) ([]ID, error) {
+	// project references
+	res, err := store.M(j.model).ProjectAll(ctx, bson.M{
+		field.Name: id,
+	}, other.Name, nil, 0, 0, false, flags...)
+	if err != nil {
+		return nil, err
+	}
+
+	// collect IDs
+	ids := make([]ID, 0, len(res))
+	for _, value := range res {
+		ids = append(ids, value.(ID))
+	}
+
+	return ids, nil
+}
+
+func (j *Join) find(ctx context.Context, store *Store, model Model, list interface{}, ids []ID, filter bson.M, sort []string, flags []Flags) error {
+	// prepare query
+	query := bson.M{
+		"_id": bson.M{
+			"$in": ids,
+		},
+	}
+
+	// add filter
+	if len(filter) > 0 {
+		query = bson.M{
+			"$and": []bson.M{query, filter},
+		}
+	}
+
+	// find documents
+	err := store.M(model).FindAll(ctx, list, query, sort, 0, 0, false, flags...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/stick"
+)
+
+type groupModel struct {
+	Base `json:"-" bson:",inline" coal:"groups"`
+	Name string `json:"name"`
+	stick.NoValidation
+}
+
+type membershipModel struct {
+	Base   `json:"-" bson:",inline" coal:"memberships"`
+	Author ID     `json:"-" coal:"author:authors"`
+	Group  ID     `json:"-" coal:"group:groups"`
+	Role   string `json:"role"`
+	stick.NoValidation
+}
+
+var memberships = NewJoin(&membershipModel{}, &authorModel{}, "Author", &groupModel{}, "Group")
+
+func TestNewJoin(t *testing.T) {
+	indexes := GetMeta(&membershipModel{}).Indexes
+	indexes = indexes[len(indexes)-2:]
+	assert.Equal(t, []string{"Author", "Group"}, indexes[0].Fields)
+	assert.True(t, indexes[0].Unique)
+	assert.Equal(t, []string{"Group"}, indexes[1].Fields)
+	assert.False(t, indexes[1].Unique)
+
+	assert.PanicsWithValue(t, `coal: field "Role" is not a to-one relationship to "authors"`, func() {
+		NewJoin(&membershipModel{}, &authorModel{}, "Role", &groupModel{}, "Group")
+	})
+
+	assert.PanicsWithValue(t, `coal: field "Author" is not a to-one relationship to "groups"`, func() {
+		NewJoin(&membershipModel{}, &authorModel{}, "Author", &groupModel{}, "Author")
+	})
+}
+
+func TestJoin(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.DeleteAll(&authorModel{})
+		tester.DeleteAll(&groupModel{})
+		tester.DeleteAll(&membershipModel{})
+
+		author1 := tester.Insert(&authorModel{Name: "a1"}).ID()
+		author2 := tester.Insert(&authorModel{Name: "a2"}).ID()
+		group1 := tester.Insert(&groupModel{Name: "g1"}).ID()
+		group2 := tester.Insert(&groupModel{Name: "g2"}).ID()
+
+		linked, err := memberships.Link(nil, tester.Store, author1, group1)
+		assert.NoError(t, err)
+		assert.True(t, linked)
+
+		linked, err = memberships.Link(nil, tester.Store, author1, group1)
+		assert.NoError(t, err)
+		assert.False(t, linked)
+
+		linked, err = memberships.LinkModel(nil, tester.Store, &membershipModel{
+			Author: author1,
+			Group:  group2,
+			Role:   "admin",
+		})
+		assert.NoError(t, err)
+		assert.True(t, linked)
+
+		linked, err = memberships.Link(nil, tester.Store, author2, group1)
+		assert.NoError(t, err)
+		assert.True(t, linked)
+
+		_, err = memberships.Link(nil, tester.Store, author2, ID{})
+		assert.Error(t, err)
+		assert.Equal(t, "missing references", err.Error())
+
+		assert.Equal(t, 3, tester.Count(&membershipModel{}))
+
+		ok, err := memberships.Linked(nil, tester.Store, author1, group2, NoTransaction)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = memberships.Linked(nil, tester.Store, author2, group2, NoTransaction)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		ids, err := memberships.RightIDs(nil, tester.Store, author1, NoTransaction)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []ID{group1, group2}, ids)
+
+		ids, err = memberships.LeftIDs(nil, tester.Store, group1, NoTransaction)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []ID{author1, author2}, ids)
+
+		var groups []*groupModel
+		err = memberships.FindRight(nil, tester.Store, &groups, author1, nil, []string{"Name"}, NoTransaction)
+		assert.NoError(t, err)
+		assert.Len(t, groups, 2)
+		assert.Equal(t, "g1", groups[0].Name)
+		assert.Equal(t, "g2", groups[1].Name)
+
+		var authors []*authorModel
+		err = memberships.FindLeft(nil, tester.Store, &authors, group1, bson.M{
+			"Name": "a2",
+		}, nil, NoTransaction)
+		assert.NoError(t, err)
+		assert.Len(t, authors, 1)
+		assert.Equal(t, author2, authors[0].ID())
+
+		unlinked, err := memberships.Unlink(nil, tester.Store, author1, group1)
+		assert.NoError(t, err)
+		assert.True(t, unlinked)
+
+		unlinked, err = memberships.Unlink(nil, tester.Store, author1, group1)
+		assert.NoError(t, err)
+		assert.False(t, unlinked)
+
+		n, err := memberships.UnlinkAll(nil, tester.Store, group2)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		list := *tester.FindAll(&membershipModel{}).(*[]*membershipModel)
+		assert.Len(t, list, 1)
+		assert.Equal(t, author2, list[0].Author)
+		assert.Equal(t, group1, list[0].Group)
+	})
+}
@@ -0,0 +1,165 @@
+package coal
+
+import (
+	"context"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func init() {
+	// add indexes
+	AddIndex(&Lease{}, true, 0, "Resource")
+	AddIndex(&Lease{}, false, time.Minute, "Expires")
+}
+
+// ErrLeaseLost is returned by RenewLease and ReleaseLease if the lease has
+// expired and has been acquired by another holder or removed.
+var ErrLeaseLost = xo.BF("lease lost")
+
+// Lease is a lightweight distributed lock on a document. Leases are stored in
+// a separate collection and identified by the collection and ID of the leased
+// document. Expired leases may be acquired by other holders and are
+// eventually removed.
+type Lease struct {
+	Base     `json:"-" bson:",inline" coal:"leases"`
+	Resource string    `json:"resource"`
+	Holder   ID        `json:"holder"`
+	Expires  time.Time `json:"expires-at" bson:"expires_at"`
+}
+
+// Validate implements the Model interface.
+func (l *Lease) Validate() error {
+	// check resource
+	if l.Resource == "" {
+		return xo.SF("missing resource")
+	}
+
+	// check holder
+	if l.Holder.IsZero() {
+		return xo.SF("missing holder")
+	}
+
+	// check expires
+	if l.Expires.IsZero() {
+		return xo.SF("missing expires")
+	}
+
+	return nil
+}
+
+// AcquireLease will attempt to acquire a lease on the specified document for
+// the provided time to live. It will return nil if the document is currently
+// leased by another holder or the lease has been acquired concurrently.
+func AcquireLease(ctx context.Context, store *Store, model Model, id ID, ttl time.Duration) (*Lease, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/AcquireLease")
+	defer span.End()
+
+	// check ttl
+	if ttl <= 0 {
+		return nil, xo.F("invalid ttl")
+	}
+
+	// prepare lease
+	lease := &Lease{
+		Base:     B(),
+		Resource: leaseResource(model, id),
+		Holder:   New(),
+		Expires:  time.Now().Add(ttl),
+	}
+
+	// tag resource
+	span.Tag("resource", lease.Resource)
+
+	// insert lease if missing
+	inserted, err := store.M(lease).InsertIfMissing(ctx, bson.M{
+		"Resource": lease.Resource,
+	}, lease, false)
+	if IsDuplicate(err) {
+		// lease has been inserted concurrently
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if inserted {
+		return lease, nil
+	}
+
+	// take over expired lease
+	found, err := store.M(lease).UpdateFirst(ctx, lease, bson.M{
+		"Resource": lease.Resource,
+		"Expires": bson.M{
+			"$lt": time.Now(),
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"Holder":  lease.Holder,
+			"Expires": lease.Expires,
+		},
+	}, nil, false)
+	if err != nil {
+		return nil, err
+	} else if !found {
+		return nil, nil
+	}
+
+	return lease, nil
+}
+
+// RenewLease will extend the provided lease by the specified time to live. It
+// will return ErrLeaseLost if the lease is not held anymore.
+func RenewLease(ctx context.Context, store *Store, lease *Lease, ttl time.Duration) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/RenewLease")
+	span.Tag("resource", lease.Resource)
+	defer span.End()
+
+	// check ttl
+	if ttl <= 0 {
+		return xo.F("invalid ttl")
+	}
+
+	// update lease if still held
+	found, err := store.M(lease).UpdateFirst(ctx, lease, bson.M{
+		"Resource": lease.Resource,
+		"Holder":   lease.Holder,
+	}, bson.M{
+		"$set": bson.M{
+			"Expires": time.Now().Add(ttl),
+		},
+	}, nil, false)
+	if err != nil {
+		return err
+	} else if !found {
+		return ErrLeaseLost.Wrap()
+	}
+
+	return nil
+}
+
+// ReleaseLease will release the provided lease. It will return ErrLeaseLost if
+// the lease is not held anymore.
+func ReleaseLease(ctx context.Context, store *Store, lease *Lease) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/ReleaseLease")
+	span.Tag("resource", lease.Resource)
+	defer span.End()
+
+	// delete lease if still held
+	found, err := store.M(lease).DeleteFirst(ctx, nil, bson.M{
+		"Resource": lease.Resource,
+		"Holder":   lease.Holder,
+	}, nil)
+	if err != nil {
+		return err
+	} else if !found {
+		return ErrLeaseLost.Wrap()
+	}
+
+	return nil
+}
+
+func leaseResource(model Model, id ID) string {
+	return GetMeta(model).Collection + "/" + id.Hex()
+}
@@ -0,0 +1,87 @@
+package coal
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLease(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		id := New()
+
+		lease1, err := AcquireLease(nil, tester.Store, &postModel{}, id, time.Minute)
+		assert.NoError(t, err)
+		assert.NotNil(t, lease1)
+		assert.Equal(t, "posts/"+id.Hex(), lease1.Resource)
+
+		lease2, err := AcquireLease(nil, tester.Store, &postModel{}, id, time.Minute)
+		assert.NoError(t, err)
+		assert.Nil(t, lease2)
+
+		other, err := AcquireLease(nil, tester.Store, &commentModel{}, id, time.Minute)
+		assert.NoError(t, err)
+		assert.NotNil(t, other)
+
+		err = RenewLease(nil, tester.Store, lease1, time.Hour)
+		assert.NoError(t, err)
+		assert.True(t, lease1.Expires.After(time.Now().Add(time.Minute)))
+
+		err = ReleaseLease(nil, tester.Store, lease1)
+		assert.NoError(t, err)
+
+		err = ReleaseLease(nil, tester.Store, lease1)
+		assert.True(t, ErrLeaseLost.Is(err))
+
+		err = RenewLease(nil, tester.Store, lease1, time.Minute)
+		assert.True(t, ErrLeaseLost.Is(err))
+
+		lease2, err = AcquireLease(nil, tester.Store, &postModel{}, id, 10*time.Millisecond)
+		assert.NoError(t, err)
+		assert.NotNil(t, lease2)
+
+		time.Sleep(20 * time.Millisecond)
+
+		lease3, err := AcquireLease(nil, tester.Store, &postModel{}, id, time.Minute)
+		assert.NoError(t, err)
+		assert.NotNil(t, lease3)
+		assert.NotEqual(t, lease2.Holder, lease3.Holder)
+
+		err = RenewLease(nil, tester.Store, lease2, time.Minute)
+		assert.True(t, ErrLeaseLost.Is(err))
+
+		err = ReleaseLease(nil, tester.Store, lease2)
+		assert.True(t, ErrLeaseLost.Is(err))
+
+		assert.Equal(t, 2, tester.Count(&Lease{}))
+	})
+}
+
+func TestLeaseConcurrent(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		id := New()
+
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		var leases int
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lease, err := AcquireLease(nil, tester.Store, &postModel{}, id, time.Minute)
+				assert.NoError(t, err)
+				if lease != nil {
+					mutex.Lock()
+					leases++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, leases)
+		assert.Equal(t, 1, tester.Count(&Lease{}))
+	})
+}
@@ -3,6 +3,7 @@ package coal
 import (
 	"context"
 	"reflect"
+	"time"
 
 	"github.com/256dpi/lungo/bsonkit"
 	"github.com/256dpi/xo"
@@ -24,6 +25,11 @@ const (
 	// TextScoreSort will prepend the sort with a sort based on the text score
 	// of documents. The Base.Score attribute is set to the respective score.
 	TextScoreSort
+
+	// Pooled will decode documents into models acquired from the model pool
+	// when loading a list of model pointers. The models may be returned to
+	// the pool using Meta.Release once they are no longer used.
+	Pooled
 )
 
 // Has returns whether the receiver has set all provided flags.
@@ -63,9 +69,13 @@ var returnAfterUpdate = options.FindOneAndUpdate().SetReturnDocument(options.Aft
 // Manager manages operations on collection of documents. It will validate
 // operations and ensure that they are safe under the MongoDB guarantees.
 type Manager struct {
-	meta  *Meta
-	coll  *Collection
-	trans *Translator
+	store   *Store
+	meta    *Meta
+	coll    *Collection
+	trans   *Translator
+	tenant  *Field
+	created *Field
+	updated *Field
 }
 
 // C is a shorthand to access the underlying collection.
@@ -80,7 +90,8 @@ func (m *Manager) T() *Translator {
 
 // Find will find the document with the specified ID. It will return whether
 // a document has been found. Lock can be set to true to force a write lock on
-// the document and prevent a stale read during a transaction.
+// the document and prevent a stale read during a transaction. Unlocked reads
+// outside of transactions are served from the store cache if configured.
 //
 // A transaction is required for locking.
 func (m *Manager) Find(ctx context.Context, model Model, id ID, lock bool, flags ...Flags) (bool, error) {
@@ -109,10 +120,15 @@ func (m *Manager) Find(ctx context.Context, model Model, id ID, lock bool, flags
 		"_id": id,
 	}
 
+	// get cache
+	cache := m.store.cache.Load()
+
 	// find document
 	var err error
 	if lock {
 		err = m.coll.FindOneAndUpdate(ctx, filter, incrementLock, returnAfterUpdate).Decode(model)
+	} else if cache != nil && cache.usable(m.meta) && !HasTransaction(ctx) {
+		err = cache.find(ctx, m.meta, m.coll, id, model)
 	} else {
 		err = m.coll.FindOne(ctx, filter).Decode(model)
 	}
@@ -171,7 +187,7 @@ func (m *Manager) FindFirst(ctx context.Context, model Model, filter bson.M, sor
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return false, err
 	}
@@ -265,7 +281,7 @@ func (m *Manager) FindAll(ctx context.Context, list interface{}, filter bson.M,
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return err
 	}
@@ -321,7 +337,11 @@ func (m *Manager) FindAll(ctx context.Context, list interface{}, filter bson.M,
 	}
 
 	// decode all
-	err = iter.All(list)
+	if Merge(flags).Has(Pooled) && et.Kind() == reflect.Ptr {
+		err = m.decodePooled(iter, list)
+	} else {
+		err = iter.All(list)
+	}
 	if err != nil {
 		return err
 	}
@@ -347,6 +367,35 @@ func (m *Manager) FindAll(ctx context.Context, list interface{}, filter bson.M,
 	return nil
 }
 
+func (m *Manager) decodePooled(iter *Iterator, list interface{}) error {
+	// ensure close
+	defer iter.Close()
+
+	// get slice
+	slice := reflect.ValueOf(list).Elem()
+
+	// decode documents
+	for iter.Next() {
+		// acquire model
+		model := m.meta.Acquire()
+
+		// decode model
+		err := iter.Decode(model)
+		if err != nil {
+			m.meta.Release(model)
+			return err
+		}
+
+		// add model
+		slice = reflect.Append(slice, reflect.ValueOf(model))
+	}
+
+	// set slice
+	reflect.ValueOf(list).Elem().Set(slice)
+
+	return iter.Error()
+}
+
 // FindEach will find all documents that match the specified filter. Lock can be
 // set to true to force a write lock on the documents and prevent a stale read
 // during a transaction.
@@ -378,7 +427,7 @@ func (m *Manager) FindEach(ctx context.Context, filter bson.M, sort []string, sk
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -537,7 +586,7 @@ func (m *Manager) project(ctx context.Context, filter bson.M, field string, sort
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return err
 	}
@@ -639,7 +688,7 @@ func (m *Manager) Count(ctx context.Context, filter bson.M, skip, limit int64, l
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return 0, err
 	}
@@ -701,7 +750,7 @@ func (m *Manager) Distinct(ctx context.Context, field string, filter bson.M, loc
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return nil, err
 	}
@@ -750,7 +799,10 @@ func (m *Manager) insert(ctx context.Context, models []Model, flags ...Flags) er
 		return nil
 	}
 
-	// check models and ensure IDs
+	// get time
+	now := time.Now()
+
+	// check models, ensure IDs and set timestamps
 	for _, model := range models {
 		// check model
 		if GetMeta(model) != m.meta {
@@ -761,6 +813,9 @@ func (m *Manager) insert(ctx context.Context, models []Model, flags ...Flags) er
 		if model.ID().IsZero() {
 			model.GetBase().DocID = New()
 		}
+
+		// set timestamps
+		m.stampInsert(model, now)
 	}
 
 	// validate models
@@ -778,6 +833,14 @@ func (m *Manager) insert(ctx context.Context, models []Model, flags ...Flags) er
 		Clean(model)
 	}
 
+	// check sizes
+	for _, model := range models {
+		err := CheckSize(m.store, model)
+		if err != nil {
+			return err
+		}
+	}
+
 	// get documents
 	docs := make([]interface{}, 0, len(models))
 	for _, model := range models {
@@ -822,7 +885,7 @@ func (m *Manager) InsertIfMissing(ctx context.Context, filter bson.M, model Mode
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return false, err
 	}
@@ -837,6 +900,9 @@ func (m *Manager) InsertIfMissing(ctx context.Context, filter bson.M, model Mode
 		model.GetBase().DocID = New()
 	}
 
+	// set timestamps
+	m.stampInsert(model, time.Now())
+
 	// validate model
 	if !Merge(flags).Has(NoValidation) {
 		err = model.Validate()
@@ -848,6 +914,12 @@ func (m *Manager) InsertIfMissing(ctx context.Context, filter bson.M, model Mode
 	// clean model
 	Clean(model)
 
+	// check size
+	err = CheckSize(m.store, model)
+	if err != nil {
+		return false, err
+	}
+
 	// prepare options
 	opts := options.Update().SetUpsert(true)
 
@@ -898,6 +970,9 @@ func (m *Manager) Replace(ctx context.Context, model Model, lock bool, flags ...
 		return false, ErrTransactionRequired.Wrap()
 	}
 
+	// set timestamps
+	m.stampReplace(model, time.Now())
+
 	// validate model
 	if !Merge(flags).Has(NoValidation) {
 		err := model.Validate()
@@ -909,6 +984,12 @@ func (m *Manager) Replace(ctx context.Context, model Model, lock bool, flags ...
 	// clean model
 	Clean(model)
 
+	// check size
+	err := CheckSize(m.store, model)
+	if err != nil {
+		return false, err
+	}
+
 	// increment lock manually
 	if lock {
 		model.GetBase().Lock += 1000
@@ -949,6 +1030,9 @@ func (m *Manager) ReplaceFirst(ctx context.Context, filter bson.M, model Model,
 		return false, ErrTransactionRequired.Wrap()
 	}
 
+	// set timestamps
+	m.stampReplace(model, time.Now())
+
 	// validate model
 	if !Merge(flags).Has(NoValidation) {
 		err := model.Validate()
@@ -960,13 +1044,19 @@ func (m *Manager) ReplaceFirst(ctx context.Context, filter bson.M, model Model,
 	// clean model
 	Clean(model)
 
+	// check size
+	err := CheckSize(m.store, model)
+	if err != nil {
+		return false, err
+	}
+
 	// increment lock manually
 	if lock {
 		model.GetBase().Lock += 1000
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return false, err
 	}
@@ -1012,6 +1102,12 @@ func (m *Manager) Update(ctx context.Context, model Model, id ID, update bson.M,
 		return false, err
 	}
 
+	// set timestamps
+	err = m.stampUpdate(&updateDoc, false, time.Now())
+	if err != nil {
+		return false, err
+	}
+
 	// increment lock
 	if lock {
 		_, err := bsonkit.Put(&updateDoc, "$inc._lk", 1, false)
@@ -1075,7 +1171,7 @@ func (m *Manager) UpdateFirst(ctx context.Context, model Model, filter, update b
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return false, err
 	}
@@ -1086,6 +1182,12 @@ func (m *Manager) UpdateFirst(ctx context.Context, model Model, filter, update b
 		return false, err
 	}
 
+	// set timestamps
+	err = m.stampUpdate(&updateDoc, false, time.Now())
+	if err != nil {
+		return false, err
+	}
+
 	// prepare options
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
@@ -1147,7 +1249,7 @@ func (m *Manager) UpdateAll(ctx context.Context, filter, update bson.M, lock boo
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return 0, err
 	}
@@ -1158,6 +1260,12 @@ func (m *Manager) UpdateAll(ctx context.Context, filter, update bson.M, lock boo
 		return 0, err
 	}
 
+	// set timestamps
+	err = m.stampUpdate(&updateDoc, false, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
 	// increment lock
 	if lock {
 		_, err := bsonkit.Put(&updateDoc, "$inc._lk", 1, false)
@@ -1206,7 +1314,7 @@ func (m *Manager) Upsert(ctx context.Context, model Model, filter, update bson.M
 	}
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return false, err
 	}
@@ -1217,6 +1325,12 @@ func (m *Manager) Upsert(ctx context.Context, model Model, filter, update bson.M
 		return false, err
 	}
 
+	// set timestamps
+	err = m.stampUpdate(&updateDoc, true, time.Now())
+	if err != nil {
+		return false, err
+	}
+
 	// increment lock
 	if lock {
 		_, err := bsonkit.Put(&updateDoc, "$inc._lk", 1, false)
@@ -1313,7 +1427,7 @@ func (m *Manager) DeleteAll(ctx context.Context, filter bson.M) (int64, error) {
 	defer span.End()
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return 0, err
 	}
@@ -1338,7 +1452,7 @@ func (m *Manager) DeleteFirst(ctx context.Context, model Model, filter bson.M, s
 	defer span.End()
 
 	// translate filter
-	filterDoc, err := m.trans.Document(filter)
+	filterDoc, err := m.filter(filter)
 	if err != nil {
 		return false, err
 	}
@@ -1375,6 +1489,165 @@ func (m *Manager) DeleteFirst(ctx context.Context, model Model, filter bson.M, s
 	return true, nil
 }
 
+// Batch configures batched mutations.
+type Batch struct {
+	// The maximum number of documents that are mutated per batch.
+	//
+	// Default: 1000.
+	Size int64
+
+	// The duration to wait between batches to throttle the load.
+	Pause time.Duration
+
+	// The callback that is called after each batch with the total number of
+	// processed documents. Returned errors will abort the operation.
+	Progress func(processed int64) error
+}
+
+// UpdateAllBatched will update the documents that match the specified filter
+// in batches ordered by document ID. It will return the number of matched
+// documents. Each batch is matched against the filter again to skip documents
+// that have been changed in the meantime.
+//
+// Warning: The operation is not isolated and should not be run as part of a
+// transaction. Documents changed by the update are not processed again.
+func (m *Manager) UpdateAllBatched(ctx context.Context, filter, update bson.M, batch Batch) (int64, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Manager.UpdateAllBatched")
+	defer span.End()
+
+	// translate update
+	updateDoc, err := m.trans.Document(update)
+	if err != nil {
+		return 0, err
+	}
+
+	// set timestamps
+	err = m.stampUpdate(&updateDoc, false, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return m.batch(ctx, filter, batch, func(filterDoc bson.D) (int64, error) {
+		res, err := m.coll.UpdateMany(ctx, filterDoc, updateDoc)
+		if err != nil {
+			return 0, err
+		}
+		return res.MatchedCount, nil
+	})
+}
+
+// DeleteAllBatched will delete the documents that match the specified filter
+// in batches ordered by document ID. It will return the number of deleted
+// documents. Each batch is matched against the filter again to skip documents
+// that have been changed in the meantime.
+//
+// Warning: The operation is not isolated and should not be run as part of a
+// transaction.
+func (m *Manager) DeleteAllBatched(ctx context.Context, filter bson.M, batch Batch) (int64, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Manager.DeleteAllBatched")
+	defer span.End()
+
+	return m.batch(ctx, filter, batch, func(filterDoc bson.D) (int64, error) {
+		res, err := m.coll.DeleteMany(ctx, filterDoc)
+		if err != nil {
+			return 0, err
+		}
+		return res.DeletedCount, nil
+	})
+}
+
+func (m *Manager) batch(ctx context.Context, filter bson.M, batch Batch, fn func(filterDoc bson.D) (int64, error)) (int64, error) {
+	// set default size
+	if batch.Size <= 0 {
+		batch.Size = 1000
+	}
+
+	// translate filter
+	filterDoc, err := m.filter(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	// prepare options
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(batch.Size).
+		SetProjection(bson.D{{Key: "_id", Value: 1}})
+
+	// process batches
+	var last ID
+	var total int64
+	for {
+		// prepare query
+		query := filterDoc
+		if !last.IsZero() {
+			query = bson.D{{Key: "$and", Value: bson.A{filterDoc, bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$gt", Value: last}}},
+			}}}}
+		}
+
+		// find IDs
+		iter, err := m.coll.Find(ctx, query, opts)
+		if err != nil {
+			return total, err
+		}
+		var docs []struct {
+			ID ID `bson:"_id"`
+		}
+		err = iter.All(&docs)
+		if err != nil {
+			return total, err
+		}
+
+		// check IDs
+		if len(docs) == 0 {
+			return total, nil
+		}
+
+		// collect IDs
+		ids := make([]ID, 0, len(docs))
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		last = ids[len(ids)-1]
+
+		// mutate batch
+		n, err := fn(bson.D{{Key: "$and", Value: bson.A{filterDoc, bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}},
+		}}}})
+		if err != nil {
+			return total, err
+		}
+
+		// update total
+		total += n
+
+		// report progress
+		if batch.Progress != nil {
+			err = batch.Progress(total)
+			if err != nil {
+				return total, xo.W(err)
+			}
+		}
+
+		// check if done
+		if int64(len(docs)) < batch.Size {
+			return total, nil
+		}
+
+		// throttle
+		if batch.Pause > 0 {
+			select {
+			case <-time.After(batch.Pause):
+			case <-ctx.Done():
+				return total, ctx.Err()
+			}
+		}
+	}
+}
+
 // ManagedIterator wraps an iterator to enforce decoding to a model.
 type ManagedIterator struct {
 	meta     *Meta
@@ -3,7 +3,9 @@ package coal
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/256dpi/xo"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -240,6 +242,37 @@ func TestManagerFindAll(t *testing.T) {
 	})
 }
 
+func TestManagerFindAllPooled(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		post1 := tester.Insert(&postModel{
+			Title: "Hello World!",
+		}).(*postModel)
+
+		post2 := tester.Insert(&postModel{
+			Title: "Hello Space!",
+		}).(*postModel)
+
+		meta := GetMeta(&postModel{})
+		m := tester.Store.M(&postModel{})
+
+		var list []*postModel
+		err := m.FindAll(nil, &list, nil, nil, 0, 0, false, NoTransaction, Pooled)
+		assert.NoError(t, err)
+		assert.Equal(t, []*postModel{post1, post2}, list)
+
+		released := list[0]
+		meta.Release(list[0], list[1])
+		assert.Equal(t, &postModel{}, released)
+
+		list = nil
+		err = m.FindAll(nil, &list, bson.M{
+			"Title": "Hello Space!",
+		}, nil, 0, 0, false, NoTransaction, Pooled)
+		assert.NoError(t, err)
+		assert.Equal(t, []*postModel{post2}, list)
+	})
+}
+
 func TestManagerFindAllTextScoreSort(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		if tester.Store.Lungo() {
@@ -931,6 +964,71 @@ func TestManagerUpdateAll(t *testing.T) {
 	})
 }
 
+func TestManagerUpdateAllBatched(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		for i := 0; i < 5; i++ {
+			tester.Insert(&postModel{
+				Title: "Hello World!",
+			})
+		}
+		tester.Insert(&postModel{
+			Title: "Hello Space!",
+		})
+
+		m := tester.Store.M(&postModel{})
+
+		// missing
+		matched, err := m.UpdateAllBatched(nil, bson.M{
+			"Title": "foo",
+		}, bson.M{
+			"$set": bson.M{
+				"Title": "Hello Space!",
+			},
+		}, Batch{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), matched)
+
+		// existing
+		var progress []int64
+		matched, err = m.UpdateAllBatched(nil, bson.M{
+			"Title": "Hello World!",
+		}, bson.M{
+			"$set": bson.M{
+				"Title": "Hello Moon!",
+			},
+		}, Batch{
+			Size:  2,
+			Pause: time.Millisecond,
+			Progress: func(processed int64) error {
+				progress = append(progress, processed)
+				return nil
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), matched)
+		assert.Equal(t, []int64{2, 4, 5}, progress)
+		assert.Equal(t, 5, tester.Count(&postModel{}, bson.M{
+			"Title": "Hello Moon!",
+		}))
+
+		// abort
+		matched, err = m.UpdateAllBatched(nil, bson.M{
+			"Title": "Hello Moon!",
+		}, bson.M{
+			"$set": bson.M{
+				"Title": "Hello World!",
+			},
+		}, Batch{
+			Size: 2,
+			Progress: func(processed int64) error {
+				return xo.F("foo")
+			},
+		})
+		assert.Error(t, err)
+		assert.Equal(t, int64(2), matched)
+	})
+}
+
 func TestManagerUpsert(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		m := tester.Store.M(&postModel{})
@@ -1072,6 +1170,44 @@ func TestManagerDeleteAll(t *testing.T) {
 	})
 }
 
+func TestManagerDeleteAllBatched(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		for i := 0; i < 5; i++ {
+			tester.Insert(&postModel{
+				Title: "Hello World!",
+			})
+		}
+		tester.Insert(&postModel{
+			Title: "Hello Space!",
+		})
+
+		m := tester.Store.M(&postModel{})
+
+		// missing
+		deleted, err := m.DeleteAllBatched(nil, bson.M{
+			"Title": "foo",
+		}, Batch{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), deleted)
+
+		// existing
+		var progress []int64
+		deleted, err = m.DeleteAllBatched(nil, bson.M{
+			"Title": "Hello World!",
+		}, Batch{
+			Size: 2,
+			Progress: func(processed int64) error {
+				progress = append(progress, processed)
+				return nil
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), deleted)
+		assert.Equal(t, []int64{2, 4, 5}, progress)
+		assert.Equal(t, 1, tester.Count(&postModel{}))
+	})
+}
+
 func TestManagerDeleteFirst(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		tester.Insert(&postModel{
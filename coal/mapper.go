@@ -0,0 +1,345 @@
+package coal
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/256dpi/xo"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// Mapper populates application-defined DTO structs from models and their
+// loaded related models. DTO fields are mapped using the "map" tag:
+//
+//   - `map:"Title"` copies the field "Title" of the model. The special field
+//     "ID" copies the model ID.
+//   - `map:"Post.Title"` copies the field "Title" of the model referenced by
+//     the relationship field "Post". Paths may only traverse to-one, has-one
+//     and polymorphic relationships.
+//   - `map:"Comments"` on a struct, struct pointer or struct slice field maps
+//     the related models of the relationship field "Comments" recursively.
+//
+// Fields without a tag are ignored. Missing related models leave the DTO field
+// unchanged. Related models must be added to the mapper before mapping.
+type Mapper struct {
+	models map[string]map[ID]Model
+	lists  map[string][]Model
+}
+
+// NewMapper creates and returns a new mapper using the provided related models.
+func NewMapper(related ...Model) *Mapper {
+	// create mapper
+	mapper := &Mapper{
+		models: map[string]map[ID]Model{},
+		lists:  map[string][]Model{},
+	}
+
+	// add models
+	mapper.Add(related...)
+
+	return mapper
+}
+
+// Add will add the provided related models. A model with the same type and ID
+// as an already added model replaces it.
+func (m *Mapper) Add(related ...Model) {
+	for _, model := range related {
+		// get type
+		typ := GetMeta(model).PluralName
+
+		// ensure map
+		if m.models[typ] == nil {
+			m.models[typ] = map[ID]Model{}
+		}
+
+		// replace existing model
+		if m.models[typ][model.ID()] != nil {
+			for i, item := range m.lists[typ] {
+				if item.ID() == model.ID() {
+					m.lists[typ][i] = model
+					break
+				}
+			}
+			m.models[typ][model.ID()] = model
+			continue
+		}
+
+		// add model
+		m.lists[typ] = append(m.lists[typ], model)
+		m.models[typ][model.ID()] = model
+	}
+}
+
+// Map will populate the provided DTO struct pointer from the specified model.
+func (m *Mapper) Map(dto interface{}, model Model) error {
+	// check value
+	value := reflect.ValueOf(dto)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return xo.F("expected struct pointer")
+	}
+
+	return m.populate(value.Elem(), model)
+}
+
+// MapAll will populate the provided pointer to a slice of DTO structs or DTO
+// struct pointers from the specified models.
+func (m *Mapper) MapAll(list interface{}, models []Model) error {
+	// check value
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Slice {
+		return xo.F("expected slice pointer")
+	}
+
+	// map models
+	slice, err := m.slice(value.Elem().Type(), models)
+	if err != nil {
+		return err
+	}
+
+	// set slice
+	value.Elem().Set(slice)
+
+	return nil
+}
+
+func (m *Mapper) populate(dto reflect.Value, model Model) error {
+	// get type
+	typ := dto.Type()
+
+	// map fields
+	for i := 0; i < typ.NumField(); i++ {
+		// get path
+		path := typ.Field(i).Tag.Get("map")
+		if path == "" {
+			continue
+		}
+
+		// map field
+		err := m.field(dto.Field(i), model, strings.Split(path, "."))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Mapper) field(dst reflect.Value, model Model, path []string) error {
+	// traverse relationships
+	for len(path) > 1 {
+		// get field
+		field := GetMeta(model).Fields[path[0]]
+		if field == nil {
+			return xo.F(`unknown field "%s" on "%s"`, path[0], GetMeta(model).Name)
+		} else if !field.ToOne && !field.HasOne && !field.Polymorphic {
+			return xo.F(`field "%s" on "%s" is not a to-one, has-one or polymorphic relationship`, path[0], GetMeta(model).Name)
+		}
+
+		// resolve related model
+		related := m.resolve(model, field)
+		if len(related) == 0 {
+			return nil
+		}
+
+		// continue with related model
+		model = related[0]
+		path = path[1:]
+	}
+
+	// handle ID
+	if path[0] == "ID" {
+		return assign(dst, reflect.ValueOf(model.ID()), "ID")
+	}
+
+	// get field
+	field := GetMeta(model).Fields[path[0]]
+	if field == nil {
+		return xo.F(`unknown field "%s" on "%s"`, path[0], GetMeta(model).Name)
+	}
+
+	// map related models
+	if field.RelName != "" && isDTO(dst.Type()) {
+		return m.related(dst, model, field)
+	}
+
+	// get value
+	value := reflect.ValueOf(stick.MustGet(model, field.Name))
+
+	return assign(dst, value, field.Name)
+}
+
+func (m *Mapper) related(dst reflect.Value, model Model, field *Field) error {
+	// resolve related models
+	related := m.resolve(model, field)
+
+	// handle slices
+	if dst.Kind() == reflect.Slice {
+		slice, err := m.slice(dst.Type(), related)
+		if err != nil {
+			return err
+		}
+		dst.Set(slice)
+		return nil
+	}
+
+	// check related
+	if len(related) == 0 {
+		return nil
+	}
+
+	// handle pointers
+	if dst.Kind() == reflect.Ptr {
+		value := reflect.New(dst.Type().Elem())
+		err := m.populate(value.Elem(), related[0])
+		if err != nil {
+			return err
+		}
+		dst.Set(value)
+		return nil
+	}
+
+	return m.populate(dst, related[0])
+}
+
+func (m *Mapper) slice(typ reflect.Type, models []Model) (reflect.Value, error) {
+	// get element type
+	elem := typ.Elem()
+	ptr := elem.Kind() == reflect.Ptr
+	if ptr {
+		elem = elem.Elem()
+	}
+
+	// check element type
+	if elem.Kind() != reflect.Struct {
+		return reflect.Value{}, xo.F("expected slice of structs or struct pointers")
+	}
+
+	// map models
+	slice := reflect.MakeSlice(typ, 0, len(models))
+	for _, model := range models {
+		value := reflect.New(elem)
+		err := m.populate(value.Elem(), model)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !ptr {
+			value = value.Elem()
+		}
+		slice = reflect.Append(slice, value)
+	}
+
+	return slice, nil
+}
+
+func (m *Mapper) resolve(model Model, field *Field) []Model {
+	// handle to-one and to-many relationships
+	if field.ToOne || field.ToMany {
+		var list []Model
+		switch value := stick.MustGet(model, field.Name).(type) {
+		case ID:
+			if related := m.models[field.RelType][value]; related != nil {
+				list = append(list, related)
+			}
+		case *ID:
+			if value != nil && m.models[field.RelType][*value] != nil {
+				list = append(list, m.models[field.RelType][*value])
+			}
+		case []ID:
+			for _, id := range value {
+				if related := m.models[field.RelType][id]; related != nil {
+					list = append(list, related)
+				}
+			}
+		}
+		return list
+	}
+
+	// handle polymorphic relationships
+	if field.Polymorphic {
+		var list []Model
+		switch value := stick.MustGet(model, field.Name).(type) {
+		case Ref:
+			if related := m.models[value.Type][value.ID]; related != nil {
+				list = append(list, related)
+			}
+		case *Ref:
+			if value != nil && m.models[value.Type][value.ID] != nil {
+				list = append(list, m.models[value.Type][value.ID])
+			}
+		}
+		return list
+	}
+
+	// handle has-one and has-many relationships
+	var list []Model
+	for _, related := range m.lists[field.RelType] {
+		// get inverse field
+		inverse := GetMeta(related).Relationships[field.RelInverse]
+		if inverse == nil {
+			continue
+		}
+
+		// check reference
+		switch value := stick.MustGet(related, inverse.Name).(type) {
+		case ID:
+			if value == model.ID() {
+				list = append(list, related)
+			}
+		case *ID:
+			if value != nil && *value == model.ID() {
+				list = append(list, related)
+			}
+		case []ID:
+			if stick.Contains(value, model.ID()) {
+				list = append(list, related)
+			}
+		}
+	}
+
+	return list
+}
+
+func isDTO(typ reflect.Type) bool {
+	// unwrap slices and pointers
+	if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	return typ.Kind() == reflect.Struct
+}
+
+func assign(dst, value reflect.Value, name string) error {
+	// dereference pointers
+	if value.Kind() == reflect.Ptr && dst.Kind() != reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	// allocate pointers
+	if dst.Kind() == reflect.Ptr && value.Kind() != reflect.Ptr {
+		ptr := reflect.New(dst.Type().Elem())
+		err := assign(ptr.Elem(), value, name)
+		if err != nil {
+			return err
+		}
+		dst.Set(ptr)
+		return nil
+	}
+
+	// assign value
+	if value.Type().AssignableTo(dst.Type()) {
+		dst.Set(value)
+		return nil
+	} else if value.Type().ConvertibleTo(dst.Type()) && value.Kind() == dst.Kind() {
+		dst.Set(value.Convert(dst.Type()))
+		return nil
+	}
+
+	return xo.F(`field "%s" of type "%s" is not assignable to "%s"`, name, value.Type(), dst.Type())
+}
@@ -0,0 +1,101 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire/stick"
+)
+
+type postDTO struct {
+	ID       ID            `map:"ID"`
+	Title    string        `map:"Title"`
+	Public   *bool         `map:"Published"`
+	Note     *noteDTO      `map:"Note"`
+	Comments []commentDTO  `map:"Comments"`
+	Tags     []*commentDTO `map:"Comments"`
+	Ignored  string
+}
+
+type noteDTO struct {
+	Title     string `map:"Title"`
+	PostTitle string `map:"Post.Title"`
+}
+
+type commentDTO struct {
+	Message   string `map:"Message"`
+	Post      ID     `map:"Post"`
+	PostTitle string `map:"Post.Title"`
+	Parent    string `map:"Parent.Message"`
+}
+
+func TestMapper(t *testing.T) {
+	post := &postModel{Base: B(), Title: "Hello", Published: true}
+	note := &noteModel{Base: B(), Title: "Note", Post: post.ID()}
+	comment1 := &commentModel{Base: B(), Message: "First", Post: post.ID()}
+	comment2 := &commentModel{Base: B(), Message: "Second", Post: post.ID(), Parent: stick.P(comment1.ID())}
+	other := &commentModel{Base: B(), Message: "Other", Post: New()}
+
+	mapper := NewMapper(post, note, comment1, comment2, other)
+
+	var dto postDTO
+	err := mapper.Map(&dto, post)
+	assert.NoError(t, err)
+	assert.Equal(t, postDTO{
+		ID:     post.ID(),
+		Title:  "Hello",
+		Public: stick.P(true),
+		Note: &noteDTO{
+			Title:     "Note",
+			PostTitle: "Hello",
+		},
+		Comments: []commentDTO{
+			{Message: "First", Post: post.ID(), PostTitle: "Hello"},
+			{Message: "Second", Post: post.ID(), PostTitle: "Hello", Parent: "First"},
+		},
+		Tags: []*commentDTO{
+			{Message: "First", Post: post.ID(), PostTitle: "Hello"},
+			{Message: "Second", Post: post.ID(), PostTitle: "Hello", Parent: "First"},
+		},
+	}, dto)
+
+	var list []commentDTO
+	err = mapper.MapAll(&list, []Model{comment2, other})
+	assert.NoError(t, err)
+	assert.Equal(t, []commentDTO{
+		{Message: "Second", Post: post.ID(), PostTitle: "Hello", Parent: "First"},
+		{Message: "Other", Post: other.Post},
+	}, list)
+
+	err = mapper.Map(dto, post)
+	assert.Error(t, err)
+
+	err = mapper.Map(&struct {
+		Foo string `map:"Foo"`
+	}{}, post)
+	assert.Error(t, err)
+	assert.Equal(t, `unknown field "Foo" on "coal.postModel"`, err.Error())
+
+	err = mapper.Map(&struct {
+		Title int `map:"Title"`
+	}{}, post)
+	assert.Error(t, err)
+	assert.Equal(t, `field "Title" of type "string" is not assignable to "int"`, err.Error())
+
+	err = mapper.Map(&struct {
+		Title string `map:"Comments.Message"`
+	}{}, post)
+	assert.Error(t, err)
+	assert.Equal(t, `field "Comments" on "coal.postModel" is not a to-one, has-one or polymorphic relationship`, err.Error())
+
+	mapper.Add(&commentModel{Base: comment1.Base, Message: "Updated", Post: post.ID()})
+
+	dto = postDTO{}
+	err = mapper.Map(&dto, post)
+	assert.NoError(t, err)
+	assert.Equal(t, []commentDTO{
+		{Message: "Updated", Post: post.ID(), PostTitle: "Hello"},
+		{Message: "Second", Post: post.ID(), PostTitle: "Hello", Parent: "Updated"},
+	}, dto.Comments)
+}
@@ -11,12 +11,16 @@ import (
 	"github.com/256dpi/fire/stick"
 )
 
-var metaMutex sync.Mutex
+var metaMutex sync.RWMutex
 var metaCache = map[reflect.Type]*Meta{}
+var metaPools = map[reflect.Type]*sync.Pool{}
 
 var itemMetaMutex sync.Mutex
 var itemMetaCache = map[reflect.Type]*ItemMeta{}
 
+var nestedMetaMutex sync.Mutex
+var nestedMetaCache = map[reflect.Type]*ItemMeta{}
+
 var baseType = reflect.TypeOf(Base{})
 var itemBaseType = reflect.TypeOf(ItemBase{})
 var toOneType = reflect.TypeOf(ID{})
@@ -24,6 +28,8 @@ var optToOneType = reflect.TypeOf(&ID{})
 var toManyType = reflect.TypeOf([]ID{})
 var hasOneType = reflect.TypeOf(HasOne{})
 var hasManyType = reflect.TypeOf(HasMany{})
+var refType = reflect.TypeOf(Ref{})
+var optRefType = reflect.TypeOf(&Ref{})
 
 // The HasOne type denotes a has-one relationship in a model declaration.
 //
@@ -44,14 +50,16 @@ type Field struct {
 	Flags []string
 
 	// The relationship status.
-	ToOne   bool
-	ToMany  bool
-	HasOne  bool
-	HasMany bool
+	ToOne       bool
+	ToMany      bool
+	HasOne      bool
+	HasMany     bool
+	Polymorphic bool
 
 	// The relationship information.
 	RelName    string
 	RelType    string
+	RelTypes   []string
 	RelInverse string
 }
 
@@ -76,8 +84,16 @@ type ItemField struct {
 	// Whether the field is a pointer and thus optional.
 	Optional bool
 
-	// The item meta if field is a type embedding ItemBase.
+	// The value type if the field is a string keyed map.
+	MapValue reflect.Type
+
+	// The item meta if field is a type embedding ItemBase or a slice or map
+	// of such types.
 	ItemMeta *ItemMeta
+
+	// The nested meta if the stored field is a plain struct or a pointer,
+	// slice or map of such types.
+	Nested *ItemMeta
 }
 
 // Meta stores extracted meta data from a model.
@@ -115,6 +131,12 @@ type Meta struct {
 	// The flagged fields.
 	FlaggedFields map[string][]*Field
 
+	// The virtual fields.
+	Virtuals map[string]*Virtual
+
+	// The virtual fields ordered.
+	OrderedVirtuals []*Virtual
+
 	// The accessor.
 	Accessor *stick.Accessor
 
@@ -151,15 +173,23 @@ type ItemMeta struct {
 //
 // Note: This method panics if the passed Model has invalid fields or tags.
 func GetMeta(model Model) *Meta {
-	// acquire mutex
-	metaMutex.Lock()
-	defer metaMutex.Unlock()
-
 	// get type and name
 	modelType := reflect.TypeOf(model).Elem()
 
 	// check if meta has already been cached
+	metaMutex.RLock()
 	meta, ok := metaCache[modelType]
+	metaMutex.RUnlock()
+	if ok {
+		return meta
+	}
+
+	// acquire mutex
+	metaMutex.Lock()
+	defer metaMutex.Unlock()
+
+	// check again if meta has been cached in the meantime
+	meta, ok = metaCache[modelType]
 	if ok {
 		return meta
 	}
@@ -240,10 +270,16 @@ func GetMeta(model Model) *Meta {
 				JSONKey:  stick.JSON.GetKey(field),
 				BSONKey:  stick.BSON.GetKey(field),
 				Optional: field.Type.Kind() == reflect.Ptr,
+				MapValue: getMapValue(field.Type),
 				ItemMeta: GetItemMeta(field.Type),
 			},
 		}
 
+		// set nested meta
+		if metaField.BSONKey != "" && metaField.ItemMeta == nil {
+			metaField.Nested = getNestedMeta(field.Type, nil)
+		}
+
 		// check if field is a valid to-one relationship
 		if field.Type == toOneType || field.Type == optToOneType {
 			if len(coalTags) > 0 && strings.Count(coalTags[0], ":") > 0 {
@@ -286,6 +322,27 @@ func GetMeta(model Model) *Meta {
 			}
 		}
 
+		// check if field is a valid polymorphic relationship
+		if field.Type == refType || field.Type == optRefType {
+			if len(coalTags) > 0 && strings.Count(coalTags[0], ":") > 0 {
+				// check tag
+				if strings.Count(coalTags[0], ":") > 1 {
+					panic(`coal: expected to find a tag of the form 'coal:"name:type|type"' on polymorphic relationship`)
+				}
+
+				// parse special polymorphic relationship tag
+				polyTag := strings.Split(coalTags[0], ":")
+
+				// set relationship data
+				metaField.Polymorphic = true
+				metaField.RelName = polyTag[0]
+				metaField.RelTypes = strings.Split(polyTag[1], "|")
+
+				// remove tag
+				coalTags = coalTags[1:]
+			}
+		}
+
 		// check if field is a valid has-one relationship
 		if field.Type == hasOneType {
 			// check tag
@@ -391,8 +448,9 @@ func GetMeta(model Model) *Meta {
 		},
 	})
 
-	// cache meta
+	// cache meta and create pool
 	metaCache[modelType] = meta
+	metaPools[modelType] = &sync.Pool{}
 
 	return meta
 }
@@ -410,6 +468,58 @@ func (m *Meta) MakeSlice() interface{} {
 	return pointer.Interface()
 }
 
+// Acquire returns a pointer to a zero initialized model from the model pool or
+// allocates a new model if the pool is empty. Acquired models may be returned
+// to the pool using Release once they are no longer referenced.
+func (m *Meta) Acquire() Model {
+	// get pooled model
+	if pool := m.getPool(); pool != nil {
+		if model, ok := pool.Get().(Model); ok {
+			return model
+		}
+	}
+
+	return m.Make()
+}
+
+// Release will reset the provided models to their zero values and return them
+// to the model pool. Referenced values like slices and maps are not modified.
+//
+// Note: Models must not be used or referenced after they have been released.
+func (m *Meta) Release(models ...Model) {
+	// get pool
+	pool := m.getPool()
+	if pool == nil {
+		return
+	}
+
+	for _, model := range models {
+		// check model
+		if model == nil {
+			continue
+		}
+		value := reflect.ValueOf(model)
+		if value.Type().Elem() != m.Type || value.IsNil() {
+			continue
+		}
+
+		// reset model
+		value.Elem().SetZero()
+
+		// pool model
+		pool.Put(model)
+	}
+}
+
+func (m *Meta) getPool() *sync.Pool {
+	// get pool
+	metaMutex.RLock()
+	pool := metaPools[m.Type]
+	metaMutex.RUnlock()
+
+	return pool
+}
+
 // GetItemMeta returns the meta structure for the specified item type. It will
 // always return the same value for the same item.
 func GetItemMeta(typ reflect.Type) *ItemMeta {
@@ -422,7 +532,7 @@ func GetItemMeta(typ reflect.Type) *ItemMeta {
 	}
 
 	// unwrap pointer
-	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
 		typ = typ.Elem()
 	}
 	if typ.Kind() != reflect.Struct {
@@ -466,9 +576,15 @@ func GetItemMeta(typ reflect.Type) *ItemMeta {
 			JSONKey:  stick.JSON.GetKey(field),
 			BSONKey:  stick.BSON.GetKey(field),
 			Optional: field.Type.Kind() == reflect.Ptr,
+			MapValue: getMapValue(field.Type),
 			ItemMeta: GetItemMeta(field.Type),
 		}
 
+		// set nested meta
+		if metaField.BSONKey != "" && metaField.ItemMeta == nil {
+			metaField.Nested = getNestedMeta(field.Type, nil)
+		}
+
 		// add field
 		meta.Fields[metaField.Name] = metaField
 		meta.OrderedFields = append(meta.OrderedFields, metaField)
@@ -503,3 +619,141 @@ func GetItemMeta(typ reflect.Type) *ItemMeta {
 
 	return meta
 }
+
+func getMapValue(typ reflect.Type) reflect.Type {
+	// unwrap pointer
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	// check map
+	if typ.Kind() != reflect.Map || typ.Key().Kind() != reflect.String {
+		return nil
+	}
+
+	return typ.Elem()
+}
+
+func getNestedMeta(typ reflect.Type, seen map[reflect.Type]*ItemMeta) *ItemMeta {
+	// unwrap pointer, slice and map
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+
+	// check type
+	if typ.Kind() != reflect.Struct || typ == timeType || typ.PkgPath() == "go.mongodb.org/mongo-driver/bson/primitive" {
+		return nil
+	}
+
+	// check custom encodings
+	ptr := reflect.PtrTo(typ)
+	if ptr.Implements(marshalerType) || ptr.Implements(valueMarshalerType) || LookupType(typ) != nil {
+		return nil
+	}
+
+	// check items
+	if typ.NumField() > 0 && typ.Field(0).Type == itemBaseType && typ.Field(0).Anonymous {
+		return nil
+	}
+
+	// check seen
+	if meta, ok := seen[typ]; ok {
+		return meta
+	}
+
+	// check cache
+	nestedMetaMutex.Lock()
+	meta, ok := nestedMetaCache[typ]
+	nestedMetaMutex.Unlock()
+	if ok {
+		return meta
+	}
+
+	// prepare meta
+	meta = &ItemMeta{
+		Type:           typ,
+		Name:           typ.String(),
+		Fields:         map[string]*ItemField{},
+		DatabaseFields: map[string]*ItemField{},
+		Attributes:     map[string]*ItemField{},
+		Accessor:       stick.BuildAccessor(reflect.New(typ).Interface()),
+	}
+
+	// mark seen to handle recursive types
+	if seen == nil {
+		seen = map[reflect.Type]*ItemMeta{}
+	}
+	seen[typ] = meta
+
+	// parse fields
+	for i := 0; i < typ.NumField(); i++ {
+		// get field
+		field := typ.Field(i)
+
+		// skip unexported fields
+		if !field.IsExported() {
+			continue
+		}
+
+		// get field kind
+		fieldKind := field.Type.Kind()
+		if fieldKind == reflect.Ptr {
+			fieldKind = field.Type.Elem().Kind()
+		}
+
+		// prepare meta
+		metaField := &ItemField{
+			Index:    i,
+			Name:     field.Name,
+			Type:     field.Type,
+			Kind:     fieldKind,
+			JSONKey:  stick.JSON.GetKey(field),
+			BSONKey:  stick.BSON.GetKey(field),
+			Optional: field.Type.Kind() == reflect.Ptr,
+			MapValue: getMapValue(field.Type),
+			ItemMeta: GetItemMeta(field.Type),
+		}
+
+		// set nested meta
+		if metaField.BSONKey != "" && metaField.ItemMeta == nil {
+			metaField.Nested = getNestedMeta(field.Type, seen)
+		}
+
+		// add field
+		meta.Fields[metaField.Name] = metaField
+		meta.OrderedFields = append(meta.OrderedFields, metaField)
+
+		// add database fields
+		if metaField.BSONKey != "" {
+			// check existence
+			if meta.DatabaseFields[metaField.BSONKey] != nil {
+				panic(fmt.Sprintf(`coal: duplicate BSON key "%s"`, metaField.BSONKey))
+			}
+
+			// add field
+			meta.DatabaseFields[metaField.BSONKey] = metaField
+		}
+
+		// add attributes
+		if metaField.JSONKey != "" {
+			// check existence
+			if meta.Attributes[metaField.JSONKey] != nil {
+				panic(fmt.Sprintf(`coal: duplicate JSON key "%s"`, metaField.JSONKey))
+			}
+
+			// add field
+			meta.Attributes[metaField.JSONKey] = metaField
+		}
+	}
+
+	// cache meta, keep existing
+	nestedMetaMutex.Lock()
+	if cached, ok := nestedMetaCache[typ]; ok {
+		meta = cached
+	} else {
+		nestedMetaCache[typ] = meta
+	}
+	nestedMetaMutex.Unlock()
+
+	return meta
+}
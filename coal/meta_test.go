@@ -620,6 +620,16 @@ func TestGetMetaErrors(t *testing.T) {
 		GetMeta(&invalidModel{})
 	})
 
+	assert.PanicsWithValue(t, `coal: expected to find a tag of the form 'coal:"name:type|type"' on polymorphic relationship`, func() {
+		type m struct {
+			Base `json:"-" bson:",inline" coal:"foo:foos"`
+			Foo  Ref `coal:"foo:foo|bar:foo"`
+			stick.NoValidation
+		}
+
+		GetMeta(&m{})
+	})
+
 	// assert.PanicsWithValue(t, `coal: duplicate JSON key "text"`, func() {
 	// 	type invalidModel struct {
 	// 		Base  `json:"-" bson:",inline" coal:"ms"`
@@ -653,6 +663,34 @@ func TestGetMetaErrors(t *testing.T) {
 	})
 }
 
+func TestMetaPolymorphic(t *testing.T) {
+	type m struct {
+		Base       `json:"-" bson:",inline" coal:"foos"`
+		Subject    Ref  `json:"-" bson:"subject" coal:"subject:posts|notes"`
+		OptSubject *Ref `json:"-" bson:"opt_subject" coal:"opt-subject:posts"`
+		stick.NoValidation
+	}
+
+	meta := GetMeta(&m{})
+
+	subject := meta.Fields["Subject"]
+	assert.True(t, subject.Polymorphic)
+	assert.False(t, subject.Optional)
+	assert.Equal(t, "subject", subject.RelName)
+	assert.Equal(t, "", subject.RelType)
+	assert.Equal(t, []string{"posts", "notes"}, subject.RelTypes)
+	assert.Equal(t, subject, meta.Relationships["subject"])
+
+	optSubject := meta.Fields["OptSubject"]
+	assert.True(t, optSubject.Polymorphic)
+	assert.True(t, optSubject.Optional)
+	assert.Equal(t, []string{"posts"}, optSubject.RelTypes)
+	assert.Equal(t, optSubject, meta.Relationships["opt-subject"])
+
+	assert.NoError(t, Verify(append([]Model{&m{}}, modelList...)))
+	assert.Equal(t, "missing type posts for relationship coal.m#subject", Verify([]Model{&m{}}, "coal.m#opt-subject").Error())
+}
+
 func TestMetaMake(t *testing.T) {
 	post := GetMeta(&postModel{}).Make()
 	assert.Equal(t, "*coal.postModel", reflect.TypeOf(post).String())
@@ -663,6 +701,22 @@ func TestMetaMakeSlice(t *testing.T) {
 	assert.Equal(t, "*[]*coal.postModel", reflect.TypeOf(posts).String())
 }
 
+func TestMetaAcquireRelease(t *testing.T) {
+	meta := GetMeta(&postModel{})
+
+	post := meta.Acquire().(*postModel)
+	assert.Equal(t, &postModel{}, post)
+
+	post.Title = "Hello World!"
+	post.Published = true
+
+	meta.Release(post, nil, &noteModel{})
+	assert.Equal(t, &postModel{}, post)
+
+	post = meta.Acquire().(*postModel)
+	assert.Equal(t, &postModel{}, post)
+}
+
 func TestMetaSpecial(t *testing.T) {
 	type m struct {
 		Base `json:"-" bson:",inline" coal:"foos"`
@@ -694,3 +748,11 @@ func BenchmarkGetMetaAccess(b *testing.B) {
 		GetMeta(&postModel{})
 	}
 }
+
+func BenchmarkGetMetaAccessParallel(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			GetMeta(&postModel{})
+		}
+	})
+}
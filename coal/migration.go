@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 	"sync/atomic"
 	"time"
 
@@ -28,6 +29,56 @@ type Migration struct {
 
 	// The migration function.
 	Migrator func(ctx context.Context, store *Store) (int64, int64, error)
+
+	// The optional function that reverts the migration. Migrations without a
+	// rollback function cannot be rolled back.
+	Rollback func(ctx context.Context, store *Store) error
+}
+
+func init() {
+	// add indexes
+	AddIndex(&AppliedMigration{}, true, 0, "Name")
+}
+
+// ErrMigrationLocked is returned by Migrator.Apply and Migrator.Rollback if
+// another process is currently migrating the store.
+var ErrMigrationLocked = xo.BF("migration locked")
+
+// AppliedMigration is the record of a migration that has been applied using
+// Migrator.Apply.
+type AppliedMigration struct {
+	Base     `json:"-" bson:",inline" coal:"migrations"`
+	Name     string    `json:"name"`
+	Applied  time.Time `json:"applied-at" bson:"applied_at"`
+	Matched  int64     `json:"matched"`
+	Modified int64     `json:"modified"`
+}
+
+// Validate implements the Model interface.
+func (m *AppliedMigration) Validate() error {
+	// check name
+	if m.Name == "" {
+		return xo.SF("missing name")
+	}
+
+	// check applied
+	if m.Applied.IsZero() {
+		return xo.SF("missing applied")
+	}
+
+	return nil
+}
+
+// MigrationStatus describes the status of a registered migration.
+type MigrationStatus struct {
+	// The name.
+	Name string
+
+	// Whether the migration has been applied.
+	Applied bool
+
+	// The time the migration has been applied.
+	AppliedAt time.Time
 }
 
 // Migrator manages multiple migrations.
@@ -40,8 +91,20 @@ func NewMigrator() *Migrator {
 	return &Migrator{}
 }
 
-// Add will add the provided migration.
-func (m *Migrator) Add(migration Migration) {
+// Add will add the provided migration. Migrations are applied in the order
+// they have been added. It will return an error and not add the migration if
+// the name is missing or has already been used.
+func (m *Migrator) Add(migration Migration) error {
+	// check name
+	if migration.Name == "" {
+		return xo.F("missing migration name")
+	}
+	for _, other := range m.migrations {
+		if other.Name == migration.Name {
+			return xo.F("duplicate migration %q", migration.Name)
+		}
+	}
+
 	// ensure timeout
 	if migration.Timeout == 0 {
 		migration.Timeout = 5 * time.Minute
@@ -49,6 +112,8 @@ func (m *Migrator) Add(migration Migration) {
 
 	// add migration
 	m.migrations = append(m.migrations, migration)
+
+	return nil
 }
 
 // Run will run all added migrations.
@@ -56,7 +121,7 @@ func (m *Migrator) Run(store *Store, logger io.Writer, reporter func(error)) err
 	// run synchronous migrations
 	for _, migration := range m.migrations {
 		if !migration.Async {
-			err := m.run(store, logger, &migration)
+			_, _, err := m.run(context.Background(), store, logger, &migration)
 			if err != nil {
 				return err
 			}
@@ -67,7 +132,7 @@ func (m *Migrator) Run(store *Store, logger io.Writer, reporter func(error)) err
 	go func() {
 		for _, migration := range m.migrations {
 			if migration.Async {
-				err := m.run(store, logger, &migration)
+				_, _, err := m.run(context.Background(), store, logger, &migration)
 				if err != nil {
 					if reporter != nil {
 						reporter(err)
@@ -82,9 +147,209 @@ func (m *Migrator) Run(store *Store, logger io.Writer, reporter func(error)) err
 	return nil
 }
 
-func (m *Migrator) run(store *Store, logger io.Writer, migration *Migration) error {
+// Status will return the status of all added migrations.
+func (m *Migrator) Status(ctx context.Context, store *Store) ([]MigrationStatus, error) {
+	// load applied migrations
+	applied, err := m.applied(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare list
+	list := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{
+			Name: migration.Name,
+		}
+		if record, ok := applied[migration.Name]; ok {
+			status.Applied = true
+			status.AppliedAt = record.Applied
+		}
+		list = append(list, status)
+	}
+
+	return list, nil
+}
+
+// Apply will run all added migrations that have not yet been applied in order
+// and record them as applied. Asynchronous migrations are run synchronously.
+// A lease on the migrations collection ensures that only one process applies
+// migrations at a time, ErrMigrationLocked is returned otherwise. It will
+// return the number of applied migrations.
+func (m *Migrator) Apply(ctx context.Context, store *Store, logger io.Writer) (int, error) {
+	// acquire lock
+	lease, err := m.lock(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+
+	// ensure release
+	defer func() {
+		_ = ReleaseLease(ctx, store, lease)
+	}()
+
+	// load applied migrations
+	applied, err := m.applied(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+
+	// run pending migrations
+	var num int
+	for _, migration := range m.migrations {
+		// skip applied migrations
+		if _, ok := applied[migration.Name]; ok {
+			continue
+		}
+
+		// extend lock
+		err = RenewLease(ctx, store, lease, migration.Timeout+time.Minute)
+		if err != nil {
+			return num, err
+		}
+
+		// run migration
+		matched, modified, err := m.run(ctx, store, logger, &migration)
+		if err != nil {
+			return num, err
+		}
+
+		// record migration
+		err = store.M(&AppliedMigration{}).Insert(ctx, &AppliedMigration{
+			Base:     B(),
+			Name:     migration.Name,
+			Applied:  time.Now(),
+			Matched:  matched,
+			Modified: modified,
+		})
+		if err != nil {
+			return num, err
+		}
+
+		// increment
+		num++
+	}
+
+	return num, nil
+}
+
+// Rollback will roll back the specified number of most recently applied
+// migrations in reverse order and remove their records. It will fail if one of
+// the migrations does not provide a rollback function. Like Apply, it will
+// return ErrMigrationLocked if another process is currently migrating. It will
+// return the number of rolled back migrations.
+func (m *Migrator) Rollback(ctx context.Context, store *Store, logger io.Writer, steps int) (int, error) {
+	// acquire lock
+	lease, err := m.lock(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+
+	// ensure release
+	defer func() {
+		_ = ReleaseLease(ctx, store, lease)
+	}()
+
+	// load applied migrations
+	applied, err := m.applied(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+
+	// collect applied migrations
+	type candidate struct {
+		migration Migration
+		record    *AppliedMigration
+	}
+	var candidates []candidate
+	for _, migration := range m.migrations {
+		if record, ok := applied[migration.Name]; ok {
+			candidates = append(candidates, candidate{
+				migration: migration,
+				record:    record,
+			})
+		}
+	}
+
+	// sort by reverse application order
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].record.ID().Hex() > candidates[j].record.ID().Hex()
+	})
+
+	// roll back applied migrations
+	var num int
+	for _, candidate := range candidates {
+		// check steps
+		if num >= steps {
+			break
+		}
+
+		// get migration and record
+		migration := candidate.migration
+		record := candidate.record
+
+		// check rollback
+		if migration.Rollback == nil {
+			return num, xo.F("migration %q cannot be rolled back", migration.Name)
+		}
+
+		// extend lock
+		err = RenewLease(ctx, store, lease, migration.Timeout+time.Minute)
+		if err != nil {
+			return num, err
+		}
+
+		// roll back migration
+		err = m.rollback(ctx, store, logger, &migration)
+		if err != nil {
+			return num, err
+		}
+
+		// remove record
+		_, err = store.M(record).Delete(ctx, nil, record.ID())
+		if err != nil {
+			return num, err
+		}
+
+		// increment
+		num++
+	}
+
+	return num, nil
+}
+
+func (m *Migrator) lock(ctx context.Context, store *Store) (*Lease, error) {
+	// acquire lease on the migrations collection
+	lease, err := AcquireLease(ctx, store, &AppliedMigration{}, ID{}, time.Minute)
+	if err != nil {
+		return nil, err
+	} else if lease == nil {
+		return nil, ErrMigrationLocked.Wrap()
+	}
+
+	return lease, nil
+}
+
+func (m *Migrator) applied(ctx context.Context, store *Store) (map[string]*AppliedMigration, error) {
+	// find records
+	var list []*AppliedMigration
+	err := store.M(&AppliedMigration{}).FindAll(ctx, &list, nil, nil, 0, 0, false, NoTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	// build map
+	applied := make(map[string]*AppliedMigration, len(list))
+	for _, record := range list {
+		applied[record.Name] = record
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) run(ctx context.Context, store *Store, logger io.Writer, migration *Migration) (int64, int64, error) {
 	// create context
-	ctx, cancel := context.WithTimeout(context.Background(), migration.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, migration.Timeout)
 	defer cancel()
 
 	// trace
@@ -99,7 +364,7 @@ func (m *Migrator) run(store *Store, logger io.Writer, migration *Migration) err
 	// call migrator
 	matched, modified, err := migration.Migrator(ctx, store)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
 	// print result
@@ -107,6 +372,34 @@ func (m *Migrator) run(store *Store, logger io.Writer, migration *Migration) err
 		_, _ = fmt.Fprintf(logger, "completed migration: %d matched, %d modified\n", matched, modified)
 	}
 
+	return matched, modified, nil
+}
+
+func (m *Migrator) rollback(ctx context.Context, store *Store, logger io.Writer, migration *Migration) error {
+	// create context
+	ctx, cancel := context.WithTimeout(ctx, migration.Timeout)
+	defer cancel()
+
+	// trace
+	ctx, span := xo.Trace(ctx, "ROLLBACK "+migration.Name)
+	defer span.End()
+
+	// log
+	if logger != nil {
+		_, _ = fmt.Fprintf(logger, "rolling back migration: %s\n", migration.Name)
+	}
+
+	// call rollback
+	err := migration.Rollback(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	// log
+	if logger != nil {
+		_, _ = fmt.Fprintf(logger, "rolled back migration: %s\n", migration.Name)
+	}
+
 	return nil
 }
 
@@ -323,7 +616,7 @@ func UnsetFields(ctx context.Context, store *Store, model Model, rawFields ...st
 // documents that do not have the field already.
 func EnsureArrayField(ctx context.Context, store *Store, model Model, rawArrayField, rawField, value string) (int64, int64, error) {
 	// check support
-	if store.Lungo() {
+	if !store.Supports(Aggregations) {
 		panic("coal: not supported by lungo")
 	}
 
@@ -377,7 +670,7 @@ func EnsureArrayField(ctx context.Context, store *Store, model Model, rawArrayFi
 // document arrays that have an element with at least on of the fields.
 func RenameArrayFields(ctx context.Context, store *Store, model Model, rawArrayField string, rawOldAndNewFields map[string]string) (int64, int64, error) {
 	// check support
-	if store.Lungo() {
+	if !store.Supports(Aggregations) {
 		panic("coal: not supported by lungo")
 	}
 
@@ -450,7 +743,7 @@ func RenameArrayFields(ctx context.Context, store *Store, model Model, rawArrayF
 // exist.
 func UnsetArrayFields(ctx context.Context, store *Store, model Model, rawArrayField string, rawFields ...string) (int64, int64, error) {
 	// check support
-	if store.Lungo() {
+	if !store.Supports(Aggregations) {
 		panic("coal: not supported by lungo")
 	}
 
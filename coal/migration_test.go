@@ -84,6 +84,121 @@ func TestMigrator(t *testing.T) {
 	})
 }
 
+func TestMigratorApply(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var log []string
+		migration := func(name string, rollback bool) Migration {
+			m := Migration{
+				Name: name,
+				Migrator: func(ctx context.Context, store *Store) (int64, int64, error) {
+					log = append(log, "apply "+name)
+					return 2, 1, nil
+				},
+			}
+			if rollback {
+				m.Rollback = func(ctx context.Context, store *Store) error {
+					log = append(log, "rollback "+name)
+					return nil
+				}
+			}
+			return m
+		}
+
+		m := NewMigrator()
+		assert.NoError(t, m.Add(migration("foo", false)))
+		assert.NoError(t, m.Add(migration("bar", true)))
+
+		err := m.Add(migration("foo", false))
+		assert.Error(t, err)
+		assert.Equal(t, `duplicate migration "foo"`, err.Error())
+
+		err = m.Add(Migration{})
+		assert.Error(t, err)
+		assert.Equal(t, "missing migration name", err.Error())
+
+		ctx := context.Background()
+
+		status, err := m.Status(ctx, tester.Store)
+		assert.NoError(t, err)
+		assert.Equal(t, []MigrationStatus{
+			{Name: "foo"},
+			{Name: "bar"},
+		}, status)
+
+		num, err := m.Apply(ctx, tester.Store, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, num)
+		assert.Equal(t, []string{"apply foo", "apply bar"}, log)
+
+		record := tester.FindLast(&AppliedMigration{}).(*AppliedMigration)
+		assert.Equal(t, "bar", record.Name)
+		assert.Equal(t, int64(2), record.Matched)
+		assert.Equal(t, int64(1), record.Modified)
+
+		status, err = m.Status(ctx, tester.Store)
+		assert.NoError(t, err)
+		assert.True(t, status[0].Applied)
+		assert.True(t, status[1].Applied)
+		assert.False(t, status[1].AppliedAt.IsZero())
+
+		/* pending */
+
+		log = nil
+		assert.NoError(t, m.Add(migration("baz", true)))
+
+		num, err = m.Apply(ctx, tester.Store, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, num)
+		assert.Equal(t, []string{"apply baz"}, log)
+
+		/* locked */
+
+		lease, err := AcquireLease(ctx, tester.Store, &AppliedMigration{}, ID{}, time.Minute)
+		assert.NoError(t, err)
+		assert.NotNil(t, lease)
+
+		num, err = m.Apply(ctx, tester.Store, nil)
+		assert.True(t, ErrMigrationLocked.Is(err))
+		assert.Equal(t, 0, num)
+
+		err = ReleaseLease(ctx, tester.Store, lease)
+		assert.NoError(t, err)
+
+		/* rollback */
+
+		log = nil
+		num, err = m.Rollback(ctx, tester.Store, nil, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, num)
+		assert.Equal(t, []string{"rollback baz", "rollback bar"}, log)
+		assert.Equal(t, 1, tester.Count(&AppliedMigration{}))
+
+		num, err = m.Rollback(ctx, tester.Store, nil, 1)
+		assert.Error(t, err)
+		assert.Equal(t, `migration "foo" cannot be rolled back`, err.Error())
+		assert.Equal(t, 0, num)
+
+		log = nil
+		num, err = m.Apply(ctx, tester.Store, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, num)
+		assert.Equal(t, []string{"apply bar", "apply baz"}, log)
+
+		/* application order */
+
+		m = NewMigrator()
+		assert.NoError(t, m.Add(migration("foo", false)))
+		assert.NoError(t, m.Add(migration("baz", true)))
+		assert.NoError(t, m.Add(migration("bar", true)))
+
+		log = nil
+		num, err = m.Rollback(ctx, tester.Store, nil, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, num)
+		assert.Equal(t, []string{"rollback baz", "rollback bar"}, log)
+	})
+}
+
 func TestProcessEach(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		for i := 0; i < 20; i++ {
@@ -0,0 +1,121 @@
+package coal
+
+import (
+	"fmt"
+
+	"github.com/256dpi/lungo/bsonkit"
+	"github.com/256dpi/lungo/mongokit"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func init() {
+	// register $pullAll used to prune and remove references
+	if mongokit.FieldUpdateOperators["$pullAll"] == nil {
+		mongokit.FieldUpdateOperators["$pullAll"] = applyPullAll
+	}
+
+	// register $addToSet used to add references atomically, lungo does not
+	// support the $each modifier of $push as an alternative
+	if mongokit.FieldUpdateOperators["$addToSet"] == nil {
+		mongokit.FieldUpdateOperators["$addToSet"] = applyAddToSet
+	}
+}
+
+func applyPullAll(ctx mongokit.Context, doc bsonkit.Doc, name, path string, v interface{}) error {
+	// get values
+	values, ok := v.(bson.A)
+	if !ok {
+		return fmt.Errorf("%s: expected array", name)
+	}
+
+	// get array
+	var array bson.A
+	switch value := bsonkit.Get(doc, path).(type) {
+	case bson.A:
+		array = value
+	default:
+		if value == bsonkit.Missing {
+			return nil
+		}
+		return fmt.Errorf("%s: expected array", name)
+	}
+
+	// remove values
+	result := make(bson.A, 0, len(array))
+	for _, value := range array {
+		if indexOf(values, value) < 0 {
+			result = append(result, value)
+		}
+	}
+
+	// check changes
+	if len(result) == len(array) {
+		return nil
+	}
+
+	// set array
+	_, err := bsonkit.Put(doc, path, result, false)
+	if err != nil {
+		return err
+	}
+
+	// record change
+	return ctx.Value.(*mongokit.Changes).Record(path, result)
+}
+
+func applyAddToSet(ctx mongokit.Context, doc bsonkit.Doc, name, path string, v interface{}) error {
+	// get values
+	values := bson.A{v}
+	if d, ok := v.(bson.D); ok && len(d) == 1 && d[0].Key == "$each" {
+		list, ok := d[0].Value.(bson.A)
+		if !ok {
+			return fmt.Errorf("%s: expected array for $each", name)
+		}
+		values = list
+	}
+
+	// get array
+	var array bson.A
+	switch value := bsonkit.Get(doc, path).(type) {
+	case bson.A:
+		array = value
+	default:
+		if value != bsonkit.Missing {
+			return fmt.Errorf("%s: expected array", name)
+		}
+	}
+
+	// add missing values
+	length := len(array)
+	for _, value := range values {
+		if indexOf(array, value) < 0 {
+			array = append(array, value)
+		}
+	}
+
+	// check changes
+	if array != nil && len(array) == length {
+		return nil
+	} else if array == nil {
+		array = bson.A{}
+	}
+
+	// set array
+	_, err := bsonkit.Put(doc, path, array, false)
+	if err != nil {
+		return err
+	}
+
+	// record change
+	return ctx.Value.(*mongokit.Changes).Record(path, array)
+}
+
+func indexOf(list bson.A, value interface{}) int {
+	for i, item := range list {
+		if bsonkit.Compare(item, value) == 0 {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,61 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPullAllOperator(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		id1, id2, id3 := New(), New(), New()
+
+		selection := tester.Insert(&selectionModel{
+			Posts: []ID{id1, id2, id3},
+		}).(*selectionModel)
+
+		_, err := tester.Store.M(selection).Update(nil, nil, selection.ID(), bson.M{
+			"$pullAll": bson.M{
+				"post_ids": []ID{id1, id3, New()},
+			},
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []ID{id2}, tester.Fetch(&selectionModel{}, selection.ID()).(*selectionModel).Posts)
+
+		_, err = tester.Store.M(selection).Update(nil, nil, selection.ID(), bson.M{
+			"$pullAll": bson.M{
+				"name": []ID{id1},
+			},
+		}, false)
+		assert.Error(t, err)
+	})
+}
+
+func TestAddToSetOperator(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		id1, id2, id3 := New(), New(), New()
+
+		selection := tester.Insert(&selectionModel{
+			Posts: []ID{id1},
+		}).(*selectionModel)
+
+		_, err := tester.Store.M(selection).Update(nil, nil, selection.ID(), bson.M{
+			"$addToSet": bson.M{
+				"post_ids": bson.M{
+					"$each": []ID{id1, id2, id3},
+				},
+			},
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []ID{id1, id2, id3}, tester.Fetch(&selectionModel{}, selection.ID()).(*selectionModel).Posts)
+
+		_, err = tester.Store.M(selection).Update(nil, nil, selection.ID(), bson.M{
+			"$addToSet": bson.M{
+				"post_ids": id2,
+			},
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []ID{id1, id2, id3}, tester.Fetch(&selectionModel{}, selection.ID()).(*selectionModel).Posts)
+	})
+}
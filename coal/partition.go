@@ -0,0 +1,399 @@
+package coal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// Period defines the time span covered by a partition.
+type Period string
+
+// The available partition periods. The value is the layout used to format
+// the partition suffix.
+const (
+	Daily   Period = "2006-01-02"
+	Monthly Period = "2006-01"
+	Yearly  Period = "2006"
+)
+
+// Start returns the start of the period that includes the provided time.
+func (p Period) Start(t time.Time) time.Time {
+	// get date
+	t = t.UTC()
+	year, month, day := t.Date()
+
+	// truncate date
+	switch p {
+	case Daily:
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	case Monthly:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	case Yearly:
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		panic("coal: unknown period")
+	}
+}
+
+// Next returns the start of the period following the provided time.
+func (p Period) Next(t time.Time) time.Time {
+	// get start
+	start := p.Start(t)
+
+	// advance date
+	switch p {
+	case Daily:
+		return start.AddDate(0, 0, 1)
+	case Monthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(1, 0, 0)
+	}
+}
+
+// Partitioned is a facade for a model whose documents are stored in
+// time-partitioned collections e.g. monthly event collections. The partition
+// of a document is determined by the value of a time field. Each partition is
+// named after the model collection followed by a dash and the formatted
+// period e.g. "events-2024-05".
+type Partitioned struct {
+	store  *Store
+	model  Model
+	meta   *Meta
+	period Period
+	field  string
+	key    string
+}
+
+// NewPartitioned creates and returns a facade for the partitioned collections
+// of the specified model. The provided field must be a time.Time field and is
+// used to route writes and restrict reads.
+func NewPartitioned(store *Store, model Model, period Period, field string) *Partitioned {
+	// get meta
+	meta := GetMeta(model)
+
+	// check field
+	f := meta.Fields[field]
+	if f == nil || f.Type != reflect.TypeOf(time.Time{}) {
+		panic(`coal: partition field "` + field + `" is not a time field`)
+	}
+
+	// check period
+	period.Start(time.Now())
+
+	return &Partitioned{
+		store:  store,
+		model:  model,
+		meta:   meta,
+		period: period,
+		field:  field,
+		key:    f.BSONKey,
+	}
+}
+
+// Partition returns the name of the partition that includes the provided time.
+func (p *Partitioned) Partition(t time.Time) string {
+	return p.meta.Collection + "-" + p.period.Start(t).Format(string(p.period))
+}
+
+// Range returns the names of the partitions that cover the provided time
+// range. The start is inclusive and the end is exclusive.
+func (p *Partitioned) Range(from, to time.Time) []string {
+	// collect names
+	var names []string
+	for start := p.period.Start(from); start.Before(to); start = p.period.Next(start) {
+		names = append(names, p.Partition(start))
+	}
+
+	return names
+}
+
+// Partitions will return the names of all existing partitions in
+// chronological order.
+func (p *Partitioned) Partitions(ctx context.Context) ([]string, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Partitioned.Partitions")
+	defer span.End()
+
+	// list collections
+	names, err := p.store.DB().ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// filter partitions
+	var list []string
+	for _, name := range names {
+		if _, ok := p.parse(name); ok {
+			list = append(list, name)
+		}
+	}
+
+	// sort partitions
+	sort.Strings(list)
+
+	return list, nil
+}
+
+// Insert will insert the provided models into their partitions. Missing IDs
+// are generated and the models are validated before being inserted.
+func (p *Partitioned) Insert(ctx context.Context, models ...Model) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Partitioned.Insert")
+	defer span.End()
+
+	// group models by partition
+	var names []string
+	partitions := map[string][]interface{}{}
+	for _, model := range models {
+		// check model
+		if GetMeta(model) != p.meta {
+			return ErrMetaMismatch.Wrap()
+		}
+
+		// ensure ID
+		if model.ID().IsZero() {
+			model.GetBase().DocID = New()
+		}
+
+		// validate model
+		err := model.Validate()
+		if err != nil {
+			return xo.W(err)
+		}
+
+		// add model
+		name := p.Partition(stick.MustGet(model, p.field).(time.Time))
+		if partitions[name] == nil {
+			names = append(names, name)
+		}
+		partitions[name] = append(partitions[name], model)
+	}
+
+	// insert models
+	for _, name := range names {
+		_, err := p.store.collection(name).InsertMany(ctx, partitions[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindAll will find all documents within the provided time range that match
+// the specified filter and append them to the list. The partitions covering
+// the range are queried in chronological order and the documents are sorted
+// by the time field. The start is inclusive and the end is exclusive.
+// Conditions on the time field in the filter are replaced by the range.
+func (p *Partitioned) FindAll(ctx context.Context, list interface{}, from, to time.Time, filter bson.M) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Partitioned.FindAll")
+	defer span.End()
+
+	// get query
+	query, err := p.query(from, to, filter)
+	if err != nil {
+		return err
+	}
+
+	// get slice
+	slice := reflect.ValueOf(list).Elem()
+
+	// find documents
+	for _, name := range p.Range(from, to) {
+		// find documents
+		iter, err := p.store.collection(name).Find(ctx, query, options.Find().SetSort(bson.D{
+			{Key: p.key, Value: 1},
+		}))
+		if err != nil {
+			return err
+		}
+
+		// decode documents
+		for iter.Next() {
+			model := p.meta.Make()
+			err = iter.Decode(model)
+			if err != nil {
+				iter.Close()
+				return err
+			}
+			slice = reflect.Append(slice, reflect.ValueOf(model))
+		}
+
+		// check error
+		err = iter.Error()
+		iter.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	// set slice
+	reflect.ValueOf(list).Elem().Set(slice)
+
+	return nil
+}
+
+// Count will count the documents within the provided time range that match
+// the specified filter. The start is inclusive and the end is exclusive.
+func (p *Partitioned) Count(ctx context.Context, from, to time.Time, filter bson.M) (int64, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Partitioned.Count")
+	defer span.End()
+
+	// get query
+	query, err := p.query(from, to, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	// count documents
+	var total int64
+	for _, name := range p.Range(from, to) {
+		count, err := p.store.collection(name).CountDocuments(ctx, query)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+
+	return total, nil
+}
+
+// Ensure will create the partitions covering the provided time range and
+// ensure that the registered indexes of the model exist.
+func (p *Partitioned) Ensure(ctx context.Context, from, to time.Time) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Partitioned.Ensure")
+	defer span.End()
+
+	// ensure partitions
+	for _, name := range p.Range(from, to) {
+		// create collection
+		err := p.store.DB().CreateCollection(ctx, name)
+		var cmdErr mongo.CommandError
+		if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Code == 48) {
+			return xo.W(err)
+		}
+
+		// ensure indexes
+		for _, index := range p.meta.Indexes {
+			// skip unsupported special indexes
+			if !p.store.Supports(SpecialIndexes) && index.special() {
+				continue
+			}
+
+			// create index
+			_, err = p.store.collection(name).Native().Indexes().CreateOne(ctx, index.compile(p.store))
+			if err != nil {
+				return xo.W(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Prune will drop all partitions that end before the provided time and return
+// the names of the dropped partitions.
+func (p *Partitioned) Prune(ctx context.Context, before time.Time) ([]string, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Partitioned.Prune")
+	defer span.End()
+
+	// get partitions
+	names, err := p.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// drop partitions
+	var dropped []string
+	for _, name := range names {
+		// check end
+		start, _ := p.parse(name)
+		if p.period.Next(start).After(before) {
+			continue
+		}
+
+		// drop partition
+		err = p.store.DB().Collection(name).Drop(ctx)
+		if err != nil {
+			return dropped, xo.W(err)
+		}
+
+		dropped = append(dropped, name)
+	}
+
+	return dropped, nil
+}
+
+// Maintain will ensure the current and the next partition and drop all
+// partitions that are older than the specified number of retained periods
+// including the current period. It should be run periodically e.g. daily.
+func (p *Partitioned) Maintain(ctx context.Context, now time.Time, retention int) ([]string, error) {
+	// ensure partitions
+	current := p.period.Start(now)
+	err := p.Ensure(ctx, current, p.period.Next(p.period.Next(current)))
+	if err != nil {
+		return nil, err
+	}
+
+	// get retention start
+	start := current
+	for i := 1; i < retention; i++ {
+		start = p.period.Start(start.Add(-time.Nanosecond))
+	}
+
+	return p.Prune(ctx, start)
+}
+
+func (p *Partitioned) query(from, to time.Time, filter bson.M) (bson.D, error) {
+	// prepare query
+	query := bson.M{}
+	for key, value := range filter {
+		query[key] = value
+	}
+
+	// add range
+	query[p.field] = bson.M{
+		"$gte": from,
+		"$lt":  to,
+	}
+
+	// translate query
+	doc, err := NewTranslator(p.model).Document(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func (p *Partitioned) parse(name string) (time.Time, bool) {
+	// check prefix
+	prefix := p.meta.Collection + "-"
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+
+	// parse suffix
+	start, err := time.Parse(string(p.period), strings.TrimPrefix(name, prefix))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return start, true
+}
@@ -0,0 +1,94 @@
+package coal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPeriod(t *testing.T) {
+	now := time.Date(2024, 5, 17, 13, 45, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC), Daily.Start(now))
+	assert.Equal(t, time.Date(2024, 5, 18, 0, 0, 0, 0, time.UTC), Daily.Next(now))
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Monthly.Start(now))
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Monthly.Next(now))
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Yearly.Start(now))
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Yearly.Next(now))
+
+	assert.PanicsWithValue(t, "coal: unknown period", func() {
+		Period("foo").Start(now)
+	})
+}
+
+func TestPartitioned(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		ctx := context.Background()
+
+		p := NewPartitioned(tester.Store, &noteModel{}, Monthly, "CreatedAt")
+
+		assert.PanicsWithValue(t, `coal: partition field "Title" is not a time field`, func() {
+			NewPartitioned(tester.Store, &noteModel{}, Monthly, "Title")
+		})
+
+		for _, name := range []string{"notes-2024-02", "notes-2024-03", "notes-2024-04", "notes-2024-05", "notes-2024-06"} {
+			_ = tester.Store.DB().Collection(name).Drop(ctx)
+		}
+
+		at := func(month time.Month, day int) time.Time {
+			return time.Date(2024, month, day, 12, 0, 0, 0, time.UTC)
+		}
+
+		assert.Equal(t, "notes-2024-05", p.Partition(at(5, 17)))
+		assert.Equal(t, []string{"notes-2024-03", "notes-2024-04", "notes-2024-05"}, p.Range(at(3, 10), at(5, 1)))
+
+		err := p.Insert(ctx,
+			&noteModel{Title: "a", CreatedAt: at(3, 5)},
+			&noteModel{Title: "b", CreatedAt: at(4, 20)},
+			&noteModel{Title: "c", CreatedAt: at(4, 10)},
+			&noteModel{Title: "d", CreatedAt: at(5, 1)},
+		)
+		assert.NoError(t, err)
+
+		err = p.Insert(ctx, &postModel{})
+		assert.True(t, ErrMetaMismatch.Is(err))
+
+		names, err := p.Partitions(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"notes-2024-03", "notes-2024-04", "notes-2024-05"}, names)
+
+		var list []*noteModel
+		err = p.FindAll(ctx, &list, at(3, 10), at(5, 2), nil)
+		assert.NoError(t, err)
+		assert.Len(t, list, 3)
+		assert.Equal(t, "c", list[0].Title)
+		assert.Equal(t, "b", list[1].Title)
+		assert.Equal(t, "d", list[2].Title)
+
+		count, err := p.Count(ctx, at(1, 1), at(12, 1), bson.M{
+			"Title": bson.M{"$in": bson.A{"a", "d"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		dropped, err := p.Maintain(ctx, at(5, 17), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"notes-2024-03"}, dropped)
+
+		names, err = p.Partitions(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"notes-2024-04", "notes-2024-05", "notes-2024-06"}, names)
+
+		count, err = p.Count(ctx, at(1, 1), at(12, 1), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		for _, name := range names {
+			err = tester.Store.DB().Collection(name).Drop(ctx)
+			assert.NoError(t, err)
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package coal
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Pipeline is a builder for aggregation pipelines that translates struct field
+// names to database field names. Field references in expressions are written
+// as "$Field" and may be prefixed with a "#" (e.g. "$#field") to specify an
+// unknown or computed field. Variables (e.g. "$$ROOT") are not translated.
+// Errors are collected and returned by Build.
+type Pipeline struct {
+	meta   *Meta
+	trans  *Translator
+	stages []bson.D
+	err    error
+}
+
+// NewPipeline creates and returns a new pipeline for the specified model.
+func NewPipeline(model Model) *Pipeline {
+	return &Pipeline{
+		meta:  GetMeta(model),
+		trans: NewTranslator(model),
+	}
+}
+
+// Match adds a "$match" stage with the provided filter.
+func (p *Pipeline) Match(filter bson.M) *Pipeline {
+	// translate filter
+	doc, err := p.trans.Document(filter)
+	if err != nil {
+		return p.fail(err)
+	}
+
+	return p.add("$match", doc)
+}
+
+// Sort adds a "$sort" stage with the provided sort fields.
+func (p *Pipeline) Sort(fields ...string) *Pipeline {
+	// translate sort
+	doc, err := p.trans.Sort(fields)
+	if err != nil {
+		return p.fail(err)
+	}
+
+	return p.add("$sort", doc)
+}
+
+// Skip adds a "$skip" stage.
+func (p *Pipeline) Skip(n int64) *Pipeline {
+	return p.add("$skip", n)
+}
+
+// Limit adds a "$limit" stage.
+func (p *Pipeline) Limit(n int64) *Pipeline {
+	return p.add("$limit", n)
+}
+
+// Project adds a "$project" stage that includes the provided fields.
+func (p *Pipeline) Project(fields ...string) *Pipeline {
+	// prepare document
+	doc := make(bson.D, 0, len(fields))
+	for _, field := range fields {
+		key, err := p.trans.Field(field)
+		if err != nil {
+			return p.fail(err)
+		}
+		doc = append(doc, bson.E{Key: key, Value: 1})
+	}
+
+	return p.add("$project", doc)
+}
+
+// Group adds a "$group" stage with the provided ID expression and accumulator
+// expressions keyed by their output field names.
+func (p *Pipeline) Group(id interface{}, accumulators bson.M) *Pipeline {
+	// translate ID
+	id, err := p.expression(id)
+	if err != nil {
+		return p.fail(err)
+	}
+
+	// prepare document
+	doc := bson.D{{Key: "_id", Value: id}}
+	for _, key := range sortedKeys(accumulators) {
+		value, err := p.expression(accumulators[key])
+		if err != nil {
+			return p.fail(err)
+		}
+		doc = append(doc, bson.E{Key: key, Value: value})
+	}
+
+	return p.add("$group", doc)
+}
+
+// Unwind adds a "$unwind" stage for the provided array field.
+func (p *Pipeline) Unwind(field string) *Pipeline {
+	// translate field
+	key, err := p.trans.Field(field)
+	if err != nil {
+		return p.fail(err)
+	}
+
+	return p.add("$unwind", "$"+key)
+}
+
+// Count adds a "$count" stage that outputs the count using the provided name.
+func (p *Pipeline) Count(name string) *Pipeline {
+	return p.add("$count", name)
+}
+
+// Stage adds a raw stage that is not translated.
+func (p *Pipeline) Stage(stage bson.D) *Pipeline {
+	p.stages = append(p.stages, stage)
+	return p
+}
+
+// Build will return the built pipeline or the first error encountered.
+func (p *Pipeline) Build() ([]bson.D, error) {
+	// check error
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return p.stages, nil
+}
+
+func (p *Pipeline) add(name string, value interface{}) *Pipeline {
+	p.stages = append(p.stages, bson.D{{Key: name, Value: value}})
+	return p
+}
+
+func (p *Pipeline) fail(err error) *Pipeline {
+	// keep first error
+	if p.err == nil {
+		p.err = err
+	}
+
+	return p
+}
+
+func (p *Pipeline) expression(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		// translate field references
+		if strings.HasPrefix(value, "$") && !strings.HasPrefix(value, "$$") {
+			key, err := p.trans.Field(value[1:])
+			if err != nil {
+				return nil, err
+			}
+			return "$" + key, nil
+		}
+		return value, nil
+	case bson.M:
+		doc := make(bson.D, 0, len(value))
+		for _, key := range sortedKeys(value) {
+			item, err := p.expression(value[key])
+			if err != nil {
+				return nil, err
+			}
+			doc = append(doc, bson.E{Key: key, Value: item})
+		}
+		return doc, nil
+	case bson.D:
+		doc := make(bson.D, 0, len(value))
+		for _, elem := range value {
+			item, err := p.expression(elem.Value)
+			if err != nil {
+				return nil, err
+			}
+			doc = append(doc, bson.E{Key: elem.Key, Value: item})
+		}
+		return doc, nil
+	case bson.A:
+		list := make(bson.A, 0, len(value))
+		for _, elem := range value {
+			item, err := p.expression(elem)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	default:
+		return value, nil
+	}
+}
+
+func sortedKeys(m bson.M) []string {
+	// collect keys
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	// sort keys
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Aggregate will run the provided pipeline and decode all results into the
+// provided list. The list may be a slice of models or custom structs.
+func (m *Manager) Aggregate(ctx context.Context, list interface{}, pipeline *Pipeline) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Manager.Aggregate")
+	defer span.End()
+
+	// check list
+	if list == nil {
+		return xo.F("missing list")
+	}
+	lt := reflect.TypeOf(list)
+	if lt.Kind() != reflect.Ptr || lt.Elem().Kind() != reflect.Slice {
+		return xo.F("expected slice pointer")
+	}
+
+	// check pipeline
+	if pipeline.meta != m.meta {
+		return ErrMetaMismatch.Wrap()
+	}
+
+	// build pipeline
+	stages, err := pipeline.Build()
+	if err != nil {
+		return err
+	}
+
+	// tag stages
+	span.Tag("stages", len(stages))
+
+	// aggregate documents
+	iter, err := m.coll.Aggregate(ctx, stages)
+	if err != nil {
+		return err
+	}
+
+	// decode all results
+	err = iter.All(list)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
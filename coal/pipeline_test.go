@@ -0,0 +1,98 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPipeline(t *testing.T) {
+	stages, err := NewPipeline(&postModel{}).
+		Match(bson.M{"Published": true}).
+		Sort("-TextBody").
+		Skip(1).
+		Limit(2).
+		Project("Title", "TextBody").
+		Unwind("#tags").
+		Group("$Title", bson.M{
+			"count": bson.M{"$sum": 1},
+			"body":  bson.M{"$first": "$TextBody"},
+			"root":  bson.M{"$first": "$$ROOT"},
+		}).
+		Count("total").
+		Stage(bson.D{{Key: "$foo", Value: "$Bar"}}).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, []bson.D{
+		{{Key: "$match", Value: bson.D{{Key: "published", Value: true}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "text_body", Value: int32(-1)}}}},
+		{{Key: "$skip", Value: int64(1)}},
+		{{Key: "$limit", Value: int64(2)}},
+		{{Key: "$project", Value: bson.D{{Key: "title", Value: 1}, {Key: "text_body", Value: 1}}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$title"},
+			{Key: "body", Value: bson.D{{Key: "$first", Value: "$text_body"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "root", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$count", Value: "total"}},
+		{{Key: "$foo", Value: "$Bar"}},
+	}, stages)
+
+	stages, err = NewPipeline(&postModel{}).
+		Match(bson.M{"Foo": true}).
+		Sort("Bar").
+		Build()
+	assert.Error(t, err)
+	assert.Equal(t, `unknown field "Foo"`, err.Error())
+	assert.Nil(t, stages)
+
+	_, err = NewPipeline(&postModel{}).
+		Group(bson.M{"title": "$Foo"}, nil).
+		Build()
+	assert.Error(t, err)
+	assert.Equal(t, `unknown field "Foo"`, err.Error())
+}
+
+func TestManagerAggregate(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		err := tester.Store.M(&postModel{}).Aggregate(nil, &[]postModel{}, NewPipeline(&noteModel{}))
+		assert.True(t, ErrMetaMismatch.Is(err))
+
+		err = tester.Store.M(&postModel{}).Aggregate(nil, nil, NewPipeline(&postModel{}))
+		assert.Error(t, err)
+
+		// lungo does not support aggregations
+		if tester.Store.Lungo() {
+			return
+		}
+
+		tester.Insert(&postModel{Title: "foo", Published: true})
+		tester.Insert(&postModel{Title: "foo", Published: true})
+		tester.Insert(&postModel{Title: "bar", Published: true})
+		tester.Insert(&postModel{Title: "baz"})
+
+		var posts []postModel
+		err = tester.Store.M(&postModel{}).Aggregate(nil, &posts, NewPipeline(&postModel{}).
+			Match(bson.M{"Published": true}).
+			Sort("Title"))
+		assert.NoError(t, err)
+		assert.Len(t, posts, 3)
+		assert.Equal(t, "bar", posts[0].Title)
+
+		var groups []struct {
+			Title string `bson:"_id"`
+			Count int    `bson:"count"`
+		}
+		err = tester.Store.M(&postModel{}).Aggregate(nil, &groups, NewPipeline(&postModel{}).
+			Match(bson.M{"Published": true}).
+			Group("$Title", bson.M{"count": bson.M{"$sum": 1}}).
+			Stage(bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}}))
+		assert.NoError(t, err)
+		assert.Len(t, groups, 2)
+		assert.Equal(t, "foo", groups[1].Title)
+		assert.Equal(t, 2, groups[1].Count)
+	})
+}
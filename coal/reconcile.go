@@ -1,6 +1,8 @@
 package coal
 
 import (
+	"context"
+
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -9,32 +11,40 @@ import (
 // After that it will yield all changes to the collection until the returned
 // stream has been closed.
 func Reconcile(store *Store, model Model, loaded func(), created, updated func(Model), deleted func(ID), errored func(error)) *Stream {
-	// prepare load
-	load := func() error {
-		// get cursor
-		iter, err := store.C(model).Find(nil, bson.M{})
-		if err != nil {
-			return err
-		}
+	return reconcile(store, model, nil, nil, loaded, created, updated, deleted, errored)
+}
 
-		// iterate over all models
-		defer iter.Close()
-		for iter.Next() {
-			// decode model
-			model := GetMeta(model).Make()
-			err := iter.Decode(model)
-			if err != nil {
-				return err
-			}
+// ResumableReconcile works like Reconcile but persists the resume token of the
+// stream under the specified name using the provided checkpointer. If a token
+// has been persisted, the stream is resumed from it and the existing models are
+// not loaded again. This allows resuming after process restarts without missing
+// events and without a full re-sync. Failed checkpoints are reported and the
+// event is delivered again once the stream has been resumed.
+func ResumableReconcile(store *Store, model Model, name string, checkpointer Checkpointer, loaded func(), created, updated func(Model), deleted func(ID), errored func(error)) (*Stream, error) {
+	// load token
+	token, err := checkpointer.Load(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare save
+	save := func(token []byte) error {
+		return checkpointer.Save(context.Background(), name, token)
+	}
+
+	return reconcile(store, model, token, save, loaded, created, updated, deleted, errored), nil
+}
 
+func reconcile(store *Store, model Model, token []byte, save func([]byte) error, loaded func(), created, updated func(Model), deleted func(ID), errored func(error)) *Stream {
+	// prepare load
+	load := func() error {
+		// load models
+		err := loadModels(store, model, func(model Model) {
 			// call callback if available
 			if created != nil {
 				created(model)
 			}
-		}
-
-		// check error
-		err = iter.Error()
+		})
 		if err != nil {
 			return err
 		}
@@ -47,27 +57,120 @@ func Reconcile(store *Store, model Model, loaded func(), created, updated func(M
 		return nil
 	}
 
+	// prepare checkpoint
+	checkpoint := func(token []byte) error {
+		if save != nil {
+			return save(token)
+		}
+		return nil
+	}
+
 	// open stream
-	stream := OpenStream(store, model, nil, func(event Event, id ID, model Model, err error, bytes []byte) error {
+	stream := OpenStream(store, model, token, func(event Event, id ID, model Model, err error, bytes []byte) error {
 		// handle events
 		switch event {
 		case Opened:
+			// skip load if resumed
+			if token != nil {
+				if loaded != nil {
+					loaded()
+				}
+				return nil
+			}
+
 			return load()
 		case Created:
 			// call callback if available
 			if created != nil {
 				created(model)
 			}
+
+			return checkpoint(bytes)
 		case Updated:
 			// call callback if available
 			if updated != nil {
 				updated(model)
 			}
+
+			return checkpoint(bytes)
 		case Deleted:
 			// call callback if available
 			if deleted != nil {
 				deleted(id)
 			}
+
+			return checkpoint(bytes)
+		case Errored:
+			// call callback if available
+			if errored != nil {
+				errored(err)
+			}
+		}
+
+		return nil
+	})
+
+	return stream
+}
+
+// BatchedReconcile works like Reconcile but uses a batched stream to deliver
+// changes in batches. Existing models are delivered as batches of created
+// changes once the stream has been opened.
+func BatchedReconcile(store *Store, model Model, batching Batching, loaded func(), changed func([]Change), errored func(error)) *Stream {
+	// prepare load
+	load := func() error {
+		// get size
+		size := batching.MaxSize
+		if size <= 0 {
+			size = 100
+		}
+
+		// load models
+		var batch []Change
+		err := loadModels(store, model, func(model Model) {
+			// add change
+			batch = append(batch, Change{
+				Event: Created,
+				ID:    model.ID(),
+				Model: model,
+			})
+
+			// call callback if full
+			if len(batch) >= size {
+				if changed != nil {
+					changed(batch)
+				}
+				batch = nil
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		// call callback with remaining changes
+		if len(batch) > 0 && changed != nil {
+			changed(batch)
+		}
+
+		// call callback if available
+		if loaded != nil {
+			loaded()
+		}
+
+		return nil
+	}
+
+	// open stream
+	stream := OpenBatchedStream(store, model, nil, batching, func(event Event, changes []Change, err error, token []byte) error {
+		// handle events
+		switch event {
+		case Opened:
+			return load()
+		case Changed:
+			// call callback if available
+			if changed != nil {
+				changed(changes)
+			}
 		case Errored:
 			// call callback if available
 			if errored != nil {
@@ -80,3 +183,33 @@ func Reconcile(store *Store, model Model, loaded func(), created, updated func(M
 
 	return stream
 }
+
+func loadModels(store *Store, model Model, fn func(Model)) error {
+	// get cursor
+	iter, err := store.C(model).Find(nil, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	// iterate over all models
+	defer iter.Close()
+	for iter.Next() {
+		// decode model
+		model := GetMeta(model).Make()
+		err := iter.Decode(model)
+		if err != nil {
+			return err
+		}
+
+		// yield model
+		fn(model)
+	}
+
+	// check error
+	err = iter.Error()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
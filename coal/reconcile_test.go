@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestReconcile(t *testing.T) {
@@ -48,3 +49,134 @@ func TestReconcile(t *testing.T) {
 		stream.Close()
 	})
 }
+
+func TestResumableReconcile(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		time.Sleep(10 * time.Millisecond)
+
+		checkpointer := &StoreCheckpointer{
+			Store:      tester.Store,
+			Collection: "reconcile-checkpoints",
+		}
+
+		_, err := tester.Store.DB().Collection("reconcile-checkpoints").DeleteMany(nil, bson.M{})
+		assert.NoError(t, err)
+
+		existing := tester.Insert(&postModel{
+			Title: "existing",
+		})
+
+		var created, updated []ID
+		open := make(chan struct{})
+		done := make(chan struct{})
+
+		stream, err := ResumableReconcile(tester.Store, &postModel{}, "posts", checkpointer, func() {
+			close(open)
+		}, func(model Model) {
+			created = append(created, model.ID())
+		}, func(model Model) {
+			updated = append(updated, model.ID())
+			close(done)
+		}, nil, func(err error) {
+			panic(err)
+		})
+		assert.NoError(t, err)
+
+		<-open
+
+		post := tester.Insert(&postModel{
+			Title: "foo",
+		}).(*postModel)
+
+		post.Title = "bar"
+		tester.Replace(post)
+
+		<-done
+
+		stream.Close()
+
+		assert.Equal(t, []ID{existing.ID(), post.ID()}, created)
+		assert.Equal(t, []ID{post.ID()}, updated)
+
+		token, err := checkpointer.Load(nil, "posts")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+
+		n, err := tester.Store.DB().Collection("reconcile-checkpoints").CountDocuments(nil, bson.M{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		tester.Delete(post)
+
+		created = nil
+		open = make(chan struct{})
+		deleted := make(chan ID, 1)
+
+		stream, err = ResumableReconcile(tester.Store, &postModel{}, "posts", checkpointer, func() {
+			close(open)
+		}, func(model Model) {
+			created = append(created, model.ID())
+		}, nil, func(id ID) {
+			deleted <- id
+		}, func(err error) {
+			panic(err)
+		})
+		assert.NoError(t, err)
+
+		<-open
+		assert.Equal(t, post.ID(), <-deleted)
+
+		stream.Close()
+
+		assert.Empty(t, created)
+	})
+}
+
+func TestBatchedReconcile(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		time.Sleep(10 * time.Millisecond)
+
+		post1 := tester.Insert(&postModel{
+			Title: "foo",
+		}).(*postModel)
+
+		open := make(chan struct{})
+		done := make(chan struct{})
+
+		var changes []Change
+		stream := BatchedReconcile(tester.Store, &postModel{}, Batching{}, func() {
+			close(open)
+		}, func(batch []Change) {
+			changes = append(changes, batch...)
+			for _, change := range batch {
+				if change.Event == Deleted {
+					close(done)
+				}
+			}
+		}, func(err error) {
+			panic(err)
+		})
+
+		<-open
+
+		assert.Len(t, changes, 1)
+		assert.Equal(t, Created, changes[0].Event)
+		assert.Equal(t, post1.ID(), changes[0].ID)
+
+		post2 := tester.Insert(&postModel{
+			Title: "bar",
+		}).(*postModel)
+
+		tester.Delete(post2)
+
+		<-done
+
+		assert.Len(t, changes, 3)
+		assert.Equal(t, Created, changes[1].Event)
+		assert.Equal(t, post2.ID(), changes[1].ID)
+		assert.Equal(t, Deleted, changes[2].Event)
+		assert.Equal(t, post2.ID(), changes[2].ID)
+
+		stream.Close()
+	})
+}
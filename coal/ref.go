@@ -0,0 +1,25 @@
+package coal
+
+// Ref is a polymorphic reference to a document of one of several models. It is
+// used as the type of polymorphic to-one relationship fields that are declared
+// using a tag of the form 'coal:"name:type|type"'.
+type Ref struct {
+	// The plural name of the referenced model.
+	Type string `json:"type" bson:"type"`
+
+	// The ID of the referenced document.
+	ID ID `json:"id" bson:"id"`
+}
+
+// R is a shorthand to construct a reference to the specified model.
+func R(model Model) Ref {
+	return Ref{
+		Type: GetMeta(model).PluralName,
+		ID:   model.ID(),
+	}
+}
+
+// IsZero returns whether the reference is zero.
+func (r Ref) IsZero() bool {
+	return r.Type == "" && r.ID.IsZero()
+}
@@ -0,0 +1,16 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRef(t *testing.T) {
+	assert.True(t, Ref{}.IsZero())
+
+	post := &postModel{Base: B()}
+	ref := R(post)
+	assert.Equal(t, Ref{Type: "posts", ID: post.ID()}, ref)
+	assert.False(t, ref.IsZero())
+}
@@ -0,0 +1,327 @@
+package coal
+
+import (
+	"context"
+
+	"github.com/256dpi/lungo/bsonkit"
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DualFieldFilter returns a filter that matches documents with the provided
+// value in the new raw field or, if the new field is missing, in the old raw
+// field. It may be used to query documents while a field rename is in
+// progress.
+func DualFieldFilter(rawOldField, rawNewField string, value interface{}) bson.M {
+	return bson.M{
+		"$or": bson.A{
+			bson.M{
+				rawNewField: value,
+			},
+			bson.M{
+				rawNewField: bson.M{
+					"$exists": false,
+				},
+				rawOldField: value,
+			},
+		},
+	}
+}
+
+// CopyField will copy the old raw field to the new raw field in all documents
+// that are missing the new field. The copy is idempotent and may be run
+// repeatedly while the application writes both fields.
+func CopyField(ctx context.Context, store *Store, model Model, rawOldField, rawNewField string) (int64, int64, error) {
+	// find documents
+	iter, err := store.C(model).Find(ctx, fieldCopyFilter(rawOldField, rawNewField), options.Find().SetProjection(bson.M{
+		rawOldField: 1,
+	}))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// ensure close
+	defer iter.Close()
+
+	// copy fields
+	var matched, modified int64
+	for iter.Next() {
+		// decode document
+		var doc bson.D
+		err = iter.Decode(&doc)
+		if err != nil {
+			return matched, modified, err
+		}
+
+		// set new field if still missing
+		res, err := store.C(model).UpdateOne(ctx, bson.M{
+			"_id": bsonkit.Get(&doc, "_id"),
+			rawNewField: bson.M{
+				"$exists": false,
+			},
+		}, bson.M{
+			"$set": bson.M{
+				rawNewField: bsonkit.Get(&doc, rawOldField),
+			},
+		})
+		if err != nil {
+			return matched, modified, err
+		}
+
+		// increment
+		matched++
+		modified += res.ModifiedCount
+	}
+
+	// check error
+	err = iter.Error()
+	if err != nil {
+		return matched, modified, err
+	}
+
+	return matched, modified, nil
+}
+
+// PendingFieldCopies will return the number of documents that have the old raw
+// field but are missing the new raw field.
+func PendingFieldCopies(ctx context.Context, store *Store, model Model, rawOldField, rawNewField string) (int64, error) {
+	return store.C(model).CountDocuments(ctx, fieldCopyFilter(rawOldField, rawNewField))
+}
+
+// RenameFieldMigrations returns two migrations that rename a raw field without
+// downtime. The copy migration copies the old field to the new field and can
+// be rolled back. Until the cleanup migration has run, the application should
+// write both fields and read the new field with a fallback to the old field,
+// e.g. using DualFieldFilter. The cleanup migration verifies that all
+// documents have been copied and removes the old field. It is usually added
+// with a later release once all processes use the new field.
+func RenameFieldMigrations(name string, model Model, rawOldField, rawNewField string) (Migration, Migration) {
+	// prepare copy migration
+	cp := Migration{
+		Name: name + "-copy",
+		Migrator: func(ctx context.Context, store *Store) (int64, int64, error) {
+			return CopyField(ctx, store, model, rawOldField, rawNewField)
+		},
+		Rollback: func(ctx context.Context, store *Store) error {
+			_, err := store.C(model).UpdateMany(ctx, bson.M{
+				rawOldField: bson.M{
+					"$exists": true,
+				},
+			}, bson.M{
+				"$unset": bson.M{
+					rawNewField: true,
+				},
+			})
+			return err
+		},
+	}
+
+	// prepare cleanup migration
+	cleanup := Migration{
+		Name: name + "-cleanup",
+		Migrator: func(ctx context.Context, store *Store) (int64, int64, error) {
+			// verify copy
+			pending, err := PendingFieldCopies(ctx, store, model, rawOldField, rawNewField)
+			if err != nil {
+				return 0, 0, err
+			} else if pending > 0 {
+				return 0, 0, xo.F("field copy incomplete: %d pending", pending)
+			}
+
+			return UnsetFields(ctx, store, model, rawOldField)
+		},
+		Rollback: func(ctx context.Context, store *Store) error {
+			_, _, err := CopyField(ctx, store, model, rawNewField, rawOldField)
+			return err
+		},
+	}
+
+	return cp, cleanup
+}
+
+// CopyCollection will copy all documents from the source to the target
+// collection. Existing documents in the target collection are replaced. The
+// copy is idempotent and may be run repeatedly to catch up with writes to the
+// source collection.
+func CopyCollection(ctx context.Context, store *Store, source, target string) (int64, int64, error) {
+	// get collections
+	src := store.collection(source)
+	dst := store.collection(target)
+
+	// find documents
+	iter, err := src.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// ensure close
+	defer iter.Close()
+
+	// copy documents
+	var matched, modified int64
+	for iter.Next() {
+		// decode document
+		var doc bson.D
+		err = iter.Decode(&doc)
+		if err != nil {
+			return matched, modified, err
+		}
+
+		// replace or insert document
+		res, err := dst.ReplaceOne(ctx, bson.M{
+			"_id": bsonkit.Get(&doc, "_id"),
+		}, doc, options.Replace().SetUpsert(true))
+		if err != nil {
+			return matched, modified, err
+		}
+
+		// increment
+		matched++
+		modified += res.ModifiedCount + res.UpsertedCount
+	}
+
+	// check error
+	err = iter.Error()
+	if err != nil {
+		return matched, modified, err
+	}
+
+	return matched, modified, nil
+}
+
+// PendingCollectionCopies will return the number of documents in the source
+// collection that do not exist in the target collection.
+func PendingCollectionCopies(ctx context.Context, store *Store, source, target string) (int64, error) {
+	// get collections
+	src := store.collection(source)
+	dst := store.collection(target)
+
+	// find IDs
+	iter, err := src.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{
+		"_id": 1,
+	}))
+	if err != nil {
+		return 0, err
+	}
+
+	// ensure close
+	defer iter.Close()
+
+	// prepare check
+	var pending int64
+	var batch bson.A
+	check := func() error {
+		count, err := dst.CountDocuments(ctx, bson.M{
+			"_id": bson.M{
+				"$in": batch,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		pending += int64(len(batch)) - count
+		batch = nil
+		return nil
+	}
+
+	// check IDs in batches
+	for iter.Next() {
+		// decode document
+		var doc bson.D
+		err = iter.Decode(&doc)
+		if err != nil {
+			return 0, err
+		}
+
+		// add ID
+		batch = append(batch, bsonkit.Get(&doc, "_id"))
+
+		// check batch
+		if len(batch) >= 100 {
+			err = check()
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// check error
+	err = iter.Error()
+	if err != nil {
+		return 0, err
+	}
+
+	// check last batch
+	if len(batch) > 0 {
+		err = check()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return pending, nil
+}
+
+// RenameCollectionMigrations returns two migrations that rename a collection
+// without downtime. The copy migration copies all documents to the target
+// collection. Until the cleanup migration has run, the application should
+// write to both collections. The cleanup migration verifies that all
+// documents have been copied and drops the source collection. Rolling back
+// the cleanup migration copies the documents back to the source collection.
+func RenameCollectionMigrations(name string, source, target string) (Migration, Migration) {
+	// prepare copy migration
+	cp := Migration{
+		Name: name + "-copy",
+		Migrator: func(ctx context.Context, store *Store) (int64, int64, error) {
+			return CopyCollection(ctx, store, source, target)
+		},
+	}
+
+	// prepare cleanup migration
+	cleanup := Migration{
+		Name: name + "-cleanup",
+		Migrator: func(ctx context.Context, store *Store) (int64, int64, error) {
+			// verify copy
+			pending, err := PendingCollectionCopies(ctx, store, source, target)
+			if err != nil {
+				return 0, 0, err
+			} else if pending > 0 {
+				return 0, 0, xo.F("collection copy incomplete: %d pending", pending)
+			}
+
+			// drop source
+			err = store.DB().Collection(source).Drop(ctx)
+			if err != nil {
+				return 0, 0, xo.W(err)
+			}
+
+			return 0, 0, nil
+		},
+		Rollback: func(ctx context.Context, store *Store) error {
+			_, _, err := CopyCollection(ctx, store, target, source)
+			return err
+		},
+	}
+
+	return cp, cleanup
+}
+
+func fieldCopyFilter(rawOldField, rawNewField string) bson.M {
+	return bson.M{
+		rawOldField: bson.M{
+			"$exists": true,
+		},
+		rawNewField: bson.M{
+			"$exists": false,
+		},
+	}
+}
+
+func (s *Store) collection(name string) *Collection {
+	return &Collection{
+		store:      s,
+		coll:       s.DB().Collection(name),
+		collations: s.Supports(Collations),
+	}
+}
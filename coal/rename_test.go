@@ -0,0 +1,147 @@
+package coal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRenameField(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		ctx := context.Background()
+
+		for _, name := range []string{"a", "b", "c"} {
+			tester.Insert(&fooModel{
+				Name: name,
+			})
+		}
+
+		_, err := tester.Store.C(&fooModel{}).InsertOne(ctx, bson.M{
+			"_id":   New(),
+			"title": "d",
+		})
+		assert.NoError(t, err)
+
+		pending, err := PendingFieldCopies(ctx, tester.Store, &fooModel{}, "name", "title")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), pending)
+
+		cp, cleanup := RenameFieldMigrations("rename-name", &fooModel{}, "name", "title")
+		assert.Equal(t, "rename-name-copy", cp.Name)
+		assert.Equal(t, "rename-name-cleanup", cleanup.Name)
+
+		matched, modified, err := cleanup.Migrator(ctx, tester.Store)
+		assert.Error(t, err)
+		assert.Equal(t, "field copy incomplete: 3 pending", err.Error())
+		assert.Zero(t, matched)
+		assert.Zero(t, modified)
+
+		m := NewMigrator()
+		m.Add(cp)
+
+		num, err := m.Apply(ctx, tester.Store, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, num)
+
+		for _, title := range []string{"a", "d"} {
+			count, err := tester.Store.C(&fooModel{}).CountDocuments(ctx, DualFieldFilter("name", "title", title))
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), count)
+		}
+
+		pending, err = PendingFieldCopies(ctx, tester.Store, &fooModel{}, "name", "title")
+		assert.NoError(t, err)
+		assert.Zero(t, pending)
+
+		m.Add(cleanup)
+
+		num, err = m.Apply(ctx, tester.Store, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, num)
+
+		count, err := tester.Store.C(&fooModel{}).CountDocuments(ctx, bson.M{
+			"name": bson.M{"$exists": true},
+		})
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+
+		count, err = tester.Store.C(&fooModel{}).CountDocuments(ctx, bson.M{
+			"title": bson.M{"$exists": true},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), count)
+
+		num, err = m.Rollback(ctx, tester.Store, nil, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, num)
+
+		count, err = tester.Store.C(&fooModel{}).CountDocuments(ctx, bson.M{
+			"name":  bson.M{"$exists": true},
+			"title": bson.M{"$exists": false},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), count)
+	})
+}
+
+func TestRenameCollection(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		ctx := context.Background()
+
+		_ = tester.Store.DB().Collection("foos-renamed").Drop(ctx)
+
+		for _, name := range []string{"a", "b", "c"} {
+			tester.Insert(&fooModel{
+				Name: name,
+			})
+		}
+
+		cp, cleanup := RenameCollectionMigrations("rename-foos", "foos", "foos-renamed")
+
+		pending, err := PendingCollectionCopies(ctx, tester.Store, "foos", "foos-renamed")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), pending)
+
+		matched, modified, err := cp.Migrator(ctx, tester.Store)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), matched)
+		assert.Equal(t, int64(3), modified)
+
+		tester.Insert(&fooModel{
+			Name: "d",
+		})
+
+		pending, err = PendingCollectionCopies(ctx, tester.Store, "foos", "foos-renamed")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), pending)
+
+		_, _, err = cleanup.Migrator(ctx, tester.Store)
+		assert.Error(t, err)
+		assert.Equal(t, "collection copy incomplete: 1 pending", err.Error())
+
+		matched, modified, err = cp.Migrator(ctx, tester.Store)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), matched)
+		assert.True(t, modified >= 1)
+
+		_, _, err = cleanup.Migrator(ctx, tester.Store)
+		assert.NoError(t, err)
+
+		count, err := tester.Store.DB().Collection("foos").CountDocuments(ctx, bson.M{})
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+
+		count, err = tester.Store.DB().Collection("foos-renamed").CountDocuments(ctx, bson.M{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), count)
+
+		err = cleanup.Rollback(ctx, tester.Store)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, tester.Count(&fooModel{}))
+
+		err = tester.Store.DB().Collection("foos-renamed").Drop(ctx)
+		assert.NoError(t, err)
+	})
+}
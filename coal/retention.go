@@ -0,0 +1,171 @@
+package coal
+
+import (
+	"context"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Retention describes the retention policy of a model. Documents older than
+// the configured age are either moved to an archive collection, handed to an
+// archiver e.g. to write them to cold storage, or deleted.
+type Retention struct {
+	// The model.
+	Model Model
+
+	// The time field used to determine the age of documents e.g. "Created".
+	Field string
+
+	// The age after which documents are removed.
+	Age time.Duration
+
+	// An additional filter to select documents.
+	Filter bson.M
+
+	// The name of the collection documents are moved to e.g. "posts_archive".
+	ArchiveCollection string
+
+	// The function called with each batch of documents before they are
+	// deleted. It is called within the transaction of the batch and should
+	// be idempotent as a batch is handed again if the removal is interrupted.
+	Archiver func(ctx context.Context, docs []bson.Raw) error
+
+	// The number of documents removed per batch.
+	//
+	// Default: 100.
+	BatchSize int64
+}
+
+// ApplyRetention will remove all documents that exceed the age of the provided
+// retention policy. Documents are moved to the archive collection and handed
+// to the archiver, if configured, before being deleted. Documents are upserted
+// in the archive collection to allow resuming an interrupted removal. Each
+// batch is processed in a transaction so that documents modified concurrently
+// are not deleted without their latest state being archived. It will return
+// the number of removed documents.
+func ApplyRetention(ctx context.Context, store *Store, retention Retention) (int64, error) {
+	// set default batch size
+	if retention.BatchSize <= 0 {
+		retention.BatchSize = 100
+	}
+
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/ApplyRetention")
+	span.Tag("collection", GetMeta(retention.Model).Collection)
+	defer span.End()
+
+	// prepare filter
+	filter := bson.M{}
+	for key, value := range retention.Filter {
+		filter[key] = value
+	}
+	filter[retention.Field] = bson.M{
+		"$lt": time.Now().Add(-retention.Age),
+	}
+
+	// translate filter
+	filterDoc, err := NewTranslator(retention.Model).Document(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	// get collection
+	coll := store.C(retention.Model)
+
+	// process batches
+	var removed int64
+	for {
+		// process batch in a transaction, documents that are modified
+		// concurrently will abort the transaction before being deleted
+		var found, deleted int64
+		err = store.T(ctx, false, func(ctx context.Context) error {
+			found, deleted, err = applyRetentionBatch(ctx, store, coll, retention, filterDoc)
+			return err
+		})
+		if err != nil {
+			return removed, err
+		}
+
+		// increment
+		removed += deleted
+
+		// check end
+		if found < retention.BatchSize {
+			break
+		}
+	}
+
+	return removed, nil
+}
+
+func applyRetentionBatch(ctx context.Context, store *Store, coll *Collection, retention Retention, filterDoc bson.D) (int64, int64, error) {
+	// find batch
+	iter, err := coll.Find(ctx, filterDoc, options.Find().
+		SetSort(bson.M{"_id": 1}).
+		SetLimit(retention.BatchSize))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// load documents
+	var docs []bson.Raw
+	err = iter.All(&docs)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// check batch
+	if len(docs) == 0 {
+		return 0, 0, nil
+	}
+
+	// collect IDs
+	ids := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.Lookup("_id"))
+	}
+
+	// move documents
+	if retention.ArchiveCollection != "" {
+		models := make([]mongo.WriteModel, 0, len(docs))
+		for i, doc := range docs {
+			models = append(models, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": ids[i]}).
+				SetReplacement(doc).
+				SetUpsert(true))
+		}
+		_, err = store.collection(retention.ArchiveCollection).BulkWrite(ctx, models)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// archive documents
+	if retention.Archiver != nil {
+		err = retention.Archiver(ctx, docs)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// delete documents that still match the filter
+	res, err := coll.DeleteMany(ctx, bson.D{
+		{Key: "$and", Value: bson.A{
+			filterDoc,
+			bson.M{
+				"_id": bson.M{
+					"$in": ids,
+				},
+			},
+		}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int64(len(docs)), res.DeletedCount, nil
+}
@@ -0,0 +1,77 @@
+package coal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestApplyRetention(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		ctx := context.Background()
+
+		archive := tester.Store.DB().Collection("notes_archive")
+		_ = archive.Drop(ctx)
+
+		old1 := tester.Insert(&noteModel{
+			Title:     "old",
+			CreatedAt: time.Now().Add(-48 * time.Hour),
+		}).(*noteModel)
+		old2 := tester.Insert(&noteModel{
+			Title:     "old",
+			CreatedAt: time.Now().Add(-36 * time.Hour),
+		}).(*noteModel)
+		tester.Insert(&noteModel{
+			Title:     "keep",
+			CreatedAt: time.Now().Add(-48 * time.Hour),
+		})
+		tester.Insert(&noteModel{
+			Title:     "new",
+			CreatedAt: time.Now(),
+		})
+
+		var archived []ID
+		removed, err := ApplyRetention(ctx, tester.Store, Retention{
+			Model: &noteModel{},
+			Field: "CreatedAt",
+			Age:   24 * time.Hour,
+			Filter: bson.M{
+				"Title": bson.M{
+					"$ne": "keep",
+				},
+			},
+			ArchiveCollection: "notes_archive",
+			Archiver: func(ctx context.Context, docs []bson.Raw) error {
+				for _, doc := range docs {
+					archived = append(archived, doc.Lookup("_id").ObjectID())
+				}
+				return nil
+			},
+			BatchSize: 1,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), removed)
+		assert.Equal(t, []ID{old1.ID(), old2.ID()}, archived)
+		assert.Equal(t, 2, tester.Count(&noteModel{}))
+
+		var docs []noteModel
+		csr, err := archive.Find(ctx, bson.M{}, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, csr.All(ctx, &docs))
+		assert.Len(t, docs, 2)
+		assert.Equal(t, old1.ID(), docs[0].ID())
+		assert.Equal(t, "old", docs[0].Title)
+
+		removed, err = ApplyRetention(ctx, tester.Store, Retention{
+			Model: &noteModel{},
+			Field: "CreatedAt",
+			Age:   24 * time.Hour,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), removed)
+		assert.Equal(t, 1, tester.Count(&noteModel{}))
+	})
+}
@@ -0,0 +1,251 @@
+package coal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// Enumerable may be implemented by field types to restrict the allowed values
+// in generated validation schemas.
+type Enumerable interface {
+	EnumValues() []interface{}
+}
+
+var idType = reflect.TypeOf(ID{})
+var dateType = reflect.TypeOf(stick.Date{})
+var enumerableType = reflect.TypeOf((*Enumerable)(nil)).Elem()
+var valueMarshalerType = reflect.TypeOf((*bson.ValueMarshaler)(nil)).Elem()
+var marshalerType = reflect.TypeOf((*bson.Marshaler)(nil)).Elem()
+
+// ValidationSchema will generate a MongoDB $jsonSchema validation schema for
+// the specified model. Non-pointer fields without the "omitempty" option are
+// required, pointer fields, slices and maps may be null. Additional fields are
+// permitted.
+func ValidationSchema(model Model) bson.M {
+	// get meta
+	meta := GetMeta(model)
+
+	// prepare properties
+	properties := bson.M{
+		"_id": bson.M{
+			"bsonType": "objectId",
+		},
+	}
+
+	// prepare required
+	required := bson.A{"_id"}
+
+	// add fields
+	for _, field := range meta.OrderedFields {
+		// skip ignored fields
+		if field.BSONKey == "" {
+			continue
+		}
+
+		// add property
+		properties[field.BSONKey] = schemaType(field.Type)
+
+		// add required
+		if !field.Optional && !hasOmitEmpty(meta.Type, field.Index) {
+			required = append(required, field.BSONKey)
+		}
+	}
+
+	return bson.M{
+		"bsonType":   "object",
+		"required":   required,
+		"properties": properties,
+	}
+}
+
+// EnsureValidators will ensure that the collections of the specified models
+// exist and enforce the generated validation schemas. Existing collections are
+// modified to use the new validators. The validators use the "moderate" level
+// so that existing documents that do not match the schema can still be
+// updated. Lungo stores are skipped as they do not support document validation.
+func EnsureValidators(store *Store, models ...Model) error {
+	// check support
+	if !store.Supports(Validators) {
+		return nil
+	}
+
+	// create context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// iterate models
+	for _, model := range models {
+		// get meta and validator
+		meta := GetMeta(model)
+		validator := bson.M{
+			"$jsonSchema": ValidationSchema(model),
+		}
+
+		// modify collection
+		err := store.DB().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: meta.Collection},
+			{Key: "validator", Value: validator},
+			{Key: "validationLevel", Value: "moderate"},
+			{Key: "validationAction", Value: "error"},
+		}).Err()
+
+		// create collection if missing
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceNotFound" {
+			err = store.DB().RunCommand(ctx, bson.D{
+				{Key: "create", Value: meta.Collection},
+				{Key: "validator", Value: validator},
+				{Key: "validationLevel", Value: "moderate"},
+				{Key: "validationAction", Value: "error"},
+			}).Err()
+		}
+		if err != nil {
+			return xo.WF(err, "unable to ensure validator for %s", meta.Name)
+		}
+	}
+
+	return nil
+}
+
+func schemaType(typ reflect.Type) bson.M {
+	// handle pointers
+	if typ.Kind() == reflect.Ptr {
+		schema := schemaType(typ.Elem())
+		return nullable(schema)
+	}
+
+	// prepare schema
+	var schema bson.M
+
+	// handle special types
+	switch {
+	case typ == timeType || typ == dateType:
+		schema = bson.M{"bsonType": "date"}
+	case typ == idType:
+		schema = bson.M{"bsonType": "objectId"}
+	case LookupType(typ) != nil && LookupType(typ).BSONType != "":
+		schema = bson.M{"bsonType": LookupType(typ).BSONType}
+	case typ.Implements(valueMarshalerType) || typ.Implements(marshalerType):
+		schema = bson.M{}
+	}
+
+	// handle kinds
+	if schema == nil {
+		switch typ.Kind() {
+		case reflect.String:
+			schema = bson.M{"bsonType": "string"}
+		case reflect.Bool:
+			schema = bson.M{"bsonType": "bool"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			schema = bson.M{"bsonType": bson.A{"int", "long"}}
+		case reflect.Float32, reflect.Float64:
+			schema = bson.M{"bsonType": "double"}
+		case reflect.Slice:
+			if typ.Elem().Kind() == reflect.Uint8 {
+				schema = nullable(bson.M{"bsonType": "binData"})
+			} else {
+				schema = nullable(bson.M{"bsonType": "array", "items": schemaType(typ.Elem())})
+			}
+		case reflect.Array:
+			schema = bson.M{"bsonType": "array", "items": schemaType(typ.Elem())}
+		case reflect.Map:
+			schema = nullable(bson.M{"bsonType": "object"})
+		case reflect.Struct:
+			schema = itemSchema(typ)
+		default:
+			schema = bson.M{}
+		}
+	}
+
+	// add enum
+	if typ.Implements(enumerableType) {
+		values := reflect.Zero(typ).Interface().(Enumerable).EnumValues()
+		schema["enum"] = bson.A(values)
+	}
+
+	return schema
+}
+
+func itemSchema(typ reflect.Type) bson.M {
+	// get item meta
+	meta := GetItemMeta(typ)
+	if meta == nil {
+		return bson.M{"bsonType": "object"}
+	}
+
+	// prepare properties and required
+	properties := bson.M{
+		"_id": bson.M{
+			"bsonType": "string",
+		},
+	}
+	required := bson.A{}
+
+	// add fields
+	for _, field := range meta.OrderedFields {
+		// skip ignored fields
+		if field.BSONKey == "" {
+			continue
+		}
+
+		// add property
+		properties[field.BSONKey] = schemaType(field.Type)
+
+		// add required
+		if !field.Optional && !hasOmitEmpty(meta.Type, field.Index) {
+			required = append(required, field.BSONKey)
+		}
+	}
+
+	// prepare schema
+	schema := bson.M{
+		"bsonType":   "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func nullable(schema bson.M) bson.M {
+	// add null type
+	switch typ := schema["bsonType"].(type) {
+	case string:
+		schema["bsonType"] = bson.A{typ, "null"}
+	case bson.A:
+		schema["bsonType"] = append(typ, "null")
+	}
+
+	// add null value
+	if enum, ok := schema["enum"].(bson.A); ok {
+		schema["enum"] = append(enum, nil)
+	}
+
+	return schema
+}
+
+func hasOmitEmpty(typ reflect.Type, index int) bool {
+	// get tag
+	tag := typ.Field(index).Tag.Get("bson")
+
+	// check options
+	for _, opt := range strings.Split(tag, ",")[1:] {
+		if opt == "omitempty" {
+			return true
+		}
+	}
+
+	return false
+}
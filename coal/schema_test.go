@@ -0,0 +1,133 @@
+package coal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/stick"
+)
+
+type schemaState string
+
+func (schemaState) EnumValues() []interface{} {
+	return []interface{}{"draft", "published"}
+}
+
+type schemaModel struct {
+	Base     `json:"-" bson:",inline" coal:"schemas"`
+	Name     string       `json:"name"`
+	Count    int          `json:"count"`
+	Rate     *float64     `json:"rate"`
+	Note     string       `json:"note" bson:",omitempty"`
+	State    schemaState  `json:"state"`
+	OptState *schemaState `json:"opt-state" bson:"opt_state"`
+	Date     stick.Date   `json:"date"`
+	Tags     []string     `json:"tags"`
+	Data     stick.Map    `json:"data"`
+	Item     listItem     `json:"item"`
+	Owner    ID           `json:"-" bson:"owner_id" coal:"owner:owners"`
+	Time     time.Time    `json:"time"`
+	Skipped  string       `json:"skipped" bson:"-"`
+}
+
+func (m *schemaModel) Validate() error {
+	return nil
+}
+
+func TestValidationSchema(t *testing.T) {
+	assert.Equal(t, bson.M{
+		"bsonType": "object",
+		"required": bson.A{"_id", "name", "count", "state", "date", "tags", "data", "item", "owner_id", "time"},
+		"properties": bson.M{
+			"_id": bson.M{
+				"bsonType": "objectId",
+			},
+			"name": bson.M{
+				"bsonType": "string",
+			},
+			"count": bson.M{
+				"bsonType": bson.A{"int", "long"},
+			},
+			"rate": bson.M{
+				"bsonType": bson.A{"double", "null"},
+			},
+			"note": bson.M{
+				"bsonType": "string",
+			},
+			"state": bson.M{
+				"bsonType": "string",
+				"enum":     bson.A{"draft", "published"},
+			},
+			"opt_state": bson.M{
+				"bsonType": bson.A{"string", "null"},
+				"enum":     bson.A{"draft", "published", nil},
+			},
+			"date": bson.M{
+				"bsonType": "date",
+			},
+			"tags": bson.M{
+				"bsonType": bson.A{"array", "null"},
+				"items": bson.M{
+					"bsonType": "string",
+				},
+			},
+			"data": bson.M{
+				"bsonType": bson.A{"object", "null"},
+			},
+			"item": bson.M{
+				"bsonType": "object",
+				"required": bson.A{"title", "done"},
+				"properties": bson.M{
+					"_id": bson.M{
+						"bsonType": "string",
+					},
+					"title": bson.M{
+						"bsonType": "string",
+					},
+					"done": bson.M{
+						"bsonType": "bool",
+					},
+				},
+			},
+			"owner_id": bson.M{
+				"bsonType": "objectId",
+			},
+			"time": bson.M{
+				"bsonType": "date",
+			},
+		},
+	}, ValidationSchema(&schemaModel{}))
+}
+
+func TestEnsureValidators(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		if tester.Store.Lungo() {
+			err := EnsureValidators(tester.Store, &schemaModel{})
+			assert.NoError(t, err)
+			return
+		}
+
+		_ = tester.Store.C(&schemaModel{}).Native().Drop(nil)
+
+		err := EnsureValidators(tester.Store, &schemaModel{})
+		assert.NoError(t, err)
+
+		err = EnsureValidators(tester.Store, &schemaModel{})
+		assert.NoError(t, err)
+
+		_, err = tester.Store.C(&schemaModel{}).InsertOne(nil, bson.M{
+			"_id":  New(),
+			"name": 42,
+		})
+		assert.Error(t, err)
+
+		_, err = tester.Store.C(&schemaModel{}).InsertOne(nil, &schemaModel{
+			Base:  B(),
+			State: "draft",
+		})
+		assert.NoError(t, err)
+	})
+}
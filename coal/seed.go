@@ -0,0 +1,290 @@
+package coal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Seed is a named set of documents applied to a store.
+type Seed struct {
+	// The name.
+	Name string
+
+	// The environments the seed is applied in e.g. "development" or "test".
+	// The seed is applied in all environments if empty.
+	Environments []string
+
+	// The timeout.
+	//
+	// Default: 5m.
+	Timeout time.Duration
+
+	// The documents inserted if missing. All documents must have an ID.
+	Documents []Model
+
+	// The optional function called after the documents have been inserted.
+	// It should itself be idempotent as an interrupted seed is applied again.
+	Seeder func(ctx context.Context, store *Store) error
+}
+
+// ParseSeed will parse the provided relaxed extended JSON array of documents
+// for the specified model and return a seed with the specified name. All
+// documents must specify an "_id".
+func ParseSeed(name string, model Model, data []byte) (Seed, error) {
+	// get meta
+	meta := GetMeta(model)
+
+	// decode documents
+	var list struct {
+		Docs []bson.Raw `bson:"docs"`
+	}
+	err := bson.UnmarshalExtJSON(append(append([]byte(`{"docs":`), data...), '}'), false, &list)
+	if err != nil {
+		return Seed{}, xo.W(err)
+	}
+
+	// decode models
+	docs := make([]Model, 0, len(list.Docs))
+	for i, raw := range list.Docs {
+		// decode model
+		doc := meta.Make()
+		err = bson.Unmarshal(raw, doc)
+		if err != nil {
+			return Seed{}, xo.W(err)
+		}
+
+		// check ID
+		if doc.ID().IsZero() {
+			return Seed{}, xo.F("seed document %d is missing an id", i)
+		}
+
+		// add model
+		docs = append(docs, doc)
+	}
+
+	return Seed{
+		Name:      name,
+		Documents: docs,
+	}, nil
+}
+
+func init() {
+	// add indexes
+	AddIndex(&AppliedSeed{}, true, 0, "Name")
+}
+
+// ErrSeedLocked is returned by Seeder.Apply if another process is currently
+// seeding the store.
+var ErrSeedLocked = xo.BF("seed locked")
+
+// AppliedSeed is the record of a seed that has been applied using
+// Seeder.Apply.
+type AppliedSeed struct {
+	Base        `json:"-" bson:",inline" coal:"seeds"`
+	Name        string    `json:"name"`
+	Environment string    `json:"environment"`
+	Applied     time.Time `json:"applied-at" bson:"applied_at"`
+	Inserted    int64     `json:"inserted"`
+}
+
+// Validate implements the Model interface.
+func (s *AppliedSeed) Validate() error {
+	// check name
+	if s.Name == "" {
+		return xo.SF("missing name")
+	}
+
+	// check applied
+	if s.Applied.IsZero() {
+		return xo.SF("missing applied")
+	}
+
+	return nil
+}
+
+// Seeder manages multiple seeds for an environment.
+type Seeder struct {
+	env   string
+	seeds []Seed
+}
+
+// NewSeeder creates and returns a new seeder for the specified environment.
+func NewSeeder(env string) *Seeder {
+	return &Seeder{
+		env: env,
+	}
+}
+
+// Add will add the provided seed. Seeds are applied in the order they have
+// been added. It will panic if the name is missing or has already been used.
+func (s *Seeder) Add(seed Seed) {
+	// check name
+	if seed.Name == "" {
+		panic("coal: missing seed name")
+	}
+	for _, other := range s.seeds {
+		if other.Name == seed.Name {
+			panic(`coal: duplicate seed "` + seed.Name + `"`)
+		}
+	}
+
+	// ensure timeout
+	if seed.Timeout == 0 {
+		seed.Timeout = 5 * time.Minute
+	}
+
+	// add seed
+	s.seeds = append(s.seeds, seed)
+}
+
+// Apply will apply all added seeds of the environment that have not yet been
+// applied in order and record them as applied. Documents are only inserted if
+// missing, which makes it safe to apply an interrupted seed again. A lease on
+// the seeds collection ensures that only one process applies seeds at a time,
+// ErrSeedLocked is returned otherwise. It will return the number of applied
+// seeds.
+func (s *Seeder) Apply(ctx context.Context, store *Store, logger io.Writer) (int, error) {
+	// acquire lease on the seeds collection
+	lease, err := AcquireLease(ctx, store, &AppliedSeed{}, ID{}, time.Minute)
+	if err != nil {
+		return 0, err
+	} else if lease == nil {
+		return 0, ErrSeedLocked.Wrap()
+	}
+
+	// ensure release
+	defer func() {
+		_ = ReleaseLease(ctx, store, lease)
+	}()
+
+	// find applied seeds
+	var list []*AppliedSeed
+	err = store.M(&AppliedSeed{}).FindAll(ctx, &list, nil, nil, 0, 0, false, NoTransaction)
+	if err != nil {
+		return 0, err
+	}
+
+	// build map
+	applied := make(map[string]bool, len(list))
+	for _, record := range list {
+		applied[record.Name] = true
+	}
+
+	// apply pending seeds
+	var num int
+	for _, seed := range s.seeds {
+		// skip applied and other seeds
+		if applied[seed.Name] || !seed.appliesTo(s.env) {
+			continue
+		}
+
+		// extend lock
+		err = RenewLease(ctx, store, lease, seed.Timeout+time.Minute)
+		if err != nil {
+			return num, err
+		}
+
+		// apply seed
+		inserted, err := s.apply(ctx, store, logger, &seed)
+		if err != nil {
+			return num, err
+		}
+
+		// record seed
+		err = store.M(&AppliedSeed{}).Insert(ctx, &AppliedSeed{
+			Base:        B(),
+			Name:        seed.Name,
+			Environment: s.env,
+			Applied:     time.Now(),
+			Inserted:    inserted,
+		})
+		if err != nil {
+			return num, err
+		}
+
+		// increment
+		num++
+	}
+
+	return num, nil
+}
+
+func (s *Seeder) apply(ctx context.Context, store *Store, logger io.Writer, seed *Seed) (int64, error) {
+	// create context
+	ctx, cancel := context.WithTimeout(ctx, seed.Timeout)
+	defer cancel()
+
+	// trace
+	ctx, span := xo.Trace(ctx, "SEED "+seed.Name)
+	defer span.End()
+
+	// log
+	if logger != nil {
+		_, _ = fmt.Fprintf(logger, "applying seed: %s\n", seed.Name)
+	}
+
+	// apply seed
+	inserted, err := ApplySeed(ctx, store, *seed)
+	if err != nil {
+		return 0, err
+	}
+
+	// log
+	if logger != nil {
+		_, _ = fmt.Fprintf(logger, "applied seed: %d inserted\n", inserted)
+	}
+
+	return inserted, nil
+}
+
+func (s *Seed) appliesTo(env string) bool {
+	// check environments
+	if len(s.Environments) == 0 {
+		return true
+	}
+	for _, item := range s.Environments {
+		if item == env {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ApplySeed will insert the missing documents of the seed and call its seeder
+// without recording the seed. It will return the number of inserted documents.
+func ApplySeed(ctx context.Context, store *Store, seed Seed) (int64, error) {
+	// insert documents
+	var inserted int64
+	for _, doc := range seed.Documents {
+		// check ID
+		if doc.ID().IsZero() {
+			return inserted, xo.F("seed document is missing an id")
+		}
+
+		// insert document if missing
+		ok, err := store.M(doc).InsertIfMissing(ctx, bson.M{
+			"_id": doc.ID(),
+		}, doc, false)
+		if err != nil {
+			return inserted, err
+		} else if ok {
+			inserted++
+		}
+	}
+
+	// call seeder
+	if seed.Seeder != nil {
+		err := seed.Seeder(ctx, store)
+		if err != nil {
+			return inserted, err
+		}
+	}
+
+	return inserted, nil
+}
@@ -0,0 +1,128 @@
+package coal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSeed(t *testing.T) {
+	id := New()
+
+	seed, err := ParseSeed("foos", &fooModel{}, []byte(`[
+		{"_id": {"$oid": "`+id.Hex()+`"}, "name": "Foo"}
+	]`))
+	assert.NoError(t, err)
+	assert.Equal(t, Seed{
+		Name: "foos",
+		Documents: []Model{
+			&fooModel{Base: B(id), Name: "Foo"},
+		},
+	}, seed)
+
+	seed, err = ParseSeed("foos", &fooModel{}, []byte(`[{"name": "Foo"}]`))
+	assert.Error(t, err)
+	assert.Equal(t, "seed document 0 is missing an id", err.Error())
+
+	seed, err = ParseSeed("foos", &fooModel{}, []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestSeeder(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		ctx := context.Background()
+
+		id1 := New()
+		id2 := New()
+
+		var calls int
+		s := NewSeeder("test")
+		s.Add(Seed{
+			Name: "foos",
+			Documents: []Model{
+				&fooModel{Base: B(id1), Name: "Foo"},
+			},
+			Seeder: func(ctx context.Context, store *Store) error {
+				calls++
+				return nil
+			},
+		})
+		s.Add(Seed{
+			Name:         "bars",
+			Environments: []string{"production"},
+			Documents: []Model{
+				&fooModel{Base: B(id2), Name: "Bar"},
+			},
+		})
+
+		assert.PanicsWithValue(t, `coal: duplicate seed "foos"`, func() {
+			s.Add(Seed{Name: "foos"})
+		})
+
+		xo.Test(func(xt *xo.Tester) {
+			num, err := s.Apply(ctx, tester.Store, xo.Sink("SEEDER"))
+			assert.NoError(t, err)
+			assert.Equal(t, 1, num)
+			assert.Equal(t, 1, calls)
+
+			assert.Equal(t, []string{
+				"applying seed: foos",
+				"applied seed: 1 inserted",
+			}, strings.Split(strings.TrimSpace(xt.Sinks["SEEDER"].String), "\n"))
+		})
+
+		assert.Equal(t, 1, tester.Count(&fooModel{}))
+
+		record := tester.FindLast(&AppliedSeed{}).(*AppliedSeed)
+		assert.Equal(t, "foos", record.Name)
+		assert.Equal(t, "test", record.Environment)
+		assert.Equal(t, int64(1), record.Inserted)
+
+		num, err := s.Apply(ctx, tester.Store, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, num)
+		assert.Equal(t, 1, calls)
+
+		/* interrupted */
+		tester.DeleteAll(&AppliedSeed{})
+
+		num, err = s.Apply(ctx, tester.Store, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, num)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, 1, tester.Count(&fooModel{}))
+
+		record = tester.FindLast(&AppliedSeed{}).(*AppliedSeed)
+		assert.Equal(t, int64(0), record.Inserted)
+
+		/* locked */
+		lease, err := AcquireLease(ctx, tester.Store, &AppliedSeed{}, ID{}, time.Minute)
+		assert.NoError(t, err)
+		assert.NotNil(t, lease)
+
+		num, err = s.Apply(ctx, tester.Store, nil)
+		assert.True(t, ErrSeedLocked.Is(err))
+		assert.Equal(t, 0, num)
+	})
+}
+
+func TestTesterSeed(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		seed := Seed{
+			Name: "foos",
+			Documents: []Model{
+				&fooModel{Base: B(), Name: "Foo"},
+			},
+		}
+
+		tester.Seed(seed)
+		tester.Seed(seed)
+
+		assert.Equal(t, 1, tester.Count(&fooModel{}))
+		assert.Equal(t, 0, tester.Count(&AppliedSeed{}))
+	})
+}
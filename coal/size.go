@@ -0,0 +1,155 @@
+package coal
+
+import (
+	"context"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MaxDocumentSize is the maximum size of a BSON document supported by MongoDB.
+const MaxDocumentSize = 16 * 1024 * 1024
+
+// ErrDocumentTooLarge is returned if a document exceeds the configured maximum
+// document size of a store.
+var ErrDocumentTooLarge = xo.BF("document too large")
+
+// SizeReport describes the document sizes of a collection.
+type SizeReport struct {
+	// The collection name.
+	Collection string
+
+	// The number of documents.
+	Count int64
+
+	// The average document size.
+	AvgSize int64
+
+	// The limit the average size is compared against.
+	Limit int64
+}
+
+// Ratio returns the ratio of the average document size to the limit.
+func (r SizeReport) Ratio() float64 {
+	return float64(r.AvgSize) / float64(r.Limit)
+}
+
+// CheckSizes will compute the average document size of the collections of the
+// provided models and return reports for collections whose average document
+// size exceeds the provided ratio (e.g. 0.5) of the stores maximum document
+// size or the MongoDB limit if not configured. This helps to detect unbounded
+// growth of embedded arrays early.
+//
+// Note: For MongoDB the "collStats" command is used while lungo collections
+// are scanned completely.
+func CheckSizes(ctx context.Context, store *Store, ratio float64, models ...Model) ([]SizeReport, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/CheckSizes")
+	defer span.End()
+
+	// get limit
+	limit := store.MaxDocumentSize
+	if limit <= 0 {
+		limit = MaxDocumentSize
+	}
+
+	// check collections
+	var reports []SizeReport
+	for _, model := range models {
+		// get sizes
+		name := GetMeta(model).Collection
+		count, size, err := collectionSize(ctx, store, model)
+		if err != nil {
+			return nil, err
+		}
+
+		// skip empty collections
+		if count == 0 {
+			continue
+		}
+
+		// prepare report
+		report := SizeReport{
+			Collection: name,
+			Count:      count,
+			AvgSize:    size / count,
+			Limit:      limit,
+		}
+
+		// check ratio
+		if report.Ratio() >= ratio {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+func collectionSize(ctx context.Context, store *Store, model Model) (int64, int64, error) {
+	// get collection
+	coll := store.C(model)
+
+	// scan collections if statistics are not supported
+	if !store.Supports(CollectionStats) {
+		// find all documents
+		iter, err := coll.Find(ctx, bson.M{})
+		if err != nil {
+			return 0, 0, err
+		}
+		defer iter.Close()
+
+		// sum sizes
+		var count, size int64
+		for iter.Next() {
+			var raw bson.Raw
+			err = iter.Decode(&raw)
+			if err != nil {
+				return 0, 0, err
+			}
+			count++
+			size += int64(len(raw))
+		}
+		if err := iter.Error(); err != nil {
+			return 0, 0, err
+		}
+
+		return count, size, nil
+	}
+
+	// get collection stats
+	var stats struct {
+		Count int64 `bson:"count"`
+		Size  int64 `bson:"size"`
+	}
+	err := store.DB().RunCommand(ctx, bson.D{
+		{Key: "collStats", Value: coll.Native().Name()},
+	}).Decode(&stats)
+	if err != nil {
+		return 0, 0, xo.W(err)
+	}
+
+	return stats.Count, stats.Size, nil
+}
+
+// CheckSize will return ErrDocumentTooLarge if the encoded model exceeds the
+// maximum document size configured on the store.
+func CheckSize(store *Store, model Model) error {
+	// get limit
+	limit := store.MaxDocumentSize
+	if limit <= 0 {
+		return nil
+	}
+
+	// encode document
+	bytes, err := bson.Marshal(model)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// check size
+	if int64(len(bytes)) > limit {
+		return ErrDocumentTooLarge.WrapF("document of size %d exceeds limit of %d", len(bytes), limit)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package coal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxDocumentSize(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Store.MaxDocumentSize = 256
+		defer func() {
+			tester.Store.MaxDocumentSize = 0
+		}()
+
+		m := tester.Store.M(&postModel{})
+
+		post := &postModel{
+			Title: "small",
+		}
+		err := m.Insert(nil, post)
+		assert.NoError(t, err)
+
+		err = m.Insert(nil, &postModel{
+			Title: strings.Repeat("x", 512),
+		})
+		assert.True(t, ErrDocumentTooLarge.Is(err))
+
+		post.TextBody = strings.Repeat("x", 512)
+		found, err := m.Replace(nil, post, false)
+		assert.True(t, ErrDocumentTooLarge.Is(err))
+		assert.False(t, found)
+
+		assert.Equal(t, 1, tester.Count(&postModel{}))
+		assert.Equal(t, "", tester.Fetch(&postModel{}, post.ID()).(*postModel).TextBody)
+
+		tester.Store.MaxDocumentSize = 0
+		tester.Insert(&postModel{
+			Title: strings.Repeat("x", 512),
+		})
+		tester.Store.MaxDocumentSize = 256
+
+		var posts []postModel
+		err = m.FindAll(nil, &posts, nil, nil, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+		assert.Len(t, posts, 2)
+	})
+}
+
+func TestCheckSizes(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Insert(&postModel{
+			Title: strings.Repeat("x", 1000),
+		})
+		tester.Insert(&postModel{
+			Title: strings.Repeat("x", 3000),
+		})
+		tester.Insert(&noteModel{
+			Title: "small",
+		})
+
+		tester.Store.MaxDocumentSize = 4096
+		defer func() {
+			tester.Store.MaxDocumentSize = 0
+		}()
+
+		reports, err := CheckSizes(nil, tester.Store, 0.25, &postModel{}, &noteModel{}, &commentModel{})
+		assert.NoError(t, err)
+		assert.Len(t, reports, 1)
+		assert.Equal(t, "posts", reports[0].Collection)
+		assert.Equal(t, int64(2), reports[0].Count)
+		assert.Equal(t, int64(4096), reports[0].Limit)
+		assert.True(t, reports[0].AvgSize > 2000)
+		assert.True(t, reports[0].Ratio() > 0.5)
+
+		reports, err = CheckSizes(nil, tester.Store, 0.9, &postModel{}, &noteModel{})
+		assert.NoError(t, err)
+		assert.Empty(t, reports)
+	})
+}
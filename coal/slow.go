@@ -0,0 +1,94 @@
+package coal
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// SlowQuery describes a command that exceeded the slow query threshold.
+type SlowQuery struct {
+	// The command name e.g. "find" or "aggregate".
+	Command string
+
+	// The database and collection, if known.
+	Database   string
+	Collection string
+
+	// The command document. It is empty for security-sensitive commands.
+	Document bson.Raw
+
+	// The duration of the command.
+	Duration time.Duration
+
+	// The failure, if the command failed.
+	Failure string
+}
+
+type pendingCommand struct {
+	name       string
+	database   string
+	collection string
+	document   bson.Raw
+}
+
+func (m *monitor) commandStarted(evt *event.CommandStartedEvent) {
+	// check hook
+	hooks := m.hooks.Load()
+	if hooks == nil || hooks.SlowQuery == nil {
+		return
+	}
+
+	// get collection
+	var collection string
+	if evt.CommandName == "getMore" {
+		collection, _ = evt.Command.Lookup("collection").StringValueOK()
+	} else if elems, err := evt.Command.Elements(); err == nil && len(elems) > 0 {
+		collection, _ = elems[0].Value().StringValueOK()
+	}
+
+	// store command
+	m.commands.Store(evt.RequestID, &pendingCommand{
+		name:       evt.CommandName,
+		database:   evt.DatabaseName,
+		collection: collection,
+		document:   evt.Command,
+	})
+}
+
+func (m *monitor) commandFinished(evt *event.CommandFinishedEvent, failure string) {
+	// get command
+	value, ok := m.commands.LoadAndDelete(evt.RequestID)
+	if !ok {
+		return
+	}
+	cmd := value.(*pendingCommand)
+
+	// check hook
+	hooks := m.hooks.Load()
+	if hooks == nil || hooks.SlowQuery == nil {
+		return
+	}
+
+	// get threshold
+	threshold := hooks.SlowQueryThreshold
+	if threshold <= 0 {
+		threshold = 100 * time.Millisecond
+	}
+
+	// check duration
+	if evt.Duration < threshold {
+		return
+	}
+
+	// call hook
+	hooks.SlowQuery(SlowQuery{
+		Command:    cmd.name,
+		Database:   cmd.database,
+		Collection: cmd.collection,
+		Document:   cmd.document,
+		Duration:   evt.Duration,
+		Failure:    failure,
+	})
+}
@@ -0,0 +1,100 @@
+package coal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSlowQueries(t *testing.T) {
+	var chained []string
+	opts := options.Client().SetMonitor(&event.CommandMonitor{
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			chained = append(chained, evt.CommandName)
+		},
+	})
+
+	m := newMonitor(opts)
+
+	var queries []SlowQuery
+	store := &Store{monitor: m}
+	store.Monitor(MonitorHooks{
+		SlowQuery: func(query SlowQuery) {
+			queries = append(queries, query)
+		},
+		SlowQueryThreshold: time.Second,
+	})
+
+	run := func(id int64, name string, cmd bson.D, duration time.Duration, failure string) {
+		raw, err := bson.Marshal(cmd)
+		assert.NoError(t, err)
+
+		opts.Monitor.Started(nil, &event.CommandStartedEvent{
+			Command:      raw,
+			DatabaseName: "test",
+			CommandName:  name,
+			RequestID:    id,
+		})
+
+		finished := event.CommandFinishedEvent{
+			Duration:     duration,
+			CommandName:  name,
+			DatabaseName: "test",
+			RequestID:    id,
+		}
+		if failure != "" {
+			opts.Monitor.Failed(nil, &event.CommandFailedEvent{
+				CommandFinishedEvent: finished,
+				Failure:              failure,
+			})
+		} else {
+			opts.Monitor.Succeeded(nil, &event.CommandSucceededEvent{
+				CommandFinishedEvent: finished,
+			})
+		}
+	}
+
+	run(1, "find", bson.D{{Key: "find", Value: "posts"}}, time.Millisecond, "")
+	run(2, "find", bson.D{{Key: "find", Value: "posts"}}, 2*time.Second, "")
+	run(3, "getMore", bson.D{{Key: "getMore", Value: int64(7)}, {Key: "collection", Value: "comments"}}, time.Second, "")
+	run(4, "aggregate", bson.D{{Key: "aggregate", Value: "posts"}}, 3*time.Second, "failed")
+
+	assert.Equal(t, []string{"aggregate"}, chained)
+	assert.Len(t, queries, 3)
+	for i := range queries {
+		queries[i].Document = nil
+	}
+	assert.Equal(t, []SlowQuery{
+		{
+			Command:    "find",
+			Database:   "test",
+			Collection: "posts",
+			Duration:   2 * time.Second,
+		},
+		{
+			Command:    "getMore",
+			Database:   "test",
+			Collection: "comments",
+			Duration:   time.Second,
+		},
+		{
+			Command:    "aggregate",
+			Database:   "test",
+			Collection: "posts",
+			Duration:   3 * time.Second,
+			Failure:    "failed",
+		},
+	}, queries)
+
+	var pending int
+	m.commands.Range(func(key, value any) bool {
+		pending++
+		return true
+	})
+	assert.Zero(t, pending)
+}
@@ -0,0 +1,227 @@
+package coal
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/text/unicode/norm"
+)
+
+func init() {
+	// add indexes
+	AddIndex(&Slug{}, true, 0, "Scope", "Name")
+	AddIndex(&Slug{}, false, 0, "Owner", "Created")
+}
+
+// ErrSlugCollision is returned by ReserveSlug if no free slug could be found
+// using suffixes.
+var ErrSlugCollision = xo.BF("slug collision")
+
+// Slug is a slug reserved by a document. Slugs are stored in a separate
+// collection and identified by the collection of the owning document and the
+// name. Slugs remain reserved when the owner switches to a new slug to allow
+// redirects from old slugs.
+type Slug struct {
+	Base    `json:"-" bson:",inline" coal:"slugs"`
+	Scope   string    `json:"scope"`
+	Name    string    `json:"name"`
+	Owner   ID        `json:"owner"`
+	Created time.Time `json:"created-at" bson:"created_at"`
+}
+
+// Validate implements the Model interface.
+func (s *Slug) Validate() error {
+	// check scope
+	if s.Scope == "" {
+		return xo.SF("missing scope")
+	}
+
+	// check name
+	if s.Name == "" {
+		return xo.SF("missing name")
+	}
+
+	// check owner
+	if s.Owner.IsZero() {
+		return xo.SF("missing owner")
+	}
+
+	// check created
+	if s.Created.IsZero() {
+		return xo.SF("missing created")
+	}
+
+	return nil
+}
+
+// the transliterations of letters that do not decompose into ASCII
+var slugTransliterations = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'ð': "d",
+	'þ': "th",
+	'ı': "i",
+	'ħ': "h",
+	'ŧ': "t",
+	'ŋ': "ng",
+}
+
+// Slugify will convert the provided string to a URL-safe slug consisting of
+// lowercase ASCII letters, digits and single dashes. Accents are removed,
+// common letters like "ß" or "ø" are transliterated and all other characters
+// are replaced with dashes.
+func Slugify(str string) string {
+	// prepare builder
+	var builder strings.Builder
+	builder.Grow(len(str))
+
+	// convert runes
+	dash := false
+	for _, r := range norm.NFKD.String(str) {
+		// skip combining marks
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		// add transliteration
+		r = unicode.ToLower(r)
+		if str, ok := slugTransliterations[r]; ok {
+			builder.WriteString(str)
+			dash = false
+			continue
+		}
+
+		// add character
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		// add dash
+		if !dash && builder.Len() > 0 {
+			builder.WriteByte('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(builder.String(), "-")
+}
+
+// ReserveSlug will reserve a unique slug generated from the provided source
+// for the specified document. If the slug is reserved by another document, a
+// numeric suffix starting with "-2" is appended. Slugs already reserved by
+// the document are reused. Reservations are atomic using a unique index.
+func ReserveSlug(ctx context.Context, store *Store, model Model, id ID, source string) (string, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/ReserveSlug")
+	defer span.End()
+
+	// get base
+	base := Slugify(source)
+	if base == "" {
+		return "", xo.F("empty slug")
+	}
+
+	// get scope
+	scope := GetMeta(model).Collection
+
+	// try candidates
+	for i := 1; i <= 100; i++ {
+		// get candidate
+		name := base
+		if i > 1 {
+			name = base + "-" + strconv.Itoa(i)
+		}
+
+		// prepare filter
+		filter := bson.M{
+			"Scope": scope,
+			"Name":  name,
+		}
+
+		// insert slug if missing
+		inserted, err := store.M(&Slug{}).InsertIfMissing(ctx, filter, &Slug{
+			Base:    B(),
+			Scope:   scope,
+			Name:    name,
+			Owner:   id,
+			Created: time.Now(),
+		}, false)
+		if err != nil && !IsDuplicate(err) {
+			return "", err
+		} else if inserted {
+			return name, nil
+		}
+
+		// check owner
+		var slug Slug
+		found, err := store.M(&Slug{}).FindFirst(ctx, &slug, filter, nil, 0, false)
+		if err != nil {
+			return "", err
+		} else if found && slug.Owner == id {
+			return name, nil
+		}
+	}
+
+	return "", ErrSlugCollision.Wrap()
+}
+
+// LookupSlug will return the ID of the document that reserved the specified
+// slug now or in the past. Callers should redirect to the current slug of the
+// document if it differs. A zero ID is returned if the slug is unknown.
+func LookupSlug(ctx context.Context, store *Store, model Model, name string) (ID, error) {
+	// find slug
+	var slug Slug
+	found, err := store.M(&Slug{}).FindFirst(ctx, &slug, bson.M{
+		"Scope": GetMeta(model).Collection,
+		"Name":  name,
+	}, nil, 0, false)
+	if err != nil {
+		return ID{}, err
+	} else if !found {
+		return ID{}, nil
+	}
+
+	return slug.Owner, nil
+}
+
+// SlugHistory will return all slugs reserved by the specified document in the
+// order they have been reserved first.
+func SlugHistory(ctx context.Context, store *Store, model Model, id ID) ([]string, error) {
+	// find slugs
+	var slugs []Slug
+	err := store.M(&Slug{}).FindAll(ctx, &slugs, bson.M{
+		"Scope": GetMeta(model).Collection,
+		"Owner": id,
+	}, []string{"Created", "_id"}, 0, 0, false, NoTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	// collect names
+	names := make([]string, 0, len(slugs))
+	for _, slug := range slugs {
+		names = append(names, slug.Name)
+	}
+
+	return names, nil
+}
+
+// ReleaseSlugs will remove all slugs reserved by the specified document. It
+// should be called when the document is deleted.
+func ReleaseSlugs(ctx context.Context, store *Store, model Model, id ID) (int64, error) {
+	return store.M(&Slug{}).DeleteAll(ctx, bson.M{
+		"Scope": GetMeta(model).Collection,
+		"Owner": id,
+	})
+}
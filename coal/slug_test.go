@@ -0,0 +1,84 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	table := map[string]string{
+		"":                   "",
+		"Hello World":        "hello-world",
+		"  Hello,   World! ": "hello-world",
+		"Crème Brûlée":       "creme-brulee",
+		"foo--bar__baz":      "foo-bar-baz",
+		"---":                "",
+		"ﬁle":                "file",
+		"Straße":             "strasse",
+		"Smørrebrød":         "smorrebrod",
+		"Æther Łódź":         "aether-lodz",
+		"Þór":                "thor",
+	}
+
+	for str, slug := range table {
+		assert.Equal(t, slug, Slugify(str), str)
+	}
+}
+
+func TestReserveSlug(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.DeleteAll(&Slug{})
+
+		post1 := New()
+		post2 := New()
+
+		slug, err := ReserveSlug(nil, tester.Store, &postModel{}, post1, "Hello World")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello-world", slug)
+
+		slug, err = ReserveSlug(nil, tester.Store, &postModel{}, post1, "Hello World!")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello-world", slug)
+
+		slug, err = ReserveSlug(nil, tester.Store, &postModel{}, post2, "Hello World")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello-world-2", slug)
+
+		slug, err = ReserveSlug(nil, tester.Store, &commentModel{}, post2, "Hello World")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello-world", slug)
+
+		slug, err = ReserveSlug(nil, tester.Store, &postModel{}, post1, "Goodbye")
+		assert.NoError(t, err)
+		assert.Equal(t, "goodbye", slug)
+
+		_, err = ReserveSlug(nil, tester.Store, &postModel{}, post1, "!!!")
+		assert.Error(t, err)
+		assert.Equal(t, "empty slug", err.Error())
+
+		id, err := LookupSlug(nil, tester.Store, &postModel{}, "hello-world")
+		assert.NoError(t, err)
+		assert.Equal(t, post1, id)
+
+		id, err = LookupSlug(nil, tester.Store, &postModel{}, "hello-world-2")
+		assert.NoError(t, err)
+		assert.Equal(t, post2, id)
+
+		id, err = LookupSlug(nil, tester.Store, &postModel{}, "foo")
+		assert.NoError(t, err)
+		assert.True(t, id.IsZero())
+
+		history, err := SlugHistory(nil, tester.Store, &postModel{}, post1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"hello-world", "goodbye"}, history)
+
+		n, err := ReleaseSlugs(nil, tester.Store, &postModel{}, post1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		slug, err = ReserveSlug(nil, tester.Store, &postModel{}, post2, "Goodbye")
+		assert.NoError(t, err)
+		assert.Equal(t, "goodbye", slug)
+	})
+}
@@ -6,13 +6,18 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/256dpi/lungo"
+	"github.com/256dpi/lungo/bsonkit"
 	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 	"go.mongodb.org/mongo-driver/x/mongo/driver"
 )
@@ -52,6 +57,9 @@ func Connect(uri string, reporter func(error), opts ...*options.ClientOptions) (
 	opt.SetReadConcern(readconcern.Majority())
 	opt.SetWriteConcern(writeconcern.New(writeconcern.WMajority()))
 
+	// install monitor
+	monitor := newMonitor(opt)
+
 	// create client
 	client, err := lungo.Connect(nil, opt)
 	if err != nil {
@@ -64,7 +72,11 @@ func Connect(uri string, reporter func(error), opts ...*options.ClientOptions) (
 		return nil, xo.W(err)
 	}
 
-	return NewStore(client, defaultDB, nil, reporter), nil
+	// create store
+	store := NewStore(client, defaultDB, nil, reporter)
+	store.monitor = monitor
+
+	return store, nil
 }
 
 // MustOpen will call Open and panic on errors.
@@ -100,24 +112,79 @@ func Open(store lungo.Store, defaultDB string, reporter func(error)) (*Store, er
 }
 
 // NewStore creates a store that uses the specified client, default database and
-// engine. The engine may be nil if no lungo database is used.
+// engine. The engine may be nil if no lungo database is used. Lungo clients are
+// assumed to support no capabilities while all other clients are assumed to
+// support all capabilities.
 func NewStore(client lungo.IClient, defaultDB string, engine *lungo.Engine, reporter func(error)) *Store {
+	// determine capabilities
+	caps := AllCapabilities
+	if _, ok := client.(*lungo.Client); ok {
+		caps = 0
+	}
+
+	// prepare close
+	var closer func()
+	if engine != nil {
+		closer = engine.Close
+	}
+
+	return NewBackendStore(NewBackend(client, caps, closer), defaultDB, reporter)
+}
+
+// NewBackendStore creates a store that uses the specified backend and default
+// database.
+func NewBackendStore(backend Backend, defaultDB string, reporter func(error)) *Store {
 	return &Store{
-		client:   client,
+		backend:  backend,
+		client:   backend.Client(),
+		caps:     backend.Capabilities(),
 		defDB:    defaultDB,
-		engine:   engine,
 		reporter: reporter,
 	}
 }
 
 // A Store manages the usage of a database client.
 type Store struct {
+	// MaxDocumentSize may be set to limit the size of documents written by
+	// managers. Inserted or replaced documents that exceed the limit are
+	// rejected with ErrDocumentTooLarge before being sent to the database.
+	// Updates are not checked as the resulting size is unknown.
+	MaxDocumentSize int64
+
+	// TenancyDebug may be set to verify that all manager queries on models
+	// with tenancy enabled filter by the tenant key. Un-scoped queries fail
+	// with ErrUnscopedQuery. Operations that address documents by ID are
+	// not verified.
+	TenancyDebug bool
+
+	// Encrypter may be set when field-level encryption is configured to allow
+	// queries for equality on deterministically encrypted fields.
+	Encrypter Encrypter
+
+	// Retries may be set to retry collection operations outside transactions
+	// that failed with a transient error. Writes are only retried if the error
+	// indicates that no writes have been performed, as a replayed write may
+	// otherwise be applied twice. Retries are delayed using an exponential backoff starting
+	// at 50ms. Transactions should be retried as a whole using RT or
+	// WithTransaction.
+	Retries int
+
+	// Decoding may be set to verify documents that are decoded into models to
+	// detect schema drift, e.g. during migrations. Documents with unknown or
+	// missing required fields are reported using the reporter or fail to
+	// decode with ErrSchemaDrift.
+	Decoding Decoding
+
+	backend  Backend
 	client   lungo.IClient
+	caps     Capabilities
 	defDB    string
-	engine   *lungo.Engine
 	reporter func(error)
 	colls    sync.Map
 	managers sync.Map
+	routes   sync.Map
+	cache    atomic.Pointer[Cache]
+	monitor  *monitor
 }
 
 // Client returns the client used by this store.
@@ -125,6 +192,16 @@ func (s *Store) Client() lungo.IClient {
 	return s.client
 }
 
+// Backend returns the backend used by this store.
+func (s *Store) Backend() Backend {
+	return s.backend
+}
+
+// Supports returns whether the backend supports the provided capabilities.
+func (s *Store) Supports(caps Capabilities) bool {
+	return s.caps.Has(caps)
+}
+
 // Lungo returns whether the stores is using a lungo instead of a mongo client.
 func (s *Store) Lungo() bool {
 	_, ok := s.client.(*lungo.Client)
@@ -136,6 +213,92 @@ func (s *Store) DB() lungo.IDatabase {
 	return s.client.Database(s.defDB)
 }
 
+// ClusterTime will return the current cluster time. Writes performed after
+// obtaining the time are guaranteed to have a later cluster time which can be
+// used to await their visibility using Stream.Await.
+func (s *Store) ClusterTime(ctx context.Context) (primitive.Timestamp, error) {
+	// use local time if cluster times are not supported
+	if !s.Supports(ClusterTimes) {
+		return bsonkit.Now(), nil
+	}
+
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Store.ClusterTime")
+	defer span.End()
+
+	// run ping to obtain operation time
+	var res struct {
+		OperationTime primitive.Timestamp `bson:"operationTime"`
+	}
+	err := s.DB().RunCommand(ctx, bson.M{"ping": 1}).Decode(&res)
+	if err != nil {
+		return primitive.Timestamp{}, xo.W(err)
+	}
+
+	// check time
+	if res.OperationTime.IsZero() {
+		return primitive.Timestamp{}, xo.F("missing operation time")
+	}
+
+	return res.OperationTime, nil
+}
+
+type route struct {
+	store    *Store
+	database string
+}
+
+// Route will route the collections of the provided models to the specified
+// database of the provided store, e.g. to store analytics models on a
+// separate cluster. If the store is nil, the receiving store is used. If the
+// database is empty, the default database of the store is used. The route is
+// also added to the provided store. Transactions may only include collections
+// of the cluster they have been started on. The store of a routed model is
+// returned by Target and should be used to run transactions for the model.
+//
+// Note: Routes should be configured before the store is used.
+func (s *Store) Route(store *Store, database string, models ...Model) {
+	// ensure store
+	if store == nil {
+		store = s
+	}
+
+	// ensure database
+	if database == "" {
+		database = store.defDB
+	}
+
+	// add routes
+	for _, model := range models {
+		// get meta
+		meta := GetMeta(model)
+
+		// store routes and remove cached collections and managers
+		for _, str := range []*Store{s, store} {
+			str.routes.Store(meta, route{
+				store:    store,
+				database: database,
+			})
+			str.colls.Delete(meta)
+			str.managers.Delete(meta)
+		}
+	}
+}
+
+// Target will return the store that serves the collection of the specified
+// model. This is the receiving store unless the model has been routed to
+// another store. The collection of the model as returned by the target store
+// is the routed collection.
+func (s *Store) Target(model Model) *Store {
+	// check route
+	val, ok := s.routes.Load(GetMeta(model))
+	if ok {
+		return val.(route).store
+	}
+
+	return s
+}
+
 // C will return the collection for the specified model. The collection is just
 // a thin wrapper around the driver collection API to integrate tracing. Since
 // it does not perform any checks, it is recommended to use the manager to
@@ -150,9 +313,17 @@ func (s *Store) C(model Model) *Collection {
 		return val.(*Collection)
 	}
 
+	// get target and database
+	target, database := s, s.defDB
+	if val, ok := s.routes.Load(meta); ok {
+		target, database = val.(route).store, val.(route).database
+	}
+
 	// create collection
 	coll := &Collection{
-		coll: s.DB().Collection(meta.Collection),
+		store:      target,
+		coll:       target.client.Database(database).Collection(meta.Collection),
+		collations: target.Supports(Collations),
 	}
 
 	// cache collection
@@ -176,11 +347,16 @@ func (s *Store) M(model Model) *Manager {
 
 	// create manager
 	manager := &Manager{
-		meta:  meta,
-		coll:  s.C(model),
-		trans: NewTranslator(model),
+		store:  s,
+		meta:   meta,
+		coll:   s.C(model),
+		trans:  NewTranslator(model),
+		tenant: TenantField(model),
 	}
 
+	// get timestamp fields
+	manager.created, manager.updated = Timestamps(model)
+
 	// cache collection
 	s.managers.Store(meta, manager)
 
@@ -218,15 +394,40 @@ func (s *Store) T(ctx context.Context, readOnly bool, fn func(ctx context.Contex
 	ctx, span := xo.Trace(ctx, "coal/Store.T")
 	defer span.End()
 
+	// get preference
+	pref := GetPreference(ctx)
+
+	// use a snapshot session for read only secondary reads as transactions
+	// only support the primary read preference
+	if readOnly && pref.ReadPreference != nil && pref.ReadPreference.Mode() != readpref.PrimaryMode && s.Supports(SnapshotSessions) {
+		opts := options.Session().SetSnapshot(true)
+		return xo.W(s.client.UseSessionWithOptions(ctx, opts, func(sc lungo.ISessionContext) error {
+			return fn(context.WithValue(sc, Transaction{}, Transaction{
+				Store:    s,
+				ReadOnly: true,
+				Snapshot: true,
+			}))
+		}))
+	}
+
 	// prepare options
 	opts := options.Session().
 		SetCausalConsistency(true).
 		SetDefaultReadConcern(readconcern.Snapshot())
 
+	// prepare transaction options
+	txOpts := options.Transaction()
+	if pref.ReadPreference != nil {
+		txOpts.SetReadPreference(pref.ReadPreference)
+	}
+	if pref.WriteConcern != nil {
+		txOpts.SetWriteConcern(pref.WriteConcern)
+	}
+
 	// start transaction
 	return xo.W(s.client.UseSessionWithOptions(ctx, opts, func(sc lungo.ISessionContext) error {
 		// start transaction
-		err := sc.StartTransaction()
+		err := sc.StartTransaction(txOpts)
 		if err != nil {
 			return xo.W(err)
 		}
@@ -273,6 +474,138 @@ func (s *Store) RT(ctx context.Context, maxAttempts int, fn func(ctx context.Con
 	ctx, span := xo.Trace(ctx, "coal/Store.RT")
 	defer span.End()
 
+	return s.retry(ctx, options.Transaction(), func(attempts int) bool {
+		return attempts < maxAttempts
+	}, fn)
+}
+
+// TransactionRetryLimit is the duration after which WithTransaction stops
+// retrying a transaction.
+var TransactionRetryLimit = 120 * time.Second
+
+// WithTransaction will create a transaction around the specified callback and
+// retry the transaction on transient errors and unknown commit results until
+// the TransactionRetryLimit has been reached, as recommended by MongoDB. The
+// callback receives a session bound context that must be used by all nested
+// operations. If the context already carries a transaction, the callback is
+// run within the existing transaction without retries. The read preference
+// and write concern set via WithPreference are used for the transaction.
+//
+// Note: The callback may be called multiple times and should therefore not
+// cause side effects outside the transaction.
+func (s *Store) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// join existing transaction
+	if HasTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Store.WithTransaction")
+	defer span.End()
+
+	// get preference
+	pref := GetPreference(ctx)
+
+	// prepare transaction options
+	txOpts := options.Transaction()
+	if pref.ReadPreference != nil {
+		txOpts.SetReadPreference(pref.ReadPreference)
+	}
+	if pref.WriteConcern != nil {
+		txOpts.SetWriteConcern(pref.WriteConcern)
+	}
+
+	// get start
+	start := time.Now()
+
+	return s.retry(ctx, txOpts, func(int) bool {
+		return time.Since(start) < TransactionRetryLimit
+	}, fn)
+}
+
+// Snapshot will run the specified callback with a context that performs all
+// reads at the same cluster time. This allows running a group of queries
+// across collections without observing concurrent writes in between, e.g. to
+// build composite responses or exports. The callback should only perform
+// reads. A snapshot session is used if supported, otherwise the callback is
+// run in a read only transaction. If the context already carries a
+// transaction, the callback is run within the existing transaction.
+func (s *Store) Snapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// join existing transaction
+	if HasTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	// use a read only transaction if snapshot sessions are not supported
+	if !s.Supports(SnapshotSessions) {
+		return s.T(ctx, true, fn)
+	}
+
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Store.Snapshot")
+	defer span.End()
+
+	// use snapshot session
+	opts := options.Session().SetSnapshot(true)
+	return xo.W(s.client.UseSessionWithOptions(ctx, opts, func(sc lungo.ISessionContext) error {
+		return fn(context.WithValue(sc, Transaction{}, Transaction{
+			Store:    s,
+			ReadOnly: true,
+			Snapshot: true,
+		}))
+	}))
+}
+
+type causalSession struct{}
+
+// Causal will run the specified callback with a context that performs all
+// operations in a causally consistent session. Reads observe the preceding
+// writes of the session, even if they are served by a secondary using a read
+// preference set via WithPreference. This allows e.g. controllers and jobs to
+// read documents right after writing them. If the context already carries a
+// causal session or transaction, the callback is run within the existing
+// session or transaction.
+//
+// Note: Transactions started within the callback use a separate session.
+func (s *Store) Causal(ctx context.Context, fn func(ctx context.Context) error) error {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// join existing session or transaction
+	if IsCausal(ctx) || HasTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Store.Causal")
+	defer span.End()
+
+	// use causally consistent session
+	opts := options.Session().SetCausalConsistency(true)
+	return xo.W(s.client.UseSessionWithOptions(ctx, opts, func(sc lungo.ISessionContext) error {
+		return fn(context.WithValue(sc, causalSession{}, s))
+	}))
+}
+
+// IsCausal will return whether the context carries a causally consistent
+// session created using Causal.
+func IsCausal(ctx context.Context) bool {
+	return ctx != nil && ctx.Value(causalSession{}) != nil
+}
+
+func (s *Store) retry(ctx context.Context, txOpts *options.TransactionOptions, retry func(attempts int) bool, fn func(ctx context.Context) error) error {
 	// prepare options
 	opts := options.Session().
 		SetCausalConsistency(true).
@@ -288,7 +621,7 @@ func (s *Store) RT(ctx context.Context, maxAttempts int, fn func(ctx context.Con
 			attempts++
 
 			// start transaction
-			err := sc.StartTransaction()
+			err := sc.StartTransaction(txOpts)
 			if err != nil {
 				return xo.W(err)
 			}
@@ -303,7 +636,7 @@ func (s *Store) RT(ctx context.Context, maxAttempts int, fn func(ctx context.Con
 				_ = sc.AbortTransaction(sc)
 
 				// handle transient transaction errors
-				if attempts < maxAttempts && sc.Err() == nil && isTransientTransactionError(err) {
+				if retry(attempts) && sc.Err() == nil && isTransientTransactionError(err) {
 					continue
 				}
 
@@ -315,12 +648,12 @@ func (s *Store) RT(ctx context.Context, maxAttempts int, fn func(ctx context.Con
 			err = sc.CommitTransaction(sc)
 			if err != nil {
 				// handle unknown commit result error
-				if attempts < maxAttempts && sc.Err() == nil && isUnknownCommitResultError(err) {
+				if retry(attempts) && sc.Err() == nil && isUnknownCommitResultError(err) {
 					goto Commit
 				}
 
 				// handle transient transaction errors
-				if attempts < maxAttempts && sc.Err() == nil && isTransientTransactionError(err) {
+				if retry(attempts) && sc.Err() == nil && isTransientTransactionError(err) {
 					continue
 				}
 
@@ -332,26 +665,124 @@ func (s *Store) RT(ctx context.Context, maxAttempts int, fn func(ctx context.Con
 	}))
 }
 
-// Close will close the store and its associated client.
-func (s *Store) Close() error {
-	// disconnect client
-	err := s.client.Disconnect(nil)
+// Iterate will find all documents of the specified model that match the
+// filter and yield them in batches ordered by document ID. The next batch is
+// only loaded after the callback returned, which allows traversing large
+// collections without loading all documents or keeping a cursor open for
+// longer than a batch. The callback may return ErrStop to stop the iteration
+// without an error. The default batch size is 1000.
+//
+// Warning: The iteration is not isolated and should not be run as part of a
+// transaction. Documents inserted with a lower ID during the iteration are
+// not yielded.
+func (s *Store) Iterate(ctx context.Context, model Model, filter bson.M, batchSize int64, fn func(batch []Model) error) error {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Store.Iterate")
+	defer span.End()
+
+	// set default size
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	// get manager
+	manager := s.M(model)
+
+	// translate filter
+	filterDoc, err := manager.filter(filter)
 	if err != nil {
-		return xo.W(err)
+		return err
 	}
 
-	// close engine
-	if s.engine != nil {
-		s.engine.Close()
+	// prepare options
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(batchSize)
+
+	// process batches
+	var last ID
+	var total int64
+	for {
+		// check context
+		err = ctx.Err()
+		if err != nil {
+			return xo.W(err)
+		}
+
+		// prepare query
+		query := filterDoc
+		if !last.IsZero() {
+			query = bson.D{{Key: "$and", Value: bson.A{filterDoc, bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$gt", Value: last}}},
+			}}}}
+		}
+
+		// find documents
+		iter, err := manager.coll.Find(ctx, query, opts)
+		if err != nil {
+			return err
+		}
+
+		// decode documents
+		batch := make([]Model, 0, batchSize)
+		for iter.Next() {
+			doc := manager.meta.Make()
+			err = iter.Decode(doc)
+			if err != nil {
+				iter.Close()
+				return err
+			}
+			Clean(doc)
+			batch = append(batch, doc)
+		}
+
+		// check error
+		err = iter.Error()
+		iter.Close()
+		if err != nil {
+			return err
+		}
+
+		// check batch
+		if len(batch) == 0 {
+			break
+		}
+
+		// update state
+		last = batch[len(batch)-1].ID()
+		total += int64(len(batch))
+
+		// yield batch
+		err = fn(batch)
+		if ErrStop.Is(err) {
+			break
+		} else if err != nil {
+			return xo.W(err)
+		}
+
+		// check if done
+		if int64(len(batch)) < batchSize {
+			break
+		}
 	}
 
+	// tag span
+	span.Tag("yielded", total)
+
 	return nil
 }
 
-// Transaction describes a transaction.
+// Close will close the store and its associated backend.
+func (s *Store) Close() error {
+	return s.backend.Close()
+}
+
+// Transaction describes a transaction. Snapshot is set if the reads are
+// performed using a snapshot session instead of a transaction.
 type Transaction struct {
 	Store    *Store
 	ReadOnly bool
+	Snapshot bool
 }
 
 // GetTransaction will return whether the context carries a transaction and the
@@ -377,6 +808,42 @@ func HasTransaction(ctx context.Context) bool {
 	return ok
 }
 
+// Preference describes the read preference and write concern used for
+// operations.
+type Preference struct {
+	ReadPreference *readpref.ReadPref
+	WriteConcern   *writeconcern.WriteConcern
+}
+
+// WithPreference will return a context that carries the provided preference.
+// Operations and transactions started with the context will use the read
+// preference and write concern if set. Within a transaction, the preference
+// of the transaction is used.
+//
+// Note: As transactions only support the primary read preference, read only
+// transactions with another read preference are run as snapshot sessions.
+func WithPreference(ctx context.Context, pref Preference) context.Context {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithValue(ctx, Preference{}, pref)
+}
+
+// GetPreference will return the preference carried by the context.
+func GetPreference(ctx context.Context) Preference {
+	// check context
+	if ctx == nil {
+		return Preference{}
+	}
+
+	// get value
+	pref, _ := ctx.Value(Preference{}).(Preference)
+
+	return pref
+}
+
 func isTransientTransactionError(err error) bool {
 	var cmdErr mongo.CommandError
 	return errors.As(err, &cmdErr) && cmdErr.HasErrorLabel(driver.TransientTransactionError)
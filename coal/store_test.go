@@ -2,16 +2,24 @@ package coal
 
 import (
 	"context"
+	"errors"
 	"io"
+	"os"
 	"sync"
 	"testing"
 
 	"github.com/256dpi/xo"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 func TestConnect(t *testing.T) {
+	if os.Getenv(MemoryEnv) != "" {
+		t.Skip("in memory mode")
+	}
+
 	store := MustConnect("mongodb://0.0.0.0/test-fire-coal", xo.Crash)
 	assert.NotNil(t, store.Client)
 
@@ -36,6 +44,10 @@ func TestOpen(t *testing.T) {
 }
 
 func TestStoreLungo(t *testing.T) {
+	if os.Getenv(MemoryEnv) != "" {
+		t.Skip("in memory mode")
+	}
+
 	assert.True(t, lungoStore.Lungo())
 	assert.False(t, mongoStore.Lungo())
 }
@@ -140,6 +152,209 @@ func TestStoreT(t *testing.T) {
 	})
 }
 
+func TestStorePreference(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.Equal(t, Preference{}, GetPreference(nil))
+
+		pref := Preference{
+			ReadPreference: readpref.SecondaryPreferred(),
+			WriteConcern:   writeconcern.Majority(),
+		}
+
+		ctx := WithPreference(nil, pref)
+		assert.Equal(t, pref, GetPreference(ctx))
+
+		tester.Insert(&postModel{})
+
+		// writes
+		assert.NoError(t, tester.Store.T(WithPreference(nil, Preference{
+			WriteConcern: writeconcern.Majority(),
+		}), false, func(tc context.Context) error {
+			ok, tx := GetTransaction(tc)
+			assert.True(t, ok)
+			assert.False(t, tx.Snapshot)
+
+			return tester.Store.M(&postModel{}).Insert(tc, &postModel{})
+		}))
+
+		// reads
+		assert.NoError(t, tester.Store.T(ctx, true, func(tc context.Context) error {
+			ok, tx := GetTransaction(tc)
+			assert.True(t, ok)
+			assert.True(t, tx.ReadOnly)
+			assert.Equal(t, !tester.Store.Lungo(), tx.Snapshot)
+
+			n, err := tester.Store.M(&postModel{}).Count(tc, bson.M{}, 0, 0, false)
+			assert.Equal(t, int64(2), n)
+
+			return err
+		}))
+
+		// plain
+		n, err := tester.Store.C(&postModel{}).CountDocuments(ctx, bson.M{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+	})
+}
+
+func TestStoreSnapshot(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Insert(&postModel{})
+		tester.Insert(&commentModel{})
+
+		assert.NoError(t, tester.Store.Snapshot(nil, func(ctx context.Context) error {
+			ok, tx := GetTransaction(ctx)
+			assert.True(t, ok)
+			assert.True(t, tx.ReadOnly)
+			assert.Equal(t, !tester.Store.Lungo(), tx.Snapshot)
+
+			n, err := tester.Store.M(&postModel{}).Count(ctx, bson.M{}, 0, 0, false)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+
+			// concurrent writes are not observed
+			if tx.Snapshot {
+				tester.Insert(&postModel{})
+				tester.Insert(&commentModel{})
+			}
+
+			n, err = tester.Store.M(&commentModel{}).Count(ctx, bson.M{}, 0, 0, false)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+
+			n, err = tester.Store.M(&postModel{}).Count(ctx, bson.M{}, 0, 0, false)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), n)
+
+			return nil
+		}))
+
+		// nested
+		assert.NoError(t, tester.Store.T(nil, false, func(tc context.Context) error {
+			return tester.Store.Snapshot(tc, func(ctx context.Context) error {
+				assert.Equal(t, tc, ctx)
+				return nil
+			})
+		}))
+
+		// errors
+		err := tester.Store.Snapshot(nil, func(ctx context.Context) error {
+			return io.EOF
+		})
+		assert.True(t, errors.Is(err, io.EOF))
+	})
+}
+
+func TestStoreRoute(t *testing.T) {
+	store := MustOpen(nil, "main", xo.Crash)
+	other := MustOpen(nil, "analytics", xo.Crash)
+
+	coll := store.C(&postModel{})
+	store.Route(other, "", &postModel{})
+	store.Route(nil, "archive", &noteModel{})
+	assert.NotSame(t, coll, store.C(&postModel{}))
+
+	assert.Equal(t, other, store.Target(&postModel{}))
+	assert.Equal(t, store, store.Target(&noteModel{}))
+	assert.Equal(t, store, store.Target(&commentModel{}))
+
+	store.Route(other, "reports", &commentModel{})
+	assert.Equal(t, "reports", store.Target(&commentModel{}).C(&commentModel{}).Native().Database().Name())
+	assert.Equal(t, "reports", store.C(&commentModel{}).Native().Database().Name())
+
+	post := &postModel{Base: B(), Title: "foo"}
+	err := store.M(&postModel{}).Insert(nil, post)
+	assert.NoError(t, err)
+
+	note := &noteModel{Base: B(), Title: "bar"}
+	err = store.M(&noteModel{}).Insert(nil, note)
+	assert.NoError(t, err)
+
+	n, err := other.DB().Collection("posts").CountDocuments(nil, bson.M{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = store.DB().Collection("posts").CountDocuments(nil, bson.M{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	n, err = store.Client().Database("archive").Collection("notes").CountDocuments(nil, bson.M{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// same cluster transactions
+	err = store.T(nil, false, func(ctx context.Context) error {
+		var list []noteModel
+		err := store.M(&noteModel{}).FindAll(ctx, &list, bson.M{}, nil, 0, 0, false)
+		assert.Len(t, list, 1)
+		return err
+	})
+	assert.NoError(t, err)
+
+	// cross cluster transactions
+	err = store.T(nil, false, func(ctx context.Context) error {
+		return store.M(&postModel{}).Insert(ctx, &postModel{Base: B()})
+	})
+	assert.True(t, ErrCrossClusterTransaction.Is(err))
+
+	// target transactions
+	err = store.Target(&postModel{}).T(nil, false, func(ctx context.Context) error {
+		return store.M(&postModel{}).Insert(ctx, &postModel{Base: B()})
+	})
+	assert.NoError(t, err)
+
+	n, err = store.M(&postModel{}).Count(nil, bson.M{}, 0, 0, false, NoTransaction)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestStoreCausal(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.False(t, IsCausal(nil))
+		assert.False(t, IsCausal(context.Background()))
+
+		ctx := WithPreference(nil, Preference{
+			ReadPreference: readpref.SecondaryPreferred(),
+		})
+
+		assert.NoError(t, tester.Store.Causal(ctx, func(ctx context.Context) error {
+			assert.True(t, IsCausal(ctx))
+			assert.False(t, HasTransaction(ctx))
+
+			// reads observe preceding writes
+			post := &postModel{Title: "foo"}
+			err := tester.Store.M(post).Insert(ctx, post)
+			assert.NoError(t, err)
+
+			var found postModel
+			ok, err := tester.Store.M(post).Find(ctx, &found, post.ID(), false)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, "foo", found.Title)
+
+			// nested
+			return tester.Store.Causal(ctx, func(nc context.Context) error {
+				assert.Equal(t, ctx, nc)
+				return nil
+			})
+		}))
+
+		// nested in transaction
+		assert.NoError(t, tester.Store.T(nil, false, func(tc context.Context) error {
+			return tester.Store.Causal(tc, func(ctx context.Context) error {
+				assert.Equal(t, tc, ctx)
+				return nil
+			})
+		}))
+
+		// errors
+		err := tester.Store.Causal(nil, func(ctx context.Context) error {
+			return io.EOF
+		})
+		assert.True(t, errors.Is(err, io.EOF))
+	})
+}
+
 func TestStoreRT(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		if tester.Store.Lungo() {
@@ -191,3 +406,144 @@ func TestStoreRT(t *testing.T) {
 		assert.Equal(t, "foo-bar-bar-bar-bar-bar", post.Title)
 	})
 }
+
+func TestStoreWithTransaction(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		err := tester.Store.WithTransaction(nil, func(ctx context.Context) error {
+			ok, tx := GetTransaction(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, tester.Store, tx.Store)
+			assert.False(t, tx.ReadOnly)
+
+			err := tester.Store.M(&postModel{}).Insert(ctx, &postModel{Title: "foo"})
+			if err != nil {
+				return err
+			}
+
+			return tester.Store.WithTransaction(ctx, func(nested context.Context) error {
+				assert.Equal(t, ctx, nested)
+				return tester.Store.M(&postModel{}).Insert(nested, &postModel{Title: "bar"})
+			})
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, tester.Count(&postModel{}))
+
+		err = tester.Store.WithTransaction(nil, func(ctx context.Context) error {
+			err := tester.Store.M(&postModel{}).Insert(ctx, &postModel{Title: "baz"})
+			if err != nil {
+				return err
+			}
+
+			return io.EOF
+		})
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, io.EOF))
+		assert.Equal(t, 2, tester.Count(&postModel{}))
+
+		if tester.Store.Lungo() {
+			return
+		}
+
+		post := tester.Insert(&postModel{
+			Title: "foo",
+		}).(*postModel)
+
+		var mutex sync.Mutex
+		var attempts int
+		var wg sync.WaitGroup
+		wg.Add(5)
+		for i := 0; i < 5; i++ {
+			go func() {
+				defer wg.Done()
+				err := tester.Store.WithTransaction(nil, func(ctx context.Context) error {
+					mutex.Lock()
+					attempts++
+					mutex.Unlock()
+
+					var p postModel
+					_, err := tester.Store.M(&p).Find(ctx, &p, post.ID(), false)
+					if err != nil {
+						return err
+					}
+
+					_, err = tester.Store.M(post).Update(ctx, post, post.ID(), bson.M{
+						"$set": bson.M{
+							"Title": p.Title + "-bar",
+						},
+					}, false)
+					return err
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.True(t, attempts >= 5)
+
+		tester.Refresh(post)
+		assert.Equal(t, "foo-bar-bar-bar-bar-bar", post.Title)
+	})
+}
+
+func TestStoreIterate(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		for i := 0; i < 5; i++ {
+			tester.Insert(&postModel{
+				Title:     "foo",
+				Published: i%2 == 0,
+			})
+		}
+
+		var sizes []int
+		var titles []string
+		err := tester.Store.Iterate(nil, &postModel{}, bson.M{}, 2, func(batch []Model) error {
+			sizes = append(sizes, len(batch))
+			for _, model := range batch {
+				titles = append(titles, model.(*postModel).Title)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 2, 1}, sizes)
+		assert.Equal(t, []string{"foo", "foo", "foo", "foo", "foo"}, titles)
+
+		sizes = nil
+		err = tester.Store.Iterate(nil, &postModel{}, bson.M{
+			"Published": true,
+		}, 0, func(batch []Model) error {
+			sizes = append(sizes, len(batch))
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3}, sizes)
+
+		sizes = nil
+		err = tester.Store.Iterate(nil, &postModel{}, bson.M{}, 2, func(batch []Model) error {
+			sizes = append(sizes, len(batch))
+			return ErrStop.Wrap()
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2}, sizes)
+
+		err = tester.Store.Iterate(nil, &postModel{}, bson.M{}, 2, func(batch []Model) error {
+			return io.EOF
+		})
+		assert.True(t, errors.Is(err, io.EOF))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		sizes = nil
+		err = tester.Store.Iterate(ctx, &postModel{}, bson.M{}, 2, func(batch []Model) error {
+			sizes = append(sizes, len(batch))
+			cancel()
+			return nil
+		})
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, []int{2}, sizes)
+
+		err = tester.Store.Iterate(nil, &postModel{}, bson.M{
+			"Foo": true,
+		}, 2, func(batch []Model) error {
+			return nil
+		})
+		assert.Error(t, err)
+	})
+}
@@ -1,8 +1,12 @@
 package coal
 
 import (
+	"context"
+	"sync"
+
 	"github.com/256dpi/xo"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"gopkg.in/tomb.v2"
@@ -11,6 +15,10 @@ import (
 // ErrStop may be returned by a receiver to stop the stream.
 var ErrStop = xo.BF("stop")
 
+// ErrStreamClosed is returned by Await if the stream has been closed before the
+// awaited event has been processed.
+var ErrStreamClosed = xo.BF("stream closed")
+
 // ErrInvalidated may be returned to the receiver if the underlying collection
 // or database has been invalidated due to a drop or rename.
 var ErrInvalidated = xo.BF("invalidated")
@@ -36,6 +44,10 @@ const (
 	// Deleted is emitted when a document has been deleted.
 	Deleted Event = "deleted"
 
+	// Changed is emitted by batched streams with a batch of created, updated
+	// and deleted changes.
+	Changed Event = "changed"
+
 	// Errored is emitted when the underlying stream or the receiver returned an
 	// error.
 	Errored Event = "errored"
@@ -55,8 +67,27 @@ type Stream struct {
 	token    []byte
 	receiver Receiver
 
-	opened bool
-	tomb   tomb.Tomb
+	preImages bool
+	startAt   *primitive.Timestamp
+	current   *change
+	batching  *Batching
+	batches   BatchReceiver
+
+	opened  bool
+	tomb    tomb.Tomb
+	mutex   sync.Mutex
+	waiters map[*waiter]struct{}
+	recent  [2]map[ID]primitive.Timestamp
+}
+
+// the number of document IDs for which the cluster time of the last processed
+// event is remembered per generation to resolve late waiters
+const streamHistory = 1000
+
+type waiter struct {
+	id    ID
+	after primitive.Timestamp
+	done  chan struct{}
 }
 
 // OpenStream will open a stream and continuously forward events to the specified
@@ -68,17 +99,84 @@ type Stream struct {
 // and reopen the stream manually to resume from a specific position.
 func OpenStream(store *Store, model Model, token []byte, receiver Receiver) *Stream {
 	// create stream
-	s := &Stream{
+	s := newStream(store, model, token, receiver)
+
+	// open stream
+	s.tomb.Go(s.open)
+
+	return s
+}
+
+func newStream(store *Store, model Model, token []byte, receiver Receiver) *Stream {
+	return &Stream{
 		store:    store,
 		model:    model,
 		token:    token,
 		receiver: receiver,
+		waiters:  map[*waiter]struct{}{},
+		recent:   [2]map[ID]primitive.Timestamp{{}, {}},
 	}
+}
 
-	// open stream
-	s.tomb.Go(s.open)
+// Await will block until the stream has processed an event for the document
+// with the specified ID that occurred at or after the provided cluster time.
+// Together with Store.ClusterTime it allows waiting until a write performed
+// by the caller has been handled by the receiver:
+//
+//	now, _ := store.ClusterTime(ctx)
+//	_ = store.M(&Post{}).Insert(ctx, post)
+//	_ = stream.Await(ctx, post.ID(), now)
+//
+// Await returns immediately if a matching event has recently been processed.
+// ErrStreamClosed is returned if the stream is closed before the event has
+// been processed.
+func (s *Stream) Await(ctx context.Context, id ID, after primitive.Timestamp) error {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
-	return s
+	// create waiter
+	w := &waiter{
+		id:    id,
+		after: after,
+		done:  make(chan struct{}),
+	}
+
+	// check if closed
+	select {
+	case <-s.tomb.Dead():
+		return ErrStreamClosed.Wrap()
+	default:
+	}
+
+	// check recently processed events and add waiter
+	s.mutex.Lock()
+	for _, recent := range s.recent {
+		if clusterTime, ok := recent[id]; ok && clusterTime.Compare(after) >= 0 {
+			s.mutex.Unlock()
+			return nil
+		}
+	}
+	s.waiters[w] = struct{}{}
+	s.mutex.Unlock()
+
+	// ensure waiter is removed
+	defer func() {
+		s.mutex.Lock()
+		delete(s.waiters, w)
+		s.mutex.Unlock()
+	}()
+
+	// await event
+	select {
+	case <-w.done:
+		return nil
+	case <-s.tomb.Dead():
+		return ErrStreamClosed.Wrap()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Close will close the stream.
@@ -116,6 +214,18 @@ func (s *Stream) tail() error {
 	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
 	if s.token != nil {
 		opts.SetResumeAfter(bson.Raw(s.token))
+	} else if s.startAt != nil {
+		opts.SetStartAtOperationTime(s.startAt)
+	}
+
+	// limit wait time if batched
+	if s.batching != nil {
+		opts.SetMaxAwaitTime(s.batching.MaxDelay)
+	}
+
+	// request pre-images if supported
+	if s.preImages && s.store.Supports(PreImages) {
+		opts.SetFullDocumentBeforeChange(options.WhenAvailable)
 	}
 
 	// get collection
@@ -148,6 +258,11 @@ func (s *Stream) tail() error {
 	// set flag
 	s.opened = true
 
+	// consume batched if configured
+	if s.batching != nil {
+		return s.consume(ctx, cs)
+	}
+
 	// iterate on elements forever
 	for cs.Next(ctx) {
 		// decode result
@@ -157,53 +272,36 @@ func (s *Stream) tail() error {
 			return xo.W(err)
 		}
 
-		// prepare type
-		var event Event
-		switch ch.OperationType {
-		case "insert":
-			event = Created
-		case "replace", "update":
-			event = Updated
-		case "delete":
-			event = Deleted
-		case "drop", "renamed", "dropDatabase", "invalidate":
-			return ErrInvalidated.Wrap()
+		// decode change
+		event, doc, skip, err := s.decode(&ch)
+		if err != nil {
+			return err
 		}
 
-		// unmarshal document for created and updated events
-		var doc Model
-		if event == Created || event == Updated {
-			// determined if just locked
-			locked := event == Updated &&
-				len(ch.UpdateDescription.RemovedFields) == 0 &&
-				len(ch.UpdateDescription.UpdatedFields) == 1 &&
-				ch.UpdateDescription.UpdatedFields["_lk"] != nil
-
-			// continue if document hast just been locked or is unavailable due
-			// to a following a delete or drop event
-			if locked || len(ch.FullDocument) == 0 {
-				// save token
-				s.token = ch.ResumeToken
-
-				continue
-			}
+		// continue if skipped
+		if skip {
+			// save token
+			s.token = ch.ResumeToken
 
-			// decode document
-			doc = GetMeta(s.model).Make()
-			err = bson.Unmarshal(ch.FullDocument, doc)
-			if err != nil {
-				return xo.W(err)
-			}
+			// release waiters
+			s.release(ch.DocumentKey.ID, ch.ClusterTime)
+
+			continue
 		}
 
 		// call receiver
+		s.current = &ch
 		err = s.receiver(event, ch.DocumentKey.ID, doc, nil, ch.ResumeToken)
+		s.current = nil
 		if err != nil {
 			return xo.W(err)
 		}
 
 		// save token
 		s.token = ch.ResumeToken
+
+		// release waiters
+		s.release(ch.DocumentKey.ID, ch.ClusterTime)
 	}
 
 	// close stream and check error
@@ -215,14 +313,80 @@ func (s *Stream) tail() error {
 	return nil
 }
 
+func (s *Stream) decode(ch *change) (Event, Model, bool, error) {
+	// prepare type
+	var event Event
+	switch ch.OperationType {
+	case "insert":
+		event = Created
+	case "replace", "update":
+		event = Updated
+	case "delete":
+		event = Deleted
+	case "drop", "renamed", "dropDatabase", "invalidate":
+		return "", nil, false, ErrInvalidated.Wrap()
+	}
+
+	// return other events directly
+	if event != Created && event != Updated {
+		return event, nil, false, nil
+	}
+
+	// determined if just locked
+	locked := event == Updated &&
+		len(ch.UpdateDescription.RemovedFields) == 0 &&
+		len(ch.UpdateDescription.UpdatedFields) == 1 &&
+		ch.UpdateDescription.UpdatedFields["_lk"] != nil
+
+	// skip if document hast just been locked or is unavailable due to a
+	// following a delete or drop event
+	if locked || len(ch.FullDocument) == 0 {
+		return "", nil, true, nil
+	}
+
+	// decode document
+	doc := GetMeta(s.model).Make()
+	err := bson.Unmarshal(ch.FullDocument, doc)
+	if err != nil {
+		return "", nil, false, xo.W(err)
+	}
+
+	return event, doc, false, nil
+}
+
+func (s *Stream) release(id ID, clusterTime primitive.Timestamp) {
+	// acquire mutex
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// rotate history if full
+	if len(s.recent[0]) >= streamHistory {
+		s.recent[1] = s.recent[0]
+		s.recent[0] = map[ID]primitive.Timestamp{}
+	}
+
+	// record cluster time
+	s.recent[0][id] = clusterTime
+
+	// release matching waiters
+	for w := range s.waiters {
+		if w.id == id && clusterTime.Compare(w.after) >= 0 {
+			close(w.done)
+			delete(s.waiters, w)
+		}
+	}
+}
+
 type change struct {
-	ResumeToken   bson.Raw `bson:"_id"`
-	OperationType string   `bson:"operationType"`
+	ResumeToken   bson.Raw            `bson:"_id"`
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
 	DocumentKey   struct {
 		ID ID `bson:"_id"`
 	} `bson:"documentKey"`
-	FullDocument      bson.Raw `bson:"fullDocument"`
-	UpdateDescription struct {
+	FullDocument             bson.Raw `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.Raw `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
 		UpdatedFields bson.M   `bson:"updatedFields"`
 		RemovedFields []string `bson:"removedFields"`
 	} `bson:"updateDescription"`
@@ -482,3 +482,76 @@ func TestStreamInvalidation(t *testing.T) {
 		stream.Close()
 	})
 }
+
+func TestStreamAwait(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		time.Sleep(100 * time.Millisecond)
+
+		open := make(chan struct{})
+
+		var titles []string
+		stream := OpenStream(tester.Store, &postModel{}, nil, func(e Event, id ID, model Model, err error, token []byte) error {
+			switch e {
+			case Opened:
+				close(open)
+			case Created, Updated:
+				titles = append(titles, model.(*postModel).Title)
+			}
+
+			return nil
+		})
+
+		<-open
+
+		now, err := tester.Store.ClusterTime(nil)
+		assert.NoError(t, err)
+		assert.False(t, now.IsZero())
+
+		post := tester.Insert(&postModel{
+			Title: "foo",
+		}).(*postModel)
+
+		err = stream.Await(nil, post.ID(), now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo"}, titles)
+
+		now, err = tester.Store.ClusterTime(nil)
+		assert.NoError(t, err)
+
+		post.Title = "bar"
+		tester.Replace(post)
+
+		err = stream.Await(nil, post.ID(), now)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo", "bar"}, titles)
+
+		now, err = tester.Store.ClusterTime(nil)
+		assert.NoError(t, err)
+
+		post.Title = "baz"
+		tester.Replace(post)
+
+		assert.Eventually(t, func() bool {
+			stream.mutex.Lock()
+			defer stream.mutex.Unlock()
+			return stream.recent[0][post.ID()].Compare(now) >= 0
+		}, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err = stream.Await(ctx, post.ID(), now)
+		assert.NoError(t, err)
+
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err = stream.Await(ctx, New(), now)
+		assert.Equal(t, context.DeadlineExceeded, err)
+
+		stream.Close()
+
+		err = stream.Await(nil, post.ID(), now)
+		assert.True(t, ErrStreamClosed.Is(err))
+	})
+}
@@ -0,0 +1,67 @@
+package coal
+
+import (
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TenantFlag is the flag used to mark the field of a model that stores the
+// tenant key. If present, tenancy is enabled for the model and the field is
+// automatically prepended to all registered indexes and unique constraints:
+//
+//	Tenant ID `json:"-" bson:"tenant_id" coal:"tenant:tenants,coal-tenant"`
+const TenantFlag = "coal-tenant"
+
+// ErrUnscopedQuery is returned by managers in tenancy debug mode if a query
+// on a model with tenancy enabled does not filter by the tenant key.
+var ErrUnscopedQuery = xo.BF("query is not scoped by tenant")
+
+// TenantField returns the tenant field of the specified model or nil if
+// tenancy is not enabled. It will panic if multiple fields have been flagged.
+func TenantField(model Model) *Field {
+	// lookup fields
+	fields := GetMeta(model).FlaggedFields[TenantFlag]
+	if len(fields) > 1 {
+		panic(`coal: multiple fields flagged as "` + TenantFlag + `" on "` + GetMeta(model).Name + `"`)
+	} else if len(fields) == 0 {
+		return nil
+	}
+
+	return fields[0]
+}
+
+func (m *Manager) filter(filter bson.M) (bson.D, error) {
+	// translate filter
+	filterDoc, err := m.trans.Document(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// verify tenancy in debug mode
+	if m.store.TenancyDebug && m.tenant != nil && !scopedByTenant(filterDoc, m.tenant.BSONKey) {
+		return nil, ErrUnscopedQuery.WrapF("missing %q in %s filter", m.tenant.Name, m.meta.Name)
+	}
+
+	return filterDoc, nil
+}
+
+func scopedByTenant(filter bson.D, key string) bool {
+	for _, elem := range filter {
+		// check key
+		if elem.Key == key {
+			return true
+		}
+
+		// check conjunctions
+		if elem.Key == "$and" {
+			list, _ := elem.Value.(bson.A)
+			for _, item := range list {
+				if doc, ok := item.(bson.D); ok && scopedByTenant(doc, key) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
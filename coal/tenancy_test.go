@@ -0,0 +1,104 @@
+package coal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTenantField(t *testing.T) {
+	assert.Nil(t, TenantField(&postModel{}))
+	assert.Equal(t, "Tenant", TenantField(&tenantModel{}).Name)
+}
+
+func TestTenantIndex(t *testing.T) {
+	oldMeta := GetMeta(&tenantModel{})
+	delete(metaCache, oldMeta.Type)
+	defer func() {
+		metaCache[oldMeta.Type] = oldMeta
+	}()
+
+	AddIndex(&tenantModel{}, true, 0, "-Name")
+	AddIndex(&tenantModel{}, false, 0, "Name", "-Tenant")
+	AddIndex(&tenantModel{}, false, time.Hour, "Name")
+	assert.Equal(t, []Index{
+		{
+			Keys: bson.D{
+				{Key: "_tg.$**", Value: 1},
+			},
+		},
+		{
+			Fields: []string{"Tenant", "Name"},
+			Keys: bson.D{
+				{Key: "tenant_id", Value: int32(1)},
+				{Key: "name", Value: int32(-1)},
+			},
+			Unique: true,
+		},
+		{
+			Fields: []string{"Name", "Tenant"},
+			Keys: bson.D{
+				{Key: "name", Value: int32(1)},
+				{Key: "tenant_id", Value: int32(-1)},
+			},
+		},
+		{
+			Fields: []string{"Name"},
+			Keys: bson.D{
+				{Key: "name", Value: int32(1)},
+			},
+			Expiry: time.Hour,
+		},
+	}, GetMeta(&tenantModel{}).Indexes)
+}
+
+func TestTenancyDebug(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Store.TenancyDebug = true
+		defer func() {
+			tester.Store.TenancyDebug = false
+		}()
+
+		tenant := New()
+		model := tester.Insert(&tenantModel{
+			Name:   "foo",
+			Tenant: tenant,
+		}).(*tenantModel)
+
+		var list []tenantModel
+		err := tester.Store.M(&tenantModel{}).FindAll(nil, &list, bson.M{
+			"Name": "foo",
+		}, nil, 0, 0, false, NoTransaction)
+		assert.Error(t, err)
+		assert.True(t, ErrUnscopedQuery.Is(err))
+
+		err = tester.Store.M(&tenantModel{}).FindAll(nil, &list, bson.M{
+			"Tenant": tenant,
+			"Name":   "foo",
+		}, nil, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+		assert.Len(t, list, 1)
+
+		n, err := tester.Store.M(&tenantModel{}).Count(nil, bson.M{
+			"$and": []bson.M{
+				{"Tenant": tenant},
+				{"Name": "foo"},
+			},
+		}, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+
+		_, err = tester.Store.M(&tenantModel{}).DeleteAll(nil, bson.M{})
+		assert.True(t, ErrUnscopedQuery.Is(err))
+
+		found, err := tester.Store.M(&tenantModel{}).Find(nil, &tenantModel{}, model.ID(), false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		n, err = tester.Store.M(&postModel{}).Count(nil, bson.M{}, 0, 0, false, NoTransaction)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+	})
+}
@@ -2,11 +2,38 @@ package coal
 
 import (
 	"context"
+	"net/url"
+	"os"
+	"strings"
 
 	"github.com/256dpi/xo"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// MemoryEnv is the environment variable that may be set to a non-empty value
+// to replace the stores returned by MustTestStore with in-memory stores.
+const MemoryEnv = "FIRE_MEMORY"
+
+// MustTestStore will connect to the MongoDB database specified by the URI and
+// panic on errors. If the MemoryEnv environment variable is set, an in-memory
+// store using the database name of the URI is returned instead. This allows
+// running the majority of the tests of controllers, callbacks and jobs without
+// a running MongoDB.
+func MustTestStore(uri string, reporter func(error)) *Store {
+	// connect store if not in memory mode
+	if os.Getenv(MemoryEnv) == "" {
+		return MustConnect(uri, reporter)
+	}
+
+	// parse url
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		panic(err)
+	}
+
+	return MustOpen(nil, strings.Trim(parsedURL.Path, "/"), reporter)
+}
+
 // Tester provides facilities to work with coal models in tests.
 type Tester struct {
 	// The store to use for cleaning the database.
@@ -155,6 +182,17 @@ func (t *Tester) Insert(model Model) Model {
 	return model
 }
 
+// Seed will apply the specified seeds without recording them.
+func (t *Tester) Seed(seeds ...Seed) {
+	// apply seeds
+	for _, seed := range seeds {
+		_, err := ApplySeed(context.Background(), t.Store, seed)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
 // Replace will replace the specified model.
 func (t *Tester) Replace(model Model) Model {
 	// replace model
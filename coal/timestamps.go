@@ -0,0 +1,120 @@
+package coal
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/256dpi/lungo/bsonkit"
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// CreatedFlag is the flag used to mark a time.Time or *time.Time field that is
+// automatically set by managers when a document is inserted. Missing created
+// timestamps are retroactively set during replaces using the timestamp
+// encoded in the document ID.
+const CreatedFlag = "coal-created"
+
+// UpdatedFlag is the flag used to mark a time.Time or *time.Time field that is
+// automatically set by managers when a document is inserted, replaced or
+// updated.
+const UpdatedFlag = "coal-updated"
+
+var timeType = reflect.TypeOf(time.Time{})
+var optTimeType = reflect.TypeOf(&time.Time{})
+
+// Timestamps returns the created and updated timestamp fields of the specified
+// model. It will panic if multiple fields have been flagged or a flagged field
+// is not a time.Time or *time.Time.
+func Timestamps(model Model) (created, updated *Field) {
+	return timestampField(model, CreatedFlag), timestampField(model, UpdatedFlag)
+}
+
+func timestampField(model Model, flag string) *Field {
+	// lookup fields
+	fields := GetMeta(model).FlaggedFields[flag]
+	if len(fields) > 1 {
+		panic(`coal: multiple fields flagged as "` + flag + `" on "` + GetMeta(model).Name + `"`)
+	} else if len(fields) == 0 {
+		return nil
+	}
+
+	// check type
+	if fields[0].Type != timeType && fields[0].Type != optTimeType {
+		panic(`coal: expected field "` + fields[0].Name + `" flagged as "` + flag + `" to be a time.Time or *time.Time`)
+	}
+
+	return fields[0]
+}
+
+func (m *Manager) stampInsert(model Model, now time.Time) {
+	// set missing created timestamp
+	if m.created != nil && getTime(model, m.created).IsZero() {
+		setTime(model, m.created, now)
+	}
+
+	// set updated timestamp
+	if m.updated != nil {
+		setTime(model, m.updated, now)
+	}
+}
+
+func (m *Manager) stampReplace(model Model, now time.Time) {
+	// set missing created timestamp from ID
+	if m.created != nil && getTime(model, m.created).IsZero() {
+		if model.ID().IsZero() {
+			setTime(model, m.created, now)
+		} else {
+			setTime(model, m.created, model.ID().Timestamp())
+		}
+	}
+
+	// set updated timestamp
+	if m.updated != nil {
+		setTime(model, m.updated, now)
+	}
+}
+
+func (m *Manager) stampUpdate(updateDoc *bson.D, upsert bool, now time.Time) error {
+	// set updated timestamp if not set explicitly
+	if m.updated != nil && bsonkit.Get(updateDoc, "$set."+m.updated.BSONKey) == bsonkit.Missing {
+		_, err := bsonkit.Put(updateDoc, "$set."+m.updated.BSONKey, now, false)
+		if err != nil {
+			return xo.WF(err, "unable to add updated timestamp")
+		}
+	}
+
+	// set created timestamp on insert if not set explicitly
+	if upsert && m.created != nil && bsonkit.Get(updateDoc, "$set."+m.created.BSONKey) == bsonkit.Missing &&
+		bsonkit.Get(updateDoc, "$setOnInsert."+m.created.BSONKey) == bsonkit.Missing {
+		_, err := bsonkit.Put(updateDoc, "$setOnInsert."+m.created.BSONKey, now, false)
+		if err != nil {
+			return xo.WF(err, "unable to add created timestamp")
+		}
+	}
+
+	return nil
+}
+
+func getTime(model Model, field *Field) time.Time {
+	// get value
+	value := stick.MustGet(model, field.Name)
+	if field.Optional {
+		if t := value.(*time.Time); t != nil {
+			return *t
+		}
+		return time.Time{}
+	}
+
+	return value.(time.Time)
+}
+
+func setTime(model Model, field *Field, t time.Time) {
+	if field.Optional {
+		stick.MustSet(model, field.Name, &t)
+	} else {
+		stick.MustSet(model, field.Name, t)
+	}
+}
@@ -0,0 +1,101 @@
+package coal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTimestamps(t *testing.T) {
+	created, updated := Timestamps(&stampModel{})
+	assert.Equal(t, "Created", created.Name)
+	assert.Equal(t, "Updated", updated.Name)
+
+	created, updated = Timestamps(&postModel{})
+	assert.Nil(t, created)
+	assert.Nil(t, updated)
+}
+
+func TestManagerTimestamps(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		m := tester.Store.M(&stampModel{})
+
+		// insert
+		before := time.Now()
+		model := &stampModel{Title: "foo"}
+		err := m.Insert(nil, model)
+		assert.NoError(t, err)
+		assert.False(t, model.Created.Before(before))
+		assert.NotNil(t, model.Updated)
+		assert.Equal(t, model.Created, *model.Updated)
+
+		// keep provided created timestamp
+		past := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+		other := &stampModel{Title: "bar", Created: past}
+		err = m.Insert(nil, other)
+		assert.NoError(t, err)
+		assert.Equal(t, past, other.Created)
+		assert.True(t, other.Updated.After(past))
+
+		// update
+		before = time.Now()
+		var result stampModel
+		found, err := m.Update(nil, &result, model.ID(), bson.M{
+			"$set": bson.M{
+				"Title": "baz",
+			},
+		}, false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.False(t, result.Updated.Before(before.Truncate(time.Millisecond)))
+		assert.True(t, result.Created.Before(*result.Updated) || result.Created.Equal(*result.Updated))
+
+		// update with explicit timestamp
+		found, err = m.Update(nil, &result, model.ID(), bson.M{
+			"$set": bson.M{
+				"Updated": past,
+			},
+		}, false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, past, result.Updated.Local())
+
+		// replace with missing created timestamp
+		result.Created = time.Time{}
+		result.Updated = nil
+		found, err = m.Replace(nil, &result, false)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, result.ID().Timestamp(), result.Created)
+		assert.NotNil(t, result.Updated)
+
+		// upsert
+		var upserted stampModel
+		inserted, err := m.Upsert(nil, &upserted, bson.M{
+			"Title": "qux",
+		}, bson.M{
+			"$set": bson.M{
+				"Title": "qux",
+			},
+		}, nil, false)
+		assert.NoError(t, err)
+		assert.True(t, inserted)
+		assert.False(t, upserted.Created.IsZero())
+		assert.NotNil(t, upserted.Updated)
+
+		// update all
+		n, err := m.UpdateAll(nil, bson.M{}, bson.M{
+			"$set": bson.M{
+				"Title": "all",
+			},
+		}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+
+		stored := tester.Fetch(&stampModel{}, other.ID()).(*stampModel)
+		assert.Equal(t, past, stored.Created.Local())
+		assert.True(t, stored.Updated.After(past))
+	})
+}
@@ -190,6 +190,7 @@ func (t *Translator) field(path *string) error {
 
 	// handle other fields
 	meta := &structField.ItemField
+	keyed := false
 	for i, field := range fields[1:] {
 		// handle slice index
 		_, ok := bsonkit.ParseIndex(field)
@@ -197,13 +198,36 @@ func (t *Translator) field(path *string) error {
 			continue
 		}
 
+		// handle map key
+		if meta.MapValue != nil && !keyed {
+			// check key
+			if field == "" || strings.HasPrefix(field, "$") {
+				return xo.F("invalid map key in %q", *path)
+			}
+
+			// keep remaining fields if values are not items or structs
+			if meta.ItemMeta == nil && meta.Nested == nil {
+				break
+			}
+
+			keyed = true
+			continue
+		}
+		keyed = false
+
+		// get item or nested meta
+		sub := meta.ItemMeta
+		if sub == nil {
+			sub = meta.Nested
+		}
+
 		// check meta
-		if meta == nil || meta.ItemMeta == nil {
+		if sub == nil {
 			return xo.F("unknown field %q", *path)
 		}
 
 		// check field
-		itemField := meta.ItemMeta.Fields[field]
+		itemField := sub.Fields[field]
 		if itemField == nil {
 			return xo.F("unknown field %q", *path)
 		} else if itemField.BSONKey == "" {
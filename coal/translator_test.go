@@ -1,6 +1,7 @@
 package coal
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -291,6 +292,96 @@ func TestTranslatorItem(t *testing.T) {
 	}, doc)
 }
 
+func TestTranslatorMap(t *testing.T) {
+	trans := NewTranslator(&mapModel{})
+
+	doc, err := trans.Document(bson.M{
+		"Labels.color":    "red",
+		"Items.foo.Title": "Hello World!",
+		"Data.foo.bar":    true,
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, bson.D{
+		{Key: "labels.color", Value: "red"},
+		{Key: "items.foo.title", Value: "Hello World!"},
+		{Key: "data.foo.bar", Value: true},
+	}, doc)
+
+	doc, err = trans.Sort([]string{"-Labels.color"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "labels.color", Value: int32(-1)},
+	}, doc)
+
+	_, err = trans.Document(bson.M{
+		"Items.foo.Missing": "foo",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, `unknown field "Items.foo.Missing"`, err.Error())
+
+	_, err = trans.Document(bson.M{
+		"Labels.$where": "foo",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, `invalid map key in "Labels.$where"`, err.Error())
+
+	meta := GetMeta(&mapModel{})
+	assert.Equal(t, reflect.TypeOf(""), meta.Fields["Labels"].MapValue)
+	assert.Equal(t, reflect.TypeOf(listItem{}), meta.Fields["Items"].MapValue)
+	assert.NotNil(t, meta.Fields["Items"].ItemMeta)
+}
+
+func TestTranslatorNested(t *testing.T) {
+	trans := NewTranslator(&contactModel{})
+
+	doc, err := trans.Document(bson.M{
+		"Address.City":            "Berlin",
+		"Address.Location.Street": "Main",
+		"Addresses.0.City":        "Paris",
+		"Named.home.City":         "Rome",
+		"Subject.ID":              "foo",
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, bson.D{
+		{Key: "address.town", Value: "Berlin"},
+		{Key: "address.location.street", Value: "Main"},
+		{Key: "addresses.0.town", Value: "Paris"},
+		{Key: "named.home.town", Value: "Rome"},
+		{Key: "subject.id", Value: "foo"},
+	}, doc)
+
+	doc, err = trans.Sort([]string{"-Address.City", "Addresses.Street"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "address.town", Value: int32(-1)},
+		{Key: "addresses.street", Value: int32(1)},
+	}, doc)
+
+	_, err = trans.Document(bson.M{
+		"Address.Missing": "foo",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, `unknown field "Address.Missing"`, err.Error())
+
+	_, err = trans.Document(bson.M{
+		"Address.Note": "foo",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, `virtual field "Address.Note"`, err.Error())
+
+	_, err = trans.Document(bson.M{
+		"Created.Year": 2020,
+	})
+	assert.Error(t, err)
+	assert.Equal(t, `unknown field "Created.Year"`, err.Error())
+
+	meta := GetMeta(&contactModel{})
+	assert.NotNil(t, meta.Fields["Address"].Nested)
+	assert.Equal(t, meta.Fields["Address"].Nested, meta.Fields["Addresses"].Nested)
+	assert.Equal(t, meta.Fields["Address"].Nested, meta.Fields["Address"].Nested.Fields["Location"].Nested)
+	assert.Nil(t, meta.Fields["Created"].Nested)
+}
+
 func BenchmarkTranslatorDocumentSimple(b *testing.B) {
 	trans := NewTranslator(&postModel{})
 
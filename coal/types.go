@@ -0,0 +1,102 @@
+package coal
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// Type describes a custom field type e.g. a decimal backed money type, a big
+// integer or an enum. JSON encoding is performed using the json.Marshaler and
+// json.Unmarshaler implementations of the type, if available.
+type Type struct {
+	// The Go type.
+	Type reflect.Type
+
+	// The BSON type used in generated validation and encryption schemas e.g.
+	// "decimal".
+	BSONType string
+
+	// The optional BSON encoder and decoder. Both are registered with the
+	// default BSON registry using Extend.
+	Encoder bsoncodec.ValueEncoder
+	Decoder bsoncodec.ValueDecoder
+
+	// The function used to parse filter values. If missing, filters on fields
+	// of this type are matched against the raw string values.
+	Parse func(string) (interface{}, error)
+
+	// The function used to validate values by IsValidType.
+	Validate func(interface{}) error
+}
+
+var typesMutex sync.Mutex
+var types = map[reflect.Type]*Type{}
+
+// RegisterType will register the provided custom field type. Types must be
+// registered before the meta of models using them is first accessed, e.g. in
+// an init function. The function will panic if the type has already been
+// registered.
+func RegisterType(typ Type) {
+	// acquire mutex
+	typesMutex.Lock()
+	defer typesMutex.Unlock()
+
+	// check type
+	if typ.Type == nil {
+		panic("coal: missing type")
+	} else if types[typ.Type] != nil {
+		panic(fmt.Sprintf(`coal: type "%s" has already been registered`, typ.Type.String()))
+	}
+
+	// register codecs
+	if typ.Encoder != nil || typ.Decoder != nil {
+		Extend(func(builder *bsoncodec.RegistryBuilder) {
+			if typ.Encoder != nil {
+				builder.RegisterTypeEncoder(typ.Type, typ.Encoder)
+			}
+			if typ.Decoder != nil {
+				builder.RegisterTypeDecoder(typ.Type, typ.Decoder)
+			}
+		})
+	}
+
+	// store type
+	types[typ.Type] = &typ
+}
+
+// LookupType will return the registered custom field type for the provided Go
+// type. Pointers are unwrapped.
+func LookupType(typ reflect.Type) *Type {
+	// unwrap pointer
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	// acquire mutex
+	typesMutex.Lock()
+	defer typesMutex.Unlock()
+
+	return types[typ]
+}
+
+// IsValidType will check the value using the validation function of its
+// registered custom field type.
+func IsValidType(sub stick.Subject) error {
+	// unwrap
+	if !sub.Unwrap() {
+		return nil
+	}
+
+	// get type
+	typ := LookupType(sub.RValue.Type())
+	if typ == nil || typ.Validate == nil {
+		panic(fmt.Sprintf("coal: cannot check validity of %T", sub.IValue))
+	}
+
+	return typ.Validate(sub.IValue)
+}
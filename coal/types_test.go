@@ -0,0 +1,133 @@
+package coal
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/256dpi/fire/stick"
+)
+
+var bigIntType = reflect.TypeOf(big.Int{})
+
+func init() {
+	RegisterType(Type{
+		Type:     bigIntType,
+		BSONType: "decimal",
+		Encoder: bsoncodec.ValueEncoderFunc(func(_ bsoncodec.EncodeContext, w bsonrw.ValueWriter, v reflect.Value) error {
+			i := v.Interface().(big.Int)
+			pd, ok := primitive.ParseDecimal128FromBigInt(&i, 0)
+			if !ok {
+				return xo.F("unable to convert big int")
+			}
+			return w.WriteDecimal128(pd)
+		}),
+		Decoder: bsoncodec.ValueDecoderFunc(func(_ bsoncodec.DecodeContext, r bsonrw.ValueReader, v reflect.Value) error {
+			pd, err := r.ReadDecimal128()
+			if err != nil {
+				return err
+			}
+			i, _, err := pd.BigInt()
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(*i))
+			return nil
+		}),
+		Parse: func(str string) (interface{}, error) {
+			i, ok := new(big.Int).SetString(str, 10)
+			if !ok {
+				return nil, xo.F("invalid big int")
+			}
+			return i, nil
+		},
+		Validate: func(v interface{}) error {
+			i := v.(big.Int)
+			if i.Sign() < 0 {
+				return xo.SF("negative")
+			}
+			return nil
+		},
+	})
+}
+
+type counterModel struct {
+	Base    `json:"-" bson:",inline" coal:"counters"`
+	Value   big.Int  `json:"value"`
+	OptMax  *big.Int `json:"opt-max"`
+	Balance Decimal  `json:"balance"`
+}
+
+func (m *counterModel) Validate() error {
+	return stick.Validate(m, func(v *stick.Validator) {
+		v.Value("Value", false, IsValidType)
+		v.Value("OptMax", true, IsValidType)
+	})
+}
+
+func TestRegisterType(t *testing.T) {
+	assert.Equal(t, bigIntType, LookupType(bigIntType).Type)
+	assert.Equal(t, bigIntType, LookupType(reflect.TypeOf(&big.Int{})).Type)
+	assert.Equal(t, "decimal", LookupType(decimalType).BSONType)
+	assert.Nil(t, LookupType(reflect.TypeOf("")))
+
+	assert.PanicsWithValue(t, `coal: type "big.Int" has already been registered`, func() {
+		RegisterType(Type{Type: bigIntType})
+	})
+
+	assert.PanicsWithValue(t, "coal: missing type", func() {
+		RegisterType(Type{})
+	})
+}
+
+func TestTypeCoding(t *testing.T) {
+	model := &counterModel{
+		Value:  *big.NewInt(42),
+		OptMax: big.NewInt(7),
+	}
+
+	bytes, err := bson.Marshal(model)
+	assert.NoError(t, err)
+
+	var doc bson.M
+	err = bson.Unmarshal(bytes, &doc)
+	assert.NoError(t, err)
+	assert.IsType(t, primitive.Decimal128{}, doc["value"])
+	assert.IsType(t, primitive.Decimal128{}, doc["optmax"])
+
+	var out counterModel
+	err = bson.Unmarshal(bytes, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "42", out.Value.String())
+	assert.Equal(t, "7", out.OptMax.String())
+}
+
+func TestTypeSchema(t *testing.T) {
+	schema := ValidationSchema(&counterModel{})
+	properties := schema["properties"].(bson.M)
+	assert.Equal(t, bson.M{"bsonType": "decimal"}, properties["value"])
+	assert.Equal(t, bson.M{"bsonType": bson.A{"decimal", "null"}}, properties["optmax"])
+	assert.Equal(t, bson.M{"bsonType": "decimal"}, properties["balance"])
+}
+
+func TestIsValidType(t *testing.T) {
+	model := &counterModel{
+		Value: *big.NewInt(1),
+	}
+	assert.NoError(t, model.Validate())
+
+	model.OptMax = big.NewInt(-1)
+	assert.Error(t, model.Validate())
+	assert.Equal(t, "OptMax: negative", model.Validate().Error())
+
+	assert.PanicsWithValue(t, "coal: cannot check validity of string", func() {
+		_ = IsValidType(stick.Subject{IValue: "foo", RValue: reflect.ValueOf("foo")})
+	})
+}
@@ -82,6 +82,84 @@ func (m *listModel) Validate() error {
 	})
 }
 
+type mapModel struct {
+	Base   `json:"-" bson:",inline" coal:"maps"`
+	Labels map[string]string   `json:"labels"`
+	Items  map[string]listItem `json:"items"`
+	Data   stick.Map           `json:"data"`
+}
+
+func (m *mapModel) Validate() error {
+	return nil
+}
+
+type addressData struct {
+	Street   string       `json:"street"`
+	City     string       `json:"city" bson:"town"`
+	Location *addressData `json:"location"`
+	Note     string       `json:"note" bson:"-"`
+}
+
+type contactModel struct {
+	Base      `json:"-" bson:",inline" coal:"contacts"`
+	Address   addressData            `json:"address"`
+	Addresses []addressData          `json:"addresses"`
+	Named     map[string]addressData `json:"named"`
+	Subject   Ref                    `json:"subject"`
+	Created   time.Time              `json:"created"`
+}
+
+func (m *contactModel) Validate() error {
+	return nil
+}
+
+type binaryModel struct {
+	Base        `json:"-" bson:",inline" coal:"binaries"`
+	Payload     Binary             `json:"payload"`
+	Preview     *Binary            `json:"preview"`
+	Attachments []binaryAttachment `json:"attachments"`
+}
+
+type binaryAttachment struct {
+	Name string `json:"name"`
+	File Binary `json:"file"`
+}
+
+func (m *binaryModel) Validate() error {
+	return nil
+}
+
+type versionModel struct {
+	Base    `json:"-" bson:",inline" coal:"versions"`
+	Title   string `json:"title"`
+	Version int64  `json:"version" coal:"coal-version"`
+}
+
+func (m *versionModel) Validate() error {
+	return nil
+}
+
+type tenantModel struct {
+	Base   `json:"-" bson:",inline" coal:"tenants"`
+	Name   string `json:"name"`
+	Tenant ID     `json:"-" bson:"tenant_id" coal:"coal-tenant"`
+}
+
+func (m *tenantModel) Validate() error {
+	return nil
+}
+
+type stampModel struct {
+	Base    `json:"-" bson:",inline" coal:"stamps"`
+	Title   string     `json:"title"`
+	Created time.Time  `json:"created-at" bson:"created_at" coal:"coal-created"`
+	Updated *time.Time `json:"updated-at" bson:"updated_at" coal:"coal-updated"`
+}
+
+func (m *stampModel) Validate() error {
+	return nil
+}
+
 func init() {
 	AddIndex(&postModel{}, false, 0, "Published", "Title")
 	AddPartialIndex(&postModel{}, false, 0, []string{"-TextBody"}, bson.M{
@@ -89,13 +167,17 @@ func init() {
 	})
 }
 
-var mongoStore = MustConnect("mongodb://0.0.0.0/test-fire-coal", xo.Crash)
+var mongoStore = MustTestStore("mongodb://0.0.0.0/test-fire-coal", xo.Crash)
 var lungoStore = MustOpen(nil, "test-fire-coal", xo.Crash)
 
-var modelList = []Model{&postModel{}, &commentModel{}, &selectionModel{}, &noteModel{}, &fooModel{}}
+var modelList = []Model{&postModel{}, &commentModel{}, &selectionModel{}, &noteModel{}, &fooModel{}, &versionModel{}, &tenantModel{}, &Lease{}, &stampModel{}, &AppliedMigration{}, &AppliedSeed{}, &Slug{}, &Counter{}}
 
 func withTester(t *testing.T, fn func(*testing.T, *Tester)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		tester := NewTester(mongoStore, modelList...)
 		tester.Clean()
 		fn(t, tester)
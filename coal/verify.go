@@ -29,6 +29,16 @@ func Verify(models []Model, ignored ...string) error {
 				continue
 			}
 
+			// check polymorphic types
+			if field.Polymorphic {
+				for _, typ := range field.RelTypes {
+					if index[typ] == nil {
+						return xo.F("missing type %s for relationship %s", typ, key)
+					}
+				}
+				continue
+			}
+
 			// get related meta
 			relMeta := index[field.RelType]
 			if relMeta == nil {
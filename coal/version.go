@@ -0,0 +1,193 @@
+package coal
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/256dpi/lungo/bsonkit"
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/256dpi/fire/stick"
+)
+
+// VersionFlag is the flag used to mark the int64 field of a model that is
+// used for optimistic locking:
+//
+//	Version int64 `json:"version" coal:"coal-version"`
+const VersionFlag = "coal-version"
+
+// ErrVersionConflict is returned by UpdateLocked and ReplaceLocked if the
+// document has been modified since the provided version has been read.
+var ErrVersionConflict = xo.BF("version conflict")
+
+var int64Type = reflect.TypeOf(int64(0))
+
+// UpdateLocked will update the document with the specified ID if its version
+// field matches the provided version. The version is incremented as part of
+// the update. It will return whether a document has been found and
+// ErrVersionConflict if the document exists with a different version.
+func (m *Manager) UpdateLocked(ctx context.Context, model Model, id ID, version int64, update bson.M, flags ...Flags) (bool, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Manager.UpdateLocked")
+	defer span.End()
+
+	// check model
+	if model == nil {
+		model = m.meta.Make()
+	}
+
+	// check model
+	if GetMeta(model) != m.meta {
+		return false, ErrMetaMismatch.Wrap()
+	}
+
+	// get version field
+	field, err := m.versionField()
+	if err != nil {
+		return false, err
+	}
+
+	// translate update
+	updateDoc, err := m.trans.Document(update)
+	if err != nil {
+		return false, err
+	}
+
+	// increment version
+	_, err = bsonkit.Put(&updateDoc, "$inc."+field.BSONKey, int64(1), false)
+	if err != nil {
+		return false, xo.WF(err, "unable to add version")
+	}
+
+	// set timestamps
+	err = m.stampUpdate(&updateDoc, false, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	// find and update document
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = m.coll.FindOneAndUpdate(ctx, bson.M{
+		"_id":         id,
+		field.BSONKey: version,
+	}, updateDoc, opts).Decode(model)
+	if IsMissing(err) {
+		return m.checkConflict(ctx, id)
+	} else if err != nil {
+		return false, err
+	}
+
+	// clean model
+	Clean(model)
+
+	// validate updated model
+	if !Merge(flags).Has(NoValidation) {
+		err = model.Validate()
+		if err != nil {
+			return false, xo.W(err)
+		}
+	}
+
+	return true, nil
+}
+
+// ReplaceLocked will replace the existing document with the provided one if
+// the version field of the stored document matches the version of the model.
+// The version of the model is incremented before the replace and restored if
+// the replace failed. It will return whether a document has been found and
+// ErrVersionConflict if the document exists with a different version.
+func (m *Manager) ReplaceLocked(ctx context.Context, model Model, flags ...Flags) (bool, error) {
+	// trace
+	ctx, span := xo.Trace(ctx, "coal/Manager.ReplaceLocked")
+	defer span.End()
+
+	// check model
+	if GetMeta(model) != m.meta {
+		return false, ErrMetaMismatch.Wrap()
+	}
+
+	// check ID
+	if model.ID().IsZero() {
+		return false, xo.F("model has a zero ID")
+	}
+
+	// get version field
+	field, err := m.versionField()
+	if err != nil {
+		return false, err
+	}
+
+	// set timestamps
+	m.stampReplace(model, time.Now())
+
+	// validate model
+	if !Merge(flags).Has(NoValidation) {
+		err := model.Validate()
+		if err != nil {
+			return false, xo.W(err)
+		}
+	}
+
+	// clean model
+	Clean(model)
+
+	// check size
+	err = CheckSize(m.store, model)
+	if err != nil {
+		return false, err
+	}
+
+	// increment version
+	version := stick.MustGet(model, field.Name).(int64)
+	stick.MustSet(model, field.Name, version+1)
+
+	// replace document
+	res, err := m.coll.ReplaceOne(ctx, bson.M{
+		"_id":         model.ID(),
+		field.BSONKey: version,
+	}, model)
+	if err != nil {
+		stick.MustSet(model, field.Name, version)
+		return false, err
+	}
+
+	// check conflict
+	if res.MatchedCount == 0 {
+		stick.MustSet(model, field.Name, version)
+		return m.checkConflict(ctx, model.ID())
+	}
+
+	return true, nil
+}
+
+func (m *Manager) versionField() (*Field, error) {
+	// lookup field
+	fields := m.meta.FlaggedFields[VersionFlag]
+	if len(fields) != 1 {
+		return nil, xo.F(`no or multiple fields flagged as "%s"`, VersionFlag)
+	}
+
+	// check type
+	if fields[0].Type != int64Type {
+		return nil, xo.F("version field is not an int64")
+	}
+
+	return fields[0], nil
+}
+
+func (m *Manager) checkConflict(ctx context.Context, id ID) (bool, error) {
+	// count document
+	count, err := m.coll.CountDocuments(ctx, bson.M{
+		"_id": id,
+	})
+	if err != nil {
+		return false, err
+	} else if count == 0 {
+		return false, nil
+	}
+
+	return false, ErrVersionConflict.Wrap()
+}
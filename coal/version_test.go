@@ -0,0 +1,85 @@
+package coal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestManagerUpdateLocked(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		m := tester.Store.M(&versionModel{})
+
+		model := tester.Insert(&versionModel{
+			Title: "foo",
+		}).(*versionModel)
+
+		var result versionModel
+		found, err := m.UpdateLocked(nil, &result, model.ID(), 0, bson.M{
+			"$set": bson.M{
+				"Title": "bar",
+			},
+		})
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "bar", result.Title)
+		assert.Equal(t, int64(1), result.Version)
+
+		found, err = m.UpdateLocked(nil, nil, model.ID(), 0, bson.M{
+			"$set": bson.M{
+				"Title": "baz",
+			},
+		})
+		assert.Error(t, err)
+		assert.True(t, ErrVersionConflict.Is(err))
+		assert.False(t, found)
+
+		found, err = m.UpdateLocked(nil, nil, New(), 0, bson.M{
+			"$set": bson.M{
+				"Title": "baz",
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		assert.Equal(t, "bar", tester.Fetch(&versionModel{}, model.ID()).(*versionModel).Title)
+
+		_, err = tester.Store.M(&postModel{}).UpdateLocked(nil, nil, New(), 0, bson.M{})
+		assert.Error(t, err)
+		assert.Equal(t, `no or multiple fields flagged as "coal-version"`, err.Error())
+	})
+}
+
+func TestManagerReplaceLocked(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		m := tester.Store.M(&versionModel{})
+
+		model := tester.Insert(&versionModel{
+			Title: "foo",
+		}).(*versionModel)
+
+		stale := *model
+
+		model.Title = "bar"
+		found, err := m.ReplaceLocked(nil, model)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, int64(1), model.Version)
+
+		stale.Title = "baz"
+		found, err = m.ReplaceLocked(nil, &stale)
+		assert.Error(t, err)
+		assert.True(t, ErrVersionConflict.Is(err))
+		assert.False(t, found)
+		assert.Equal(t, int64(0), stale.Version)
+
+		found, err = m.ReplaceLocked(nil, &versionModel{Base: B()})
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		result := tester.Fetch(&versionModel{}, model.ID()).(*versionModel)
+		assert.Equal(t, "bar", result.Title)
+		assert.Equal(t, int64(1), result.Version)
+	})
+}
@@ -0,0 +1,107 @@
+package coal
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Virtual contains the meta information about a virtual field of a model.
+// Virtual fields are not persisted but computed from the model when it is
+// serialized.
+type Virtual struct {
+	// The method or field name e.g. "FullName".
+	Name string
+
+	// The JSON object key name e.g. "full-name".
+	JSONKey string
+
+	// The value type, if known.
+	Type reflect.Type
+
+	// The function that computes the value.
+	Compute func(Model) (interface{}, error)
+}
+
+// AddVirtual adds a virtual field with the specified JSON key that is computed
+// by calling the named method of the model. The method must not accept any
+// parameters and return a value and optionally an error.
+//
+// Note: This method panics if the method is missing, has an invalid signature
+// or the key conflicts with an existing field.
+func AddVirtual(model Model, key, method string) {
+	// get meta
+	meta := GetMeta(model)
+
+	// get method
+	fn, ok := reflect.PtrTo(meta.Type).MethodByName(method)
+	if !ok {
+		panic(fmt.Sprintf(`coal: missing virtual method "%s" for model "%s"`, method, meta.Name))
+	}
+
+	// check parameters and return values
+	if fn.Type.NumIn() != 1 || fn.Type.NumOut() < 1 || fn.Type.NumOut() > 2 {
+		panic(fmt.Sprintf(`coal: expected virtual method "%s" for model "%s" to have no parameters and one or two return values`, method, meta.Name))
+	}
+
+	// check second return value
+	if fn.Type.NumOut() == 2 && fn.Type.Out(1) != reflect.TypeOf((*error)(nil)).Elem() {
+		panic(fmt.Sprintf(`coal: expected second return value of virtual method "%s" for model "%s" to be of type error`, method, meta.Name))
+	}
+
+	// add virtual
+	addVirtual(meta, &Virtual{
+		Name:    method,
+		JSONKey: key,
+		Type:    fn.Type.Out(0),
+		Compute: func(model Model) (interface{}, error) {
+			// call method
+			out := fn.Func.Call([]reflect.Value{reflect.ValueOf(model)})
+
+			// check error
+			if len(out) == 2 {
+				err, _ := out[1].Interface().(error)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return out[0].Interface(), nil
+		},
+	})
+}
+
+// AddVirtualFunc adds a virtual field with the specified name and JSON key that
+// is computed using the provided function.
+//
+// Note: This method panics if the key conflicts with an existing field.
+func AddVirtualFunc(model Model, name, key string, fn func(Model) (interface{}, error)) {
+	addVirtual(GetMeta(model), &Virtual{
+		Name:    name,
+		JSONKey: key,
+		Compute: fn,
+	})
+}
+
+func addVirtual(meta *Meta, virtual *Virtual) {
+	// check key
+	if virtual.JSONKey == "" {
+		panic(fmt.Sprintf(`coal: missing key for virtual "%s"`, virtual.Name))
+	}
+
+	// check function
+	if virtual.Compute == nil {
+		panic(fmt.Sprintf(`coal: missing function for virtual "%s"`, virtual.Name))
+	}
+
+	// check existence
+	if meta.Attributes[virtual.JSONKey] != nil || meta.Relationships[virtual.JSONKey] != nil || meta.Virtuals[virtual.JSONKey] != nil {
+		panic(fmt.Sprintf(`coal: virtual "%s" conflicts with existing field`, virtual.JSONKey))
+	}
+
+	// add virtual
+	if meta.Virtuals == nil {
+		meta.Virtuals = map[string]*Virtual{}
+	}
+	meta.Virtuals[virtual.JSONKey] = virtual
+	meta.OrderedVirtuals = append(meta.OrderedVirtuals, virtual)
+}
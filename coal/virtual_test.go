@@ -0,0 +1,93 @@
+package coal
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire/stick"
+)
+
+type virtualModel struct {
+	Base               `json:"-" bson:",inline" coal:"virtuals"`
+	Name               string `json:"name"`
+	stick.NoValidation `json:"-" bson:"-"`
+}
+
+func (m *virtualModel) Upper() string {
+	return strings.ToUpper(m.Name)
+}
+
+func (m *virtualModel) Failing() (int, error) {
+	return 0, io.EOF
+}
+
+func (m *virtualModel) Invalid(int) string {
+	return ""
+}
+
+func init() {
+	AddVirtual(&virtualModel{}, "upper", "Upper")
+	AddVirtual(&virtualModel{}, "failing", "Failing")
+	AddVirtualFunc(&virtualModel{}, "Length", "length", func(model Model) (interface{}, error) {
+		return len(model.(*virtualModel).Name), nil
+	})
+}
+
+func TestAddVirtual(t *testing.T) {
+	meta := GetMeta(&virtualModel{})
+	assert.Len(t, meta.Virtuals, 3)
+	assert.Len(t, meta.OrderedVirtuals, 3)
+
+	upper := meta.Virtuals["upper"]
+	assert.Equal(t, "Upper", upper.Name)
+	assert.Equal(t, "upper", upper.JSONKey)
+	assert.Equal(t, reflect.TypeOf(""), upper.Type)
+
+	value, err := upper.Compute(&virtualModel{Name: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "FOO", value)
+
+	value, err = meta.Virtuals["failing"].Compute(&virtualModel{})
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, value)
+
+	length := meta.Virtuals["length"]
+	assert.Equal(t, "Length", length.Name)
+	assert.Nil(t, length.Type)
+
+	value, err = length.Compute(&virtualModel{Name: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, value)
+
+	assert.Equal(t, []*Virtual{
+		meta.Virtuals["upper"],
+		meta.Virtuals["failing"],
+		meta.Virtuals["length"],
+	}, meta.OrderedVirtuals)
+
+	assert.PanicsWithValue(t, `coal: missing virtual method "Missing" for model "coal.virtualModel"`, func() {
+		AddVirtual(&virtualModel{}, "missing", "Missing")
+	})
+
+	assert.PanicsWithValue(t, `coal: expected virtual method "Invalid" for model "coal.virtualModel" to have no parameters and one or two return values`, func() {
+		AddVirtual(&virtualModel{}, "invalid", "Invalid")
+	})
+
+	assert.PanicsWithValue(t, `coal: virtual "name" conflicts with existing field`, func() {
+		AddVirtual(&virtualModel{}, "name", "Upper")
+	})
+
+	assert.PanicsWithValue(t, `coal: virtual "upper" conflicts with existing field`, func() {
+		AddVirtual(&virtualModel{}, "upper", "Upper")
+	})
+
+	assert.Nil(t, GetMeta(&postModel{}).Virtuals)
+
+	out := VisualizeDOT("Test", &virtualModel{})
+	assert.Contains(t, out, `<i>Upper</i><font face="Arial" color="grey60"> string</font>`)
+	assert.Contains(t, out, `<i>Length</i><font face="Arial" color="grey60"> virtual</font>`)
+}
@@ -143,6 +143,15 @@ func VisualizeDOT(title string, models ...Model) string {
 			}
 		}
 
+		// write virtual fields
+		for _, virtual := range GetMeta(model).OrderedVirtuals {
+			typ := "virtual"
+			if virtual.Type != nil {
+				typ = dotEscape(strings.ReplaceAll(virtual.Type.String(), "primitive.ObjectID", "coal.ID"))
+			}
+			out.WriteString(fmt.Sprintf(`<tr><td align="left" width="130" port="%s"><i>%s</i><font face="Arial" color="grey60"> %s</font></td></tr>`, virtual.Name, virtual.Name, typ))
+		}
+
 		// write end of tail table
 		out.WriteString(`</table>>`)
 
@@ -178,6 +187,18 @@ func VisualizeDOT(title string, models ...Model) string {
 
 				relNames = append(relNames, name+"-"+field.RelName)
 			}
+
+			// add all polymorphic relationships
+			if field.Polymorphic {
+				for _, typ := range field.RelTypes {
+					list[name+"-"+field.RelName+"-"+typ] = &rel{
+						from: name,
+						to:   typ,
+					}
+
+					relNames = append(relNames, name+"-"+field.RelName+"-"+typ)
+				}
+			}
 		}
 	}
 
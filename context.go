@@ -6,10 +6,12 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/256dpi/jsonapi/v2"
 	"github.com/256dpi/xo"
 	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/text/language"
 
 	"github.com/256dpi/fire/coal"
 	"github.com/256dpi/fire/stick"
@@ -176,7 +178,7 @@ type Context struct {
 	GetWritableFields func(coal.Model) []string
 
 	// Only the whitelisted readable properties are exposed to the client as
-	// attributes.
+	// attributes. Computed attributes are listed using their attribute key.
 	//
 	// Usage: Reduce only
 	// Availability: Authorizers
@@ -204,11 +206,12 @@ type Context struct {
 	// Operations: Create, Update, Delete, ResourceAction
 	Model coal.Model
 
-	// The models that will be returned for a List operation.
+	// The models that will be returned for a List operation or deleted by a
+	// bulk Delete operation.
 	//
 	// Usage: Modify only
 	// Availability: Decorators
-	// Operations: List
+	// Operations: List, Delete?
 	Models []coal.Model
 
 	// The original model that is being updated. Can be used to lookup up
@@ -223,7 +226,7 @@ type Context struct {
 	//
 	// Usage: Read only
 	// Availability: Authorizers
-	// Operations: List?, Find?
+	// Operations: List?, Find?, Create?
 	Parent coal.Model
 
 	// The document that has been received by the client.
@@ -233,6 +236,13 @@ type Context struct {
 	// Operations: !List, !Find, !CollectionAction, !ResourceAction
 	Request *jsonapi.Document
 
+	// The decoded and validated request body of an action that declares a
+	// body prototype.
+	//
+	// Usage: Read only
+	// Operations: CollectionAction, ResourceAction
+	Body interface{}
+
 	// The document that will be written to the client.
 	//
 	// Usage: Modify only
@@ -285,6 +295,24 @@ type Context struct {
 	//
 	// Usage: Read only
 	Tracer *xo.Tracer
+
+	// The language of the request as matched against the languages of the
+	// group locale. It is undetermined if the group has no locale.
+	//
+	// Usage: Read only
+	Language language.Tag
+
+	// The time zone of the request as requested by the client or the default
+	// of the group locale. It is UTC if the group has no locale.
+	//
+	// Usage: Read only
+	Location *time.Location
+
+	// the pending atomic reference updates
+	referenceUpdates bson.M
+
+	// whether the context loads included resources
+	including bool
 }
 
 // With will run the provided function with the specified context temporarily
@@ -354,7 +382,8 @@ func (c *Context) Query() bson.M {
 
 // Modified will return whether the specified field has been changed. During an
 // update operation the modification is checked against the original model. For
-// all other operations, the field is checked against its zero value.
+// all other operations, the field is checked against its zero value. Values
+// are compared as stored in the database using coal.Equal.
 func (c *Context) Modified(field string) bool {
 	// determine old value
 	var oldValue interface{}
@@ -367,7 +396,7 @@ func (c *Context) Modified(field string) bool {
 	// get new value
 	newValue := stick.MustGet(c.Model, field)
 
-	return !reflect.DeepEqual(newValue, oldValue)
+	return !coal.Equal(newValue, oldValue)
 }
 
 // Parse will decode a custom JSON body to the specified value.
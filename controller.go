@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/bits"
@@ -18,6 +20,8 @@ import (
 	"github.com/256dpi/xo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
 	"github.com/256dpi/fire/coal"
 	"github.com/256dpi/fire/stick"
@@ -25,6 +29,10 @@ import (
 
 const blankCursor = "*"
 
+const bulkDeleteAction = "delete-many"
+
+const countAction = "count"
+
 var cursorEncoding = base64.URLEncoding.WithPadding(base64.NoPadding)
 
 // Stage defines a controller callback stage.
@@ -66,12 +74,37 @@ func (s Stage) Split() []Stage {
 // expression.
 type FilterHandler func(ctx *Context, values []string) (bson.M, error)
 
+// BulkMode defines how callbacks are run during bulk operations.
+type BulkMode int
+
+// The available bulk modes.
+const (
+	// PerDocument will load all matching documents and run the modifiers,
+	// the model validation and the validators for each document.
+	PerDocument BulkMode = iota
+
+	// InBulk will not load the matching documents and only run the
+	// validators once. The validators are expected to inspect the query to
+	// decide whether the operation is permitted.
+	InBulk
+)
+
+// ComputeHandler defines a function that computes the value of a virtual
+// attribute for the provided model.
+type ComputeHandler func(ctx *Context, model coal.Model) (interface{}, error)
+
 // A Controller provides a JSON API based interface to a model.
 //
 // Database transactions are automatically used for list, find, create, update
 // and delete operations. The created session can be accessed through the
 // context to use it in callbacks.
 //
+// To-many relationships may be updated with delta semantics by setting the
+// "operation" member of the relationship meta to "add" or "remove". The
+// referenced resources are then added to or removed from the relationship
+// instead of replacing it. The default "replace" operation replaces the
+// relationship as usual.
+//
 // Note: A controller must not be modified after being added to a group.
 type Controller struct {
 	// The model that this controller should provide (e.g. &Foo{}).
@@ -91,7 +124,9 @@ type Controller struct {
 	Search bool
 
 	// Filters is a list of fields that are filterable. Only fields that are
-	// exposed and indexed should be made filterable.
+	// exposed and indexed should be made filterable. Randomly encrypted fields
+	// cannot be filtered. Filter values of deterministically encrypted fields
+	// are encrypted using the stores encrypter if available.
 	//
 	// Note: The filter[field] query parameters are used for filtering.
 	Filters []string
@@ -102,16 +137,30 @@ type Controller struct {
 	FilterHandlers map[string]FilterHandler
 
 	// Sorters is a list of fields that are sortable. Only fields that are
-	// exposed and indexed should be made sortable.
+	// exposed and indexed should be made sortable. Encrypted fields cannot be
+	// sorted.
 	//
 	// Note: The "sort" query parameters is used for sorting.
 	Sorters []string
 
+	// IndexedFilters is a list of fields that are always filtered on, e.g. by
+	// authorizers, and should be included with the indexes suggested by
+	// Group.SuggestIndexes.
+	IndexedFilters []string
+
 	// Properties is a mapping of model properties to attribute keys. These
 	// properties are called and their result set as attributes before returning
 	// the response.
 	Properties map[string]string
 
+	// Computed is a mapping of attribute keys to compute handlers. The handlers
+	// are called per request with the context and model and their result set
+	// as attributes before returning the response. Computed attributes are
+	// never persisted and are treated as properties using the attribute key
+	// as the property name regarding readability. Virtual fields added to the
+	// model using coal.AddVirtual are handled the same way.
+	Computed map[string]ComputeHandler
+
 	// Authorizers authorize the requested operation on the requested resource
 	// and are run before any models are loaded from the store. Returned "safe"
 	// errors will cause the abortion of the request with an unauthorized status.
@@ -220,6 +269,11 @@ type Controller struct {
 	// fields are changed during a Create or Update operation.
 	TolerateViolations []string
 
+	// LenientDocuments can be set to true to ignore unknown attributes and
+	// relationships in request documents instead of rejecting them. Type and
+	// linkage errors are still reported.
+	LenientDocuments bool
+
 	// IdempotentCreate can be set to true to enable the idempotent create
 	// mechanism. When creating resources, clients have to generate and submit a
 	// unique "create token". The controller will then first check if a document
@@ -239,6 +293,14 @@ type Controller struct {
 	// the "fire-consistent-update" flag.
 	ConsistentUpdate bool
 
+	// GuardedFields may list fields that are owned by background processes
+	// like torch computations or axe jobs. Updates are only written if the
+	// guarded fields of the stored document still have the values loaded by
+	// the request. Otherwise, the request fails with a "409 Conflict" error and
+	// may be retried by the client. This prevents updates from silently
+	// overwriting concurrently computed values.
+	GuardedFields []string
+
 	// SoftDelete can be set to true to enable the soft delete mechanism. If
 	// enabled, the controller will flag documents as deleted instead of
 	// immediately removing them. It will also exclude soft deleted documents
@@ -247,12 +309,170 @@ type Controller struct {
 	// a TTL index to delete the documents automatically after some timeout.
 	SoftDelete bool
 
+	// DeduplicateReads can be set to true to deduplicate concurrent identical
+	// List and Find operations. Operations on the same store and database that
+	// result in the same query, sorting and pagination after running the
+	// authorizers will share a single database round trip and receive copies
+	// of the loaded models. As the query is run within the transaction of the
+	// first request, other requests may observe data that has been committed
+	// slightly before their own transaction has been started.
+	DeduplicateReads bool
+
+	// PoolModels can be set to true to reduce allocations by loading the
+	// models of List operations from a model pool. The models are reset and
+	// returned to the pool once the response has been written. Callbacks must
+	// therefore not retain references to loaded models beyond the request.
+	// Pooling cannot be combined with DeduplicateReads.
+	PoolModels bool
+
+	// SidepostRelationships enables the creation of dependent resources that
+	// are included in the document of a Create operation. The listed has-one
+	// and has-many relationships are matched by type with the included
+	// resources. After the primary resource has been inserted, the included
+	// resources are created in the same transaction using the related
+	// controller with all its callbacks. The inverse relationship is set to the
+	// created resource and must therefore be writable.
+	SidepostRelationships []string
+
+	// CountRelationships lists has-many relationships for which the number of
+	// related resources is included as the "count" meta field of the
+	// relationship in List and Find responses. The counts are computed using a
+	// single aggregation per relationship for all returned resources and
+	// exclude soft deleted and filtered related resources. Counts are only
+	// included for readable relationships.
+	CountRelationships []string
+
+	// Counters may provide materialized counters for relationships listed in
+	// CountRelationships. The counts are then loaded from the counters instead
+	// of being aggregated per request. The counters should be maintained using
+	// Counting.Track or Counting.Increment as missing counters are counted but
+	// not stored in the read only transactions of requests. Soft deleted
+	// related resources must be excluded using the filter of the counting
+	// while relationship filters are not applied.
+	Counters map[string]*coal.Counting
+
+	// Includes lists the relationship paths that may be requested using the
+	// "include" query parameter in List and Find operations e.g. "author" or
+	// "comments.author". The related resources are loaded using the related
+	// controllers with all their authorizers and field restrictions and added
+	// as included resources to the document. Nested paths require their parent
+	// paths to be listed. The optional function of a path may gate the
+	// inclusion e.g. based on OAuth2 scopes or policy decisions. Requests for
+	// unlisted paths are rejected with a "Bad Request" error and requests for
+	// denied paths with a "Forbidden" error. The parameter is ignored if no
+	// includes are configured.
+	Includes map[string]func(ctx *Context) bool
+
+	// ReadPreferences and WriteConcerns can be set to configure the read
+	// preference and write concern used by the store operations of specific
+	// operations, e.g. "secondaryPreferred" for List or "majority" for Delete.
+	// As transactions only support the primary read preference, List and Find
+	// operations with another read preference are run using snapshot sessions.
+	ReadPreferences map[Operation]*readpref.ReadPref
+	WriteConcerns   map[Operation]*writeconcern.WriteConcern
+
+	// DenyFiltered can be set to true to respond with a "Forbidden" error
+	// instead of a "Not Found" error if a specific resource exists but has
+	// been excluded by the filters set by the authorizers. This reveals the
+	// existence of resources and applies to Find, Update, Delete, relationship
+	// and resource action requests.
+	DenyFiltered bool
+
+	// BulkDelete can be set to true to enable the "delete-many" collection
+	// action (e.g. "DELETE /posts/delete-many?filter[title]=foo"). The action
+	// is handled as a Delete operation that removes all resources matching the
+	// provided filters and the filters set by the authorizers in a single
+	// transaction. To prevent the accidental deletion of all resources, at
+	// least one filter must be provided in the query. The response includes
+	// the number of deleted resources as the "deleted" meta field.
+	BulkDelete bool
+
+	// BulkDeleteMode defines how callbacks are run during a bulk delete. In
+	// the PerDocument mode, the models field is available to verifiers and
+	// notifiers and the model field is set to each document while running the
+	// modifiers and validators. In the InBulk mode, the validators are run
+	// once with neither field being set. Validators that require a model must
+	// then inspect the query instead, which is supported by the built-in
+	// DependentResourcesValidator.
+	//
+	// Default: PerDocument.
+	BulkDeleteMode BulkMode
+
+	// Counting can be set to true to enable the "count" collection action
+	// (e.g. "GET /posts/count?filter[title]=foo"). The action is handled as a
+	// List operation that counts the resources matching the provided filters
+	// and the filters set by the authorizers without loading them. The count
+	// is returned as the "count" meta field. Only the authorizers and
+	// notifiers are run for these requests.
+	Counting bool
+
+	// WaitLimit can be set to enable long polling for List operations. Clients
+	// may then provide a "wait" query parameter (e.g. "wait=30s") up to the
+	// limit to hold the request open until a document that matches the
+	// selector and the request filters has been created, updated or deleted
+	// or the duration has elapsed. The list is loaded as usual afterwards.
+	// Changes are observed using a change stream that is shared by all
+	// requests of the controller and closed when the group is drained. The
+	// authorizers are run before waiting to reject unauthorized clients and
+	// to consider their filters. They are therefore run twice for these
+	// requests. Documents that are updated to no longer match the filters are
+	// not detected.
+	WaitLimit time.Duration
+
+	// Archiver can be set to archive all requests handled by the controller
+	// and their responses (e.g. for compliance). Archives are stored
+	// synchronously after the response has been written and errors are
+	// reported using the group reporter. The "Authorization", "Cookie" and
+	// "Set-Cookie" headers are always redacted.
+	Archiver Archiver
+
+	// ArchiveRedactions lists attributes that are redacted in the archived
+	// request and response documents. Bodies that are not JSON documents are
+	// omitted if redactions are configured.
+	ArchiveRedactions []string
+
+	// Traits are applied to the controller when it is prepared. Their
+	// callbacks are run before the callbacks of the controller in the order
+	// of the traits. Their actions are added to the actions of the controller.
+	Traits []*Trait
+
+	// Deprecations may be set to deprecate operations of the controller.
+	// Deprecated operations are served with "Deprecation" and "Sunset"
+	// headers until they are removed and aborted afterwards.
+	Deprecations []Deprecation
+
+	// StrictQuery can be set to true to abort requests with unknown or
+	// unsupported query parameters with a bad request error instead of
+	// ignoring them. This helps clients to detect misspelled or unsupported
+	// parameters like "filters[title]" or "page[size]" on a Find operation.
+	// Query parameters of actions and sub requests (e.g. includes) are not
+	// checked. The "access_token" parameter and the time zone parameter of the
+	// group locale are always accepted.
+	StrictQuery bool
+
+	// QueryParameters lists additional query parameters that are accepted in
+	// strict mode, e.g. parameters that are read by callbacks.
+	QueryParameters []string
+
+	// SimpleJSON can be set to true to serve responses as simple JSON to
+	// clients that request the SimpleJSONType using the "Accept" header.
+	// Resources are returned as plain objects with camel cased keys and
+	// relationships flattened to ID fields, see Simplify for details. The
+	// serialization and policies of the controller are applied as usual.
+	// Requests and errors still use the JSON-API format.
+	SimpleJSON bool
+
 	parser     jsonapi.Parser
 	meta       *coal.Meta
 	properties map[string]func(coal.Model) (interface{}, error)
+	flight     flight
+	watcher    watcher
 }
 
 func (c *Controller) prepare() {
+	// apply traits
+	c.applyTraits()
+
 	// ensure supported
 	if c.Supported == nil {
 		c.Supported = All()
@@ -270,24 +490,27 @@ func (c *Controller) prepare() {
 	// add collection actions
 	for name, action := range c.CollectionActions {
 		// check collision
-		if name == "" || coal.IsHex(name) {
+		if name == "" || coal.IsHex(name) || (c.BulkDelete && name == bulkDeleteAction) || (c.Counting && name == countAction) {
 			panic(fmt.Sprintf(`fire: invalid collection action "%s"`, name))
 		}
 
-		// set default body limit
-		if action.BodyLimit == 0 {
-			action.BodyLimit = serve.MustByteSize("8M")
-		}
-
-		// set default timeout
-		if action.Timeout == 0 {
-			action.Timeout = 30 * time.Second
-		}
+		// prepare action
+		action.prepare()
 
 		// add to parser
 		c.parser.CollectionActions[name] = action.Methods
 	}
 
+	// add bulk delete action
+	if c.BulkDelete {
+		c.parser.CollectionActions[bulkDeleteAction] = []string{"DELETE"}
+	}
+
+	// add count action
+	if c.Counting {
+		c.parser.CollectionActions[countAction] = []string{"GET"}
+	}
+
 	// add resource actions
 	for name, action := range c.ResourceActions {
 		// check collision
@@ -295,15 +518,8 @@ func (c *Controller) prepare() {
 			panic(fmt.Sprintf(`fire: invalid resource action "%s"`, name))
 		}
 
-		// set default body limit
-		if action.BodyLimit == 0 {
-			action.BodyLimit = serve.MustByteSize("8M")
-		}
-
-		// set default timeout
-		if action.Timeout == 0 {
-			action.Timeout = 30 * time.Second
-		}
+		// prepare action
+		action.prepare()
 
 		// add to parser
 		c.parser.ResourceActions[name] = action.Methods
@@ -322,6 +538,11 @@ func (c *Controller) prepare() {
 		c.WriteTimeout = 30 * time.Second
 	}
 
+	// check model pooling
+	if c.PoolModels && c.DeduplicateReads {
+		panic(`fire: model pooling cannot be combined with deduplicated reads`)
+	}
+
 	// check soft delete field
 	if c.SoftDelete {
 		fieldName := coal.L(c.Model, "fire-soft-delete", true)
@@ -346,6 +567,83 @@ func (c *Controller) prepare() {
 		}
 	}
 
+	// check guarded fields
+	for _, name := range c.GuardedFields {
+		if c.meta.Fields[name] == nil {
+			panic(fmt.Sprintf(`fire: guarded field "%s" is not a field`, name))
+		}
+	}
+
+	// check sidepost relationships
+	sidepostTypes := map[string]bool{}
+	for _, name := range c.SidepostRelationships {
+		rel := c.meta.Relationships[name]
+		if rel == nil || (!rel.HasOne && !rel.HasMany) {
+			panic(fmt.Sprintf(`fire: sidepost relationship "%s" is not a has-one or has-many relationship`, name))
+		} else if sidepostTypes[rel.RelType] {
+			panic(fmt.Sprintf(`fire: multiple sidepost relationships for type "%s"`, rel.RelType))
+		}
+		sidepostTypes[rel.RelType] = true
+	}
+
+	// check count relationships
+	for _, name := range c.CountRelationships {
+		rel := c.meta.Relationships[name]
+		if rel == nil || !rel.HasMany {
+			panic(fmt.Sprintf(`fire: count relationship "%s" is not a has-many relationship`, name))
+		}
+	}
+
+	// check counters
+	for name := range c.Counters {
+		if !stick.Contains(c.CountRelationships, name) {
+			panic(fmt.Sprintf(`fire: counter "%s" is not a count relationship`, name))
+		}
+	}
+
+	// check includes
+	for path := range c.Includes {
+		segments := strings.Split(path, ".")
+		if rel := c.meta.Relationships[segments[0]]; rel == nil {
+			panic(fmt.Sprintf(`fire: include path "%s" does not start with a relationship`, path))
+		}
+		for i := 1; i < len(segments); i++ {
+			if _, ok := c.Includes[strings.Join(segments[:i], ".")]; !ok {
+				panic(fmt.Sprintf(`fire: include path "%s" is missing its parent path`, path))
+			}
+		}
+	}
+
+	// check map key filters
+	for _, name := range c.Filters {
+		if field, key, ok := strings.Cut(name, "."); ok {
+			if f := c.meta.Fields[field]; f == nil || f.MapValue == nil || key == "" {
+				panic(fmt.Sprintf(`fire: filter "%s" does not refer to a key of a map field`, name))
+			}
+		}
+	}
+
+	// check indexed filters
+	for _, name := range c.IndexedFilters {
+		if c.meta.Fields[name] == nil {
+			panic(fmt.Sprintf(`fire: indexed filter "%s" is not a field`, name))
+		}
+	}
+
+	// check encrypted filters
+	for _, name := range append(append([]string{}, c.Filters...), c.IndexedFilters...) {
+		if coal.GetEncryption(c.Model, name) == coal.Random {
+			panic(fmt.Sprintf(`fire: filter "%s" is randomly encrypted`, name))
+		}
+	}
+
+	// check encrypted sorters
+	for _, name := range c.Sorters {
+		if coal.GetEncryption(c.Model, name) != coal.Unencrypted {
+			panic(fmt.Sprintf(`fire: sorter "%s" is encrypted`, name))
+		}
+	}
+
 	// check filter handlers
 	for name := range c.FilterHandlers {
 		if !stick.Contains(c.Filters, name) {
@@ -353,6 +651,30 @@ func (c *Controller) prepare() {
 		}
 	}
 
+	// check computed attributes
+	for key := range c.Computed {
+		if c.meta.Attributes[key] != nil || c.meta.Relationships[key] != nil {
+			panic(fmt.Sprintf(`fire: computed attribute "%s" conflicts with existing field`, key))
+		}
+		for name, property := range c.Properties {
+			if key == name || key == property {
+				panic(fmt.Sprintf(`fire: computed attribute "%s" conflicts with property "%s"`, key, name))
+			}
+		}
+	}
+
+	// check virtual fields
+	for key := range c.meta.Virtuals {
+		if c.Computed[key] != nil {
+			panic(fmt.Sprintf(`fire: computed attribute "%s" conflicts with virtual field`, key))
+		}
+		for name, property := range c.Properties {
+			if key == name || key == property {
+				panic(fmt.Sprintf(`fire: virtual field "%s" conflicts with property "%s"`, key, name))
+			}
+		}
+	}
+
 	// lookup properties
 	c.properties = map[string]func(coal.Model) (interface{}, error){}
 	for name := range c.Properties {
@@ -371,7 +693,15 @@ func (c *Controller) handle(prefix string, ctx *Context, selector bson.M, write
 
 	// parse incoming JSON-API request if not yet present
 	if ctx.JSONAPIRequest == nil {
-		req, err := parser.ParseRequest(ctx.HTTPRequest)
+		// accept simple JSON if enabled
+		r := ctx.HTTPRequest
+		if c.SimpleJSON && acceptsSimpleJSON(r) {
+			r = r.Clone(r.Context())
+			r.Header.Set("Accept", jsonapi.MediaType)
+		}
+
+		// parse request
+		req, err := parser.ParseRequest(r)
 		xo.AbortIf(err)
 		ctx.JSONAPIRequest = req
 	}
@@ -412,6 +742,11 @@ func (c *Controller) handle(prefix string, ctx *Context, selector bson.M, write
 		ctx.Operation = Update
 	case jsonapi.CollectionAction:
 		ctx.Operation = CollectionAction
+		if c.BulkDelete && ctx.JSONAPIRequest.CollectionAction == bulkDeleteAction {
+			ctx.Operation = Delete
+		} else if c.Counting && ctx.JSONAPIRequest.CollectionAction == countAction {
+			ctx.Operation = List
+		}
 	case jsonapi.ResourceAction:
 		ctx.Operation = ResourceAction
 	}
@@ -424,6 +759,14 @@ func (c *Controller) handle(prefix string, ctx *Context, selector bson.M, write
 		))
 	}
 
+	// check deprecations
+	c.checkDeprecations(ctx)
+
+	// check query parameters of top-level requests
+	if write && c.StrictQuery && !ctx.Operation.Action() {
+		c.checkQuery(ctx)
+	}
+
 	// ensure selector
 	if selector == nil {
 		selector = bson.M{}
@@ -438,9 +781,28 @@ func (c *Controller) handle(prefix string, ctx *Context, selector bson.M, write
 	ctx.ReadableProperties = c.initialProperties(ctx.JSONAPIRequest)
 	ctx.RelationshipFilters = map[string][]bson.M{}
 
+	// set preference if configured
+	rp, wc := c.ReadPreferences[ctx.Operation], c.WriteConcerns[ctx.Operation]
+	if rp != nil || wc != nil {
+		ctx.Context = coal.WithPreference(ctx.Context, coal.Preference{
+			ReadPreference: rp,
+			WriteConcern:   wc,
+		})
+	}
+
+	// run group before hooks
+	if write && ctx.Group != nil {
+		ctx.Group.runHooks(ctx, ctx.Group.before, http.StatusBadRequest)
+	}
+
+	// await changes if requested
+	if ctx.Operation == List {
+		c.awaitChanges(ctx)
+	}
+
 	// run operation with transaction if not an action
 	if !ctx.Operation.Action() {
-		xo.AbortIf(c.Store.T(ctx.Context, ctx.Operation.Read(), func(tc context.Context) error {
+		xo.AbortIf(c.Store.Target(c.Model).T(ctx.Context, ctx.Operation.Read(), func(tc context.Context) error {
 			return ctx.With(tc, func() error {
 				c.runOperation(ctx)
 				return nil
@@ -450,9 +812,40 @@ func (c *Controller) handle(prefix string, ctx *Context, selector bson.M, write
 		c.runOperation(ctx)
 	}
 
+	// run group after hooks
+	if write && ctx.Group != nil {
+		ctx.Group.runHooks(ctx, ctx.Group.after, http.StatusInternalServerError)
+	}
+
 	// write response if available
 	if write && ctx.Response != nil {
-		xo.AbortIf(jsonapi.WriteResponse(ctx.ResponseWriter, ctx.ResponseCode, ctx.Response))
+		// skip response if the client aborted the request
+		if errors.Is(ctx.HTTPRequest.Context().Err(), context.Canceled) {
+			xo.Abort(ErrClientAborted.Wrap())
+		}
+
+		// build links
+		if ctx.Group != nil {
+			ctx.Group.buildLinks(ctx, ctx.Response)
+		}
+
+		// responses vary by the accepted media type if enabled
+		if c.SimpleJSON {
+			ctx.ResponseWriter.Header().Add("Vary", "Accept")
+		}
+
+		// write simple JSON if requested
+		if c.SimpleJSON && acceptsSimpleJSON(ctx.HTTPRequest) {
+			xo.AbortIf(writeSimpleJSON(ctx.ResponseWriter, ctx.ResponseCode, ctx.Response, ctx.Group.metas()))
+		} else {
+			xo.AbortIf(jsonapi.WriteResponse(ctx.ResponseWriter, ctx.ResponseCode, ctx.Response))
+		}
+	}
+
+	// release pooled models
+	if write && c.PoolModels && ctx.Operation == List {
+		c.meta.Release(ctx.Models...)
+		ctx.Models = nil
 	}
 }
 
@@ -480,7 +873,13 @@ func (c *Controller) runOperation(ctx *Context) {
 	case jsonapi.RemoveFromRelationship:
 		c.removeFromRelationship(ctx)
 	case jsonapi.CollectionAction:
-		c.handleCollectionAction(ctx)
+		if ctx.Operation == Delete {
+			c.deleteResources(ctx)
+		} else if ctx.Operation == List {
+			c.countResources(ctx)
+		} else {
+			c.handleCollectionAction(ctx)
+		}
 	case jsonapi.ResourceAction:
 		c.handleResourceAction(ctx)
 	}
@@ -507,12 +906,25 @@ func (c *Controller) listResources(ctx *Context) {
 	// preload relationships
 	relationships := c.preloadRelationships(ctx, ctx.Models)
 
+	// prepare resources
+	resources := c.resourcesForModels(ctx, ctx.Models, relationships)
+
+	// count relationships
+	c.countRelationships(ctx, ctx.Models, resources)
+
+	// prepare links, skip for included resources as they are not rendered
+	var links *jsonapi.DocumentLinks
+	if !ctx.including {
+		links = c.listLinks(ctx)
+	}
+
 	// compose response
 	ctx.Response = &jsonapi.Document{
 		Data: &jsonapi.HybridResource{
-			Many: c.resourcesForModels(ctx, ctx.Models, relationships),
+			Many: resources,
 		},
-		Links: c.listLinks(ctx),
+		Included: c.includeResources(ctx, resources),
+		Links:    links,
 	}
 	ctx.ResponseCode = http.StatusOK
 
@@ -541,11 +953,18 @@ func (c *Controller) findResource(ctx *Context) {
 	// preload relationships
 	relationships := c.preloadRelationships(ctx, []coal.Model{ctx.Model})
 
+	// prepare resource
+	resource := c.resourceForModel(ctx, ctx.Model, relationships)
+
+	// count relationships
+	c.countRelationships(ctx, []coal.Model{ctx.Model}, []*jsonapi.Resource{resource})
+
 	// compose response
 	ctx.Response = &jsonapi.Document{
 		Data: &jsonapi.HybridResource{
-			One: c.resourceForModel(ctx, ctx.Model, relationships),
+			One: resource,
 		},
+		Included: c.includeResources(ctx, []*jsonapi.Resource{resource}),
 		Links: &jsonapi.DocumentLinks{
 			Self: jsonapi.Link(ctx.JSONAPIRequest.Self()),
 		},
@@ -575,12 +994,12 @@ func (c *Controller) createResource(ctx *Context) {
 
 	// check resource type
 	if ctx.Request.Data.One.Type != ctx.JSONAPIRequest.ResourceType {
-		xo.Abort(jsonapi.BadRequest("resource type mismatch"))
+		xo.Abort(jsonapi.BadRequestPointer("resource type mismatch", "/data/type"))
 	}
 
 	// check ID
 	if ctx.Request.Data.One.ID != "" {
-		xo.Abort(jsonapi.BadRequest("unnecessary resource ID"))
+		xo.Abort(jsonapi.BadRequestPointer("unnecessary resource ID", "/data/id"))
 	}
 
 	// run authorizers
@@ -633,6 +1052,8 @@ func (c *Controller) createResource(ctx *Context) {
 		}, ctx.Model, false)
 		if coal.IsDuplicate(err) {
 			xo.Abort(ErrDocumentNotUnique.Wrap())
+		} else if coal.ErrDocumentTooLarge.Is(err) {
+			xo.Abort(ErrDocumentTooLarge.Wrap())
 		}
 		xo.AbortIf(err)
 
@@ -645,10 +1066,15 @@ func (c *Controller) createResource(ctx *Context) {
 		err := ctx.Store.M(c.Model).Insert(ctx, ctx.Model)
 		if coal.IsDuplicate(err) {
 			xo.Abort(ErrDocumentNotUnique.Wrap())
+		} else if coal.ErrDocumentTooLarge.Is(err) {
+			xo.Abort(ErrDocumentTooLarge.Wrap())
 		}
 		xo.AbortIf(err)
 	}
 
+	// create included resources
+	included := c.createIncluded(ctx)
+
 	// run decorators
 	c.runCallbacks(ctx, Decorator, c.Decorators, http.StatusInternalServerError)
 
@@ -662,6 +1088,7 @@ func (c *Controller) createResource(ctx *Context) {
 		Data: &jsonapi.HybridResource{
 			One: c.resourceForModel(ctx, ctx.Model, nil),
 		},
+		Included: included,
 		Links: &jsonapi.DocumentLinks{
 			Self: jsonapi.Link(selfLink.Self()),
 		},
@@ -672,6 +1099,120 @@ func (c *Controller) createResource(ctx *Context) {
 	c.runCallbacks(ctx, Notifier, c.Notifiers, http.StatusInternalServerError)
 }
 
+func (c *Controller) createIncluded(ctx *Context) []*jsonapi.Resource {
+	// check included resources
+	if len(ctx.Request.Included) == 0 {
+		return nil
+	}
+
+	// trace
+	ctx.Tracer.Push("fire/Controller.createIncluded")
+	defer ctx.Tracer.Pop()
+
+	// check support
+	if len(c.SidepostRelationships) == 0 {
+		xo.Abort(jsonapi.BadRequestPointer("included resources are not supported", "/included"))
+	}
+
+	// create resources
+	list := make([]*jsonapi.Resource, 0, len(ctx.Request.Included))
+	counts := map[string]int{}
+	for i, res := range ctx.Request.Included {
+		// prepare pointer
+		pointer := fmt.Sprintf("/included/%d", i)
+
+		// find relationship
+		var rel *coal.Field
+		for _, name := range c.SidepostRelationships {
+			if c.meta.Relationships[name].RelType == res.Type {
+				rel = c.meta.Relationships[name]
+			}
+		}
+		if rel == nil {
+			xo.Abort(jsonapi.BadRequestPointer("invalid included resource type", pointer+"/type"))
+		}
+
+		// check ID
+		if res.ID != "" {
+			xo.Abort(jsonapi.BadRequestPointer("unnecessary resource ID", pointer+"/id"))
+		}
+
+		// check has-one count
+		counts[rel.Name]++
+		if rel.HasOne && counts[rel.Name] > 1 {
+			xo.Abort(jsonapi.BadRequestPointer("multiple resources for has one relationship", pointer))
+		}
+
+		// get related controller
+		rc := ctx.Group.controllers[rel.RelType]
+		if rc == nil {
+			xo.Abort(xo.F("missing related controller for %s", rel.RelType))
+		}
+
+		// find inverse relationship
+		inverse := rc.meta.Relationships[rel.RelInverse]
+		if inverse == nil {
+			xo.Abort(xo.F("no relationship matching the inverse name %s", rel.RelInverse))
+		}
+
+		// check inverse relationship
+		if res.Relationships[inverse.RelName] != nil {
+			xo.Abort(jsonapi.BadRequestPointer("inverse relationship must not be set", pointer+"/relationships/"+inverse.RelName))
+		}
+
+		// copy resource and set inverse relationship
+		sub := *res
+		sub.Relationships = map[string]*jsonapi.Document{}
+		for name, doc := range res.Relationships {
+			sub.Relationships[name] = doc
+		}
+		ref := &jsonapi.Resource{
+			Type: c.meta.PluralName,
+			ID:   ctx.Model.ID().Hex(),
+		}
+		if inverse.ToMany {
+			sub.Relationships[inverse.RelName] = &jsonapi.Document{
+				Data: &jsonapi.HybridResource{Many: []*jsonapi.Resource{ref}},
+			}
+		} else {
+			sub.Relationships[inverse.RelName] = &jsonapi.Document{
+				Data: &jsonapi.HybridResource{One: ref},
+			}
+		}
+
+		// prepare sub context
+		subCtx := &Context{
+			Context:        ctx,
+			Data:           stick.Map{},
+			Parent:         ctx.Model,
+			Request:        &jsonapi.Document{Data: &jsonapi.HybridResource{One: &sub}},
+			HTTPRequest:    ctx.HTTPRequest,
+			ResponseWriter: nil,
+			Controller:     rc,
+			Group:          ctx.Group,
+			Tracer:         ctx.Tracer,
+			Language:       ctx.Language,
+			Location:       ctx.Location,
+		}
+
+		// copy and prepare request
+		req := *ctx.JSONAPIRequest
+		req.Intent = jsonapi.CreateResource
+		req.ResourceType = rel.RelType
+		req.ResourceID = ""
+		req.RelatedResource = ""
+		subCtx.JSONAPIRequest = &req
+
+		// handle virtual request
+		rc.handle("", subCtx, nil, false)
+
+		// add resource
+		list = append(list, subCtx.Response.Data.One)
+	}
+
+	return list
+}
+
 func (c *Controller) updateResource(ctx *Context) {
 	// trace
 	ctx.Tracer.Push("fire/Controller.updateResource")
@@ -691,12 +1232,12 @@ func (c *Controller) updateResource(ctx *Context) {
 
 	// check resource type
 	if ctx.Request.Data.One.Type != ctx.JSONAPIRequest.ResourceType {
-		xo.Abort(jsonapi.BadRequest("resource type mismatch"))
+		xo.Abort(jsonapi.BadRequestPointer("resource type mismatch", "/data/type"))
 	}
 
 	// check ID
 	if ctx.Request.Data.One.ID != ctx.JSONAPIRequest.ResourceID {
-		xo.Abort(jsonapi.BadRequest("resource ID mismatch"))
+		xo.Abort(jsonapi.BadRequestPointer("resource ID mismatch", "/data/id"))
 	}
 
 	// load model
@@ -757,13 +1298,16 @@ func (c *Controller) updateResource(ctx *Context) {
 		// generate new update token
 		stick.MustSet(ctx.Model, consistentUpdateField, coal.New().Hex())
 
+		// prepare filter
+		filter := c.guardFilter(ctx)
+		filter[consistentUpdateField] = consistentUpdateToken
+
 		// update model
-		found, err := ctx.Store.M(c.Model).ReplaceFirst(ctx, bson.M{
-			"_id":                 ctx.Model.ID(),
-			consistentUpdateField: consistentUpdateToken,
-		}, ctx.Model, false)
+		found, err := c.writeModel(ctx, filter)
 		if coal.IsDuplicate(err) {
 			xo.Abort(ErrDocumentNotUnique.Wrap())
+		} else if coal.ErrDocumentTooLarge.Is(err) {
+			xo.Abort(ErrDocumentTooLarge.Wrap())
 		}
 		xo.AbortIf(err)
 
@@ -771,11 +1315,27 @@ func (c *Controller) updateResource(ctx *Context) {
 		if !found {
 			xo.Abort(jsonapi.ErrorFromStatus(http.StatusConflict, "existing document with different consistent update token"))
 		}
+	} else if len(c.GuardedFields) > 0 {
+		// update model
+		found, err := c.writeModel(ctx, c.guardFilter(ctx))
+		if coal.IsDuplicate(err) {
+			xo.Abort(ErrDocumentNotUnique.Wrap())
+		} else if coal.ErrDocumentTooLarge.Is(err) {
+			xo.Abort(ErrDocumentTooLarge.Wrap())
+		}
+		xo.AbortIf(err)
+
+		// fail if not found
+		if !found {
+			xo.Abort(jsonapi.ErrorFromStatus(http.StatusConflict, "guarded fields have been modified concurrently"))
+		}
 	} else {
-		// replace model
-		found, err := ctx.Store.M(c.Model).Replace(ctx, ctx.Model, false)
+		// write model
+		found, err := c.writeModel(ctx, nil)
 		if coal.IsDuplicate(err) {
 			xo.Abort(ErrDocumentNotUnique.Wrap())
+		} else if coal.ErrDocumentTooLarge.Is(err) {
+			xo.Abort(ErrDocumentTooLarge.Wrap())
 		}
 		xo.AbortIf(err)
 
@@ -870,114 +1430,310 @@ func (c *Controller) deleteResource(ctx *Context) {
 	ctx.ResponseWriter.WriteHeader(http.StatusNoContent)
 }
 
-func (c *Controller) getRelatedResources(ctx *Context) {
+func (c *Controller) deleteResources(ctx *Context) {
 	// trace
-	ctx.Tracer.Push("fire/Controller.getRelatedResources")
+	ctx.Tracer.Push("fire/Controller.deleteResources")
 	defer ctx.Tracer.Pop()
 
 	// create context
-	ct, cancel := context.WithTimeout(ctx.Context, c.ReadTimeout)
+	ct, cancel := context.WithTimeout(ctx.Context, c.WriteTimeout)
 	defer cancel()
 
 	// replace context
 	ctx.Context = ct
 
-	// find relationship
-	rel := c.meta.Relationships[ctx.JSONAPIRequest.RelatedResource]
-	if rel == nil {
-		xo.Abort(jsonapi.BadRequest("invalid relationship"))
-	}
-
-	// load model
-	c.loadModel(ctx)
+	// parse filters
+	c.parseFilters(ctx)
 
-	// check if relationship is readable
-	if !stick.Contains(c.readableFields(ctx, ctx.Model), rel.Name) {
-		xo.Abort(jsonapi.BadRequest("relationship is not readable"))
+	// require filters
+	if len(ctx.JSONAPIRequest.Filters) == 0 {
+		xo.Abort(jsonapi.BadRequest("missing filter"))
 	}
 
-	// get related controller
-	rc := ctx.Group.controllers[rel.RelType]
-	if rc == nil {
-		xo.Abort(xo.F("missing related controller for %s", rel.RelType))
-	}
+	// filter out deleted documents if configured
+	if c.SoftDelete {
+		// get soft delete field
+		softDeleteField := coal.L(c.Model, "fire-soft-delete", true)
 
-	// prepare sub context
-	subCtx := &Context{
-		Context:        ctx,
-		Data:           stick.Map{},
-		Parent:         ctx.Model,
-		HTTPRequest:    ctx.HTTPRequest,
-		ResponseWriter: nil,
-		Controller:     rc,
-		Group:          ctx.Group,
-		Tracer:         ctx.Tracer,
+		// set filter
+		ctx.Selector[softDeleteField] = nil
 	}
 
-	// copy and prepare request
-	req := *ctx.JSONAPIRequest
-	req.Intent = jsonapi.ListResources
-	req.ResourceType = rel.RelType
-	req.ResourceID = ""
-	req.RelatedResource = ""
-	subCtx.JSONAPIRequest = &req
-
-	// finish to-one relationship
-	if rel.ToOne {
-		// lookup ID of related resource
-		id := coal.New()
-		if rel.Optional {
-			rid := stick.MustGet(ctx.Model, rel.Name).(*coal.ID)
-			if rid != nil {
-				id = *rid
-			}
-		} else {
-			id = stick.MustGet(ctx.Model, rel.Name).(coal.ID)
-		}
+	// add filters
+	c.addFilters(ctx)
 
-		// prepare selector
-		selector := bson.M{
-			"_id": id,
-		}
+	// run authorizers
+	c.runCallbacks(ctx, Authorizer, c.Authorizers, http.StatusUnauthorized)
 
-		// when run to request even if the relation is not present to capture
-		// a proper response with potential meta information
+	// check filter readability
+	c.checkFilters(ctx, c.readableFields(ctx, nil))
 
-		// handle virtual request
-		rc.handle("", subCtx, selector, false)
+	// prepare query
+	query := ctx.Query()
 
-		// check response
-		if len(subCtx.Response.Data.Many) > 1 {
-			xo.Abort(xo.F("to one relationship returned more than one result"))
+	// handle modes
+	if c.BulkDeleteMode == InBulk {
+		// run validators
+		c.runCallbacks(ctx, Validator, c.Validators, http.StatusBadRequest)
+	} else {
+		// load models
+		models := c.meta.MakeSlice()
+		xo.AbortIf(ctx.Store.M(c.Model).FindAll(ctx, models, query, nil, 0, 0, true))
+		ctx.Models = coal.Slice(models)
+
+		// run verifiers
+		c.runCallbacks(ctx, Verifier, c.Verifiers, http.StatusUnauthorized)
+
+		// collect IDs
+		ids := make([]coal.ID, 0, len(ctx.Models))
+		for _, model := range ctx.Models {
+			ids = append(ids, model.ID())
 		}
 
-		// pull out resource
-		if len(subCtx.Response.Data.Many) == 1 {
-			subCtx.Response.Data.One = subCtx.Response.Data.Many[0]
+		// restrict query to loaded models
+		query = bson.M{
+			"$and": []bson.M{query, {"_id": bson.M{"$in": ids}}},
 		}
 
-		// unset list
-		subCtx.Response.Data.Many = nil
-	}
+		// check models
+		for _, model := range ctx.Models {
+			// set model
+			ctx.Model = model
 
-	// finish to-many relationship
-	if rel.ToMany {
-		// get IDs from loaded model
-		ids := stick.MustGet(ctx.Model, rel.Name).([]coal.ID)
+			// run modifiers
+			c.runCallbacks(ctx, Modifier, c.Modifiers, http.StatusBadRequest)
 
-		// prepare selector
-		selector := bson.M{
-			"_id": bson.M{"$in": ids},
+			// validate model
+			err := ctx.Model.Validate()
+			if xo.IsSafe(err) {
+				xo.Abort(jsonapi.BadRequest(err.Error()))
+			} else if err != nil {
+				xo.Abort(err)
+			}
+
+			// run validators
+			c.runCallbacks(ctx, Validator, c.Validators, http.StatusBadRequest)
 		}
 
-		// handle virtual request
-		rc.handle("", subCtx, selector, false)
+		// unset model
+		ctx.Model = nil
 	}
 
-	// finish has-one relationship
-	if rel.HasOne {
-		// find inverse relationship
-		inverse := rc.meta.Relationships[rel.RelInverse]
+	// delete models
+	var deleted int64
+	if c.SoftDelete {
+		// get soft delete field
+		softDeleteField := coal.L(c.Model, "fire-soft-delete", true)
+
+		// soft delete models
+		n, err := ctx.Store.M(c.Model).UpdateAll(ctx, query, bson.M{
+			"$set": bson.M{
+				softDeleteField: time.Now(),
+			},
+		}, false)
+		xo.AbortIf(err)
+		deleted = n
+	} else {
+		// delete models
+		n, err := ctx.Store.M(c.Model).DeleteAll(ctx, query)
+		xo.AbortIf(err)
+		deleted = n
+	}
+
+	// compose response
+	ctx.Response = &jsonapi.Document{
+		Meta: jsonapi.Map{
+			"deleted": deleted,
+		},
+	}
+	ctx.ResponseCode = http.StatusOK
+
+	// run notifiers
+	c.runCallbacks(ctx, Notifier, c.Notifiers, http.StatusInternalServerError)
+}
+
+func (c *Controller) countResources(ctx *Context) {
+	// trace
+	ctx.Tracer.Push("fire/Controller.countResources")
+	defer ctx.Tracer.Pop()
+
+	// create context
+	ct, cancel := context.WithTimeout(ctx.Context, c.ReadTimeout)
+	defer cancel()
+
+	// replace context
+	ctx.Context = ct
+
+	// parse filters
+	c.parseFilters(ctx)
+
+	// filter out deleted documents if configured
+	if c.SoftDelete {
+		// get soft delete field
+		softDeleteField := coal.L(c.Model, "fire-soft-delete", true)
+
+		// set filter
+		ctx.Selector[softDeleteField] = nil
+	}
+
+	// add filters
+	c.addFilters(ctx)
+
+	// run authorizers
+	c.runCallbacks(ctx, Authorizer, c.Authorizers, http.StatusUnauthorized)
+
+	// check filter readability
+	c.checkFilters(ctx, c.readableFields(ctx, nil))
+
+	// count models
+	count, err := ctx.Store.M(c.Model).Count(ctx, ctx.Query(), 0, 0, false)
+	xo.AbortIf(err)
+
+	// compose response
+	ctx.Response = &jsonapi.Document{
+		Meta: jsonapi.Map{
+			"count": count,
+		},
+	}
+	ctx.ResponseCode = http.StatusOK
+
+	// run notifiers
+	c.runCallbacks(ctx, Notifier, c.Notifiers, http.StatusInternalServerError)
+}
+
+func (c *Controller) getRelatedResources(ctx *Context) {
+	// trace
+	ctx.Tracer.Push("fire/Controller.getRelatedResources")
+	defer ctx.Tracer.Pop()
+
+	// create context
+	ct, cancel := context.WithTimeout(ctx.Context, c.ReadTimeout)
+	defer cancel()
+
+	// replace context
+	ctx.Context = ct
+
+	// find relationship
+	rel := c.meta.Relationships[ctx.JSONAPIRequest.RelatedResource]
+	if rel == nil {
+		xo.Abort(jsonapi.BadRequest("invalid relationship"))
+	}
+
+	// load model
+	c.loadModel(ctx)
+
+	// check if relationship is readable
+	if !stick.Contains(c.readableFields(ctx, ctx.Model), rel.Name) {
+		xo.Abort(jsonapi.BadRequest("relationship is not readable"))
+	}
+
+	// get related type
+	relType := rel.RelType
+	var ref *coal.Ref
+	if rel.Polymorphic {
+		// get reference
+		if rel.Optional {
+			ref = stick.MustGet(ctx.Model, rel.Name).(*coal.Ref)
+		} else {
+			val := stick.MustGet(ctx.Model, rel.Name).(coal.Ref)
+			ref = &val
+		}
+
+		// use referenced type or first candidate
+		relType = rel.RelTypes[0]
+		if ref != nil && ref.Type != "" {
+			relType = ref.Type
+		}
+	}
+
+	// get related controller
+	rc := ctx.Group.controllers[relType]
+	if rc == nil {
+		xo.Abort(xo.F("missing related controller for %s", relType))
+	}
+
+	// prepare sub context
+	subCtx := &Context{
+		Context:        ctx,
+		Data:           stick.Map{},
+		Parent:         ctx.Model,
+		HTTPRequest:    ctx.HTTPRequest,
+		ResponseWriter: nil,
+		Controller:     rc,
+		Group:          ctx.Group,
+		Tracer:         ctx.Tracer,
+		Language:       ctx.Language,
+		Location:       ctx.Location,
+	}
+
+	// copy and prepare request
+	req := *ctx.JSONAPIRequest
+	req.Intent = jsonapi.ListResources
+	req.ResourceType = relType
+	req.ResourceID = ""
+	req.RelatedResource = ""
+	subCtx.JSONAPIRequest = &req
+
+	// finish to-one and polymorphic relationship
+	if rel.ToOne || rel.Polymorphic {
+		// lookup ID of related resource
+		id := coal.New()
+		if rel.Polymorphic {
+			if ref != nil && !ref.ID.IsZero() {
+				id = ref.ID
+			}
+		} else if rel.Optional {
+			rid := stick.MustGet(ctx.Model, rel.Name).(*coal.ID)
+			if rid != nil {
+				id = *rid
+			}
+		} else {
+			id = stick.MustGet(ctx.Model, rel.Name).(coal.ID)
+		}
+
+		// prepare selector
+		selector := bson.M{
+			"_id": id,
+		}
+
+		// when run to request even if the relation is not present to capture
+		// a proper response with potential meta information
+
+		// handle virtual request
+		rc.handle("", subCtx, selector, false)
+
+		// check response
+		if len(subCtx.Response.Data.Many) > 1 {
+			xo.Abort(xo.F("to one relationship returned more than one result"))
+		}
+
+		// pull out resource
+		if len(subCtx.Response.Data.Many) == 1 {
+			subCtx.Response.Data.One = subCtx.Response.Data.Many[0]
+		}
+
+		// unset list
+		subCtx.Response.Data.Many = nil
+	}
+
+	// finish to-many relationship
+	if rel.ToMany {
+		// get IDs from loaded model
+		ids := stick.MustGet(ctx.Model, rel.Name).([]coal.ID)
+
+		// prepare selector
+		selector := bson.M{
+			"_id": bson.M{"$in": ids},
+		}
+
+		// handle virtual request
+		rc.handle("", subCtx, selector, false)
+	}
+
+	// finish has-one relationship
+	if rel.HasOne {
+		// find inverse relationship
+		inverse := rc.meta.Relationships[rel.RelInverse]
 		if inverse == nil {
 			xo.Abort(xo.F("no relationship matching the inverse name %s", inverse.RelInverse))
 		}
@@ -1099,7 +1855,7 @@ func (c *Controller) setRelationship(ctx *Context) {
 
 	// get relationship
 	rel := c.meta.Relationships[ctx.JSONAPIRequest.Relationship]
-	if rel == nil || (!rel.ToOne && !rel.ToMany) {
+	if rel == nil || (!rel.ToOne && !rel.ToMany && !rel.Polymorphic) {
 		xo.Abort(jsonapi.BadRequest("invalid relationship"))
 	}
 
@@ -1112,7 +1868,7 @@ func (c *Controller) setRelationship(ctx *Context) {
 	}
 
 	// assign relationship
-	c.assignRelationship(ctx, ctx.Request, rel)
+	c.assignRelationship(ctx, ctx.Request, rel, "")
 
 	// run modifiers
 	c.runCallbacks(ctx, Modifier, c.Modifiers, http.StatusBadRequest)
@@ -1128,10 +1884,12 @@ func (c *Controller) setRelationship(ctx *Context) {
 	// run validators
 	c.runCallbacks(ctx, Validator, c.Validators, http.StatusBadRequest)
 
-	// replace model
-	found, err := ctx.Store.M(c.Model).Replace(ctx, ctx.Model, false)
+	// write model
+	found, err := c.writeModel(ctx, nil)
 	if coal.IsDuplicate(err) {
 		xo.Abort(ErrDocumentNotUnique.Wrap())
+	} else if coal.ErrDocumentTooLarge.Is(err) {
+		xo.Abort(ErrDocumentTooLarge.Wrap())
 	}
 	xo.AbortIf(err)
 
@@ -1188,31 +1946,11 @@ func (c *Controller) appendToRelationship(ctx *Context) {
 		xo.Abort(jsonapi.BadRequest("relationship is not writable"))
 	}
 
-	// process all references
-	for _, ref := range ctx.Request.Data.Many {
-		// check type
-		if ref.Type != rel.RelType {
-			xo.Abort(jsonapi.BadRequest("resource type mismatch"))
-		}
-
-		// get ID
-		refID, err := coal.FromHex(ref.ID)
-		if err != nil {
-			xo.Abort(jsonapi.BadRequest("invalid relationship ID"))
-		}
-
-		// get current IDs
-		ids := stick.MustGet(ctx.Model, rel.Name).([]coal.ID)
-
-		// check if ID is already present
-		if stick.Contains(ids, refID) {
-			continue
-		}
+	// check linkage
+	c.checkLinkage(ctx.Request, rel, "")
 
-		// add ID
-		ids = append(ids, refID)
-		stick.MustSet(ctx.Model, rel.Name, ids)
-	}
+	// add references
+	c.addReferences(ctx, ctx.Request.Data.Many, rel, "")
 
 	// run modifiers
 	c.runCallbacks(ctx, Modifier, c.Modifiers, http.StatusBadRequest)
@@ -1228,10 +1966,12 @@ func (c *Controller) appendToRelationship(ctx *Context) {
 	// run validators
 	c.runCallbacks(ctx, Validator, c.Validators, http.StatusBadRequest)
 
-	// replace model
-	found, err := ctx.Store.M(c.Model).Replace(ctx, ctx.Model, false)
+	// write model
+	found, err := c.writeModel(ctx, nil)
 	if coal.IsDuplicate(err) {
 		xo.Abort(ErrDocumentNotUnique.Wrap())
+	} else if coal.ErrDocumentTooLarge.Is(err) {
+		xo.Abort(ErrDocumentTooLarge.Wrap())
 	}
 	xo.AbortIf(err)
 
@@ -1288,38 +2028,11 @@ func (c *Controller) removeFromRelationship(ctx *Context) {
 		xo.Abort(jsonapi.BadRequest("relationship is not writable"))
 	}
 
-	// process all references
-	for _, ref := range ctx.Request.Data.Many {
-		// check type
-		if ref.Type != rel.RelType {
-			xo.Abort(jsonapi.BadRequest("resource type mismatch"))
-		}
-
-		// get ID
-		refID, err := coal.FromHex(ref.ID)
-		if err != nil {
-			xo.Abort(jsonapi.BadRequest("invalid relationship ID"))
-		}
-
-		// prepare mark
-		var pos = -1
-
-		// get current IDs
-		ids := stick.MustGet(ctx.Model, rel.Name).([]coal.ID)
-
-		// check if ID is already present
-		for i, id := range ids {
-			if id == refID {
-				pos = i
-			}
-		}
+	// check linkage
+	c.checkLinkage(ctx.Request, rel, "")
 
-		// remove ID if present
-		if pos >= 0 {
-			ids = append(ids[:pos], ids[pos+1:]...)
-			stick.MustSet(ctx.Model, rel.Name, ids)
-		}
-	}
+	// remove references
+	c.removeReferences(ctx, ctx.Request.Data.Many, rel, "")
 
 	// run modifiers
 	c.runCallbacks(ctx, Modifier, c.Modifiers, http.StatusBadRequest)
@@ -1335,10 +2048,12 @@ func (c *Controller) removeFromRelationship(ctx *Context) {
 	// run validators
 	c.runCallbacks(ctx, Validator, c.Validators, http.StatusBadRequest)
 
-	// replace model
-	found, err := ctx.Store.M(c.Model).Replace(ctx, ctx.Model, false)
+	// write model
+	found, err := c.writeModel(ctx, nil)
 	if coal.IsDuplicate(err) {
 		xo.Abort(ErrDocumentNotUnique.Wrap())
+	} else if coal.ErrDocumentTooLarge.Is(err) {
+		xo.Abort(ErrDocumentTooLarge.Wrap())
 	}
 	xo.AbortIf(err)
 
@@ -1431,7 +2146,7 @@ func (c *Controller) initialFields(write bool, r *jsonapi.Request) []string {
 
 	// add relationships
 	for _, f := range c.meta.Relationships {
-		if !write || f.ToOne || f.ToMany {
+		if !write || f.ToOne || f.ToMany || f.Polymorphic {
 			list = append(list, f.Name)
 		}
 	}
@@ -1447,7 +2162,7 @@ func (c *Controller) initialFields(write bool, r *jsonapi.Request) []string {
 			}
 
 			// add relationship
-			if f := c.meta.Relationships[field]; f != nil && (!write || f.ToOne || f.ToMany) {
+			if f := c.meta.Relationships[field]; f != nil && (!write || f.ToOne || f.ToMany || f.Polymorphic) {
 				requested = append(requested, f.Name)
 			}
 		}
@@ -1464,13 +2179,23 @@ func (c *Controller) initialFields(write bool, r *jsonapi.Request) []string {
 
 func (c *Controller) initialProperties(r *jsonapi.Request) []string {
 	// prepare list
-	list := make([]string, 0, len(c.Properties))
+	list := make([]string, 0, len(c.Properties)+len(c.Computed)+len(c.meta.Virtuals))
 
 	// add properties
 	for name := range c.Properties {
 		list = append(list, name)
 	}
 
+	// add computed attributes
+	for key := range c.Computed {
+		list = append(list, key)
+	}
+
+	// add virtual fields
+	for key := range c.meta.Virtuals {
+		list = append(list, key)
+	}
+
 	// check if a field whitelist has been provided
 	if r != nil && len(r.Fields[c.meta.PluralName]) > 0 {
 		// convert requested fields list
@@ -1488,6 +2213,11 @@ func (c *Controller) initialProperties(r *jsonapi.Request) []string {
 			if found {
 				requested = append(requested, name)
 			}
+
+			// add computed attribute or virtual field
+			if c.Computed[field] != nil || c.meta.Virtuals[field] != nil {
+				requested = append(requested, field)
+			}
 		}
 
 		// whitelist requested fields
@@ -1523,23 +2253,43 @@ func (c *Controller) loadModel(ctx *Context) {
 	// lock document if a write operation is expected
 	lock := ctx.Operation.Write()
 
+	// prepare query
+	query := ctx.Query()
+
 	// find model
-	model := c.meta.Make()
-	found, err := ctx.Store.M(c.Model).FindFirst(ctx, model, ctx.Query(), nil, 0, lock)
-	xo.AbortIf(err)
+	models := c.sharedLoad(ctx, !lock, bson.D{
+		{Key: "find", Value: query},
+	}, func() ([]coal.Model, error) {
+		model := c.meta.Make()
+		found, err := ctx.Store.M(c.Model).FindFirst(ctx, model, query, nil, 0, lock)
+		if err != nil || !found {
+			return nil, err
+		}
+		return []coal.Model{model}, nil
+	})
 
 	// check if missing
-	if !found {
+	if len(models) == 0 {
+		// check if filtered
+		if c.DenyFiltered && len(ctx.Filters) > 0 {
+			count, err := ctx.Store.M(c.Model).Count(ctx, ctx.Selector, 0, 1, false)
+			xo.AbortIf(err)
+			if count > 0 {
+				xo.Abort(ErrResourceForbidden.Wrap())
+			}
+		}
+
 		xo.Abort(ErrResourceNotFound.Wrap())
 	}
 
 	// set model
+	model := models[0]
 	ctx.Model = model
 
 	// set original on update operations
 	if ctx.Operation == Update {
-		original := c.meta.Make()
-		xo.AbortIf(stick.BSON.Transfer(model, original))
+		original, err := coal.Clone(model)
+		xo.AbortIf(err)
 		ctx.Original = original
 	}
 
@@ -1547,6 +2297,20 @@ func (c *Controller) loadModel(ctx *Context) {
 	c.runCallbacks(ctx, Verifier, c.Verifiers, http.StatusUnauthorized)
 }
 
+func (c *Controller) guardFilter(ctx *Context) bson.M {
+	// prepare filter
+	filter := bson.M{
+		"_id": ctx.Model.ID(),
+	}
+
+	// add loaded values of guarded fields
+	for _, name := range c.GuardedFields {
+		filter[name] = stick.MustGet(ctx.Original, name)
+	}
+
+	return filter
+}
+
 func (c *Controller) loadModels(ctx *Context) {
 	// trace
 	ctx.Tracer.Push("fire/Controller.loadModels")
@@ -1562,103 +2326,13 @@ func (c *Controller) loadModels(ctx *Context) {
 	}
 
 	// add filters
-	for name, values := range ctx.JSONAPIRequest.Filters {
-		// get field
-		field := c.meta.RequestFields[name]
-		if field == nil {
-			xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
-		}
+	c.addFilters(ctx)
 
-		// handle filter handlers
-		if handler := c.FilterHandlers[field.Name]; handler != nil {
-			expression, err := handler(ctx, values)
-			if xo.IsSafe(err) {
-				xo.Abort(jsonapi.BadRequest(err.Error()))
-			} else if err != nil {
-				xo.Abort(err)
-			}
-			if len(expression) > 0 {
-				ctx.Filters = append(ctx.Filters, expression)
-				continue
-			}
-		}
-
-		// handle attributes filter
-		if field.JSONKey != "" {
-			// check whitelist
-			if !stick.Contains(c.Filters, field.Name) {
-				xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
-			}
-
-			// readability is checked after running authorizers
-
-			// handle boolean attributes
-			if field.Kind == reflect.Bool && len(values) == 1 {
-				ctx.Filters = append(ctx.Filters, bson.M{field.Name: values[0] == "true"})
-				continue
-			}
-
-			// split values
-			var items []string
-			for _, value := range values {
-				if value != "" {
-					items = append(items, strings.Split(value, ",")...)
-				}
-			}
-
-			// handle string values
-			if len(items) > 0 {
-				ctx.Filters = append(ctx.Filters, bson.M{field.Name: bson.M{"$in": items}})
-			} else {
-				ctx.Filters = append(ctx.Filters, bson.M{field.Name: ""})
-			}
-
-			continue
-		}
-
-		// handle relationship filters
-		if field.RelName != "" {
-			// check whitelist
-			if !field.ToOne && !field.ToMany || !stick.Contains(c.Filters, field.Name) {
-				xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
-			}
-
-			// readability is checked after running authorizers
-
-			// convert to object IDs
-			var ids []coal.ID
-			for _, value := range values {
-				if value == "" && (field.ToOne && field.Optional || field.ToMany) {
-					continue
-				}
-				for _, str := range strings.Split(value, ",") {
-					refID, err := coal.FromHex(str)
-					if err != nil {
-						xo.Abort(jsonapi.BadRequest("relationship filter value is not an object ID"))
-					}
-					ids = append(ids, refID)
-				}
-			}
-
-			// set relationship filter
-			if len(ids) > 0 {
-				ctx.Filters = append(ctx.Filters, bson.M{field.Name: bson.M{"$in": ids}})
-			} else {
-				ctx.Filters = append(ctx.Filters, bson.M{field.Name: nil})
-			}
-
-			continue
-		}
-
-		// raise an error on a unsupported filter
-		xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
-	}
-
-	// add search
-	if ctx.JSONAPIRequest.Search != "" {
-		// check availability
-		if !c.Search {
-			xo.Abort(jsonapi.BadRequest("search not supported"))
+	// add search
+	if ctx.JSONAPIRequest.Search != "" {
+		// check availability
+		if !c.Search {
+			xo.Abort(jsonapi.BadRequest("search not supported"))
 		}
 
 		// add filter
@@ -1740,26 +2414,7 @@ func (c *Controller) loadModels(ctx *Context) {
 	readableFields := c.readableFields(ctx, nil)
 
 	// check filter readability
-	for name := range ctx.JSONAPIRequest.Filters {
-		// handle attributes filter
-		if field := c.meta.Attributes[name]; field != nil {
-			if !stick.Contains(readableFields, field.Name) {
-				xo.Abort(jsonapi.BadRequest("filter field is not readable"))
-			}
-			continue
-		}
-
-		// handle relationship filters
-		if field := c.meta.Relationships[name]; field != nil {
-			if !stick.Contains(readableFields, field.Name) {
-				xo.Abort(jsonapi.BadRequest("filter field is not readable"))
-			}
-			continue
-		}
-
-		// raise an error on a unsupported filter
-		xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
-	}
+	c.checkFilters(ctx, readableFields)
 
 	// check sorting readability
 	for _, sorter := range ctx.JSONAPIRequest.Sorting {
@@ -1892,12 +2547,26 @@ func (c *Controller) loadModels(ctx *Context) {
 		flags |= coal.TextScoreSort
 	}
 
-	// load documents
-	models := c.meta.MakeSlice()
-	xo.AbortIf(ctx.Store.M(c.Model).FindAll(ctx, models, query, sorting, skip, limit, false, flags))
+	// enable model pooling
+	if c.PoolModels {
+		flags |= coal.Pooled
+	}
 
-	// set models
-	ctx.Models = coal.Slice(models)
+	// load documents
+	ctx.Models = c.sharedLoad(ctx, true, bson.D{
+		{Key: "list", Value: query},
+		{Key: "sort", Value: sorting},
+		{Key: "skip", Value: skip},
+		{Key: "limit", Value: limit},
+		{Key: "flags", Value: int(flags)},
+	}, func() ([]coal.Model, error) {
+		models := c.meta.MakeSlice()
+		err := ctx.Store.M(c.Model).FindAll(ctx, models, query, sorting, skip, limit, false, flags)
+		if err != nil {
+			return nil, err
+		}
+		return coal.Slice(models), nil
+	})
 
 	// undo reversion
 	if reverse {
@@ -1910,6 +2579,434 @@ func (c *Controller) loadModels(ctx *Context) {
 	c.runCallbacks(ctx, Verifier, c.Verifiers, http.StatusUnauthorized)
 }
 
+func (c *Controller) checkQuery(ctx *Context) {
+	// related resources and relationships are listed
+	list := ctx.Operation == List
+	switch ctx.JSONAPIRequest.Intent {
+	case jsonapi.GetRelatedResources, jsonapi.GetRelationship:
+		list = true
+	}
+
+	for key := range ctx.HTTPRequest.URL.Query() {
+		// get name
+		name := key
+		if i := strings.Index(key, "["); i > 0 && strings.HasSuffix(key, "]") {
+			switch key[:i] {
+			case "fields", "filter":
+				name = key[:i+1] + "]"
+			}
+		}
+
+		// check parameter
+		switch name {
+		case "include", "fields[]", "access_token":
+			continue
+		case "sort", "page[number]", "page[size]", "page[offset]", "page[limit]", "page[before]", "page[after]", "pagination", "search", "wait":
+			if list {
+				continue
+			}
+		case "filter[]":
+			if list || ctx.Operation == Delete && ctx.JSONAPIRequest.CollectionAction == bulkDeleteAction {
+				continue
+			}
+		default:
+			if stick.Contains(c.QueryParameters, key) {
+				continue
+			}
+			if ctx.Group != nil && ctx.Group.locale != nil && key == ctx.Group.locale.ParameterName {
+				continue
+			}
+			xo.Abort(jsonapi.BadRequestParam(fmt.Sprintf(`unknown query parameter "%s"`, key), key))
+		}
+
+		// raise an error on an unsupported parameter
+		xo.Abort(jsonapi.BadRequestParam(fmt.Sprintf(`query parameter "%s" is not supported by %s operations`, key, ctx.Operation), key))
+	}
+}
+
+func (c *Controller) awaitChanges(ctx *Context) {
+	// get wait parameter
+	param := ctx.HTTPRequest.URL.Query().Get("wait")
+	if param == "" {
+		return
+	}
+
+	// trace
+	ctx.Tracer.Push("fire/Controller.awaitChanges")
+	defer ctx.Tracer.Pop()
+
+	// check support
+	if c.WaitLimit <= 0 {
+		xo.Abort(jsonapi.BadRequestParam("long polling not supported", "wait"))
+	}
+
+	// parse duration
+	wait, err := time.ParseDuration(param)
+	if err != nil || wait <= 0 {
+		xo.Abort(jsonapi.BadRequestParam("invalid wait duration", "wait"))
+	} else if wait > c.WaitLimit {
+		xo.Abort(jsonapi.BadRequestParam("max wait duration exceeded", "wait"))
+	}
+
+	// prepare a copy to not alter the context
+	copied := *ctx
+	copied.Selector = bson.M{}
+	for key, value := range ctx.Selector {
+		copied.Selector[key] = value
+	}
+	copied.Filters = []bson.M{}
+	copied.RelationshipFilters = map[string][]bson.M{}
+	copied.ReadableFields = append([]string{}, ctx.ReadableFields...)
+	copied.ReadableProperties = append([]string{}, ctx.ReadableProperties...)
+
+	// add filters and run authorizers
+	xo.AbortIf(c.Store.Target(c.Model).T(ctx.Context, true, func(tc context.Context) error {
+		return copied.With(tc, func() error {
+			c.addFilters(&copied)
+			c.runCallbacks(&copied, Authorizer, c.Authorizers, http.StatusUnauthorized)
+			return nil
+		})
+	}))
+
+	// get query
+	query := copied.Query()
+
+	// get reporter
+	var reporter func(error)
+	if ctx.Group != nil {
+		reporter = ctx.Group.reporter
+	}
+
+	// create context
+	ct, cancel := context.WithTimeout(ctx.Context, wait)
+	defer cancel()
+
+	// await change
+	c.watcher.await(ct, c.Store, c.Model, reporter, func(event coal.Event, model coal.Model) bool {
+		// deleted documents cannot be matched
+		if model == nil {
+			return true
+		}
+
+		// match model
+		ok, err := coal.Match(model, query, true)
+		return ok || err != nil
+	})
+}
+
+func (c *Controller) parseFilters(ctx *Context) {
+	// the parser does not handle query parameters for collection actions
+	for key, values := range ctx.HTTPRequest.URL.Query() {
+		if strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]") {
+			if ctx.JSONAPIRequest.Filters == nil {
+				ctx.JSONAPIRequest.Filters = map[string][]string{}
+			}
+			name := key[7 : len(key)-1]
+			ctx.JSONAPIRequest.Filters[name] = append(ctx.JSONAPIRequest.Filters[name], values...)
+		}
+	}
+}
+
+func (c *Controller) addFilters(ctx *Context) {
+	for name, values := range ctx.JSONAPIRequest.Filters {
+		// handle map key filters
+		if field, key := c.mapFilter(name); field != nil {
+			c.addMapFilter(ctx, field, key, name, values)
+			continue
+		}
+
+		// get field
+		field := c.meta.RequestFields[name]
+		if field == nil {
+			xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
+		}
+
+		// handle filter handlers
+		if handler := c.FilterHandlers[field.Name]; handler != nil {
+			expression, err := handler(ctx, values)
+			if xo.IsSafe(err) {
+				xo.Abort(jsonapi.BadRequest(err.Error()))
+			} else if err != nil {
+				xo.Abort(err)
+			}
+			if len(expression) > 0 {
+				ctx.Filters = append(ctx.Filters, expression)
+				continue
+			}
+		}
+
+		// handle attributes filter
+		if field.JSONKey != "" {
+			// check whitelist
+			if !stick.Contains(c.Filters, field.Name) {
+				xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
+			}
+
+			// readability is checked after running authorizers
+
+			// handle boolean attributes
+			if field.Kind == reflect.Bool && len(values) == 1 {
+				ctx.Filters = append(ctx.Filters, bson.M{field.Name: c.encryptFilter(ctx, field, values[0] == "true")})
+				continue
+			}
+
+			// split values
+			var items []string
+			for _, value := range values {
+				if value != "" {
+					items = append(items, strings.Split(value, ",")...)
+				}
+			}
+
+			// handle custom type values
+			if typ := coal.LookupType(field.Type); len(items) > 0 && typ != nil && typ.Parse != nil {
+				list := make([]interface{}, 0, len(items))
+				for _, item := range items {
+					value, err := typ.Parse(item)
+					if err != nil {
+						xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid value for filter "%s"`, name)))
+					}
+					list = append(list, c.encryptFilter(ctx, field, value))
+				}
+				ctx.Filters = append(ctx.Filters, bson.M{field.Name: bson.M{"$in": list}})
+				continue
+			}
+
+			// handle encrypted string values
+			if len(items) > 0 && c.encrypted(ctx, field) {
+				list := make([]interface{}, 0, len(items))
+				for _, item := range items {
+					list = append(list, c.encryptFilter(ctx, field, item))
+				}
+				ctx.Filters = append(ctx.Filters, bson.M{field.Name: bson.M{"$in": list}})
+				continue
+			}
+
+			// handle string values
+			if len(items) > 0 {
+				ctx.Filters = append(ctx.Filters, bson.M{field.Name: bson.M{"$in": items}})
+			} else {
+				ctx.Filters = append(ctx.Filters, bson.M{field.Name: c.encryptFilter(ctx, field, "")})
+			}
+
+			continue
+		}
+
+		// handle relationship filters
+		if field.RelName != "" {
+			// check whitelist
+			if !field.ToOne && !field.ToMany || !stick.Contains(c.Filters, field.Name) {
+				xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
+			}
+
+			// readability is checked after running authorizers
+
+			// convert to object IDs
+			var ids []coal.ID
+			for _, value := range values {
+				if value == "" && (field.ToOne && field.Optional || field.ToMany) {
+					continue
+				}
+				for _, str := range strings.Split(value, ",") {
+					refID, err := coal.FromHex(str)
+					if err != nil {
+						xo.Abort(jsonapi.BadRequest("relationship filter value is not an object ID"))
+					}
+					ids = append(ids, refID)
+				}
+			}
+
+			// set relationship filter
+			if len(ids) > 0 {
+				ctx.Filters = append(ctx.Filters, bson.M{field.Name: bson.M{"$in": ids}})
+			} else {
+				ctx.Filters = append(ctx.Filters, bson.M{field.Name: nil})
+			}
+
+			continue
+		}
+
+		// raise an error on a unsupported filter
+		xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
+	}
+}
+
+func (c *Controller) encrypted(ctx *Context, field *coal.Field) bool {
+	return ctx.Store.Encrypter != nil && coal.GetEncryption(c.Model, field.Name) == coal.Deterministic
+}
+
+func (c *Controller) encryptFilter(ctx *Context, field *coal.Field, value interface{}) interface{} {
+	// check encryption
+	if !c.encrypted(ctx, field) {
+		return value
+	}
+
+	// encrypt value
+	value, err := ctx.Store.Encrypter.EncryptValue(ctx, c.Model, field.Name, value)
+	xo.AbortIf(err)
+
+	return value
+}
+
+func (c *Controller) mapFilter(name string) (*coal.Field, string) {
+	// split name
+	attribute, key, ok := strings.Cut(name, ".")
+	if !ok {
+		return nil, ""
+	}
+
+	// get field
+	field := c.meta.Attributes[attribute]
+	if field == nil || field.MapValue == nil {
+		return nil, ""
+	}
+
+	return field, key
+}
+
+func (c *Controller) addMapFilter(ctx *Context, field *coal.Field, key, name string, values []string) {
+	// check whitelist
+	path := field.Name + "." + key
+	if !stick.Contains(c.Filters, path) {
+		xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
+	}
+
+	// readability is checked after running authorizers
+
+	// handle boolean values
+	if field.MapValue.Kind() == reflect.Bool {
+		if len(values) != 1 {
+			xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid value for filter "%s"`, name)))
+		}
+		ctx.Filters = append(ctx.Filters, bson.M{path: values[0] == "true"})
+		return
+	}
+
+	// split values
+	var items []string
+	for _, value := range values {
+		if value != "" {
+			items = append(items, strings.Split(value, ",")...)
+		}
+	}
+
+	// handle missing values
+	if len(items) == 0 {
+		ctx.Filters = append(ctx.Filters, bson.M{path: bson.M{"$exists": false}})
+		return
+	}
+
+	// handle custom type values
+	if typ := coal.LookupType(field.MapValue); typ != nil && typ.Parse != nil {
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			value, err := typ.Parse(item)
+			if err != nil {
+				xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid value for filter "%s"`, name)))
+			}
+			list = append(list, value)
+		}
+		ctx.Filters = append(ctx.Filters, bson.M{path: bson.M{"$in": list}})
+		return
+	}
+
+	// handle other values
+	switch field.MapValue.Kind() {
+	case reflect.String, reflect.Interface:
+		ctx.Filters = append(ctx.Filters, bson.M{path: bson.M{"$in": items}})
+	default:
+		xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`unsupported filter "%s"`, name)))
+	}
+}
+
+func (c *Controller) checkFilters(ctx *Context, readableFields []string) {
+	for name := range ctx.JSONAPIRequest.Filters {
+		// handle map key filters
+		if field, _ := c.mapFilter(name); field != nil {
+			if !stick.Contains(readableFields, field.Name) {
+				xo.Abort(jsonapi.BadRequest("filter field is not readable"))
+			}
+			continue
+		}
+
+		// handle attributes filter
+		if field := c.meta.Attributes[name]; field != nil {
+			if !stick.Contains(readableFields, field.Name) {
+				xo.Abort(jsonapi.BadRequest("filter field is not readable"))
+			}
+			continue
+		}
+
+		// handle relationship filters
+		if field := c.meta.Relationships[name]; field != nil {
+			if !stick.Contains(readableFields, field.Name) {
+				xo.Abort(jsonapi.BadRequest("filter field is not readable"))
+			}
+			continue
+		}
+
+		// raise an error on a unsupported filter
+		xo.Abort(jsonapi.BadRequest(fmt.Sprintf(`invalid filter "%s"`, name)))
+	}
+}
+
+func (c *Controller) sharedLoad(ctx *Context, shareable bool, key bson.D, fn func() ([]coal.Model, error)) []coal.Model {
+	// load directly if not enabled or shareable
+	if !c.DeduplicateReads || !shareable {
+		models, err := fn()
+		xo.AbortIf(err)
+		return models
+	}
+
+	// trace
+	ctx.Tracer.Push("fire/Controller.sharedLoad")
+	defer ctx.Tracer.Pop()
+
+	// translate queries to get a stable key
+	for i, item := range key {
+		if query, ok := item.Value.(bson.M); ok {
+			doc, err := ctx.Store.M(c.Model).T().Document(query)
+			xo.AbortIf(err)
+			key[i].Value = doc
+		}
+	}
+
+	// scope key to store and database
+	key = append(bson.D{
+		{Key: "store", Value: fmt.Sprintf("%p", ctx.Store)},
+		{Key: "database", Value: ctx.Store.DB().Name()},
+	}, key...)
+
+	// encode key
+	rawKey, err := bson.Marshal(key)
+	xo.AbortIf(err)
+
+	// load models
+	res, shared, err := c.flight.do(string(rawKey), func() (interface{}, error) {
+		return fn()
+	})
+	xo.AbortIf(err)
+
+	// get models
+	models := res.([]coal.Model)
+
+	// return models directly if not shared
+	if !shared {
+		return models
+	}
+
+	// otherwise, copy shared models
+	ctx.Tracer.Tag("shared", true)
+	list := make([]coal.Model, 0, len(models))
+	for _, model := range models {
+		model2 := c.meta.Make()
+		xo.AbortIf(stick.BSON.Transfer(model, model2))
+		list = append(list, model2)
+	}
+
+	return list
+}
+
 func (c *Controller) assignData(ctx *Context, res *jsonapi.Resource) {
 	// trace
 	ctx.Tracer.Push("fire/Controller.assignData")
@@ -1941,10 +3038,16 @@ func (c *Controller) assignData(ctx *Context, res *jsonapi.Resource) {
 	verifyReadOnly := make([]string, 0, len(res.Attributes)+len(res.Relationships))
 
 	// collect properties
-	properties := make([]string, 0, len(c.Properties))
+	properties := make([]string, 0, len(c.Properties)+len(c.Computed)+len(c.meta.Virtuals))
 	for _, key := range c.Properties {
 		properties = append(properties, key)
 	}
+	for key := range c.Computed {
+		properties = append(properties, key)
+	}
+	for key := range c.meta.Virtuals {
+		properties = append(properties, key)
+	}
 
 	// whitelist attributes
 	attributes := make(jsonapi.Map)
@@ -1952,8 +3055,8 @@ func (c *Controller) assignData(ctx *Context, res *jsonapi.Resource) {
 		// get field
 		field := c.meta.Attributes[name]
 		if field == nil {
-			// continue if property
-			if stick.Contains(properties, name) {
+			// continue if property or lenient
+			if stick.Contains(properties, name) || c.LenientDocuments {
 				continue
 			}
 
@@ -1976,19 +3079,25 @@ func (c *Controller) assignData(ctx *Context, res *jsonapi.Resource) {
 	}
 
 	// map attributes to struct
-	xo.AbortIf(attributes.Assign(ctx.Model))
+	c.assignAttributes(ctx, attributes)
 
 	// iterate relationships
 	for name, rel := range res.Relationships {
 		// get relationship
 		field := c.meta.Relationships[name]
 		if field == nil {
+			// continue if lenient
+			if c.LenientDocuments {
+				continue
+			}
+
+			// otherwise, raise error
 			pointer := fmt.Sprintf("/data/relationships/%s", name)
 			xo.Abort(jsonapi.BadRequestPointer("invalid relationship", pointer))
 		}
 
 		// check whitelist
-		if !stick.Contains(whitelist, name) || (!field.ToOne && !field.ToMany) {
+		if !stick.Contains(whitelist, name) || (!field.ToOne && !field.ToMany && !field.Polymorphic) {
 			// ignore violation if tolerated or verify read only access
 			if stick.Contains(c.TolerateViolations, field.Name) {
 				continue
@@ -1996,88 +3105,345 @@ func (c *Controller) assignData(ctx *Context, res *jsonapi.Resource) {
 			verifyReadOnly = append(verifyReadOnly, field.Name)
 		}
 
-		// assign relationship
-		c.assignRelationship(ctx, rel, field)
+		// assign relationship
+		c.assignRelationship(ctx, rel, field, "/data/relationships/"+name)
+	}
+
+	// verify read only fields
+	for _, field := range verifyReadOnly {
+		if ctx.Modified(field) {
+			xo.Abort(jsonapi.BadRequestPointer("field is not writable", field))
+		}
+	}
+}
+
+func (c *Controller) assignAttributes(ctx *Context, attributes jsonapi.Map) {
+	// map attributes to struct
+	err := attributes.Assign(ctx.Model)
+	if err == nil {
+		return
+	}
+
+	// handle type errors
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		pointer := "/data/attributes/" + strings.ReplaceAll(typeErr.Field, ".", "/")
+		xo.Abort(jsonapi.BadRequestPointer("invalid attribute type", pointer))
+	}
+
+	// find invalid attribute
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if (jsonapi.Map{name: attributes[name]}).Assign(c.meta.Make()) != nil {
+			xo.Abort(jsonapi.BadRequestPointer("invalid attribute value", "/data/attributes/"+name))
+		}
+	}
+
+	// otherwise, raise error
+	xo.Abort(jsonapi.BadRequestPointer("invalid attributes", "/data/attributes"))
+}
+
+func (c *Controller) checkLinkage(rel *jsonapi.Document, field *coal.Field, pointer string) {
+	// check data
+	if rel.Data == nil {
+		return
+	}
+
+	// check to-one and polymorphic linkage
+	if (field.ToOne || field.Polymorphic) && rel.Data.Many != nil {
+		xo.Abort(jsonapi.BadRequestPointer("invalid relationship linkage", pointer+"/data"))
+	}
+
+	// check to-many linkage
+	if field.ToMany && rel.Data.One != nil {
+		xo.Abort(jsonapi.BadRequestPointer("invalid relationship linkage", pointer+"/data"))
+	}
+}
+
+func (c *Controller) assignRelationship(ctx *Context, rel *jsonapi.Document, field *coal.Field, pointer string) {
+	// trace
+	ctx.Tracer.Push("fire/Controller.assignRelationship")
+	defer ctx.Tracer.Pop()
+
+	// check linkage
+	c.checkLinkage(rel, field, pointer)
+
+	// handle to-one relationship
+	if field.ToOne {
+		// prepare zero value
+		var id coal.ID
+
+		// set and check ID if available
+		if rel.Data != nil && rel.Data.One != nil {
+			// check type
+			if rel.Data.One.Type != field.RelType {
+				xo.Abort(jsonapi.BadRequestPointer("resource type mismatch", pointer+"/data/type"))
+			}
+
+			// get ID
+			relID, err := coal.FromHex(rel.Data.One.ID)
+			if err != nil {
+				xo.Abort(jsonapi.BadRequestPointer("invalid relationship ID", pointer+"/data/id"))
+			}
+
+			// extract ID
+			id = relID
+		}
+
+		// set ID properly
+		if !field.Optional {
+			stick.MustSet(ctx.Model, field.Name, id)
+		} else {
+			if !id.IsZero() {
+				stick.MustSet(ctx.Model, field.Name, &id)
+			} else {
+				stick.MustSet(ctx.Model, field.Name, stick.N[coal.ID]())
+			}
+		}
+	}
+
+	// handle polymorphic relationship
+	if field.Polymorphic {
+		// prepare zero value
+		var ref coal.Ref
+
+		// set and check reference if available
+		if rel.Data != nil && rel.Data.One != nil {
+			// check type
+			if !stick.Contains(field.RelTypes, rel.Data.One.Type) {
+				xo.Abort(jsonapi.BadRequestPointer("resource type mismatch", pointer+"/data/type"))
+			}
+
+			// get ID
+			relID, err := coal.FromHex(rel.Data.One.ID)
+			if err != nil {
+				xo.Abort(jsonapi.BadRequestPointer("invalid relationship ID", pointer+"/data/id"))
+			}
+
+			// extract reference
+			ref = coal.Ref{
+				Type: rel.Data.One.Type,
+				ID:   relID,
+			}
+		}
+
+		// set reference properly
+		if !field.Optional {
+			stick.MustSet(ctx.Model, field.Name, ref)
+		} else {
+			if !ref.IsZero() {
+				stick.MustSet(ctx.Model, field.Name, &ref)
+			} else {
+				stick.MustSet(ctx.Model, field.Name, stick.N[coal.Ref]())
+			}
+		}
+	}
+
+	// handle to-many relationship
+	if field.ToMany {
+		// get references
+		var refs []*jsonapi.Resource
+		if rel.Data != nil {
+			refs = rel.Data.Many
+		}
+
+		// get operation
+		operation, _ := rel.Meta["operation"].(string)
+
+		// apply operation
+		switch operation {
+		case "", "replace":
+			stick.MustSet(ctx.Model, field.Name, c.parseReferences(refs, field, pointer))
+		case "add":
+			c.addReferences(ctx, refs, field, pointer)
+		case "remove":
+			c.removeReferences(ctx, refs, field, pointer)
+		default:
+			xo.Abort(jsonapi.BadRequestPointer("invalid relationship operation", pointer+"/meta/operation"))
+		}
+	}
+}
+
+func (c *Controller) parseReferences(refs []*jsonapi.Resource, field *coal.Field, pointer string) []coal.ID {
+	// check length
+	if len(refs) == 0 {
+		return nil
+	}
+
+	// prepare IDs
+	ids := make([]coal.ID, len(refs))
+
+	// convert all IDs
+	for i, ref := range refs {
+		// check type
+		if ref.Type != field.RelType {
+			xo.Abort(jsonapi.BadRequestPointer("resource type mismatch", fmt.Sprintf("%s/data/%d/type", pointer, i)))
+		}
+
+		// get ID
+		refID, err := coal.FromHex(ref.ID)
+		if err != nil {
+			xo.Abort(jsonapi.BadRequestPointer("invalid relationship ID", fmt.Sprintf("%s/data/%d/id", pointer, i)))
+		}
+
+		// set ID
+		ids[i] = refID
+	}
+
+	return ids
+}
+
+func (c *Controller) addReferences(ctx *Context, refs []*jsonapi.Resource, field *coal.Field, pointer string) {
+	// get added IDs
+	added := c.parseReferences(refs, field, pointer)
+
+	// get current IDs
+	current := stick.MustGet(ctx.Model, field.Name).([]coal.ID)
+
+	// prepare IDs
+	ids := make([]coal.ID, len(current), len(current)+len(added))
+	copy(ids, current)
+
+	// add missing IDs
+	for _, refID := range added {
+		if !stick.Contains(ids, refID) {
+			ids = append(ids, refID)
+		}
+	}
+
+	// set IDs
+	stick.MustSet(ctx.Model, field.Name, ids)
+
+	// skip atomic update if the stored field is not an array
+	if current == nil {
+		return
 	}
 
-	// verify read only fields
-	for _, field := range verifyReadOnly {
-		if ctx.Modified(field) {
-			xo.Abort(jsonapi.BadRequestPointer("field is not writable", field))
+	// queue atomic update
+	c.queueReferenceUpdate(ctx, "$addToSet", field, bson.M{
+		"$each": stick.Unique(append([]coal.ID{}, added...)),
+	})
+}
+
+func (c *Controller) removeReferences(ctx *Context, refs []*jsonapi.Resource, field *coal.Field, pointer string) {
+	// get removed IDs
+	removed := c.parseReferences(refs, field, pointer)
+
+	// get current IDs
+	current := stick.MustGet(ctx.Model, field.Name).([]coal.ID)
+
+	// keep remaining IDs
+	ids := make([]coal.ID, 0, len(current))
+	for _, id := range current {
+		if !stick.Contains(removed, id) {
+			ids = append(ids, id)
 		}
 	}
-}
 
-func (c *Controller) assignRelationship(ctx *Context, rel *jsonapi.Document, field *coal.Field) {
-	// trace
-	ctx.Tracer.Push("fire/Controller.assignRelationship")
-	defer ctx.Tracer.Pop()
+	// set IDs
+	stick.MustSet(ctx.Model, field.Name, ids)
 
-	// handle to-one relationship
-	if field.ToOne {
-		// prepare zero value
-		var id coal.ID
+	// skip atomic update if the stored field is not an array
+	if current == nil {
+		return
+	}
 
-		// set and check ID if available
-		if rel.Data != nil && rel.Data.One != nil {
-			// check type
-			if rel.Data.One.Type != field.RelType {
-				xo.Abort(jsonapi.BadRequest("resource type mismatch"))
-			}
+	// queue atomic update
+	c.queueReferenceUpdate(ctx, "$pullAll", field, append([]coal.ID{}, removed...))
+}
 
-			// get ID
-			relID, err := coal.FromHex(rel.Data.One.ID)
-			if err != nil {
-				xo.Abort(jsonapi.BadRequest("invalid relationship ID"))
-			}
+func (c *Controller) queueReferenceUpdate(ctx *Context, operator string, field *coal.Field, value interface{}) {
+	// ensure map
+	if ctx.referenceUpdates == nil {
+		ctx.referenceUpdates = bson.M{}
+	}
 
-			// extract ID
-			id = relID
+	// get operator document
+	doc, _ := ctx.referenceUpdates[operator].(bson.M)
+	if doc == nil {
+		doc = bson.M{}
+		ctx.referenceUpdates[operator] = doc
+	}
+
+	// add update
+	doc[field.BSONKey] = value
+}
+
+func (c *Controller) writeModel(ctx *Context, filter bson.M) (bool, error) {
+	// replace model if no reference updates are pending
+	if len(ctx.referenceUpdates) == 0 {
+		if filter == nil {
+			return ctx.Store.M(c.Model).Replace(ctx, ctx.Model, false)
 		}
+		return ctx.Store.M(c.Model).ReplaceFirst(ctx, filter, ctx.Model, false)
+	}
 
-		// set ID properly
-		if !field.Optional {
-			stick.MustSet(ctx.Model, field.Name, id)
-		} else {
-			if !id.IsZero() {
-				stick.MustSet(ctx.Model, field.Name, &id)
-			} else {
-				stick.MustSet(ctx.Model, field.Name, stick.N[coal.ID]())
-			}
+	// validate model as a replacement would
+	err := ctx.Model.Validate()
+	if err != nil {
+		return false, xo.W(err)
+	}
+
+	// check size as a replacement would
+	err = coal.CheckSize(ctx.Store, ctx.Model)
+	if err != nil {
+		return false, err
+	}
+
+	// collect updated keys
+	updated := map[string]bool{}
+	for _, doc := range ctx.referenceUpdates {
+		for key := range doc.(bson.M) {
+			updated[key] = true
 		}
 	}
 
-	// handle to-many relationship
-	if field.ToMany {
-		// prepare IDs
-		var ids []coal.ID
-
-		// check if data is available
-		if rel.Data != nil && len(rel.Data.Many) > 0 {
-			// prepare IDs
-			ids = make([]coal.ID, len(rel.Data.Many))
-
-			// convert all IDs
-			for i, r := range rel.Data.Many {
-				// check type
-				if r.Type != field.RelType {
-					xo.Abort(jsonapi.BadRequest("resource type mismatch"))
-				}
+	// marshal model
+	var doc bson.M
+	err = stick.BSON.Transfer(ctx.Model, &doc)
+	if err != nil {
+		return false, err
+	}
 
-				// get ID
-				relID, err := coal.FromHex(r.ID)
-				if err != nil {
-					xo.Abort(jsonapi.BadRequest("invalid relationship ID"))
-				}
+	// prepare update
+	update := bson.M{}
+	for operator, doc := range ctx.referenceUpdates {
+		update[operator] = doc
+	}
 
-				// set ID
-				ids[i] = relID
-			}
+	// set or unset all other fields as a replacement would
+	set := bson.M{}
+	unset := bson.M{}
+	for _, field := range c.meta.OrderedFields {
+		// skip ignored and updated fields
+		if field.BSONKey == "" || updated[field.BSONKey] {
+			continue
+		}
+
+		// add field
+		if value, ok := doc[field.BSONKey]; ok {
+			set[field.BSONKey] = value
+		} else {
+			unset[field.BSONKey] = true
 		}
+	}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
 
-		// set IDs
-		stick.MustSet(ctx.Model, field.Name, ids)
+	// update model
+	if filter == nil {
+		return ctx.Store.M(c.Model).Update(ctx, ctx.Model, ctx.Model.ID(), update, false)
 	}
+
+	return ctx.Store.M(c.Model).UpdateFirst(ctx, ctx.Model, filter, update, nil, false)
 }
 
 func (c *Controller) preloadRelationships(ctx *Context, models []coal.Model) map[string]map[coal.ID][]coal.ID {
@@ -2110,8 +3476,8 @@ func (c *Controller) preloadRelationships(ctx *Context, models []coal.Model) map
 
 	// go through all relationships
 	for _, field := range c.meta.Relationships {
-		// skip to one and to many relationships
-		if field.ToOne || field.ToMany {
+		// skip to one, to many and polymorphic relationships
+		if field.ToOne || field.ToMany || field.Polymorphic {
 			continue
 		}
 
@@ -2213,6 +3579,157 @@ func (c *Controller) preloadRelationships(ctx *Context, models []coal.Model) map
 	return relationships
 }
 
+func (c *Controller) countRelationships(ctx *Context, models []coal.Model, resources []*jsonapi.Resource) {
+	// check relationships
+	if len(c.CountRelationships) == 0 || len(models) == 0 {
+		return
+	}
+
+	// trace
+	ctx.Tracer.Push("fire/Controller.countRelationships")
+	defer ctx.Tracer.Pop()
+
+	// collect model IDs
+	modelIDs := make([]coal.ID, 0, len(models))
+	for _, model := range models {
+		modelIDs = append(modelIDs, model.ID())
+	}
+
+	// go through all relationships
+	for _, name := range c.CountRelationships {
+		// get field
+		field := c.meta.Relationships[name]
+
+		// check readability
+		var readable bool
+		for _, resource := range resources {
+			if resource.Relationships[field.RelName] != nil {
+				readable = true
+				break
+			}
+		}
+		if !readable {
+			continue
+		}
+
+		// use materialized counter if available
+		if counting := c.Counters[name]; counting != nil {
+			// load counts
+			counts, err := counting.Load(ctx, ctx.Store, modelIDs...)
+			xo.AbortIf(err)
+
+			// set counts
+			setRelationshipCounts(field, models, resources, counts)
+
+			continue
+		}
+
+		// get related controller
+		rc := ctx.Group.controllers[field.RelType]
+		if rc == nil {
+			xo.Abort(xo.F("missing related controller %s", field.RelType))
+		}
+
+		// find relationship
+		rel := rc.meta.Relationships[field.RelInverse]
+		if rel == nil {
+			xo.Abort(xo.F("no relationship matching the inverse name %s", field.RelInverse))
+		}
+
+		// prepare query
+		query := bson.M{
+			rel.Name: bson.M{
+				"$in": modelIDs,
+			},
+		}
+
+		// exclude soft deleted documents
+		if rc.SoftDelete {
+			// get soft delete field
+			softDeleteField := coal.L(rc.Model, "fire-soft-delete", true)
+
+			// set filter
+			query[softDeleteField] = nil
+		}
+
+		// prepare filters
+		filters := []bson.M{query}
+
+		// add relationship filters
+		filters = append(filters, ctx.RelationshipFilters[field.Name]...)
+
+		// count references
+		counts := make(map[coal.ID]int64, len(modelIDs))
+		if !ctx.Store.Supports(coal.Aggregations) {
+			// project references if aggregations are not supported
+			references, err := ctx.Store.M(rc.Model).ProjectAll(ctx, bson.M{
+				"$and": filters,
+			}, rel.Name, nil, 0, 0, false)
+			xo.AbortIf(err)
+
+			// count references
+			for _, value := range references {
+				if rid, ok := value.(coal.ID); ok {
+					counts[rid]++
+				} else if rids, ok := value.(bson.A); ok {
+					for _, rid := range rids {
+						if rid, ok := rid.(coal.ID); ok {
+							counts[rid]++
+						}
+					}
+				}
+			}
+		} else {
+			// prepare pipeline
+			pipeline := coal.NewPipeline(rc.Model).Match(bson.M{
+				"$and": filters,
+			})
+
+			// unwind to many references
+			if rel.ToMany {
+				pipeline = pipeline.Unwind(rel.Name).Match(bson.M{
+					rel.Name: bson.M{
+						"$in": modelIDs,
+					},
+				})
+			}
+
+			// group references
+			pipeline = pipeline.Group("$"+rel.Name, bson.M{
+				"count": bson.M{
+					"$sum": 1,
+				},
+			})
+
+			// aggregate counts
+			var groups []struct {
+				ID    coal.ID `bson:"_id"`
+				Count int64   `bson:"count"`
+			}
+			err := ctx.Store.M(rc.Model).Aggregate(ctx, &groups, pipeline)
+			xo.AbortIf(err)
+
+			// collect counts
+			for _, group := range groups {
+				counts[group.ID] = group.Count
+			}
+		}
+
+		// set counts
+		setRelationshipCounts(field, models, resources, counts)
+	}
+}
+
+func setRelationshipCounts(field *coal.Field, models []coal.Model, resources []*jsonapi.Resource, counts map[coal.ID]int64) {
+	for i, resource := range resources {
+		if doc := resource.Relationships[field.RelName]; doc != nil {
+			doc.Meta = jsonapi.Map{
+				"count": counts[models[i].ID()],
+			}
+		}
+	}
+}
+
 func (c *Controller) resourceForModel(ctx *Context, model coal.Model, relationships map[string]map[coal.ID][]coal.ID) *jsonapi.Resource {
 	// trace
 	ctx.Tracer.Push("fire/Controller.resourceForModel")
@@ -2330,6 +3847,36 @@ func (c *Controller) constructResource(ctx *Context, model coal.Model, relations
 				}
 			}
 
+			// set links and reference
+			resource.Relationships[field.RelName] = &jsonapi.Document{
+				Links: links,
+				Data: &jsonapi.HybridResource{
+					One: reference,
+				},
+			}
+		} else if field.Polymorphic {
+			// prepare reference
+			var reference *jsonapi.Resource
+
+			// get reference
+			var ref coal.Ref
+			if field.Optional {
+				// get and check optional field
+				if r := stick.MustGet(model, field.Name).(*coal.Ref); r != nil {
+					ref = *r
+				}
+			} else {
+				ref = stick.MustGet(model, field.Name).(coal.Ref)
+			}
+
+			// create reference if available
+			if !ref.IsZero() {
+				reference = &jsonapi.Resource{
+					Type: ref.Type,
+					ID:   ref.ID.Hex(),
+				}
+			}
+
 			// set links and reference
 			resource.Relationships[field.RelName] = &jsonapi.Document{
 				Links: links,
@@ -2455,6 +4002,36 @@ func (c *Controller) constructResource(ctx *Context, model coal.Model, relations
 		resource.Attributes[key] = value
 	}
 
+	// compute attributes
+	for key, handler := range c.Computed {
+		// check whitelist
+		if !stick.Contains(readableProperties, key) {
+			continue
+		}
+
+		// compute attribute
+		value, err := handler(ctx, model)
+		xo.AbortIf(err)
+
+		// set attribute
+		resource.Attributes[key] = value
+	}
+
+	// compute virtual fields
+	for key, virtual := range c.meta.Virtuals {
+		// check whitelist
+		if !stick.Contains(readableProperties, key) {
+			continue
+		}
+
+		// compute value
+		value, err := virtual.Compute(model)
+		xo.AbortIf(err)
+
+		// set attribute
+		resource.Attributes[key] = value
+	}
+
 	// add score meta on search
 	if ctx.Operation == List && ctx.JSONAPIRequest.Search != "" {
 		resource.Meta = jsonapi.Map{
@@ -2465,6 +4042,158 @@ func (c *Controller) constructResource(ctx *Context, model coal.Model, relations
 	return resource
 }
 
+func (c *Controller) includeResources(ctx *Context, resources []*jsonapi.Resource) []*jsonapi.Resource {
+	// check includes
+	if len(c.Includes) == 0 || len(ctx.JSONAPIRequest.Include) == 0 {
+		return nil
+	}
+
+	// trace
+	ctx.Tracer.Push("fire/Controller.includeResources")
+	defer ctx.Tracer.Pop()
+
+	// check paths and collect parent paths
+	var paths []string
+	for _, path := range ctx.JSONAPIRequest.Include {
+		segments := strings.Split(path, ".")
+		for i := range segments {
+			// get path
+			sub := strings.Join(segments[:i+1], ".")
+			if stick.Contains(paths, sub) {
+				continue
+			}
+
+			// check path
+			gate, ok := c.Includes[sub]
+			if !ok {
+				xo.Abort(jsonapi.BadRequestParam(fmt.Sprintf(`invalid include path "%s"`, path), "include"))
+			}
+
+			// check gate
+			if gate != nil && !gate(ctx) {
+				err := jsonapi.ErrorFromStatus(http.StatusForbidden, fmt.Sprintf(`include path "%s" is not permitted`, sub))
+				err.Source = &jsonapi.ErrorSource{Parameter: "include"}
+				xo.Abort(err)
+			}
+
+			// add path
+			paths = append(paths, sub)
+		}
+	}
+
+	// sort paths by depth
+	sort.SliceStable(paths, func(i, j int) bool {
+		return strings.Count(paths[i], ".") < strings.Count(paths[j], ".")
+	})
+
+	// prepare index of known resources
+	known := map[string]bool{}
+	for _, res := range resources {
+		known[res.Type+"/"+res.ID] = true
+	}
+
+	// load related resources
+	var included []*jsonapi.Resource
+	loaded := map[string][]*jsonapi.Resource{"": resources}
+	for _, path := range paths {
+		// get parent resources and relationship name
+		parent, name := "", path
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			parent, name = path[:i], path[i+1:]
+		}
+
+		// collect references by type
+		var types []string
+		refs := map[string][]coal.ID{}
+		for _, res := range loaded[parent] {
+			// get linkage
+			doc := res.Relationships[name]
+			if doc == nil || doc.Data == nil {
+				continue
+			}
+
+			// collect references
+			for _, ref := range append([]*jsonapi.Resource{doc.Data.One}, doc.Data.Many...) {
+				if ref == nil {
+					continue
+				}
+				id, err := coal.FromHex(ref.ID)
+				if err != nil {
+					xo.Abort(xo.F("invalid reference ID %s", ref.ID))
+				}
+				if refs[ref.Type] == nil {
+					types = append(types, ref.Type)
+				}
+				refs[ref.Type] = append(refs[ref.Type], id)
+			}
+		}
+
+		// load resources by type
+		for _, typ := range types {
+			// get related controller
+			rc := ctx.Group.controllers[typ]
+			if rc == nil {
+				xo.Abort(xo.F("missing related controller for %s", typ))
+			}
+
+			// get IDs and chunk size, chunks must not exceed the list limit of
+			// the related controller to load all resources
+			ids := stick.Unique(refs[typ])
+			size := len(ids)
+			if rc.ListLimit > 0 && int64(size) > rc.ListLimit {
+				size = int(rc.ListLimit)
+			}
+
+			// load chunks
+			for len(ids) > 0 {
+				// get chunk
+				chunk := ids
+				if len(chunk) > size {
+					chunk = ids[:size]
+				}
+				ids = ids[len(chunk):]
+
+				// prepare sub context
+				subCtx := &Context{
+					Context:     ctx,
+					Data:        stick.Map{},
+					HTTPRequest: ctx.HTTPRequest,
+					Controller:  rc,
+					Group:       ctx.Group,
+					Tracer:      ctx.Tracer,
+					Language:    ctx.Language,
+					Location:    ctx.Location,
+					JSONAPIRequest: &jsonapi.Request{
+						Intent:       jsonapi.ListResources,
+						Prefix:       ctx.JSONAPIRequest.Prefix,
+						ResourceType: typ,
+						Fields:       ctx.JSONAPIRequest.Fields,
+					},
+					including: true,
+				}
+
+				// handle virtual request
+				rc.handle("", subCtx, bson.M{
+					"_id": bson.M{
+						"$in": chunk,
+					},
+				}, false)
+
+				// add resources
+				for _, res := range subCtx.Response.Data.Many {
+					loaded[path] = append(loaded[path], res)
+					if !known[res.Type+"/"+res.ID] {
+						known[res.Type+"/"+res.ID] = true
+						included = append(included, res)
+					}
+				}
+			}
+		}
+	}
+
+	return included
+}
+
 func (c *Controller) listLinks(ctx *Context) *jsonapi.DocumentLinks {
 	// trace
 	ctx.Tracer.Push("fire/Controller.listLinks")
@@ -2615,16 +4344,40 @@ func (c *Controller) runCallbackList(ctx *Context, stage Stage, list []*Callback
 			continue
 		}
 
+		// check budget
+		c.checkBudget(ctx)
+
 		// set stage
 		ctx.Stage = stage
 
 		// call callback
+		start := time.Now()
 		err := xo.W(cb.Handler(ctx))
+		ctx.Tracer.Tag("callback."+cb.Name, time.Since(start).String())
 		if xo.IsSafe(err) {
 			xo.Abort(jsonapi.ErrorFromStatus(errorStatus, err.Error()))
 		} else if err != nil {
 			xo.Abort(err)
 		}
+
+		// check budget
+		c.checkBudget(ctx)
+	}
+}
+
+func (c *Controller) checkBudget(ctx *Context) {
+	// tag remaining budget
+	deadline, ok := ctx.Deadline()
+	if ok {
+		ctx.Tracer.Tag("budget", time.Until(deadline).String())
+	}
+
+	// abort if cancelled or exceeded
+	if ctx.Err() != nil {
+		if ctx.HTTPRequest != nil && errors.Is(ctx.HTTPRequest.Context().Err(), context.Canceled) {
+			xo.Abort(ErrClientAborted.Wrap())
+		}
+		xo.Abort(ErrBudgetExhausted.Wrap())
 	}
 }
 
@@ -2633,6 +4386,12 @@ func (c *Controller) runAction(a *Action, ctx *Context, errorStatus int) {
 	ctx.Tracer.Push("fire/Controller.runAction")
 	defer ctx.Tracer.Pop()
 
+	// check budget
+	c.checkBudget(ctx)
+
+	// validate request
+	xo.AbortIf(a.validate(ctx))
+
 	// call action
 	err := xo.W(a.Handler(ctx))
 	if xo.IsSafe(err) {
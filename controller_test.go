@@ -1,20 +1,27 @@
 package fire
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/256dpi/jsonapi/v2"
 	"github.com/256dpi/serve"
 	"github.com/256dpi/xo"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/tidwall/gjson"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
 	"github.com/256dpi/fire/coal"
 	"github.com/256dpi/fire/stick"
@@ -68,7 +75,10 @@ func TestBasicOperations(t *testing.T) {
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource type mismatch"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/type"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -85,7 +95,10 @@ func TestBasicOperations(t *testing.T) {
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "unnecessary resource ID"
+					"detail": "unnecessary resource ID",
+					"source": {
+						"pointer": "/data/id"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -238,7 +251,10 @@ func TestBasicOperations(t *testing.T) {
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource type mismatch"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/type"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -258,7 +274,10 @@ func TestBasicOperations(t *testing.T) {
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource ID mismatch"
+					"detail": "resource ID mismatch",
+					"source": {
+						"pointer": "/data/id"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -602,7 +621,10 @@ func TestHasOneRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource type mismatch"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/relationships/post/data/type"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -802,7 +824,10 @@ func TestHasManyRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource type mismatch"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/relationships/post/data/type"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1104,7 +1129,10 @@ func TestToOneRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource type mismatch"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/type"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1121,7 +1149,10 @@ func TestToOneRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "invalid relationship ID"
+					"detail": "invalid relationship ID",
+					"source": {
+						"pointer": "/data/id"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1251,6 +1282,198 @@ func TestToOneRelationships(t *testing.T) {
 	})
 }
 
+func TestPolymorphicRelationships(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		}, &Controller{
+			Model: &reactionModel{},
+		})
+
+		// create post
+		post := tester.Insert(&postModel{
+			Title: "Post",
+		}).ID().Hex()
+
+		// create note
+		note := tester.Insert(&noteModel{
+			Title: "Note",
+			Post:  coal.MustFromHex(post),
+		}).ID().Hex()
+
+		// attempt to create reaction with invalid type
+		tester.Request("POST", "reactions", `{
+			"data": {
+				"type": "reactions",
+				"relationships": {
+					"subject": {
+						"data": {
+							"type": "comments",
+							"id": "`+coal.New().Hex()+`"
+						}
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/relationships/subject/data/type"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		var reaction string
+
+		// create reaction
+		tester.Request("POST", "reactions", `{
+			"data": {
+				"type": "reactions",
+				"attributes": {
+					"emoji": "+1"
+				},
+				"relationships": {
+					"subject": {
+						"data": {
+							"type": "notes",
+							"id": "`+note+`"
+						}
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			reaction = tester.FindLast(&reactionModel{}).ID().Hex()
+
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "reactions",
+					"id": "`+reaction+`",
+					"attributes": {
+						"emoji": "+1"
+					},
+					"relationships": {
+						"subject": {
+							"data": {
+								"type": "notes",
+								"id": "`+note+`"
+							},
+							"links": {
+								"self": "/reactions/`+reaction+`/relationships/subject",
+								"related": "/reactions/`+reaction+`/subject"
+							}
+						},
+						"opt-subject": {
+							"data": null,
+							"links": {
+								"self": "/reactions/`+reaction+`/relationships/opt-subject",
+								"related": "/reactions/`+reaction+`/opt-subject"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/reactions/`+reaction+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// check model
+		model := tester.FindLast(&reactionModel{}).(*reactionModel)
+		assert.Equal(t, coal.Ref{Type: "notes", ID: coal.MustFromHex(note)}, model.Subject)
+		assert.Nil(t, model.OptSubject)
+
+		// get related note
+		tester.Request("GET", "reactions/"+reaction+"/subject", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "notes",
+					"id": "`+note+`",
+					"attributes": {
+						"title": "Note"
+					},
+					"relationships": {
+						"post": {
+							"data": {
+								"type": "posts",
+								"id": "`+post+`"
+							},
+							"links": {
+								"self": "/notes/`+note+`/relationships/post",
+								"related": "/notes/`+note+`/post"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/reactions/`+reaction+`/subject"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// set optional subject relationship
+		tester.Request("PATCH", "reactions/"+reaction+"/relationships/opt-subject", `{
+			"data": {
+				"type": "posts",
+				"id": "`+post+`"
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "posts",
+					"id": "`+post+`"
+				},
+				"links": {
+					"self": "/reactions/`+reaction+`/relationships/opt-subject",
+					"related": "/reactions/`+reaction+`/opt-subject"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// check model
+		model = tester.FindLast(&reactionModel{}).(*reactionModel)
+		assert.Equal(t, &coal.Ref{Type: "posts", ID: coal.MustFromHex(post)}, model.OptSubject)
+
+		// unset optional subject relationship
+		tester.Request("PATCH", "reactions/"+reaction+"/relationships/opt-subject", `{
+			"data": null
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": null,
+				"links": {
+					"self": "/reactions/`+reaction+`/relationships/opt-subject",
+					"related": "/reactions/`+reaction+`/opt-subject"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// fetch unset related resource
+		tester.Request("GET", "reactions/"+reaction+"/opt-subject", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": null,
+				"links": {
+					"self": "/reactions/`+reaction+`/opt-subject"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
 func TestToManyRelationships(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		tester.Assign("", &Controller{
@@ -1455,7 +1678,10 @@ func TestToManyRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource type mismatch"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/0/type"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1474,7 +1700,10 @@ func TestToManyRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "invalid relationship ID"
+					"detail": "invalid relationship ID",
+					"source": {
+						"pointer": "/data/0/id"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1567,7 +1796,10 @@ func TestToManyRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource type mismatch"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/0/type"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1586,7 +1818,10 @@ func TestToManyRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "invalid relationship ID"
+					"detail": "invalid relationship ID",
+					"source": {
+						"pointer": "/data/0/id"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1701,7 +1936,10 @@ func TestToManyRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "resource type mismatch"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/0/type"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1720,7 +1958,10 @@ func TestToManyRelationships(t *testing.T) {
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "invalid relationship ID"
+					"detail": "invalid relationship ID",
+					"source": {
+						"pointer": "/data/0/id"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
@@ -1784,41 +2025,107 @@ func TestToManyRelationships(t *testing.T) {
 	})
 }
 
-func TestModelValidation(t *testing.T) {
+func TestToManyRelationshipOperations(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		tester.Assign("", &Controller{
 			Model: &postModel{},
-		}, &Controller{
-			Model: &commentModel{},
 		}, &Controller{
 			Model: &selectionModel{},
-		}, &Controller{
-			Model: &noteModel{},
 		})
 
-		// create post
-		post := tester.Insert(&postModel{
-			Title:     "post-1",
-			Published: true,
+		post1 := tester.Insert(&postModel{
+			Title: "Post 1",
+		}).ID()
+		post2 := tester.Insert(&postModel{
+			Title: "Post 2",
+		}).ID()
+		post3 := tester.Insert(&postModel{
+			Title: "Post 3",
 		}).ID()
 
-		// list
-		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		selection := tester.Insert(&selectionModel{
+			Name:  "Selection 1",
+			Posts: []coal.ID{post1},
+		}).ID()
+
+		// add posts
+		tester.Request("PATCH", "selections/"+selection.Hex(), `{
+			"data": {
+				"type": "selections",
+				"id": "`+selection.Hex()+`",
+				"relationships": {
+					"posts": {
+						"data": [
+							{
+								"type": "posts",
+								"id": "`+post1.Hex()+`"
+							},
+							{
+								"type": "posts",
+								"id": "`+post2.Hex()+`"
+							},
+							{
+								"type": "posts",
+								"id": "`+post3.Hex()+`"
+							}
+						],
+						"meta": {
+							"operation": "add"
+						}
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 		})
 
-		// find
-		tester.Request("GET", "/posts/"+post.Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		model := tester.Fetch(&selectionModel{}, selection).(*selectionModel)
+		assert.Equal(t, []coal.ID{post1, post2, post3}, model.Posts)
+
+		// remove posts
+		tester.Request("PATCH", "selections/"+selection.Hex()+"/relationships/posts", `{
+			"data": [
+				{
+					"type": "posts",
+					"id": "`+post1.Hex()+`"
+				},
+				{
+					"type": "posts",
+					"id": "`+post3.Hex()+`"
+				}
+			],
+			"meta": {
+				"operation": "remove"
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": [
+					{
+						"type": "posts",
+						"id": "`+post2.Hex()+`"
+					}
+				],
+				"links": {
+					"self": "/selections/`+selection.Hex()+`/relationships/posts",
+					"related": "/selections/`+selection.Hex()+`/posts"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// create
-		tester.Request("POST", "/posts", `{
-			"data": {
-				"type": "posts",
-				"attributes": {
-					"title": "error"
+		model = tester.Fetch(&selectionModel{}, selection).(*selectionModel)
+		assert.Equal(t, []coal.ID{post2}, model.Posts)
+
+		// invalid ID
+		tester.Request("PATCH", "selections/"+selection.Hex()+"/relationships/posts", `{
+			"data": [
+				{
+					"type": "posts",
+					"id": "foo"
 				}
+			],
+			"meta": {
+				"operation": "add"
 			}
 		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
@@ -1826,19 +2133,24 @@ func TestModelValidation(t *testing.T) {
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "validation error"
+					"detail": "invalid relationship ID",
+					"source": {
+						"pointer": "/data/0/id"
+					}
 				}]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+			}`, r.Body.String())
 		})
 
-		// update
-		tester.Request("PATCH", "/posts/"+post.Hex(), `{
-			"data": {
-				"type": "posts",
-				"id": "`+post.Hex()+`",
-				"attributes": {
-					"title": "error"
+		// invalid type
+		tester.Request("PATCH", "selections/"+selection.Hex()+"/relationships/posts", `{
+			"data": [
+				{
+					"type": "selections",
+					"id": "`+post1.Hex()+`"
 				}
+			],
+			"meta": {
+				"operation": "remove"
 			}
 		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
@@ -1846,432 +2158,511 @@ func TestModelValidation(t *testing.T) {
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "validation error"
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/0/type"
+					}
 				}]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+			}`, r.Body.String())
 		})
 
-		// delete
-		tester.Request("DELETE", "/posts/"+post.Hex(), "{}", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusNoContent, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, "", r.Body.String(), tester.DebugRequest(rq, r))
+		// invalid operation
+		tester.Request("PATCH", "selections/"+selection.Hex()+"/relationships/posts", `{
+			"data": [],
+			"meta": {
+				"operation": "merge"
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid relationship operation",
+					"source": {
+						"pointer": "/meta/operation"
+					}
+				}]
+			}`, r.Body.String())
+		})
+
+		model = tester.Fetch(&selectionModel{}, selection).(*selectionModel)
+		assert.Equal(t, []coal.ID{post2}, model.Posts)
+
+		// replace posts
+		tester.Request("PATCH", "selections/"+selection.Hex()+"/relationships/posts", `{
+			"data": [
+				{
+					"type": "posts",
+					"id": "`+post3.Hex()+`"
+				}
+			],
+			"meta": {
+				"operation": "replace"
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 		})
+
+		model = tester.Fetch(&selectionModel{}, selection).(*selectionModel)
+		assert.Equal(t, []coal.ID{post3}, model.Posts)
 	})
 }
 
-func TestSupported(t *testing.T) {
+func TestToManyRelationshipConcurrentOperations(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
+		post1 := tester.Insert(&postModel{
+			Title: "Post 1",
+		}).ID()
+		post2 := tester.Insert(&postModel{
+			Title: "Post 2",
+		}).ID()
+		post3 := tester.Insert(&postModel{
+			Title: "Post 3",
+		}).ID()
+
+		selection := tester.Insert(&selectionModel{
+			Name:  "Selection 1",
+			Posts: []coal.ID{post1},
+		}).ID()
+
+		var concurrent bson.M
 		tester.Assign("", &Controller{
 			Model: &postModel{},
-		}, &Controller{
-			Model:     &commentModel{},
-			Supported: Except(List),
 		}, &Controller{
 			Model: &selectionModel{},
-		}, &Controller{
-			Model: &noteModel{},
+			Validators: L{
+				C("concurrent", Validator, Only(Update), func(ctx *Context) error {
+					_, err := ctx.Store.M(&selectionModel{}).Update(ctx, nil, selection, concurrent, false)
+					return err
+				}),
+			},
 		})
 
-		// attempt list comments
-		tester.Request("GET", "comments", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusMethodNotAllowed, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors":[{
-					"status": "405",
-					"title": "method not allowed",
-					"detail": "unsupported operation"
-				}]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		// add post while another post is added concurrently
+		concurrent = bson.M{
+			"$push": bson.M{
+				"Posts": post3,
+			},
+		}
+		tester.Request("POST", "selections/"+selection.Hex()+"/relationships/posts", `{
+			"data": [
+				{
+					"type": "posts",
+					"id": "`+post2.Hex()+`"
+				}
+			]
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		model := tester.Fetch(&selectionModel{}, selection).(*selectionModel)
+		assert.Equal(t, []coal.ID{post1, post3, post2}, model.Posts)
+
+		// remove post while another post is removed concurrently
+		concurrent = bson.M{
+			"$pullAll": bson.M{
+				"Posts": []coal.ID{post1},
+			},
+		}
+		tester.Request("PATCH", "selections/"+selection.Hex(), `{
+			"data": {
+				"type": "selections",
+				"id": "`+selection.Hex()+`",
+				"attributes": {
+					"name": "Selection 2"
+				},
+				"relationships": {
+					"posts": {
+						"data": [
+							{
+								"type": "posts",
+								"id": "`+post2.Hex()+`"
+							}
+						],
+						"meta": {
+							"operation": "remove"
+						}
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 		})
+
+		model = tester.Fetch(&selectionModel{}, selection).(*selectionModel)
+		assert.Equal(t, "Selection 2", model.Name)
+		assert.Equal(t, []coal.ID{post3}, model.Posts)
 	})
 }
 
-func TestFiltering(t *testing.T) {
+func TestModelValidation(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		tester.Assign("", &Controller{
-			Model:   &postModel{},
-			Filters: []string{"Title", "Published"},
+			Model: &postModel{},
 		}, &Controller{
-			Model:   &commentModel{},
-			Filters: []string{"Message", "Parent"},
+			Model: &commentModel{},
 		}, &Controller{
-			Model:   &selectionModel{},
-			Filters: []string{"Posts"},
+			Model: &selectionModel{},
 		}, &Controller{
-			Model:   &noteModel{},
-			Filters: []string{"Post"},
+			Model: &noteModel{},
 		})
 
-		// create posts
-		post1 := tester.Insert(&postModel{
+		// create post
+		post := tester.Insert(&postModel{
 			Title:     "post-1",
 			Published: true,
-		}).ID().Hex()
-		post2 := tester.Insert(&postModel{
-			Title:     "post-2",
-			Published: false,
-		}).ID().Hex()
-		post3 := tester.Insert(&postModel{
-			Title:     "post-3",
-			Published: true,
-		}).ID().Hex()
-
-		// create comments
-		comment1 := tester.Insert(&commentModel{
-			Post: coal.MustFromHex(post1),
-		}).ID().Hex()
+		}).ID()
 
-		// create selections
-		selection1 := tester.Insert(&selectionModel{
-			Name: "selection-1",
-			Posts: []coal.ID{
-				coal.MustFromHex(post1),
-				coal.MustFromHex(post2),
-				coal.MustFromHex(post3),
-			},
-		}).ID().Hex()
-		selection2 := tester.Insert(&selectionModel{
-			Name: "selection-2",
-		}).ID().Hex()
+		// list
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
 
-		// create notes
-		note1 := tester.Insert(&noteModel{
-			Title: "note-1",
-			Post:  coal.MustFromHex(post1),
-		}).ID().Hex()
-		tester.Insert(&noteModel{
-			Title: "note-2",
-			Post:  coal.New(),
+		// find
+		tester.Request("GET", "/posts/"+post.Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 		})
 
-		// test invalid filter
-		tester.Request("GET", "posts?filter[foo]=bar", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// create
+		tester.Request("POST", "/posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "error"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors":[{
+				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "invalid filter \"foo\""
+					"detail": "validation error"
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// test not supported filter
-		tester.Request("GET", "posts?filter[text-body]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// update
+		tester.Request("PATCH", "/posts/"+post.Hex(), `{
+			"data": {
+				"type": "posts",
+				"id": "`+post.Hex()+`",
+				"attributes": {
+					"title": "error"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors":[{
+				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "invalid filter \"text-body\""
+					"detail": "validation error"
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// get posts with single value filter
-		tester.Request("GET", "posts?filter[title]=post-1", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			data := gjson.Get(r.Body.String(), "data").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
+		// delete
+		tester.Request("DELETE", "/posts/"+post.Hex(), "{}", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNoContent, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "", r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
-				{
-					"type": "posts",
-					"id": "`+post1+`",
-					"attributes": {
-						"title": "post-1",
-						"published": true,
-						"text-body": ""
-					},
-					"relationships": {
-						"comments": {
-							"data": [
-								{
-									"type": "comments",
-									"id": "`+comment1+`"
-								}
-							],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/comments",
-								"related": "/posts/`+post1+`/comments"
-							}
-						},
-						"selections": {
-							"data": [
-								{
-									"type": "selections",
-									"id": "`+selection1+`"
-								}
-							],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/selections",
-								"related": "/posts/`+post1+`/selections"
-							}
-						},
-						"note": {
-							"data": {
-								"type": "notes",
-								"id": "`+note1+`"
-							},
-							"links": {
-								"self": "/posts/`+post1+`/relationships/note",
-								"related": "/posts/`+post1+`/note"
-							}
-						}
-					}
-				}
-			]`, data, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?filter[title]=post-1"
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+func TestDocumentErrors(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model:            &noteModel{},
+			LenientDocuments: true,
 		})
 
-		// get posts with multi value filter
-		tester.Request("GET", "posts?filter[title]=post-2,post-3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			data := gjson.Get(r.Body.String(), "data").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
+		post := tester.Insert(&postModel{
+			Title: "Post",
+		}).ID().Hex()
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
-				{
-					"type": "posts",
-					"id": "`+post2+`",
-					"attributes": {
-						"title": "post-2",
-						"published": false,
-						"text-body": ""
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post2+`/relationships/comments",
-								"related": "/posts/`+post2+`/comments"
-							}
-						},
-						"selections": {
-							"data": [
-								{
-									"type": "selections",
-									"id": "`+selection1+`"
-								}
-							],
-							"links": {
-								"self": "/posts/`+post2+`/relationships/selections",
-								"related": "/posts/`+post2+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+post2+`/relationships/note",
-								"related": "/posts/`+post2+`/note"
-							}
-						}
-					}
-				},
-				{
-					"type": "posts",
-					"id": "`+post3+`",
-					"attributes": {
-						"title": "post-3",
-						"published": true,
-						"text-body": ""
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post3+`/relationships/comments",
-								"related": "/posts/`+post3+`/comments"
-							}
-						},
-						"selections": {
-							"data": [
-								{
-									"type": "selections",
-									"id": "`+selection1+`"
-								}
-							],
-							"links": {
-								"self": "/posts/`+post3+`/relationships/selections",
-								"related": "/posts/`+post3+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+post3+`/relationships/note",
-								"related": "/posts/`+post3+`/note"
-							}
-						}
-					}
+		// attempt to create post with invalid attribute type
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": 42
 				}
-			]`, data, tester.DebugRequest(rq, r))
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?filter[title]=post-2,post-3"
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
-		})
-
-		// get posts with positive boolean
-		tester.Request("GET", "posts?filter[published]=true", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			data := gjson.Get(r.Body.String(), "data").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
-				{
-					"type": "posts",
-					"id": "`+post1+`",
-					"attributes": {
-						"title": "post-1",
-						"published": true,
-						"text-body": ""
-					},
-					"relationships": {
-						"comments": {
-							"data": [
-								{
-									"type": "comments",
-									"id": "`+comment1+`"
-								}
-							],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/comments",
-								"related": "/posts/`+post1+`/comments"
-							}
-						},
-						"selections": {
-							"data": [
-								{
-									"type": "selections",
-									"id": "`+selection1+`"
-								}
-							],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/selections",
-								"related": "/posts/`+post1+`/selections"
-							}
-						},
-						"note": {
-							"data": {
-								"type": "notes",
-								"id": "`+note1+`"
-							},
-							"links": {
-								"self": "/posts/`+post1+`/relationships/note",
-								"related": "/posts/`+post1+`/note"
-							}
-						}
-					}
-				},
-				{
-					"type": "posts",
-					"id": "`+post3+`",
-					"attributes": {
-						"title": "post-3",
-						"published": true,
-						"text-body": ""
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post3+`/relationships/comments",
-								"related": "/posts/`+post3+`/comments"
-							}
-						},
-						"selections": {
-							"data": [
-								{
-									"type": "selections",
-									"id": "`+selection1+`"
-								}
-							],
-							"links": {
-								"self": "/posts/`+post3+`/relationships/selections",
-								"related": "/posts/`+post3+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+post3+`/relationships/note",
-								"related": "/posts/`+post3+`/note"
-							}
-						}
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid attribute type",
+					"source": {
+						"pointer": "/data/attributes/title"
 					}
-				}
-			]`, data, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?filter[published]=true"
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// get posts with negative boolean
-		tester.Request("GET", "posts?filter[published]=false", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			data := gjson.Get(r.Body.String(), "data").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
-				{
-					"type": "posts",
-					"id": "`+post2+`",
-					"attributes": {
-						"title": "post-2",
-						"published": false,
-						"text-body": ""
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post2+`/relationships/comments",
-								"related": "/posts/`+post2+`/comments"
-							}
-						},
-						"selections": {
-							"data": [
-								{
-									"type": "selections",
-									"id": "`+selection1+`"
-								}
-							],
-							"links": {
-								"self": "/posts/`+post2+`/relationships/selections",
-								"related": "/posts/`+post2+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+post2+`/relationships/note",
-								"related": "/posts/`+post2+`/note"
-							}
-						}
+		// attempt to create comment with invalid to-one linkage
+		tester.Request("POST", "comments", `{
+			"data": {
+				"type": "comments",
+				"relationships": {
+					"post": {
+						"data": [{
+							"type": "posts",
+							"id": "`+post+`"
+						}]
 					}
 				}
-			]`, data, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?filter[published]=false"
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
-		})
-
-		// test not supported relationship filter
-		tester.Request("GET", "comments?filter[post]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors":[{
+				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "invalid filter \"post\""
+					"detail": "invalid relationship linkage",
+					"source": {
+						"pointer": "/data/relationships/post/data"
+					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// get to-many posts with negative boolean
-		tester.Request("GET", "selections/"+selection1+"/posts?filter[published]=false", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// attempt to create comment with invalid relationship ID
+		tester.Request("POST", "comments", `{
+			"data": {
+				"type": "comments",
+				"relationships": {
+					"post": {
+						"data": {
+							"type": "posts",
+							"id": "foo"
+						}
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid relationship ID",
+					"source": {
+						"pointer": "/data/relationships/post/data/id"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// attempt to create selection with invalid to-many linkage
+		tester.Request("POST", "selections", `{
+			"data": {
+				"type": "selections",
+				"relationships": {
+					"posts": {
+						"data": {
+							"type": "posts",
+							"id": "`+post+`"
+						}
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid relationship linkage",
+					"source": {
+						"pointer": "/data/relationships/posts/data"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// attempt to create selection with mismatched reference type
+		tester.Request("POST", "selections", `{
+			"data": {
+				"type": "selections",
+				"relationships": {
+					"posts": {
+						"data": [{
+							"type": "posts",
+							"id": "`+post+`"
+						}, {
+							"type": "comments",
+							"id": "`+post+`"
+						}]
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "resource type mismatch",
+					"source": {
+						"pointer": "/data/relationships/posts/data/1/type"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// create note with unknown fields in lenient mode
+		tester.Request("POST", "notes", `{
+			"data": {
+				"type": "notes",
+				"attributes": {
+					"title": "Note",
+					"foo": "bar"
+				},
+				"relationships": {
+					"post": {
+						"data": {
+							"type": "posts",
+							"id": "`+post+`"
+						}
+					},
+					"bar": {
+						"data": null
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		// check note
+		note := tester.FindLast(&noteModel{}).(*noteModel)
+		assert.Equal(t, "Note", note.Title)
+		assert.Equal(t, post, note.Post.Hex())
+	})
+}
+
+func TestSupported(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+		}, &Controller{
+			Model:     &commentModel{},
+			Supported: Except(List),
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		// attempt list comments
+		tester.Request("GET", "comments", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusMethodNotAllowed, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "405",
+					"title": "method not allowed",
+					"detail": "unsupported operation"
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestFiltering(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:   &postModel{},
+			Filters: []string{"Title", "Published"},
+		}, &Controller{
+			Model:   &commentModel{},
+			Filters: []string{"Message", "Parent"},
+		}, &Controller{
+			Model:   &selectionModel{},
+			Filters: []string{"Posts"},
+		}, &Controller{
+			Model:   &noteModel{},
+			Filters: []string{"Post"},
+		})
+
+		// create posts
+		post1 := tester.Insert(&postModel{
+			Title:     "post-1",
+			Published: true,
+		}).ID().Hex()
+		post2 := tester.Insert(&postModel{
+			Title:     "post-2",
+			Published: false,
+		}).ID().Hex()
+		post3 := tester.Insert(&postModel{
+			Title:     "post-3",
+			Published: true,
+		}).ID().Hex()
+
+		// create comments
+		comment1 := tester.Insert(&commentModel{
+			Post: coal.MustFromHex(post1),
+		}).ID().Hex()
+
+		// create selections
+		selection1 := tester.Insert(&selectionModel{
+			Name: "selection-1",
+			Posts: []coal.ID{
+				coal.MustFromHex(post1),
+				coal.MustFromHex(post2),
+				coal.MustFromHex(post3),
+			},
+		}).ID().Hex()
+		selection2 := tester.Insert(&selectionModel{
+			Name: "selection-2",
+		}).ID().Hex()
+
+		// create notes
+		note1 := tester.Insert(&noteModel{
+			Title: "note-1",
+			Post:  coal.MustFromHex(post1),
+		}).ID().Hex()
+		tester.Insert(&noteModel{
+			Title: "note-2",
+			Post:  coal.New(),
+		})
+
+		// test invalid filter
+		tester.Request("GET", "posts?filter[foo]=bar", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid filter \"foo\""
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// test not supported filter
+		tester.Request("GET", "posts?filter[text-body]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid filter \"text-body\""
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// get posts with single value filter
+		tester.Request("GET", "posts?filter[title]=post-1", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			data := gjson.Get(r.Body.String(), "data").Raw
 			links := gjson.Get(r.Body.String(), "links").Raw
 
@@ -2279,18 +2670,23 @@ func TestFiltering(t *testing.T) {
 			assert.JSONEq(t, `[
 				{
 					"type": "posts",
-					"id": "`+post2+`",
+					"id": "`+post1+`",
 					"attributes": {
-						"title": "post-2",
-						"published": false,
+						"title": "post-1",
+						"published": true,
 						"text-body": ""
 					},
 					"relationships": {
 						"comments": {
-							"data": [],
+							"data": [
+								{
+									"type": "comments",
+									"id": "`+comment1+`"
+								}
+							],
 							"links": {
-								"self": "/posts/`+post2+`/relationships/comments",
-								"related": "/posts/`+post2+`/comments"
+								"self": "/posts/`+post1+`/relationships/comments",
+								"related": "/posts/`+post1+`/comments"
 							}
 						},
 						"selections": {
@@ -2301,449 +2697,286 @@ func TestFiltering(t *testing.T) {
 								}
 							],
 							"links": {
-								"self": "/posts/`+post2+`/relationships/selections",
-								"related": "/posts/`+post2+`/selections"
+								"self": "/posts/`+post1+`/relationships/selections",
+								"related": "/posts/`+post1+`/selections"
 							}
 						},
 						"note": {
-							"data": null,
+							"data": {
+								"type": "notes",
+								"id": "`+note1+`"
+							},
 							"links": {
-								"self": "/posts/`+post2+`/relationships/note",
-								"related": "/posts/`+post2+`/note"
+								"self": "/posts/`+post1+`/relationships/note",
+								"related": "/posts/`+post1+`/note"
 							}
 						}
 					}
 				}
 			]`, data, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/selections/`+selection1+`/posts?filter[published]=false"
+				"self": "/posts?filter[title]=post-1"
 			}`, linkUnescape(links), tester.DebugRequest(rq, r))
 		})
 
-		// test invalid relationship filter ID
-		tester.Request("GET", "notes?filter[post]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors":[{
-					"status": "400",
-					"title": "bad request",
-					"detail": "relationship filter value is not an object ID"
-				}]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
-		})
-
-		// filter notes with to-one relationship filter
-		tester.Request("GET", "notes?filter[post]="+post1, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// get posts with multi value filter
+		tester.Request("GET", "posts?filter[title]=post-2,post-3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			data := gjson.Get(r.Body.String(), "data").Raw
 			links := gjson.Get(r.Body.String(), "links").Raw
 
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `[
 				{
-					"type": "notes",
-					"id": "`+note1+`",
+					"type": "posts",
+					"id": "`+post2+`",
 					"attributes": {
-						"title": "note-1"
+						"title": "post-2",
+						"published": false,
+						"text-body": ""
 					},
 					"relationships": {
-						"post": {
-							"data": {
-								"type": "posts",
-								"id": "`+post1+`"
-							},
+						"comments": {
+							"data": [],
 							"links": {
-								"self": "/notes/`+note1+`/relationships/post",
-								"related": "/notes/`+note1+`/post"
+								"self": "/posts/`+post2+`/relationships/comments",
+								"related": "/posts/`+post2+`/comments"
+							}
+						},
+						"selections": {
+							"data": [
+								{
+									"type": "selections",
+									"id": "`+selection1+`"
+								}
+							],
+							"links": {
+								"self": "/posts/`+post2+`/relationships/selections",
+								"related": "/posts/`+post2+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post2+`/relationships/note",
+								"related": "/posts/`+post2+`/note"
 							}
 						}
 					}
-				}
-			]`, data, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/notes?filter[post]=`+post1+`"
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
-		})
-
-		// filter selections with absent to-many relationship filter
-		tester.Request("GET", "selections?filter[posts]=", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			data := gjson.Get(r.Body.String(), "data").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
-			{
-					"type": "selections",
-					"id": "`+selection2+`",
+				},
+				{
+					"type": "posts",
+					"id": "`+post3+`",
 					"attributes": {
-						"name": "selection-2"
+						"title": "post-3",
+						"published": true,
+						"text-body": ""
 					},
 					"relationships": {
-						"posts": {
+						"comments": {
 							"data": [],
 							"links": {
-								"self": "/selections/`+selection2+`/relationships/posts",
-								"related": "/selections/`+selection2+`/posts"
+								"self": "/posts/`+post3+`/relationships/comments",
+								"related": "/posts/`+post3+`/comments"
+							}
+						},
+						"selections": {
+							"data": [
+								{
+									"type": "selections",
+									"id": "`+selection1+`"
+								}
+							],
+							"links": {
+								"self": "/posts/`+post3+`/relationships/selections",
+								"related": "/posts/`+post3+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post3+`/relationships/note",
+								"related": "/posts/`+post3+`/note"
 							}
 						}
 					}
 				}
 			]`, data, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/selections?filter[posts]="
+				"self": "/posts?filter[title]=post-2,post-3"
 			}`, linkUnescape(links), tester.DebugRequest(rq, r))
 		})
 
-		// filter selections with to-many relationship filter
-		tester.Request("GET", "selections?filter[posts]="+post1, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// get posts with positive boolean
+		tester.Request("GET", "posts?filter[published]=true", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			data := gjson.Get(r.Body.String(), "data").Raw
 			links := gjson.Get(r.Body.String(), "links").Raw
 
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `[
 				{
-					"type": "selections",
-					"id": "`+selection1+`",
+					"type": "posts",
+					"id": "`+post1+`",
 					"attributes": {
-						"name": "selection-1"
+						"title": "post-1",
+						"published": true,
+						"text-body": ""
 					},
 					"relationships": {
-						"posts": {
+						"comments": {
 							"data": [
 								{
-									"type": "posts",
-									"id": "`+post1+`"
-								},
-								{
-									"type": "posts",
-									"id": "`+post2+`"
-								},
+									"type": "comments",
+									"id": "`+comment1+`"
+								}
+							],
+							"links": {
+								"self": "/posts/`+post1+`/relationships/comments",
+								"related": "/posts/`+post1+`/comments"
+							}
+						},
+						"selections": {
+							"data": [
 								{
-									"type": "posts",
-									"id": "`+post3+`"
+									"type": "selections",
+									"id": "`+selection1+`"
 								}
 							],
 							"links": {
-								"self": "/selections/`+selection1+`/relationships/posts",
-								"related": "/selections/`+selection1+`/posts"
+								"self": "/posts/`+post1+`/relationships/selections",
+								"related": "/posts/`+post1+`/selections"
+							}
+						},
+						"note": {
+							"data": {
+								"type": "notes",
+								"id": "`+note1+`"
+							},
+							"links": {
+								"self": "/posts/`+post1+`/relationships/note",
+								"related": "/posts/`+post1+`/note"
 							}
 						}
 					}
-				}
-			]`, data, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/selections?filter[posts]=`+post1+`"
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
-		})
-
-		// filter selections with multiple to-many relationship filters
-		tester.Request("GET", "selections?filter[posts]="+post1+","+post2, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			data := gjson.Get(r.Body.String(), "data").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
+				},
 				{
-					"type": "selections",
-					"id": "`+selection1+`",
+					"type": "posts",
+					"id": "`+post3+`",
 					"attributes": {
-						"name": "selection-1"
+						"title": "post-3",
+						"published": true,
+						"text-body": ""
 					},
 					"relationships": {
-						"posts": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post3+`/relationships/comments",
+								"related": "/posts/`+post3+`/comments"
+							}
+						},
+						"selections": {
 							"data": [
 								{
-									"type": "posts",
-									"id": "`+post1+`"
-								},
-								{
-									"type": "posts",
-									"id": "`+post2+`"
-								},
-								{
-									"type": "posts",
-									"id": "`+post3+`"
+									"type": "selections",
+									"id": "`+selection1+`"
 								}
 							],
 							"links": {
-								"self": "/selections/`+selection1+`/relationships/posts",
-								"related": "/selections/`+selection1+`/posts"
+								"self": "/posts/`+post3+`/relationships/selections",
+								"related": "/posts/`+post3+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post3+`/relationships/note",
+								"related": "/posts/`+post3+`/note"
 							}
 						}
 					}
 				}
 			]`, data, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/selections?filter[posts]=`+post1+`,`+post2+`"
+				"self": "/posts?filter[published]=true"
 			}`, linkUnescape(links), tester.DebugRequest(rq, r))
 		})
 
-		// filter comments for absent string
-		tester.Request("GET", "comments?filter[message]=", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// get posts with negative boolean
+		tester.Request("GET", "posts?filter[published]=false", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			data := gjson.Get(r.Body.String(), "data").Raw
 			links := gjson.Get(r.Body.String(), "links").Raw
 
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `[
 				{
-					"type": "comments",
-					"id": "`+comment1+`",
+					"type": "posts",
+					"id": "`+post2+`",
 					"attributes": {
-						"message": ""
+						"title": "post-2",
+						"published": false,
+						"text-body": ""
 					},
 					"relationships": {
-						"post": {
-							"data": {
-								"type": "posts",
-								"id": "`+post1+`"
-							},
+						"comments": {
+							"data": [],
 							"links": {
-								"self": "/comments/`+comment1+`/relationships/post",
-								"related": "/comments/`+comment1+`/post"
+								"self": "/posts/`+post2+`/relationships/comments",
+								"related": "/posts/`+post2+`/comments"
 							}
 						},
-						"parent": {
+						"selections": {
+							"data": [
+								{
+									"type": "selections",
+									"id": "`+selection1+`"
+								}
+							],
+							"links": {
+								"self": "/posts/`+post2+`/relationships/selections",
+								"related": "/posts/`+post2+`/selections"
+							}
+						},
+						"note": {
 							"data": null,
 							"links": {
-								"self": "/comments/`+comment1+`/relationships/parent",
-								"related": "/comments/`+comment1+`/parent"
+								"self": "/posts/`+post2+`/relationships/note",
+								"related": "/posts/`+post2+`/note"
 							}
 						}
 					}
 				}
 			]`, data, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/comments?filter[message]="
+				"self": "/posts?filter[published]=false"
 			}`, linkUnescape(links), tester.DebugRequest(rq, r))
 		})
 
-		// filter comments for absent relationship
-		tester.Request("GET", "comments?filter[parent]=", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			data := gjson.Get(r.Body.String(), "data").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
-				{
-					"type": "comments",
-					"id": "`+comment1+`",
-					"attributes": {
-						"message": ""
-					},
-					"relationships": {
-						"post": {
-							"data": {
-								"type": "posts",
-								"id": "`+post1+`"
-							},
-							"links": {
-								"self": "/comments/`+comment1+`/relationships/post",
-								"related": "/comments/`+comment1+`/post"
-							}
-						},
-						"parent": {
-							"data": null,
-							"links": {
-								"self": "/comments/`+comment1+`/relationships/parent",
-								"related": "/comments/`+comment1+`/parent"
-							}
-						}
-					}
-				}
-			]`, data, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/comments?filter[parent]="
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
-		})
-	})
-}
-
-func TestFilterHandlers(t *testing.T) {
-	withTester(t, func(t *testing.T, tester *Tester) {
-		assert.PanicsWithValue(t, `fire: filter handler for missing filter "Title"`, func() {
-			tester.Assign("", &Controller{
-				Model: &postModel{},
-				FilterHandlers: map[string]FilterHandler{
-					"Title": nil,
-				},
-			})
-		})
-
-		tester.Assign("", &Controller{
-			Model:   &postModel{},
-			Filters: []string{"Title"},
-			FilterHandlers: map[string]FilterHandler{
-				"Title": func(ctx *Context, values []string) (bson.M, error) {
-					if len(values) == 1 && values[0] == "error" {
-						return nil, xo.SF("invalid title filter")
-					} else if len(values) == 1 && values[0] == "true" {
-						return bson.M{
-							"Title": "bar",
-						}, nil
-					}
-					return nil, nil
-				},
-			},
-		}, &Controller{
-			Model: &commentModel{},
-		}, &Controller{
-			Model:   &selectionModel{},
-			Filters: []string{"Posts"},
-		}, &Controller{
-			Model:   &noteModel{},
-			Filters: []string{"Post"},
-		})
-
-		// create posts
-		post1 := tester.Insert(&postModel{
-			Title: "foo",
-		}).ID().Hex()
-		post2 := tester.Insert(&postModel{
-			Title: "bar",
-		}).ID().Hex()
-		tester.Insert(&postModel{
-			Title: "baz",
-		})
-
-		// test filter handler error
-		tester.Request("GET", "posts?filter[title]=error", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors":[{
-					"status": "400",
-					"title": "bad request",
-					"detail": "invalid title filter"
-				}]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
-		})
-
-		// test filter handler
-		tester.Request("GET", "posts?filter[title]=true", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			ids := gjson.Get(r.Body.String(), "data.#.id").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
-				"`+post2+`"
-			]`, ids, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?filter[title]=true"
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
-		})
-
-		// test nil expression filter handler
-		tester.Request("GET", "posts?filter[title]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			ids := gjson.Get(r.Body.String(), "data.#.id").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `[
-				"`+post1+`"
-			]`, ids, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?filter[title]=foo"
-			}`, linkUnescape(links), tester.DebugRequest(rq, r))
-		})
-	})
-}
-
-func TestSorting(t *testing.T) {
-	withTester(t, func(t *testing.T, tester *Tester) {
-		tester.Assign("", &Controller{
-			Model:   &postModel{},
-			Sorters: []string{"Title", "TextBody"},
-		}, &Controller{
-			Model:   &commentModel{},
-			Sorters: []string{"Message"},
-		}, &Controller{
-			Model: &selectionModel{},
-		}, &Controller{
-			Model: &noteModel{},
-		})
-
-		// create posts in random order
-		post2 := tester.Insert(&postModel{
-			Title:    "post-2",
-			TextBody: "body-2",
-		}).ID().Hex()
-		post1 := tester.Insert(&postModel{
-			Title:    "post-1",
-			TextBody: "body-1",
-		}).ID().Hex()
-		post3 := tester.Insert(&postModel{
-			Title:    "post-3",
-			TextBody: "body-3",
-		}).ID().Hex()
-
-		// test invalid sorter
-		tester.Request("GET", "posts?sort=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors":[{
-					"status": "400",
-					"title": "bad request",
-					"detail": "invalid sorter \"foo\""
-				}]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
-		})
-
-		// test invalid sorter
-		tester.Request("GET", "posts?sort=published", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// test not supported relationship filter
+		tester.Request("GET", "comments?filter[post]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
 				"errors":[{
 					"status": "400",
 					"title": "bad request",
-					"detail": "unsupported sorter \"published\""
+					"detail": "invalid filter \"post\""
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// get posts in ascending order
-		tester.Request("GET", "posts?sort=title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// get to-many posts with negative boolean
+		tester.Request("GET", "selections/"+selection1+"/posts?filter[published]=false", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			data := gjson.Get(r.Body.String(), "data").Raw
 			links := gjson.Get(r.Body.String(), "links").Raw
 
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `[
-				{
-					"type": "posts",
-					"id": "`+post1+`",
-					"attributes": {
-						"title": "post-1",
-						"published": false,
-						"text-body": "body-1"
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/comments",
-								"related": "/posts/`+post1+`/comments"
-							}
-						},
-						"selections": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/selections",
-								"related": "/posts/`+post1+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+post1+`/relationships/note",
-								"related": "/posts/`+post1+`/note"
-							}
-						}
-					}
-				},
 				{
 					"type": "posts",
 					"id": "`+post2+`",
 					"attributes": {
 						"title": "post-2",
 						"published": false,
-						"text-body": "body-2"
+						"text-body": ""
 					},
 					"relationships": {
 						"comments": {
@@ -2754,7 +2987,12 @@ func TestSorting(t *testing.T) {
 							}
 						},
 						"selections": {
-							"data": [],
+							"data": [
+								{
+									"type": "selections",
+									"id": "`+selection1+`"
+								}
+							],
 							"links": {
 								"self": "/posts/`+post2+`/relationships/selections",
 								"related": "/posts/`+post2+`/selections"
@@ -2768,215 +3006,452 @@ func TestSorting(t *testing.T) {
 							}
 						}
 					}
-				},
-				{
-					"type": "posts",
-					"id": "`+post3+`",
-					"attributes": {
-						"title": "post-3",
-						"published": false,
-						"text-body": "body-3"
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post3+`/relationships/comments",
-								"related": "/posts/`+post3+`/comments"
-							}
-						},
-						"selections": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post3+`/relationships/selections",
-								"related": "/posts/`+post3+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+post3+`/relationships/note",
-								"related": "/posts/`+post3+`/note"
-							}
-						}
-					}
 				}
 			]`, data, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?sort=title"
+				"self": "/selections/`+selection1+`/posts?filter[published]=false"
 			}`, linkUnescape(links), tester.DebugRequest(rq, r))
 		})
 
-		// get posts in descending order
-		tester.Request("GET", "posts?sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			data := gjson.Get(r.Body.String(), "data").Raw
-			links := gjson.Get(r.Body.String(), "links").Raw
+		// test invalid relationship filter ID
+		tester.Request("GET", "notes?filter[post]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "400",
+					"title": "bad request",
+					"detail": "relationship filter value is not an object ID"
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// filter notes with to-one relationship filter
+		tester.Request("GET", "notes?filter[post]="+post1, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			data := gjson.Get(r.Body.String(), "data").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
 
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `[
 				{
-					"type": "posts",
-					"id": "`+post3+`",
+					"type": "notes",
+					"id": "`+note1+`",
 					"attributes": {
-						"title": "post-3",
-						"published": false,
-						"text-body": "body-3"
+						"title": "note-1"
 					},
 					"relationships": {
-						"comments": {
-							"data": [],
+						"post": {
+							"data": {
+								"type": "posts",
+								"id": "`+post1+`"
+							},
 							"links": {
-								"self": "/posts/`+post3+`/relationships/comments",
-								"related": "/posts/`+post3+`/comments"
+								"self": "/notes/`+note1+`/relationships/post",
+								"related": "/notes/`+note1+`/post"
 							}
-						},
-						"selections": {
+						}
+					}
+				}
+			]`, data, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/notes?filter[post]=`+post1+`"
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+
+		// filter selections with absent to-many relationship filter
+		tester.Request("GET", "selections?filter[posts]=", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			data := gjson.Get(r.Body.String(), "data").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
+			{
+					"type": "selections",
+					"id": "`+selection2+`",
+					"attributes": {
+						"name": "selection-2"
+					},
+					"relationships": {
+						"posts": {
 							"data": [],
 							"links": {
-								"self": "/posts/`+post3+`/relationships/selections",
-								"related": "/posts/`+post3+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+post3+`/relationships/note",
-								"related": "/posts/`+post3+`/note"
+								"self": "/selections/`+selection2+`/relationships/posts",
+								"related": "/selections/`+selection2+`/posts"
 							}
 						}
 					}
-				},
+				}
+			]`, data, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/selections?filter[posts]="
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+
+		// filter selections with to-many relationship filter
+		tester.Request("GET", "selections?filter[posts]="+post1, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			data := gjson.Get(r.Body.String(), "data").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
 				{
-					"type": "posts",
-					"id": "`+post2+`",
+					"type": "selections",
+					"id": "`+selection1+`",
 					"attributes": {
-						"title": "post-2",
-						"published": false,
-						"text-body": "body-2"
+						"name": "selection-1"
 					},
 					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post2+`/relationships/comments",
-								"related": "/posts/`+post2+`/comments"
-							}
-						},
-						"selections": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post2+`/relationships/selections",
-								"related": "/posts/`+post2+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
+						"posts": {
+							"data": [
+								{
+									"type": "posts",
+									"id": "`+post1+`"
+								},
+								{
+									"type": "posts",
+									"id": "`+post2+`"
+								},
+								{
+									"type": "posts",
+									"id": "`+post3+`"
+								}
+							],
 							"links": {
-								"self": "/posts/`+post2+`/relationships/note",
-								"related": "/posts/`+post2+`/note"
+								"self": "/selections/`+selection1+`/relationships/posts",
+								"related": "/selections/`+selection1+`/posts"
 							}
 						}
 					}
-				},
+				}
+			]`, data, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/selections?filter[posts]=`+post1+`"
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+
+		// filter selections with multiple to-many relationship filters
+		tester.Request("GET", "selections?filter[posts]="+post1+","+post2, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			data := gjson.Get(r.Body.String(), "data").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
 				{
-					"type": "posts",
-					"id": "`+post1+`",
+					"type": "selections",
+					"id": "`+selection1+`",
 					"attributes": {
-						"title": "post-1",
-						"published": false,
-						"text-body": "body-1"
+						"name": "selection-1"
 					},
 					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/comments",
-								"related": "/posts/`+post1+`/comments"
-							}
-						},
-						"selections": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/selections",
-								"related": "/posts/`+post1+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
+						"posts": {
+							"data": [
+								{
+									"type": "posts",
+									"id": "`+post1+`"
+								},
+								{
+									"type": "posts",
+									"id": "`+post2+`"
+								},
+								{
+									"type": "posts",
+									"id": "`+post3+`"
+								}
+							],
 							"links": {
-								"self": "/posts/`+post1+`/relationships/note",
-								"related": "/posts/`+post1+`/note"
+								"self": "/selections/`+selection1+`/relationships/posts",
+								"related": "/selections/`+selection1+`/posts"
 							}
 						}
 					}
 				}
 			]`, data, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?sort=-title"
+				"self": "/selections?filter[posts]=`+post1+`,`+post2+`"
 			}`, linkUnescape(links), tester.DebugRequest(rq, r))
 		})
 
-		// get posts in ascending order
-		tester.Request("GET", "posts?sort=text-body", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// filter comments for absent string
+		tester.Request("GET", "comments?filter[message]=", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			data := gjson.Get(r.Body.String(), "data").Raw
 			links := gjson.Get(r.Body.String(), "links").Raw
 
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `[
 				{
-					"type": "posts",
-					"id": "`+post1+`",
+					"type": "comments",
+					"id": "`+comment1+`",
 					"attributes": {
-						"title": "post-1",
-						"published": false,
-						"text-body": "body-1"
+						"message": ""
 					},
 					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+post1+`/relationships/comments",
-								"related": "/posts/`+post1+`/comments"
-							}
-						},
-						"selections": {
-							"data": [],
+						"post": {
+							"data": {
+								"type": "posts",
+								"id": "`+post1+`"
+							},
 							"links": {
-								"self": "/posts/`+post1+`/relationships/selections",
-								"related": "/posts/`+post1+`/selections"
+								"self": "/comments/`+comment1+`/relationships/post",
+								"related": "/comments/`+comment1+`/post"
 							}
 						},
-						"note": {
+						"parent": {
 							"data": null,
 							"links": {
-								"self": "/posts/`+post1+`/relationships/note",
-								"related": "/posts/`+post1+`/note"
+								"self": "/comments/`+comment1+`/relationships/parent",
+								"related": "/comments/`+comment1+`/parent"
 							}
 						}
 					}
-				},
+				}
+			]`, data, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/comments?filter[message]="
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+
+		// filter comments for absent relationship
+		tester.Request("GET", "comments?filter[parent]=", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			data := gjson.Get(r.Body.String(), "data").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
 				{
-					"type": "posts",
-					"id": "`+post2+`",
+					"type": "comments",
+					"id": "`+comment1+`",
 					"attributes": {
-						"title": "post-2",
-						"published": false,
-						"text-body": "body-2"
+						"message": ""
 					},
 					"relationships": {
-						"comments": {
-							"data": [],
+						"post": {
+							"data": {
+								"type": "posts",
+								"id": "`+post1+`"
+							},
 							"links": {
-								"self": "/posts/`+post2+`/relationships/comments",
-								"related": "/posts/`+post2+`/comments"
+								"self": "/comments/`+comment1+`/relationships/post",
+								"related": "/comments/`+comment1+`/post"
 							}
 						},
-						"selections": {
-							"data": [],
+						"parent": {
+							"data": null,
 							"links": {
-								"self": "/posts/`+post2+`/relationships/selections",
-								"related": "/posts/`+post2+`/selections"
-							}
+								"self": "/comments/`+comment1+`/relationships/parent",
+								"related": "/comments/`+comment1+`/parent"
+							}
+						}
+					}
+				}
+			]`, data, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/comments?filter[parent]="
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestFilterHandlers(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: filter handler for missing filter "Title"`, func() {
+			tester.Assign("", &Controller{
+				Model: &postModel{},
+				FilterHandlers: map[string]FilterHandler{
+					"Title": nil,
+				},
+			})
+		})
+
+		tester.Assign("", &Controller{
+			Model:   &postModel{},
+			Filters: []string{"Title"},
+			FilterHandlers: map[string]FilterHandler{
+				"Title": func(ctx *Context, values []string) (bson.M, error) {
+					if len(values) == 1 && values[0] == "error" {
+						return nil, xo.SF("invalid title filter")
+					} else if len(values) == 1 && values[0] == "true" {
+						return bson.M{
+							"Title": "bar",
+						}, nil
+					}
+					return nil, nil
+				},
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model:   &selectionModel{},
+			Filters: []string{"Posts"},
+		}, &Controller{
+			Model:   &noteModel{},
+			Filters: []string{"Post"},
+		})
+
+		// create posts
+		post1 := tester.Insert(&postModel{
+			Title: "foo",
+		}).ID().Hex()
+		post2 := tester.Insert(&postModel{
+			Title: "bar",
+		}).ID().Hex()
+		tester.Insert(&postModel{
+			Title: "baz",
+		})
+
+		// test filter handler error
+		tester.Request("GET", "posts?filter[title]=error", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid title filter"
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// test filter handler
+		tester.Request("GET", "posts?filter[title]=true", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			ids := gjson.Get(r.Body.String(), "data.#.id").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
+				"`+post2+`"
+			]`, ids, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?filter[title]=true"
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+
+		// test nil expression filter handler
+		tester.Request("GET", "posts?filter[title]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			ids := gjson.Get(r.Body.String(), "data.#.id").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
+				"`+post1+`"
+			]`, ids, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?filter[title]=foo"
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestSorting(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:   &postModel{},
+			Sorters: []string{"Title", "TextBody"},
+		}, &Controller{
+			Model:   &commentModel{},
+			Sorters: []string{"Message"},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		// create posts in random order
+		post2 := tester.Insert(&postModel{
+			Title:    "post-2",
+			TextBody: "body-2",
+		}).ID().Hex()
+		post1 := tester.Insert(&postModel{
+			Title:    "post-1",
+			TextBody: "body-1",
+		}).ID().Hex()
+		post3 := tester.Insert(&postModel{
+			Title:    "post-3",
+			TextBody: "body-3",
+		}).ID().Hex()
+
+		// test invalid sorter
+		tester.Request("GET", "posts?sort=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid sorter \"foo\""
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// test invalid sorter
+		tester.Request("GET", "posts?sort=published", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "400",
+					"title": "bad request",
+					"detail": "unsupported sorter \"published\""
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// get posts in ascending order
+		tester.Request("GET", "posts?sort=title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			data := gjson.Get(r.Body.String(), "data").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
+				{
+					"type": "posts",
+					"id": "`+post1+`",
+					"attributes": {
+						"title": "post-1",
+						"published": false,
+						"text-body": "body-1"
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post1+`/relationships/comments",
+								"related": "/posts/`+post1+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post1+`/relationships/selections",
+								"related": "/posts/`+post1+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post1+`/relationships/note",
+								"related": "/posts/`+post1+`/note"
+							}
+						}
+					}
+				},
+				{
+					"type": "posts",
+					"id": "`+post2+`",
+					"attributes": {
+						"title": "post-2",
+						"published": false,
+						"text-body": "body-2"
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post2+`/relationships/comments",
+								"related": "/posts/`+post2+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post2+`/relationships/selections",
+								"related": "/posts/`+post2+`/selections"
+							}
 						},
 						"note": {
 							"data": null,
@@ -3021,49 +3496,267 @@ func TestSorting(t *testing.T) {
 				}
 			]`, data, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?sort=text-body"
+				"self": "/posts?sort=title"
 			}`, linkUnescape(links), tester.DebugRequest(rq, r))
 		})
 
-		// create post
-		post := tester.Insert(&postModel{
-			Title: "Post",
-		}).ID()
-
-		// create some comments
-		comment1 := tester.Insert(&commentModel{
-			Message: "Comment 1",
-			Post:    post,
-		}).ID().Hex()
-		comment2 := tester.Insert(&commentModel{
-			Message: "Comment 2",
-			Post:    post,
-		}).ID().Hex()
-		comment3 := tester.Insert(&commentModel{
-			Message: "Comment 3",
-			Post:    post,
-		}).ID().Hex()
-
-		// get first page of comments
-		tester.Request("GET", "posts/"+post.Hex()+"/comments?sort=message", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// get posts in descending order
+		tester.Request("GET", "posts?sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			data := gjson.Get(r.Body.String(), "data").Raw
 			links := gjson.Get(r.Body.String(), "links").Raw
 
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `[
 				{
-					"type": "comments",
-					"id": "`+comment1+`",
+					"type": "posts",
+					"id": "`+post3+`",
 					"attributes": {
-						"message": "Comment 1"
+						"title": "post-3",
+						"published": false,
+						"text-body": "body-3"
 					},
 					"relationships": {
-						"parent": {
-							"data": null,
+						"comments": {
+							"data": [],
 							"links": {
-								"self": "/comments/`+comment1+`/relationships/parent",
-								"related": "/comments/`+comment1+`/parent"
-							}
+								"self": "/posts/`+post3+`/relationships/comments",
+								"related": "/posts/`+post3+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post3+`/relationships/selections",
+								"related": "/posts/`+post3+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post3+`/relationships/note",
+								"related": "/posts/`+post3+`/note"
+							}
+						}
+					}
+				},
+				{
+					"type": "posts",
+					"id": "`+post2+`",
+					"attributes": {
+						"title": "post-2",
+						"published": false,
+						"text-body": "body-2"
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post2+`/relationships/comments",
+								"related": "/posts/`+post2+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post2+`/relationships/selections",
+								"related": "/posts/`+post2+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post2+`/relationships/note",
+								"related": "/posts/`+post2+`/note"
+							}
+						}
+					}
+				},
+				{
+					"type": "posts",
+					"id": "`+post1+`",
+					"attributes": {
+						"title": "post-1",
+						"published": false,
+						"text-body": "body-1"
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post1+`/relationships/comments",
+								"related": "/posts/`+post1+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post1+`/relationships/selections",
+								"related": "/posts/`+post1+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post1+`/relationships/note",
+								"related": "/posts/`+post1+`/note"
+							}
+						}
+					}
+				}
+			]`, data, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?sort=-title"
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+
+		// get posts in ascending order
+		tester.Request("GET", "posts?sort=text-body", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			data := gjson.Get(r.Body.String(), "data").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
+				{
+					"type": "posts",
+					"id": "`+post1+`",
+					"attributes": {
+						"title": "post-1",
+						"published": false,
+						"text-body": "body-1"
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post1+`/relationships/comments",
+								"related": "/posts/`+post1+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post1+`/relationships/selections",
+								"related": "/posts/`+post1+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post1+`/relationships/note",
+								"related": "/posts/`+post1+`/note"
+							}
+						}
+					}
+				},
+				{
+					"type": "posts",
+					"id": "`+post2+`",
+					"attributes": {
+						"title": "post-2",
+						"published": false,
+						"text-body": "body-2"
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post2+`/relationships/comments",
+								"related": "/posts/`+post2+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post2+`/relationships/selections",
+								"related": "/posts/`+post2+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post2+`/relationships/note",
+								"related": "/posts/`+post2+`/note"
+							}
+						}
+					}
+				},
+				{
+					"type": "posts",
+					"id": "`+post3+`",
+					"attributes": {
+						"title": "post-3",
+						"published": false,
+						"text-body": "body-3"
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post3+`/relationships/comments",
+								"related": "/posts/`+post3+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post3+`/relationships/selections",
+								"related": "/posts/`+post3+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post3+`/relationships/note",
+								"related": "/posts/`+post3+`/note"
+							}
+						}
+					}
+				}
+			]`, data, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?sort=text-body"
+			}`, linkUnescape(links), tester.DebugRequest(rq, r))
+		})
+
+		// create post
+		post := tester.Insert(&postModel{
+			Title: "Post",
+		}).ID()
+
+		// create some comments
+		comment1 := tester.Insert(&commentModel{
+			Message: "Comment 1",
+			Post:    post,
+		}).ID().Hex()
+		comment2 := tester.Insert(&commentModel{
+			Message: "Comment 2",
+			Post:    post,
+		}).ID().Hex()
+		comment3 := tester.Insert(&commentModel{
+			Message: "Comment 3",
+			Post:    post,
+		}).ID().Hex()
+
+		// get first page of comments
+		tester.Request("GET", "posts/"+post.Hex()+"/comments?sort=message", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			data := gjson.Get(r.Body.String(), "data").Raw
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `[
+				{
+					"type": "comments",
+					"id": "`+comment1+`",
+					"attributes": {
+						"message": "Comment 1"
+					},
+					"relationships": {
+						"parent": {
+							"data": null,
+							"links": {
+								"self": "/comments/`+comment1+`/relationships/parent",
+								"related": "/comments/`+comment1+`/parent"
+							}
 						},
 						"post": {
 							"data": {
@@ -5763,40 +6456,1751 @@ func TestRelationshipFilters(t *testing.T) {
 	})
 }
 
-func TestDeferredCallbacks(t *testing.T) {
-	withTester(t, func(t *testing.T, tester *Tester) {
-		tester.Assign("", &Controller{
-			Model: &postModel{},
-			Authorizers: L{
-				C("Test", Authorizer, All(), func(ctx *Context) error {
-					ctx.Defer(C("Test", Validator, Only(Create), func(ctx *Context) error {
-						return io.EOF
-					}))
-					return nil
-				}),
-			},
+func TestDeferredCallbacks(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+			Authorizers: L{
+				C("Test", Authorizer, All(), func(ctx *Context) error {
+					ctx.Defer(C("Test", Validator, Only(Create), func(ctx *Context) error {
+						return io.EOF
+					}))
+					return nil
+				}),
+			},
+		})
+
+		// missing resource
+		tester.Request("POST", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "400",
+						"title": "bad request",
+						"detail": "EOF"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestDatabaseErrors(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		// missing resource
+		tester.Request("GET", "posts/"+coal.New().Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNotFound, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "404",
+						"title": "not found",
+						"detail": "resource not found"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// add unique index
+		index, err := tester.Store.C(&postModel{}).Native().Indexes().CreateOne(tester.Context, mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "title", Value: int32(1)},
+			},
+			Options: options.Index().SetUnique(true),
+		})
+		assert.NoError(t, err)
+
+		// first post
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Post 1"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		// second post
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Post 1"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "400",
+						"title": "bad request",
+						"detail": "document not unique"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// remove index
+		_, err = tester.Store.C(&postModel{}).Native().Indexes().DropOne(tester.Context, index)
+		assert.NoError(t, err)
+	})
+}
+
+func TestTolerateViolations(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+			Authorizers: L{
+				C("TestWritableFields", Authorizer, All(), func(ctx *Context) error {
+					ctx.WritableFields = []string{"Title"}
+					return nil
+				}),
+			},
+			TolerateViolations: []string{"Published"},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+			Authorizers: L{
+				C("TestWritableFields", Authorizer, All(), func(ctx *Context) error {
+					ctx.WritableFields = []string{}
+					return nil
+				}),
+			},
+			TolerateViolations: []string{"Posts"},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		// attempt to create post with protected field
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Post 1",
+					"published": true
+				},
+				"relationships": {}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			post := tester.FindLast(&postModel{}).ID().Hex()
+
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "posts",
+					"id": "`+post+`",
+					"attributes": {
+						"title": "Post 1",
+						"published": false,
+						"text-body": ""
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post+`/relationships/comments",
+								"related": "/posts/`+post+`/comments"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+post+`/relationships/note",
+								"related": "/posts/`+post+`/note"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+post+`/relationships/selections",
+								"related": "/posts/`+post+`/selections"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/posts/`+post+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// attempt to create selection with protected relationship
+		tester.Request("POST", "selections", `{
+			"data": {
+				"type": "selections",
+				"attributes": {},
+				"relationships": {
+					"posts": {
+						"data": [{
+							"type": "posts",
+							"id": "`+coal.New().Hex()+`"
+						}]
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			selection := tester.FindLast(&selectionModel{}).ID().Hex()
+
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "selections",
+					"id": "`+selection+`",
+					"attributes": {
+						"name": ""
+					},
+					"relationships": {
+						"posts": {
+							"data": [],
+							"links": {
+								"self": "/selections/`+selection+`/relationships/posts",
+								"related": "/selections/`+selection+`/posts"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/selections/`+selection+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestOffsetPagination(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:     &postModel{},
+			ListLimit: 7,
+		}, &Controller{
+			Model:     &commentModel{},
+			ListLimit: 7,
+		}, &Controller{
+			Model:     &selectionModel{},
+			ListLimit: 7,
+		}, &Controller{
+			Model:     &noteModel{},
+			ListLimit: 7,
+		})
+
+		// prepare IDs
+		var ids []coal.ID
+
+		// create some posts
+		for i := 0; i < 10; i++ {
+			ids = append(ids, tester.Insert(&postModel{
+				Title: fmt.Sprintf("Post %d", i+1),
+			}).ID())
+		}
+
+		// get first page of posts
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[number]=1&page[size]=7",
+				"first": "/posts?page[number]=1&page[size]=7",
+				"last": "/posts?page[number]=2&page[size]=7",
+				"next": "/posts?page[number]=2&page[size]=7"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of posts
+		tester.Request("GET", "posts?page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[number]=1&page[size]=5",
+				"first": "/posts?page[number]=1&page[size]=5",
+				"last": "/posts?page[number]=2&page[size]=5",
+				"next": "/posts?page[number]=2&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of posts
+		tester.Request("GET", "posts?page[number]=1&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[number]=1&page[size]=5",
+				"first": "/posts?page[number]=1&page[size]=5",
+				"last": "/posts?page[number]=2&page[size]=5",
+				"next": "/posts?page[number]=2&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get second page of posts
+		tester.Request("GET", "posts?page[number]=2&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 6", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[number]=2&page[size]=5",
+				"first": "/posts?page[number]=1&page[size]=5",
+				"last": "/posts?page[number]=2&page[size]=5",
+				"prev": "/posts?page[number]=1&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// create selection
+		selection := tester.Insert(&selectionModel{
+			Posts: ids,
+		}).ID().Hex()
+
+		// get first page of posts
+		tester.Request("GET", "selections/"+selection+"/posts?page[number]=1&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/selections/`+selection+`/posts?page[number]=1&page[size]=5",
+				"first": "/selections/`+selection+`/posts?page[number]=1&page[size]=5",
+				"last": "/selections/`+selection+`/posts?page[number]=2&page[size]=5",
+				"next": "/selections/`+selection+`/posts?page[number]=2&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get second page of posts
+		tester.Request("GET", "selections/"+selection+"/posts?page[number]=2&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 6", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/selections/`+selection+`/posts?page[number]=2&page[size]=5",
+				"first": "/selections/`+selection+`/posts?page[number]=1&page[size]=5",
+				"last": "/selections/`+selection+`/posts?page[number]=2&page[size]=5",
+				"prev": "/selections/`+selection+`/posts?page[number]=1&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// create post
+		post := tester.Insert(&postModel{
+			Title: "Post",
+		}).ID()
+
+		// create some comments
+		for i := 0; i < 10; i++ {
+			tester.Insert(&commentModel{
+				Message: fmt.Sprintf("Comment %d", i+1),
+				Post:    post,
+			})
+		}
+
+		// get first page of comments
+		tester.Request("GET", "posts/"+post.Hex()+"/comments?page[number]=1&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Comment 1", list[0].Get("attributes.message").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts/`+post.Hex()+`/comments?page[number]=1&page[size]=5",
+				"first": "/posts/`+post.Hex()+`/comments?page[number]=1&page[size]=5",
+				"last": "/posts/`+post.Hex()+`/comments?page[number]=2&page[size]=5",
+				"next": "/posts/`+post.Hex()+`/comments?page[number]=2&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get second page of comments
+		tester.Request("GET", "posts/"+post.Hex()+"/comments?page[number]=2&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Comment 6", list[0].Get("attributes.message").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts/`+post.Hex()+`/comments?page[number]=2&page[size]=5",
+				"first": "/posts/`+post.Hex()+`/comments?page[number]=1&page[size]=5",
+				"last": "/posts/`+post.Hex()+`/comments?page[number]=2&page[size]=5",
+				"prev": "/posts/`+post.Hex()+`/comments?page[number]=1&page[size]=5"
+			}`, linkUnescape(links))
+		})
+	})
+}
+
+func TestCursorPagination(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:            &postModel{},
+			Filters:          []string{"Published"},
+			Sorters:          []string{"Title", "TextBody", "Published"},
+			ListLimit:        7,
+			CursorPagination: true,
+		}, &Controller{
+			Model:            &commentModel{},
+			ListLimit:        7,
+			CursorPagination: true,
+		}, &Controller{
+			Model:            &selectionModel{},
+			ListLimit:        7,
+			CursorPagination: true,
+		}, &Controller{
+			Model:            &noteModel{},
+			ListLimit:        7,
+			CursorPagination: true,
+		})
+
+		// create some posts
+		for i := 0; i < 10; i++ {
+			tester.Insert(&postModel{
+				Base:      coal.B(numID(uint8(i) + 1)),
+				Title:     fmt.Sprintf("Post %02d", i+1),
+				TextBody:  fmt.Sprintf("Body %02d", 10-i),
+				Published: i >= 5,
+			})
+		}
+
+		/* ascending */
+
+		// get first page of posts
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 01", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 07", list[6].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[after]=*&page[size]=7",
+				"first": "/posts?page[after]=*&page[size]=7",
+				"prev": null,
+				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABwA&page[size]=7",
+				"last": "/posts?page[before]=*&page[size]=7"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of posts with size
+		tester.Request("GET", "posts?page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 01", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 05", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[after]=*&page[size]=5",
+				"first": "/posts?page[after]=*&page[size]=5",
+				"prev": null,
+				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5",
+				"last": "/posts?page[before]=*&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get last page of posts
+		tester.Request("GET", "posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 10", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5",
+				"first": "/posts?page[after]=*&page[size]=5",
+				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=5",
+				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAACgA&page[size]=5",
+				"last": "/posts?page[before]=*&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get empty last page of posts
+		tester.Request("GET", "posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAACgA&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 0, len(list), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAACgA&page[size]=5",
+				"first": "/posts?page[after]=*&page[size]=5",
+				"prev": "/posts?page[before]=*&page[size]=5",
+				"next": null,
+				"last": "/posts?page[before]=*&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get capped page of posts
+		tester.Request("GET", "posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=6", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 10", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=6",
+				"first": "/posts?page[after]=*&page[size]=6",
+				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=6",
+				"next": null,
+				"last": "/posts?page[before]=*&page[size]=6"
+			}`, linkUnescape(links))
+		})
+
+		/* descending */
+
+		// get last page of posts (reverse)
+		tester.Request("GET", "posts?page[before]=*&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 10", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[before]=*&page[size]=5",
+				"first": "/posts?page[after]=*&page[size]=5",
+				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=5",
+				"next": null,
+				"last": "/posts?page[before]=*&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get middle page of posts (reverse)
+		tester.Request("GET", "posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 3, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 03", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 05", list[2].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=3",
+				"first": "/posts?page[after]=*&page[size]=3",
+				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAAAwA&page[size]=3",
+				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=3",
+				"last": "/posts?page[before]=*&page[size]=3"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of posts (reverse)
+		tester.Request("GET", "posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 01", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 05", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=5",
+				"first": "/posts?page[after]=*&page[size]=5",
+				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAAAQA&page[size]=5",
+				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5",
+				"last": "/posts?page[before]=*&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get empty first page of posts (reverse)
+		tester.Request("GET", "posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAAAQA&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 0, len(list), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAAAQA&page[size]=5",
+				"first": "/posts?page[after]=*&page[size]=5",
+				"prev": null,
+				"next": "/posts?page[after]=*&page[size]=5",
+				"last": "/posts?page[before]=*&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// get capped first page of posts (reverse)
+		tester.Request("GET", "posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=6", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 01", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 05", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=6",
+				"first": "/posts?page[after]=*&page[size]=6",
+				"prev": null,
+				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=6",
+				"last": "/posts?page[before]=*&page[size]=6"
+			}`, linkUnescape(links))
+		})
+
+		/* sorted */
+
+		// get first page of sorted posts
+		tester.Request("GET", "posts?sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 10", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 04", list[6].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[after]=*&page[size]=7&sort=-title",
+				"first": "/posts?page[after]=*&page[size]=7&sort=-title",
+				"prev": null,
+				"next": "/posts?page[after]=IwAAAAIwAAgAAABQb3N0IDA0AAcxAAAAAAAAAAAAAAAABAA&page[size]=7&sort=-title",
+				"last": "/posts?page[before]=*&page[size]=7&sort=-title"
+			}`, linkUnescape(links))
+		})
+
+		// get last page of sorted posts
+		tester.Request("GET", "posts?page[after]=IwAAAAIwAAgAAABQb3N0IDA0AAcxAAAAAAAAAAAAAAAABAA&page[size]=7&sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 3, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 03", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 01", list[2].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[after]=IwAAAAIwAAgAAABQb3N0IDA0AAcxAAAAAAAAAAAAAAAABAA&page[size]=7&sort=-title",
+				"first": "/posts?page[after]=*&page[size]=7&sort=-title",
+				"prev": "/posts?page[before]=IwAAAAIwAAgAAABQb3N0IDAzAAcxAAAAAAAAAAAAAAAAAwA&page[size]=7&sort=-title",
+				"next": null,
+				"last": "/posts?page[before]=*&page[size]=7&sort=-title"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of sorted posts (reverse)
+		tester.Request("GET", "posts?page[before]=IwAAAAIwAAgAAABQb3N0IDAzAAcxAAAAAAAAAAAAAAAAAwA&page[size]=7&sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 10", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 04", list[6].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[before]=IwAAAAIwAAgAAABQb3N0IDAzAAcxAAAAAAAAAAAAAAAAAwA&page[size]=7&sort=-title",
+				"first": "/posts?page[after]=*&page[size]=7&sort=-title",
+				"prev": "/posts?page[before]=IwAAAAIwAAgAAABQb3N0IDEwAAcxAAAAAAAAAAAAAAAACgA&page[size]=7&sort=-title",
+				"next": "/posts?page[after]=IwAAAAIwAAgAAABQb3N0IDA0AAcxAAAAAAAAAAAAAAAABAA&page[size]=7&sort=-title",
+				"last": "/posts?page[before]=*&page[size]=7&sort=-title"
+			}`, linkUnescape(links))
+		})
+
+		/* filtered */
+
+		// get first page of filtered posts
+		tester.Request("GET", "posts?filter[published]=true&page[size]=3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 3, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 08", list[2].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?filter[published]=true&page[after]=*&page[size]=3",
+				"first": "/posts?filter[published]=true&page[after]=*&page[size]=3",
+				"prev": null,
+				"next": "/posts?filter[published]=true&page[after]=FAAAAAcwAAAAAAAAAAAAAAAACAA&page[size]=3",
+				"last": "/posts?filter[published]=true&page[before]=*&page[size]=3"
+			}`, linkUnescape(links))
+		})
+
+		// get last page of filtered posts
+		tester.Request("GET", "posts?filter[published]=true&page[after]=FAAAAAcwAAAAAAAAAAAAAAAACAA&page[size]=3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 2, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 09", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 10", list[1].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?filter[published]=true&page[after]=FAAAAAcwAAAAAAAAAAAAAAAACAA&page[size]=3",
+				"first": "/posts?filter[published]=true&page[after]=*&page[size]=3",
+				"prev": "/posts?filter[published]=true&page[before]=FAAAAAcwAAAAAAAAAAAAAAAACQA&page[size]=3",
+				"next": null,
+				"last": "/posts?filter[published]=true&page[before]=*&page[size]=3"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of filtered posts (reverse)
+		tester.Request("GET", "posts?filter[published]=true&page[before]=FAAAAAcwAAAAAAAAAAAAAAAACQA&page[size]=3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 3, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 08", list[2].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?filter[published]=true&page[before]=FAAAAAcwAAAAAAAAAAAAAAAACQA&page[size]=3",
+				"first": "/posts?filter[published]=true&page[after]=*&page[size]=3",
+				"prev": "/posts?filter[published]=true&page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=3",
+				"next": "/posts?filter[published]=true&page[after]=FAAAAAcwAAAAAAAAAAAAAAAACAA&page[size]=3",
+				"last": "/posts?filter[published]=true&page[before]=*&page[size]=3"
+			}`, linkUnescape(links))
+		})
+
+		// TODO: Test relationship pagination.
+
+		// try range pagination
+		tester.Request("GET", "posts?page[after]=X&page[before]=X", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "range pagination not supported"
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// try invalid cursor sorting combination
+		tester.Request("GET", "posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5&sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "cursor sorting mismatch"
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestPaginationSelection(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:     &postModel{},
+			ListLimit: 7,
+		}, &Controller{
+			Model:            &commentModel{},
+			ListLimit:        7,
+			CursorPagination: true,
+		}, &Controller{
+			Model:     &selectionModel{},
+			ListLimit: 7,
+		}, &Controller{
+			Model:     &noteModel{},
+			ListLimit: 7,
+		})
+
+		// create some posts
+		for i := 0; i < 10; i++ {
+			tester.Insert(&postModel{
+				Base:  coal.B(numID(uint8(i) + 1)),
+				Title: fmt.Sprintf("Post %d", i+1),
+			})
+		}
+
+		// get first page of posts with default pagination
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[number]=1&page[size]=7",
+				"first": "/posts?page[number]=1&page[size]=7",
+				"last": "/posts?page[number]=2&page[size]=7",
+				"next": "/posts?page[number]=2&page[size]=7"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of posts using offset pagination
+		tester.Request("GET", "posts?pagination=offset", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[number]=1&page[size]=7&pagination=offset",
+				"first": "/posts?page[number]=1&page[size]=7&pagination=offset",
+				"last": "/posts?page[number]=2&page[size]=7&pagination=offset",
+				"next": "/posts?page[number]=2&page[size]=7&pagination=offset"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of posts using cursor pagination
+		tester.Request("GET", "posts?pagination=cursor", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[after]=*&page[size]=7&pagination=cursor",
+				"first": "/posts?page[after]=*&page[size]=7&pagination=cursor",
+				"prev": null,
+				"last": "/posts?page[before]=*&page[size]=7&pagination=cursor",
+				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABwA&page[size]=7&pagination=cursor"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of posts using unknown pagination
+		tester.Request("GET", "posts?pagination=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "unknown pagination",
+					"source": {
+						"parameter": "pagination"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// create post
+		post := tester.Insert(&postModel{
+			Title: "Post",
+		}).ID()
+
+		// create some comments
+		for i := 0; i < 10; i++ {
+			tester.Insert(&commentModel{
+				Base:    coal.B(numID(uint8(i) + 1)),
+				Message: fmt.Sprintf("Comment %d", i+1),
+				Post:    post,
+			})
+		}
+
+		// get first page of comments with default pagination
+		tester.Request("GET", "posts/"+post.Hex()+"/comments", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Comment 1", list[0].Get("attributes.message").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts/`+post.Hex()+`/comments?page[after]=*&page[size]=7",
+				"first": "/posts/`+post.Hex()+`/comments?page[after]=*&page[size]=7",
+				"prev": null,
+				"last": "/posts/`+post.Hex()+`/comments?page[before]=*&page[size]=7",
+				"next": "/posts/`+post.Hex()+`/comments?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABwA&page[size]=7"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of comments with cursor pagination
+		tester.Request("GET", "posts/"+post.Hex()+"/comments?pagination=cursor", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Comment 1", list[0].Get("attributes.message").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts/`+post.Hex()+`/comments?page[after]=*&page[size]=7&pagination=cursor",
+				"first": "/posts/`+post.Hex()+`/comments?page[after]=*&page[size]=7&pagination=cursor",
+				"prev": null,
+				"last": "/posts/`+post.Hex()+`/comments?page[before]=*&page[size]=7&pagination=cursor",
+				"next": "/posts/`+post.Hex()+`/comments?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABwA&page[size]=7&pagination=cursor"
+			}`, linkUnescape(links))
+		})
+
+		// get first page of comments with offset pagination
+		tester.Request("GET", "posts/"+post.Hex()+"/comments?pagination=offset", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "unsupported pagination",
+					"source": {
+						"parameter": "pagination"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestListLimit(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:     &postModel{},
+			ListLimit: 5,
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		// create some posts
+		for i := 0; i < 10; i++ {
+			tester.Insert(&postModel{
+				Title: fmt.Sprintf("Post %d", i+1),
+			})
+		}
+
+		// get first page of posts
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			list := gjson.Get(r.Body.String(), "data").Array()
+			links := gjson.Get(r.Body.String(), "links").Raw
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "/posts?page[number]=1&page[size]=5",
+				"first": "/posts?page[number]=1&page[size]=5",
+				"last": "/posts?page[number]=2&page[size]=5",
+				"next": "/posts?page[number]=2&page[size]=5"
+			}`, linkUnescape(links))
+		})
+
+		// try bigger page size than limit
+		tester.Request("GET", "posts?page[size]=7", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "max page size exceeded",
+					"source": {
+						"parameter": "page[size]"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestCollectionActions(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: invalid collection action ""`, func() {
+			tester.Assign("api", &Controller{
+				Model: &postModel{},
+				CollectionActions: M{
+					"": A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
+						return nil
+					}),
+				},
+			})
+		})
+
+		id := coal.New().Hex()
+		assert.PanicsWithValue(t, `fire: invalid collection action "`+id+`"`, func() {
+			tester.Assign("api", &Controller{
+				Model: &postModel{},
+				CollectionActions: M{
+					id: A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
+						return nil
+					}),
+				},
+			})
+		})
+
+		tester.Assign("api", &Controller{
+			Model: &postModel{},
+			CollectionActions: M{
+				"bytes": A("bytes", []string{"POST"}, 0, 0, func(ctx *Context) error {
+					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, []byte("PAYLOAD"), bytes)
+
+					_, err = ctx.ResponseWriter.Write([]byte("RESPONSE"))
+					assert.NoError(t, err)
+
+					return nil
+				}),
+				"empty": A("empty", []string{"POST"}, 0, 0, func(ctx *Context) error {
+					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
+					assert.NoError(t, err)
+					assert.Empty(t, bytes)
+
+					return nil
+				}),
+				"error": A("error", []string{"POST"}, 3, 0, func(ctx *Context) error {
+					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
+					assert.Error(t, err)
+					assert.Equal(t, []byte{}, bytes)
+
+					ctx.ResponseWriter.WriteHeader(http.StatusRequestEntityTooLarge)
+
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		// get byte response
+		tester.Header["Content-Type"] = "text/plain"
+		tester.Header["Accept"] = "text/plain"
+		tester.Request("POST", "posts/bytes", "PAYLOAD", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "text/plain; charset=utf-8", r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
+			assert.Equal(t, "RESPONSE", r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// get empty response
+		tester.Header["Content-Type"] = ""
+		tester.Header["Accept"] = ""
+		tester.Request("POST", "posts/empty", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// error
+		tester.Request("POST", "posts/error", "PAYLOAD", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusRequestEntityTooLarge, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestResourceActions(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: invalid resource action ""`, func() {
+			tester.Assign("api", &Controller{
+				Model: &postModel{},
+				ResourceActions: M{
+					"": A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
+						return nil
+					}),
+				},
+			})
+		})
+
+		assert.PanicsWithValue(t, `fire: invalid resource action "relationships"`, func() {
+			tester.Assign("api", &Controller{
+				Model: &postModel{},
+				ResourceActions: M{
+					"relationships": A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
+						return nil
+					}),
+				},
+			})
+		})
+
+		assert.PanicsWithValue(t, `fire: invalid resource action "note"`, func() {
+			tester.Assign("api", &Controller{
+				Model: &postModel{},
+				ResourceActions: M{
+					"note": A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
+						return nil
+					}),
+				},
+			})
+		})
+
+		tester.Assign("api", &Controller{
+			Model: &postModel{},
+			ResourceActions: M{
+				"bytes": A("bytes", []string{"POST"}, 0, 0, func(ctx *Context) error {
+					assert.NotEmpty(t, ctx.Model)
+
+					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, []byte("PAYLOAD"), bytes)
+
+					_, err = ctx.ResponseWriter.Write([]byte("RESPONSE"))
+					assert.NoError(t, err)
+
+					return nil
+				}),
+				"empty": A("empty", []string{"POST"}, 0, 0, func(ctx *Context) error {
+					assert.NotEmpty(t, ctx.Model)
+
+					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
+					assert.NoError(t, err)
+					assert.Empty(t, bytes)
+
+					return nil
+				}),
+				"error": A("error", []string{"POST"}, 3, 0, func(ctx *Context) error {
+					assert.NotEmpty(t, ctx.Model)
+
+					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
+					assert.Error(t, err)
+					assert.Equal(t, []byte{}, bytes)
+					assert.Equal(t, serve.ErrBodyLimitExceeded, err)
+
+					ctx.ResponseWriter.WriteHeader(http.StatusRequestEntityTooLarge)
+
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		post := tester.Insert(&postModel{
+			Title: "Post",
+		}).(*postModel).ID()
+
+		// get byte response
+		tester.Header["Content-Type"] = "text/plain"
+		tester.Header["Accept"] = "text/plain"
+		tester.Request("POST", "posts/"+post.Hex()+"/bytes", "PAYLOAD", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "text/plain; charset=utf-8", r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
+			assert.Equal(t, "RESPONSE", r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// get empty response
+		tester.Header["Content-Type"] = ""
+		tester.Header["Accept"] = ""
+		tester.Request("POST", "posts/"+post.Hex()+"/empty", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// get error
+		tester.Request("POST", "posts/"+post.Hex()+"/error", "PAYLOAD", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusRequestEntityTooLarge, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestActionValidation(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("api", &Controller{
+			Model: &postModel{},
+			CollectionActions: M{
+				"validate": &Action{
+					Methods: []string{"POST"},
+					Body:    &actionInput{},
+					Query: map[string][]stick.Rule{
+						"mode": {stick.IsNotZero},
+					},
+					BodyLimit: serve.MustByteSize("8M"),
+					Timeout:   time.Minute,
+					Handler: func(ctx *Context) error {
+						return ctx.Respond(ctx.Body)
+					},
+				},
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		tester.Header["Content-Type"] = "application/json"
+		tester.Header["Accept"] = "application/json"
+
+		// valid request
+		tester.Request("POST", "posts/validate?mode=foo", `{"name":"foo","count":2,"tags":["a"]}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{"name":"foo","count":2,"tags":["a"]}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// invalid body and query
+		tester.Request("POST", "posts/validate?foo=bar", `{"name":"","count":0,"tags":["a",""]}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{"status": "400", "title": "bad request", "detail": "invalid parameter", "source": {"parameter": "foo"}},
+					{"status": "400", "title": "bad request", "detail": "zero", "source": {"parameter": "mode"}},
+					{"status": "400", "title": "bad request", "detail": "too small", "source": {"pointer": "/count"}},
+					{"status": "400", "title": "bad request", "detail": "zero", "source": {"pointer": "/name"}},
+					{"status": "400", "title": "bad request", "detail": "zero", "source": {"pointer": "/tags/1"}}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// unknown field
+		tester.Request("POST", "posts/validate?mode=foo", `{"name":"foo","count":1,"bar":true}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{"status": "400", "title": "bad request", "detail": "unknown field", "source": {"pointer": "/bar"}}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// invalid type
+		tester.Request("POST", "posts/validate?mode=foo", `{"name":"foo","count":"1"}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{"status": "400", "title": "bad request", "detail": "invalid type", "source": {"pointer": "/count"}}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// missing body
+		tester.Request("POST", "posts/validate?mode=foo", ``, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{"status": "400", "title": "bad request", "detail": "incomplete request body"}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestSoftDelete(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		// missing field on model
+		assert.PanicsWithValue(t, `coal: no or multiple fields flagged as "fire-soft-delete" on "fire.missingSoftDeleteField"`, func() {
+			type missingSoftDeleteField struct {
+				coal.Base `json:"-" bson:",inline" coal:"models"`
+				stick.NoValidation
+			}
+
+			tester.Assign("", &Controller{
+				Model:      &missingSoftDeleteField{},
+				SoftDelete: true,
+			})
+		})
+
+		// invalid field type
+		assert.PanicsWithValue(t, `fire: soft delete field "Foo" for model "fire.invalidSoftDeleteFieldType" is not of type "*time.Time"`, func() {
+			type invalidSoftDeleteFieldType struct {
+				coal.Base `json:"-" bson:",inline" coal:"models"`
+				Foo       int `coal:"fire-soft-delete"`
+				stick.NoValidation
+			}
+
+			tester.Assign("", &Controller{
+				Model:      &invalidSoftDeleteFieldType{},
+				SoftDelete: true,
+			})
+		})
+
+		tester.Assign("", &Controller{
+			Model:      &postModel{},
+			SoftDelete: true,
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		id := tester.Insert(&postModel{
+			Title: "Post 1",
+		}).ID().Hex()
+
+		// get list of posts
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": [
+					{
+						"type": "posts",
+						"id": "`+id+`",
+						"attributes": {
+							"title": "Post 1",
+							"published": false,
+							"text-body": ""
+						},
+						"relationships": {
+							"comments": {
+								"data": [],
+								"links": {
+									"self": "/posts/`+id+`/relationships/comments",
+									"related": "/posts/`+id+`/comments"
+								}
+							},
+							"selections": {
+								"data": [],
+								"links": {
+									"self": "/posts/`+id+`/relationships/selections",
+									"related": "/posts/`+id+`/selections"
+								}
+							},
+							"note": {
+								"data": null,
+								"links": {
+									"self": "/posts/`+id+`/relationships/note",
+									"related": "/posts/`+id+`/note"
+								}
+							}
+						}
+					}
+				],
+				"links": {
+					"self": "/posts"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// get single post
+		tester.Request("GET", "posts/"+id, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "posts",
+					"id": "`+id+`",
+					"attributes": {
+						"title": "Post 1",
+						"published": false,
+						"text-body": ""
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+id+`/relationships/comments",
+								"related": "/posts/`+id+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+id+`/relationships/selections",
+								"related": "/posts/`+id+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+id+`/relationships/note",
+								"related": "/posts/`+id+`/note"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/posts/`+id+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// delete post
+		tester.Request("DELETE", "posts/"+id, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNoContent, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "", r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// check post
+		post := tester.FindLast(&postModel{}).(*postModel)
+		assert.NotNil(t, post)
+		assert.NotNil(t, post.Deleted)
+
+		// get empty list of posts
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data":[],
+				"links": {
+					"self": "/posts"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// get missing post
+		tester.Request("GET", "posts/"+id, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNotFound, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "404",
+						"title": "not found",
+						"detail": "resource not found"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// TODO: Test has one and has many relationships.
+	})
+}
+
+func TestIdempotentCreate(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		// missing field on model
+		assert.PanicsWithValue(t, `coal: no or multiple fields flagged as "fire-idempotent-create" on "fire.missingIdempotentCreateField"`, func() {
+			type missingIdempotentCreateField struct {
+				coal.Base `json:"-" bson:",inline" coal:"models"`
+				stick.NoValidation
+			}
+
+			tester.Assign("", &Controller{
+				Model:            &missingIdempotentCreateField{},
+				IdempotentCreate: true,
+			})
+		})
+
+		// invalid field type
+		assert.PanicsWithValue(t, `fire: idempotent create field "Foo" for model "fire.invalidIdempotentCreateFieldType" is not of type "string"`, func() {
+			type invalidIdempotentCreateFieldType struct {
+				coal.Base `json:"-" bson:",inline" coal:"models"`
+				Foo       int `coal:"fire-idempotent-create"`
+				stick.NoValidation
+			}
+
+			tester.Assign("", &Controller{
+				Model:            &invalidIdempotentCreateFieldType{},
+				IdempotentCreate: true,
+			})
+		})
+
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model:            &selectionModel{},
+			IdempotentCreate: true,
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		// missing create token
+		tester.Request("POST", "selections", `{
+			"data": {
+				"type": "selections",
+				"attributes": {
+					"name": "test"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "400",
+						"title": "bad request",
+						"detail": "missing idempotent create token"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		var id string
+
+		// create selection
+		tester.Request("POST", "selections", `{
+			"data": {
+				"type": "selections",
+				"attributes": {
+					"name": "Selection 1",
+					"create-token": "foo123"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			selection := tester.FindLast(&selectionModel{})
+			id = selection.ID().Hex()
+
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "selections",
+					"id": "`+id+`",
+					"attributes": {
+						"name": "Selection 1",
+						"create-token": "foo123"
+					},
+					"relationships": {
+						"posts": {
+							"data": [],
+							"links": {
+								"self": "/selections/`+id+`/relationships/posts",
+								"related": "/selections/`+id+`/posts"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/selections/`+id+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// attempt to create duplicate
+		tester.Request("POST", "selections", `{
+			"data": {
+				"type": "selections",
+				"attributes": {
+					"name": "Selection 1",
+					"create-token": "foo123"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusConflict, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "409",
+						"title": "conflict",
+						"detail": "existing document with same idempotent create token"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// attempt to change create token
+		tester.Request("PATCH", "selections/"+id, `{
+			"data": {
+				"type": "selections",
+				"id": "`+id+`",
+				"attributes": {
+					"create-token": "bar456"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "400",
+						"title": "bad request",
+						"detail": "idempotent create token cannot be changed"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestConsistentUpdate(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		// missing field on model
+		assert.PanicsWithValue(t, `coal: no or multiple fields flagged as "fire-consistent-update" on "fire.missingConsistentUpdateField"`, func() {
+			type missingConsistentUpdateField struct {
+				coal.Base `json:"-" bson:",inline" coal:"models"`
+				stick.NoValidation
+			}
+
+			tester.Assign("", &Controller{
+				Model:            &missingConsistentUpdateField{},
+				ConsistentUpdate: true,
+			})
+		})
+
+		// invalid field type
+		assert.PanicsWithValue(t, `fire: consistent update field "Foo" for model "fire.invalidConsistentUpdateFieldType" is not of type "string"`, func() {
+			type invalidConsistentUpdateFieldType struct {
+				coal.Base `json:"-" bson:",inline" coal:"models"`
+				Foo       int `coal:"fire-consistent-update"`
+				stick.NoValidation
+			}
+
+			tester.Assign("", &Controller{
+				Model:            &invalidConsistentUpdateFieldType{},
+				ConsistentUpdate: true,
+			})
+		})
+
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model:            &selectionModel{},
+			ConsistentUpdate: true,
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		var id string
+		var selection *selectionModel
+
+		// create selection
+		tester.Request("POST", "selections", `{
+			"data": {
+				"type": "selections",
+				"attributes": {
+					"name": "Selection 1"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			selection = tester.FindLast(&selectionModel{}).(*selectionModel)
+			id = selection.ID().Hex()
+
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "selections",
+					"id": "`+id+`",
+					"attributes": {
+						"name": "Selection 1",
+						"update-token": "`+selection.UpdateToken+`"
+					},
+					"relationships": {
+						"posts": {
+							"data": [],
+							"links": {
+								"self": "/selections/`+id+`/relationships/posts",
+								"related": "/selections/`+id+`/posts"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/selections/`+id+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// missing update token
+		tester.Request("PATCH", "selections/"+id, `{
+			"data": {
+				"type": "selections",
+				"id": "`+id+`",
+				"attributes": {}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "400",
+						"title": "bad request",
+						"detail": "invalid consistent update token"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// missing resource
-		tester.Request("POST", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// invalid update token
+		tester.Request("PATCH", "selections/"+id, `{
+			"data": {
+				"type": "selections",
+				"id": "`+id+`",
+				"attributes": {
+					"update-token": "bar123"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
 				"errors": [
 					{
 						"status": "400",
 						"title": "bad request",
-						"detail": "EOF"
+						"detail": "invalid consistent update token"
 					}
 				]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
+
+		// update selection
+		tester.Request("PATCH", "selections/"+id, `{
+			"data": {
+				"type": "selections",
+				"id": "`+id+`",
+				"attributes": {
+					"update-token": "`+selection.UpdateToken+`"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			selection = tester.FindLast(&selectionModel{}).(*selectionModel)
+
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "selections",
+					"id": "`+id+`",
+					"attributes": {
+						"name": "Selection 1",
+						"update-token": "`+selection.UpdateToken+`"
+					},
+					"relationships": {
+						"posts": {
+							"data": [],
+							"links": {
+								"self": "/selections/`+id+`/relationships/posts",
+								"related": "/selections/`+id+`/posts"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/selections/`+id+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
 	})
 }
 
-func TestDatabaseErrors(t *testing.T) {
+func TestGuardedFields(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: guarded field "Foo" is not a field`, func() {
+			tester.Assign("", &Controller{
+				Model:         &postModel{},
+				GuardedFields: []string{"Foo"},
+			})
+		})
+
+		var concurrent bool
 		tester.Assign("", &Controller{
-			Model: &postModel{},
+			Model:         &postModel{},
+			GuardedFields: []string{"TextBody"},
+			Modifiers: L{
+				C("Concurrent", Modifier, Only(Update), func(ctx *Context) error {
+					if concurrent {
+						_, err := ctx.Store.M(&postModel{}).Update(ctx, nil, ctx.Model.ID(), bson.M{
+							"$set": bson.M{
+								"TextBody": "Computed",
+							},
+						}, false)
+						return err
+					}
+					return nil
+				}),
+			},
 		}, &Controller{
 			Model: &commentModel{},
 		}, &Controller{
@@ -5805,978 +8209,1215 @@ func TestDatabaseErrors(t *testing.T) {
 			Model: &noteModel{},
 		})
 
-		// missing resource
-		tester.Request("GET", "posts/"+coal.New().Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusNotFound, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "404",
-						"title": "not found",
-						"detail": "resource not found"
-					}
-				]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
-		})
-
-		// add unique index
-		index, err := tester.Store.C(&postModel{}).Native().Indexes().CreateOne(tester.Context, mongo.IndexModel{
-			Keys: bson.D{
-				{Key: "title", Value: int32(1)},
-			},
-			Options: options.Index().SetUnique(true),
-		})
-		assert.NoError(t, err)
+		post := tester.Insert(&postModel{
+			Title:    "Post 1",
+			TextBody: "Body",
+		}).(*postModel)
+		id := post.ID().Hex()
 
-		// first post
-		tester.Request("POST", "posts", `{
+		// update without concurrent modification
+		tester.Request("PATCH", "posts/"+id, `{
 			"data": {
 				"type": "posts",
+				"id": "`+id+`",
 				"attributes": {
-					"title": "Post 1"
+					"title": "Post 2"
 				}
 			}
 		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 		})
 
-		// second post
-		tester.Request("POST", "posts", `{
+		post = tester.Fetch(&postModel{}, post.ID()).(*postModel)
+		assert.Equal(t, "Post 2", post.Title)
+		assert.Equal(t, "Body", post.TextBody)
+
+		// update with concurrent modification
+		concurrent = true
+		tester.Request("PATCH", "posts/"+id, `{
 			"data": {
 				"type": "posts",
+				"id": "`+id+`",
 				"attributes": {
-					"title": "Post 1"
+					"title": "Post 3"
 				}
 			}
 		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, http.StatusConflict, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "400",
-						"title": "bad request",
-						"detail": "document not unique"
-					}
-				]
+				"errors": [{
+					"status": "409",
+					"title": "conflict",
+					"detail": "guarded fields have been modified concurrently"
+				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// remove index
-		_, err = tester.Store.C(&postModel{}).Native().Indexes().DropOne(tester.Context, index)
-		assert.NoError(t, err)
+		post = tester.Fetch(&postModel{}, post.ID()).(*postModel)
+		assert.Equal(t, "Post 2", post.Title)
 	})
 }
 
-func TestTolerateViolations(t *testing.T) {
+func TestTransactions(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
-		tester.Assign("", &Controller{
+		group := tester.Assign("", &Controller{
 			Model: &postModel{},
-			Authorizers: L{
-				C("TestWritableFields", Authorizer, All(), func(ctx *Context) error {
-					ctx.WritableFields = []string{"Title"}
+			Notifiers: L{
+				C("foo", Notifier, All(), func(ctx *Context) error {
+					if ctx.Model.(*postModel).Title == "FAIL" {
+						return xo.F("foo")
+					}
 					return nil
 				}),
 			},
-			TolerateViolations: []string{"Published"},
 		}, &Controller{
 			Model: &commentModel{},
 		}, &Controller{
 			Model: &selectionModel{},
-			Authorizers: L{
-				C("TestWritableFields", Authorizer, All(), func(ctx *Context) error {
-					ctx.WritableFields = []string{}
-					return nil
-				}),
-			},
-			TolerateViolations: []string{"Posts"},
 		}, &Controller{
 			Model: &noteModel{},
 		})
 
-		// attempt to create post with protected field
+		var errs []string
+		group.reporter = func(err error) {
+			errs = append(errs, err.Error())
+		}
+
+		var id string
+
+		// create post
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Post 1"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			post := tester.FindLast(&postModel{})
+			id = post.ID().Hex()
+
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": {
+					"type": "posts",
+					"id": "`+id+`",
+					"attributes": {
+						"title": "Post 1",
+						"published": false,
+						"text-body": ""
+					},
+					"relationships": {
+						"comments": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+id+`/relationships/comments",
+								"related": "/posts/`+id+`/comments"
+							}
+						},
+						"selections": {
+							"data": [],
+							"links": {
+								"self": "/posts/`+id+`/relationships/selections",
+								"related": "/posts/`+id+`/selections"
+							}
+						},
+						"note": {
+							"data": null,
+							"links": {
+								"self": "/posts/`+id+`/relationships/note",
+								"related": "/posts/`+id+`/note"
+							}
+						}
+					}
+				},
+				"links": {
+					"self": "/posts/`+id+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// create post error
 		tester.Request("POST", "posts", `{
 			"data": {
 				"type": "posts",
 				"attributes": {
-					"title": "Post 1",
-					"published": true
-				},
-				"relationships": {}
+					"title": "FAIL"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			post := tester.FindLast(&postModel{})
+			id = post.ID().Hex()
+
+			assert.Equal(t, http.StatusInternalServerError, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [
+					{
+						"status": "500",
+						"title": "internal server error"
+					}
+				]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// update post
+		tester.Request("PATCH", "posts/"+id, `{
+			"data": {
+				"type": "posts",
+				"id": "`+id+`",
+				"attributes": {
+					"text-body": "Post 1 Text"
+				}
 			}
 		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			post := tester.FindLast(&postModel{}).ID().Hex()
-
-			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
 				"data": {
 					"type": "posts",
-					"id": "`+post+`",
+					"id": "`+id+`",
 					"attributes": {
 						"title": "Post 1",
 						"published": false,
-						"text-body": ""
+						"text-body": "Post 1 Text"
 					},
 					"relationships": {
 						"comments": {
 							"data": [],
 							"links": {
-								"self": "/posts/`+post+`/relationships/comments",
-								"related": "/posts/`+post+`/comments"
+								"self": "/posts/`+id+`/relationships/comments",
+								"related": "/posts/`+id+`/comments"
 							}
 						},
-						"note": {
-							"data": null,
+						"selections": {
+							"data": [],
 							"links": {
-								"self": "/posts/`+post+`/relationships/note",
-								"related": "/posts/`+post+`/note"
+								"self": "/posts/`+id+`/relationships/selections",
+								"related": "/posts/`+id+`/selections"
 							}
 						},
-						"selections": {
-							"data": [],
+						"note": {
+							"data": null,
 							"links": {
-								"self": "/posts/`+post+`/relationships/selections",
-								"related": "/posts/`+post+`/selections"
+								"self": "/posts/`+id+`/relationships/note",
+								"related": "/posts/`+id+`/note"
 							}
 						}
 					}
 				},
 				"links": {
-					"self": "/posts/`+post+`"
+					"self": "/posts/`+id+`"
 				}
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// attempt to create selection with protected relationship
-		tester.Request("POST", "selections", `{
+		// update post
+		tester.Request("PATCH", "posts/"+id, `{
 			"data": {
-				"type": "selections",
-				"attributes": {},
-				"relationships": {
-					"posts": {
-						"data": [{
-							"type": "posts",
-							"id": "`+coal.New().Hex()+`"
-						}]
-					}
+				"type": "posts",
+				"id": "`+id+`",
+				"attributes": {
+					"title": "FAIL"
 				}
 			}
 		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			selection := tester.FindLast(&selectionModel{}).ID().Hex()
-
-			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, http.StatusInternalServerError, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"data": {
-					"type": "selections",
-					"id": "`+selection+`",
-					"attributes": {
-						"name": ""
-					},
-					"relationships": {
-						"posts": {
-							"data": [],
-							"links": {
-								"self": "/selections/`+selection+`/relationships/posts",
-								"related": "/selections/`+selection+`/posts"
-							}
-						}
+				"errors": [
+					{
+						"status": "500",
+						"title": "internal server error"
 					}
-				},
-				"links": {
-					"self": "/selections/`+selection+`"
-				}
+				]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
-	})
-}
-
-func TestOffsetPagination(t *testing.T) {
-	withTester(t, func(t *testing.T, tester *Tester) {
-		tester.Assign("", &Controller{
-			Model:     &postModel{},
-			ListLimit: 7,
-		}, &Controller{
-			Model:     &commentModel{},
-			ListLimit: 7,
-		}, &Controller{
-			Model:     &selectionModel{},
-			ListLimit: 7,
-		}, &Controller{
-			Model:     &noteModel{},
-			ListLimit: 7,
-		})
-
-		// prepare IDs
-		var ids []coal.ID
-
-		// create some posts
-		for i := 0; i < 10; i++ {
-			ids = append(ids, tester.Insert(&postModel{
-				Title: fmt.Sprintf("Post %d", i+1),
-			}).ID())
-		}
-
-		// get first page of posts
-		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[number]=1&page[size]=7",
-				"first": "/posts?page[number]=1&page[size]=7",
-				"last": "/posts?page[number]=2&page[size]=7",
-				"next": "/posts?page[number]=2&page[size]=7"
-			}`, linkUnescape(links))
-		})
-
-		// get first page of posts
-		tester.Request("GET", "posts?page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[number]=1&page[size]=5",
-				"first": "/posts?page[number]=1&page[size]=5",
-				"last": "/posts?page[number]=2&page[size]=5",
-				"next": "/posts?page[number]=2&page[size]=5"
-			}`, linkUnescape(links))
-		})
-
-		// get first page of posts
-		tester.Request("GET", "posts?page[number]=1&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[number]=1&page[size]=5",
-				"first": "/posts?page[number]=1&page[size]=5",
-				"last": "/posts?page[number]=2&page[size]=5",
-				"next": "/posts?page[number]=2&page[size]=5"
-			}`, linkUnescape(links))
-		})
-
-		// get second page of posts
-		tester.Request("GET", "posts?page[number]=2&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 6", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[number]=2&page[size]=5",
-				"first": "/posts?page[number]=1&page[size]=5",
-				"last": "/posts?page[number]=2&page[size]=5",
-				"prev": "/posts?page[number]=1&page[size]=5"
-			}`, linkUnescape(links))
-		})
-
-		// create selection
-		selection := tester.Insert(&selectionModel{
-			Posts: ids,
-		}).ID().Hex()
-
-		// get first page of posts
-		tester.Request("GET", "selections/"+selection+"/posts?page[number]=1&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/selections/`+selection+`/posts?page[number]=1&page[size]=5",
-				"first": "/selections/`+selection+`/posts?page[number]=1&page[size]=5",
-				"last": "/selections/`+selection+`/posts?page[number]=2&page[size]=5",
-				"next": "/selections/`+selection+`/posts?page[number]=2&page[size]=5"
-			}`, linkUnescape(links))
-		})
-
-		// get second page of posts
-		tester.Request("GET", "selections/"+selection+"/posts?page[number]=2&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 6", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/selections/`+selection+`/posts?page[number]=2&page[size]=5",
-				"first": "/selections/`+selection+`/posts?page[number]=1&page[size]=5",
-				"last": "/selections/`+selection+`/posts?page[number]=2&page[size]=5",
-				"prev": "/selections/`+selection+`/posts?page[number]=1&page[size]=5"
-			}`, linkUnescape(links))
-		})
-
-		// create post
-		post := tester.Insert(&postModel{
-			Title: "Post",
-		}).ID()
-
-		// create some comments
-		for i := 0; i < 10; i++ {
-			tester.Insert(&commentModel{
-				Message: fmt.Sprintf("Comment %d", i+1),
-				Post:    post,
-			})
-		}
-
-		// get first page of comments
-		tester.Request("GET", "posts/"+post.Hex()+"/comments?page[number]=1&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Comment 1", list[0].Get("attributes.message").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts/`+post.Hex()+`/comments?page[number]=1&page[size]=5",
-				"first": "/posts/`+post.Hex()+`/comments?page[number]=1&page[size]=5",
-				"last": "/posts/`+post.Hex()+`/comments?page[number]=2&page[size]=5",
-				"next": "/posts/`+post.Hex()+`/comments?page[number]=2&page[size]=5"
-			}`, linkUnescape(links))
-		})
-
-		// get second page of comments
-		tester.Request("GET", "posts/"+post.Hex()+"/comments?page[number]=2&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Comment 6", list[0].Get("attributes.message").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts/`+post.Hex()+`/comments?page[number]=2&page[size]=5",
-				"first": "/posts/`+post.Hex()+`/comments?page[number]=1&page[size]=5",
-				"last": "/posts/`+post.Hex()+`/comments?page[number]=2&page[size]=5",
-				"prev": "/posts/`+post.Hex()+`/comments?page[number]=1&page[size]=5"
-			}`, linkUnescape(links))
-		})
+		assert.Equal(t, 1, tester.Count(&postModel{}))
+		assert.Equal(t, "Post 1", stick.MustGet(tester.Fetch(&postModel{}, coal.MustFromHex(id)), "Title"))
+
+		assert.Equal(t, []string{"foo", "foo"}, errs)
 	})
 }
 
-func TestCursorPagination(t *testing.T) {
+func TestDeduplicateReads(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		tester.Assign("", &Controller{
 			Model:            &postModel{},
-			Filters:          []string{"Published"},
-			Sorters:          []string{"Title", "TextBody", "Published"},
-			ListLimit:        7,
-			CursorPagination: true,
+			DeduplicateReads: true,
+			Decorators: L{
+				C("TestDecorator", Decorator, Only(List), func(ctx *Context) error {
+					for _, model := range ctx.Models {
+						model.(*postModel).Title += "!"
+					}
+					return nil
+				}),
+			},
 		}, &Controller{
-			Model:            &commentModel{},
-			ListLimit:        7,
-			CursorPagination: true,
+			Model: &commentModel{},
 		}, &Controller{
-			Model:            &selectionModel{},
-			ListLimit:        7,
-			CursorPagination: true,
+			Model: &selectionModel{},
 		}, &Controller{
-			Model:            &noteModel{},
-			ListLimit:        7,
-			CursorPagination: true,
+			Model: &noteModel{},
 		})
 
-		// create some posts
+		post := tester.Insert(&postModel{
+			Title: "Hello",
+		}).(*postModel)
+
+		var wg sync.WaitGroup
 		for i := 0; i < 10; i++ {
-			tester.Insert(&postModel{
-				Base:      coal.B(numID(uint8(i) + 1)),
-				Title:     fmt.Sprintf("Post %02d", i+1),
-				TextBody:  fmt.Sprintf("Body %02d", 10-i),
-				Published: i >= 5,
-			})
+			wg.Add(2)
+
+			go func() {
+				defer wg.Done()
+				tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+					assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+					assert.Equal(t, "Hello!", gjson.Get(r.Body.String(), "data.0.attributes.title").String(), tester.DebugRequest(rq, r))
+				})
+			}()
+
+			go func() {
+				defer wg.Done()
+				tester.Request("GET", "posts/"+post.ID().Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+					assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+					assert.Equal(t, "Hello", gjson.Get(r.Body.String(), "data.attributes.title").String(), tester.DebugRequest(rq, r))
+				})
+			}()
 		}
 
-		/* ascending */
-
-		// get first page of posts
-		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		wg.Wait()
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 01", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 07", list[6].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[after]=*&page[size]=7",
-				"first": "/posts?page[after]=*&page[size]=7",
-				"prev": null,
-				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABwA&page[size]=7",
-				"last": "/posts?page[before]=*&page[size]=7"
-			}`, linkUnescape(links))
+		tester.Request("GET", "posts/"+coal.New().Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNotFound, r.Result().StatusCode, tester.DebugRequest(rq, r))
 		})
+	})
+}
 
-		// get first page of posts with size
-		tester.Request("GET", "posts?page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 01", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 05", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[after]=*&page[size]=5",
-				"first": "/posts?page[after]=*&page[size]=5",
-				"prev": null,
-				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5",
-				"last": "/posts?page[before]=*&page[size]=5"
-			}`, linkUnescape(links))
+func TestSideposting(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: sidepost relationship "title" is not a has-one or has-many relationship`, func() {
+			tester.Assign("", &Controller{
+				Model:                 &postModel{},
+				SidepostRelationships: []string{"title"},
+			})
 		})
 
-		// get last page of posts
-		tester.Request("GET", "posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		var parents []coal.Model
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 10", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5",
-				"first": "/posts?page[after]=*&page[size]=5",
-				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=5",
-				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAACgA&page[size]=5",
-				"last": "/posts?page[before]=*&page[size]=5"
-			}`, linkUnescape(links))
+		tester.Assign("", &Controller{
+			Model:                 &postModel{},
+			SidepostRelationships: []string{"comments", "note"},
+		}, &Controller{
+			Model: &commentModel{},
+			Validators: L{
+				C("TestSideposting", Validator, All(), func(ctx *Context) error {
+					parents = append(parents, ctx.Parent)
+					if ctx.Model.(*commentModel).Message == "error" {
+						return xo.SF("invalid message")
+					}
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+			Authorizers: L{
+				C("TestSideposting", Authorizer, All(), func(ctx *Context) error {
+					if ctx.Operation == Create && ctx.HTTPRequest.Header.Get("X-Readonly") != "" {
+						ctx.WritableFields = []string{"Title"}
+					}
+					return nil
+				}),
+			},
 		})
 
-		// get empty last page of posts
-		tester.Request("GET", "posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAACgA&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		// create post with comments and note
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Post"
+				}
+			},
+			"included": [
+				{
+					"type": "comments",
+					"attributes": {
+						"message": "Comment 1"
+					}
+				},
+				{
+					"type": "comments",
+					"attributes": {
+						"message": "Comment 2"
+					}
+				},
+				{
+					"type": "notes",
+					"attributes": {
+						"title": "Note"
+					}
+				}
+			]
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			post := tester.FindLast(&postModel{}).(*postModel)
+			comments := *tester.FindAll(&commentModel{}).(*[]*commentModel)
+			note := tester.FindLast(&noteModel{}).(*noteModel)
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 0, len(list), tester.DebugRequest(rq, r))
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "posts", gjson.Get(r.Body.String(), "data.type").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, post.ID().Hex(), gjson.Get(r.Body.String(), "data.id").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, int64(3), gjson.Get(r.Body.String(), "included.#").Int(), tester.DebugRequest(rq, r))
+			assert.Equal(t, []interface{}{"comments", "comments", "notes"}, gjson.Get(r.Body.String(), "included.#.type").Value(), tester.DebugRequest(rq, r))
+			assert.Equal(t, comments[0].ID().Hex(), gjson.Get(r.Body.String(), "included.0.id").String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, post.ID().Hex(), gjson.Get(r.Body.String(), "included.0.relationships.post.data.id").String(), tester.DebugRequest(rq, r))
+
+			assert.Len(t, comments, 2)
+			assert.Equal(t, "Comment 1", comments[0].Message)
+			assert.Equal(t, post.ID(), comments[0].Post)
+			assert.Equal(t, "Comment 2", comments[1].Message)
+			assert.Equal(t, post.ID(), comments[1].Post)
+			assert.Equal(t, "Note", note.Title)
+			assert.Equal(t, post.ID(), note.Post)
+			assert.Len(t, parents, 2)
+			assert.Equal(t, post.ID(), parents[0].ID())
+		})
+
+		// invalid included resources
+		for _, item := range []struct {
+			included string
+			detail   string
+			pointer  string
+		}{
+			{
+				included: `{"type": "selections"}`,
+				detail:   "invalid included resource type",
+				pointer:  "/included/0/type",
+			},
+			{
+				included: `{"type": "comments", "id": "` + coal.New().Hex() + `"}`,
+				detail:   "unnecessary resource ID",
+				pointer:  "/included/0/id",
+			},
+			{
+				included: `{"type": "comments", "relationships": {"post": {"data": {"type": "posts", "id": "` + coal.New().Hex() + `"}}}}`,
+				detail:   "inverse relationship must not be set",
+				pointer:  "/included/0/relationships/post",
+			},
+			{
+				included: `{"type": "notes"}, {"type": "notes"}`,
+				detail:   "multiple resources for has one relationship",
+				pointer:  "/included/1",
+			},
+		} {
+			tester.Request("POST", "posts", `{
+				"data": {
+					"type": "posts",
+					"attributes": {
+						"title": "Post"
+					}
+				},
+				"included": [`+item.included+`]
+			}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+				assert.JSONEq(t, `{
+					"errors": [{
+						"status": "400",
+						"title": "bad request",
+						"detail": "`+item.detail+`",
+						"source": {
+							"pointer": "`+item.pointer+`"
+						}
+					}]
+				}`, r.Body.String(), tester.DebugRequest(rq, r))
+			})
+		}
+
+		// failing callback
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Post"
+				}
+			},
+			"included": [
+				{
+					"type": "comments",
+					"attributes": {
+						"message": "error"
+					}
+				}
+			]
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAACgA&page[size]=5",
-				"first": "/posts?page[after]=*&page[size]=5",
-				"prev": "/posts?page[before]=*&page[size]=5",
-				"next": null,
-				"last": "/posts?page[before]=*&page[size]=5"
-			}`, linkUnescape(links))
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid message"
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// get capped page of posts
-		tester.Request("GET", "posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=6", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 10", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+		// non-writable inverse relationship
+		tester.Header["X-Readonly"] = "1"
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Post"
+				}
+			},
+			"included": [
+				{
+					"type": "notes",
+					"attributes": {
+						"title": "Note"
+					}
+				}
+			]
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=6",
-				"first": "/posts?page[after]=*&page[size]=6",
-				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=6",
-				"next": null,
-				"last": "/posts?page[before]=*&page[size]=6"
-			}`, linkUnescape(links))
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "field is not writable",
+					"source": {
+						"pointer": "Post"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
+		delete(tester.Header, "X-Readonly")
 
-		/* descending */
-
-		// get last page of posts (reverse)
-		tester.Request("GET", "posts?page[before]=*&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		// nothing has been created by failed requests
+		assert.Equal(t, 1, tester.Count(&postModel{}))
+		assert.Equal(t, 2, tester.Count(&commentModel{}))
+		assert.Equal(t, 1, tester.Count(&noteModel{}))
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 10", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+		// unsupported included resources
+		tester.Request("POST", "comments", `{
+			"data": {
+				"type": "comments",
+				"attributes": {
+					"message": "Comment"
+				},
+				"relationships": {
+					"post": {
+						"data": {
+							"type": "posts",
+							"id": "`+coal.New().Hex()+`"
+						}
+					}
+				}
+			},
+			"included": [
+				{
+					"type": "comments"
+				}
+			]
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?page[before]=*&page[size]=5",
-				"first": "/posts?page[after]=*&page[size]=5",
-				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=5",
-				"next": null,
-				"last": "/posts?page[before]=*&page[size]=5"
-			}`, linkUnescape(links))
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "included resources are not supported",
+					"source": {
+						"pointer": "/included"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
+	})
+}
 
-		// get middle page of posts (reverse)
-		tester.Request("GET", "posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+func TestPreferences(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var prefs []coal.Preference
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 3, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 03", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 05", list[2].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=3",
-				"first": "/posts?page[after]=*&page[size]=3",
-				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAAAwA&page[size]=3",
-				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=3",
-				"last": "/posts?page[before]=*&page[size]=3"
-			}`, linkUnescape(links))
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+			ReadPreferences: map[Operation]*readpref.ReadPref{
+				List: readpref.SecondaryPreferred(),
+			},
+			WriteConcerns: map[Operation]*writeconcern.WriteConcern{
+				Delete: writeconcern.Majority(),
+			},
+			Authorizers: L{
+				C("TestPreferences", Authorizer, All(), func(ctx *Context) error {
+					prefs = append(prefs, coal.GetPreference(ctx))
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
 		})
 
-		// get first page of posts (reverse)
-		tester.Request("GET", "posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		post := tester.Insert(&postModel{
+			Title: "Post",
+		}).ID().Hex()
 
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 01", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 05", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=5",
-				"first": "/posts?page[after]=*&page[size]=5",
-				"prev": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAAAQA&page[size]=5",
-				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5",
-				"last": "/posts?page[before]=*&page[size]=5"
-			}`, linkUnescape(links))
+			assert.Equal(t, int64(1), gjson.Get(r.Body.String(), "data.#").Int(), tester.DebugRequest(rq, r))
 		})
 
-		// get empty first page of posts (reverse)
-		tester.Request("GET", "posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAAAQA&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
+		tester.Request("GET", "posts/"+post, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 0, len(list), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAAAQA&page[size]=5",
-				"first": "/posts?page[after]=*&page[size]=5",
-				"prev": null,
-				"next": "/posts?page[after]=*&page[size]=5",
-				"last": "/posts?page[before]=*&page[size]=5"
-			}`, linkUnescape(links))
 		})
 
-		// get capped first page of posts (reverse)
-		tester.Request("GET", "posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=6", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
-
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 01", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 05", list[4].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=6",
-				"first": "/posts?page[after]=*&page[size]=6",
-				"prev": null,
-				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=6",
-				"last": "/posts?page[before]=*&page[size]=6"
-			}`, linkUnescape(links))
+		tester.Request("DELETE", "posts/"+post, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNoContent, r.Result().StatusCode, tester.DebugRequest(rq, r))
 		})
 
-		/* sorted */
+		assert.Equal(t, []coal.Preference{
+			{ReadPreference: readpref.SecondaryPreferred()},
+			{},
+			{WriteConcern: writeconcern.Majority()},
+		}, prefs)
+	})
+}
 
-		// get first page of sorted posts
-		tester.Request("GET", "posts?sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+func TestCallbackBudget(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var called bool
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 10", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 04", list[6].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[after]=*&page[size]=7&sort=-title",
-				"first": "/posts?page[after]=*&page[size]=7&sort=-title",
-				"prev": null,
-				"next": "/posts?page[after]=IwAAAAIwAAgAAABQb3N0IDA0AAcxAAAAAAAAAAAAAAAABAA&page[size]=7&sort=-title",
-				"last": "/posts?page[before]=*&page[size]=7&sort=-title"
-			}`, linkUnescape(links))
+		tester.Assign("", &Controller{
+			Model:       &postModel{},
+			ReadTimeout: 10 * time.Millisecond,
+			Decorators: L{
+				C("Slow", Decorator, All(), func(ctx *Context) error {
+					time.Sleep(20 * time.Millisecond)
+					return nil
+				}),
+				C("Next", Decorator, All(), func(ctx *Context) error {
+					called = true
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
 		})
 
-		// get last page of sorted posts
-		tester.Request("GET", "posts?page[after]=IwAAAAIwAAgAAABQb3N0IDA0AAcxAAAAAAAAAAAAAAAABAA&page[size]=7&sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		tester.Insert(&postModel{
+			Title: "Post",
+		})
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 3, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 03", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 01", list[2].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusServiceUnavailable, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?page[after]=IwAAAAIwAAgAAABQb3N0IDA0AAcxAAAAAAAAAAAAAAAABAA&page[size]=7&sort=-title",
-				"first": "/posts?page[after]=*&page[size]=7&sort=-title",
-				"prev": "/posts?page[before]=IwAAAAIwAAgAAABQb3N0IDAzAAcxAAAAAAAAAAAAAAAAAwA&page[size]=7&sort=-title",
-				"next": null,
-				"last": "/posts?page[before]=*&page[size]=7&sort=-title"
-			}`, linkUnescape(links))
+				"errors": [{
+					"status": "503",
+					"title": "service unavailable",
+					"detail": "request budget exhausted"
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// get first page of sorted posts (reverse)
-		tester.Request("GET", "posts?page[before]=IwAAAAIwAAgAAABQb3N0IDAzAAcxAAAAAAAAAAAAAAAAAwA&page[size]=7&sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		assert.False(t, called)
+	})
+}
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 10", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 04", list[6].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[before]=IwAAAAIwAAgAAABQb3N0IDAzAAcxAAAAAAAAAAAAAAAAAwA&page[size]=7&sort=-title",
-				"first": "/posts?page[after]=*&page[size]=7&sort=-title",
-				"prev": "/posts?page[before]=IwAAAAIwAAgAAABQb3N0IDEwAAcxAAAAAAAAAAAAAAAACgA&page[size]=7&sort=-title",
-				"next": "/posts?page[after]=IwAAAAIwAAgAAABQb3N0IDA0AAcxAAAAAAAAAAAAAAAABAA&page[size]=7&sort=-title",
-				"last": "/posts?page[before]=*&page[size]=7&sort=-title"
-			}`, linkUnescape(links))
+func TestDenyFiltered(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:        &postModel{},
+			DenyFiltered: true,
+			Authorizers: L{
+				C("TestDenyFiltered", Authorizer, All(), func(ctx *Context) error {
+					ctx.Filters = append(ctx.Filters, bson.M{
+						"Published": true,
+					})
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
 		})
 
-		/* filtered */
-
-		// get first page of filtered posts
-		tester.Request("GET", "posts?filter[published]=true&page[size]=3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		hidden := tester.Insert(&postModel{
+			Title: "Hidden",
+		}).ID().Hex()
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 3, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 08", list[2].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?filter[published]=true&page[after]=*&page[size]=3",
-				"first": "/posts?filter[published]=true&page[after]=*&page[size]=3",
-				"prev": null,
-				"next": "/posts?filter[published]=true&page[after]=FAAAAAcwAAAAAAAAAAAAAAAACAA&page[size]=3",
-				"last": "/posts?filter[published]=true&page[before]=*&page[size]=3"
-			}`, linkUnescape(links))
-		})
+		visible := tester.Insert(&postModel{
+			Title:     "Visible",
+			Published: true,
+		}).ID().Hex()
 
-		// get last page of filtered posts
-		tester.Request("GET", "posts?filter[published]=true&page[after]=FAAAAAcwAAAAAAAAAAAAAAAACAA&page[size]=3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		missing := coal.New().Hex()
 
+		tester.Request("GET", "posts/"+visible, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 2, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 09", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 10", list[1].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?filter[published]=true&page[after]=FAAAAAcwAAAAAAAAAAAAAAAACAA&page[size]=3",
-				"first": "/posts?filter[published]=true&page[after]=*&page[size]=3",
-				"prev": "/posts?filter[published]=true&page[before]=FAAAAAcwAAAAAAAAAAAAAAAACQA&page[size]=3",
-				"next": null,
-				"last": "/posts?filter[published]=true&page[before]=*&page[size]=3"
-			}`, linkUnescape(links))
 		})
 
-		// get first page of filtered posts (reverse)
-		tester.Request("GET", "posts?filter[published]=true&page[before]=FAAAAAcwAAAAAAAAAAAAAAAACQA&page[size]=3", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		for _, item := range []struct {
+			method string
+			path   string
+			body   string
+		}{
+			{method: "GET", path: "posts/%s"},
+			{method: "PATCH", path: "posts/%s", body: `{"data": {"type": "posts", "id": "%s", "attributes": {"title": "Foo"}}}`},
+			{method: "DELETE", path: "posts/%s"},
+			{method: "GET", path: "posts/%s/comments"},
+			{method: "GET", path: "posts/%s/relationships/note"},
+		} {
+			body := item.body
+			if body != "" {
+				body = fmt.Sprintf(body, hidden)
+			}
+			tester.Request(item.method, fmt.Sprintf(item.path, hidden), body, func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusForbidden, r.Result().StatusCode, tester.DebugRequest(rq, r))
+				assert.JSONEq(t, `{
+					"errors": [{
+						"status": "403",
+						"title": "forbidden",
+						"detail": "access forbidden"
+					}]
+				}`, r.Body.String(), tester.DebugRequest(rq, r))
+			})
 
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 3, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 06", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 08", list[2].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?filter[published]=true&page[before]=FAAAAAcwAAAAAAAAAAAAAAAACQA&page[size]=3",
-				"first": "/posts?filter[published]=true&page[after]=*&page[size]=3",
-				"prev": "/posts?filter[published]=true&page[before]=FAAAAAcwAAAAAAAAAAAAAAAABgA&page[size]=3",
-				"next": "/posts?filter[published]=true&page[after]=FAAAAAcwAAAAAAAAAAAAAAAACAA&page[size]=3",
-				"last": "/posts?filter[published]=true&page[before]=*&page[size]=3"
-			}`, linkUnescape(links))
+			body = item.body
+			if body != "" {
+				body = fmt.Sprintf(body, missing)
+			}
+			tester.Request(item.method, fmt.Sprintf(item.path, missing), body, func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusNotFound, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			})
+		}
+	})
+}
+
+func TestBulkDelete(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var validated []string
+		tester.Assign("", &Controller{
+			Model:      &postModel{},
+			Filters:    []string{"Title"},
+			BulkDelete: true,
+			Authorizers: L{
+				C("TestBulkDelete", Authorizer, All(), func(ctx *Context) error {
+					ctx.Filters = append(ctx.Filters, bson.M{
+						"Published": true,
+					})
+					return nil
+				}),
+			},
+			Validators: L{
+				C("TestBulkDelete", Validator, Only(Delete), func(ctx *Context) error {
+					post := ctx.Model.(*postModel)
+					if post.TextBody == "protected" {
+						return xo.SF("protected post")
+					}
+					validated = append(validated, post.Title)
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
 		})
 
-		// TODO: Test relationship pagination.
+		tester.Insert(&postModel{
+			Title:     "A",
+			Published: true,
+		})
+		tester.Insert(&postModel{
+			Title: "A",
+		})
+		tester.Insert(&postModel{
+			Title:     "B",
+			Published: true,
+		})
+		tester.Insert(&postModel{
+			Title:     "C",
+			Published: true,
+			TextBody:  "protected",
+		})
 
-		// try range pagination
-		tester.Request("GET", "posts?page[after]=X&page[before]=X", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		tester.Request("DELETE", "posts/delete-many", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "range pagination not supported"
+					"detail": "missing filter"
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// try invalid cursor sorting combination
-		tester.Request("GET", "posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABQA&page[size]=5&sort=-title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		tester.Request("DELETE", "posts/delete-many?filter[title]=A", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"meta": {
+					"deleted": 1
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		assert.Equal(t, []string{"A"}, validated)
+		assert.Equal(t, 3, tester.Count(&postModel{}))
+
+		tester.Request("DELETE", "posts/delete-many?filter[title]=B,C", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "cursor sorting mismatch"
+					"detail": "protected post"
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
+
+		assert.Equal(t, 3, tester.Count(&postModel{}))
 	})
 }
 
-func TestPaginationSelection(t *testing.T) {
+func TestBulkDeleteInBulk(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
+		var calls int
 		tester.Assign("", &Controller{
-			Model:     &postModel{},
-			ListLimit: 7,
+			Model:          &postModel{},
+			Filters:        []string{"Published"},
+			SoftDelete:     true,
+			BulkDelete:     true,
+			BulkDeleteMode: InBulk,
+			Validators: L{
+				C("TestBulkDeleteInBulk", Validator, Only(Delete), func(ctx *Context) error {
+					assert.Nil(t, ctx.Model)
+					assert.Nil(t, ctx.Models)
+					calls++
+					return nil
+				}),
+			},
 		}, &Controller{
-			Model:            &commentModel{},
-			ListLimit:        7,
-			CursorPagination: true,
+			Model: &commentModel{},
 		}, &Controller{
-			Model:     &selectionModel{},
-			ListLimit: 7,
+			Model: &selectionModel{},
 		}, &Controller{
-			Model:     &noteModel{},
-			ListLimit: 7,
+			Model: &noteModel{},
 		})
 
-		// create some posts
-		for i := 0; i < 10; i++ {
-			tester.Insert(&postModel{
-				Base:  coal.B(numID(uint8(i) + 1)),
-				Title: fmt.Sprintf("Post %d", i+1),
-			})
-		}
-
-		// get first page of posts with default pagination
-		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		tester.Insert(&postModel{
+			Title:     "A",
+			Published: true,
+		})
+		tester.Insert(&postModel{
+			Title:     "B",
+			Published: true,
+		})
+		tester.Insert(&postModel{
+			Title: "C",
+		})
 
+		tester.Request("DELETE", "posts/delete-many?filter[published]=true", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?page[number]=1&page[size]=7",
-				"first": "/posts?page[number]=1&page[size]=7",
-				"last": "/posts?page[number]=2&page[size]=7",
-				"next": "/posts?page[number]=2&page[size]=7"
-			}`, linkUnescape(links))
+				"meta": {
+					"deleted": 2
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// get first page of posts using offset pagination
-		tester.Request("GET", "posts?pagination=offset", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, 3, tester.Count(&postModel{}))
 
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"self": "/posts?page[number]=1&page[size]=7&pagination=offset",
-				"first": "/posts?page[number]=1&page[size]=7&pagination=offset",
-				"last": "/posts?page[number]=2&page[size]=7&pagination=offset",
-				"next": "/posts?page[number]=2&page[size]=7&pagination=offset"
-			}`, linkUnescape(links))
+			assert.Equal(t, "C", gjson.Get(r.Body.String(), "data.0.attributes.title").String())
+			assert.Equal(t, int64(1), gjson.Get(r.Body.String(), "data.#").Int())
 		})
+	})
+}
 
-		// get first page of posts using cursor pagination
-		tester.Request("GET", "posts?pagination=cursor", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+func TestCounting(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:    &postModel{},
+			Filters:  []string{"Title"},
+			Counting: true,
+			Authorizers: L{
+				C("TestCounting", Authorizer, Only(List), func(ctx *Context) error {
+					ctx.Filters = append(ctx.Filters, bson.M{
+						"Published": true,
+					})
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		tester.Insert(&postModel{
+			Title:     "A",
+			Published: true,
+		})
+		tester.Insert(&postModel{
+			Title: "A",
+		})
+		tester.Insert(&postModel{
+			Title:     "B",
+			Published: true,
+		})
 
+		tester.Request("GET", "posts/count", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?page[after]=*&page[size]=7&pagination=cursor",
-				"first": "/posts?page[after]=*&page[size]=7&pagination=cursor",
-				"prev": null,
-				"last": "/posts?page[before]=*&page[size]=7&pagination=cursor",
-				"next": "/posts?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABwA&page[size]=7&pagination=cursor"
-			}`, linkUnescape(links))
+				"meta": {
+					"count": 2
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// get first page of posts using unknown pagination
-		tester.Request("GET", "posts?pagination=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		tester.Request("GET", "posts/count?filter[title]=A", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"meta": {
+					"count": 1
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		tester.Request("GET", "posts/count?filter[text-body]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "unknown pagination",
-					"source": {
-						"parameter": "pagination"
-					}
+					"detail": "invalid filter \"text-body\""
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
+	})
+}
+
+func TestComputed(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: computed attribute "title" conflicts with existing field`, func() {
+			tester.Assign("", &Controller{
+				Model: &postModel{},
+				Computed: map[string]ComputeHandler{
+					"title": func(*Context, coal.Model) (interface{}, error) {
+						return nil, nil
+					},
+				},
+			})
+		})
+
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+			Computed: map[string]ComputeHandler{
+				"owned": func(ctx *Context, model coal.Model) (interface{}, error) {
+					return model.(*postModel).TextBody == ctx.Data["user"], nil
+				},
+			},
+			Authorizers: L{
+				C("TestComputed", Authorizer, All(), func(ctx *Context) error {
+					ctx.Data["user"] = ctx.HTTPRequest.Header.Get("User")
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
 
-		// create post
 		post := tester.Insert(&postModel{
-			Title: "Post",
-		}).ID()
+			Title:    "post",
+			TextBody: "alice",
+		}).ID().Hex()
 
-		// create some comments
-		for i := 0; i < 10; i++ {
-			tester.Insert(&commentModel{
-				Base:    coal.B(numID(uint8(i) + 1)),
-				Message: fmt.Sprintf("Comment %d", i+1),
-				Post:    post,
-			})
-		}
+		tester.Header["User"] = "alice"
+		tester.Request("GET", "posts/"+post, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.True(t, gjson.Get(r.Body.String(), "data.attributes.owned").Bool())
+		})
 
-		// get first page of comments with default pagination
-		tester.Request("GET", "posts/"+post.Hex()+"/comments", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		tester.Header["User"] = "bob"
+		tester.Request("GET", "posts?fields[posts]=title,owned", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"title": "post",
+				"owned": false
+			}`, gjson.Get(r.Body.String(), "data.0.attributes").Raw, tester.DebugRequest(rq, r))
+		})
 
+		tester.Request("GET", "posts?fields[posts]=title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Comment 1", list[0].Get("attributes.message").String(), tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts/`+post.Hex()+`/comments?page[after]=*&page[size]=7",
-				"first": "/posts/`+post.Hex()+`/comments?page[after]=*&page[size]=7",
-				"prev": null,
-				"last": "/posts/`+post.Hex()+`/comments?page[before]=*&page[size]=7",
-				"next": "/posts/`+post.Hex()+`/comments?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABwA&page[size]=7"
-			}`, linkUnescape(links))
+				"title": "post"
+			}`, gjson.Get(r.Body.String(), "data.0.attributes").Raw, tester.DebugRequest(rq, r))
 		})
 
-		// get first page of comments with cursor pagination
-		tester.Request("GET", "posts/"+post.Hex()+"/comments?pagination=cursor", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		tester.Request("PATCH", "posts/"+post, `{
+			"data": {
+				"type": "posts",
+				"id": "`+post+`",
+				"attributes": {
+					"title": "updated",
+					"owned": true
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "updated", gjson.Get(r.Body.String(), "data.attributes.title").String())
+			assert.False(t, gjson.Get(r.Body.String(), "data.attributes.owned").Bool())
+		})
+	})
+}
+
+func TestVirtualFields(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: computed attribute "full-name" conflicts with virtual field`, func() {
+			tester.Assign("", &Controller{
+				Model: &profileModel{},
+				Computed: map[string]ComputeHandler{
+					"full-name": func(*Context, coal.Model) (interface{}, error) {
+						return nil, nil
+					},
+				},
+			})
+		})
+
+		tester.Assign("", &Controller{
+			Model: &profileModel{},
+		})
+
+		profile := tester.Insert(&profileModel{
+			FirstName: "Jane",
+			LastName:  "Doe",
+		}).ID().Hex()
 
+		tester.Request("GET", "profiles/"+profile, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 7, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Comment 1", list[0].Get("attributes.message").String(), tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts/`+post.Hex()+`/comments?page[after]=*&page[size]=7&pagination=cursor",
-				"first": "/posts/`+post.Hex()+`/comments?page[after]=*&page[size]=7&pagination=cursor",
-				"prev": null,
-				"last": "/posts/`+post.Hex()+`/comments?page[before]=*&page[size]=7&pagination=cursor",
-				"next": "/posts/`+post.Hex()+`/comments?page[after]=FAAAAAcwAAAAAAAAAAAAAAAABwA&page[size]=7&pagination=cursor"
-			}`, linkUnescape(links))
+				"first-name": "Jane",
+				"last-name": "Doe",
+				"full-name": "Jane Doe"
+			}`, gjson.Get(r.Body.String(), "data.attributes").Raw, tester.DebugRequest(rq, r))
 		})
 
-		// get first page of comments with offset pagination
-		tester.Request("GET", "posts/"+post.Hex()+"/comments?pagination=offset", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		tester.Request("GET", "profiles?fields[profiles]=first-name", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors": [{
-					"status": "400",
-					"title": "bad request",
-					"detail": "unsupported pagination",
-					"source": {
-						"parameter": "pagination"
-					}
-				}]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+				"first-name": "Jane"
+			}`, gjson.Get(r.Body.String(), "data.0.attributes").Raw, tester.DebugRequest(rq, r))
+		})
+
+		tester.Request("PATCH", "profiles/"+profile, `{
+			"data": {
+				"type": "profiles",
+				"id": "`+profile+`",
+				"attributes": {
+					"first-name": "John",
+					"full-name": "Foo Bar"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "John Doe", gjson.Get(r.Body.String(), "data.attributes.full-name").String())
 		})
 	})
 }
 
-func TestListLimit(t *testing.T) {
+func TestStrictQuery(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
-		tester.Assign("", &Controller{
-			Model:     &postModel{},
-			ListLimit: 5,
+		group := tester.Assign("", &Controller{
+			Model:           &postModel{},
+			Filters:         []string{"Title"},
+			Sorters:         []string{"Title"},
+			StrictQuery:     true,
+			QueryParameters: []string{"debug"},
 		}, &Controller{
-			Model: &commentModel{},
+			Model:   &commentModel{},
+			Sorters: []string{"Message"},
+			Includes: map[string]func(*Context) bool{
+				"post": nil,
+			},
+			StrictQuery: true,
 		}, &Controller{
 			Model: &selectionModel{},
 		}, &Controller{
 			Model: &noteModel{},
 		})
+		group.Localize(&Locale{})
+
+		post := tester.Insert(&postModel{
+			Title: "post",
+		}).ID().Hex()
+
+		tester.Insert(&commentModel{
+			Message: "comment",
+			Post:    coal.MustFromHex(post),
+		})
 
-		// create some posts
-		for i := 0; i < 10; i++ {
-			tester.Insert(&postModel{
-				Title: fmt.Sprintf("Post %d", i+1),
-			})
-		}
-
-		// get first page of posts
-		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			list := gjson.Get(r.Body.String(), "data").Array()
-			links := gjson.Get(r.Body.String(), "links").Raw
+		tester.Request("GET", "posts?filter[title]=post&sort=title&page[number]=1&page[size]=5&fields[posts]=title&include=comments&debug=1", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
 
+		tester.Request("GET", "posts/"+post+"?fields[posts]=title", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, 5, len(list), tester.DebugRequest(rq, r))
-			assert.Equal(t, "Post 1", list[0].Get("attributes.title").String(), tester.DebugRequest(rq, r))
+		})
+
+		tester.Request("GET", "posts?filters[title]=post", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"self": "/posts?page[number]=1&page[size]=5",
-				"first": "/posts?page[number]=1&page[size]=5",
-				"last": "/posts?page[number]=2&page[size]=5",
-				"next": "/posts?page[number]=2&page[size]=5"
-			}`, linkUnescape(links))
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "unknown query parameter \"filters[title]\"",
+					"source": {
+						"parameter": "filters[title]"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// try bigger page size than limit
-		tester.Request("GET", "posts?page[size]=7", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		tester.Request("GET", "posts/"+post+"?page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
 				"errors": [{
 					"status": "400",
 					"title": "bad request",
-					"detail": "max page size exceeded",
+					"detail": "query parameter \"page[size]\" is not supported by Find operations",
 					"source": {
 						"parameter": "page[size]"
 					}
 				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
-	})
-}
 
-func TestCollectionActions(t *testing.T) {
-	withTester(t, func(t *testing.T, tester *Tester) {
-		assert.PanicsWithValue(t, `fire: invalid collection action ""`, func() {
-			tester.Assign("api", &Controller{
-				Model: &postModel{},
-				CollectionActions: M{
-					"": A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
-						return nil
-					}),
-				},
-			})
+		tester.Request("GET", "comments?sort=message&page[number]=1&page[size]=5&include=post", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, int64(1), gjson.Get(r.Body.String(), "included.#").Int(), tester.DebugRequest(rq, r))
 		})
 
-		id := coal.New().Hex()
-		assert.PanicsWithValue(t, `fire: invalid collection action "`+id+`"`, func() {
-			tester.Assign("api", &Controller{
-				Model: &postModel{},
-				CollectionActions: M{
-					id: A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
-						return nil
-					}),
-				},
-			})
+		tester.Request("GET", "posts/"+post+"/comments?sort=message&page[number]=1&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, int64(1), gjson.Get(r.Body.String(), "data.#").Int(), tester.DebugRequest(rq, r))
 		})
 
-		tester.Assign("api", &Controller{
-			Model: &postModel{},
-			CollectionActions: M{
-				"bytes": A("bytes", []string{"POST"}, 0, 0, func(ctx *Context) error {
-					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
-					assert.NoError(t, err)
-					assert.Equal(t, []byte("PAYLOAD"), bytes)
+		tester.Request("GET", "posts/"+post+"/relationships/comments?page[number]=1&page[size]=5", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
 
-					_, err = ctx.ResponseWriter.Write([]byte("RESPONSE"))
-					assert.NoError(t, err)
+		tester.Request("GET", "posts/"+post+"?access_token=foo&tz=Europe/Zurich", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
 
-					return nil
-				}),
-				"empty": A("empty", []string{"POST"}, 0, 0, func(ctx *Context) error {
-					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
-					assert.NoError(t, err)
-					assert.Empty(t, bytes)
+		tester.Request("GET", "comments?serach=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+	})
+}
 
-					return nil
-				}),
-				"error": A("error", []string{"POST"}, 3, 0, func(ctx *Context) error {
-					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
-					assert.Error(t, err)
-					assert.Equal(t, []byte{}, bytes)
+func TestRoutedModel(t *testing.T) {
+	store := coal.MustOpen(nil, "test-fire-main", xo.Crash)
+	other := coal.MustOpen(nil, "test-fire-other", xo.Crash)
+	store.Route(other, "routed", &fooModel{})
 
-					ctx.ResponseWriter.WriteHeader(http.StatusRequestEntityTooLarge)
+	tester := NewTester(store, modelList...)
+	tester.Clean()
 
-					return nil
-				}),
+	tester.Assign("", &Controller{
+		Model: &fooModel{},
+	}, &Controller{
+		Model: &barModel{},
+	})
+
+	foo := coal.New().Hex()
+	bar := coal.New().Hex()
+
+	var id string
+	tester.Request("POST", "foos", `{
+		"data": {
+			"type": "foos",
+			"attributes": {
+				"string": "foo"
 			},
+			"relationships": {
+				"foo": {
+					"data": {
+						"type": "foos",
+						"id": "`+foo+`"
+					}
+				},
+				"bar": {
+					"data": {
+						"type": "bars",
+						"id": "`+bar+`"
+					}
+				}
+			}
+		}
+	}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+		assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		id = gjson.Get(r.Body.String(), "data.id").String()
+	})
+
+	n, err := other.Client().Database("routed").Collection("foos").CountDocuments(nil, bson.M{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = store.DB().Collection("foos").CountDocuments(nil, bson.M{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	tester.Request("GET", "foos", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		assert.Equal(t, int64(1), gjson.Get(r.Body.String(), "data.#").Int(), tester.DebugRequest(rq, r))
+	})
+
+	tester.Request("PATCH", "foos/"+id, `{
+		"data": {
+			"type": "foos",
+			"id": "`+id+`",
+			"attributes": {
+				"string": "bar"
+			}
+		}
+	}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+		assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		assert.Equal(t, "bar", gjson.Get(r.Body.String(), "data.attributes.string").String(), tester.DebugRequest(rq, r))
+	})
+
+	tester.Request("DELETE", "foos/"+id, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		assert.Equal(t, http.StatusNoContent, r.Result().StatusCode, tester.DebugRequest(rq, r))
+	})
+
+	n, err = other.Client().Database("routed").Collection("foos").CountDocuments(nil, bson.M{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestDocumentTooLarge(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model: &postModel{},
 		}, &Controller{
 			Model: &commentModel{},
 		}, &Controller{
@@ -6785,102 +9426,156 @@ func TestCollectionActions(t *testing.T) {
 			Model: &noteModel{},
 		})
 
-		// get byte response
-		tester.Header["Content-Type"] = "text/plain"
-		tester.Header["Accept"] = "text/plain"
-		tester.Request("POST", "posts/bytes", "PAYLOAD", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, "text/plain; charset=utf-8", r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
-			assert.Equal(t, "RESPONSE", r.Body.String(), tester.DebugRequest(rq, r))
-		})
+		tester.Store.MaxDocumentSize = 256
+		defer func() {
+			tester.Store.MaxDocumentSize = 0
+		}()
 
-		// get empty response
-		tester.Header["Content-Type"] = ""
-		tester.Header["Accept"] = ""
-		tester.Request("POST", "posts/empty", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Empty(t, r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
-			assert.Empty(t, r.Body.String(), tester.DebugRequest(rq, r))
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "`+strings.Repeat("x", 512)+`"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusRequestEntityTooLarge, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "413",
+					"title": "request entity too large",
+					"detail": "document too large"
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// error
-		tester.Request("POST", "posts/error", "PAYLOAD", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		assert.Equal(t, 0, tester.Count(&postModel{}))
+
+		tester.Store.MaxDocumentSize = 0
+		post1 := tester.Insert(&postModel{
+			Title: "Post 1",
+		}).ID()
+		post2 := tester.Insert(&postModel{
+			Title: "Post 2",
+		}).ID()
+		selection := tester.Insert(&selectionModel{
+			Name:  "Selection 1",
+			Posts: []coal.ID{post1},
+		}).ID()
+		tester.Store.MaxDocumentSize = 256
+
+		tester.Request("PATCH", "selections/"+selection.Hex(), `{
+			"data": {
+				"type": "selections",
+				"id": "`+selection.Hex()+`",
+				"attributes": {
+					"name": "`+strings.Repeat("x", 512)+`"
+				},
+				"relationships": {
+					"posts": {
+						"data": [
+							{
+								"type": "posts",
+								"id": "`+post2.Hex()+`"
+							}
+						],
+						"meta": {
+							"operation": "add"
+						}
+					}
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusRequestEntityTooLarge, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Empty(t, r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
-			assert.Empty(t, r.Body.String(), tester.DebugRequest(rq, r))
 		})
+
+		model := tester.Fetch(&selectionModel{}, selection).(*selectionModel)
+		assert.Equal(t, "Selection 1", model.Name)
+		assert.Equal(t, []coal.ID{post1}, model.Posts)
 	})
 }
 
-func TestResourceActions(t *testing.T) {
+func TestLongPolling(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
-		assert.PanicsWithValue(t, `fire: invalid resource action ""`, func() {
-			tester.Assign("api", &Controller{
-				Model: &postModel{},
-				ResourceActions: M{
-					"": A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
-						return nil
-					}),
-				},
-			})
+		tester.Assign("", &Controller{
+			Model:     &postModel{},
+			Filters:   []string{"Title"},
+			WaitLimit: 5 * time.Second,
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
 		})
 
-		assert.PanicsWithValue(t, `fire: invalid resource action "relationships"`, func() {
-			tester.Assign("api", &Controller{
-				Model: &postModel{},
-				ResourceActions: M{
-					"relationships": A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
-						return nil
-					}),
-				},
+		for _, item := range []struct {
+			param  string
+			detail string
+		}{
+			{param: "foo", detail: "invalid wait duration"},
+			{param: "-1s", detail: "invalid wait duration"},
+			{param: "10s", detail: "max wait duration exceeded"},
+		} {
+			tester.Request("GET", "posts?wait="+item.param, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+				assert.JSONEq(t, `{
+					"errors": [{
+						"status": "400",
+						"title": "bad request",
+						"detail": "`+item.detail+`",
+						"source": {
+							"parameter": "wait"
+						}
+					}]
+				}`, r.Body.String(), tester.DebugRequest(rq, r))
 			})
-		})
+		}
 
-		assert.PanicsWithValue(t, `fire: invalid resource action "note"`, func() {
-			tester.Assign("api", &Controller{
-				Model: &postModel{},
-				ResourceActions: M{
-					"note": A("foo", []string{"POST"}, 0, 0, func(ctx *Context) error {
-						return nil
-					}),
-				},
-			})
+		// timeout
+		start := time.Now()
+		tester.Request("GET", "posts?wait=100ms", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, int64(0), gjson.Get(r.Body.String(), "data.#").Int())
 		})
+		assert.True(t, time.Since(start) >= 100*time.Millisecond)
 
-		tester.Assign("api", &Controller{
-			Model: &postModel{},
-			ResourceActions: M{
-				"bytes": A("bytes", []string{"POST"}, 0, 0, func(ctx *Context) error {
-					assert.NotEmpty(t, ctx.Model)
-
-					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
-					assert.NoError(t, err)
-					assert.Equal(t, []byte("PAYLOAD"), bytes)
-
-					_, err = ctx.ResponseWriter.Write([]byte("RESPONSE"))
-					assert.NoError(t, err)
-
-					return nil
-				}),
-				"empty": A("empty", []string{"POST"}, 0, 0, func(ctx *Context) error {
-					assert.NotEmpty(t, ctx.Model)
-
-					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
-					assert.NoError(t, err)
-					assert.Empty(t, bytes)
-
-					return nil
-				}),
-				"error": A("error", []string{"POST"}, 3, 0, func(ctx *Context) error {
-					assert.NotEmpty(t, ctx.Model)
-
-					bytes, err := io.ReadAll(ctx.HTTPRequest.Body)
-					assert.Error(t, err)
-					assert.Equal(t, []byte{}, bytes)
-					assert.Equal(t, serve.ErrBodyLimitExceeded, err)
+		// change
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			tester.Insert(&postModel{
+				Title: "other",
+			})
+			time.Sleep(200 * time.Millisecond)
+			tester.Insert(&postModel{
+				Title: "match",
+			})
+		}()
 
-					ctx.ResponseWriter.WriteHeader(http.StatusRequestEntityTooLarge)
+		start = time.Now()
+		tester.Request("GET", "posts?wait=4s&filter[title]=match", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, int64(1), gjson.Get(r.Body.String(), "data.#").Int())
+			assert.Equal(t, "match", gjson.Get(r.Body.String(), "data.0.attributes.title").String())
+		})
+		assert.True(t, time.Since(start) >= 400*time.Millisecond)
+		assert.True(t, time.Since(start) < 4*time.Second)
+	})
+}
 
+func TestLongPollingAuthorization(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		group := tester.Assign("", &Controller{
+			Model:     &postModel{},
+			WaitLimit: 5 * time.Second,
+			Authorizers: L{
+				C("TestLongPollingAuthorization", Authorizer, All(), func(ctx *Context) error {
+					if ctx.HTTPRequest.Header.Get("Authorization") == "" {
+						return xo.SF("access denied")
+					}
+					ctx.Filters = append(ctx.Filters, bson.M{
+						"Title": "visible",
+					})
 					return nil
 				}),
 			},
@@ -6892,691 +9587,614 @@ func TestResourceActions(t *testing.T) {
 			Model: &noteModel{},
 		})
 
-		post := tester.Insert(&postModel{
-			Title: "Post",
-		}).(*postModel).ID()
+		// unauthorized
+		start := time.Now()
+		tester.Request("GET", "posts?wait=4s", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusUnauthorized, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+		assert.True(t, time.Since(start) < time.Second)
 
-		// get byte response
-		tester.Header["Content-Type"] = "text/plain"
-		tester.Header["Accept"] = "text/plain"
-		tester.Request("POST", "posts/"+post.Hex()+"/bytes", "PAYLOAD", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		tester.Header["Authorization"] = "Bearer foo"
+		defer delete(tester.Header, "Authorization")
+
+		// authorizer filters
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			tester.Insert(&postModel{
+				Title: "hidden",
+			})
+			time.Sleep(200 * time.Millisecond)
+			tester.Insert(&postModel{
+				Title: "visible",
+			})
+		}()
+
+		start = time.Now()
+		tester.Request("GET", "posts?wait=4s", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, "text/plain; charset=utf-8", r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
-			assert.Equal(t, "RESPONSE", r.Body.String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, int64(1), gjson.Get(r.Body.String(), "data.#").Int())
 		})
+		assert.True(t, time.Since(start) >= 400*time.Millisecond)
+		assert.True(t, time.Since(start) < 4*time.Second)
 
-		// get empty response
-		tester.Header["Content-Type"] = ""
-		tester.Header["Accept"] = ""
-		tester.Request("POST", "posts/"+post.Hex()+"/empty", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// drain
+		drained := make(chan error, 1)
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			drained <- group.Drain(context.Background())
+		}()
+
+		start = time.Now()
+		tester.Request("GET", "posts?wait=4s", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Empty(t, r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
-			assert.Empty(t, r.Body.String(), tester.DebugRequest(rq, r))
 		})
+		assert.NoError(t, <-drained)
+		assert.True(t, time.Since(start) < 4*time.Second)
+	})
+}
 
-		// get error
-		tester.Request("POST", "posts/"+post.Hex()+"/error", "PAYLOAD", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusRequestEntityTooLarge, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Empty(t, r.Result().Header.Get("Content-Type"), tester.DebugRequest(rq, r))
-			assert.Empty(t, r.Body.String(), tester.DebugRequest(rq, r))
+func TestLongPollingUnsupported(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		tester.Request("GET", "posts?wait=1s", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "long polling not supported",
+					"source": {
+						"parameter": "wait"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 	})
 }
 
-func TestSoftDelete(t *testing.T) {
+func TestCountRelationships(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
-		// missing field on model
-		assert.PanicsWithValue(t, `coal: no or multiple fields flagged as "fire-soft-delete" on "fire.missingSoftDeleteField"`, func() {
-			type missingSoftDeleteField struct {
-				coal.Base `json:"-" bson:",inline" coal:"models"`
-				stick.NoValidation
-			}
-
+		assert.PanicsWithValue(t, `fire: count relationship "note" is not a has-many relationship`, func() {
 			tester.Assign("", &Controller{
-				Model:      &missingSoftDeleteField{},
-				SoftDelete: true,
+				Model:              &postModel{},
+				CountRelationships: []string{"note"},
 			})
 		})
 
-		// invalid field type
-		assert.PanicsWithValue(t, `fire: soft delete field "Foo" for model "fire.invalidSoftDeleteFieldType" is not of type "*time.Time"`, func() {
-			type invalidSoftDeleteFieldType struct {
-				coal.Base `json:"-" bson:",inline" coal:"models"`
-				Foo       int `coal:"fire-soft-delete"`
-				stick.NoValidation
-			}
+		tester.Assign("", &Controller{
+			Model:              &postModel{},
+			CountRelationships: []string{"comments", "selections"},
+		}, &Controller{
+			Model:      &commentModel{},
+			SoftDelete: true,
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		post1 := tester.Insert(&postModel{
+			Title: "Post 1",
+		}).ID()
+		post2 := tester.Insert(&postModel{
+			Title: "Post 2",
+		}).ID()
+
+		for i := 0; i < 3; i++ {
+			tester.Insert(&commentModel{
+				Message: "Comment",
+				Post:    post1,
+			})
+		}
+		tester.Insert(&commentModel{
+			Message: "Deleted",
+			Post:    post1,
+			Deleted: stick.P(time.Now()),
+		})
+		tester.Insert(&selectionModel{
+			Name:  "Selection 1",
+			Posts: []coal.ID{post1, post2},
+		})
+		tester.Insert(&selectionModel{
+			Name:  "Selection 2",
+			Posts: []coal.ID{post2},
+		})
+
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+
+			body := r.Body.String()
+			assert.Equal(t, post1.Hex(), gjson.Get(body, "data.0.id").String())
+			assert.Equal(t, `[3,0]`, gjson.Get(body, "data.#.relationships.comments.meta.count").Raw)
+			assert.Equal(t, `[1,2]`, gjson.Get(body, "data.#.relationships.selections.meta.count").Raw)
+			assert.False(t, gjson.Get(body, "data.0.relationships.note.meta").Exists())
+		})
+
+		tester.Request("GET", "posts/"+post1.Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+
+			body := r.Body.String()
+			assert.Equal(t, `{"count":3}`, gjson.Get(body, "data.relationships.comments.meta").Raw)
+			assert.Equal(t, `{"count":1}`, gjson.Get(body, "data.relationships.selections.meta").Raw)
+		})
+	})
+}
+
+func TestCountRelationshipsCounters(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		counting := &coal.Counting{
+			Name:  "post-comments",
+			Model: &commentModel{},
+			Field: "Post",
+			Filter: bson.M{
+				"Deleted": nil,
+			},
+		}
 
+		assert.PanicsWithValue(t, `fire: counter "selections" is not a count relationship`, func() {
 			tester.Assign("", &Controller{
-				Model:      &invalidSoftDeleteFieldType{},
-				SoftDelete: true,
+				Model:              &postModel{},
+				CountRelationships: []string{"comments"},
+				Counters: map[string]*coal.Counting{
+					"selections": counting,
+				},
 			})
 		})
 
 		tester.Assign("", &Controller{
-			Model:      &postModel{},
-			SoftDelete: true,
+			Model:              &postModel{},
+			CountRelationships: []string{"comments"},
+			Counters: map[string]*coal.Counting{
+				"comments": counting,
+			},
 		}, &Controller{
-			Model: &commentModel{},
+			Model:      &commentModel{},
+			SoftDelete: true,
 		}, &Controller{
 			Model: &selectionModel{},
 		}, &Controller{
 			Model: &noteModel{},
 		})
 
-		id := tester.Insert(&postModel{
+		post := tester.Insert(&postModel{
 			Title: "Post 1",
-		}).ID().Hex()
+		}).ID()
 
-		// get list of posts
-		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"data": [
-					{
-						"type": "posts",
-						"id": "`+id+`",
-						"attributes": {
-							"title": "Post 1",
-							"published": false,
-							"text-body": ""
-						},
-						"relationships": {
-							"comments": {
-								"data": [],
-								"links": {
-									"self": "/posts/`+id+`/relationships/comments",
-									"related": "/posts/`+id+`/comments"
-								}
-							},
-							"selections": {
-								"data": [],
-								"links": {
-									"self": "/posts/`+id+`/relationships/selections",
-									"related": "/posts/`+id+`/selections"
-								}
-							},
-							"note": {
-								"data": null,
-								"links": {
-									"self": "/posts/`+id+`/relationships/note",
-									"related": "/posts/`+id+`/note"
-								}
-							}
-						}
-					}
-				],
-				"links": {
-					"self": "/posts"
-				}
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		for i := 0; i < 2; i++ {
+			tester.Insert(&commentModel{
+				Message: "Comment",
+				Post:    post,
+			})
+		}
+		tester.Insert(&commentModel{
+			Message: "Deleted",
+			Post:    post,
+			Deleted: stick.P(time.Now()),
 		})
 
-		// get single post
-		tester.Request("GET", "posts/"+id, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// missing counters are counted
+		tester.Request("GET", "posts/"+post.Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"data": {
-					"type": "posts",
-					"id": "`+id+`",
-					"attributes": {
-						"title": "Post 1",
-						"published": false,
-						"text-body": ""
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+id+`/relationships/comments",
-								"related": "/posts/`+id+`/comments"
-							}
-						},
-						"selections": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+id+`/relationships/selections",
-								"related": "/posts/`+id+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+id+`/relationships/note",
-								"related": "/posts/`+id+`/note"
-							}
-						}
-					}
-				},
-				"links": {
-					"self": "/posts/`+id+`"
-				}
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, `{"count":2}`, gjson.Get(r.Body.String(), "data.relationships.comments.meta").Raw)
 		})
+		assert.Equal(t, 0, tester.Count(&coal.Counter{}))
 
-		// delete post
-		tester.Request("DELETE", "posts/"+id, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusNoContent, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.Equal(t, "", r.Body.String(), tester.DebugRequest(rq, r))
+		// materialize counters
+		_, err := counting.Load(nil, tester.Store, post)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, tester.Count(&coal.Counter{}))
+
+		tester.Insert(&commentModel{
+			Message: "Comment",
+			Post:    post,
 		})
 
-		// check post
-		post := tester.FindLast(&postModel{}).(*postModel)
-		assert.NotNil(t, post)
-		assert.NotNil(t, post.Deleted)
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `[2]`, gjson.Get(r.Body.String(), "data.#.relationships.comments.meta.count").Raw)
+		})
+
+		err = counting.Increment(nil, tester.Store, post, 1)
+		assert.NoError(t, err)
 
-		// get empty list of posts
 		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"data":[],
-				"links": {
-					"self": "/posts"
-				}
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+			assert.Equal(t, `[3]`, gjson.Get(r.Body.String(), "data.#.relationships.comments.meta.count").Raw)
+		})
+	})
+}
+
+func TestPoolModels(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: model pooling cannot be combined with deduplicated reads`, func() {
+			tester.Assign("", &Controller{
+				Model:            &postModel{},
+				PoolModels:       true,
+				DeduplicateReads: true,
+			})
+		})
+
+		var loaded []coal.Model
+		tester.Assign("", &Controller{
+			Model:      &postModel{},
+			PoolModels: true,
+			Notifiers: L{
+				C("TestPoolModels", Notifier, Only(List), func(ctx *Context) error {
+					loaded = ctx.Models
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
 		})
 
-		// get missing post
-		tester.Request("GET", "posts/"+id, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusNotFound, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "404",
-						"title": "not found",
-						"detail": "resource not found"
-					}
-				]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
-		})
+		post1 := tester.Insert(&postModel{
+			Title: "Post 1",
+		}).ID().Hex()
+		post2 := tester.Insert(&postModel{
+			Title: "Post 2",
+		}).ID().Hex()
 
-		// TODO: Test has one and has many relationships.
+		for i := 0; i < 2; i++ {
+			tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+
+				body := r.Body.String()
+				assert.Equal(t, `["`+post1+`","`+post2+`"]`, gjson.Get(body, "data.#.id").Raw)
+				assert.Equal(t, `["Post 1","Post 2"]`, gjson.Get(body, "data.#.attributes.title").Raw)
+			})
+
+			assert.Len(t, loaded, 2)
+			for _, model := range loaded {
+				assert.Equal(t, &postModel{}, model)
+			}
+		}
 	})
 }
 
-func TestIdempotentCreate(t *testing.T) {
-	withTester(t, func(t *testing.T, tester *Tester) {
-		// missing field on model
-		assert.PanicsWithValue(t, `coal: no or multiple fields flagged as "fire-idempotent-create" on "fire.missingIdempotentCreateField"`, func() {
-			type missingIdempotentCreateField struct {
-				coal.Base `json:"-" bson:",inline" coal:"models"`
-				stick.NoValidation
-			}
+type testEncrypter struct{}
+
+func (testEncrypter) EncryptValue(_ context.Context, _ coal.Model, _ string, value interface{}) (interface{}, error) {
+	return fmt.Sprintf("enc:%v", value), nil
+}
 
+func TestEncryptedFilters(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		assert.PanicsWithValue(t, `fire: filter "Code" is randomly encrypted`, func() {
 			tester.Assign("", &Controller{
-				Model:            &missingIdempotentCreateField{},
-				IdempotentCreate: true,
+				Model:   &secretModel{},
+				Filters: []string{"Code"},
 			})
 		})
 
-		// invalid field type
-		assert.PanicsWithValue(t, `fire: idempotent create field "Foo" for model "fire.invalidIdempotentCreateFieldType" is not of type "string"`, func() {
-			type invalidIdempotentCreateFieldType struct {
-				coal.Base `json:"-" bson:",inline" coal:"models"`
-				Foo       int `coal:"fire-idempotent-create"`
-				stick.NoValidation
-			}
-
+		assert.PanicsWithValue(t, `fire: sorter "Name" is encrypted`, func() {
 			tester.Assign("", &Controller{
-				Model:            &invalidIdempotentCreateFieldType{},
-				IdempotentCreate: true,
+				Model:   &secretModel{},
+				Filters: []string{"Name"},
+				Sorters: []string{"Name"},
 			})
 		})
 
 		tester.Assign("", &Controller{
-			Model: &postModel{},
-		}, &Controller{
-			Model: &commentModel{},
-		}, &Controller{
-			Model:            &selectionModel{},
-			IdempotentCreate: true,
-		}, &Controller{
-			Model: &noteModel{},
+			Model:   &secretModel{},
+			Filters: []string{"Name"},
 		})
 
-		// missing create token
-		tester.Request("POST", "selections", `{
-			"data": {
-				"type": "selections",
-				"attributes": {
-					"name": "test"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "400",
-						"title": "bad request",
-						"detail": "missing idempotent create token"
-					}
-				]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		secret1 := tester.Insert(&secretModel{
+			Name: "enc:foo",
+		}).ID().Hex()
+		secret2 := tester.Insert(&secretModel{
+			Name: "bar",
+		}).ID().Hex()
+
+		tester.Request("GET", "secrets?filter[name]=bar", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `["`+secret2+`"]`, gjson.Get(r.Body.String(), "data.#.id").Raw)
 		})
 
-		var id string
+		tester.Store.Encrypter = testEncrypter{}
+		defer func() {
+			tester.Store.Encrypter = nil
+		}()
 
-		// create selection
-		tester.Request("POST", "selections", `{
-			"data": {
-				"type": "selections",
-				"attributes": {
-					"name": "Selection 1",
-					"create-token": "foo123"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			selection := tester.FindLast(&selectionModel{})
-			id = selection.ID().Hex()
+		tester.Request("GET", "secrets?filter[name]=foo,baz", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `["`+secret1+`"]`, gjson.Get(r.Body.String(), "data.#.id").Raw)
+		})
 
-			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"data": {
-					"type": "selections",
-					"id": "`+id+`",
-					"attributes": {
-						"name": "Selection 1",
-						"create-token": "foo123"
-					},
-					"relationships": {
-						"posts": {
-							"data": [],
-							"links": {
-								"self": "/selections/`+id+`/relationships/posts",
-								"related": "/selections/`+id+`/posts"
-							}
-						}
-					}
-				},
-				"links": {
-					"self": "/selections/`+id+`"
-				}
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		tester.Request("GET", "secrets?filter[name]=bar", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `[]`, gjson.Get(r.Body.String(), "data.#.id").Raw)
 		})
+	})
+}
 
-		// attempt to create duplicate
-		tester.Request("POST", "selections", `{
-			"data": {
-				"type": "selections",
-				"attributes": {
-					"name": "Selection 1",
-					"create-token": "foo123"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusConflict, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "409",
-						"title": "conflict",
-						"detail": "existing document with same idempotent create token"
-					}
-				]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+func TestCustomTypeFilters(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:   &productModel{},
+			Filters: []string{"Price"},
+			Sorters: []string{"Price"},
 		})
 
-		// attempt to change create token
-		tester.Request("PATCH", "selections/"+id, `{
-			"data": {
-				"type": "selections",
-				"id": "`+id+`",
-				"attributes": {
-					"create-token": "bar456"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+		product1 := tester.Insert(&productModel{
+			Price: decimal.RequireFromString("10.5"),
+		}).ID().Hex()
+		product2 := tester.Insert(&productModel{
+			Price: decimal.RequireFromString("2.25"),
+		}).ID().Hex()
+
+		tester.Request("GET", "products?filter[price]=10.50", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `["`+product1+`"]`, gjson.Get(r.Body.String(), "data.#.id").Raw)
+			assert.Equal(t, `["10.5"]`, gjson.Get(r.Body.String(), "data.#.attributes.price").Raw)
+		})
+
+		tester.Request("GET", "products?filter[price]=2.25,10.5&sort=price", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `["`+product2+`","`+product1+`"]`, gjson.Get(r.Body.String(), "data.#.id").Raw)
+		})
+
+		tester.Request("GET", "products?filter[price]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "400",
-						"title": "bad request",
-						"detail": "idempotent create token cannot be changed"
-					}
-				]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid value for filter \"price\""
+				}]
+			}`, r.Body.String())
 		})
 	})
 }
 
-func TestConsistentUpdate(t *testing.T) {
+func TestIncludes(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
-		// missing field on model
-		assert.PanicsWithValue(t, `coal: no or multiple fields flagged as "fire-consistent-update" on "fire.missingConsistentUpdateField"`, func() {
-			type missingConsistentUpdateField struct {
-				coal.Base `json:"-" bson:",inline" coal:"models"`
-				stick.NoValidation
-			}
-
+		assert.PanicsWithValue(t, `fire: include path "foo" does not start with a relationship`, func() {
 			tester.Assign("", &Controller{
-				Model:            &missingConsistentUpdateField{},
-				ConsistentUpdate: true,
+				Model: &commentModel{},
+				Includes: map[string]func(*Context) bool{
+					"foo": nil,
+				},
 			})
 		})
 
-		// invalid field type
-		assert.PanicsWithValue(t, `fire: consistent update field "Foo" for model "fire.invalidConsistentUpdateFieldType" is not of type "string"`, func() {
-			type invalidConsistentUpdateFieldType struct {
-				coal.Base `json:"-" bson:",inline" coal:"models"`
-				Foo       int `coal:"fire-consistent-update"`
-				stick.NoValidation
-			}
-
+		assert.PanicsWithValue(t, `fire: include path "parent.post" is missing its parent path`, func() {
 			tester.Assign("", &Controller{
-				Model:            &invalidConsistentUpdateFieldType{},
-				ConsistentUpdate: true,
+				Model: &commentModel{},
+				Includes: map[string]func(*Context) bool{
+					"parent.post": nil,
+				},
 			})
 		})
 
 		tester.Assign("", &Controller{
 			Model: &postModel{},
+			Authorizers: L{
+				C("TestIncludes", Authorizer, All(), func(ctx *Context) error {
+					ctx.ReadableFields = stick.Subtract(ctx.ReadableFields, []string{"TextBody"})
+					return nil
+				}),
+			},
 		}, &Controller{
 			Model: &commentModel{},
+			Includes: map[string]func(*Context) bool{
+				"post":   nil,
+				"parent": nil,
+				"parent.post": func(ctx *Context) bool {
+					return ctx.HTTPRequest.Header.Get("X-Admin") == "true"
+				},
+			},
 		}, &Controller{
-			Model:            &selectionModel{},
-			ConsistentUpdate: true,
+			Model: &selectionModel{},
 		}, &Controller{
 			Model: &noteModel{},
 		})
 
-		var id string
-		var selection *selectionModel
+		post1 := tester.Insert(&postModel{
+			Title:    "post-1",
+			TextBody: "secret",
+		}).ID()
+		post2 := tester.Insert(&postModel{
+			Title: "post-2",
+		}).ID()
+		comment1 := tester.Insert(&commentModel{
+			Message: "comment-1",
+			Post:    post1,
+		}).ID()
+		comment2 := tester.Insert(&commentModel{
+			Message: "comment-2",
+			Parent:  &comment1,
+			Post:    post1,
+		}).ID()
 
-		// create selection
-		tester.Request("POST", "selections", `{
-			"data": {
-				"type": "selections",
-				"attributes": {
-					"name": "Selection 1"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			selection = tester.FindLast(&selectionModel{}).(*selectionModel)
-			id = selection.ID().Hex()
+		// included post
+		tester.Request("GET", "comments/"+comment2.Hex()+"?include=post", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `["`+post1.Hex()+`"]`, gjson.Get(r.Body.String(), "included.#.id").Raw)
+			assert.Equal(t, `"post-1"`, gjson.Get(r.Body.String(), "included.0.attributes.title").Raw)
+			assert.False(t, gjson.Get(r.Body.String(), "included.0.attributes.text-body").Exists())
+		})
 
-			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"data": {
-					"type": "selections",
-					"id": "`+id+`",
-					"attributes": {
-						"name": "Selection 1",
-						"update-token": "`+selection.UpdateToken+`"
-					},
-					"relationships": {
-						"posts": {
-							"data": [],
-							"links": {
-								"self": "/selections/`+id+`/relationships/posts",
-								"related": "/selections/`+id+`/posts"
-							}
-						}
-					}
-				},
-				"links": {
-					"self": "/selections/`+id+`"
-				}
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		// deduplicated includes
+		tester.Request("GET", "comments?include=post,parent", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `["`+post1.Hex()+`"]`, gjson.Get(r.Body.String(), "included.#.id").Raw)
+		})
+
+		// nested include
+		tester.Header["X-Admin"] = "true"
+		tester.Request("GET", "comments/"+comment2.Hex()+"?include=parent.post", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, `["`+comment1.Hex()+`","`+post1.Hex()+`"]`, gjson.Get(r.Body.String(), "included.#.id").Raw)
 		})
+		delete(tester.Header, "X-Admin")
 
-		// missing update token
-		tester.Request("PATCH", "selections/"+id, `{
-			"data": {
-				"type": "selections",
-				"id": "`+id+`",
-				"attributes": {}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		// denied include
+		tester.Request("GET", "comments/"+comment2.Hex()+"?include=parent.post", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusForbidden, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "400",
-						"title": "bad request",
-						"detail": "invalid consistent update token"
+				"errors": [{
+					"status": "403",
+					"title": "forbidden",
+					"detail": "include path \"parent.post\" is not permitted",
+					"source": {
+						"parameter": "include"
 					}
-				]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+				}]
+			}`, r.Body.String())
 		})
 
-		// invalid update token
-		tester.Request("PATCH", "selections/"+id, `{
-			"data": {
-				"type": "selections",
-				"id": "`+id+`",
-				"attributes": {
-					"update-token": "bar123"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+		// invalid include
+		tester.Request("GET", "comments?include=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "400",
-						"title": "bad request",
-						"detail": "invalid consistent update token"
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid include path \"foo\"",
+					"source": {
+						"parameter": "include"
 					}
-				]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+				}]
+			}`, r.Body.String())
 		})
 
-		// update selection
-		tester.Request("PATCH", "selections/"+id, `{
-			"data": {
-				"type": "selections",
-				"id": "`+id+`",
-				"attributes": {
-					"update-token": "`+selection.UpdateToken+`"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			selection = tester.FindLast(&selectionModel{}).(*selectionModel)
-
+		// ignored include
+		tester.Request("GET", "posts/"+post2.Hex()+"?include=comments", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
 			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"data": {
-					"type": "selections",
-					"id": "`+id+`",
-					"attributes": {
-						"name": "Selection 1",
-						"update-token": "`+selection.UpdateToken+`"
-					},
-					"relationships": {
-						"posts": {
-							"data": [],
-							"links": {
-								"self": "/selections/`+id+`/relationships/posts",
-								"related": "/selections/`+id+`/posts"
-							}
-						}
-					}
-				},
-				"links": {
-					"self": "/selections/`+id+`"
-				}
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+			assert.False(t, gjson.Get(r.Body.String(), "included").Exists())
 		})
 	})
 }
 
-func TestTransactions(t *testing.T) {
+func TestIncludesListLimit(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
-		group := tester.Assign("", &Controller{
-			Model: &postModel{},
-			Notifiers: L{
-				C("foo", Notifier, All(), func(ctx *Context) error {
-					if ctx.Model.(*postModel).Title == "FAIL" {
-						return xo.F("foo")
-					}
-					return nil
-				}),
+		tester.Assign("", &Controller{
+			Model:     &postModel{},
+			ListLimit: 2,
+		}, &Controller{
+			Model: &selectionModel{},
+			Includes: map[string]func(*Context) bool{
+				"posts": nil,
 			},
 		}, &Controller{
 			Model: &commentModel{},
-		}, &Controller{
-			Model: &selectionModel{},
 		}, &Controller{
 			Model: &noteModel{},
 		})
 
-		var errs []string
-		group.reporter = func(err error) {
-			errs = append(errs, err.Error())
+		var posts []coal.ID
+		var ids []string
+		for i := 0; i < 5; i++ {
+			post := tester.Insert(&postModel{
+				Title: "post",
+			}).ID()
+			posts = append(posts, post)
+			ids = append(ids, post.Hex())
 		}
 
-		var id string
+		selection := tester.Insert(&selectionModel{
+			Posts: posts,
+		}).ID()
 
-		// create post
-		tester.Request("POST", "posts", `{
-			"data": {
-				"type": "posts",
-				"attributes": {
-					"title": "Post 1"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			post := tester.FindLast(&postModel{})
-			id = post.ID().Hex()
+		tester.Request("GET", "selections/"+selection.Hex()+"?include=posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
 
-			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"data": {
-					"type": "posts",
-					"id": "`+id+`",
-					"attributes": {
-						"title": "Post 1",
-						"published": false,
-						"text-body": ""
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+id+`/relationships/comments",
-								"related": "/posts/`+id+`/comments"
-							}
-						},
-						"selections": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+id+`/relationships/selections",
-								"related": "/posts/`+id+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+id+`/relationships/note",
-								"related": "/posts/`+id+`/note"
-							}
-						}
-					}
-				},
-				"links": {
-					"self": "/posts/`+id+`"
-				}
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+			var included []string
+			for _, id := range gjson.Get(r.Body.String(), "included.#.id").Array() {
+				included = append(included, id.String())
+			}
+			assert.ElementsMatch(t, ids, included)
 		})
+	})
+}
 
-		// create post error
-		tester.Request("POST", "posts", `{
-			"data": {
-				"type": "posts",
-				"attributes": {
-					"title": "FAIL"
-				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			post := tester.FindLast(&postModel{})
-			id = post.ID().Hex()
+func TestMapFilters(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:   &settingModel{},
+			Filters: []string{"Labels.color", "Flags.active"},
+		})
 
-			assert.Equal(t, http.StatusInternalServerError, r.Result().StatusCode, tester.DebugRequest(rq, r))
-			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "500",
-						"title": "internal server error"
-					}
-				]
-			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		assert.PanicsWithValue(t, `fire: filter "Title.foo" does not refer to a key of a map field`, func() {
+			tester.Assign("", &Controller{
+				Model:   &postModel{},
+				Filters: []string{"Title.foo"},
+			})
 		})
 
-		// update post
-		tester.Request("PATCH", "posts/"+id, `{
-			"data": {
-				"type": "posts",
-				"id": "`+id+`",
-				"attributes": {
-					"text-body": "Post 1 Text"
+		setting1 := tester.Insert(&settingModel{
+			Labels: map[string]string{"color": "red", "size": "xl"},
+			Flags:  map[string]bool{"active": true},
+		}).ID().Hex()
+		setting2 := tester.Insert(&settingModel{
+			Labels: map[string]string{"color": "blue"},
+			Flags:  map[string]bool{"active": false},
+		}).ID().Hex()
+		setting3 := tester.Insert(&settingModel{}).ID().Hex()
+
+		list := func(query string) []string {
+			var ids []string
+			tester.Request("GET", "settings?"+query, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+				doc, err := jsonapi.ParseDocument(r.Body)
+				assert.NoError(t, err)
+				for _, res := range doc.Data.Many {
+					ids = append(ids, res.ID)
 				}
-			}
-		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			})
+			return ids
+		}
+
+		assert.Equal(t, []string{setting1}, list("filter[labels.color]=red"))
+		assert.ElementsMatch(t, []string{setting1, setting2}, list("filter[labels.color]=red,blue"))
+		assert.Equal(t, []string{setting1}, list("filter[flags.active]=true"))
+		assert.Equal(t, []string{setting2}, list("filter[flags.active]=false"))
+		assert.Equal(t, []string{setting3}, list("filter[labels.color]="))
+
+		tester.Request("GET", "settings?filter[labels.size]=xl", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"data": {
-					"type": "posts",
-					"id": "`+id+`",
-					"attributes": {
-						"title": "Post 1",
-						"published": false,
-						"text-body": "Post 1 Text"
-					},
-					"relationships": {
-						"comments": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+id+`/relationships/comments",
-								"related": "/posts/`+id+`/comments"
-							}
-						},
-						"selections": {
-							"data": [],
-							"links": {
-								"self": "/posts/`+id+`/relationships/selections",
-								"related": "/posts/`+id+`/selections"
-							}
-						},
-						"note": {
-							"data": null,
-							"links": {
-								"self": "/posts/`+id+`/relationships/note",
-								"related": "/posts/`+id+`/note"
-							}
-						}
-					}
-				},
-				"links": {
-					"self": "/posts/`+id+`"
-				}
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "invalid filter \"labels.size\""
+				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
 
-		// update post
-		tester.Request("PATCH", "posts/"+id, `{
+		tester.Request("POST", "settings", `{
 			"data": {
-				"type": "posts",
-				"id": "`+id+`",
+				"type": "settings",
 				"attributes": {
-					"title": "FAIL"
+					"labels": {
+						"color": "very very red"
+					}
 				}
 			}
 		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
-			assert.Equal(t, http.StatusInternalServerError, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
 			assert.JSONEq(t, `{
-				"errors": [
-					{
-						"status": "500",
-						"title": "internal server error"
-					}
-				]
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "Labels.color: too long"
+				}]
 			}`, r.Body.String(), tester.DebugRequest(rq, r))
 		})
-
-		assert.Equal(t, 1, tester.Count(&postModel{}))
-		assert.Equal(t, "Post 1", stick.MustGet(tester.Fetch(&postModel{}, coal.MustFromHex(id)), "Title"))
-
-		assert.Equal(t, []string{"foo", "foo"}, errs)
 	})
 }
@@ -0,0 +1,198 @@
+package fire
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/256dpi/jsonapi/v2"
+	"github.com/256dpi/xo"
+
+	"github.com/256dpi/fire/heat"
+)
+
+// CSRF configures a double-submit cookie based CSRF protection for groups
+// that serve cookie authenticated (browser) clients.
+type CSRF struct {
+	// The name of the cookie that stores the token.
+	//
+	// Default: "csrf-token".
+	CookieName string
+
+	// The name of the header that must carry the token.
+	//
+	// Default: "X-CSRF-Token".
+	HeaderName string
+
+	// The path and domain of the cookie.
+	//
+	// Default: "/", "".
+	Path   string
+	Domain string
+
+	// Whether the cookie should only be sent over secure connections.
+	Secure bool
+
+	// The same site mode of the cookie.
+	//
+	// Default: http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// The lifetime of the cookie. A zero value will create a session cookie.
+	MaxAge time.Duration
+
+	// The secret used to sign issued tokens. Tokens sent by clients that have
+	// not been signed using the secret are rejected and replaced. A shared
+	// secret must be configured if the application runs multiple instances.
+	//
+	// Default: A random secret.
+	Secret heat.Secret
+}
+
+func (c *CSRF) prepare() {
+	// set default cookie name
+	if c.CookieName == "" {
+		c.CookieName = "csrf-token"
+	}
+
+	// set default header name
+	if c.HeaderName == "" {
+		c.HeaderName = "X-CSRF-Token"
+	}
+
+	// set default path
+	if c.Path == "" {
+		c.Path = "/"
+	}
+
+	// set default same site mode
+	if c.SameSite == 0 {
+		c.SameSite = http.SameSiteLaxMode
+	}
+
+	// set default secret
+	if len(c.Secret) == 0 {
+		c.Secret = heat.MustRand(32)
+	}
+}
+
+// Rotate will issue a new token and set it as a cookie on the provided
+// response writer. It should be called when a client authenticates to prevent
+// the fixation of tokens. The new token is returned.
+func (c *CSRF) Rotate(w http.ResponseWriter) (string, error) {
+	// generate token
+	token, err := c.generate()
+	if err != nil {
+		return "", err
+	}
+
+	// set cookie
+	c.set(w, token)
+
+	return token, nil
+}
+
+func (c *CSRF) issue(ctx *Context) error {
+	// reuse existing token if it has been issued by us
+	var token string
+	if cookie, err := ctx.HTTPRequest.Cookie(c.CookieName); err == nil && c.valid(cookie.Value) {
+		token = cookie.Value
+	}
+
+	// otherwise, generate token
+	if token == "" {
+		var err error
+		token, err = c.generate()
+		if err != nil {
+			return err
+		}
+	}
+
+	// set cookie
+	c.set(ctx.ResponseWriter, token)
+
+	// set header
+	ctx.ResponseWriter.Header().Set("Content-Type", "application/json")
+	ctx.ResponseWriter.Header().Set("Cache-Control", "no-store")
+
+	return ctx.Respond(map[string]string{
+		"token": token,
+	})
+}
+
+func (c *CSRF) generate() (string, error) {
+	// generate nonce
+	nonce, err := heat.Rand(32)
+	if err != nil {
+		return "", err
+	}
+
+	// encode nonce
+	value := base64.RawURLEncoding.EncodeToString(nonce)
+
+	return value + "." + c.sign(value), nil
+}
+
+func (c *CSRF) sign(value string) string {
+	mac := hmac.New(sha256.New, c.Secret)
+	_, _ = mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *CSRF) valid(token string) bool {
+	// split token
+	value, signature, ok := strings.Cut(token, ".")
+	if !ok || value == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(c.sign(value)))
+}
+
+func (c *CSRF) set(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.CookieName,
+		Value:    token,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		MaxAge:   int(c.MaxAge / time.Second),
+		Secure:   c.Secure,
+		HttpOnly: true,
+		SameSite: c.SameSite,
+	})
+}
+
+func (c *CSRF) verify(ctx *Context) {
+	// skip safe methods
+	switch ctx.HTTPRequest.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return
+	}
+
+	// skip requests authenticated using the authorization header as they
+	// cannot be forged by browsers
+	if ctx.HTTPRequest.Header.Get("Authorization") != "" {
+		return
+	}
+
+	// get cookie
+	cookie, err := ctx.HTTPRequest.Cookie(c.CookieName)
+	if err != nil || cookie.Value == "" {
+		xo.Abort(jsonapi.ErrorFromStatus(http.StatusForbidden, "missing csrf token"))
+	}
+
+	// check token
+	if !c.valid(cookie.Value) {
+		xo.Abort(jsonapi.ErrorFromStatus(http.StatusForbidden, "invalid csrf token"))
+	}
+
+	// compare token
+	header := ctx.HTTPRequest.Header.Get(c.HeaderName)
+	if subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+		xo.Abort(jsonapi.ErrorFromStatus(http.StatusForbidden, "invalid csrf token"))
+	}
+}
@@ -0,0 +1,64 @@
+package fire
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/256dpi/jsonapi/v2"
+	"github.com/256dpi/xo"
+)
+
+// Deprecation marks operations of a controller as deprecated. The operations
+// are removed once the removal time has been reached, which allows staged
+// deprecations without removing code.
+type Deprecation struct {
+	// The deprecated operations.
+	Operations Operation
+
+	// The time after which the operations are removed.
+	Removed time.Time
+
+	// Whether requests to removed operations are aborted with a "405 Method
+	// Not Allowed" instead of a "410 Gone" status.
+	NotAllowed bool
+
+	// The migration hint that is included as the "migration" error meta
+	// field if the operations have been removed.
+	Hint string
+}
+
+func (c *Controller) checkDeprecations(ctx *Context) {
+	for _, deprecation := range c.Deprecations {
+		// check operation
+		if ctx.Operation&deprecation.Operations == 0 {
+			continue
+		}
+
+		// set headers if not yet removed, sub contexts used to load related
+		// and included resources have no response writer
+		if time.Now().Before(deprecation.Removed) {
+			if ctx.ResponseWriter != nil {
+				ctx.ResponseWriter.Header().Set("Deprecation", "true")
+				ctx.ResponseWriter.Header().Set("Sunset", deprecation.Removed.UTC().Format(http.TimeFormat))
+			}
+			continue
+		}
+
+		// get status
+		status := http.StatusGone
+		if deprecation.NotAllowed {
+			status = http.StatusMethodNotAllowed
+		}
+
+		// prepare error
+		err := jsonapi.ErrorFromStatus(status, "removed operation")
+		err.Meta = jsonapi.Map{
+			"removed": deprecation.Removed.UTC().Format(time.RFC3339),
+		}
+		if deprecation.Hint != "" {
+			err.Meta["migration"] = deprecation.Hint
+		}
+
+		xo.Abort(err)
+	}
+}
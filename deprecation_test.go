@@ -0,0 +1,131 @@
+package fire
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/256dpi/fire/coal"
+)
+
+func TestDeprecations(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		removed := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		sunset := time.Now().Add(time.Hour).UTC()
+
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+			Deprecations: []Deprecation{
+				{
+					Operations: Delete,
+					Removed:    removed,
+					Hint:       "archive posts instead",
+				},
+				{
+					Operations: Create,
+					Removed:    removed,
+					NotAllowed: true,
+				},
+				{
+					Operations: List | Find,
+					Removed:    sunset,
+				},
+			},
+		}, &Controller{
+			Model: &commentModel{},
+			Includes: map[string]func(*Context) bool{
+				"post": nil,
+			},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		post := tester.Insert(&postModel{
+			Title: "post",
+		}).ID().Hex()
+
+		// list posts
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "true", r.Header().Get("Deprecation"))
+			assert.Equal(t, sunset.Format(http.TimeFormat), r.Header().Get("Sunset"))
+		})
+
+		// delete post
+		tester.Request("DELETE", "posts/"+post, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusGone, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "410",
+					"title": "gone",
+					"detail": "removed operation",
+					"meta": {
+						"removed": "2020-01-01T00:00:00Z",
+						"migration": "archive posts instead"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// create post
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "foo"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusMethodNotAllowed, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"errors":[{
+					"status": "405",
+					"title": "method not allowed",
+					"detail": "removed operation",
+					"meta": {
+						"removed": "2020-01-01T00:00:00Z"
+					}
+				}]
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		// update post
+		tester.Request("PATCH", "posts/"+post, `{
+			"data": {
+				"type": "posts",
+				"id": "`+post+`",
+				"attributes": {
+					"title": "bar"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Empty(t, r.Header().Get("Deprecation"))
+		})
+
+		comment := tester.Insert(&commentModel{
+			Message: "comment",
+			Post:    coal.MustFromHex(post),
+		}).ID().Hex()
+
+		// include deprecated posts
+		tester.Request("GET", "comments?include=post", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, post, gjson.Get(r.Body.String(), "included.0.id").String(), tester.DebugRequest(rq, r))
+		})
+
+		// get related deprecated post
+		tester.Request("GET", "comments/"+comment+"/post", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, post, gjson.Get(r.Body.String(), "data.id").String(), tester.DebugRequest(rq, r))
+		})
+
+		assert.Equal(t, 1, tester.Count(&postModel{}))
+	})
+}
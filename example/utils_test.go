@@ -9,11 +9,15 @@ import (
 	"github.com/256dpi/fire/coal"
 )
 
-var mongoStore = coal.MustConnect("mongodb://0.0.0.0/test-fire-example", xo.Crash)
+var mongoStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire-example", xo.Crash)
 var lungoStore = coal.MustOpen(nil, "test-fire-example", xo.Crash)
 
 func withTester(t *testing.T, fn func(*testing.T, *fire.Tester)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		tester := fire.NewTester(mongoStore, models.All()...)
 		tester.Clean()
 		fn(t, tester)
@@ -31,6 +31,16 @@ const (
 	// ResourceOwnerContextKey is the key used to save the resource owner in a
 	// context.
 	ResourceOwnerContextKey = ctxKey("resource-owner")
+
+	// TokenInfoContextKey is the key used to save the token info in a
+	// context.
+	TokenInfoContextKey = ctxKey("token-info")
+
+	// ClientIPContextKey is the key used to save the client IP in a context.
+	ClientIPContextKey = ctxKey("client-ip")
+
+	// the key used to save the scope usage of sampled requests
+	scopeUsageContextKey = ctxKey("scope-usage")
 )
 
 // Authenticator provides OAuth2 based authentication and authorization. The
@@ -92,10 +102,11 @@ func (a *Authenticator) Endpoint(prefix string) http.Handler {
 
 		// prepare context
 		ctx := &Context{
-			Context: rcx,
-			Request: r,
-			writer:  w,
-			Tracer:  tracer,
+			Context:  rcx,
+			Request:  r,
+			ClientIP: a.policy.TrustedProxies.ClientIP(r, a.policy.TrustedHeader),
+			Tracer:   tracer,
+			writer:   w,
 		}
 
 		// call endpoints
@@ -175,10 +186,11 @@ func (a *Authenticator) Authorizer(scope []string, force, loadClient, loadResour
 
 			// prepare context
 			ctx := &Context{
-				Context: rcx,
-				Request: r,
-				writer:  w,
-				Tracer:  tracer,
+				Context:  rcx,
+				Request:  r,
+				ClientIP: a.policy.TrustedProxies.ClientIP(r, a.policy.TrustedHeader),
+				Tracer:   tracer,
+				writer:   w,
 			}
 
 			// get token
@@ -205,43 +217,51 @@ func (a *Authenticator) Authorizer(scope []string, force, loadClient, loadResour
 				xo.Abort(oauth2.InsufficientScope(scope))
 			}
 
-			// create new context with access token
-			rcx = context.WithValue(rcx, AccessTokenContextKey, accessToken)
-
-			// call next handler if client should not be loaded
-			if !loadClient {
-				// call next handler
-				next.ServeHTTP(w, r.WithContext(rcx))
-
-				return
+			// record scope usage of sampled requests
+			if a.policy.ScopeUsage != nil && a.policy.ScopeUsage.sample() {
+				a.policy.ScopeUsage.record(data.ClientID, data.Scope, scope, true)
+				rcx = context.WithValue(rcx, scopeUsageContextKey, a.policy.ScopeUsage)
 			}
 
-			// get client
-			client := a.getFirstClient(ctx, data.ClientID)
-			if client == nil {
-				xo.Abort(xo.F("missing client"))
-			}
+			// create new context with access token and client IP
+			rcx = context.WithValue(rcx, AccessTokenContextKey, accessToken)
+			rcx = context.WithValue(rcx, ClientIPContextKey, ctx.ClientIP)
+
+			// load client if requested
+			var client Client
+			if loadClient {
+				// get client
+				client = a.getFirstClient(ctx, data.ClientID)
+				if client == nil {
+					xo.Abort(xo.F("missing client"))
+				}
 
-			// create new context with client
-			rcx = context.WithValue(rcx, ClientContextKey, client)
+				// create new context with client
+				rcx = context.WithValue(rcx, ClientContextKey, client)
+			}
 
-			// call next handler if resource owner does not exist or should not
-			// be loaded
-			if data.ResourceOwnerID == nil || !loadResourceOwner {
-				// call next handler
-				next.ServeHTTP(w, r.WithContext(rcx))
+			// load resource owner if requested and present
+			var resourceOwner ResourceOwner
+			if loadClient && loadResourceOwner && data.ResourceOwnerID != nil {
+				// get resource owner
+				resourceOwner = a.getFirstResourceOwner(ctx, client, *data.ResourceOwnerID)
+				if resourceOwner == nil {
+					xo.Abort(oauth2.InvalidToken("missing resource owner"))
+				}
 
-				return
+				// create new context with resource owner
+				rcx = context.WithValue(rcx, ResourceOwnerContextKey, resourceOwner)
 			}
 
-			// get resource owner
-			resourceOwner := a.getFirstResourceOwner(ctx, client, *data.ResourceOwnerID)
-			if resourceOwner == nil {
-				xo.Abort(oauth2.InvalidToken("missing resource owner"))
-			}
+			// load token info if available
+			if a.policy.TokenInfo != nil {
+				// get token info
+				info, err := a.policy.TokenInfo(ctx, client, resourceOwner, accessToken)
+				xo.AbortIf(err)
 
-			// create new context with resource owner
-			rcx = context.WithValue(rcx, ResourceOwnerContextKey, resourceOwner)
+				// create new context with token info
+				rcx = context.WithValue(rcx, TokenInfoContextKey, info)
+			}
 
 			// call next handler
 			next.ServeHTTP(w, r.WithContext(rcx))
@@ -405,7 +425,7 @@ func (a *Authenticator) tokenEndpoint(ctx *Context) {
 	xo.AbortIf(err)
 
 	// make sure the grant type is known
-	if !oauth2.KnownGrantType(req.GrantType) {
+	if !oauth2.KnownGrantType(req.GrantType) && req.GrantType != WorkloadGrantType {
 		xo.Abort(oauth2.InvalidRequest("unknown grant type"))
 	}
 
@@ -453,6 +473,14 @@ func (a *Authenticator) tokenEndpoint(ctx *Context) {
 
 		// handle authorization code grant
 		a.handleAuthorizationCodeGrant(ctx, req, client)
+	case WorkloadGrantType:
+		// check availability
+		if !ctx.grants.WorkloadIdentity || a.policy.WorkloadVerifier == nil {
+			xo.Abort(oauth2.UnsupportedGrantType(""))
+		}
+
+		// handle workload identity grant
+		a.handleWorkloadIdentityGrant(ctx, req, client)
 	}
 }
 
@@ -536,6 +564,56 @@ func (a *Authenticator) handleClientCredentialsGrant(ctx *Context, req *oauth2.T
 	xo.AbortIf(oauth2.WriteTokenResponse(ctx.writer, res))
 }
 
+func (a *Authenticator) handleWorkloadIdentityGrant(ctx *Context, req *oauth2.TokenRequest, client Client) {
+	// trace
+	ctx.Tracer.Push("flame/Authenticator.handleWorkloadIdentityGrant")
+	defer ctx.Tracer.Pop()
+
+	// get assertion
+	assertion := ctx.Request.PostForm.Get("assertion")
+	if assertion == "" {
+		xo.Abort(oauth2.InvalidRequest("missing assertion"))
+	}
+
+	// verify assertion
+	identity, err := a.policy.WorkloadVerifier.Verify(assertion)
+	if ErrInvalidWorkload.Is(err) {
+		xo.Abort(oauth2.InvalidGrant("invalid assertion"))
+	} else if err != nil {
+		xo.Abort(err)
+	}
+
+	// check workload
+	wc, ok := client.(WorkloadClient)
+	if !ok || !wc.ValidWorkload(identity.Subject) {
+		xo.Abort(oauth2.InvalidGrant("unknown workload"))
+	}
+
+	// set workload
+	ctx.Workload = identity
+
+	// validate & grant scope
+	scope, err := a.policy.GrantStrategy(ctx, client, nil, req.Scope)
+	if ErrGrantRejected.Is(err) {
+		xo.Abort(oauth2.AccessDenied("grant rejected"))
+	} else if ErrInvalidScope.Is(err) {
+		xo.Abort(oauth2.InvalidScope(""))
+	} else if err != nil {
+		xo.Abort(err)
+	}
+
+	// issue access token
+	res := a.issueTokens(ctx, false, scope, "", client, nil)
+
+	// invoke callback if available
+	if a.policy.TokensIssued != nil {
+		xo.AbortIf(a.policy.TokensIssued(ctx, client, nil, scope))
+	}
+
+	// write response
+	xo.AbortIf(oauth2.WriteTokenResponse(ctx.writer, res))
+}
+
 func (a *Authenticator) handleRefreshTokenGrant(ctx *Context, req *oauth2.TokenRequest, client Client) {
 	// trace
 	ctx.Tracer.Push("flame/Authenticator.handleRefreshTokenGrant")
@@ -1021,10 +1099,22 @@ func (a *Authenticator) findResourceOwner(ctx *Context, client Client, model Res
 	// prepare resource owner
 	resourceOwner := coal.GetMeta(model).Make().(ResourceOwner)
 
+	// prepare query context
+	var qcx context.Context = ctx
+
 	// use tagged field if present
 	var filters []bson.M
 	idField := coal.L(model, "flame-resource-owner-id", false)
 	if idField != "" {
+		// apply lookup strategy
+		if lro, ok := model.(LookupResourceOwner); ok {
+			lookup := lro.Lookup()
+			id = lookup.Normalize(id)
+			if lookup.Collation != nil {
+				qcx = coal.WithCollation(qcx, lookup.Collation)
+			}
+		}
+
 		filters = []bson.M{
 			{idField: id},
 		}
@@ -1053,7 +1143,7 @@ func (a *Authenticator) findResourceOwner(ctx *Context, client Client, model Res
 	}
 
 	// fetch resource owner
-	found, err := a.store.M(model).FindFirst(ctx, resourceOwner, bson.M{
+	found, err := a.store.M(model).FindFirst(qcx, resourceOwner, bson.M{
 		"$and": filters,
 	}, nil, 0, false)
 	xo.AbortIf(err)
@@ -232,6 +232,104 @@ func TestContextKeys(t *testing.T) {
 	})
 }
 
+func TestTokenInfo(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		var calls int
+		policy := DefaultPolicy(testNotary)
+		policy.TokenInfo = func(ctx *Context, c Client, ro ResourceOwner, token GenericToken) (stick.Map, error) {
+			calls++
+			return stick.Map{
+				"role": ro.(*User).Name,
+			}, nil
+		}
+
+		authenticator := NewAuthenticator(tester.Store, policy, xo.Crash)
+		tester.Handler = newHandler(authenticator, false)
+
+		application := tester.Insert(&Application{
+			Name: "App",
+			Key:  "application",
+		}).(*Application).ID()
+
+		user := tester.Insert(&User{
+			Name:     "admin",
+			Email:    "email@example.com",
+			Password: "foo",
+		}).(*User).ID()
+
+		accessToken := tester.Insert(&Token{
+			Type:        AccessToken,
+			ExpiresAt:   time.Now().Add(authenticator.policy.AccessTokenLifespan),
+			Application: application,
+			User:        &user,
+		}).(*Token).ID()
+
+		token := mustIssue(authenticator.policy, AccessToken, accessToken, time.Now().Add(time.Hour))
+
+		auth := authenticator.Authorizer(nil, true, true, true)
+
+		tester.Handler.(*http.ServeMux).Handle("/api/info", auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, stick.Map{
+				"role": "admin",
+			}, r.Context().Value(TokenInfoContextKey))
+		})))
+
+		tester.Header["Authorization"] = "Bearer " + token
+		tester.Request("GET", "api/info", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Code, tester.DebugRequest(rq, r))
+		})
+
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		var clientIP string
+		policy := DefaultPolicy(testNotary)
+		policy.TrustedProxies = MustParseTrustedProxies("192.0.2.0/24")
+		policy.TokenInfo = func(ctx *Context, c Client, ro ResourceOwner, token GenericToken) (stick.Map, error) {
+			clientIP = ctx.ClientIP
+			return nil, nil
+		}
+
+		authenticator := NewAuthenticator(tester.Store, policy, xo.Crash)
+		handler := newHandler(authenticator, false)
+
+		application := tester.Insert(&Application{
+			Name: "App",
+			Key:  "application",
+		}).(*Application).ID()
+
+		accessToken := tester.Insert(&Token{
+			Type:        AccessToken,
+			ExpiresAt:   time.Now().Add(authenticator.policy.AccessTokenLifespan),
+			Application: application,
+		}).(*Token).ID()
+
+		token := mustIssue(authenticator.policy, AccessToken, accessToken, time.Now().Add(time.Hour))
+
+		auth := authenticator.Authorizer(nil, true, true, true)
+
+		handler.(*http.ServeMux).Handle("/api/info", auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "1.2.3.4", r.Context().Value(ClientIPContextKey))
+		})))
+
+		tester.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.RemoteAddr = "192.0.2.1:1234"
+			handler.ServeHTTP(w, r)
+		})
+
+		tester.Header["Authorization"] = "Bearer " + token
+		tester.Header["X-Forwarded-For"] = "9.9.9.9, 1.2.3.4"
+		tester.Request("GET", "api/info", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Code, tester.DebugRequest(rq, r))
+		})
+
+		assert.Equal(t, "1.2.3.4", clientIP)
+	})
+}
+
 func TestInvalidGrantType(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *fire.Tester) {
 		policy := DefaultPolicy(testNotary)
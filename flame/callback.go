@@ -4,6 +4,7 @@ import (
 	"github.com/256dpi/oauth2/v2"
 
 	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/stick"
 )
 
 // AuthInfoDataKey is the key used to store the auth info struct.
@@ -14,6 +15,8 @@ type AuthInfo struct {
 	Client        Client
 	ResourceOwner ResourceOwner
 	AccessToken   GenericToken
+	TokenInfo     stick.Map
+	ClientIP      string
 }
 
 // Callback returns a callback that can be used in controllers to protect
@@ -45,19 +48,64 @@ func Callback(force bool, scope ...string) *fire.Callback {
 			return fire.ErrAccessDenied.Wrap()
 		}
 
+		// record scope usage
+		recordUsage(ctx, data, requiredScope)
+
 		// get client
 		client := ctx.Value(ClientContextKey).(Client)
 
 		// get resource owner
 		resourceOwner, _ := ctx.Value(ResourceOwnerContextKey).(ResourceOwner)
 
+		// get token info
+		tokenInfo, _ := ctx.Value(TokenInfoContextKey).(stick.Map)
+
+		// get client IP
+		clientIP, _ := ctx.Value(ClientIPContextKey).(string)
+
 		// store auth info
 		ctx.Data[AuthInfoDataKey] = &AuthInfo{
 			Client:        client,
 			ResourceOwner: resourceOwner,
 			AccessToken:   accessToken,
+			TokenInfo:     tokenInfo,
+			ClientIP:      clientIP,
 		}
 
 		return nil
 	})
 }
+
+// IncludeGate returns a function that can be used to gate controller includes
+// by requiring an access token with the provided scope to be granted.
+//
+// Note: The function requires that the request has already been authorized
+// using the Authorizer middleware from an Authenticator.
+func IncludeGate(scope ...string) func(ctx *fire.Context) bool {
+	return func(ctx *fire.Context) bool {
+		// get access token
+		accessToken, _ := ctx.Value(AccessTokenContextKey).(GenericToken)
+		if accessToken == nil {
+			return false
+		}
+
+		// check scope
+		data := accessToken.GetTokenData()
+		if !data.Scope.Includes(oauth2.Scope(scope)) {
+			return false
+		}
+
+		// record scope usage
+		recordUsage(ctx, data, oauth2.Scope(scope))
+
+		return true
+	}
+}
+
+func recordUsage(ctx *fire.Context, data TokenData, scope oauth2.Scope) {
+	// record usage if the request has been sampled by the authorizer
+	usage, _ := ctx.Value(scopeUsageContextKey).(*ScopeUsage)
+	if usage != nil && len(scope) > 0 {
+		usage.record(data.ClientID, nil, scope, false)
+	}
+}
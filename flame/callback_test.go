@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/stick"
 )
 
 func TestCallback(t *testing.T) {
@@ -26,6 +27,8 @@ func TestCallback(t *testing.T) {
 		tester.Context = context.WithValue(tester.Context, ClientContextKey, client)
 		tester.Context = context.WithValue(tester.Context, ResourceOwnerContextKey, resourceOwner)
 		tester.Context = context.WithValue(tester.Context, AccessTokenContextKey, token)
+		tester.Context = context.WithValue(tester.Context, TokenInfoContextKey, stick.Map{"role": "admin"})
+		tester.Context = context.WithValue(tester.Context, ClientIPContextKey, "1.2.3.4")
 
 		cb := Callback(true, "foo")
 
@@ -38,6 +41,8 @@ func TestCallback(t *testing.T) {
 			Client:        client,
 			ResourceOwner: resourceOwner,
 			AccessToken:   token,
+			TokenInfo:     stick.Map{"role": "admin"},
+			ClientIP:      "1.2.3.4",
 		}, *ctx.Data[AuthInfoDataKey].(*AuthInfo))
 	})
 }
@@ -81,3 +86,20 @@ func TestCallbackInsufficientAccessToken(t *testing.T) {
 		assert.Len(t, ctx.Data, 0)
 	})
 }
+
+func TestIncludeGate(t *testing.T) {
+	gate := IncludeGate("foo")
+
+	ctx := &fire.Context{Context: context.Background()}
+	assert.False(t, gate(ctx))
+
+	ctx.Context = context.WithValue(context.Background(), AccessTokenContextKey, &Token{
+		Scope: []string{"bar"},
+	})
+	assert.False(t, gate(ctx))
+
+	ctx.Context = context.WithValue(context.Background(), AccessTokenContextKey, &Token{
+		Scope: []string{"foo", "bar"},
+	})
+	assert.True(t, gate(ctx))
+}
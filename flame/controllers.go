@@ -0,0 +1,87 @@
+package flame
+
+import (
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/stick"
+)
+
+// TokenController returns a controller for the built-in token model that
+// allows operators to list, find and revoke tokens. Tokens are revoked by
+// deleting them. All tokens of an application or user may be revoked at once
+// using the "delete-many" collection action with the "application" or "user"
+// relationship filter. The requests must be authorized using an access token
+// that grants the provided scope.
+func TokenController(store *coal.Store, scope ...string) *fire.Controller {
+	return &fire.Controller{
+		Model:     &Token{},
+		Store:     store,
+		Supported: fire.Only(fire.List | fire.Find | fire.Delete),
+		Filters:   []string{"Type", "Application", "User"},
+		Sorters:   []string{"ExpiresAt"},
+		Authorizers: fire.L{
+			Callback(true, scope...),
+		},
+		BulkDelete: true,
+	}
+}
+
+// ApplicationController returns a controller for the built-in application
+// model that allows operators to manage clients and their redirect URIs. The
+// key of an application cannot be changed once created and a provided secret
+// is hashed before the application is stored. Deleting an application will
+// also revoke all of its tokens. Additionally, the "revoke-tokens" resource
+// action revokes all tokens of an application and responds with the number of
+// revoked tokens. The requests must be authorized using an access token that
+// grants the provided scope.
+func ApplicationController(store *coal.Store, scope ...string) *fire.Controller {
+	return &fire.Controller{
+		Model:   &Application{},
+		Store:   store,
+		Filters: []string{"Name", "Key"},
+		Sorters: []string{"Name", "Key"},
+		Authorizers: fire.L{
+			Callback(true, scope...),
+		},
+		Modifiers: fire.L{
+			fire.C("flame/ApplicationController", fire.Modifier, fire.Only(fire.Delete), func(ctx *fire.Context) error {
+				_, err := revokeTokens(ctx, ctx.Model.ID())
+				return err
+			}),
+		},
+		Validators: fire.L{
+			fire.ProtectedFieldsValidator(map[string]interface{}{
+				"Key": fire.NoDefault,
+			}),
+		},
+		ResourceActions: fire.M{
+			"revoke-tokens": fire.A("flame/ApplicationController", []string{http.MethodPost}, 0, 0, func(ctx *fire.Context) error {
+				// revoke tokens
+				revoked, err := revokeTokens(ctx, ctx.Model.ID())
+				if err != nil {
+					return err
+				}
+
+				// respond with count
+				err = ctx.Respond(stick.Map{
+					"revoked": revoked,
+				})
+				if err != nil {
+					return err
+				}
+
+				return nil
+			}),
+		},
+	}
+}
+
+func revokeTokens(ctx *fire.Context, application coal.ID) (int64, error) {
+	return ctx.Store.M(&Token{}).DeleteAll(ctx, bson.M{
+		"Application": application,
+	})
+}
@@ -0,0 +1,174 @@
+package flame
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/coal"
+)
+
+func TestTokenController(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		tester.Assign("", TokenController(tester.Store, "admin"))
+		authorize(tester, "admin")
+
+		app1 := tester.Insert(&Application{Name: "app1", Key: "app1"}).ID()
+		app2 := tester.Insert(&Application{Name: "app2", Key: "app2"}).ID()
+
+		token1 := tester.Insert(&Token{
+			Type:        AccessToken,
+			Scope:       []string{"foo"},
+			ExpiresAt:   time.Now().Add(time.Hour),
+			Application: app1,
+		}).ID()
+		tester.Insert(&Token{
+			Type:        RefreshToken,
+			Scope:       []string{"foo"},
+			ExpiresAt:   time.Now().Add(time.Hour),
+			Application: app1,
+		})
+		tester.Insert(&Token{
+			Type:        AccessToken,
+			Scope:       []string{"foo"},
+			ExpiresAt:   time.Now().Add(time.Hour),
+			Application: app2,
+		})
+
+		tester.Request("GET", "tokens?filter[application]="+app1.Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Contains(t, r.Body.String(), token1.Hex())
+		})
+
+		tester.Request("DELETE", "tokens/"+token1.Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNoContent, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+		assert.Equal(t, 2, tester.Count(&Token{}))
+
+		tester.Request("DELETE", "tokens/delete-many?filter[application]="+app1.Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{"meta": {"deleted": 1}}`, r.Body.String())
+		})
+		assert.Equal(t, 1, tester.Count(&Token{}))
+
+		tester.Request("POST", "tokens", `{
+			"data": {
+				"type": "tokens"
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusMethodNotAllowed, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestTokenControllerUnauthorized(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		tester.Assign("", TokenController(tester.Store, "admin"))
+		authorize(tester, "foo")
+
+		tester.Request("GET", "tokens", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusUnauthorized, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+	})
+}
+
+func TestApplicationController(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		tester.Assign("", ApplicationController(tester.Store, "admin"))
+		authorize(tester, "admin")
+
+		var id coal.ID
+		tester.Request("POST", "applications", `{
+			"data": {
+				"type": "applications",
+				"attributes": {
+					"name": "App",
+					"key": "app",
+					"secret": "secret",
+					"redirect-uris": ["https://example.org/callback"]
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.NotContains(t, r.Body.String(), `"secret"`)
+		})
+
+		app := tester.FindLast(&Application{}).(*Application)
+		id = app.ID()
+		assert.True(t, app.IsConfidential())
+		assert.True(t, app.ValidSecret("secret"))
+		assert.True(t, app.ValidRedirectURI("https://example.org/callback"))
+
+		tester.Request("PATCH", "applications/"+id.Hex(), `{
+			"data": {
+				"type": "applications",
+				"id": "`+id.Hex()+`",
+				"attributes": {
+					"redirect-uris": ["https://example.org/callback", "https://example.org/other"]
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		app = tester.Fetch(&Application{}, id).(*Application)
+		assert.True(t, app.ValidRedirectURI("https://example.org/other"))
+
+		tester.Request("PATCH", "applications/"+id.Hex(), `{
+			"data": {
+				"type": "applications",
+				"id": "`+id.Hex()+`",
+				"attributes": {
+					"key": "other"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		for i := 0; i < 2; i++ {
+			tester.Insert(&Token{
+				Type:        AccessToken,
+				Scope:       []string{"foo"},
+				ExpiresAt:   time.Now().Add(time.Hour),
+				Application: id,
+			})
+		}
+
+		tester.Request("POST", "applications/"+id.Hex()+"/revoke-tokens", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{"revoked": 2}`, r.Body.String())
+		})
+		assert.Equal(t, 0, tester.Count(&Token{}))
+
+		tester.Insert(&Token{
+			Type:        AccessToken,
+			Scope:       []string{"foo"},
+			ExpiresAt:   time.Now().Add(time.Hour),
+			Application: id,
+		})
+
+		tester.Request("DELETE", "applications/"+id.Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNoContent, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+		assert.Equal(t, 0, tester.Count(&Application{}))
+		assert.Equal(t, 0, tester.Count(&Token{}))
+	})
+}
+
+func authorize(tester *fire.Tester, scope ...string) {
+	// get handler
+	handler := tester.Handler
+
+	// wrap handler
+	tester.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ClientContextKey, &Application{})
+		ctx = context.WithValue(ctx, AccessTokenContextKey, &Token{Scope: scope})
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,143 @@
+package flame
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+	"github.com/256dpi/xo"
+)
+
+// IntrospectionContextKey is the key used to save the introspection response
+// in a context.
+const IntrospectionContextKey = ctxKey("introspection")
+
+// Introspector authorizes requests on resource servers by verifying access
+// tokens using the introspection endpoint of an authenticator. It does not
+// parse tokens and thus works with signed and opaque tokens.
+type Introspector struct {
+	client       *oauth2.Client
+	clientID     string
+	clientSecret string
+	reporter     func(error)
+}
+
+// NewIntrospector constructs a new introspector that uses the provided client
+// config and client credentials to introspect tokens.
+func NewIntrospector(config oauth2.ClientConfig, clientID, clientSecret string, reporter func(error)) *Introspector {
+	return &Introspector{
+		client:       oauth2.NewClient(config),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		reporter:     reporter,
+	}
+}
+
+// Introspect will introspect the provided token and return the response.
+func (i *Introspector) Introspect(ctx context.Context, token string) (*oauth2.IntrospectionResponse, error) {
+	// trace
+	_, span := xo.Trace(ctx, "flame/Introspector.Introspect")
+	defer span.End()
+
+	// introspect token
+	res, err := i.client.Introspect(oauth2.IntrospectionRequest{
+		Token:         token,
+		TokenTypeHint: oauth2.AccessToken,
+		ClientID:      i.clientID,
+		ClientSecret:  i.clientSecret,
+	})
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	return res, nil
+}
+
+// Authorizer returns a middleware that can be used to authorize a request by
+// requiring an active access token with the provided scope to be granted. The
+// introspection response is stored in the context.
+func (i *Introspector) Authorizer(scope []string, force bool) func(http.Handler) http.Handler {
+	// get scope str
+	scopeStr := oauth2.Scope(scope).String()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// create tracer
+			tracer, rcx := xo.CreateTracer(r.Context(), "flame/Introspector.Authorizer")
+			tracer.Tag("scope", scopeStr)
+			tracer.Tag("force", force)
+			defer tracer.End()
+			r = r.WithContext(rcx)
+
+			// immediately pass on request if force is not set and there is
+			// no authentication information provided
+			if !force && r.Header.Get("Authorization") == "" {
+				// call next handler
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			// continue any previous aborts
+			defer xo.Resume(func(err error) {
+				// directly write bearer errors
+				var bearerError *oauth2.Error
+				if errors.As(err, &bearerError) {
+					_ = oauth2.WriteBearerError(w, bearerError)
+					return
+				}
+
+				// record error
+				tracer.Record(err)
+
+				// otherwise, report critical errors
+				if i.reporter != nil {
+					i.reporter(err)
+				}
+
+				// write generic server error
+				_ = oauth2.WriteBearerError(w, oauth2.ServerError(""))
+			})
+
+			// parse bearer token
+			tk, err := oauth2.ParseBearerToken(r)
+			xo.AbortIf(err)
+
+			// introspect token
+			res, err := i.Introspect(rcx, tk)
+			var introspectionError *oauth2.Error
+			if errors.As(err, &introspectionError) {
+				if introspectionError.Name == oauth2.InvalidRequest("").Name {
+					xo.Abort(oauth2.InvalidToken("malformed bearer token"))
+				}
+				xo.Abort(xo.F("introspection failed: %s", introspectionError.Name))
+			}
+			xo.AbortIf(err)
+
+			// check activity
+			if !res.Active {
+				xo.Abort(oauth2.InvalidToken("inactive bearer token"))
+			}
+
+			// validate token type
+			if res.TokenType != oauth2.AccessToken {
+				xo.Abort(oauth2.InvalidToken("invalid bearer token type"))
+			}
+
+			// validate expiration
+			if res.ExpiresAt != 0 && time.Unix(res.ExpiresAt, 0).Before(time.Now()) {
+				xo.Abort(oauth2.InvalidToken("expired access token"))
+			}
+
+			// validate scope
+			if !res.Scope.Includes(scope) {
+				xo.Abort(oauth2.InsufficientScope(scope))
+			}
+
+			// call next handler
+			next.ServeHTTP(w, r.WithContext(context.WithValue(rcx, IntrospectionContextKey, res)))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package flame
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/heat"
+	"github.com/256dpi/fire/stick"
+)
+
+func TestIntrospector(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		policy := DefaultPolicy(testNotary)
+		policy.Format = OpaqueTokens
+		policy.OpaqueSecret = heat.MustRand(32)
+
+		authenticator := NewAuthenticator(tester.Store, policy, xo.Crash)
+
+		server := httptest.NewServer(authenticator.Endpoint("/oauth2/"))
+		defer server.Close()
+
+		app := tester.Insert(&Application{
+			Name:       "App",
+			Key:        "app",
+			SecretHash: heat.MustHash("secret"),
+		}).(*Application)
+
+		user := tester.Insert(&User{
+			Name:     "User",
+			Email:    "user@example.com",
+			Password: "foo",
+		}).(*User)
+
+		validToken := tester.Insert(&Token{
+			Type:        AccessToken,
+			ExpiresAt:   time.Now().Add(time.Hour),
+			Scope:       []string{"foo"},
+			Application: app.ID(),
+			User:        stick.P(user.ID()),
+		}).(*Token)
+
+		expiredToken := tester.Insert(&Token{
+			Type:        AccessToken,
+			ExpiresAt:   time.Now().Add(-time.Hour),
+			Scope:       []string{"foo"},
+			Application: app.ID(),
+		}).(*Token)
+
+		refreshToken := tester.Insert(&Token{
+			Type:        RefreshToken,
+			ExpiresAt:   time.Now().Add(time.Hour),
+			Scope:       []string{"foo"},
+			Application: app.ID(),
+		}).(*Token)
+
+		introspector := NewIntrospector(oauth2.Default(server.URL), "app", "secret", func(err error) {
+			t.Error(err)
+		})
+
+		res, err := introspector.Introspect(nil, mustIssue(policy, AccessToken, validToken.ID(), validToken.ExpiresAt))
+		assert.NoError(t, err)
+		assert.True(t, res.Active)
+		assert.Equal(t, oauth2.Scope{"foo"}, res.Scope)
+		assert.Equal(t, app.ID().Hex(), res.ClientID)
+		assert.Equal(t, user.ID().Hex(), res.Username)
+		assert.Equal(t, validToken.ID().Hex(), res.Identifier)
+
+		handler := introspector.Authorizer([]string{"foo"}, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := r.Context().Value(IntrospectionContextKey).(*oauth2.IntrospectionResponse)
+			_, _ = w.Write([]byte(res.Identifier))
+		}))
+
+		request := func(token string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api", nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			handler.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := request("")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("foo")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "malformed bearer token")
+
+		rec = request(mustIssue(policy, AccessToken, validToken.ID(), validToken.ExpiresAt))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, validToken.ID().Hex(), rec.Body.String())
+
+		rec = request(mustIssue(policy, AccessToken, expiredToken.ID(), expiredToken.ExpiresAt))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "inactive bearer token")
+
+		rec = request(mustIssue(policy, RefreshToken, refreshToken.ID(), refreshToken.ExpiresAt))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "invalid bearer token type")
+
+		handler = introspector.Authorizer([]string{"bar"}, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		rec = request(mustIssue(policy, AccessToken, validToken.ID(), validToken.ExpiresAt))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,79 @@
+package flame
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer is a function that normalizes a resource owner identifier.
+type Normalizer func(id string) string
+
+// NormalizeSpace trims leading and trailing white space.
+func NormalizeSpace(id string) string {
+	return strings.TrimSpace(id)
+}
+
+// NormalizeUnicode converts the identifier to the unicode normalization form C
+// to ensure that equivalent characters are represented the same.
+func NormalizeUnicode(id string) string {
+	return norm.NFC.String(id)
+}
+
+// NormalizeCase converts the identifier to lower case.
+func NormalizeCase(id string) string {
+	return strings.ToLower(id)
+}
+
+// StripPlusAlias removes the plus alias from the local part of an email
+// address e.g. "joe+news@example.com" becomes "joe@example.com".
+func StripPlusAlias(id string) string {
+	// split address
+	at := strings.LastIndex(id, "@")
+	if at < 0 {
+		return id
+	}
+
+	// strip alias
+	local, domain := id[:at], id[at:]
+	if i := strings.Index(local, "+"); i > 0 {
+		local = local[:i]
+	}
+
+	return local + domain
+}
+
+// Lookup describes how a resource owner is looked up using the identifier
+// provided by a client e.g. the username of a password grant.
+type Lookup struct {
+	// The normalizers applied in order to the identifier before the lookup.
+	// The stored identifiers should be normalized the same way e.g. during
+	// validation.
+	Normalizers []Normalizer
+
+	// The collation used to match the identifier e.g. coal.CaseInsensitive.
+	// The identifier field should be indexed using the same collation with
+	// coal.AddCollatedIndex to enforce uniqueness and use the index.
+	//
+	// Note: Lungo stores do not support collations and ignore them.
+	Collation *options.Collation
+}
+
+// Normalize will apply all normalizers to the provided identifier.
+func (l Lookup) Normalize(id string) string {
+	for _, normalizer := range l.Normalizers {
+		id = normalizer(id)
+	}
+	return id
+}
+
+// LookupResourceOwner may be implemented by resource owners to customize how
+// they are looked up using the field flagged with "flame-resource-owner-id".
+// By default, the identifier is matched exactly.
+type LookupResourceOwner interface {
+	ResourceOwner
+
+	// Lookup should return the lookup strategy.
+	Lookup() Lookup
+}
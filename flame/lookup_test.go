@@ -0,0 +1,105 @@
+package flame
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/256dpi/oauth2/v2/oauth2test"
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/heat"
+	"github.com/256dpi/fire/stick"
+)
+
+type memberModel struct {
+	coal.Base          `json:"-" bson:",inline" coal:"members"`
+	Email              string `json:"email" coal:"flame-resource-owner-id"`
+	PasswordHash       []byte `json:"-" bson:"password"`
+	stick.NoValidation `json:"-" bson:"-"`
+}
+
+func (m *memberModel) ValidPassword(password string) bool {
+	return heat.Compare(m.PasswordHash, password) == nil
+}
+
+func (m *memberModel) Lookup() Lookup {
+	return Lookup{
+		Normalizers: []Normalizer{NormalizeSpace, NormalizeUnicode, NormalizeCase, StripPlusAlias},
+		Collation:   coal.CaseInsensitive("en"),
+	}
+}
+
+func TestNormalizers(t *testing.T) {
+	assert.Equal(t, "foo", NormalizeSpace("  foo \n"))
+	assert.Equal(t, "café", NormalizeUnicode("café"))
+	assert.Equal(t, "joe@example.com", NormalizeCase("Joe@Example.COM"))
+	assert.Equal(t, "joe@example.com", StripPlusAlias("joe+news@example.com"))
+	assert.Equal(t, "+joe@example.com", StripPlusAlias("+joe@example.com"))
+	assert.Equal(t, "joe+news", StripPlusAlias("joe+news"))
+
+	lookup := Lookup{
+		Normalizers: []Normalizer{NormalizeSpace, NormalizeCase, StripPlusAlias},
+	}
+	assert.Equal(t, "joe@example.com", lookup.Normalize(" Joe+News@Example.com "))
+	assert.Equal(t, "Joe", Lookup{}.Normalize("Joe"))
+}
+
+func TestLookupResourceOwner(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		policy := DefaultPolicy(testNotary)
+		policy.Grants = StaticGrants(true, false, false, false, false)
+		policy.ResourceOwners = func(*Context, Client) ([]ResourceOwner, error) {
+			return []ResourceOwner{&memberModel{}}, nil
+		}
+
+		authenticator := NewAuthenticator(tester.Store, policy, xo.Crash)
+		handler := newHandler(authenticator, false)
+
+		application := tester.Insert(&Application{
+			Name: "App",
+			Key:  "application",
+		}).(*Application)
+
+		hash, err := heat.Hash("secret")
+		assert.NoError(t, err)
+
+		tester.Insert(&memberModel{
+			Email:        "caf\u00e9@example.com",
+			PasswordHash: hash,
+		})
+
+		for _, username := range []string{"café@example.com", " Café+News@Example.com "} {
+			oauth2test.Do(handler, &oauth2test.Request{
+				Method:   "POST",
+				Path:     "/oauth2/token",
+				Username: application.Key,
+				Form: map[string]string{
+					"grant_type": "password",
+					"username":   username,
+					"password":   "secret",
+				},
+				Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+					assert.Equal(t, http.StatusOK, r.Code, r.Body.String())
+				},
+			})
+		}
+
+		oauth2test.Do(handler, &oauth2test.Request{
+			Method:   "POST",
+			Path:     "/oauth2/token",
+			Username: application.Key,
+			Form: map[string]string{
+				"grant_type": "password",
+				"username":   "other@example.com",
+				"password":   "secret",
+			},
+			Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusForbidden, r.Code)
+			},
+		})
+	})
+}
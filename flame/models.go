@@ -152,6 +152,7 @@ type Application struct {
 	Secret       string   `json:"secret,omitempty" bson:"-"`
 	SecretHash   []byte   `json:"-" bson:"secret"`
 	RedirectURIs []string `json:"redirect-uris" bson:"redirect_uris"`
+	Workloads    []string `json:"workloads,omitempty" bson:"workloads,omitempty"`
 }
 
 // IsConfidential implements the flame.Client interface.
@@ -164,6 +165,11 @@ func (a *Application) ValidRedirectURI(uri string) bool {
 	return stick.Contains(a.RedirectURIs, uri)
 }
 
+// ValidWorkload implements the flame.WorkloadClient interface.
+func (a *Application) ValidWorkload(subject string) bool {
+	return stick.Contains(a.Workloads, subject)
+}
+
 // ValidSecret implements the flame.Client interface.
 func (a *Application) ValidSecret(secret string) bool {
 	return heat.Compare(a.SecretHash, secret) == nil
@@ -181,6 +187,7 @@ func (a *Application) Validate() error {
 		v.Value("Name", false, stick.IsNotZero, stick.IsValidUTF8)
 		v.Value("Key", false, stick.IsNotZero, stick.IsValidUTF8)
 		v.Items("RedirectURIs", stick.IsNotZero, stick.IsValidUTF8)
+		v.Items("Workloads", stick.IsNotZero, stick.IsValidUTF8)
 	})
 }
 
@@ -208,7 +215,9 @@ func (a *Application) HashSecret() error {
 
 // ResourceOwner is the interface that must be implemented by resource owners.
 // The field used to uniquely identify the resource owner may be flagged with
-// "flame-resource-owner-id". If missing the model ID is used instead.
+// "flame-resource-owner-id". If missing the model ID is used instead. The
+// lookup using the flagged field may be customized by implementing the
+// LookupResourceOwner interface.
 type ResourceOwner interface {
 	coal.Model
 
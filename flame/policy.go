@@ -2,6 +2,9 @@ package flame
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"net/http"
 	"time"
 
@@ -9,6 +12,7 @@ import (
 	"github.com/256dpi/xo"
 	"go.mongodb.org/mongo-driver/bson"
 
+	"github.com/256dpi/fire/coal"
 	"github.com/256dpi/fire/heat"
 	"github.com/256dpi/fire/stick"
 )
@@ -33,6 +37,20 @@ var ErrApprovalRejected = xo.BF("approval rejected")
 // requested scope exceeds the grantable scope.
 var ErrInvalidScope = xo.BF("invalid scope")
 
+// TokenFormat defines the format of issued tokens and codes.
+type TokenFormat int
+
+const (
+	// SignedTokens are self-contained JWT tokens issued by the notary that
+	// include the token ID, expiry and token data.
+	SignedTokens TokenFormat = iota
+
+	// OpaqueTokens are reference tokens that only encode the token ID and a
+	// MAC. All other data is kept server-side and resource servers should use
+	// token introspection to verify them (see Introspector).
+	OpaqueTokens
+)
+
 // Key is they key used to issue and verify tokens and codes.
 type Key struct {
 	heat.Base `json:"-" heat:"flame/key,1h"`
@@ -50,6 +68,7 @@ type Grants struct {
 	Implicit          bool
 	AuthorizationCode bool
 	RefreshToken      bool
+	WorkloadIdentity  bool
 }
 
 // A Context provides useful contextual information.
@@ -65,11 +84,21 @@ type Context struct {
 	// Usage: Read Only
 	Request *http.Request
 
+	// The IP of the client as determined using the trusted proxies.
+	//
+	// Usage: Read Only
+	ClientIP string
+
 	// The current tracer.
 	//
 	// Usage: Read Only
 	Tracer *xo.Tracer
 
+	// The verified workload identity in workload identity grants.
+	//
+	// Usage: Read Only
+	Workload *WorkloadIdentity
+
 	writer http.ResponseWriter
 	grants Grants
 }
@@ -80,12 +109,34 @@ type Policy struct {
 	// The notary used to issue and verify tokens and codes.
 	Notary *heat.Notary
 
+	// The format of issued tokens and codes.
+	//
+	// Default: SignedTokens.
+	Format TokenFormat
+
+	// The secret used to authenticate opaque tokens. It must be at least 16
+	// bytes long if opaque tokens are used.
+	OpaqueSecret heat.Secret
+
 	// The token model.
 	Token GenericToken
 
 	// The client models.
 	Clients []Client
 
+	// The proxies that are trusted to report the client IP using the
+	// TrustedHeader. The determined client IP is available from the context
+	// and the auth info and should be used for rate limiting, lockouts and
+	// audit logging.
+	TrustedProxies TrustedProxies
+
+	// The header that is set by the trusted proxies to report the client IP.
+	// Either "X-Forwarded-For", "Forwarded" or a custom header that carries a
+	// comma separated address list like "X-Real-IP". Only this header is read.
+	//
+	// Default: "X-Forwarded-For".
+	TrustedHeader string
+
 	// Grants should return the permitted grants for the provided client.
 	Grants func(ctx *Context, c Client) (Grants, error)
 
@@ -117,9 +168,16 @@ type Policy struct {
 	// callback should return the scope that should be granted. It can return
 	// ErrGrantRejected or ErrInvalidScope to cancel the grant request.
 	//
-	// Note: ResourceOwner is not set for a client credentials grant.
+	// Note: ResourceOwner is not set for client credentials and workload
+	// identity grants. The verified workload identity is available from the
+	// context in workload identity grants.
 	GrantStrategy func(ctx *Context, c Client, ro ResourceOwner, scope oauth2.Scope) (oauth2.Scope, error)
 
+	// The verifier used to verify workload identity tokens exchanged using the
+	// workload identity grant. The client must implement WorkloadClient to
+	// permit the verified workload subject.
+	WorkloadVerifier *WorkloadVerifier
+
 	// The URL to the page that obtains the approval of the user in implicit and
 	// authorization code grants.
 	ApprovalURL func(ctx *Context, c Client) (string, error)
@@ -142,6 +200,19 @@ type Policy struct {
 	// introspection's response "extra" field.
 	TokenData func(c Client, ro ResourceOwner, token GenericToken) map[string]interface{}
 
+	// TokenInfo is invoked by the authorizer after an access token has been
+	// verified and may return additional information (e.g. roles, tenant or
+	// permissions) about the request. The info is loaded once per request,
+	// stored in the context and made available via the auth info.
+	//
+	// Note: The client and resource owner are only provided if they have
+	// been loaded by the authorizer.
+	TokenInfo func(ctx *Context, c Client, ro ResourceOwner, token GenericToken) (stick.Map, error)
+
+	// The optional recorder that samples the scopes exercised by clients in
+	// requests authorized by the authorizer.
+	ScopeUsage *ScopeUsage
+
 	// The token and code lifespans.
 	AccessTokenLifespan       time.Duration
 	RefreshTokenLifespan      time.Duration
@@ -232,8 +303,13 @@ func DefaultPolicy(notary *heat.Notary) *Policy {
 	}
 }
 
-// Issue will issue a JWT token based on the provided information.
+// Issue will issue a JWT or opaque token based on the provided information.
 func (p *Policy) Issue(ctx context.Context, token GenericToken, client Client, resourceOwner ResourceOwner) (string, error) {
+	// handle opaque tokens
+	if p.Format == OpaqueTokens {
+		return p.issueOpaque(token.ID())
+	}
+
 	// get data
 	data := token.GetTokenData()
 
@@ -268,8 +344,15 @@ func (p *Policy) Issue(ctx context.Context, token GenericToken, client Client, r
 	return str, nil
 }
 
-// Verify will verify the presented token and return the decoded raw key.
+// Verify will verify the presented token and return the decoded raw key. For
+// opaque tokens only the key ID is set and the expiry must be checked using
+// the stored token.
 func (p *Policy) Verify(ctx context.Context, str string) (*Key, error) {
+	// handle opaque tokens
+	if p.Format == OpaqueTokens {
+		return p.verifyOpaque(str)
+	}
+
 	// parse token and check expired errors
 	var key Key
 	err := p.Notary.Verify(ctx, &key, str)
@@ -279,3 +362,49 @@ func (p *Policy) Verify(ctx context.Context, str string) (*Key, error) {
 
 	return &key, nil
 }
+
+func (p *Policy) issueOpaque(id coal.ID) (string, error) {
+	// check secret
+	if len(p.OpaqueSecret) < 16 {
+		return "", xo.F("opaque secret too small")
+	}
+
+	// compute token
+	buf := append(id[:], p.opaqueMAC(id)...)
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (p *Policy) verifyOpaque(str string) (*Key, error) {
+	// check secret
+	if len(p.OpaqueSecret) < 16 {
+		return nil, xo.F("opaque secret too small")
+	}
+
+	// decode token
+	buf, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil || len(buf) != len(coal.ID{})+sha256.Size {
+		return nil, heat.ErrInvalidToken.Wrap()
+	}
+
+	// get ID
+	var id coal.ID
+	copy(id[:], buf)
+
+	// verify MAC
+	if !hmac.Equal(buf[len(id):], p.opaqueMAC(id)) {
+		return nil, heat.ErrInvalidToken.Wrap()
+	}
+
+	return &Key{
+		Base: heat.Base{
+			ID: id,
+		},
+	}, nil
+}
+
+func (p *Policy) opaqueMAC(id coal.ID) []byte {
+	mac := hmac.New(sha256.New, p.OpaqueSecret)
+	mac.Write(id[:])
+	return mac.Sum(nil)
+}
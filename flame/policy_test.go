@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/heat"
 	"github.com/256dpi/fire/stick"
 )
 
@@ -34,3 +35,45 @@ func TestPolicyIssueAndVerify(t *testing.T) {
 		"name": "Hello",
 	}, key.Extra)
 }
+
+func TestPolicyOpaqueTokens(t *testing.T) {
+	p := DefaultPolicy(testNotary)
+	p.Format = OpaqueTokens
+
+	token := &Token{
+		Base:      coal.B(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	_, err := p.Issue(nil, token, nil, &User{Name: "Hello"})
+	assert.Error(t, err)
+	assert.Equal(t, "opaque secret too small", err.Error())
+
+	p.OpaqueSecret = heat.MustRand(32)
+
+	sig, err := p.Issue(nil, token, nil, &User{Name: "Hello"})
+	assert.NoError(t, err)
+	assert.NotContains(t, sig, ".")
+
+	key, err := p.Verify(nil, sig)
+	assert.NoError(t, err)
+	assert.Equal(t, token.ID(), key.ID)
+	assert.True(t, key.Expires.IsZero())
+	assert.Nil(t, key.Extra)
+
+	_, err = p.Verify(nil, "foo")
+	assert.True(t, heat.ErrInvalidToken.Is(err))
+
+	other := DefaultPolicy(testNotary)
+	other.Format = OpaqueTokens
+	other.OpaqueSecret = heat.MustRand(32)
+
+	_, err = other.Verify(nil, sig)
+	assert.True(t, heat.ErrInvalidToken.Is(err))
+
+	signed, err := DefaultPolicy(testNotary).Issue(nil, token, nil, &User{Name: "Hello"})
+	assert.NoError(t, err)
+
+	_, err = p.Verify(nil, signed)
+	assert.True(t, heat.ErrInvalidToken.Is(err))
+}
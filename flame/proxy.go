@@ -0,0 +1,165 @@
+package flame
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/256dpi/xo"
+)
+
+// TrustedProxies is a list of networks from which proxies are trusted to
+// report the client IP using a forwarding header.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies will parse the provided CIDR ranges or plain IP addresses
+// and return the list of trusted proxy networks.
+func ParseTrustedProxies(list ...string) (TrustedProxies, error) {
+	// prepare networks
+	networks := make(TrustedProxies, 0, len(list))
+
+	// parse networks
+	for _, item := range list {
+		// handle plain addresses
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, xo.F("invalid trusted proxy address: %s", item)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{
+				IP:   ip,
+				Mask: net.CIDRMask(bits, bits),
+			})
+			continue
+		}
+
+		// parse network
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, xo.W(err)
+		}
+
+		// add network
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+// MustParseTrustedProxies will call ParseTrustedProxies and panic on errors.
+func MustParseTrustedProxies(list ...string) TrustedProxies {
+	// parse proxies
+	proxies, err := ParseTrustedProxies(list...)
+	if err != nil {
+		panic(err)
+	}
+
+	return proxies
+}
+
+// Contains returns whether the provided IP is a trusted proxy.
+func (p TrustedProxies) Contains(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the IP of the client that issued the request. If the
+// request has been received from a trusted proxy, the forwarded addresses of
+// the specified header are traversed from the nearest to the farthest hop and
+// the first address that is not a trusted proxy is returned. The header may be
+// "X-Forwarded-For" (default), "Forwarded" or any other header that carries a
+// comma separated address list. It should be the one that is set by the trusted
+// proxies, as other headers may be spoofed by clients. If
+// no proxies are trusted, the remote address of the request is returned.
+func (p TrustedProxies) ClientIP(r *http.Request, header string) string {
+	// get remote address
+	remote := parseAddress(r.RemoteAddr)
+	if remote == nil {
+		return r.RemoteAddr
+	}
+
+	// return remote address if not a trusted proxy
+	if !p.Contains(remote) {
+		return remote.String()
+	}
+
+	// get forwarded addresses
+	addresses := forwardedAddresses(r.Header, header)
+
+	// traverse addresses starting with the nearest hop
+	client := remote
+	for i := len(addresses) - 1; i >= 0; i-- {
+		// parse address, stop on invalid or obfuscated addresses
+		ip := parseAddress(addresses[i])
+		if ip == nil {
+			break
+		}
+
+		// set client
+		client = ip
+
+		// stop if not a trusted proxy
+		if !p.Contains(ip) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+func forwardedAddresses(header http.Header, name string) []string {
+	// prepare list
+	var list []string
+
+	// parse "Forwarded" headers
+	if strings.EqualFold(name, "Forwarded") {
+		for _, value := range header.Values("Forwarded") {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+					if ok && strings.EqualFold(key, "for") {
+						list = append(list, strings.Trim(value, `"`))
+					}
+				}
+			}
+		}
+
+		return list
+	}
+
+	// set default name
+	if name == "" {
+		name = "X-Forwarded-For"
+	}
+
+	// parse address list headers
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+
+	return list
+}
+
+func parseAddress(addr string) net.IP {
+	// remove port
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	// remove brackets
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+
+	return net.ParseIP(addr)
+}
@@ -0,0 +1,168 @@
+package flame
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8", "192.168.1.1", "fd00::/8", "::1")
+	assert.NoError(t, err)
+	assert.Len(t, proxies, 4)
+
+	assert.True(t, proxies.Contains(net.ParseIP("10.1.2.3")))
+	assert.True(t, proxies.Contains(net.ParseIP("192.168.1.1")))
+	assert.False(t, proxies.Contains(net.ParseIP("192.168.1.2")))
+	assert.True(t, proxies.Contains(net.ParseIP("fd12::1")))
+	assert.True(t, proxies.Contains(net.ParseIP("::1")))
+	assert.False(t, proxies.Contains(net.ParseIP("2001:db8::1")))
+
+	_, err = ParseTrustedProxies("foo")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid trusted proxy address: foo", err.Error())
+
+	_, err = ParseTrustedProxies("10.0.0.0/33")
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		MustParseTrustedProxies("foo")
+	})
+}
+
+func TestTrustedProxiesClientIP(t *testing.T) {
+	proxies := MustParseTrustedProxies("10.0.0.0/8")
+
+	table := []struct {
+		proxies TrustedProxies
+		header  string
+		remote  string
+		headers map[string]string
+		result  string
+	}{
+		// direct request
+		{
+			proxies: proxies,
+			remote:  "1.2.3.4:1234",
+			result:  "1.2.3.4",
+		},
+		// untrusted proxy
+		{
+			proxies: proxies,
+			remote:  "1.2.3.4:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			result: "1.2.3.4",
+		},
+		// no trusted proxies
+		{
+			remote: "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			result: "10.0.0.1",
+		},
+		// trusted proxy
+		{
+			proxies: proxies,
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			result: "5.6.7.8",
+		},
+		// spoofed chain
+		{
+			proxies: proxies,
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "9.9.9.9, 5.6.7.8, 10.0.0.2",
+			},
+			result: "5.6.7.8",
+		},
+		// only trusted proxies
+		{
+			proxies: proxies,
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "10.0.0.3, 10.0.0.2",
+			},
+			result: "10.0.0.3",
+		},
+		// invalid address
+		{
+			proxies: proxies,
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "5.6.7.8, foo, 10.0.0.2",
+			},
+			result: "10.0.0.2",
+		},
+		// spoofed forwarded header
+		{
+			proxies: proxies,
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"Forwarded":       `for=9.9.9.9`,
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			result: "5.6.7.8",
+		},
+		// forwarded header
+		{
+			proxies: proxies,
+			header:  "Forwarded",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"Forwarded":       `for=9.9.9.9, for="[2001:db8::1]:4711";proto=https, For=10.0.0.2`,
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			result: "2001:db8::1",
+		},
+		// spoofed address list header
+		{
+			proxies: proxies,
+			header:  "Forwarded",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "5.6.7.8",
+			},
+			result: "10.0.0.1",
+		},
+		// custom header
+		{
+			proxies: proxies,
+			header:  "X-Real-IP",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Real-IP":       "5.6.7.8",
+				"X-Forwarded-For": "9.9.9.9",
+			},
+			result: "5.6.7.8",
+		},
+		// forwarded header with obfuscated address
+		{
+			proxies: proxies,
+			header:  "Forwarded",
+			remote:  "10.0.0.1:1234",
+			headers: map[string]string{
+				"Forwarded": `for=_hidden;by=10.0.0.1`,
+			},
+			result: "10.0.0.1",
+		},
+	}
+
+	for i, item := range table {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = item.remote
+		for key, value := range item.headers {
+			req.Header.Set(key, value)
+		}
+
+		assert.Equal(t, item.result, item.proxies.ClientIP(req, item.header), i)
+	}
+}
@@ -0,0 +1,205 @@
+package flame
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/256dpi/xo"
+
+	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/heat"
+	"github.com/256dpi/fire/stick"
+)
+
+// Revocation is the signed message published when a token has been revoked.
+type Revocation struct {
+	heat.Base `json:"-" heat:"flame/revocation,1h"`
+
+	// The ID of the revoked token.
+	Token coal.ID `json:"token"`
+
+	stick.NoValidation `json:"-"`
+}
+
+// RevocationPublisher tails the change stream of a token model and publishes
+// a signed revocation message for every deleted token. This allows resource
+// servers that verify tokens offline to maintain a denylist of revoked tokens
+// using a Denylist.
+//
+// Note: Tokens deleted while the publisher is not running are not published.
+type RevocationPublisher struct {
+	stream *coal.Stream
+	closed chan struct{}
+	once   sync.Once
+}
+
+// PublishRevocations will open a revocation publisher that uses the provided
+// notary to sign and the provided publisher to publish revocation messages to
+// the specified subject. Failed messages are published again after a delay.
+func PublishRevocations(store *coal.Store, token GenericToken, notary *heat.Notary, publisher coal.Publisher, subject string, reporter func(error)) *RevocationPublisher {
+	// create publisher
+	p := &RevocationPublisher{
+		closed: make(chan struct{}),
+	}
+
+	// open stream
+	p.stream = coal.OpenStream(store, token, nil, func(event coal.Event, id coal.ID, _ coal.Model, err error, _ []byte) error {
+		switch event {
+		case coal.Deleted:
+			// sign revocation
+			msg, err := notary.Issue(context.Background(), &Revocation{
+				Token: id,
+			})
+			if err != nil {
+				return err
+			}
+
+			// publish revocation
+			err = publisher.Publish(context.Background(), subject, id.Hex(), []byte(msg))
+			if err != nil {
+				return err
+			}
+		case coal.Errored:
+			// report error
+			if reporter != nil {
+				reporter(err)
+			}
+
+			// delay resumption
+			select {
+			case <-time.After(time.Second):
+			case <-p.closed:
+				return coal.ErrStop.Wrap()
+			}
+		}
+
+		return nil
+	})
+
+	return p
+}
+
+// Close will close the publisher.
+func (p *RevocationPublisher) Close() {
+	// signal close
+	p.once.Do(func() {
+		close(p.closed)
+	})
+
+	// close stream
+	p.stream.Close()
+}
+
+// Denylist tracks revoked tokens on resource servers that verify tokens
+// offline. Revoked tokens are retained for the configured lifespan, which
+// should be at least as long as the access token lifespan of the policy.
+type Denylist struct {
+	notary   *heat.Notary
+	lifespan time.Duration
+
+	mutex   sync.RWMutex
+	entries map[coal.ID]time.Time
+	pruned  time.Time
+}
+
+// NewDenylist creates and returns a new denylist that uses the provided notary
+// to verify revocation messages.
+func NewDenylist(notary *heat.Notary, lifespan time.Duration) *Denylist {
+	return &Denylist{
+		notary:   notary,
+		lifespan: lifespan,
+		entries:  map[coal.ID]time.Time{},
+		pruned:   time.Now(),
+	}
+}
+
+// Receive will verify the provided revocation message and add the revoked
+// token to the denylist.
+func (d *Denylist) Receive(ctx context.Context, msg []byte) error {
+	// verify revocation
+	var revocation Revocation
+	err := d.notary.Verify(ctx, &revocation, string(msg))
+	if err != nil {
+		return err
+	}
+
+	// check token
+	if revocation.Token.IsZero() {
+		return xo.F("missing token")
+	}
+
+	// add token
+	d.Add(revocation.Token)
+
+	return nil
+}
+
+// Add will add the specified token to the denylist.
+func (d *Denylist) Add(id coal.ID) {
+	// acquire mutex
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// get time
+	now := time.Now()
+
+	// add entry
+	d.entries[id] = now.Add(d.lifespan)
+
+	// prune expired entries
+	if now.Sub(d.pruned) > d.lifespan {
+		d.prune(now)
+	}
+}
+
+// Revoked returns whether the specified token has been revoked.
+func (d *Denylist) Revoked(id coal.ID) bool {
+	// acquire mutex
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	// check entry
+	expires, ok := d.entries[id]
+
+	return ok && time.Now().Before(expires)
+}
+
+// Check will verify the provided token using the specified policy and return
+// the decoded key if the token has not been revoked.
+func (d *Denylist) Check(ctx context.Context, policy *Policy, token string) (*Key, error) {
+	// verify token
+	key, err := policy.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// check revocation
+	if d.Revoked(key.Base.ID) {
+		return nil, heat.ErrInvalidToken.Wrap()
+	}
+
+	return key, nil
+}
+
+// Prune will remove all expired entries.
+func (d *Denylist) Prune() {
+	// acquire mutex
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// prune entries
+	d.prune(time.Now())
+}
+
+func (d *Denylist) prune(now time.Time) {
+	// remove expired entries
+	for id, expires := range d.entries {
+		if !now.Before(expires) {
+			delete(d.entries, id)
+		}
+	}
+
+	// set time
+	d.pruned = now
+}
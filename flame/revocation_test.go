@@ -0,0 +1,94 @@
+package flame
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/heat"
+)
+
+type testPublisher struct {
+	messages chan []byte
+}
+
+func (p *testPublisher) Publish(_ context.Context, subject, key string, payload []byte) error {
+	p.messages <- payload
+	return nil
+}
+
+func TestRevocations(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		policy := DefaultPolicy(testNotary)
+
+		publisher := &testPublisher{
+			messages: make(chan []byte, 10),
+		}
+
+		p := PublishRevocations(tester.Store, &Token{}, testNotary, publisher, "revocations", nil)
+		defer p.Close()
+
+		time.Sleep(100 * time.Millisecond)
+
+		token := tester.Insert(&Token{
+			Type:        AccessToken,
+			ExpiresAt:   time.Now().Add(time.Hour),
+			Application: coal.New(),
+		}).(*Token)
+
+		str, err := policy.Issue(nil, token, nil, nil)
+		assert.NoError(t, err)
+
+		denylist := NewDenylist(testNotary, time.Hour)
+
+		key, err := denylist.Check(nil, policy, str)
+		assert.NoError(t, err)
+		assert.Equal(t, token.ID(), key.Base.ID)
+
+		found, err := tester.Store.M(&Token{}).Delete(nil, nil, token.ID())
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		var msg []byte
+		select {
+		case msg = <-publisher.messages:
+		case <-time.After(time.Second):
+			t.Error("missing message")
+		}
+
+		err = denylist.Receive(nil, msg)
+		assert.NoError(t, err)
+		assert.True(t, denylist.Revoked(token.ID()))
+		assert.False(t, denylist.Revoked(coal.New()))
+
+		key, err = denylist.Check(nil, policy, str)
+		assert.True(t, heat.ErrInvalidToken.Is(err))
+		assert.Nil(t, key)
+
+		err = NewDenylist(heat.NewNotary("test", heat.MustRand(32)), time.Hour).Receive(nil, msg)
+		assert.Error(t, err)
+	})
+}
+
+func TestDenylistPrune(t *testing.T) {
+	denylist := NewDenylist(testNotary, 10*time.Millisecond)
+
+	id1 := coal.New()
+	denylist.Add(id1)
+	assert.True(t, denylist.Revoked(id1))
+
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, denylist.Revoked(id1))
+
+	id2 := coal.New()
+	denylist.Add(id2)
+	assert.Len(t, denylist.entries, 1)
+
+	time.Sleep(20 * time.Millisecond)
+	denylist.Prune()
+	assert.Empty(t, denylist.entries)
+}
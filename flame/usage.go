@@ -0,0 +1,175 @@
+package flame
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+
+	"github.com/256dpi/fire/coal"
+)
+
+// ScopeUsage records the scopes exercised by clients in authorized requests.
+// The recorded data helps operators to tighten over-broad scope grants.
+type ScopeUsage struct {
+	rate    float64
+	mutex   sync.Mutex
+	clients map[coal.ID]*clientUsage
+	since   time.Time
+}
+
+type clientUsage struct {
+	granted  map[string]bool
+	used     map[string]int64
+	requests int64
+	last     time.Time
+}
+
+// ClientScopeReport describes the scope usage of a single client.
+type ClientScopeReport struct {
+	// The client ID.
+	Client coal.ID
+
+	// The estimated number of authorized requests.
+	Requests int64
+
+	// The scopes granted to the tokens of the client.
+	Granted []string
+
+	// The estimated number of requests that required each scope.
+	Used map[string]int64
+
+	// The granted scopes that have not been required by any request.
+	Unused []string
+
+	// The time of the last recorded request.
+	LastSeen time.Time
+}
+
+// NewScopeUsage creates and returns a new scope usage recorder that samples
+// the provided rate of requests e.g. 0.1 for ten percent. Counters are scaled
+// by the rate and are therefore estimates. A rate of zero or above one records
+// all requests.
+func NewScopeUsage(rate float64) *ScopeUsage {
+	// check rate
+	if rate <= 0 || rate > 1 {
+		rate = 1
+	}
+
+	return &ScopeUsage{
+		rate:    rate,
+		clients: map[coal.ID]*clientUsage{},
+		since:   time.Now(),
+	}
+}
+
+// Record will record the use of the required scope by the specified client
+// using a token with the granted scope. The authorizer records every sampled
+// request, and callbacks and include gates additionally record the scope they
+// require for those requests.
+func (u *ScopeUsage) Record(client coal.ID, granted, required oauth2.Scope) {
+	// sample request
+	if !u.sample() {
+		return
+	}
+
+	// record request
+	u.record(client, granted, required, true)
+}
+
+func (u *ScopeUsage) sample() bool {
+	return u.rate >= 1 || rand.Float64() < u.rate
+}
+
+func (u *ScopeUsage) record(client coal.ID, granted, required oauth2.Scope, request bool) {
+	// acquire mutex
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	// get usage
+	usage := u.clients[client]
+	if usage == nil {
+		usage = &clientUsage{
+			granted: map[string]bool{},
+			used:    map[string]int64{},
+		}
+		u.clients[client] = usage
+	}
+
+	// update usage
+	if request {
+		usage.requests++
+	}
+	usage.last = time.Now()
+	for _, scope := range granted {
+		usage.granted[scope] = true
+	}
+	for _, scope := range required {
+		usage.used[scope]++
+	}
+}
+
+// Report will return a report for each recorded client ordered by client ID
+// and the time since when usage has been recorded.
+func (u *ScopeUsage) Report() ([]ClientScopeReport, time.Time) {
+	// acquire mutex
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	// prepare reports
+	reports := make([]ClientScopeReport, 0, len(u.clients))
+	for id, usage := range u.clients {
+		// prepare report
+		report := ClientScopeReport{
+			Client:   id,
+			Requests: u.scale(usage.requests),
+			Used:     make(map[string]int64, len(usage.used)),
+			LastSeen: usage.last,
+		}
+
+		// add granted scopes
+		for scope := range usage.granted {
+			report.Granted = append(report.Granted, scope)
+		}
+		sort.Strings(report.Granted)
+
+		// add used scopes
+		for scope, count := range usage.used {
+			report.Used[scope] = u.scale(count)
+		}
+
+		// collect unused scopes
+		for _, scope := range report.Granted {
+			if usage.used[scope] == 0 {
+				report.Unused = append(report.Unused, scope)
+			}
+		}
+
+		// add report
+		reports = append(reports, report)
+	}
+
+	// sort reports
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Client.Hex() < reports[j].Client.Hex()
+	})
+
+	return reports, u.since
+}
+
+// Reset will clear all recorded usage.
+func (u *ScopeUsage) Reset() {
+	// acquire mutex
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	// reset usage
+	u.clients = map[coal.ID]*clientUsage{}
+	u.since = time.Now()
+}
+
+func (u *ScopeUsage) scale(count int64) int64 {
+	return int64(float64(count) / u.rate)
+}
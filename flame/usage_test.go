@@ -0,0 +1,121 @@
+package flame
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire"
+	"github.com/256dpi/fire/coal"
+)
+
+func TestScopeUsage(t *testing.T) {
+	usage := NewScopeUsage(1)
+
+	client1 := coal.MustFromHex("000000000000000000000001")
+	client2 := coal.MustFromHex("000000000000000000000002")
+
+	usage.Record(client1, oauth2.Scope{"foo", "bar"}, oauth2.Scope{"foo"})
+	usage.Record(client1, oauth2.Scope{"foo", "bar"}, oauth2.Scope{"foo"})
+	usage.Record(client2, oauth2.Scope{"baz"}, nil)
+
+	reports, since := usage.Report()
+	assert.False(t, since.IsZero())
+	assert.Len(t, reports, 2)
+	assert.Equal(t, client1, reports[0].Client)
+	assert.Equal(t, int64(2), reports[0].Requests)
+	assert.Equal(t, []string{"bar", "foo"}, reports[0].Granted)
+	assert.Equal(t, map[string]int64{"foo": 2}, reports[0].Used)
+	assert.Equal(t, []string{"bar"}, reports[0].Unused)
+	assert.Equal(t, client2, reports[1].Client)
+	assert.Equal(t, int64(1), reports[1].Requests)
+	assert.Equal(t, []string{"baz"}, reports[1].Unused)
+
+	usage.Reset()
+	reports, _ = usage.Report()
+	assert.Empty(t, reports)
+
+	// sampled counters are scaled
+	usage = NewScopeUsage(0.5)
+	for i := 0; i < 1000; i++ {
+		usage.Record(client1, oauth2.Scope{"foo"}, oauth2.Scope{"foo"})
+	}
+	reports, _ = usage.Report()
+	assert.InDelta(t, 1000, reports[0].Requests, 200)
+	assert.Equal(t, reports[0].Requests, reports[0].Used["foo"])
+}
+
+func TestAuthorizerScopeUsage(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		policy := DefaultPolicy(testNotary)
+		policy.ScopeUsage = NewScopeUsage(1)
+
+		authenticator := NewAuthenticator(tester.Store, policy, xo.Crash)
+		tester.Handler = newHandler(authenticator, false)
+
+		application := tester.Insert(&Application{
+			Name: "App",
+			Key:  "application",
+		}).(*Application).ID()
+
+		accessToken := tester.Insert(&Token{
+			Type:        AccessToken,
+			Scope:       []string{"foo", "bar"},
+			ExpiresAt:   time.Now().Add(authenticator.policy.AccessTokenLifespan),
+			Application: application,
+		}).(*Token).ID()
+
+		token := mustIssue(authenticator.policy, AccessToken, accessToken, time.Now().Add(time.Hour))
+
+		auth := authenticator.Authorizer([]string{"foo"}, true, false, false)
+
+		tester.Handler.(*http.ServeMux).Handle("/api/usage", auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+		tester.Header["Authorization"] = "Bearer " + token
+		tester.Request("GET", "api/usage", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Code, tester.DebugRequest(rq, r))
+		})
+
+		reports, _ := policy.ScopeUsage.Report()
+		assert.Len(t, reports, 1)
+		assert.Equal(t, application, reports[0].Client)
+		assert.Equal(t, map[string]int64{"foo": 1}, reports[0].Used)
+		assert.Equal(t, []string{"bar"}, reports[0].Unused)
+	})
+}
+
+func TestCallbackScopeUsage(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		usage := NewScopeUsage(1)
+
+		client := &Application{
+			Base: coal.B(),
+			Name: "app",
+		}
+
+		tester.Context = context.WithValue(tester.Context, ClientContextKey, client)
+		tester.Context = context.WithValue(tester.Context, AccessTokenContextKey, &Token{
+			Scope:       []string{"foo", "bar", "baz"},
+			Application: client.ID(),
+		})
+		tester.Context = context.WithValue(tester.Context, scopeUsageContextKey, usage)
+
+		err := tester.RunCallback(&fire.Context{}, Callback(true, "foo"))
+		assert.NoError(t, err)
+
+		assert.True(t, IncludeGate("bar")(&fire.Context{Context: tester.Context}))
+		assert.False(t, IncludeGate("qux")(&fire.Context{Context: tester.Context}))
+
+		reports, _ := usage.Report()
+		assert.Len(t, reports, 1)
+		assert.Equal(t, client.ID(), reports[0].Client)
+		assert.Equal(t, int64(0), reports[0].Requests)
+		assert.Equal(t, map[string]int64{"foo": 1, "bar": 1}, reports[0].Used)
+	})
+}
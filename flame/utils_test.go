@@ -11,10 +11,10 @@ import (
 	"github.com/256dpi/fire/heat"
 )
 
-var mongoStore = coal.MustConnect("mongodb://0.0.0.0/test-fire-flame", xo.Crash)
+var mongoStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire-flame", xo.Crash)
 var lungoStore = coal.MustOpen(nil, "test-fire-flame", xo.Crash)
 
-var modelList = []coal.Model{&User{}, &Application{}, &Token{}}
+var modelList = []coal.Model{&User{}, &Application{}, &Token{}, &memberModel{}}
 
 var testNotary = heat.NewNotary("test", heat.MustRand(32))
 
@@ -24,6 +24,10 @@ func init() {
 
 func withTester(t *testing.T, fn func(*testing.T, *fire.Tester)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		tester := fire.NewTester(mongoStore, modelList...)
 		tester.Clean()
 		fn(t, tester)
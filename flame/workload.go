@@ -0,0 +1,255 @@
+package flame
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/256dpi/xo"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// WorkloadGrantType is the grant type used to exchange a workload identity
+// token for an access token (RFC 7523).
+const WorkloadGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// ErrInvalidWorkload is returned by the WorkloadVerifier if the workload
+// identity token is invalid.
+var ErrInvalidWorkload = xo.BF("invalid workload")
+
+var workloadParser = jwt.NewParser(jwt.WithValidMethods([]string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+}))
+
+// WorkloadIdentity is a verified workload identity.
+type WorkloadIdentity struct {
+	// The issuer of the token e.g. the Kubernetes API server.
+	Issuer string
+
+	// The subject of the token e.g. "system:serviceaccount:default:api" or
+	// "spiffe://example.com/ns/default/sa/api".
+	Subject string
+
+	// The audience of the token.
+	Audience []string
+
+	// All token claims.
+	Claims jwt.MapClaims
+}
+
+// WorkloadClient may be implemented by clients to allow workload identity
+// grants for the specified workload subjects.
+type WorkloadClient interface {
+	Client
+
+	// ValidWorkload should return whether the specified workload subject may
+	// obtain access tokens for this client.
+	ValidWorkload(subject string) bool
+}
+
+// WorkloadVerifier verifies workload identity tokens like Kubernetes service
+// account tokens or SPIFFE JWT-SVIDs.
+type WorkloadVerifier struct {
+	// The expected issuer. Any issuer is accepted if empty.
+	Issuer string
+
+	// The expected audience.
+	Audience string
+
+	// The optional SPIFFE trust domain e.g. "example.com". If set, only
+	// subjects with a "spiffe://example.com/" prefix are accepted.
+	TrustDomain string
+
+	// The public keys by key ID used to verify tokens e.g. as parsed from
+	// the JWKS of the Kubernetes API server or a SPIFFE bundle.
+	Keys map[string]crypto.PublicKey
+}
+
+// Verify will verify the provided token and return the workload identity.
+// Tokens must expire and be issued for the configured audience, which rejects
+// long-lived legacy Kubernetes service account tokens.
+func (v *WorkloadVerifier) Verify(token string) (*WorkloadIdentity, error) {
+	// check audience
+	if v.Audience == "" {
+		return nil, xo.F("missing audience")
+	}
+
+	// parse token
+	var claims jwt.MapClaims
+	_, err := workloadParser.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		// get key ID
+		kid, _ := token.Header["kid"].(string)
+
+		// get key
+		key, ok := v.Keys[kid]
+		if !ok {
+			return nil, xo.F("unknown key")
+		}
+
+		return key, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidWorkload.Wrap()
+	}
+
+	// check expiry
+	if _, ok := claims["exp"]; !ok {
+		return nil, ErrInvalidWorkload.Wrap()
+	}
+
+	// check audience
+	if !claims.VerifyAudience(v.Audience, true) {
+		return nil, ErrInvalidWorkload.Wrap()
+	}
+
+	// check issuer
+	if v.Issuer != "" && !claims.VerifyIssuer(v.Issuer, true) {
+		return nil, ErrInvalidWorkload.Wrap()
+	}
+
+	// check subject
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, ErrInvalidWorkload.Wrap()
+	}
+
+	// check trust domain
+	if v.TrustDomain != "" && !strings.HasPrefix(subject, "spiffe://"+v.TrustDomain+"/") {
+		return nil, ErrInvalidWorkload.Wrap()
+	}
+
+	// get issuer
+	issuer, _ := claims["iss"].(string)
+
+	// get audience
+	var audience []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audience = []string{aud}
+	case []interface{}:
+		for _, item := range aud {
+			if str, ok := item.(string); ok {
+				audience = append(audience, str)
+			}
+		}
+	}
+
+	return &WorkloadIdentity{
+		Issuer:   issuer,
+		Subject:  subject,
+		Audience: audience,
+		Claims:   claims,
+	}, nil
+}
+
+// ServiceAccountSubject returns the subject of Kubernetes service account
+// tokens for the specified namespace and service account.
+func ServiceAccountSubject(namespace, name string) string {
+	return "system:serviceaccount:" + namespace + ":" + name
+}
+
+// ParseJWKS will parse the RSA and EC public keys from the provided JSON Web
+// Key Set. Keys with other types or uses are ignored.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	// decode set
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// parse keys
+	keys := map[string]crypto.PublicKey{}
+	for _, key := range set.Keys {
+		// skip encryption keys
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		switch key.Kty {
+		case "RSA":
+			// decode parameters
+			n, err := decodeJWKInt(key.N)
+			if err != nil {
+				return nil, err
+			}
+			e, err := decodeJWKInt(key.E)
+			if err != nil {
+				return nil, err
+			}
+
+			// add key
+			keys[key.Kid] = &rsa.PublicKey{
+				N: n,
+				E: int(e.Int64()),
+			}
+		case "EC":
+			// get curve
+			var curve elliptic.Curve
+			switch key.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, xo.F("unsupported curve %q", key.Crv)
+			}
+
+			// decode parameters
+			x, err := decodeJWKInt(key.X)
+			if err != nil {
+				return nil, err
+			}
+			y, err := decodeJWKInt(key.Y)
+			if err != nil {
+				return nil, err
+			}
+
+			// check point
+			if !curve.IsOnCurve(x, y) {
+				return nil, xo.F("invalid key %q", key.Kid)
+			}
+
+			// add key
+			keys[key.Kid] = &ecdsa.PublicKey{
+				Curve: curve,
+				X:     x,
+				Y:     y,
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+func decodeJWKInt(str string) (*big.Int, error) {
+	// decode value
+	buf, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return nil, xo.W(err)
+	} else if len(buf) == 0 {
+		return nil, xo.F("missing key parameter")
+	}
+
+	return new(big.Int).SetBytes(buf), nil
+}
@@ -0,0 +1,215 @@
+package flame
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/256dpi/oauth2/v2"
+	"github.com/256dpi/oauth2/v2/oauth2test"
+	"github.com/256dpi/xo"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire"
+)
+
+var testWorkloadKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+func signWorkload(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	str, err := token.SignedString(testWorkloadKey)
+	assert.NoError(t, err)
+	return str
+}
+
+func TestWorkloadVerifier(t *testing.T) {
+	verifier := &WorkloadVerifier{
+		Issuer:      "https://kubernetes.default.svc",
+		Audience:    "fire",
+		TrustDomain: "example.com",
+		Keys: map[string]crypto.PublicKey{
+			"k1": &testWorkloadKey.PublicKey,
+		},
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	identity, err := verifier.Verify(signWorkload(t, "k1", jwt.MapClaims{
+		"iss": "https://kubernetes.default.svc",
+		"sub": "spiffe://example.com/ns/default/sa/api",
+		"aud": []string{"fire", "other"},
+		"exp": exp,
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://kubernetes.default.svc", identity.Issuer)
+	assert.Equal(t, "spiffe://example.com/ns/default/sa/api", identity.Subject)
+	assert.Equal(t, []string{"fire", "other"}, identity.Audience)
+
+	for _, claims := range []jwt.MapClaims{
+		{"iss": "https://kubernetes.default.svc", "sub": "spiffe://example.com/api", "aud": "fire"},
+		{"iss": "https://kubernetes.default.svc", "sub": "spiffe://example.com/api", "aud": "fire", "exp": time.Now().Add(-time.Hour).Unix()},
+		{"iss": "https://kubernetes.default.svc", "sub": "spiffe://example.com/api", "aud": "other", "exp": exp},
+		{"iss": "https://example.com", "sub": "spiffe://example.com/api", "aud": "fire", "exp": exp},
+		{"iss": "https://kubernetes.default.svc", "sub": "spiffe://other.com/api", "aud": "fire", "exp": exp},
+		{"iss": "https://kubernetes.default.svc", "aud": "fire", "exp": exp},
+	} {
+		identity, err = verifier.Verify(signWorkload(t, "k1", claims))
+		assert.True(t, ErrInvalidWorkload.Is(err), claims)
+		assert.Nil(t, identity)
+	}
+
+	identity, err = verifier.Verify(signWorkload(t, "k2", jwt.MapClaims{
+		"iss": "https://kubernetes.default.svc",
+		"sub": "spiffe://example.com/api",
+		"aud": "fire",
+		"exp": exp,
+	}))
+	assert.True(t, ErrInvalidWorkload.Is(err))
+	assert.Nil(t, identity)
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": "https://kubernetes.default.svc",
+		"sub": "spiffe://example.com/api",
+		"aud": "fire",
+		"exp": exp,
+	}).SignedString([]byte("secret"))
+	assert.NoError(t, err)
+
+	identity, err = verifier.Verify(hmacToken)
+	assert.True(t, ErrInvalidWorkload.Is(err))
+	assert.Nil(t, identity)
+}
+
+func TestParseJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	enc := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+
+	keys, err := ParseJWKS([]byte(`{"keys": [
+		{"kid": "rsa", "kty": "RSA", "use": "sig", "n": "` + enc(testWorkloadKey.N) + `", "e": "AQAB"},
+		{"kid": "ec", "kty": "EC", "crv": "P-256", "x": "` + enc(ecKey.X) + `", "y": "` + enc(ecKey.Y) + `"},
+		{"kid": "enc", "kty": "RSA", "use": "enc", "n": "` + enc(testWorkloadKey.N) + `", "e": "AQAB"},
+		{"kid": "oct", "kty": "oct", "k": "c2VjcmV0"}
+	]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]crypto.PublicKey{
+		"rsa": &testWorkloadKey.PublicKey,
+		"ec":  &ecKey.PublicKey,
+	}, keys)
+
+	keys, err = ParseJWKS([]byte(`{"keys": [{"kid": "ec", "kty": "EC", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`))
+	assert.Error(t, err)
+	assert.Nil(t, keys)
+
+	keys, err = ParseJWKS([]byte(`{"keys": [{"kid": "rsa", "kty": "RSA", "n": "", "e": "AQAB"}]}`))
+	assert.Error(t, err)
+	assert.Nil(t, keys)
+}
+
+func TestWorkloadIdentityGrant(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *fire.Tester) {
+		policy := DefaultPolicy(testNotary)
+		policy.Grants = func(*Context, Client) (Grants, error) {
+			return Grants{WorkloadIdentity: true}, nil
+		}
+		policy.WorkloadVerifier = &WorkloadVerifier{
+			Audience: "fire",
+			Keys: map[string]crypto.PublicKey{
+				"k1": &testWorkloadKey.PublicKey,
+			},
+		}
+
+		var workload string
+		policy.GrantStrategy = func(ctx *Context, _ Client, ro ResourceOwner, scope oauth2.Scope) (oauth2.Scope, error) {
+			assert.Nil(t, ro)
+			workload = ctx.Workload.Subject
+			return scope, nil
+		}
+
+		authenticator := NewAuthenticator(tester.Store, policy, xo.Crash)
+		handler := newHandler(authenticator, false)
+
+		application := tester.Insert(&Application{
+			Name:      "App",
+			Key:       "application",
+			Workloads: []string{ServiceAccountSubject("default", "api")},
+		}).(*Application)
+
+		exp := time.Now().Add(time.Hour).Unix()
+
+		oauth2test.Do(handler, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/token",
+			Form: map[string]string{
+				"grant_type": WorkloadGrantType,
+				"client_id":  application.Key,
+				"assertion": signWorkload(t, "k1", jwt.MapClaims{
+					"sub": "system:serviceaccount:default:api",
+					"aud": "fire",
+					"exp": exp,
+				}),
+			},
+			Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusOK, r.Code, r.Body.String())
+				var res oauth2.TokenResponse
+				assert.NoError(t, json.Unmarshal(r.Body.Bytes(), &res))
+				assert.NotEmpty(t, res.AccessToken)
+				assert.Empty(t, res.RefreshToken)
+			},
+		})
+
+		assert.Equal(t, "system:serviceaccount:default:api", workload)
+
+		oauth2test.Do(handler, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/token",
+			Form: map[string]string{
+				"grant_type": WorkloadGrantType,
+				"client_id":  application.Key,
+				"assertion": signWorkload(t, "k1", jwt.MapClaims{
+					"sub": "system:serviceaccount:default:other",
+					"aud": "fire",
+					"exp": exp,
+				}),
+			},
+			Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusBadRequest, r.Code)
+				assert.JSONEq(t, `{
+					"error": "invalid_grant",
+					"error_description": "unknown workload"
+				}`, r.Body.String())
+			},
+		})
+
+		oauth2test.Do(handler, &oauth2test.Request{
+			Method: "POST",
+			Path:   "/oauth2/token",
+			Form: map[string]string{
+				"grant_type": WorkloadGrantType,
+				"client_id":  application.Key,
+				"assertion":  "foo",
+			},
+			Callback: func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, http.StatusBadRequest, r.Code)
+				assert.JSONEq(t, `{
+					"error": "invalid_grant",
+					"error_description": "invalid assertion"
+				}`, r.Body.String())
+			},
+		})
+	})
+}
@@ -0,0 +1,68 @@
+package fire
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/coal"
+)
+
+// GeoFilter returns a filter handler for the specified coal.Point field. The
+// "near" filter matches points within a radius in meters and the "within"
+// filter matches points within a bounding box:
+//
+//	filter[location]=near:<lng>,<lat>,<radius>
+//	filter[location]=within:<min-lng>,<min-lat>,<max-lng>,<max-lat>
+//
+// The filtered field must have a geo index, see coal.AddGeoIndex.
+func GeoFilter(field string) FilterHandler {
+	return func(ctx *Context, values []string) (bson.M, error) {
+		// check values
+		if len(values) != 1 {
+			return nil, xo.SF("invalid geo filter")
+		}
+
+		// split value
+		kind, args, ok := strings.Cut(values[0], ":")
+		if !ok {
+			return nil, xo.SF("invalid geo filter")
+		}
+
+		// parse numbers
+		var nums []float64
+		for _, arg := range strings.Split(args, ",") {
+			num, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+			if err != nil || math.IsNaN(num) || math.IsInf(num, 0) {
+				return nil, xo.SF("invalid geo filter")
+			}
+			nums = append(nums, num)
+		}
+
+		// prepare expression
+		var expr bson.M
+		switch {
+		case kind == "near" && len(nums) == 3 && nums[2] > 0:
+			expr = coal.WithinRadius(coal.NewPoint(nums[0], nums[1]), nums[2])
+		case kind == "within" && len(nums) == 4:
+			expr = coal.Within(coal.Box(nums[0], nums[1], nums[2], nums[3]))
+		default:
+			return nil, xo.SF("invalid geo filter")
+		}
+
+		// check points
+		for i := 0; i+1 < len(nums); i += 2 {
+			err := coal.NewPoint(nums[i], nums[i+1]).Validate()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return bson.M{
+			field: expr,
+		}, nil
+	}
+}
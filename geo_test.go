@@ -0,0 +1,50 @@
+package fire
+
+import (
+	"testing"
+
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/coal"
+)
+
+func TestGeoFilter(t *testing.T) {
+	handler := GeoFilter("Location")
+
+	expr, err := handler(nil, []string{"near:8.5,47.4,1000"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"Location": coal.WithinRadius(coal.NewPoint(8.5, 47.4), 1000),
+	}, expr)
+
+	expr, err = handler(nil, []string{"within:5, 45, 11, 48"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"Location": coal.Within(coal.Box(5, 45, 11, 48)),
+	}, expr)
+
+	for _, values := range [][]string{
+		nil,
+		{"near:1,2,3", "near:1,2,3"},
+		{"near"},
+		{"near:1,2"},
+		{"near:1,2,0"},
+		{"near:1,2,foo"},
+		{"within:1,2,3"},
+		{"around:1,2,3"},
+		{"near:200,2,3"},
+		{"near:1,95,3"},
+		{"near:NaN,2,3"},
+		{"near:1,2,Inf"},
+		{"within:1,2,3,NaN"},
+		{"within:1,2,190,48"},
+		{"within:1,2,3,-91"},
+	} {
+		expr, err = handler(nil, values)
+		assert.Error(t, err, values)
+		assert.True(t, xo.IsSafe(err))
+		assert.Nil(t, expr)
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/256dpi/fire/stick"
 )
 
-var mongoStore = coal.MustConnect("mongodb://0.0.0.0/test-fire-glut", xo.Crash)
+var mongoStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire-glut", xo.Crash)
 var lungoStore = coal.MustOpen(nil, "test-fire-glut", xo.Crash)
 
 var modelList = []coal.Model{&Model{}}
@@ -57,6 +57,10 @@ func (v *restrictedValue) GetDeadline() *time.Time {
 
 func withTester(t *testing.T, fn func(*testing.T, *coal.Tester)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		tester := coal.NewTester(mongoStore, modelList...)
 		tester.Clean()
 		fn(t, tester)
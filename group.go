@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/256dpi/jsonapi/v2"
@@ -18,6 +19,14 @@ import (
 	"github.com/256dpi/fire/stick"
 )
 
+// ErrClientAborted is returned if the client aborted the request before the
+// response has been written.
+var ErrClientAborted = xo.BF("client aborted")
+
+// StatusClientClosedRequest is the non-standard status code set for requests
+// that have been aborted by the client.
+const StatusClientClosedRequest = 499
+
 // GroupAction defines a group action.
 type GroupAction struct {
 	// Authorizers authorize the group action and are run before the action.
@@ -29,11 +38,61 @@ type GroupAction struct {
 	Action *Action
 }
 
+// LinkBuilder defines a function that builds the final link from a generated
+// relative link (e.g. "/api/posts?page[number]=2").
+type LinkBuilder func(ctx *Context, link string) string
+
+// AbsoluteLinks returns a link builder that turns generated links into absolute
+// links using the provided base URL (e.g. "https://api.example.com"). If the
+// base URL is empty, it is derived from the request scheme and host. If proxy
+// is set, the "X-Forwarded-Proto", "X-Forwarded-Host" and "X-Forwarded-Prefix"
+// headers are respected when deriving the base URL.
+func AbsoluteLinks(baseURL string, proxy bool) LinkBuilder {
+	// trim base URL
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	return func(ctx *Context, link string) string {
+		// use static base URL if available
+		if baseURL != "" {
+			return baseURL + link
+		}
+
+		// get scheme, host and prefix
+		scheme := "http"
+		if ctx.HTTPRequest.TLS != nil {
+			scheme = "https"
+		}
+		host := ctx.HTTPRequest.Host
+		var prefix string
+		if proxy {
+			if value := ctx.HTTPRequest.Header.Get("X-Forwarded-Proto"); value != "" {
+				scheme = strings.TrimSpace(strings.Split(value, ",")[0])
+			}
+			if value := ctx.HTTPRequest.Header.Get("X-Forwarded-Host"); value != "" {
+				host = strings.TrimSpace(strings.Split(value, ",")[0])
+			}
+			prefix = strings.TrimRight(ctx.HTTPRequest.Header.Get("X-Forwarded-Prefix"), "/")
+		}
+
+		return scheme + "://" + host + prefix + link
+	}
+}
+
 // A Group manages access to multiple controllers and their interconnections.
 type Group struct {
 	reporter    func(error)
 	controllers map[string]*Controller
+	metaMap     map[string]*coal.Meta
 	actions     map[string]*GroupAction
+	before      []*Callback
+	after       []*Callback
+	links       LinkBuilder
+	csrf        *CSRF
+	locale      *Locale
+	mutex       sync.Mutex
+	active      int
+	draining    bool
+	idle        chan struct{}
 }
 
 // NewGroup creates and returns a new group.
@@ -41,6 +100,7 @@ func NewGroup(reporter func(error)) *Group {
 	return &Group{
 		reporter:    reporter,
 		controllers: make(map[string]*Controller),
+		metaMap:     make(map[string]*coal.Meta),
 		actions:     make(map[string]*GroupAction),
 	}
 }
@@ -59,8 +119,9 @@ func (g *Group) Add(controllers ...*Controller) {
 			panic(fmt.Sprintf(`fire: controller with name "%s" already exists`, name))
 		}
 
-		// create entry in controller map
+		// create entry in controller and meta map
 		g.controllers[name] = controller
+		g.metaMap[name] = controller.meta
 	}
 }
 
@@ -71,15 +132,8 @@ func (g *Group) Handle(name string, a *GroupAction) {
 		panic(fmt.Sprintf(`fire: invalid group action "%s"`, name))
 	}
 
-	// set default body limit
-	if a.Action.BodyLimit == 0 {
-		a.Action.BodyLimit = serve.MustByteSize("8M")
-	}
-
-	// set default timeout
-	if a.Action.Timeout == 0 {
-		a.Action.Timeout = 30 * time.Second
-	}
+	// prepare action
+	a.Action.prepare()
 
 	// check existence
 	if g.actions[name] != nil {
@@ -90,6 +144,250 @@ func (g *Group) Handle(name string, a *GroupAction) {
 	g.actions[name] = a
 }
 
+// Before will add callbacks that are run before every operation handled by the
+// controllers of the group. They are run after the operation has been
+// determined and the context has been prepared, but before the authorizers
+// and outside any transaction. Returned errors will cause the abortion of the
+// request with a "Bad Request" status by default.
+//
+// Note: Forwarded operations of related controllers do not run the callbacks
+// again as they share the context of the initial operation.
+func (g *Group) Before(cbs ...*Callback) {
+	g.before = append(g.before, cbs...)
+}
+
+// After will add callbacks that are run after every successful operation
+// handled by the controllers of the group. They are run after the notifiers
+// and before the response is written. Returned errors will cause the abortion
+// of the request with an "Internal Server Error" status by default.
+func (g *Group) After(cbs ...*Callback) {
+	g.after = append(g.after, cbs...)
+}
+
+// Links will set a link builder that is used to build all links of the
+// responses written by the controllers of the group. This allows generating
+// absolute links or applying custom link templates to match the public URLs
+// when the API is served behind a proxy or API gateway.
+func (g *Group) Links(builder LinkBuilder) {
+	g.links = builder
+}
+
+// ProtectCSRF will enable the provided double-submit cookie CSRF protection for
+// all requests handled by the group. A group action with the provided name is
+// added that issues the token as a cookie and returns it in the response body.
+// Requests using unsafe methods must then echo the token using the configured
+// header. Requests that carry an "Authorization" header are not checked as
+// they cannot be forged by browsers. Only tokens signed by the protection are
+// accepted and CSRF.Rotate should be used to issue a new token when a client
+// authenticates.
+func (g *Group) ProtectCSRF(name string, csrf *CSRF) {
+	// check existence
+	if g.csrf != nil {
+		panic("fire: csrf protection already enabled")
+	}
+
+	// prepare protection
+	csrf.prepare()
+
+	// add issuance action
+	g.Handle(name, &GroupAction{
+		Action: A("fire/CSRF.issue", []string{"GET"}, 0, 0, csrf.issue),
+	})
+
+	// set protection
+	g.csrf = csrf
+}
+
+// Localize will set the provided locale that is used to determine the
+// language and time zone of all requests handled by the group. The values are
+// made available on the context to validators, serializers and computations.
+func (g *Group) Localize(locale *Locale) {
+	// prepare locale
+	locale.prepare()
+
+	// set locale
+	g.locale = locale
+}
+
+func (g *Group) metas() map[string]*coal.Meta {
+	// check group
+	if g == nil {
+		return nil
+	}
+
+	return g.metaMap
+}
+
+func (g *Group) buildLinks(ctx *Context, doc *jsonapi.Document) {
+	// return early if not configured
+	if g.links == nil {
+		return
+	}
+
+	// prepare visited links, links may be shared between documents
+	visited := map[*jsonapi.DocumentLinks]bool{}
+
+	// prepare link builder
+	build := func(links *jsonapi.DocumentLinks) {
+		// check links
+		if links == nil || visited[links] {
+			return
+		}
+		visited[links] = true
+
+		// build links
+		for _, link := range []*jsonapi.Link{&links.Self, &links.Related, &links.First, &links.Previous, &links.Next, &links.Last} {
+			if *link != "" {
+				*link = jsonapi.Link(g.links(ctx, string(*link)))
+			}
+		}
+	}
+
+	// prepare resources
+	var resources []*jsonapi.Resource
+	if doc.Data != nil {
+		if doc.Data.One != nil {
+			resources = append(resources, doc.Data.One)
+		}
+		resources = append(resources, doc.Data.Many...)
+	}
+	resources = append(resources, doc.Included...)
+
+	// build document links
+	build(doc.Links)
+
+	// build relationship links
+	for _, res := range resources {
+		for _, rel := range res.Relationships {
+			build(rel.Links)
+		}
+	}
+}
+
+func (g *Group) runHooks(ctx *Context, list []*Callback, errorStatus int) {
+	// return early if list is empty
+	if len(list) == 0 {
+		return
+	}
+
+	// trace
+	ctx.Tracer.Push("fire/Group.runHooks")
+	defer ctx.Tracer.Pop()
+
+	// run callbacks and handle errors
+	for _, cb := range list {
+		// check if callback should be run
+		if !cb.Matcher(ctx) {
+			continue
+		}
+
+		// call callback
+		err := xo.W(cb.Handler(ctx))
+		if xo.IsSafe(err) {
+			xo.Abort(jsonapi.ErrorFromStatus(errorStatus, err.Error()))
+		} else if err != nil {
+			xo.Abort(err)
+		}
+	}
+}
+
+// Drain will reject new requests with a "Service Unavailable" status and wait
+// until all in-flight requests have been completed or the context is done.
+// Long polling requests are released and the change streams used to observe
+// changes are closed.
+func (g *Group) Drain(ctx context.Context) error {
+	// reject requests
+	g.reject()
+
+	// close watchers
+	for _, controller := range g.controllers {
+		controller.watcher.close()
+	}
+
+	// acquire mutex
+	g.mutex.Lock()
+
+	// check active requests
+	if g.active == 0 {
+		g.mutex.Unlock()
+		return nil
+	}
+
+	// ensure channel
+	if g.idle == nil {
+		g.idle = make(chan struct{})
+	}
+	idle := g.idle
+
+	// release mutex
+	g.mutex.Unlock()
+
+	// await idle
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return xo.W(ctx.Err())
+	}
+}
+
+func clientAborted(r *http.Request, err error) bool {
+	return ErrClientAborted.Is(err) || (errors.Is(err, context.Canceled) && errors.Is(r.Context().Err(), context.Canceled))
+}
+
+func storeError(err error) *jsonapi.Error {
+	// map classified store errors
+	if coal.ErrDuplicateKey.Is(err) {
+		return jsonapi.ErrorFromStatus(http.StatusConflict, "document conflict")
+	} else if coal.ErrInvalidDocument.Is(err) {
+		return jsonapi.ErrorFromStatus(http.StatusUnprocessableEntity, "document invalid")
+	} else if coal.ErrTimeout.Is(err) || coal.ErrTransient.Is(err) {
+		return jsonapi.ErrorFromStatus(http.StatusServiceUnavailable, "service unavailable")
+	}
+
+	return nil
+}
+
+func (g *Group) reject() {
+	// acquire mutex
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// set flag
+	g.draining = true
+}
+
+func (g *Group) enter() bool {
+	// acquire mutex
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// check flag
+	if g.draining {
+		return false
+	}
+
+	// increment
+	g.active++
+
+	return true
+}
+
+func (g *Group) leave() {
+	// acquire mutex
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// decrement
+	g.active--
+
+	// signal idle
+	if g.active == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+}
+
 // Endpoint will return a handler that serves requests for this group. The
 // specified prefix is used to parse the requests and generate URLs for the
 // resources.
@@ -98,11 +396,26 @@ func (g *Group) Endpoint(prefix string) http.Handler {
 	prefix = strings.Trim(prefix, "/")
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// track request
+		if !g.enter() {
+			_ = jsonapi.WriteError(w, jsonapi.ErrorFromStatus(http.StatusServiceUnavailable, "shutting down"))
+			return
+		}
+		defer g.leave()
+
 		// create tracer
 		tracer, tc := xo.CreateTracer(r.Context(), "fire/Group.Endpoint")
 		defer tracer.End()
 		r = r.WithContext(tc)
 
+		// archive request at last to capture all responses
+		var archive func()
+		defer func() {
+			if archive != nil {
+				archive()
+			}
+		}()
+
 		// recover any panic
 		defer xo.Recover(func(err error) {
 			// record error
@@ -119,6 +432,13 @@ func (g *Group) Endpoint(prefix string) http.Handler {
 
 		// continue any previous aborts
 		defer xo.Resume(func(err error) {
+			// skip response if the client aborted the request
+			if clientAborted(r, err) {
+				tracer.Tag("aborted", true)
+				w.WriteHeader(StatusClientClosedRequest)
+				return
+			}
+
 			// directly write jsonapi errors
 			var jsonapiError *jsonapi.Error
 			if errors.As(err, &jsonapiError) {
@@ -126,6 +446,20 @@ func (g *Group) Endpoint(prefix string) http.Handler {
 				return
 			}
 
+			// directly write jsonapi error lists
+			var jsonapiErrors errorList
+			if errors.As(err, &jsonapiErrors) {
+				_ = jsonapi.WriteErrorList(w, jsonapiErrors...)
+				return
+			}
+
+			// directly write client store errors
+			storeErr := storeError(err)
+			if storeErr != nil && storeErr.Status != http.StatusServiceUnavailable {
+				_ = jsonapi.WriteError(w, storeErr)
+				return
+			}
+
 			// record error
 			tracer.Record(err)
 
@@ -134,6 +468,12 @@ func (g *Group) Endpoint(prefix string) http.Handler {
 				g.reporter(err)
 			}
 
+			// write unavailable store errors
+			if storeErr != nil {
+				_ = jsonapi.WriteError(w, storeErr)
+				return
+			}
+
 			// write internal server error
 			_ = jsonapi.WriteError(w, jsonapi.InternalServerError(""))
 		})
@@ -159,6 +499,17 @@ func (g *Group) Endpoint(prefix string) http.Handler {
 			ResponseWriter: w,
 			Group:          g,
 			Tracer:         tracer,
+			Location:       time.UTC,
+		}
+
+		// resolve locale
+		if g.locale != nil {
+			ctx.Language, ctx.Location = g.locale.resolve(r)
+		}
+
+		// verify csrf token
+		if g.csrf != nil {
+			g.csrf.verify(ctx)
 		}
 
 		// get controller
@@ -167,6 +518,11 @@ func (g *Group) Endpoint(prefix string) http.Handler {
 			// set controller
 			ctx.Controller = controller
 
+			// prepare archive
+			if controller.Archiver != nil {
+				w, archive = controller.archive(ctx)
+			}
+
 			// handle request
 			controller.handle(prefix, ctx, nil, true)
 
@@ -204,6 +560,9 @@ func (g *Group) Endpoint(prefix string) http.Handler {
 				// replace context
 				ctx.Context = ct
 
+				// validate request
+				xo.AbortIf(action.Action.validate(ctx))
+
 				// call action with context
 				xo.AbortIf(action.Action.Handler(ctx))
 
@@ -1,12 +1,21 @@
 package fire
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/256dpi/xo"
 	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/text/language"
+
+	"github.com/256dpi/fire/coal"
 )
 
 func TestGroupAdd(t *testing.T) {
@@ -117,6 +126,104 @@ func TestGroupPanic(t *testing.T) {
 	})
 }
 
+func TestGroupStoreErrors(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var reported []error
+
+		group := NewGroup(func(err error) {
+			reported = append(reported, err)
+		})
+
+		var actionErr error
+		group.Handle("foo", &GroupAction{
+			Action: A("TestGroupStoreErrors", []string{"GET"}, 0, 0, func(ctx *Context) error {
+				return actionErr
+			}),
+		})
+
+		tester.Handler = group.Endpoint("")
+
+		for _, item := range []struct {
+			err    error
+			status int
+			detail string
+		}{
+			{
+				err:    errors.New("E11000 duplicate key error"),
+				status: http.StatusConflict,
+				detail: "document conflict",
+			},
+			{
+				err:    mongo.CommandError{Code: 121, Message: "Document failed validation"},
+				status: http.StatusUnprocessableEntity,
+				detail: "document invalid",
+			},
+			{
+				err:    mongo.CommandError{Code: 189, Message: "primary stepped down"},
+				status: http.StatusServiceUnavailable,
+				detail: "service unavailable",
+			},
+		} {
+			actionErr = coal.Classify(item.err)
+			tester.Request("GET", "foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+				assert.Equal(t, item.status, r.Result().StatusCode)
+				assert.Equal(t, item.detail, gjson.Get(r.Body.String(), "errors.0.detail").String())
+			})
+		}
+
+		assert.Len(t, reported, 1)
+	})
+}
+
+func TestGroupClientAbort(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var errs []error
+		var cancel context.CancelFunc
+
+		group := NewGroup(func(err error) {
+			errs = append(errs, err)
+		})
+
+		group.Add(&Controller{
+			Model: &postModel{},
+			Store: tester.Store,
+			Authorizers: L{
+				C("Cancel", Authorizer, Only(Find), func(ctx *Context) error {
+					cancel()
+					return ctx.Err()
+				}),
+			},
+			Decorators: L{
+				C("Cancel", Decorator, Only(List), func(ctx *Context) error {
+					cancel()
+					return nil
+				}),
+			},
+		})
+
+		post := tester.Insert(&postModel{
+			Title: "Hello",
+		})
+
+		handler := group.Endpoint("")
+
+		for _, path := range []string{"/posts", "/posts/" + post.ID().Hex()} {
+			ctx, done := context.WithCancel(context.Background())
+			cancel = done
+
+			r := httptest.NewRecorder()
+			rq := httptest.NewRequest("GET", path, nil).WithContext(ctx)
+			handler.ServeHTTP(r, rq)
+			done()
+
+			assert.Equal(t, StatusClientClosedRequest, r.Result().StatusCode, path)
+			assert.Empty(t, r.Body.String(), path)
+		}
+
+		assert.Empty(t, errs)
+	})
+}
+
 func TestGroupAction(t *testing.T) {
 	withTester(t, func(t *testing.T, tester *Tester) {
 		group := NewGroup(xo.Crash)
@@ -148,6 +255,14 @@ func TestGroupAction(t *testing.T) {
 			})
 		})
 
+		assert.PanicsWithValue(t, `fire: action body must be a pointer`, func() {
+			group.Handle("bar", &GroupAction{
+				Action: &Action{
+					Body: valueInput{},
+				},
+			})
+		})
+
 		tester.Handler = group.Endpoint("")
 
 		tester.Request("GET", "foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
@@ -167,3 +282,347 @@ func TestGroupAction(t *testing.T) {
 		})
 	})
 }
+
+func TestGroupHooks(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var events []string
+
+		group := NewGroup(xo.Crash)
+
+		group.Add(&Controller{
+			Model: &postModel{},
+			Store: tester.Store,
+			Authorizers: L{
+				C("Authorizer", Authorizer, All(), func(ctx *Context) error {
+					events = append(events, "authorizer:"+ctx.Data["tenant"].(string))
+					return nil
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+			Store: tester.Store,
+		}, &Controller{
+			Model: &selectionModel{},
+			Store: tester.Store,
+		}, &Controller{
+			Model: &noteModel{},
+			Store: tester.Store,
+		})
+
+		group.Before(C("Before", 0, All(), func(ctx *Context) error {
+			if ctx.HTTPRequest.Header.Get("X-Tenant") == "" {
+				return xo.SF("missing tenant")
+			}
+			ctx.Data["tenant"] = ctx.HTTPRequest.Header.Get("X-Tenant")
+			events = append(events, "before:"+ctx.Operation.String())
+			return nil
+		}))
+
+		group.After(C("After", 0, Only(List), func(ctx *Context) error {
+			events = append(events, "after:"+ctx.Operation.String())
+			return nil
+		}))
+
+		tester.Handler = group.Endpoint("")
+
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusBadRequest, r.Result().StatusCode)
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "400",
+					"title": "bad request",
+					"detail": "missing tenant"
+				}]
+			}`, r.Body.String())
+		})
+		assert.Empty(t, events)
+
+		tester.Header["X-Tenant"] = "foo"
+		defer delete(tester.Header, "X-Tenant")
+
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+		})
+		assert.Equal(t, []string{"before:List", "authorizer:foo", "after:List"}, events)
+
+		events = nil
+		tester.Request("GET", "posts/"+coal.New().Hex(), "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusNotFound, r.Result().StatusCode)
+		})
+		assert.Equal(t, []string{"before:Find", "authorizer:foo"}, events)
+	})
+}
+
+func TestGroupLinks(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		group := tester.Assign("api", &Controller{
+			Model:     &postModel{},
+			ListLimit: 1,
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		post := tester.Insert(&postModel{
+			Title: "post",
+		}).ID().Hex()
+
+		group.Links(AbsoluteLinks("", true))
+
+		tester.Header["X-Forwarded-Proto"] = "https"
+		tester.Header["X-Forwarded-Host"] = "example.com, proxy.local"
+		tester.Header["X-Forwarded-Prefix"] = "/v1/"
+		defer func() {
+			delete(tester.Header, "X-Forwarded-Proto")
+			delete(tester.Header, "X-Forwarded-Host")
+			delete(tester.Header, "X-Forwarded-Prefix")
+		}()
+
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "https://example.com/v1/api/posts?page[number]=1&page[size]=1",
+				"first": "https://example.com/v1/api/posts?page[number]=1&page[size]=1",
+				"last": "https://example.com/v1/api/posts?page[number]=1&page[size]=1"
+			}`, linkUnescape(gjson.Get(r.Body.String(), "links").Raw), tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"self": "https://example.com/v1/api/posts/`+post+`/relationships/comments",
+				"related": "https://example.com/v1/api/posts/`+post+`/comments"
+			}`, gjson.Get(r.Body.String(), "data.0.relationships.comments.links").Raw, tester.DebugRequest(rq, r))
+		})
+
+		group.Links(AbsoluteLinks("https://api.example.com/", false))
+
+		tester.Request("GET", "posts/"+post, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "https://api.example.com/api/posts/"+post, gjson.Get(r.Body.String(), "links.self").String())
+			assert.Equal(t, "https://api.example.com/api/posts/"+post+"/note", gjson.Get(r.Body.String(), "data.relationships.note.links.related").String())
+		})
+
+		group.Links(func(ctx *Context, link string) string {
+			return strings.Replace(link, "/api/", "/gateway/", 1)
+		})
+
+		tester.Request("GET", "posts/"+post+"/relationships/comments", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, "/gateway/posts/"+post+"/relationships/comments", gjson.Get(r.Body.String(), "links.self").String())
+			assert.Equal(t, "/gateway/posts/"+post+"/comments", gjson.Get(r.Body.String(), "links.related").String())
+		})
+	})
+}
+
+func TestGroupCSRF(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		group := NewGroup(xo.Crash)
+
+		group.Add(&Controller{
+			Model: &postModel{},
+			Store: tester.Store,
+		}, &Controller{
+			Model: &commentModel{},
+			Store: tester.Store,
+		}, &Controller{
+			Model: &selectionModel{},
+			Store: tester.Store,
+		}, &Controller{
+			Model: &noteModel{},
+			Store: tester.Store,
+		})
+
+		group.Handle("ping", &GroupAction{
+			Action: A("ping", []string{"POST"}, 0, 0, func(ctx *Context) error {
+				_, _ = ctx.ResponseWriter.Write([]byte("pong"))
+				return nil
+			}),
+		})
+
+		group.ProtectCSRF("csrf", &CSRF{})
+
+		assert.PanicsWithValue(t, `fire: csrf protection already enabled`, func() {
+			group.ProtectCSRF("csrf2", &CSRF{})
+		})
+
+		tester.Handler = group.Endpoint("")
+
+		var token string
+		tester.Request("GET", "csrf", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			token = gjson.Get(r.Body.String(), "token").String()
+			assert.NotEmpty(t, token)
+
+			cookies := r.Result().Cookies()
+			assert.Len(t, cookies, 1)
+			assert.Equal(t, "csrf-token", cookies[0].Name)
+			assert.Equal(t, token, cookies[0].Value)
+			assert.True(t, cookies[0].HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		})
+
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+		})
+
+		payload := `{"data":{"type":"posts","attributes":{"title":"Hello"}}}`
+
+		tester.Request("POST", "posts", payload, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusForbidden, r.Result().StatusCode)
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "403",
+					"title": "forbidden",
+					"detail": "missing csrf token"
+				}]
+			}`, r.Body.String())
+		})
+
+		tester.Header["Cookie"] = "csrf-token=" + token
+		defer delete(tester.Header, "Cookie")
+
+		tester.Request("POST", "posts", payload, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusForbidden, r.Result().StatusCode)
+			assert.JSONEq(t, `{
+				"errors": [{
+					"status": "403",
+					"title": "forbidden",
+					"detail": "invalid csrf token"
+				}]
+			}`, r.Body.String())
+		})
+
+		tester.Request("POST", "ping", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusForbidden, r.Result().StatusCode)
+		})
+
+		tester.Request("GET", "csrf", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Equal(t, token, gjson.Get(r.Body.String(), "token").String())
+		})
+
+		tester.Header["X-CSRF-Token"] = token
+		defer delete(tester.Header, "X-CSRF-Token")
+
+		tester.Request("POST", "posts", payload, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode)
+		})
+
+		tester.Request("POST", "ping", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Equal(t, "pong", r.Body.String())
+		})
+
+		// fixated tokens are rejected and replaced
+		tester.Header["Cookie"] = "csrf-token=fixated"
+		tester.Header["X-CSRF-Token"] = "fixated"
+
+		tester.Request("POST", "ping", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusForbidden, r.Result().StatusCode)
+		})
+
+		tester.Request("GET", "csrf", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			issued := gjson.Get(r.Body.String(), "token").String()
+			assert.NotEqual(t, "fixated", issued)
+			assert.NotEqual(t, token, issued)
+		})
+
+		// rotated tokens are accepted
+		rec := httptest.NewRecorder()
+		rotated, err := group.csrf.Rotate(rec)
+		assert.NoError(t, err)
+		assert.NotEqual(t, token, rotated)
+		assert.Equal(t, rotated, rec.Result().Cookies()[0].Value)
+
+		tester.Header["Cookie"] = "csrf-token=" + rotated
+		tester.Header["X-CSRF-Token"] = rotated
+
+		tester.Request("POST", "ping", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+		})
+
+		delete(tester.Header, "Cookie")
+		delete(tester.Header, "X-CSRF-Token")
+		tester.Header["Authorization"] = "Bearer foo"
+		defer delete(tester.Header, "Authorization")
+
+		tester.Request("POST", "ping", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+		})
+	})
+}
+
+func TestGroupLocalize(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		group := NewGroup(xo.Crash)
+
+		var lang language.Tag
+		var loc *time.Location
+		group.Handle("locale", &GroupAction{
+			Action: A("locale", []string{"GET"}, 0, 0, func(ctx *Context) error {
+				lang = ctx.Language
+				loc = ctx.Location
+				return nil
+			}),
+		})
+
+		tester.Handler = group.Endpoint("")
+
+		tester.Request("GET", "locale", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Equal(t, language.Und, lang)
+			assert.Equal(t, time.UTC, loc)
+		})
+
+		zurich, err := time.LoadLocation("Europe/Zurich")
+		assert.NoError(t, err)
+
+		group.Localize(&Locale{
+			Languages: []language.Tag{language.English, language.German},
+			Location:  zurich,
+		})
+
+		tester.Request("GET", "locale", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Equal(t, language.English, lang)
+			assert.Equal(t, zurich, loc)
+		})
+
+		tester.Header["Accept-Language"] = "fr-CH, de-CH;q=0.8, en;q=0.5"
+		defer delete(tester.Header, "Accept-Language")
+		tester.Header["Time-Zone"] = "America/New_York"
+		defer delete(tester.Header, "Time-Zone")
+
+		tester.Request("GET", "locale", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Equal(t, language.German, lang)
+			assert.Equal(t, "America/New_York", loc.String())
+		})
+
+		tester.Request("GET", "locale?tz=Asia/Tokyo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Equal(t, "Asia/Tokyo", loc.String())
+		})
+
+		// loaded locations are cached
+		tokyo := loc
+		tester.Request("GET", "locale?tz=Asia/Tokyo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Same(t, tokyo, loc)
+		})
+
+		tester.Header["Accept-Language"] = "invalid;;"
+		tester.Header["Time-Zone"] = "Invalid/Zone"
+
+		tester.Request("GET", "locale", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Equal(t, language.English, lang)
+			assert.Equal(t, zurich, loc)
+		})
+
+		// invalid locations are not cached
+		_, ok := group.locale.locations.Load("Invalid/Zone")
+		assert.False(t, ok)
+	})
+}
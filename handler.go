@@ -1,9 +1,18 @@
 package fire
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"time"
 
+	"github.com/256dpi/jsonapi/v2"
+	"github.com/256dpi/serve"
 	"github.com/256dpi/xo"
+
+	"github.com/256dpi/fire/stick"
 )
 
 // A Callback is called during the request processing flow of a controller.
@@ -72,6 +81,19 @@ type Action struct {
 	// Default: 30s.
 	Timeout time.Duration
 
+	// Body may be set to a prototype of the expected JSON request body. A new
+	// value of the same type is decoded from the request body and validated
+	// before the handler is run. The value is then available as ctx.Body.
+	// Unknown fields, decoding and validation errors are returned as "Bad
+	// Request" errors that point to the offending fields.
+	Body stick.Validatable
+
+	// Query may be set to declare the allowed query parameters and the rules
+	// used to validate their values before the handler is run. Missing
+	// parameters are validated as empty strings while undeclared parameters
+	// are rejected.
+	Query map[string][]stick.Rule
+
 	// The handler that gets executed with the context.
 	//
 	// If returned errors are marked with Safe() they will be included in the
@@ -79,6 +101,138 @@ type Action struct {
 	Handler Handler
 }
 
+func (a *Action) prepare() {
+	// set default body limit
+	if a.BodyLimit == 0 {
+		a.BodyLimit = serve.MustByteSize("8M")
+	}
+
+	// set default timeout
+	if a.Timeout == 0 {
+		a.Timeout = 30 * time.Second
+	}
+
+	// check body
+	if a.Body != nil && reflect.TypeOf(a.Body).Kind() != reflect.Ptr {
+		panic("fire: action body must be a pointer")
+	}
+}
+
+func (a *Action) validate(ctx *Context) error {
+	// prepare errors
+	var list errorList
+
+	// validate query
+	if a.Query != nil {
+		list = append(list, a.validateQuery(ctx)...)
+	}
+
+	// decode and validate body
+	if a.Body != nil {
+		body, errs, err := a.validateBody(ctx)
+		if err != nil {
+			return err
+		}
+		list = append(list, errs...)
+		ctx.Body = body
+	}
+
+	// check errors
+	if len(list) > 0 {
+		return list
+	}
+
+	return nil
+}
+
+func (a *Action) validateQuery(ctx *Context) errorList {
+	// get query
+	query := ctx.HTTPRequest.URL.Query()
+
+	// prepare errors
+	var list errorList
+
+	// check undeclared parameters
+	for name := range query {
+		if _, ok := a.Query[name]; !ok {
+			list = append(list, jsonapi.BadRequestParam("invalid parameter", name))
+		}
+	}
+
+	// validate declared parameters
+	for name, rules := range a.Query {
+		// get values
+		values := query[name]
+		if len(values) == 0 {
+			values = []string{""}
+		}
+
+		// run rules
+		for _, value := range values {
+			rv := reflect.ValueOf(&value).Elem()
+			for _, rule := range rules {
+				err := rule(stick.Subject{IValue: value, RValue: rv})
+				if err != nil {
+					list = append(list, jsonapi.BadRequestParam(safeMessage(err), name))
+				}
+			}
+		}
+	}
+
+	// sort errors
+	list.sort()
+
+	return list
+}
+
+func (a *Action) validateBody(ctx *Context) (stick.Validatable, errorList, error) {
+	// allocate body
+	body := reflect.New(reflect.TypeOf(a.Body).Elem()).Interface().(stick.Validatable)
+
+	// decode body
+	dec := json.NewDecoder(ctx.HTTPRequest.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(body)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if err == io.EOF {
+			return nil, errorList{jsonapi.BadRequest("incomplete request body")}, nil
+		} else if errors.As(err, &typeErr) {
+			pointer := "/" + strings.ReplaceAll(typeErr.Field, ".", "/")
+			return nil, errorList{jsonapi.BadRequestPointer("invalid type", pointer)}, nil
+		} else if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			return nil, errorList{jsonapi.BadRequestPointer("unknown field", "/"+field)}, nil
+		}
+		return nil, errorList{jsonapi.BadRequest("invalid request body")}, nil
+	}
+
+	// validate body
+	err = body.Validate()
+	if err == nil {
+		return body, nil, nil
+	}
+
+	// handle validation errors
+	var valErr stick.ValidationError
+	if errors.As(err, &valErr) {
+		var list errorList
+		for err, path := range valErr {
+			pointer := jsonPointer(reflect.TypeOf(body), path)
+			list = append(list, jsonapi.BadRequestPointer(safeMessage(err), pointer))
+		}
+		list.sort()
+		return nil, list, nil
+	}
+
+	// handle safe errors
+	if xo.IsSafe(err) {
+		return nil, errorList{jsonapi.BadRequest(err.Error())}, nil
+	}
+
+	return nil, nil, xo.W(err)
+}
+
 // M is a shorthand type to create a map of actions.
 type M = map[string]*Action
 
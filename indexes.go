@@ -0,0 +1,163 @@
+package fire
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/stick"
+)
+
+// IndexSuggestion describes an index that is needed to support a query issued
+// by a controller.
+type IndexSuggestion struct {
+	// The model of the controller.
+	Model coal.Model
+
+	// The suggested index fields.
+	Fields []string
+
+	// The reason for the suggestion e.g. `filter "Title"`.
+	Reason string
+
+	// The problem if the query cannot be supported by an index.
+	Problem string
+}
+
+// SuggestIndexes will inspect the filters and sorters of all controllers in
+// the group and return suggestions for the indexes that are needed to support
+// them. Each query is composed of the indexed filters, the soft delete field
+// and a single filter or sorter. Queries that are already supported by a
+// registered index are omitted. If register is true, the suggested indexes
+// are added to the models index list. Suggestions that cannot be supported by
+// an index have a problem set and are never registered.
+//
+// Note: Combinations of multiple filters and sorters are not considered.
+func (g *Group) SuggestIndexes(register bool) []IndexSuggestion {
+	// sort names
+	names := make([]string, 0, len(g.controllers))
+	for name := range g.controllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// collect suggestions
+	var list []IndexSuggestion
+	seen := map[string]bool{}
+	for _, name := range names {
+		for _, suggestion := range g.controllers[name].suggestIndexes() {
+			// check seen
+			key := name + ":" + strings.Join(suggestion.Fields, ",")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			// register index
+			if register && suggestion.Problem == "" {
+				coal.AddIndex(suggestion.Model, false, 0, suggestion.Fields...)
+			}
+
+			// add suggestion
+			list = append(list, suggestion)
+		}
+	}
+
+	return list
+}
+
+func (c *Controller) suggestIndexes() []IndexSuggestion {
+	// prepare base
+	base := append([]string{}, c.IndexedFilters...)
+	if c.SoftDelete {
+		base = append(base, coal.L(c.Model, "fire-soft-delete", true))
+	}
+
+	// prepare list
+	var list []IndexSuggestion
+
+	// add suggestions for filters
+	for _, filter := range c.Filters {
+		// prepare suggestion
+		suggestion := IndexSuggestion{
+			Model:  c.Model,
+			Fields: base,
+			Reason: fmt.Sprintf(`filter "%s"`, filter),
+		}
+		if !stick.Contains(base, filter) {
+			suggestion.Fields = append(append([]string{}, base...), filter)
+		}
+
+		// check handler
+		if c.FilterHandlers[filter] != nil {
+			suggestion.Problem = "filter uses a custom handler"
+		}
+
+		// add suggestion
+		list = c.addSuggestion(list, suggestion, suggestion.Fields, nil)
+	}
+
+	// add suggestions for sorters
+	for _, sorter := range c.Sorters {
+		// skip filtered sorters
+		if stick.Contains(base, sorter) {
+			continue
+		}
+
+		// add suggestion
+		list = c.addSuggestion(list, IndexSuggestion{
+			Model:  c.Model,
+			Fields: append(append([]string{}, base...), sorter),
+			Reason: fmt.Sprintf(`sorter "%s"`, sorter),
+		}, base, []string{sorter})
+	}
+
+	// check if base is covered by a supported suggestion
+	covered := false
+	for _, suggestion := range list {
+		if suggestion.Problem == "" {
+			covered = true
+		}
+	}
+
+	// add suggestion for base
+	if len(base) > 0 && !covered {
+		list = c.addSuggestion(list, IndexSuggestion{
+			Model:  c.Model,
+			Fields: base,
+			Reason: "indexed filters",
+		}, base, nil)
+	}
+
+	return list
+}
+
+func (c *Controller) addSuggestion(list []IndexSuggestion, suggestion IndexSuggestion, fields, sort []string) []IndexSuggestion {
+	// check arrays
+	if suggestion.Problem == "" {
+		var arrays int
+		for _, field := range suggestion.Fields {
+			typ := c.meta.Fields[field].Type
+			if typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8 {
+				arrays++
+			}
+		}
+		if arrays > 1 {
+			suggestion.Problem = "index cannot contain multiple array fields"
+		}
+	}
+
+	// check existing index
+	if suggestion.Problem == "" {
+		index, err := coal.LookupIndex(c.Model, fields, sort)
+		if err != nil {
+			suggestion.Problem = err.Error()
+		} else if index != nil {
+			return list
+		}
+	}
+
+	return append(list, suggestion)
+}
@@ -0,0 +1,83 @@
+package fire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/256dpi/fire/coal"
+)
+
+func TestGroupSuggestIndexes(t *testing.T) {
+	postMeta := coal.GetMeta(&postModel{})
+	fooMeta := coal.GetMeta(&fooModel{})
+	postIndexes := postMeta.Indexes
+	fooIndexes := fooMeta.Indexes
+	defer func() {
+		postMeta.Indexes = postIndexes
+		fooMeta.Indexes = fooIndexes
+	}()
+
+	coal.AddIndex(&postModel{}, false, 0, "Deleted", "Published")
+
+	group := NewGroup(nil)
+	group.Add(&Controller{
+		Model:      &postModel{},
+		Filters:    []string{"Title", "Published", "TextBody"},
+		Sorters:    []string{"Title"},
+		SoftDelete: true,
+		FilterHandlers: map[string]FilterHandler{
+			"TextBody": func(ctx *Context, values []string) (bson.M, error) {
+				return nil, nil
+			},
+		},
+	}, &Controller{
+		Model:          &fooModel{},
+		Filters:        []string{"Bars", "String"},
+		IndexedFilters: []string{"Foos"},
+	}, &Controller{
+		Model: &noteModel{},
+	})
+
+	assert.Equal(t, []IndexSuggestion{
+		{
+			Model:   &fooModel{},
+			Fields:  []string{"Foos", "Bars"},
+			Reason:  `filter "Bars"`,
+			Problem: "index cannot contain multiple array fields",
+		},
+		{
+			Model:  &fooModel{},
+			Fields: []string{"Foos", "String"},
+			Reason: `filter "String"`,
+		},
+		{
+			Model:  &postModel{},
+			Fields: []string{"Deleted", "Title"},
+			Reason: `filter "Title"`,
+		},
+		{
+			Model:   &postModel{},
+			Fields:  []string{"Deleted", "TextBody"},
+			Reason:  `filter "TextBody"`,
+			Problem: "filter uses a custom handler",
+		},
+	}, group.SuggestIndexes(false))
+
+	assert.Len(t, group.SuggestIndexes(true), 4)
+	assert.Equal(t, []IndexSuggestion{
+		{
+			Model:   &fooModel{},
+			Fields:  []string{"Foos", "Bars"},
+			Reason:  `filter "Bars"`,
+			Problem: "index cannot contain multiple array fields",
+		},
+		{
+			Model:   &postModel{},
+			Fields:  []string{"Deleted", "TextBody"},
+			Reason:  `filter "TextBody"`,
+			Problem: "filter uses a custom handler",
+		},
+	}, group.SuggestIndexes(false))
+}
@@ -0,0 +1,113 @@
+package fire
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// Locale configures how the language and time zone of a request handled by
+// a group are determined.
+type Locale struct {
+	// The languages supported by the application. The language of a request
+	// is matched against these using the "Accept-Language" header. The first
+	// language is used as the default.
+	//
+	// Default: language.English.
+	Languages []language.Tag
+
+	// The time zone used if the request does not specify a valid one.
+	//
+	// Default: time.UTC.
+	Location *time.Location
+
+	// The name of the header that carries the IANA time zone name (e.g.
+	// "Europe/Zurich").
+	//
+	// Default: "Time-Zone".
+	HeaderName string
+
+	// The name of the query parameter that carries the IANA time zone name.
+	// The parameter takes precedence over the header.
+	//
+	// Default: "tz".
+	ParameterName string
+
+	matcher   language.Matcher
+	locations sync.Map
+}
+
+func (l *Locale) prepare() {
+	// set default languages
+	if len(l.Languages) == 0 {
+		l.Languages = []language.Tag{language.English}
+	}
+
+	// set default location
+	if l.Location == nil {
+		l.Location = time.UTC
+	}
+
+	// set default header name
+	if l.HeaderName == "" {
+		l.HeaderName = "Time-Zone"
+	}
+
+	// set default parameter name
+	if l.ParameterName == "" {
+		l.ParameterName = "tz"
+	}
+
+	// create matcher
+	l.matcher = language.NewMatcher(l.Languages)
+}
+
+func (l *Locale) resolve(r *http.Request) (language.Tag, *time.Location) {
+	// match language
+	lang := l.Languages[0]
+	if header := r.Header.Get("Accept-Language"); header != "" {
+		tags, _, err := language.ParseAcceptLanguage(header)
+		if err == nil && len(tags) > 0 {
+			_, index, confidence := l.matcher.Match(tags...)
+			if confidence != language.No {
+				lang = l.Languages[index]
+			}
+		}
+	}
+
+	// get time zone name
+	name := r.URL.Query().Get(l.ParameterName)
+	if name == "" {
+		name = r.Header.Get(l.HeaderName)
+	}
+
+	// load location
+	loc := l.Location
+	if name != "" && name != "Local" {
+		if parsed := l.load(name); parsed != nil {
+			loc = parsed
+		}
+	}
+
+	return lang, loc
+}
+
+func (l *Locale) load(name string) *time.Location {
+	// check cache
+	if value, ok := l.locations.Load(name); ok {
+		return value.(*time.Location)
+	}
+
+	// load location
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+
+	// cache valid locations only, which keeps the cache bounded
+	l.locations.Store(name, loc)
+
+	return loc
+}
@@ -0,0 +1,198 @@
+package fire
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Delivery is a single request captured by a Receiver.
+type Delivery struct {
+	// The request method, path and header.
+	Method string
+	Path   string
+	Header http.Header
+
+	// The request body.
+	Body []byte
+
+	// The attempt number of the delivery for the path, starting at one.
+	Attempt int
+
+	// The status code that has been responded.
+	Status int
+}
+
+// Success returns whether the delivery has been accepted.
+func (d Delivery) Success() bool {
+	return d.Status < 300
+}
+
+// Decode will decode the JSON body of the delivery into the provided value.
+func (d Delivery) Decode(value interface{}) error {
+	return json.Unmarshal(d.Body, value)
+}
+
+// Receiver is a mock upstream HTTP receiver that captures webhooks and other
+// outgoing requests issued by notifiers and callbacks during tests. Failures
+// may be injected to verify retry behaviour.
+type Receiver struct {
+	server     *httptest.Server
+	deliveries []Delivery
+	attempts   map[string]int
+	failures   []int
+	notify     chan struct{}
+	mutex      sync.Mutex
+}
+
+// NewReceiver creates and starts a new receiver. The receiver should be closed
+// when no longer needed.
+func NewReceiver() *Receiver {
+	// create receiver
+	r := &Receiver{
+		attempts: map[string]int{},
+		notify:   make(chan struct{}, 1),
+	}
+
+	// create server
+	r.server = httptest.NewServer(http.HandlerFunc(r.handle))
+
+	return r
+}
+
+// URL returns the base URL of the receiver.
+func (r *Receiver) URL() string {
+	return r.server.URL
+}
+
+// Fail will respond to the next n deliveries with the provided status code.
+func (r *Receiver) Fail(n int, status int) {
+	// acquire mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// queue failures
+	for i := 0; i < n; i++ {
+		r.failures = append(r.failures, status)
+	}
+}
+
+// Deliveries returns all captured deliveries including failed ones.
+func (r *Receiver) Deliveries() []Delivery {
+	// acquire mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Delivery{}, r.deliveries...)
+}
+
+// Delivered returns all captured deliveries that have been accepted.
+func (r *Receiver) Delivered() []Delivery {
+	// acquire mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// collect successful deliveries
+	var list []Delivery
+	for _, delivery := range r.deliveries {
+		if delivery.Success() {
+			list = append(list, delivery)
+		}
+	}
+
+	return list
+}
+
+// Attempts returns the number of delivery attempts for the specified path.
+func (r *Receiver) Attempts(path string) int {
+	// acquire mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.attempts[path]
+}
+
+// Await will wait until the specified number of deliveries have been accepted
+// or the timeout has been reached. It will return the accepted deliveries.
+func (r *Receiver) Await(n int, timeout time.Duration) []Delivery {
+	// prepare timer
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		// check deliveries
+		list := r.Delivered()
+		if len(list) >= n {
+			return list
+		}
+
+		// await next delivery or timeout
+		select {
+		case <-r.notify:
+		case <-timer.C:
+			return list
+		}
+	}
+}
+
+// Reset will remove all captured deliveries and pending failures.
+func (r *Receiver) Reset() {
+	// acquire mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// reset state
+	r.deliveries = nil
+	r.attempts = map[string]int{}
+	r.failures = nil
+}
+
+// Close will stop the receiver.
+func (r *Receiver) Close() {
+	r.server.Close()
+}
+
+func (r *Receiver) handle(w http.ResponseWriter, req *http.Request) {
+	// read body
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// acquire mutex
+	r.mutex.Lock()
+
+	// determine status
+	status := http.StatusOK
+	if len(r.failures) > 0 {
+		status = r.failures[0]
+		r.failures = r.failures[1:]
+	}
+
+	// record delivery
+	r.attempts[req.URL.Path]++
+	r.deliveries = append(r.deliveries, Delivery{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Header:  req.Header.Clone(),
+		Body:    body,
+		Attempt: r.attempts[req.URL.Path],
+		Status:  status,
+	})
+
+	// release mutex
+	r.mutex.Unlock()
+
+	// notify waiters
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+
+	// write status
+	w.WriteHeader(status)
+}
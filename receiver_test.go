@@ -0,0 +1,81 @@
+package fire
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiver(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		receiver := NewReceiver()
+		defer receiver.Close()
+
+		tester.Assign("", &Controller{
+			Model: &postModel{},
+			Notifiers: L{
+				C("Webhook", Notifier, Only(Create), func(ctx *Context) error {
+					body := []byte(`{"title":"` + ctx.Model.(*postModel).Title + `"}`)
+					for i := 0; i < 3; i++ {
+						res, err := http.Post(receiver.URL()+"/hooks/posts", "application/json", bytes.NewReader(body))
+						if err != nil {
+							return err
+						}
+						_ = res.Body.Close()
+						if res.StatusCode < 300 {
+							return nil
+						}
+					}
+
+					return xo.F("delivery failed")
+				}),
+			},
+		}, &Controller{
+			Model: &commentModel{},
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		receiver.Fail(2, http.StatusServiceUnavailable)
+
+		tester.Request("POST", "posts", `{
+			"data": {
+				"type": "posts",
+				"attributes": {
+					"title": "Hello"
+				}
+			}
+		}`, func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusCreated, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+
+		list := receiver.Await(1, time.Second)
+		assert.Len(t, list, 1)
+		assert.Equal(t, "POST", list[0].Method)
+		assert.Equal(t, "/hooks/posts", list[0].Path)
+		assert.Equal(t, "application/json", list[0].Header.Get("Content-Type"))
+		assert.Equal(t, 3, list[0].Attempt)
+
+		var payload map[string]string
+		err := list[0].Decode(&payload)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"title": "Hello"}, payload)
+
+		assert.Equal(t, 3, receiver.Attempts("/hooks/posts"))
+		assert.Len(t, receiver.Deliveries(), 3)
+		assert.False(t, receiver.Deliveries()[0].Success())
+		assert.Equal(t, http.StatusServiceUnavailable, receiver.Deliveries()[1].Status)
+
+		receiver.Reset()
+		assert.Empty(t, receiver.Deliveries())
+		assert.Equal(t, 0, receiver.Attempts("/hooks/posts"))
+		assert.Empty(t, receiver.Await(1, 10*time.Millisecond))
+	})
+}
@@ -52,6 +52,29 @@ func ConvertModel(model coal.Model) (*jsonapi.Resource, error) {
 			}
 		}
 
+		// handle polymorphic relationship
+		if rel.Polymorphic {
+			var ref *coal.Ref
+			switch val := stick.MustGet(model, rel.Name).(type) {
+			case coal.Ref:
+				ref = &val
+			case *coal.Ref:
+				ref = val
+			}
+			if ref != nil && !ref.IsZero() {
+				relationships[rel.RelName] = &jsonapi.Document{
+					Data: &jsonapi.HybridResource{
+						One: &jsonapi.Resource{
+							Type: ref.Type,
+							ID:   ref.ID.Hex(),
+						},
+					},
+				}
+			} else {
+				relationships[rel.RelName] = &jsonapi.Document{}
+			}
+		}
+
 		// handle to-many relationship
 		if rel.ToMany {
 			ids := stick.MustGet(model, rel.Name).([]coal.ID)
@@ -127,6 +150,24 @@ func AssignResource(model coal.Model, res *jsonapi.Resource) error {
 			}
 		}
 
+		// handle polymorphic
+		if rel.Polymorphic {
+			var ref *coal.Ref
+			if doc.Data != nil && doc.Data.One != nil {
+				ref = &coal.Ref{
+					Type: doc.Data.One.Type,
+					ID:   coal.MustFromHex(doc.Data.One.ID),
+				}
+			}
+			if rel.Optional {
+				stick.MustSet(model, rel.Name, ref)
+			} else if ref != nil {
+				stick.MustSet(model, rel.Name, *ref)
+			} else {
+				stick.MustSet(model, rel.Name, coal.Ref{})
+			}
+		}
+
 		// handle to many
 		if rel.ToMany {
 			if len(doc.Data.Many) > 0 {
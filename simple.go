@@ -0,0 +1,161 @@
+package fire
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/256dpi/jsonapi/v2"
+
+	"github.com/256dpi/fire/coal"
+)
+
+// SimpleJSONType is the media type that is requested by clients to receive
+// simple JSON responses from controllers that enable SimpleJSON.
+const SimpleJSONType = "application/vnd.fire.simple+json"
+
+// SimpleDocument is the simple JSON representation of a JSON-API document. The
+// primary data is either a single object or a list of objects. Included
+// resources are grouped by their type.
+type SimpleDocument struct {
+	Data     interface{}              `json:"data"`
+	Included map[string][]jsonapi.Map `json:"included,omitempty"`
+	Links    *jsonapi.DocumentLinks   `json:"links,omitempty"`
+	Meta     jsonapi.Map              `json:"meta,omitempty"`
+}
+
+// Simplify will convert the provided JSON-API document to its simple JSON
+// representation. Resources are converted to plain objects that contain the
+// "id" and all attributes using camel cased keys. Relationships are flattened
+// to fields with the related IDs. The metas of the resource types are used to
+// name these fields after the database keys of to-one and to-many
+// relationships (e.g. "post_ids" becomes "postIds"). Other relationships and
+// resources without a meta use "<name>Id" and "<name>Ids" fields.
+func Simplify(doc *jsonapi.Document, metas map[string]*coal.Meta) *SimpleDocument {
+	// prepare result
+	simple := &SimpleDocument{
+		Links: doc.Links,
+		Meta:  doc.Meta,
+	}
+
+	// convert data
+	if doc.Data != nil {
+		if doc.Data.Many != nil {
+			list := make([]jsonapi.Map, 0, len(doc.Data.Many))
+			for _, res := range doc.Data.Many {
+				list = append(list, simplifyResource(res, metas[res.Type]))
+			}
+			simple.Data = list
+		} else if doc.Data.One != nil {
+			simple.Data = simplifyResource(doc.Data.One, metas[doc.Data.One.Type])
+		}
+	}
+
+	// convert included
+	if len(doc.Included) > 0 {
+		simple.Included = map[string][]jsonapi.Map{}
+		for _, res := range doc.Included {
+			key := camelCase(res.Type)
+			simple.Included[key] = append(simple.Included[key], simplifyResource(res, metas[res.Type]))
+		}
+	}
+
+	return simple
+}
+
+func simplifyResource(res *jsonapi.Resource, meta *coal.Meta) jsonapi.Map {
+	// prepare object
+	obj := make(jsonapi.Map, len(res.Attributes)+len(res.Relationships)+1)
+	obj["id"] = res.ID
+
+	// add attributes
+	for key, value := range res.Attributes {
+		obj[camelCase(key)] = value
+	}
+
+	// add relationships
+	for name, rel := range res.Relationships {
+		// skip relationships without data
+		if rel == nil || rel.Data == nil {
+			continue
+		}
+
+		// add to-many IDs
+		if rel.Data.Many != nil {
+			ids := make([]string, 0, len(rel.Data.Many))
+			for _, ref := range rel.Data.Many {
+				ids = append(ids, ref.ID)
+			}
+			obj[simpleKey(meta, name, "Ids")] = ids
+			continue
+		}
+
+		// add to-one ID
+		if rel.Data.One != nil {
+			obj[simpleKey(meta, name, "Id")] = rel.Data.One.ID
+		} else {
+			obj[simpleKey(meta, name, "Id")] = nil
+		}
+	}
+
+	return obj
+}
+
+func simpleKey(meta *coal.Meta, name, suffix string) string {
+	// use database key of stored relationships
+	if meta != nil {
+		field := meta.Relationships[name]
+		if field != nil && (field.ToOne || field.ToMany) && field.BSONKey != "" {
+			return camelCase(field.BSONKey)
+		}
+	}
+
+	return camelCase(name) + suffix
+}
+
+func acceptsSimpleJSON(r *http.Request) bool {
+	// check accepted media types
+	for _, item := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err == nil && mediaType == SimpleJSONType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeSimpleJSON(w http.ResponseWriter, status int, doc *jsonapi.Document, metas map[string]*coal.Meta) error {
+	// set content type
+	w.Header().Set("Content-Type", SimpleJSONType)
+
+	// write status
+	w.WriteHeader(status)
+
+	// write document
+	return json.NewEncoder(w).Encode(Simplify(doc, metas))
+}
+
+func camelCase(str string) string {
+	// prepare builder
+	var builder strings.Builder
+	builder.Grow(len(str))
+
+	// convert separators
+	upper := false
+	for _, r := range str {
+		if r == '-' || r == '_' {
+			upper = builder.Len() > 0
+			continue
+		}
+		if upper {
+			builder.WriteString(strings.ToUpper(string(r)))
+			upper = false
+			continue
+		}
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,211 @@
+package fire
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/256dpi/jsonapi/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/fire/coal"
+)
+
+func TestSimplify(t *testing.T) {
+	simple := Simplify(&jsonapi.Document{
+		Data: &jsonapi.HybridResource{
+			One: &jsonapi.Resource{
+				Type: "posts",
+				ID:   "1",
+				Attributes: jsonapi.Map{
+					"title":     "foo",
+					"text-body": "bar",
+				},
+				Relationships: map[string]*jsonapi.Document{
+					"author": {
+						Data: &jsonapi.HybridResource{
+							One: &jsonapi.Resource{Type: "users", ID: "2"},
+						},
+					},
+					"cover-image": {
+						Data: &jsonapi.HybridResource{},
+					},
+					"comments": {
+						Data: &jsonapi.HybridResource{
+							Many: []*jsonapi.Resource{
+								{Type: "comments", ID: "3"},
+								{Type: "comments", ID: "4"},
+							},
+						},
+					},
+					"links-only": {
+						Links: &jsonapi.DocumentLinks{Self: "/foo"},
+					},
+				},
+			},
+		},
+		Included: []*jsonapi.Resource{
+			{Type: "blog-users", ID: "2"},
+		},
+		Meta: jsonapi.Map{
+			"count": 1,
+		},
+	}, nil)
+	assert.Equal(t, &SimpleDocument{
+		Data: jsonapi.Map{
+			"id":           "1",
+			"title":        "foo",
+			"textBody":     "bar",
+			"authorId":     "2",
+			"coverImageId": nil,
+			"commentsIds":  []string{"3", "4"},
+		},
+		Included: map[string][]jsonapi.Map{
+			"blogUsers": {
+				{"id": "2"},
+			},
+		},
+		Meta: jsonapi.Map{
+			"count": 1,
+		},
+	}, simple)
+
+	assert.Equal(t, &SimpleDocument{
+		Data: []jsonapi.Map{},
+	}, Simplify(&jsonapi.Document{
+		Data: &jsonapi.HybridResource{
+			Many: []*jsonapi.Resource{},
+		},
+	}, nil))
+
+	assert.Equal(t, &SimpleDocument{
+		Data: []jsonapi.Map{
+			{
+				"id":      "1",
+				"name":    "foo",
+				"postIds": []string{"2"},
+			},
+		},
+	}, Simplify(&jsonapi.Document{
+		Data: &jsonapi.HybridResource{
+			Many: []*jsonapi.Resource{
+				{
+					Type: "selections",
+					ID:   "1",
+					Attributes: jsonapi.Map{
+						"name": "foo",
+					},
+					Relationships: map[string]*jsonapi.Document{
+						"posts": {
+							Data: &jsonapi.HybridResource{
+								Many: []*jsonapi.Resource{
+									{Type: "posts", ID: "2"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, map[string]*coal.Meta{
+		"selections": coal.GetMeta(&selectionModel{}),
+	}))
+}
+
+func TestSimpleJSON(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		tester.Assign("", &Controller{
+			Model:      &postModel{},
+			SimpleJSON: true,
+		}, &Controller{
+			Model:      &commentModel{},
+			SimpleJSON: true,
+		}, &Controller{
+			Model: &selectionModel{},
+		}, &Controller{
+			Model: &noteModel{},
+		})
+
+		post := tester.Insert(&postModel{
+			Title:    "post",
+			TextBody: "body",
+		}).ID().Hex()
+
+		tester.Header["Accept"] = SimpleJSONType
+		defer delete(tester.Header, "Accept")
+
+		tester.Request("GET", "posts/"+post, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, SimpleJSONType, r.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", r.Header().Get("Vary"))
+			assert.JSONEq(t, `{
+				"data": {
+					"id": "`+post+`",
+					"title": "post",
+					"published": false,
+					"textBody": "body",
+					"commentsIds": [],
+					"selectionsIds": [],
+					"noteId": null
+				},
+				"links": {
+					"self": "/posts/`+post+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		tester.Request("GET", "posts", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.JSONEq(t, `{
+				"data": [{
+					"id": "`+post+`",
+					"title": "post",
+					"published": false,
+					"textBody": "body",
+					"commentsIds": [],
+					"selectionsIds": [],
+					"noteId": null
+				}],
+				"links": {
+					"self": "/posts"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		comment := tester.Insert(&commentModel{
+			Message: "comment",
+			Post:    coal.MustFromHex(post),
+		}).ID().Hex()
+
+		tester.Request("GET", "comments/"+comment, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, SimpleJSONType, r.Header().Get("Content-Type"))
+			assert.JSONEq(t, `{
+				"data": {
+					"id": "`+comment+`",
+					"message": "comment",
+					"parentId": null,
+					"postId": "`+post+`"
+				},
+				"links": {
+					"self": "/comments/`+comment+`"
+				}
+			}`, r.Body.String(), tester.DebugRequest(rq, r))
+		})
+
+		tester.Header["Accept"] = "application/json, " + SimpleJSONType + ";q=0.9"
+
+		tester.Request("GET", "comments/"+comment, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, SimpleJSONType, r.Header().Get("Content-Type"))
+		})
+
+		tester.Header["Accept"] = "application/json"
+
+		tester.Request("GET", "comments/"+comment, "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+			assert.Equal(t, jsonapi.MediaType, r.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", r.Header().Get("Vary"))
+		})
+	})
+}
@@ -17,13 +17,17 @@ type itemModel struct {
 	stick.NoValidation
 }
 
-var mongoStore = coal.MustConnect("mongodb://0.0.0.0/test-fire-spark", xo.Crash)
+var mongoStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire-spark", xo.Crash)
 var lungoStore = coal.MustOpen(nil, "test-fire-spark", xo.Crash)
 
 var modelList = []coal.Model{&itemModel{}}
 
 func withTester(t *testing.T, fn func(*testing.T, *fire.Tester)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		tester := fire.NewTester(mongoStore, modelList...)
 		tester.Clean()
 		fn(t, tester)
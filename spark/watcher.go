@@ -1,6 +1,7 @@
 package spark
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/256dpi/fire"
@@ -67,3 +68,15 @@ func (w *Watcher) Close() {
 	// close manager
 	w.manager.close()
 }
+
+// Component returns a component that closes the watcher during an application
+// shutdown.
+func (w *Watcher) Component() fire.Component {
+	return fire.Component{
+		Name: "watcher",
+		Close: func(context.Context) error {
+			w.Close()
+			return nil
+		},
+	}
+}
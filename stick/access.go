@@ -6,7 +6,7 @@ import (
 	"sync"
 )
 
-var accessMutex sync.Mutex
+var accessMutex sync.RWMutex
 var accessCache = map[reflect.Type]*Accessor{}
 
 // Accessible is a type that provides a custom accessor for dynamic access.
@@ -34,12 +34,20 @@ func Access(v interface{}, ignore ...string) *Accessor {
 	// get type
 	typ := structType(v)
 
+	// check if accessor has already been cached
+	accessMutex.RLock()
+	accessor, ok := accessCache[typ]
+	accessMutex.RUnlock()
+	if ok {
+		return accessor
+	}
+
 	// acquire mutex
 	accessMutex.Lock()
 	defer accessMutex.Unlock()
 
-	// check if accessor has already been cached
-	accessor, ok := accessCache[typ]
+	// check again if accessor has been cached in the meantime
+	accessor, ok = accessCache[typ]
 	if ok {
 		return accessor
 	}
@@ -196,6 +204,59 @@ func MustSet(v interface{}, name string, value interface{}) {
 	}
 }
 
+// GetEntry will look up and return the value of the specified key in the
+// string keyed map at the named field and whether the entry was found.
+func GetEntry(v interface{}, name, key string) (interface{}, bool) {
+	// get map
+	value, ok := GetRaw(v, name)
+	if !ok || value.Kind() != reflect.Map || value.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	// get entry
+	entry := value.MapIndex(reflect.ValueOf(key).Convert(value.Type().Key()))
+	if !entry.IsValid() {
+		return nil, false
+	}
+
+	return entry.Interface(), true
+}
+
+// SetEntry will set the specified key in the string keyed map at the named
+// field to the provided value and return whether the field has been found and
+// the value has been set. A nil map is created if necessary.
+func SetEntry(v interface{}, name, key string, value interface{}) bool {
+	// get map
+	mapValue, ok := GetRaw(v, name)
+	if !ok || mapValue.Kind() != reflect.Map || mapValue.Type().Key().Kind() != reflect.String {
+		return false
+	}
+
+	// get value value
+	elemType := mapValue.Type().Elem()
+	valueValue := reflect.ValueOf(value)
+
+	// correct untyped nil values
+	if value == nil && (elemType.Kind() == reflect.Ptr || elemType.Kind() == reflect.Interface) {
+		valueValue = reflect.Zero(elemType)
+	}
+
+	// check type
+	if !valueValue.IsValid() || !valueValue.Type().AssignableTo(elemType) {
+		return false
+	}
+
+	// ensure map
+	if mapValue.IsNil() {
+		mapValue.Set(reflect.MakeMap(mapValue.Type()))
+	}
+
+	// set entry
+	mapValue.SetMapIndex(reflect.ValueOf(key).Convert(mapValue.Type().Key()), valueValue)
+
+	return true
+}
+
 func structType(v interface{}) reflect.Type {
 	typ := reflect.TypeOf(v)
 	if typ.Kind() != reflect.Ptr {
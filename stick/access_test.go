@@ -178,6 +178,47 @@ func TestMustSet(t *testing.T) {
 	})
 }
 
+func TestEntries(t *testing.T) {
+	type mapAccessible struct {
+		Labels map[string]string
+		Values map[string]*int
+		Other  map[int]string
+		String string
+	}
+
+	acc := &mapAccessible{}
+
+	_, ok := GetEntry(acc, "Labels", "foo")
+	assert.False(t, ok)
+
+	ok = SetEntry(acc, "Labels", "foo", "bar")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"foo": "bar"}, acc.Labels)
+
+	value, ok := GetEntry(acc, "Labels", "foo")
+	assert.True(t, ok)
+	assert.Equal(t, "bar", value)
+
+	ok = SetEntry(acc, "Labels", "foo", 1)
+	assert.False(t, ok)
+
+	ok = SetEntry(acc, "Values", "foo", nil)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]*int{"foo": nil}, acc.Values)
+
+	ok = SetEntry(acc, "Labels", "foo", nil)
+	assert.False(t, ok)
+
+	ok = SetEntry(acc, "Other", "foo", "bar")
+	assert.False(t, ok)
+
+	_, ok = GetEntry(acc, "String", "foo")
+	assert.False(t, ok)
+
+	ok = SetEntry(acc, "Missing", "foo", "bar")
+	assert.False(t, ok)
+}
+
 func BenchmarkBuildAccessor(b *testing.B) {
 	acc := &accessible{}
 
@@ -0,0 +1,251 @@
+package stick
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/256dpi/xo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// ByteSize is a number of bytes that is encoded as a human-readable string
+// (e.g. "5MB" or "2GiB") in JSON and stored as an integer in BSON. JSON numbers
+// are accepted as bytes. The rules IsMinInt and IsMaxInt may be used to
+// validate sizes.
+type ByteSize int64
+
+// The available byte size units.
+const (
+	Byte     ByteSize = 1
+	Kilobyte          = 1000 * Byte
+	Megabyte          = 1000 * Kilobyte
+	Gigabyte          = 1000 * Megabyte
+	Terabyte          = 1000 * Gigabyte
+	Kibibyte          = 1024 * Byte
+	Mebibyte          = 1024 * Kibibyte
+	Gibibyte          = 1024 * Mebibyte
+	Tebibyte          = 1024 * Gibibyte
+)
+
+var byteSizeUnits = []struct {
+	name string
+	size ByteSize
+}{
+	{"TiB", Tebibyte},
+	{"TB", Terabyte},
+	{"GiB", Gibibyte},
+	{"GB", Gigabyte},
+	{"MiB", Mebibyte},
+	{"MB", Megabyte},
+	{"KiB", Kibibyte},
+	{"KB", Kilobyte},
+	{"B", Byte},
+}
+
+// ParseByteSize will parse a byte size like "512", "1.5KB" or "2 GiB". Unit
+// names are case-insensitive.
+func ParseByteSize(str string) (ByteSize, error) {
+	// trim string
+	str = strings.TrimSpace(str)
+
+	// find unit
+	unit := Byte
+	for _, u := range byteSizeUnits {
+		if len(str) > len(u.name) && strings.EqualFold(str[len(str)-len(u.name):], u.name) {
+			unit = u.size
+			str = strings.TrimSpace(str[:len(str)-len(u.name)])
+			break
+		}
+	}
+
+	// parse number
+	num, err := strconv.ParseFloat(str, 64)
+	if err != nil || num < 0 || math.IsInf(num, 0) || math.IsNaN(num) {
+		return 0, xo.SF("invalid byte size")
+	}
+
+	// compute size
+	size := num * float64(unit)
+	if size > math.MaxInt64 || size != math.Trunc(size) {
+		return 0, xo.SF("invalid byte size")
+	}
+
+	return ByteSize(size), nil
+}
+
+// String will format the size using the largest unit that evenly divides the
+// size.
+func (s ByteSize) String() string {
+	// handle zero and negative sizes
+	if s <= 0 {
+		return strconv.FormatInt(int64(s), 10) + "B"
+	}
+
+	// find unit
+	for _, u := range byteSizeUnits {
+		if s%u.size == 0 {
+			return strconv.FormatInt(int64(s/u.size), 10) + u.name
+		}
+	}
+
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *ByteSize) UnmarshalJSON(data []byte) error {
+	// handle numbers
+	var num int64
+	if json.Unmarshal(data, &num) == nil {
+		*s = ByteSize(num)
+		return nil
+	}
+
+	// decode string
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return xo.SF("invalid byte size")
+	}
+
+	// parse string
+	size, err := ParseByteSize(str)
+	if err != nil {
+		return err
+	}
+
+	*s = size
+
+	return nil
+}
+
+// Duration is a duration that is encoded as a human-readable string (e.g. "5m"
+// or "2h30m") in JSON and stored as an integer of nanoseconds in BSON. JSON
+// numbers are accepted as nanoseconds. The rules IsMinInt and IsMaxInt may be
+// used to validate durations.
+type Duration time.Duration
+
+// String will format the duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	// handle numbers
+	var num int64
+	if json.Unmarshal(data, &num) == nil {
+		*d = Duration(num)
+		return nil
+	}
+
+	// decode string
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return xo.SF("invalid duration")
+	}
+
+	// parse string
+	dur, err := time.ParseDuration(str)
+	if err != nil {
+		return xo.SF("invalid duration")
+	}
+
+	*d = Duration(dur)
+
+	return nil
+}
+
+// DateLayout is the layout used to format and parse dates.
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date without a time that is encoded as "YYYY-MM-DD" in JSON
+// and stored as a date time at midnight UTC in BSON.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the date for the specified year, month and day.
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf returns the date of the provided time in its location.
+func DateOf(t time.Time) Date {
+	return NewDate(t.Date())
+}
+
+// ParseDate will parse a date of the form "YYYY-MM-DD".
+func ParseDate(str string) (Date, error) {
+	// parse date
+	t, err := time.Parse(DateLayout, str)
+	if err != nil {
+		return Date{}, xo.SF("invalid date")
+	}
+
+	return Date{Time: t}, nil
+}
+
+// String will format the date.
+func (d Date) String() string {
+	return d.Time.Format(DateLayout)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	// decode string
+	var str string
+	err := json.Unmarshal(data, &str)
+	if err != nil {
+		return xo.SF("invalid date")
+	}
+
+	// parse string
+	date, err := ParseDate(str)
+	if err != nil {
+		return err
+	}
+
+	*d = date
+
+	return nil
+}
+
+// MarshalBSONValue implements the bson.ValueMarshaler interface.
+func (d Date) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(DateOf(d.Time).Time)
+}
+
+// UnmarshalBSONValue implements the bson.ValueUnmarshaler interface.
+func (d *Date) UnmarshalBSONValue(typ bsontype.Type, bytes []byte) error {
+	// check type
+	if typ != bson.TypeDateTime {
+		return xo.F("cannot decode %s into a date", typ)
+	}
+
+	// decode time
+	t := bson.RawValue{Type: typ, Value: bytes}.Time()
+
+	*d = DateOf(t.UTC())
+
+	return nil
+}
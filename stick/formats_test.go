@@ -0,0 +1,137 @@
+package stick
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type formatsModel struct {
+	Size     ByteSize `json:"size"`
+	Duration Duration `json:"duration"`
+	Date     Date     `json:"date"`
+	OptDate  *Date    `json:"opt-date"`
+}
+
+func TestParseByteSize(t *testing.T) {
+	for str, size := range map[string]ByteSize{
+		"0":       0,
+		"512":     512,
+		"512B":    512,
+		"1.5KB":   1500,
+		"2 kib":   2048,
+		"5MB":     5 * Megabyte,
+		"1.5GiB":  1536 * Mebibyte,
+		" 3TB ":   3 * Terabyte,
+		"0.5 TiB": 512 * Gibibyte,
+	} {
+		ret, err := ParseByteSize(str)
+		assert.NoError(t, err, str)
+		assert.Equal(t, size, ret, str)
+	}
+
+	for _, str := range []string{"", "KB", "-5MB", "1.5B", "foo", "5XB", "1e30TB"} {
+		_, err := ParseByteSize(str)
+		assert.Error(t, err, str)
+		assert.Equal(t, "invalid byte size", err.Error())
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	assert.Equal(t, "0B", ByteSize(0).String())
+	assert.Equal(t, "7B", ByteSize(7).String())
+	assert.Equal(t, "1KB", Kilobyte.String())
+	assert.Equal(t, "1KiB", Kibibyte.String())
+	assert.Equal(t, "1536KiB", (1536 * Kibibyte).String())
+	assert.Equal(t, "2GiB", (2 * Gibibyte).String())
+	assert.Equal(t, "5MB", (5 * Megabyte).String())
+	assert.Equal(t, "1001B", ByteSize(1001).String())
+}
+
+func TestDate(t *testing.T) {
+	date := NewDate(2020, time.March, 15)
+	assert.Equal(t, "2020-03-15", date.String())
+
+	loc := time.FixedZone("test", -5*3600)
+	assert.Equal(t, date, DateOf(time.Date(2020, time.March, 15, 22, 0, 0, 0, loc)))
+
+	ret, err := ParseDate("2020-03-15")
+	assert.NoError(t, err)
+	assert.Equal(t, date, ret)
+
+	_, err = ParseDate("2020-03-15T00:00:00Z")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid date", err.Error())
+}
+
+func TestFormatsCoding(t *testing.T) {
+	date := NewDate(2020, time.March, 15)
+	model := formatsModel{
+		Size:     5 * Megabyte,
+		Duration: Duration(90 * time.Minute),
+		Date:     date,
+		OptDate:  &date,
+	}
+
+	/* json */
+
+	data, err := JSON.Marshal(model)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"size": "5MB",
+		"duration": "1h30m0s",
+		"date": "2020-03-15",
+		"opt-date": "2020-03-15"
+	}`, string(data))
+
+	var out formatsModel
+	err = JSON.Unmarshal(data, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, model, out)
+
+	out = formatsModel{}
+	err = JSON.Unmarshal([]byte(`{
+		"size": 1024,
+		"duration": "5m",
+		"date": "2021-12-31"
+	}`), &out)
+	assert.NoError(t, err)
+	assert.Equal(t, formatsModel{
+		Size:     Kibibyte,
+		Duration: Duration(5 * time.Minute),
+		Date:     NewDate(2021, time.December, 31),
+	}, out)
+
+	for _, doc := range []string{
+		`{"size": "foo"}`,
+		`{"size": true}`,
+		`{"duration": "5x"}`,
+		`{"date": "31.12.2021"}`,
+		`{"date": 5}`,
+	} {
+		err = json.Unmarshal([]byte(doc), &out)
+		assert.Error(t, err, doc)
+	}
+
+	/* bson */
+
+	data, err = BSON.Marshal(model)
+	assert.NoError(t, err)
+
+	var doc bson.M
+	err = BSON.Unmarshal(data, &doc)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5*Megabyte), doc["size"])
+	assert.Equal(t, int64(90*time.Minute), doc["duration"])
+	assert.Equal(t, primitive.NewDateTimeFromTime(date.Time), doc["date"])
+	assert.Equal(t, primitive.NewDateTimeFromTime(date.Time), doc["optdate"])
+
+	out = formatsModel{}
+	err = BSON.Unmarshal(data, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, model, out)
+}
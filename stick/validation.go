@@ -158,6 +158,61 @@ func (v *Validator) Items(name string, rules ...Rule) {
 	})
 }
 
+// Keys will validate each key of the string keyed map at the named field using
+// the provided rules.
+func (v *Validator) Keys(name string, rules ...Rule) {
+	v.entries(name, true, rules)
+}
+
+// Entries will validate each value of the string keyed map at the named field
+// using the provided rules.
+func (v *Validator) Entries(name string, rules ...Rule) {
+	v.entries(name, false, rules)
+}
+
+func (v *Validator) entries(name string, keys bool, rules []Rule) {
+	// get map
+	value := MustGetRaw(v.value, name)
+
+	// check type
+	if value.Kind() != reflect.Map || value.Type().Key().Kind() != reflect.String {
+		panic("stick: expected string keyed map")
+	}
+
+	// sort keys
+	mapKeys := value.MapKeys()
+	sort.Slice(mapKeys, func(i, j int) bool {
+		return mapKeys[i].String() < mapKeys[j].String()
+	})
+
+	// execute rules for each key or value
+	v.Nest(name, func() {
+		for _, key := range mapKeys {
+			// get addressable key or value
+			item := key
+			if !keys {
+				item = value.MapIndex(key)
+			}
+			copied := reflect.New(item.Type()).Elem()
+			copied.Set(item)
+
+			// prepare subject
+			sub := Subject{
+				IValue: copied.Interface(),
+				RValue: copied,
+			}
+
+			// execute rules
+			for _, rule := range rules {
+				err := rule(sub)
+				if err != nil {
+					v.Report(key.String(), err)
+				}
+			}
+		}
+	})
+}
+
 // Report will report a validation error.
 func (v *Validator) Report(name string, err error) {
 	// ensure error
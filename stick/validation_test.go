@@ -44,6 +44,7 @@ type validatable struct {
 	Validatable    subValidatable
 	OptValidatable *subValidatable
 	Validatables   []subValidatable
+	Labels         map[string]string
 }
 
 func (v *validatable) Validate() error {
@@ -132,6 +133,20 @@ func TestValidate(t *testing.T) {
 	})
 	assert.Error(t, err)
 	assert.Equal(t, "OptValidatable: invalid; Validatable: invalid; Validatables.0: invalid; Validatables: too short", err.Error())
+
+	assert.PanicsWithValue(t, "stick: expected string keyed map", func() {
+		err = Validate(obj, func(v *Validator) {
+			v.Entries("Strings")
+		})
+	})
+
+	obj.Labels = map[string]string{"b": "", "a": "foo", "": "bar"}
+	err = Validate(obj, func(v *Validator) {
+		v.Keys("Labels", IsNotZero)
+		v.Entries("Labels", IsMinLen(2))
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "Labels.: zero; Labels.b: too short", err.Error())
 }
 
 func TestValidateErrorIsolation(t *testing.T) {
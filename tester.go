@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/256dpi/jsonapi/v2"
 	"github.com/256dpi/serve"
@@ -197,6 +198,11 @@ func (t *Tester) WithContext(ctx *Context, fn func(ctx *Context) error) error {
 		ctx.Store = t.Store
 	}
 
+	// ensure location
+	if ctx.Location == nil {
+		ctx.Location = time.UTC
+	}
+
 	// set request
 	if ctx.HTTPRequest == nil {
 		// create request
@@ -8,13 +8,17 @@ import (
 	"github.com/256dpi/fire/coal"
 )
 
-var mongoStore = coal.MustConnect("mongodb://0.0.0.0/test-fire-torch", xo.Crash)
+var mongoStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire-torch", xo.Crash)
 var lungoStore = coal.MustOpen(nil, "test-fire-torch", xo.Crash)
 
 var modelList = []coal.Model{&axe.Model{}, &testModel{}, &checkModel{}}
 
 func withStore(t *testing.T, fn func(*testing.T, *coal.Store)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		coal.NewTester(mongoStore, modelList...).Clean()
 		fn(t, mongoStore)
 	})
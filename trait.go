@@ -0,0 +1,88 @@
+package fire
+
+import "fmt"
+
+// Trait bundles reusable controller configuration like callbacks, settings
+// and actions that can be applied to multiple controllers. This allows common
+// patterns like "timestamped", "owned" or "soft-deletable" to be declared
+// once.
+type Trait struct {
+	// The name of the trait.
+	Name string
+
+	// Configure is called with the controller before it is prepared and may
+	// be used to adjust its settings (e.g. enable SoftDelete or add filters).
+	Configure func(c *Controller)
+
+	// The callbacks that are run before the callbacks of the controller.
+	Authorizers []*Callback
+	Verifiers   []*Callback
+	Modifiers   []*Callback
+	Validators  []*Callback
+	Decorators  []*Callback
+	Notifiers   []*Callback
+
+	// The actions that are added to the controller.
+	CollectionActions map[string]*Action
+	ResourceActions   map[string]*Action
+}
+
+func (c *Controller) applyTraits() {
+	// get traits
+	traits := c.Traits
+	if len(traits) == 0 {
+		return
+	}
+
+	// prevent repeated application
+	c.Traits = nil
+
+	// prepare lists
+	var authorizers, verifiers, modifiers, validators, decorators, notifiers []*Callback
+
+	// apply traits
+	for _, trait := range traits {
+		// configure controller
+		if trait.Configure != nil {
+			trait.Configure(c)
+		}
+
+		// collect callbacks
+		authorizers = append(authorizers, trait.Authorizers...)
+		verifiers = append(verifiers, trait.Verifiers...)
+		modifiers = append(modifiers, trait.Modifiers...)
+		validators = append(validators, trait.Validators...)
+		decorators = append(decorators, trait.Decorators...)
+		notifiers = append(notifiers, trait.Notifiers...)
+
+		// add collection actions
+		for name, action := range trait.CollectionActions {
+			if c.CollectionActions[name] != nil {
+				panic(fmt.Sprintf(`fire: trait "%s" collection action "%s" already exists`, trait.Name, name))
+			}
+			if c.CollectionActions == nil {
+				c.CollectionActions = map[string]*Action{}
+			}
+			c.CollectionActions[name] = action
+		}
+
+		// add resource actions
+		for name, action := range trait.ResourceActions {
+			if c.ResourceActions[name] != nil {
+				panic(fmt.Sprintf(`fire: trait "%s" resource action "%s" already exists`, trait.Name, name))
+			}
+			if c.ResourceActions == nil {
+				c.ResourceActions = map[string]*Action{}
+			}
+			c.ResourceActions[name] = action
+		}
+	}
+
+	// prepend callbacks
+	c.Authorizers = append(authorizers, c.Authorizers...)
+	c.Verifiers = append(verifiers, c.Verifiers...)
+	c.Modifiers = append(modifiers, c.Modifiers...)
+	c.Validators = append(validators, c.Validators...)
+	c.Decorators = append(decorators, c.Decorators...)
+	c.Notifiers = append(notifiers, c.Notifiers...)
+}
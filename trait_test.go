@@ -0,0 +1,114 @@
+package fire
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraits(t *testing.T) {
+	withTester(t, func(t *testing.T, tester *Tester) {
+		var events []string
+
+		record := func(name string) *Callback {
+			return C(name, 0, All(), func(ctx *Context) error {
+				events = append(events, name)
+				return nil
+			})
+		}
+
+		timestamped := &Trait{
+			Name: "timestamped",
+			Configure: func(c *Controller) {
+				c.Filters = append(c.Filters, "Title")
+			},
+			Authorizers: L{record("timestamped-authorizer")},
+			Notifiers:   L{record("timestamped-notifier")},
+		}
+
+		owned := &Trait{
+			Name:        "owned",
+			Authorizers: L{record("owned-authorizer")},
+			CollectionActions: M{
+				"owner": A("owner", []string{"GET"}, 0, 0, func(ctx *Context) error {
+					_, _ = ctx.ResponseWriter.Write([]byte("owner"))
+					return nil
+				}),
+			},
+		}
+
+		tester.Assign("", &Controller{
+			Model:       &postModel{},
+			Store:       tester.Store,
+			Traits:      []*Trait{timestamped, owned},
+			Authorizers: L{record("authorizer")},
+			Notifiers:   L{record("notifier")},
+		}, &Controller{
+			Model:  &commentModel{},
+			Store:  tester.Store,
+			Traits: []*Trait{timestamped},
+		}, &Controller{
+			Model: &selectionModel{},
+			Store: tester.Store,
+		}, &Controller{
+			Model: &noteModel{},
+			Store: tester.Store,
+		})
+
+		tester.Request("GET", "posts?filter[title]=foo", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode, tester.DebugRequest(rq, r))
+		})
+		assert.Equal(t, []string{
+			"timestamped-authorizer",
+			"owned-authorizer",
+			"authorizer",
+			"timestamped-notifier",
+			"notifier",
+		}, events)
+
+		events = nil
+		tester.Request("GET", "posts/owner", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+			assert.Equal(t, "owner", r.Body.String())
+		})
+		assert.Equal(t, []string{
+			"timestamped-authorizer",
+			"owned-authorizer",
+			"authorizer",
+		}, events)
+
+		events = nil
+		tester.Request("GET", "comments", "", func(r *httptest.ResponseRecorder, rq *http.Request) {
+			assert.Equal(t, http.StatusOK, r.Result().StatusCode)
+		})
+		assert.Equal(t, []string{
+			"timestamped-authorizer",
+			"timestamped-notifier",
+		}, events)
+	})
+}
+
+func TestTraitsActionConflict(t *testing.T) {
+	trait := &Trait{
+		Name: "foo",
+		CollectionActions: M{
+			"bar": A("bar", []string{"GET"}, 0, 0, func(ctx *Context) error {
+				return nil
+			}),
+		},
+	}
+
+	assert.PanicsWithValue(t, `fire: trait "foo" collection action "bar" already exists`, func() {
+		NewGroup(nil).Add(&Controller{
+			Model:  &postModel{},
+			Traits: []*Trait{trait},
+			CollectionActions: M{
+				"bar": A("bar", []string{"GET"}, 0, 0, func(ctx *Context) error {
+					return nil
+				}),
+			},
+		})
+	})
+}
@@ -1,10 +1,18 @@
 package fire
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/256dpi/jsonapi/v2"
+	"github.com/256dpi/xo"
 
 	"github.com/256dpi/fire/coal"
+	"github.com/256dpi/fire/stick"
 )
 
 // P is a shorthand to look up the specified property method on the provided
@@ -51,3 +59,253 @@ func P(model coal.Model, name string) func(coal.Model) (interface{}, error) {
 		return out[0].Interface(), nil
 	}
 }
+
+type flight struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg     sync.WaitGroup
+	result interface{}
+	err    error
+	dups   int
+}
+
+// do will run the provided function once for all concurrent callers that use
+// the same key. It returns whether the result has been shared with other
+// callers. Shared results must not be modified.
+func (f *flight) do(key string, fn func() (interface{}, error)) (interface{}, bool, error) {
+	// acquire mutex
+	f.mutex.Lock()
+
+	// ensure map
+	if f.calls == nil {
+		f.calls = map[string]*flightCall{}
+	}
+
+	// join existing call
+	if call, ok := f.calls[key]; ok {
+		call.dups++
+		f.mutex.Unlock()
+		call.wg.Wait()
+		return call.result, true, call.err
+	}
+
+	// create call
+	call := &flightCall{}
+	call.wg.Add(1)
+	f.calls[key] = call
+
+	// release mutex
+	f.mutex.Unlock()
+
+	// run function
+	func() {
+		defer call.wg.Done()
+		defer func() {
+			f.mutex.Lock()
+			delete(f.calls, key)
+			f.mutex.Unlock()
+		}()
+		call.result, call.err = fn()
+	}()
+
+	// get shared state
+	f.mutex.Lock()
+	shared := call.dups > 0
+	f.mutex.Unlock()
+
+	return call.result, shared, call.err
+}
+
+type watcher struct {
+	mutex  sync.Mutex
+	stream *coal.Stream
+	ready  chan struct{}
+	subs   map[*watcherSub]struct{}
+}
+
+type watcherSub struct {
+	match func(coal.Event, coal.Model) bool
+	done  chan struct{}
+}
+
+// await will block until a change to a document of the provided model has
+// been observed that is accepted by the match function or the context is
+// done. The underlying stream is opened lazily and shared by all callers.
+func (w *watcher) await(ctx context.Context, store *coal.Store, model coal.Model, reporter func(error), match func(coal.Event, coal.Model) bool) {
+	// acquire mutex
+	w.mutex.Lock()
+
+	// ensure stream
+	if w.stream == nil {
+		w.ready = make(chan struct{})
+		w.subs = map[*watcherSub]struct{}{}
+		w.stream = coal.OpenStream(store, model, nil, func(event coal.Event, _ coal.ID, model coal.Model, err error, _ []byte) error {
+			w.receive(event, model, err, reporter)
+			return nil
+		})
+	}
+
+	// add subscriber
+	sub := &watcherSub{
+		match: match,
+		done:  make(chan struct{}),
+	}
+	w.subs[sub] = struct{}{}
+	ready := w.ready
+
+	// release mutex
+	w.mutex.Unlock()
+
+	// ensure subscriber is removed
+	defer func() {
+		w.mutex.Lock()
+		delete(w.subs, sub)
+		w.mutex.Unlock()
+	}()
+
+	// await stream
+	select {
+	case <-ready:
+	case <-sub.done:
+		return
+	case <-ctx.Done():
+		return
+	}
+
+	// await change
+	select {
+	case <-sub.done:
+	case <-ctx.Done():
+	}
+}
+
+// close will close the underlying stream and release all subscribers.
+func (w *watcher) close() {
+	// acquire mutex
+	w.mutex.Lock()
+
+	// get and unset stream
+	stream := w.stream
+	w.stream = nil
+
+	// release subscribers
+	for sub := range w.subs {
+		close(sub.done)
+		delete(w.subs, sub)
+	}
+
+	// release mutex
+	w.mutex.Unlock()
+
+	// close stream
+	if stream != nil {
+		stream.Close()
+	}
+}
+
+func (w *watcher) receive(event coal.Event, model coal.Model, err error, reporter func(error)) {
+	// acquire mutex
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// handle events
+	switch event {
+	case coal.Opened:
+		close(w.ready)
+		return
+	case coal.Stopped:
+		return
+	case coal.Errored:
+		if reporter != nil {
+			reporter(err)
+		}
+	}
+
+	// release subscribers, changes may have been missed on errors and resumes
+	for sub := range w.subs {
+		if event == coal.Errored || event == coal.Resumed || sub.match(event, model) {
+			close(sub.done)
+			delete(w.subs, sub)
+		}
+	}
+}
+
+type errorList []*jsonapi.Error
+
+func (l errorList) Error() string {
+	// collect messages
+	messages := make([]string, 0, len(l))
+	for _, err := range l {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (l errorList) sort() {
+	sort.SliceStable(l, func(i, j int) bool {
+		a, b := errorSource(l[i]), errorSource(l[j])
+		if a != b {
+			return a < b
+		}
+		return l[i].Detail < l[j].Detail
+	})
+}
+
+func errorSource(err *jsonapi.Error) string {
+	// check source
+	if err.Source == nil {
+		return ""
+	}
+
+	return err.Source.Pointer + err.Source.Parameter
+}
+
+func safeMessage(err error) string {
+	// check safety
+	if xo.IsSafe(err) {
+		return err.Error()
+	}
+
+	return "invalid"
+}
+
+func jsonPointer(typ reflect.Type, path []string) string {
+	// map field names to JSON keys
+	segments := make([]string, 0, len(path))
+	for _, name := range path {
+		// unwrap pointers
+		for typ != nil && typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		// handle structs
+		if typ != nil && typ.Kind() == reflect.Struct {
+			field, ok := typ.FieldByName(name)
+			if ok {
+				if key := stick.JSON.GetKey(field); key != "" {
+					name = key
+				}
+				segments = append(segments, name)
+				typ = field.Type
+				continue
+			}
+		}
+
+		// handle slices, arrays and maps
+		if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map) {
+			segments = append(segments, name)
+			typ = typ.Elem()
+			continue
+		}
+
+		// otherwise keep name
+		segments = append(segments, name)
+		typ = nil
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
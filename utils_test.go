@@ -2,10 +2,12 @@ package fire
 
 import (
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/256dpi/xo"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/256dpi/fire/coal"
 	"github.com/256dpi/fire/stick"
@@ -93,13 +95,86 @@ type barModel struct {
 	stick.NoValidation `json:"-" bson:"-"`
 }
 
-var mongoStore = coal.MustConnect("mongodb://0.0.0.0/test-fire", xo.Crash)
+type secretModel struct {
+	coal.Base          `json:"-" bson:",inline" coal:"secrets"`
+	Name               string `json:"name" coal:"coal-deterministic"`
+	Code               string `json:"code" coal:"coal-encrypted"`
+	stick.NoValidation `json:"-" bson:"-"`
+}
+
+type productModel struct {
+	coal.Base          `json:"-" bson:",inline" coal:"products"`
+	Price              coal.Decimal `json:"price"`
+	stick.NoValidation `json:"-" bson:"-"`
+}
+
+type reactionModel struct {
+	coal.Base          `json:"-" bson:",inline" coal:"reactions"`
+	Emoji              string    `json:"emoji"`
+	Subject            coal.Ref  `json:"-" bson:"subject" coal:"subject:posts|notes"`
+	OptSubject         *coal.Ref `json:"-" bson:"opt_subject" coal:"opt-subject:posts|comments"`
+	stick.NoValidation `json:"-" bson:"-"`
+}
+
+type profileModel struct {
+	coal.Base          `json:"-" bson:",inline" coal:"profiles"`
+	FirstName          string `json:"first-name"`
+	LastName           string `json:"last-name"`
+	stick.NoValidation `json:"-" bson:"-"`
+}
+
+func (p *profileModel) FullName() string {
+	return p.FirstName + " " + p.LastName
+}
+
+func init() {
+	coal.AddVirtual(&profileModel{}, "full-name", "FullName")
+}
+
+type settingModel struct {
+	coal.Base `json:"-" bson:",inline" coal:"settings"`
+	Labels    map[string]string `json:"labels"`
+	Flags     map[string]bool   `json:"flags"`
+}
+
+func (s *settingModel) Validate() error {
+	return stick.Validate(s, func(v *stick.Validator) {
+		v.Keys("Labels", stick.IsNotZero)
+		v.Entries("Labels", stick.IsMaxLen(10))
+	})
+}
+
+type actionInput struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+}
+
+func (i *actionInput) Validate() error {
+	return stick.Validate(i, func(v *stick.Validator) {
+		v.Value("Name", false, stick.IsNotZero)
+		v.Value("Count", false, stick.IsMinInt(1))
+		v.Items("Tags", stick.IsNotZero)
+	})
+}
+
+type valueInput struct{}
+
+func (i valueInput) Validate() error {
+	return nil
+}
+
+var mongoStore = coal.MustTestStore("mongodb://0.0.0.0/test-fire", xo.Crash)
 var lungoStore = coal.MustOpen(nil, "test-fire", xo.Crash)
 
-var modelList = []coal.Model{&postModel{}, &commentModel{}, &selectionModel{}, &noteModel{}, &fooModel{}, &barModel{}}
+var modelList = []coal.Model{&postModel{}, &commentModel{}, &selectionModel{}, &noteModel{}, &fooModel{}, &barModel{}, &secretModel{}, &reactionModel{}, &productModel{}, &profileModel{}, &settingModel{}, &coal.Counter{}}
 
 func withTester(t *testing.T, fn func(*testing.T, *Tester)) {
 	t.Run("Mongo", func(t *testing.T) {
+		if mongoStore.Lungo() {
+			t.Skip("in memory mode")
+		}
+
 		tester := NewTester(mongoStore, modelList...)
 		tester.Clean()
 		fn(t, tester)
@@ -122,3 +197,69 @@ func linkUnescape(str string) string {
 	str = strings.ReplaceAll(str, "%2A", "*")
 	return strings.ReplaceAll(str, "%2C", ",")
 }
+
+func TestFlight(t *testing.T) {
+	var f flight
+
+	var calls int
+	release := make(chan struct{})
+
+	fn := func() (interface{}, error) {
+		calls++
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 5)
+	shared := make([]bool, 5)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results[0], shared[0], _ = f.do("foo", fn)
+	}()
+
+	for {
+		f.mutex.Lock()
+		n := len(f.calls)
+		f.mutex.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 1; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], shared[i], _ = f.do("foo", fn)
+		}(i)
+	}
+
+	for {
+		f.mutex.Lock()
+		n := f.calls["foo"].dups
+		f.mutex.Unlock()
+		if n == 4 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []interface{}{42, 42, 42, 42, 42}, results)
+	assert.Equal(t, []bool{true, true, true, true, true}, shared)
+
+	res, ok, err := f.do("foo", func() (interface{}, error) {
+		return 7, nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 7, res)
+	assert.Empty(t, f.calls)
+}